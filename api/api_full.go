@@ -325,6 +325,16 @@ type FullNode interface {
 	ClientStartDeal(ctx context.Context, params *StartDealParams) (*cid.Cid, error) //perm:admin
 	// ClientStatelessDeal fire-and-forget-proposes an offline deal to a miner without subsequent tracking.
 	ClientStatelessDeal(ctx context.Context, params *StartDealParams) (*cid.Cid, error) //perm:write
+	// ClientSaveDealTemplate saves deal parameters under a name, replacing any
+	// template previously saved under that name
+	ClientSaveDealTemplate(ctx context.Context, name string, params DealTemplate) error //perm:admin
+	// ClientStartDealFromTemplate proposes a deal for imported data with the
+	// parameters of a saved template
+	ClientStartDealFromTemplate(ctx context.Context, name string, root cid.Cid) (*cid.Cid, error) //perm:admin
+	// ClientAcceptStartEpochCounterOffer proposes a deal the provider rejected
+	// with a counter-offered start epoch again, starting at that epoch and
+	// otherwise with the same terms. It returns the CID of the new proposal.
+	ClientAcceptStartEpochCounterOffer(ctx context.Context, propCid cid.Cid) (*cid.Cid, error) //perm:admin
 	// ClientGetDealInfo returns the latest information about a given deal.
 	ClientGetDealInfo(context.Context, cid.Cid) (*DealInfo, error) //perm:read
 	// ClientListDeals returns information about the deals made by the local client.
@@ -333,13 +343,27 @@ type FullNode interface {
 	ClientGetDealUpdates(ctx context.Context) (<-chan DealInfo, error) //perm:write
 	// ClientGetDealStatus returns status given a code
 	ClientGetDealStatus(ctx context.Context, statusCode uint64) (string, error) //perm:read
+	// ClientDealSealETA asks the provider of a published deal for the epoch at
+	// which the sector containing the deal is expected to be sealed and proving.
+	ClientDealSealETA(ctx context.Context, dealID abi.DealID) (abi.ChainEpoch, error) //perm:read
+	// ClientSetDealRetrievalTerms sends retrieval terms, signed by the deal
+	// wallet, to the provider of a storage deal it accepted. If the provider
+	// accepts the terms, it prices later retrievals of the deal's piece by this
+	// client at these terms instead of its retrieval ask, until the deal ends.
+	ClientSetDealRetrievalTerms(ctx context.Context, proposalCid cid.Cid, pricePerByte, unsealPrice abi.TokenAmount) error //perm:sign
 	// ClientHasLocal indicates whether a certain CID is locally stored.
 	ClientHasLocal(ctx context.Context, root cid.Cid) (bool, error) //perm:write
 	// ClientFindData identifies peers that have a certain file, and returns QueryOffers (one per peer).
 	ClientFindData(ctx context.Context, root cid.Cid, piece *cid.Cid) ([]QueryOffer, error) //perm:read
+	// ClientFindDataAsync is like ClientFindData, but queries peers in parallel
+	// and returns offers as they arrive. The channel is closed once all peers
+	// responded, or their queries failed.
+	ClientFindDataAsync(ctx context.Context, root cid.Cid, piece *cid.Cid) (<-chan QueryOffer, error) //perm:read
 	// ClientMinerQueryOffer returns a QueryOffer for the specific miner and file.
 	ClientMinerQueryOffer(ctx context.Context, miner address.Address, root cid.Cid, piece *cid.Cid) (QueryOffer, error) //perm:read
 	// ClientRetrieve initiates the retrieval of a file, as specified in the order.
+	// Orders made from offers of miners serving retrievals over HTTP are
+	// retrieved over HTTP.
 	ClientRetrieve(ctx context.Context, order RetrievalOrder, ref *FileRef) error //perm:admin
 	// ClientRetrieveWithEvents initiates the retrieval of a file, as specified in the order, and provides a channel
 	// of status updates.
@@ -352,8 +376,10 @@ type FullNode interface {
 	ClientQueryAsk(ctx context.Context, p peer.ID, miner address.Address) (*storagemarket.StorageAsk, error) //perm:read
 	// ClientCalcCommP calculates the CommP and data size of the specified CID
 	ClientDealPieceCID(ctx context.Context, root cid.Cid) (DataCIDSize, error) //perm:read
-	// ClientCalcCommP calculates the CommP for a specified file
-	ClientCalcCommP(ctx context.Context, inpath string) (*CommPRet, error) //perm:write
+	// ClientCalcCommP calculates the CommP and piece size of a CAR file, the
+	// same way a storage provider does when the file is imported for an
+	// offline deal
+	ClientCalcCommP(ctx context.Context, inpath string) (DataCIDSize, error) //perm:write
 	// ClientGenCar generates a CAR file for the specified file.
 	ClientGenCar(ctx context.Context, ref FileRef, outpath string) error //perm:write
 	// ClientDealSize calculates real deal data size
@@ -731,6 +757,10 @@ type DealInfo struct {
 	DealStages  *storagemarket.DealStages
 	Provider    address.Address
 
+	// start epoch the provider counter-offered when rejecting the deal, see
+	// ClientAcceptStartEpochCounterOffer. 0 when there is none.
+	StartEpochCounterOffer abi.ChainEpoch
+
 	DataRef  *storagemarket.DataRef
 	PieceCID cid.Cid
 	Size     uint64
@@ -870,6 +900,17 @@ type QueryOffer struct {
 	PaymentIntervalIncrease uint64
 	Miner                   address.Address
 	MinerPeer               retrievalmarket.RetrievalPeer
+
+	// transports the miner serves retrievals over besides graphsync
+	Transports []RetrievalTransport
+}
+
+// RetrievalTransport is a transport a miner serves retrievals over besides
+// graphsync
+type RetrievalTransport struct {
+	// Name of the transport, e.g. "http"
+	Name string
+	URL  string
 }
 
 func (o *QueryOffer) Order(client address.Address) RetrievalOrder {
@@ -883,8 +924,9 @@ func (o *QueryOffer) Order(client address.Address) RetrievalOrder {
 		PaymentIntervalIncrease: o.PaymentIntervalIncrease,
 		Client:                  client,
 
-		Miner:     o.Miner,
-		MinerPeer: &o.MinerPeer,
+		Miner:      o.Miner,
+		MinerPeer:  &o.MinerPeer,
+		Transports: o.Transports,
 	}
 }
 
@@ -898,11 +940,17 @@ type MarketDeal struct {
 	State    market.DealState
 }
 
+// Selector is an IPLD selector, encoded as dag-json
+type Selector string
+
 type RetrievalOrder struct {
 	// TODO: make this less unixfs specific
 	Root  cid.Cid
 	Piece *cid.Cid
-	Size  uint64
+	// if specified, only the part of the DAG under Root matched by the
+	// selector is retrieved, and it can only be exported as a CAR
+	DataSelector *Selector
+	Size         uint64
 
 	LocalStore *multistore.StoreID // if specified, get data from local store
 	// TODO: support offset
@@ -913,6 +961,10 @@ type RetrievalOrder struct {
 	Client                  address.Address
 	Miner                   address.Address
 	MinerPeer               *retrievalmarket.RetrievalPeer
+
+	// if an http transport is listed and no DataSelector is set, the data
+	// is fetched over HTTP instead of in a retrieval deal, free of charge
+	Transports []RetrievalTransport
 }
 
 type InvocResult struct {
@@ -958,6 +1010,18 @@ func (s *StartDealParams) UnmarshalJSON(raw []byte) (err error) {
 	return nil
 }
 
+// DealTemplate holds the parameters of a storage deal which don't depend on
+// the data being stored, so that they can be reused for many deals
+type DealTemplate struct {
+	Wallet             address.Address
+	Miner              address.Address
+	EpochPrice         types.BigInt
+	MinBlocksDuration  uint64
+	ProviderCollateral big.Int
+	FastRetrieval      bool
+	VerifiedDeal       bool
+}
+
 type IpldObject struct {
 	Cid cid.Cid
 	Obj interface{}
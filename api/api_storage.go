@@ -12,6 +12,7 @@ import (
 	"github.com/libp2p/go-libp2p-core/peer"
 
 	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-bitfield"
 	datatransfer "github.com/filecoin-project/go-data-transfer"
 	"github.com/filecoin-project/go-fil-markets/piecestore"
 	"github.com/filecoin-project/go-fil-markets/retrievalmarket"
@@ -20,6 +21,7 @@ import (
 	"github.com/filecoin-project/specs-actors/v2/actors/builtin/market"
 	"github.com/filecoin-project/specs-storage/storage"
 
+	"github.com/filecoin-project/lotus/chain/actors/builtin/power"
 	"github.com/filecoin-project/lotus/chain/types"
 	"github.com/filecoin-project/lotus/extern/sector-storage/fsutil"
 	"github.com/filecoin-project/lotus/extern/sector-storage/stores"
@@ -47,8 +49,37 @@ type StorageMiner interface {
 	ActorSectorSize(context.Context, address.Address) (abi.SectorSize, error) //perm:read
 	ActorAddressConfig(ctx context.Context) (AddressConfig, error)            //perm:read
 
+	// MinerPowerBreakdown returns the power of the miner at chain head, split
+	// into active, faulty and recovering power
+	MinerPowerBreakdown(ctx context.Context) (PowerBreakdown, error) //perm:read
+	// MinerDeadlineLoad returns the number of partitions and sectors in each
+	// proving deadline of the miner, and which deadline the next new sector is
+	// expected to be assigned to
+	MinerDeadlineLoad(ctx context.Context) ([]DeadlineLoad, error) //perm:read
+
 	MiningBase(context.Context) (*types.TipSet, error) //perm:read
 
+	// MiningSetEnabled pauses or resumes block production. While disabled the
+	// miner keeps running, but won't attempt to mine or submit any blocks.
+	MiningSetEnabled(ctx context.Context, enabled bool) error //perm:admin
+	// MiningStatus returns whether block production is enabled, and since when
+	MiningStatus(context.Context) (MiningStatus, error) //perm:read
+
+	// ParamsStatus returns the result of the last scheduled verification of
+	// the checksums of the proof parameter files, see
+	// Proving.ParamsVerifyInterval
+	ParamsStatus(context.Context) (ParamsStatus, error) //perm:read
+
+	// SlashFilterHistory returns the blocks recorded by the slash filter as
+	// mined at or after the given epoch. The miner refuses to produce blocks
+	// which would be a consensus fault together with any of these blocks
+	SlashFilterHistory(ctx context.Context, since abi.ChainEpoch) ([]SlashFilterRecord, error) //perm:admin
+	// SlashFilterPrune removes the records of blocks mined before the given
+	// epoch, which must be past finality, and returns the number of removed
+	// records. The miner is no longer protected from consensus faults against
+	// the removed blocks, the call fails unless confirm is set
+	SlashFilterPrune(ctx context.Context, before abi.ChainEpoch, confirm bool) (int, error) //perm:admin
+
 	// Temp api for testing
 	PledgeSector(context.Context) (abi.SectorID, error) //perm:write
 
@@ -58,17 +89,34 @@ type StorageMiner interface {
 	// List all staged sectors
 	SectorsList(context.Context) ([]abi.SectorNumber, error) //perm:read
 
+	// SectorFaultHistory returns the WindowPoSt fault and recovery events
+	// recorded for the sector, oldest first
+	SectorFaultHistory(ctx context.Context, sid abi.SectorNumber) ([]SectorFaultEvent, error) //perm:read
+
 	// Get summary info of sectors
 	SectorsSummary(ctx context.Context) (map[SectorState]int, error) //perm:read
 
 	// List sectors in particular states
 	SectorsListInStates(context.Context, []SectorState) ([]abi.SectorNumber, error) //perm:read
 
+	// SectorsOpen lists the sectors in WaitDeals that are accepting deals,
+	// with the space already used in each of them
+	SectorsOpen(context.Context) ([]sealiface.OpenSector, error) //perm:read
+
 	SectorsRefs(context.Context) (map[string][]SealedRef, error) //perm:read
 
 	// SectorStartSealing can be called on sectors in Empty or WaitDeals states
 	// to trigger sealing early
 	SectorStartSealing(context.Context, abi.SectorNumber) error //perm:write
+	// SectorAbortWaitDeals removes a sector in WaitDeals which doesn't hold
+	// any deals yet. Sectors with deals have to be sealed, see
+	// SectorStartSealing
+	SectorAbortWaitDeals(context.Context, abi.SectorNumber) error //perm:admin
+	// SectorMatchPendingPiecesToOpenSectors matches the deal pieces waiting
+	// for a sector to the sectors accepting deals, and creates a new deal
+	// sector for the pieces which don't fit into them, unless the sealing
+	// config has ConservativePacking set
+	SectorMatchPendingPiecesToOpenSectors(ctx context.Context) error //perm:admin
 	// SectorSetSealDelay sets the time that a newly-created sector
 	// waits for more deals before it starts sealing
 	SectorSetSealDelay(context.Context, time.Duration) error //perm:write
@@ -90,8 +138,12 @@ type StorageMiner interface {
 	// Returns null if message wasn't sent
 	SectorTerminateFlush(ctx context.Context) (*cid.Cid, error) //perm:admin
 	// SectorTerminatePending returns a list of pending sector terminations to be sent in the next batch message
-	SectorTerminatePending(ctx context.Context) ([]abi.SectorID, error)  //perm:admin
-	SectorMarkForUpgrade(ctx context.Context, id abi.SectorNumber) error //perm:admin
+	SectorTerminatePending(ctx context.Context) ([]abi.SectorID, error) //perm:admin
+	// SectorExtend sends a message extending the expiration of a sector to newExpiration, which has
+	// to be within the max lifetime of the sector. Sectors in the deadline being proven, or the next
+	// one, can't be extended until the deadline is proven.
+	SectorExtend(ctx context.Context, num abi.SectorNumber, newExpiration abi.ChainEpoch) (cid.Cid, error) //perm:admin
+	SectorMarkForUpgrade(ctx context.Context, id abi.SectorNumber) error                                   //perm:admin
 	// SectorPreCommitFlush immediately sends a PreCommit message with sectors batched for PreCommit.
 	// Returns null if message wasn't sent
 	SectorPreCommitFlush(ctx context.Context) ([]sealiface.PreCommitBatchRes, error) //perm:admin
@@ -102,11 +154,43 @@ type StorageMiner interface {
 	SectorCommitFlush(ctx context.Context) ([]sealiface.CommitBatchRes, error) //perm:admin
 	// SectorCommitPending returns a list of pending Commit sectors to be sent in the next aggregate message
 	SectorCommitPending(ctx context.Context) ([]abi.SectorID, error) //perm:admin
+	// SectorFinalizeFlush immediately releases all sectors waiting in the
+	// FinalizeReady state to be finalized and moved to long-term storage.
+	// Returns the released sectors.
+	SectorFinalizeFlush(ctx context.Context) ([]abi.SectorID, error) //perm:admin
+	// SectorFinalizePending returns a list of sectors waiting to be finalized
+	// in the next batch
+	SectorFinalizePending(ctx context.Context) ([]abi.SectorID, error) //perm:admin
+	// SectorVerifyCommR checks the sealed replica and cache files of the
+	// sector against its on-chain replica commitment (CommR), returning false
+	// if they don't match, e.g. because of bit-rot. CommR isn't recomputed
+	// over the whole replica, which the proofs library doesn't support; many
+	// sets of random PoSt challenges are proven against it instead, so damage
+	// to unchallenged replica nodes can go unnoticed. Only files in the
+	// storage of the miner node are checked.
+	SectorVerifyCommR(ctx context.Context, sid abi.SectorNumber) (bool, error) //perm:admin
+	// SectorStorageLocations returns the storage paths holding the sealed and
+	// cache files of the sector, and whether they're online, so that paths the
+	// sector can't be proven from are noticed before its proving window
+	SectorStorageLocations(ctx context.Context, sid abi.SectorNumber) ([]stores.StorageLocation, error) //perm:admin
+	// SectorsReconcileReport compares the state of every sector in the
+	// sealing state machine to its state on chain, and returns the sectors
+	// whose states don't match. Sectors waiting for a message to land aren't
+	// checked. The report doesn't change sector states, the periodic
+	// reconciler moves the sectors with a Correction.
+	SectorsReconcileReport(ctx context.Context) (sealiface.ReconcileReport, error) //perm:admin
 
 	// WorkerConnect tells the node to connect to workers RPC
 	WorkerConnect(context.Context, string) error                              //perm:admin retry:true
 	WorkerStats(context.Context) (map[uuid.UUID]storiface.WorkerStats, error) //perm:admin
 	WorkerJobs(context.Context) (map[uuid.UUID][]storiface.WorkerJob, error)  //perm:admin
+	// WorkerResourceUtilization returns the last resource utilization sample
+	// reported by each worker in its heartbeat
+	WorkerResourceUtilization(context.Context) (map[uuid.UUID]storiface.WorkerUtilization, error) //perm:admin
+
+	// StorageTransferStats returns the state of sector moves to long-term
+	// storage, which are limited by the Storage.ParallelMoveLimit config
+	StorageTransferStats(context.Context) (storiface.TransferStats, error) //perm:admin
 
 	//storiface.WorkerReturn
 	ReturnAddPiece(ctx context.Context, callID storiface.CallID, pi abi.PieceInfo, err *storiface.CallError) error                //perm:admin retry:true
@@ -125,6 +209,23 @@ type StorageMiner interface {
 	SealingSchedDiag(ctx context.Context, doSched bool) (interface{}, error) //perm:admin
 	SealingAbort(ctx context.Context, call storiface.CallID) error           //perm:admin
 
+	// SealingQueueDepth returns the number of sealing tasks of each type which
+	// are queued in the scheduler, waiting to be assigned to a worker
+	SealingQueueDepth(ctx context.Context) (map[sealtasks.TaskType]int, error) //perm:admin
+
+	// SealingSnapshot pauses sector state transitions for a moment, and
+	// returns the state of all sectors tracked by the sealing state machine.
+	// The snapshot can be loaded into another miner instance with SealingRestore.
+	SealingSnapshot(ctx context.Context) (SealingSnapshot, error) //perm:admin
+	// SealingRestore loads sectors from a snapshot into the sealing state
+	// machine, and restarts them. The miner must not be tracking any sectors.
+	SealingRestore(ctx context.Context, snap SealingSnapshot) error //perm:admin
+
+	// SealingPipelineLimits returns the effective limits of the sealing
+	// pipeline, read from the current config, along with the current number of
+	// sectors counted against each limit
+	SealingPipelineLimits(ctx context.Context) ([]sealiface.PipelineLimit, error) //perm:read
+
 	//stores.SectorIndex
 	StorageAttach(context.Context, stores.StorageInfo, fsutil.FsStat) error                                                                                             //perm:admin
 	StorageInfo(context.Context, stores.ID) (stores.StorageInfo, error)                                                                                                 //perm:admin
@@ -140,23 +241,65 @@ type StorageMiner interface {
 	StorageLocal(ctx context.Context) (map[stores.ID]string, error)       //perm:admin
 	StorageStat(ctx context.Context, id stores.ID) (fsutil.FsStat, error) //perm:admin
 
-	MarketImportDealData(ctx context.Context, propcid cid.Cid, path string) error                                                                                                        //perm:write
-	MarketListDeals(ctx context.Context) ([]MarketDeal, error)                                                                                                                           //perm:read
-	MarketListRetrievalDeals(ctx context.Context) ([]retrievalmarket.ProviderDealState, error)                                                                                           //perm:read
-	MarketGetDealUpdates(ctx context.Context) (<-chan storagemarket.MinerDeal, error)                                                                                                    //perm:read
-	MarketListIncompleteDeals(ctx context.Context) ([]storagemarket.MinerDeal, error)                                                                                                    //perm:read
+	MarketImportDealData(ctx context.Context, propcid cid.Cid, path string) error              //perm:write
+	MarketListDeals(ctx context.Context) ([]MarketDeal, error)                                 //perm:read
+	MarketListRetrievalDeals(ctx context.Context) ([]retrievalmarket.ProviderDealState, error) //perm:read
+	MarketGetDealUpdates(ctx context.Context) (<-chan storagemarket.MinerDeal, error)          //perm:read
+	MarketListIncompleteDeals(ctx context.Context) ([]storagemarket.MinerDeal, error)          //perm:read
+	// MarketListDealsAwaitingData lists offline deals waiting for their data to
+	// be imported with MarketImportDealData
+	MarketListDealsAwaitingData(ctx context.Context) ([]AwaitingDataDeal, error)                                                                                                         //perm:read
 	MarketSetAsk(ctx context.Context, price types.BigInt, verifiedPrice types.BigInt, duration abi.ChainEpoch, minPieceSize abi.PaddedPieceSize, maxPieceSize abi.PaddedPieceSize) error //perm:admin
 	MarketGetAsk(ctx context.Context) (*storagemarket.SignedStorageAsk, error)                                                                                                           //perm:read
 	MarketSetRetrievalAsk(ctx context.Context, rask *retrievalmarket.Ask) error                                                                                                          //perm:admin
 	MarketGetRetrievalAsk(ctx context.Context) (*retrievalmarket.Ask, error)                                                                                                             //perm:read
 	MarketListDataTransfers(ctx context.Context) ([]DataTransferChannel, error)                                                                                                          //perm:write
 	MarketDataTransferUpdates(ctx context.Context) (<-chan DataTransferChannel, error)                                                                                                   //perm:write
+	// MarketSetRetrievalAskTiers sets the tiers applied on top of the retrieval
+	// ask when pricing retrievals. It fails when retrievals are priced by an
+	// external pricing script, which the tiers don't apply to
+	MarketSetRetrievalAskTiers(ctx context.Context, tiers RetrievalAskTiers) error //perm:admin
+	MarketGetRetrievalAskTiers(ctx context.Context) (RetrievalAskTiers, error)     //perm:read
+	// MarketDealDiagnostics returns the last state changes of storage deals
+	// recorded by the provider, oldest first
+	MarketDealDiagnostics(ctx context.Context) ([]DealDiagnostic, error) //perm:read
+	// MarketDealTransferStatus returns the state of the data transfer for the
+	// storage deal with the given proposal CID
+	MarketDealTransferStatus(ctx context.Context, propCid cid.Cid) (TransferStatus, error) //perm:write
+	// MarketDataTransferChannelDebug returns the full internal state of a data
+	// transfer channel, for debugging stuck transfers
+	MarketDataTransferChannelDebug(ctx context.Context, chid datatransfer.ChannelID) (ChannelDebug, error) //perm:admin
 	// MarketRestartDataTransfer attempts to restart a data transfer with the given transfer ID and other peer
 	MarketRestartDataTransfer(ctx context.Context, transferID datatransfer.TransferID, otherPeer peer.ID, isInitiator bool) error //perm:write
 	// MarketCancelDataTransfer cancels a data transfer with the given transfer ID and other peer
 	MarketCancelDataTransfer(ctx context.Context, transferID datatransfer.TransferID, otherPeer peer.ID, isInitiator bool) error //perm:write
 	MarketPendingDeals(ctx context.Context) (PendingDealInfo, error)                                                             //perm:write
 	MarketPublishPendingDeals(ctx context.Context) error                                                                         //perm:admin
+	// MarketClientLimits returns how many deals were accepted from each client
+	// in its current deal rate limit window
+	MarketClientLimits(ctx context.Context) ([]ClientDealLimit, error) //perm:read
+	// MarketResetClientLimit clears the deal rate limit usage of the client,
+	// so that its deals are accepted again
+	MarketResetClientLimit(ctx context.Context, client address.Address) error //perm:admin
+	// MarketListQuarantinedData lists the staged data of failed deals which is
+	// retained for inspection, see Dealmaking.FailedDealRetention
+	MarketListQuarantinedData(ctx context.Context) ([]QuarantinedData, error) //perm:read
+	// MarketPurgeQuarantinedData removes the retained data of the failed deal
+	// before its retention period expires
+	MarketPurgeQuarantinedData(ctx context.Context, propCid cid.Cid) error //perm:admin
+	// MarketTestDealFilter runs the configured external storage deal filter
+	// command against the proposal, or a sample proposal when nil is passed,
+	// and returns the filter decision along with what the command wrote to
+	// stderr
+	MarketTestDealFilter(ctx context.Context, proposal *market.DealProposal) (*DealFilterResult, error) //perm:admin
+	// MarketRevenue returns the storage payments released to the miner for
+	// its deals since the given epoch, per deal and in total, along with the
+	// payments locked for the rest of the deals
+	MarketRevenue(ctx context.Context, since abi.ChainEpoch) (RevenueReport, error) //perm:read
+	// MarketSimulatePacking returns how the deal pieces would be packed into
+	// the open and new sectors, along with the space wasted on padding,
+	// without assigning any pieces or creating sectors
+	MarketSimulatePacking(ctx context.Context, deals []sealiface.PackingDeal) (sealiface.PackingPlan, error) //perm:read
 
 	DealsImportData(ctx context.Context, dealPropCid cid.Cid, file string) error //perm:admin
 	DealsList(ctx context.Context) ([]MarketDeal, error)                         //perm:admin
@@ -190,12 +333,103 @@ type StorageMiner interface {
 
 	CheckProvable(ctx context.Context, pp abi.RegisteredPoStProof, sectors []storage.SectorRef, expensive bool) (map[abi.SectorNumber]string, error) //perm:admin
 
+	// ComputeWindowPoStDryRun checks the sectors of the deadline like the
+	// WindowPoSt scheduler does ahead of the deadline, and returns the faults
+	// and recoveries it would declare, without declaring them
+	ComputeWindowPoStDryRun(ctx context.Context, deadline uint64) (WindowPoStDryRun, error) //perm:admin
+
 	ComputeProof(ctx context.Context, ssi []builtin.SectorInfo, rand abi.PoStRandomness) ([]builtin.PoStProof, error) //perm:read
 }
 
 var _ storiface.WorkerReturn = *new(StorageMiner)
 var _ stores.SectorIndex = *new(StorageMiner)
 
+type MiningStatus struct {
+	Enabled bool
+	Since   time.Time
+}
+
+// PowerBreakdown splits miner power by the state of the sectors it comes
+// from. Recovering power is a part of faulty power.
+type PowerBreakdown struct {
+	Active     power.Claim
+	Faulty     power.Claim
+	Recovering power.Claim
+}
+
+// DeadlineLoad describes how many sectors are proven in a deadline.
+// TotalSectors also counts terminated sectors which weren't compacted away
+// yet. Preferred is set on the deadline the miner actor will assign the next
+// newly committed sector to at current chain head.
+type DeadlineLoad struct {
+	Index         uint64
+	Partitions    uint64
+	TotalSectors  uint64
+	LiveSectors   uint64
+	FaultySectors uint64
+
+	Preferred bool
+}
+
+// ClientDealLimit is the deal rate limit usage of a client
+type ClientDealLimit struct {
+	Client   address.Address
+	Deals    int
+	MaxDeals int
+	ResetsAt time.Time
+}
+
+// DealFilterResult is the decision of a deal filter command
+type DealFilterResult struct {
+	Accept bool
+	Reason string
+	Stderr string
+}
+
+// DealRevenue is the revenue of the provider from a deal
+type DealRevenue struct {
+	DealID        abi.DealID
+	Client        address.Address
+	PieceCID      cid.Cid
+	StartEpoch    abi.ChainEpoch
+	EndEpoch      abi.ChainEpoch
+	PricePerEpoch abi.TokenAmount
+
+	Active  bool
+	Slashed bool
+
+	// payments released to the provider since the requested epoch
+	Realized abi.TokenAmount
+	// payments still due for the rest of the deal
+	Locked abi.TokenAmount
+}
+
+// RevenueReport is the revenue of a provider from its deals in the market
+// actor state at Epoch
+type RevenueReport struct {
+	Epoch abi.ChainEpoch
+	Since abi.ChainEpoch
+
+	Deals []DealRevenue
+
+	Realized abi.TokenAmount
+	Locked   abi.TokenAmount
+}
+
+// QuarantinedData is the retained staged data of a failed deal
+type QuarantinedData struct {
+	ProposalCid cid.Cid
+	PieceCid    cid.Cid
+	Client      address.Address
+	Message     string
+
+	Files []string
+	Size  int64
+
+	QuarantinedAt time.Time
+	ExpiresAt     time.Time
+}
+
 type SealRes struct {
 	Err   string
 	GoErr error `json:"-"`
@@ -226,6 +460,9 @@ type SectorInfo struct {
 	Retries      uint64
 	ToUpgrade    bool
 
+	// Whether the sector is sealed with synthetic PoRep
+	SyntheticPoRep bool
+
 	LastErr string
 
 	Log []SectorLog
@@ -244,6 +481,13 @@ type SectorInfo struct {
 	Early abi.ChainEpoch
 }
 
+// SealingSnapshot holds the state of all sectors in the sealing state machine
+type SealingSnapshot struct {
+	Taken time.Time
+	// CBOR-encoded sector infos, as stored in the sealing state machine
+	Sectors [][]byte
+}
+
 type SealedRef struct {
 	SectorID abi.SectorNumber
 	Offset   abi.PaddedPieceSize
@@ -291,10 +535,116 @@ type AddressConfig struct {
 	TerminateControl   []address.Address
 	DealPublishControl []address.Address
 
+	// DealCollateralFromPublishControl selects the wallet funding provider
+	// deal collateral from DealPublishControl, instead of the worker
+	DealCollateralFromPublishControl bool
+
 	DisableOwnerFallback  bool
 	DisableWorkerFallback bool
 }
 
+// ParamsStatus is the result of the last scheduled verification of the proof
+// parameter files
+type ParamsStatus struct {
+	// LastVerified is zero until the files are first verified
+	LastVerified time.Time
+	// Checked is the number of files whose checksum was computed
+	Checked int
+	// Corrupted are the files whose checksum didn't match the manifest, and
+	// Redownloaded those of them which were downloaded again
+	Corrupted    []string
+	Redownloaded []string
+	Error        string
+}
+
+// SlashFilterRecord is a block mined by the miner, as recorded by the slash
+// filter
+type SlashFilterRecord struct {
+	Miner  address.Address
+	Height abi.ChainEpoch
+	Block  cid.Cid
+}
+
+// DealDiagnostic is a state change of a storage deal on the provider
+type DealDiagnostic struct {
+	Time        time.Time
+	ProposalCid cid.Cid
+	DealID      abi.DealID
+	Event       string
+	State       string
+	Message     string
+}
+
+// RetrievalAskTiers adjust the retrieval ask of the provider by the retrieved
+// piece
+type RetrievalAskTiers struct {
+	// Bytes of the payload of each piece retrieved for free, the price per
+	// byte applies to the remaining bytes
+	FreeBytes uint64
+	// Added to the unseal price of retrievals of pieces without an unsealed
+	// copy
+	SealedSurcharge abi.TokenAmount
+}
+
+type SectorFaultEventKind string
+
+const (
+	// SectorFaultDeclared is recorded when the sector was declared faulty
+	SectorFaultDeclared SectorFaultEventKind = "FaultDeclared"
+	// SectorFaultSkipped is recorded when the sector was skipped in a submitted
+	// WindowPoSt, which marks it as faulty on chain
+	SectorFaultSkipped SectorFaultEventKind = "Skipped"
+	// SectorRecoveryDeclared is recorded when the sector was declared as
+	// recovered
+	SectorRecoveryDeclared SectorFaultEventKind = "RecoveryDeclared"
+)
+
+// SectorFaultEvent is a single entry in the WindowPoSt fault history of a
+// sector
+type SectorFaultEvent struct {
+	Kind SectorFaultEventKind
+
+	Epoch     abi.ChainEpoch
+	Deadline  uint64
+	Partition uint64
+
+	Message cid.Cid `json:",omitempty"`
+}
+
+// WindowPoStDryRun lists the sectors of a deadline which the WindowPoSt
+// scheduler would declare as faulty or recovered at the given height
+type WindowPoStDryRun struct {
+	Deadline uint64
+	Height   abi.ChainEpoch
+
+	// Live sectors which can't be proven. After the ignition upgrade they
+	// aren't declared, but skipped in the WindowPoSt instead.
+	Faults []PartitionSectors
+	// Faulty sectors which can be proven again
+	Recoveries []PartitionSectors
+}
+
+// PartitionSectors are sectors in a partition of a deadline
+type PartitionSectors struct {
+	Partition uint64
+	Sectors   bitfield.BitField
+}
+
+// AwaitingDataDeal is an offline deal for which the provider is waiting for
+// the deal data to be imported
+type AwaitingDataDeal struct {
+	ProposalCid cid.Cid
+	Client      address.Address
+
+	PieceCid  cid.Cid
+	PieceSize abi.PaddedPieceSize
+
+	StartEpoch abi.ChainEpoch
+	// ImportDeadline is the last epoch at which data can be imported while
+	// leaving the expected seal duration to seal the deal before StartEpoch
+	ImportDeadline abi.ChainEpoch
+}
+
 // PendingDealInfo has info about pending deals and when they are due to be
 // published
 type PendingDealInfo struct {
@@ -177,6 +177,8 @@ func init() {
 	addExample(filestore2.Path(".lotusminer/fstmp123"))
 	si := multistore.StoreID(12)
 	addExample(&si)
+	sel := api.Selector(`{"R":{"l":{"none":{}},":>":{"a":{">":{"@":{}}}}}}`)
+	addExample(&sel)
 	addExample(retrievalmarket.DealID(5))
 	addExample(abi.ActorID(1000))
 	addExample(map[string][]api.SealedRef{
@@ -189,6 +191,7 @@ func init() {
 		},
 	})
 	addExample(api.SectorState(sealing.Proving))
+	addExample(api.SectorFaultSkipped)
 	addExample(stores.ID("76f1988b-ef30-4d7e-b3ec-9a627f4ba5a8"))
 	addExample(storiface.FTUnsealed)
 	addExample(storiface.PathSealing)
@@ -235,6 +238,22 @@ func init() {
 			MemUsedMax: 0,
 			GpuUsed:    false,
 			CpuUse:     0,
+			Utilization: storiface.WorkerUtilization{
+				Sampled:     time.Unix(1605172927, 0).UTC(),
+				CPU:         0.42,
+				GPU:         1,
+				MemUsed:     96 << 30,
+				MemPhysical: 256 << 30,
+			},
+		},
+	})
+	addExample(map[uuid.UUID]storiface.WorkerUtilization{
+		uuid.MustParse("ef8d99a2-6865-4189-8ffa-9fef0f806eee"): {
+			Sampled:     time.Unix(1605172927, 0).UTC(),
+			CPU:         0.42,
+			GPU:         1,
+			MemUsed:     96 << 30,
+			MemPhysical: 256 << 30,
 		},
 	})
 	addExample(storiface.ErrorCode(0))
@@ -253,6 +272,9 @@ func init() {
 		sealtasks.TTPreCommit2: {},
 	})
 	addExample(sealtasks.TTCommit2)
+	addExample(map[sealtasks.TaskType]int{
+		sealtasks.TTCommit2: 3,
+	})
 	addExample(apitypes.OpenRPCDocument{
 		"openrpc": "1.2.6",
 		"info": map[string]interface{}{
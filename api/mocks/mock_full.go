@@ -418,11 +418,26 @@ func (mr *MockFullNodeMockRecorder) ChainTipSetWeight(arg0, arg1 interface{}) *g
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ChainTipSetWeight", reflect.TypeOf((*MockFullNode)(nil).ChainTipSetWeight), arg0, arg1)
 }
 
+// ClientAcceptStartEpochCounterOffer mocks base method.
+func (m *MockFullNode) ClientAcceptStartEpochCounterOffer(arg0 context.Context, arg1 cid.Cid) (*cid.Cid, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClientAcceptStartEpochCounterOffer", arg0, arg1)
+	ret0, _ := ret[0].(*cid.Cid)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ClientAcceptStartEpochCounterOffer indicates an expected call of ClientAcceptStartEpochCounterOffer.
+func (mr *MockFullNodeMockRecorder) ClientAcceptStartEpochCounterOffer(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClientAcceptStartEpochCounterOffer", reflect.TypeOf((*MockFullNode)(nil).ClientAcceptStartEpochCounterOffer), arg0, arg1)
+}
+
 // ClientCalcCommP mocks base method.
-func (m *MockFullNode) ClientCalcCommP(arg0 context.Context, arg1 string) (*api.CommPRet, error) {
+func (m *MockFullNode) ClientCalcCommP(arg0 context.Context, arg1 string) (api.DataCIDSize, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "ClientCalcCommP", arg0, arg1)
-	ret0, _ := ret[0].(*api.CommPRet)
+	ret0, _ := ret[0].(api.DataCIDSize)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
@@ -491,6 +506,21 @@ func (mr *MockFullNodeMockRecorder) ClientDealPieceCID(arg0, arg1 interface{}) *
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClientDealPieceCID", reflect.TypeOf((*MockFullNode)(nil).ClientDealPieceCID), arg0, arg1)
 }
 
+// ClientDealSealETA mocks base method.
+func (m *MockFullNode) ClientDealSealETA(arg0 context.Context, arg1 abi.DealID) (abi.ChainEpoch, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClientDealSealETA", arg0, arg1)
+	ret0, _ := ret[0].(abi.ChainEpoch)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ClientDealSealETA indicates an expected call of ClientDealSealETA.
+func (mr *MockFullNodeMockRecorder) ClientDealSealETA(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClientDealSealETA", reflect.TypeOf((*MockFullNode)(nil).ClientDealSealETA), arg0, arg1)
+}
+
 // ClientDealSize mocks base method.
 func (m *MockFullNode) ClientDealSize(arg0 context.Context, arg1 cid.Cid) (api.DataSize, error) {
 	m.ctrl.T.Helper()
@@ -521,6 +551,21 @@ func (mr *MockFullNodeMockRecorder) ClientFindData(arg0, arg1, arg2 interface{})
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClientFindData", reflect.TypeOf((*MockFullNode)(nil).ClientFindData), arg0, arg1, arg2)
 }
 
+// ClientFindDataAsync mocks base method.
+func (m *MockFullNode) ClientFindDataAsync(arg0 context.Context, arg1 cid.Cid, arg2 *cid.Cid) (<-chan api.QueryOffer, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClientFindDataAsync", arg0, arg1, arg2)
+	ret0, _ := ret[0].(<-chan api.QueryOffer)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ClientFindDataAsync indicates an expected call of ClientFindDataAsync.
+func (mr *MockFullNodeMockRecorder) ClientFindDataAsync(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClientFindDataAsync", reflect.TypeOf((*MockFullNode)(nil).ClientFindDataAsync), arg0, arg1, arg2)
+}
+
 // ClientGenCar mocks base method.
 func (m *MockFullNode) ClientGenCar(arg0 context.Context, arg1 api.FileRef, arg2 string) error {
 	m.ctrl.T.Helper()
@@ -786,6 +831,34 @@ func (mr *MockFullNodeMockRecorder) ClientRetrieveWithEvents(arg0, arg1, arg2 in
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClientRetrieveWithEvents", reflect.TypeOf((*MockFullNode)(nil).ClientRetrieveWithEvents), arg0, arg1, arg2)
 }
 
+// ClientSaveDealTemplate mocks base method.
+func (m *MockFullNode) ClientSaveDealTemplate(arg0 context.Context, arg1 string, arg2 api.DealTemplate) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClientSaveDealTemplate", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ClientSaveDealTemplate indicates an expected call of ClientSaveDealTemplate.
+func (mr *MockFullNodeMockRecorder) ClientSaveDealTemplate(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClientSaveDealTemplate", reflect.TypeOf((*MockFullNode)(nil).ClientSaveDealTemplate), arg0, arg1, arg2)
+}
+
+// ClientSetDealRetrievalTerms mocks base method.
+func (m *MockFullNode) ClientSetDealRetrievalTerms(arg0 context.Context, arg1 cid.Cid, arg2 big.Int, arg3 big.Int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClientSetDealRetrievalTerms", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ClientSetDealRetrievalTerms indicates an expected call of ClientSetDealRetrievalTerms.
+func (mr *MockFullNodeMockRecorder) ClientSetDealRetrievalTerms(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClientSetDealRetrievalTerms", reflect.TypeOf((*MockFullNode)(nil).ClientSetDealRetrievalTerms), arg0, arg1, arg2, arg3)
+}
+
 // ClientStartDeal mocks base method.
 func (m *MockFullNode) ClientStartDeal(arg0 context.Context, arg1 *api.StartDealParams) (*cid.Cid, error) {
 	m.ctrl.T.Helper()
@@ -801,6 +874,21 @@ func (mr *MockFullNodeMockRecorder) ClientStartDeal(arg0, arg1 interface{}) *gom
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClientStartDeal", reflect.TypeOf((*MockFullNode)(nil).ClientStartDeal), arg0, arg1)
 }
 
+// ClientStartDealFromTemplate mocks base method.
+func (m *MockFullNode) ClientStartDealFromTemplate(arg0 context.Context, arg1 string, arg2 cid.Cid) (*cid.Cid, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClientStartDealFromTemplate", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*cid.Cid)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ClientStartDealFromTemplate indicates an expected call of ClientStartDealFromTemplate.
+func (mr *MockFullNodeMockRecorder) ClientStartDealFromTemplate(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClientStartDealFromTemplate", reflect.TypeOf((*MockFullNode)(nil).ClientStartDealFromTemplate), arg0, arg1, arg2)
+}
+
 // ClientStatelessDeal mocks base method.
 func (m *MockFullNode) ClientStatelessDeal(arg0 context.Context, arg1 *api.StartDealParams) (*cid.Cid, error) {
 	m.ctrl.T.Helper()
@@ -157,7 +157,9 @@ type FullNodeStruct struct {
 
 		ChainTipSetWeight func(p0 context.Context, p1 types.TipSetKey) (types.BigInt, error) `perm:"read"`
 
-		ClientCalcCommP func(p0 context.Context, p1 string) (*CommPRet, error) `perm:"write"`
+		ClientAcceptStartEpochCounterOffer func(p0 context.Context, p1 cid.Cid) (*cid.Cid, error) `perm:"admin"`
+
+		ClientCalcCommP func(p0 context.Context, p1 string) (DataCIDSize, error) `perm:"write"`
 
 		ClientCancelDataTransfer func(p0 context.Context, p1 datatransfer.TransferID, p2 peer.ID, p3 bool) error `perm:"write"`
 
@@ -167,10 +169,14 @@ type FullNodeStruct struct {
 
 		ClientDealPieceCID func(p0 context.Context, p1 cid.Cid) (DataCIDSize, error) `perm:"read"`
 
+		ClientDealSealETA func(p0 context.Context, p1 abi.DealID) (abi.ChainEpoch, error) `perm:"read"`
+
 		ClientDealSize func(p0 context.Context, p1 cid.Cid) (DataSize, error) `perm:"read"`
 
 		ClientFindData func(p0 context.Context, p1 cid.Cid, p2 *cid.Cid) ([]QueryOffer, error) `perm:"read"`
 
+		ClientFindDataAsync func(p0 context.Context, p1 cid.Cid, p2 *cid.Cid) (<-chan QueryOffer, error) `perm:"read"`
+
 		ClientGenCar func(p0 context.Context, p1 FileRef, p2 string) error `perm:"write"`
 
 		ClientGetDealInfo func(p0 context.Context, p1 cid.Cid) (*DealInfo, error) `perm:"read"`
@@ -207,8 +213,14 @@ type FullNodeStruct struct {
 
 		ClientRetrieveWithEvents func(p0 context.Context, p1 RetrievalOrder, p2 *FileRef) (<-chan marketevents.RetrievalEvent, error) `perm:"admin"`
 
+		ClientSaveDealTemplate func(p0 context.Context, p1 string, p2 DealTemplate) error `perm:"admin"`
+
+		ClientSetDealRetrievalTerms func(p0 context.Context, p1 cid.Cid, p2 abi.TokenAmount, p3 abi.TokenAmount) error `perm:"sign"`
+
 		ClientStartDeal func(p0 context.Context, p1 *StartDealParams) (*cid.Cid, error) `perm:"admin"`
 
+		ClientStartDealFromTemplate func(p0 context.Context, p1 string, p2 cid.Cid) (*cid.Cid, error) `perm:"admin"`
+
 		ClientStatelessDeal func(p0 context.Context, p1 *StartDealParams) (*cid.Cid, error) `perm:"write"`
 
 		CreateBackup func(p0 context.Context, p1 string) error `perm:"admin"`
@@ -563,6 +575,8 @@ type StorageMinerStruct struct {
 
 		ComputeProof func(p0 context.Context, p1 []builtin.SectorInfo, p2 abi.PoStRandomness) ([]builtin.PoStProof, error) `perm:"read"`
 
+		ComputeWindowPoStDryRun func(p0 context.Context, p1 uint64) (WindowPoStDryRun, error) `perm:"admin"`
+
 		CreateBackup func(p0 context.Context, p1 string) error `perm:"admin"`
 
 		DealsConsiderOfflineRetrievalDeals func(p0 context.Context) (bool, error) `perm:"admin"`
@@ -599,36 +613,72 @@ type StorageMinerStruct struct {
 
 		MarketCancelDataTransfer func(p0 context.Context, p1 datatransfer.TransferID, p2 peer.ID, p3 bool) error `perm:"write"`
 
+		MarketClientLimits func(p0 context.Context) ([]ClientDealLimit, error) `perm:"read"`
+
+		MarketDataTransferChannelDebug func(p0 context.Context, p1 datatransfer.ChannelID) (ChannelDebug, error) `perm:"admin"`
+
 		MarketDataTransferUpdates func(p0 context.Context) (<-chan DataTransferChannel, error) `perm:"write"`
 
+		MarketDealDiagnostics func(p0 context.Context) ([]DealDiagnostic, error) `perm:"read"`
+
+		MarketDealTransferStatus func(p0 context.Context, p1 cid.Cid) (TransferStatus, error) `perm:"write"`
+
 		MarketGetAsk func(p0 context.Context) (*storagemarket.SignedStorageAsk, error) `perm:"read"`
 
 		MarketGetDealUpdates func(p0 context.Context) (<-chan storagemarket.MinerDeal, error) `perm:"read"`
 
 		MarketGetRetrievalAsk func(p0 context.Context) (*retrievalmarket.Ask, error) `perm:"read"`
 
+		MarketGetRetrievalAskTiers func(p0 context.Context) (RetrievalAskTiers, error) `perm:"read"`
+
 		MarketImportDealData func(p0 context.Context, p1 cid.Cid, p2 string) error `perm:"write"`
 
 		MarketListDataTransfers func(p0 context.Context) ([]DataTransferChannel, error) `perm:"write"`
 
 		MarketListDeals func(p0 context.Context) ([]MarketDeal, error) `perm:"read"`
 
+		MarketListDealsAwaitingData func(p0 context.Context) ([]AwaitingDataDeal, error) `perm:"read"`
+
 		MarketListIncompleteDeals func(p0 context.Context) ([]storagemarket.MinerDeal, error) `perm:"read"`
 
+		MarketListQuarantinedData func(p0 context.Context) ([]QuarantinedData, error) `perm:"read"`
+
 		MarketListRetrievalDeals func(p0 context.Context) ([]retrievalmarket.ProviderDealState, error) `perm:"read"`
 
 		MarketPendingDeals func(p0 context.Context) (PendingDealInfo, error) `perm:"write"`
 
 		MarketPublishPendingDeals func(p0 context.Context) error `perm:"admin"`
 
+		MarketPurgeQuarantinedData func(p0 context.Context, p1 cid.Cid) error `perm:"admin"`
+
+		MarketResetClientLimit func(p0 context.Context, p1 address.Address) error `perm:"admin"`
+
 		MarketRestartDataTransfer func(p0 context.Context, p1 datatransfer.TransferID, p2 peer.ID, p3 bool) error `perm:"write"`
 
+		MarketRevenue func(p0 context.Context, p1 abi.ChainEpoch) (RevenueReport, error) `perm:"read"`
+
 		MarketSetAsk func(p0 context.Context, p1 types.BigInt, p2 types.BigInt, p3 abi.ChainEpoch, p4 abi.PaddedPieceSize, p5 abi.PaddedPieceSize) error `perm:"admin"`
 
 		MarketSetRetrievalAsk func(p0 context.Context, p1 *retrievalmarket.Ask) error `perm:"admin"`
 
+		MarketSetRetrievalAskTiers func(p0 context.Context, p1 RetrievalAskTiers) error `perm:"admin"`
+
+		MarketSimulatePacking func(p0 context.Context, p1 []sealiface.PackingDeal) (sealiface.PackingPlan, error) `perm:"read"`
+
+		MarketTestDealFilter func(p0 context.Context, p1 *market.DealProposal) (*DealFilterResult, error) `perm:"admin"`
+
+		MinerDeadlineLoad func(p0 context.Context) ([]DeadlineLoad, error) `perm:"read"`
+
+		MinerPowerBreakdown func(p0 context.Context) (PowerBreakdown, error) `perm:"read"`
+
 		MiningBase func(p0 context.Context) (*types.TipSet, error) `perm:"read"`
 
+		MiningSetEnabled func(p0 context.Context, p1 bool) error `perm:"admin"`
+
+		MiningStatus func(p0 context.Context) (MiningStatus, error) `perm:"read"`
+
+		ParamsStatus func(p0 context.Context) (ParamsStatus, error) `perm:"read"`
+
 		PiecesGetCIDInfo func(p0 context.Context, p1 cid.Cid) (*piecestore.CIDInfo, error) `perm:"read"`
 
 		PiecesGetPieceInfo func(p0 context.Context, p1 cid.Cid) (*piecestore.PieceInfo, error) `perm:"read"`
@@ -663,18 +713,38 @@ type StorageMinerStruct struct {
 
 		SealingAbort func(p0 context.Context, p1 storiface.CallID) error `perm:"admin"`
 
+		SealingPipelineLimits func(p0 context.Context) ([]sealiface.PipelineLimit, error) `perm:"read"`
+
+		SealingQueueDepth func(p0 context.Context) (map[sealtasks.TaskType]int, error) `perm:"admin"`
+
+		SealingRestore func(p0 context.Context, p1 SealingSnapshot) error `perm:"admin"`
+
 		SealingSchedDiag func(p0 context.Context, p1 bool) (interface{}, error) `perm:"admin"`
 
+		SealingSnapshot func(p0 context.Context) (SealingSnapshot, error) `perm:"admin"`
+
+		SectorAbortWaitDeals func(p0 context.Context, p1 abi.SectorNumber) error `perm:"admin"`
+
 		SectorCommitFlush func(p0 context.Context) ([]sealiface.CommitBatchRes, error) `perm:"admin"`
 
 		SectorCommitPending func(p0 context.Context) ([]abi.SectorID, error) `perm:"admin"`
 
+		SectorExtend func(p0 context.Context, p1 abi.SectorNumber, p2 abi.ChainEpoch) (cid.Cid, error) `perm:"admin"`
+
+		SectorFaultHistory func(p0 context.Context, p1 abi.SectorNumber) ([]SectorFaultEvent, error) `perm:"read"`
+
+		SectorFinalizeFlush func(p0 context.Context) ([]abi.SectorID, error) `perm:"admin"`
+
+		SectorFinalizePending func(p0 context.Context) ([]abi.SectorID, error) `perm:"admin"`
+
 		SectorGetExpectedSealDuration func(p0 context.Context) (time.Duration, error) `perm:"read"`
 
 		SectorGetSealDelay func(p0 context.Context) (time.Duration, error) `perm:"read"`
 
 		SectorMarkForUpgrade func(p0 context.Context, p1 abi.SectorNumber) error `perm:"admin"`
 
+		SectorMatchPendingPiecesToOpenSectors func(p0 context.Context) error `perm:"admin"`
+
 		SectorPreCommitFlush func(p0 context.Context) ([]sealiface.PreCommitBatchRes, error) `perm:"admin"`
 
 		SectorPreCommitPending func(p0 context.Context) ([]abi.SectorID, error) `perm:"admin"`
@@ -687,16 +757,24 @@ type StorageMinerStruct struct {
 
 		SectorStartSealing func(p0 context.Context, p1 abi.SectorNumber) error `perm:"write"`
 
+		SectorStorageLocations func(p0 context.Context, p1 abi.SectorNumber) ([]stores.StorageLocation, error) `perm:"admin"`
+
 		SectorTerminate func(p0 context.Context, p1 abi.SectorNumber) error `perm:"admin"`
 
 		SectorTerminateFlush func(p0 context.Context) (*cid.Cid, error) `perm:"admin"`
 
 		SectorTerminatePending func(p0 context.Context) ([]abi.SectorID, error) `perm:"admin"`
 
+		SectorVerifyCommR func(p0 context.Context, p1 abi.SectorNumber) (bool, error) `perm:"admin"`
+
 		SectorsList func(p0 context.Context) ([]abi.SectorNumber, error) `perm:"read"`
 
 		SectorsListInStates func(p0 context.Context, p1 []SectorState) ([]abi.SectorNumber, error) `perm:"read"`
 
+		SectorsOpen func(p0 context.Context) ([]sealiface.OpenSector, error) `perm:"read"`
+
+		SectorsReconcileReport func(p0 context.Context) (sealiface.ReconcileReport, error) `perm:"admin"`
+
 		SectorsRefs func(p0 context.Context) (map[string][]SealedRef, error) `perm:"read"`
 
 		SectorsStatus func(p0 context.Context, p1 abi.SectorNumber, p2 bool) (SectorInfo, error) `perm:"read"`
@@ -705,6 +783,10 @@ type StorageMinerStruct struct {
 
 		SectorsUpdate func(p0 context.Context, p1 abi.SectorNumber, p2 SectorState) error `perm:"admin"`
 
+		SlashFilterHistory func(p0 context.Context, p1 abi.ChainEpoch) ([]SlashFilterRecord, error) `perm:"admin"`
+
+		SlashFilterPrune func(p0 context.Context, p1 abi.ChainEpoch, p2 bool) (int, error) `perm:"admin"`
+
 		StorageAddLocal func(p0 context.Context, p1 string) error `perm:"admin"`
 
 		StorageAttach func(p0 context.Context, p1 stores.StorageInfo, p2 fsutil.FsStat) error `perm:"admin"`
@@ -729,12 +811,16 @@ type StorageMinerStruct struct {
 
 		StorageStat func(p0 context.Context, p1 stores.ID) (fsutil.FsStat, error) `perm:"admin"`
 
+		StorageTransferStats func(p0 context.Context) (storiface.TransferStats, error) `perm:"admin"`
+
 		StorageTryLock func(p0 context.Context, p1 abi.SectorID, p2 storiface.SectorFileType, p3 storiface.SectorFileType) (bool, error) `perm:"admin"`
 
 		WorkerConnect func(p0 context.Context, p1 string) error `perm:"admin"`
 
 		WorkerJobs func(p0 context.Context) (map[uuid.UUID][]storiface.WorkerJob, error) `perm:"admin"`
 
+		WorkerResourceUtilization func(p0 context.Context) (map[uuid.UUID]storiface.WorkerUtilization, error) `perm:"admin"`
+
 		WorkerStats func(p0 context.Context) (map[uuid.UUID]storiface.WorkerStats, error) `perm:"admin"`
 	}
 }
@@ -768,6 +854,8 @@ type WorkerStruct struct {
 	Internal struct {
 		AddPiece func(p0 context.Context, p1 storage.SectorRef, p2 []abi.UnpaddedPieceSize, p3 abi.UnpaddedPieceSize, p4 storage.Data) (storiface.CallID, error) `perm:"admin"`
 
+		CachedResult func(p0 context.Context, p1 string) ([]byte, error) `perm:"admin"`
+
 		Enabled func(p0 context.Context) (bool, error) `perm:"admin"`
 
 		Fetch func(p0 context.Context, p1 storage.SectorRef, p2 storiface.SectorFileType, p3 storiface.PathType, p4 storiface.AcquireMode) (storiface.CallID, error) `perm:"admin"`
@@ -790,7 +878,7 @@ type WorkerStruct struct {
 
 		SealCommit2 func(p0 context.Context, p1 storage.SectorRef, p2 storage.Commit1Out) (storiface.CallID, error) `perm:"admin"`
 
-		SealPreCommit1 func(p0 context.Context, p1 storage.SectorRef, p2 abi.SealRandomness, p3 []abi.PieceInfo) (storiface.CallID, error) `perm:"admin"`
+		SealPreCommit1 func(p0 context.Context, p1 storage.SectorRef, p2 abi.SealRandomness, p3 []abi.PieceInfo, p4 storiface.PreCommit1Options) (storiface.CallID, error) `perm:"admin"`
 
 		SealPreCommit2 func(p0 context.Context, p1 storage.SectorRef, p2 storage.PreCommit1Out) (storiface.CallID, error) `perm:"admin"`
 
@@ -808,6 +896,8 @@ type WorkerStruct struct {
 
 		UnsealPiece func(p0 context.Context, p1 storage.SectorRef, p2 storiface.UnpaddedByteIndex, p3 abi.UnpaddedPieceSize, p4 abi.SealRandomness, p5 cid.Cid) (storiface.CallID, error) `perm:"admin"`
 
+		Utilization func(p0 context.Context) (storiface.WorkerUtilization, error) `perm:"admin"`
+
 		Version func(p0 context.Context) (Version, error) `perm:"admin"`
 
 		WaitQuiet func(p0 context.Context) error `perm:"admin"`
@@ -1217,14 +1307,22 @@ func (s *FullNodeStub) ChainTipSetWeight(p0 context.Context, p1 types.TipSetKey)
 	return *new(types.BigInt), xerrors.New("method not supported")
 }
 
-func (s *FullNodeStruct) ClientCalcCommP(p0 context.Context, p1 string) (*CommPRet, error) {
-	return s.Internal.ClientCalcCommP(p0, p1)
+func (s *FullNodeStruct) ClientAcceptStartEpochCounterOffer(p0 context.Context, p1 cid.Cid) (*cid.Cid, error) {
+	return s.Internal.ClientAcceptStartEpochCounterOffer(p0, p1)
 }
 
-func (s *FullNodeStub) ClientCalcCommP(p0 context.Context, p1 string) (*CommPRet, error) {
+func (s *FullNodeStub) ClientAcceptStartEpochCounterOffer(p0 context.Context, p1 cid.Cid) (*cid.Cid, error) {
 	return nil, xerrors.New("method not supported")
 }
 
+func (s *FullNodeStruct) ClientCalcCommP(p0 context.Context, p1 string) (DataCIDSize, error) {
+	return s.Internal.ClientCalcCommP(p0, p1)
+}
+
+func (s *FullNodeStub) ClientCalcCommP(p0 context.Context, p1 string) (DataCIDSize, error) {
+	return *new(DataCIDSize), xerrors.New("method not supported")
+}
+
 func (s *FullNodeStruct) ClientCancelDataTransfer(p0 context.Context, p1 datatransfer.TransferID, p2 peer.ID, p3 bool) error {
 	return s.Internal.ClientCancelDataTransfer(p0, p1, p2, p3)
 }
@@ -1257,6 +1355,14 @@ func (s *FullNodeStub) ClientDealPieceCID(p0 context.Context, p1 cid.Cid) (DataC
 	return *new(DataCIDSize), xerrors.New("method not supported")
 }
 
+func (s *FullNodeStruct) ClientDealSealETA(p0 context.Context, p1 abi.DealID) (abi.ChainEpoch, error) {
+	return s.Internal.ClientDealSealETA(p0, p1)
+}
+
+func (s *FullNodeStub) ClientDealSealETA(p0 context.Context, p1 abi.DealID) (abi.ChainEpoch, error) {
+	return *new(abi.ChainEpoch), xerrors.New("method not supported")
+}
+
 func (s *FullNodeStruct) ClientDealSize(p0 context.Context, p1 cid.Cid) (DataSize, error) {
 	return s.Internal.ClientDealSize(p0, p1)
 }
@@ -1273,6 +1379,14 @@ func (s *FullNodeStub) ClientFindData(p0 context.Context, p1 cid.Cid, p2 *cid.Ci
 	return *new([]QueryOffer), xerrors.New("method not supported")
 }
 
+func (s *FullNodeStruct) ClientFindDataAsync(p0 context.Context, p1 cid.Cid, p2 *cid.Cid) (<-chan QueryOffer, error) {
+	return s.Internal.ClientFindDataAsync(p0, p1, p2)
+}
+
+func (s *FullNodeStub) ClientFindDataAsync(p0 context.Context, p1 cid.Cid, p2 *cid.Cid) (<-chan QueryOffer, error) {
+	return nil, xerrors.New("method not supported")
+}
+
 func (s *FullNodeStruct) ClientGenCar(p0 context.Context, p1 FileRef, p2 string) error {
 	return s.Internal.ClientGenCar(p0, p1, p2)
 }
@@ -1417,6 +1531,22 @@ func (s *FullNodeStub) ClientRetrieveWithEvents(p0 context.Context, p1 Retrieval
 	return nil, xerrors.New("method not supported")
 }
 
+func (s *FullNodeStruct) ClientSaveDealTemplate(p0 context.Context, p1 string, p2 DealTemplate) error {
+	return s.Internal.ClientSaveDealTemplate(p0, p1, p2)
+}
+
+func (s *FullNodeStub) ClientSaveDealTemplate(p0 context.Context, p1 string, p2 DealTemplate) error {
+	return xerrors.New("method not supported")
+}
+
+func (s *FullNodeStruct) ClientSetDealRetrievalTerms(p0 context.Context, p1 cid.Cid, p2 abi.TokenAmount, p3 abi.TokenAmount) error {
+	return s.Internal.ClientSetDealRetrievalTerms(p0, p1, p2, p3)
+}
+
+func (s *FullNodeStub) ClientSetDealRetrievalTerms(p0 context.Context, p1 cid.Cid, p2 abi.TokenAmount, p3 abi.TokenAmount) error {
+	return xerrors.New("method not supported")
+}
+
 func (s *FullNodeStruct) ClientStartDeal(p0 context.Context, p1 *StartDealParams) (*cid.Cid, error) {
 	return s.Internal.ClientStartDeal(p0, p1)
 }
@@ -1425,6 +1555,14 @@ func (s *FullNodeStub) ClientStartDeal(p0 context.Context, p1 *StartDealParams)
 	return nil, xerrors.New("method not supported")
 }
 
+func (s *FullNodeStruct) ClientStartDealFromTemplate(p0 context.Context, p1 string, p2 cid.Cid) (*cid.Cid, error) {
+	return s.Internal.ClientStartDealFromTemplate(p0, p1, p2)
+}
+
+func (s *FullNodeStub) ClientStartDealFromTemplate(p0 context.Context, p1 string, p2 cid.Cid) (*cid.Cid, error) {
+	return nil, xerrors.New("method not supported")
+}
+
 func (s *FullNodeStruct) ClientStatelessDeal(p0 context.Context, p1 *StartDealParams) (*cid.Cid, error) {
 	return s.Internal.ClientStatelessDeal(p0, p1)
 }
@@ -2745,6 +2883,14 @@ func (s *StorageMinerStub) ComputeProof(p0 context.Context, p1 []builtin.SectorI
 	return *new([]builtin.PoStProof), xerrors.New("method not supported")
 }
 
+func (s *StorageMinerStruct) ComputeWindowPoStDryRun(p0 context.Context, p1 uint64) (WindowPoStDryRun, error) {
+	return s.Internal.ComputeWindowPoStDryRun(p0, p1)
+}
+
+func (s *StorageMinerStub) ComputeWindowPoStDryRun(p0 context.Context, p1 uint64) (WindowPoStDryRun, error) {
+	return *new(WindowPoStDryRun), xerrors.New("method not supported")
+}
+
 func (s *StorageMinerStruct) CreateBackup(p0 context.Context, p1 string) error {
 	return s.Internal.CreateBackup(p0, p1)
 }
@@ -2889,6 +3035,22 @@ func (s *StorageMinerStub) MarketCancelDataTransfer(p0 context.Context, p1 datat
 	return xerrors.New("method not supported")
 }
 
+func (s *StorageMinerStruct) MarketClientLimits(p0 context.Context) ([]ClientDealLimit, error) {
+	return s.Internal.MarketClientLimits(p0)
+}
+
+func (s *StorageMinerStub) MarketClientLimits(p0 context.Context) ([]ClientDealLimit, error) {
+	return *new([]ClientDealLimit), xerrors.New("method not supported")
+}
+
+func (s *StorageMinerStruct) MarketDataTransferChannelDebug(p0 context.Context, p1 datatransfer.ChannelID) (ChannelDebug, error) {
+	return s.Internal.MarketDataTransferChannelDebug(p0, p1)
+}
+
+func (s *StorageMinerStub) MarketDataTransferChannelDebug(p0 context.Context, p1 datatransfer.ChannelID) (ChannelDebug, error) {
+	return *new(ChannelDebug), xerrors.New("method not supported")
+}
+
 func (s *StorageMinerStruct) MarketDataTransferUpdates(p0 context.Context) (<-chan DataTransferChannel, error) {
 	return s.Internal.MarketDataTransferUpdates(p0)
 }
@@ -2897,6 +3059,22 @@ func (s *StorageMinerStub) MarketDataTransferUpdates(p0 context.Context) (<-chan
 	return nil, xerrors.New("method not supported")
 }
 
+func (s *StorageMinerStruct) MarketDealDiagnostics(p0 context.Context) ([]DealDiagnostic, error) {
+	return s.Internal.MarketDealDiagnostics(p0)
+}
+
+func (s *StorageMinerStub) MarketDealDiagnostics(p0 context.Context) ([]DealDiagnostic, error) {
+	return *new([]DealDiagnostic), xerrors.New("method not supported")
+}
+
+func (s *StorageMinerStruct) MarketDealTransferStatus(p0 context.Context, p1 cid.Cid) (TransferStatus, error) {
+	return s.Internal.MarketDealTransferStatus(p0, p1)
+}
+
+func (s *StorageMinerStub) MarketDealTransferStatus(p0 context.Context, p1 cid.Cid) (TransferStatus, error) {
+	return *new(TransferStatus), xerrors.New("method not supported")
+}
+
 func (s *StorageMinerStruct) MarketGetAsk(p0 context.Context) (*storagemarket.SignedStorageAsk, error) {
 	return s.Internal.MarketGetAsk(p0)
 }
@@ -2921,6 +3099,14 @@ func (s *StorageMinerStub) MarketGetRetrievalAsk(p0 context.Context) (*retrieval
 	return nil, xerrors.New("method not supported")
 }
 
+func (s *StorageMinerStruct) MarketGetRetrievalAskTiers(p0 context.Context) (RetrievalAskTiers, error) {
+	return s.Internal.MarketGetRetrievalAskTiers(p0)
+}
+
+func (s *StorageMinerStub) MarketGetRetrievalAskTiers(p0 context.Context) (RetrievalAskTiers, error) {
+	return *new(RetrievalAskTiers), xerrors.New("method not supported")
+}
+
 func (s *StorageMinerStruct) MarketImportDealData(p0 context.Context, p1 cid.Cid, p2 string) error {
 	return s.Internal.MarketImportDealData(p0, p1, p2)
 }
@@ -2945,6 +3131,14 @@ func (s *StorageMinerStub) MarketListDeals(p0 context.Context) ([]MarketDeal, er
 	return *new([]MarketDeal), xerrors.New("method not supported")
 }
 
+func (s *StorageMinerStruct) MarketListDealsAwaitingData(p0 context.Context) ([]AwaitingDataDeal, error) {
+	return s.Internal.MarketListDealsAwaitingData(p0)
+}
+
+func (s *StorageMinerStub) MarketListDealsAwaitingData(p0 context.Context) ([]AwaitingDataDeal, error) {
+	return *new([]AwaitingDataDeal), xerrors.New("method not supported")
+}
+
 func (s *StorageMinerStruct) MarketListIncompleteDeals(p0 context.Context) ([]storagemarket.MinerDeal, error) {
 	return s.Internal.MarketListIncompleteDeals(p0)
 }
@@ -2953,6 +3147,14 @@ func (s *StorageMinerStub) MarketListIncompleteDeals(p0 context.Context) ([]stor
 	return *new([]storagemarket.MinerDeal), xerrors.New("method not supported")
 }
 
+func (s *StorageMinerStruct) MarketListQuarantinedData(p0 context.Context) ([]QuarantinedData, error) {
+	return s.Internal.MarketListQuarantinedData(p0)
+}
+
+func (s *StorageMinerStub) MarketListQuarantinedData(p0 context.Context) ([]QuarantinedData, error) {
+	return *new([]QuarantinedData), xerrors.New("method not supported")
+}
+
 func (s *StorageMinerStruct) MarketListRetrievalDeals(p0 context.Context) ([]retrievalmarket.ProviderDealState, error) {
 	return s.Internal.MarketListRetrievalDeals(p0)
 }
@@ -2977,6 +3179,22 @@ func (s *StorageMinerStub) MarketPublishPendingDeals(p0 context.Context) error {
 	return xerrors.New("method not supported")
 }
 
+func (s *StorageMinerStruct) MarketPurgeQuarantinedData(p0 context.Context, p1 cid.Cid) error {
+	return s.Internal.MarketPurgeQuarantinedData(p0, p1)
+}
+
+func (s *StorageMinerStub) MarketPurgeQuarantinedData(p0 context.Context, p1 cid.Cid) error {
+	return xerrors.New("method not supported")
+}
+
+func (s *StorageMinerStruct) MarketResetClientLimit(p0 context.Context, p1 address.Address) error {
+	return s.Internal.MarketResetClientLimit(p0, p1)
+}
+
+func (s *StorageMinerStub) MarketResetClientLimit(p0 context.Context, p1 address.Address) error {
+	return xerrors.New("method not supported")
+}
+
 func (s *StorageMinerStruct) MarketRestartDataTransfer(p0 context.Context, p1 datatransfer.TransferID, p2 peer.ID, p3 bool) error {
 	return s.Internal.MarketRestartDataTransfer(p0, p1, p2, p3)
 }
@@ -2985,6 +3203,14 @@ func (s *StorageMinerStub) MarketRestartDataTransfer(p0 context.Context, p1 data
 	return xerrors.New("method not supported")
 }
 
+func (s *StorageMinerStruct) MarketRevenue(p0 context.Context, p1 abi.ChainEpoch) (RevenueReport, error) {
+	return s.Internal.MarketRevenue(p0, p1)
+}
+
+func (s *StorageMinerStub) MarketRevenue(p0 context.Context, p1 abi.ChainEpoch) (RevenueReport, error) {
+	return *new(RevenueReport), xerrors.New("method not supported")
+}
+
 func (s *StorageMinerStruct) MarketSetAsk(p0 context.Context, p1 types.BigInt, p2 types.BigInt, p3 abi.ChainEpoch, p4 abi.PaddedPieceSize, p5 abi.PaddedPieceSize) error {
 	return s.Internal.MarketSetAsk(p0, p1, p2, p3, p4, p5)
 }
@@ -3001,6 +3227,46 @@ func (s *StorageMinerStub) MarketSetRetrievalAsk(p0 context.Context, p1 *retriev
 	return xerrors.New("method not supported")
 }
 
+func (s *StorageMinerStruct) MarketSetRetrievalAskTiers(p0 context.Context, p1 RetrievalAskTiers) error {
+	return s.Internal.MarketSetRetrievalAskTiers(p0, p1)
+}
+
+func (s *StorageMinerStub) MarketSetRetrievalAskTiers(p0 context.Context, p1 RetrievalAskTiers) error {
+	return xerrors.New("method not supported")
+}
+
+func (s *StorageMinerStruct) MarketSimulatePacking(p0 context.Context, p1 []sealiface.PackingDeal) (sealiface.PackingPlan, error) {
+	return s.Internal.MarketSimulatePacking(p0, p1)
+}
+
+func (s *StorageMinerStub) MarketSimulatePacking(p0 context.Context, p1 []sealiface.PackingDeal) (sealiface.PackingPlan, error) {
+	return *new(sealiface.PackingPlan), xerrors.New("method not supported")
+}
+
+func (s *StorageMinerStruct) MarketTestDealFilter(p0 context.Context, p1 *market.DealProposal) (*DealFilterResult, error) {
+	return s.Internal.MarketTestDealFilter(p0, p1)
+}
+
+func (s *StorageMinerStub) MarketTestDealFilter(p0 context.Context, p1 *market.DealProposal) (*DealFilterResult, error) {
+	return nil, xerrors.New("method not supported")
+}
+
+func (s *StorageMinerStruct) MinerDeadlineLoad(p0 context.Context) ([]DeadlineLoad, error) {
+	return s.Internal.MinerDeadlineLoad(p0)
+}
+
+func (s *StorageMinerStub) MinerDeadlineLoad(p0 context.Context) ([]DeadlineLoad, error) {
+	return *new([]DeadlineLoad), xerrors.New("method not supported")
+}
+
+func (s *StorageMinerStruct) MinerPowerBreakdown(p0 context.Context) (PowerBreakdown, error) {
+	return s.Internal.MinerPowerBreakdown(p0)
+}
+
+func (s *StorageMinerStub) MinerPowerBreakdown(p0 context.Context) (PowerBreakdown, error) {
+	return *new(PowerBreakdown), xerrors.New("method not supported")
+}
+
 func (s *StorageMinerStruct) MiningBase(p0 context.Context) (*types.TipSet, error) {
 	return s.Internal.MiningBase(p0)
 }
@@ -3009,6 +3275,30 @@ func (s *StorageMinerStub) MiningBase(p0 context.Context) (*types.TipSet, error)
 	return nil, xerrors.New("method not supported")
 }
 
+func (s *StorageMinerStruct) MiningSetEnabled(p0 context.Context, p1 bool) error {
+	return s.Internal.MiningSetEnabled(p0, p1)
+}
+
+func (s *StorageMinerStub) MiningSetEnabled(p0 context.Context, p1 bool) error {
+	return xerrors.New("method not supported")
+}
+
+func (s *StorageMinerStruct) MiningStatus(p0 context.Context) (MiningStatus, error) {
+	return s.Internal.MiningStatus(p0)
+}
+
+func (s *StorageMinerStub) MiningStatus(p0 context.Context) (MiningStatus, error) {
+	return *new(MiningStatus), xerrors.New("method not supported")
+}
+
+func (s *StorageMinerStruct) ParamsStatus(p0 context.Context) (ParamsStatus, error) {
+	return s.Internal.ParamsStatus(p0)
+}
+
+func (s *StorageMinerStub) ParamsStatus(p0 context.Context) (ParamsStatus, error) {
+	return *new(ParamsStatus), xerrors.New("method not supported")
+}
+
 func (s *StorageMinerStruct) PiecesGetCIDInfo(p0 context.Context, p1 cid.Cid) (*piecestore.CIDInfo, error) {
 	return s.Internal.PiecesGetCIDInfo(p0, p1)
 }
@@ -3145,6 +3435,30 @@ func (s *StorageMinerStub) SealingAbort(p0 context.Context, p1 storiface.CallID)
 	return xerrors.New("method not supported")
 }
 
+func (s *StorageMinerStruct) SealingPipelineLimits(p0 context.Context) ([]sealiface.PipelineLimit, error) {
+	return s.Internal.SealingPipelineLimits(p0)
+}
+
+func (s *StorageMinerStub) SealingPipelineLimits(p0 context.Context) ([]sealiface.PipelineLimit, error) {
+	return *new([]sealiface.PipelineLimit), xerrors.New("method not supported")
+}
+
+func (s *StorageMinerStruct) SealingQueueDepth(p0 context.Context) (map[sealtasks.TaskType]int, error) {
+	return s.Internal.SealingQueueDepth(p0)
+}
+
+func (s *StorageMinerStub) SealingQueueDepth(p0 context.Context) (map[sealtasks.TaskType]int, error) {
+	return *new(map[sealtasks.TaskType]int), xerrors.New("method not supported")
+}
+
+func (s *StorageMinerStruct) SealingRestore(p0 context.Context, p1 SealingSnapshot) error {
+	return s.Internal.SealingRestore(p0, p1)
+}
+
+func (s *StorageMinerStub) SealingRestore(p0 context.Context, p1 SealingSnapshot) error {
+	return xerrors.New("method not supported")
+}
+
 func (s *StorageMinerStruct) SealingSchedDiag(p0 context.Context, p1 bool) (interface{}, error) {
 	return s.Internal.SealingSchedDiag(p0, p1)
 }
@@ -3153,6 +3467,22 @@ func (s *StorageMinerStub) SealingSchedDiag(p0 context.Context, p1 bool) (interf
 	return nil, xerrors.New("method not supported")
 }
 
+func (s *StorageMinerStruct) SealingSnapshot(p0 context.Context) (SealingSnapshot, error) {
+	return s.Internal.SealingSnapshot(p0)
+}
+
+func (s *StorageMinerStub) SealingSnapshot(p0 context.Context) (SealingSnapshot, error) {
+	return *new(SealingSnapshot), xerrors.New("method not supported")
+}
+
+func (s *StorageMinerStruct) SectorAbortWaitDeals(p0 context.Context, p1 abi.SectorNumber) error {
+	return s.Internal.SectorAbortWaitDeals(p0, p1)
+}
+
+func (s *StorageMinerStub) SectorAbortWaitDeals(p0 context.Context, p1 abi.SectorNumber) error {
+	return xerrors.New("method not supported")
+}
+
 func (s *StorageMinerStruct) SectorCommitFlush(p0 context.Context) ([]sealiface.CommitBatchRes, error) {
 	return s.Internal.SectorCommitFlush(p0)
 }
@@ -3169,6 +3499,38 @@ func (s *StorageMinerStub) SectorCommitPending(p0 context.Context) ([]abi.Sector
 	return *new([]abi.SectorID), xerrors.New("method not supported")
 }
 
+func (s *StorageMinerStruct) SectorExtend(p0 context.Context, p1 abi.SectorNumber, p2 abi.ChainEpoch) (cid.Cid, error) {
+	return s.Internal.SectorExtend(p0, p1, p2)
+}
+
+func (s *StorageMinerStub) SectorExtend(p0 context.Context, p1 abi.SectorNumber, p2 abi.ChainEpoch) (cid.Cid, error) {
+	return *new(cid.Cid), xerrors.New("method not supported")
+}
+
+func (s *StorageMinerStruct) SectorFaultHistory(p0 context.Context, p1 abi.SectorNumber) ([]SectorFaultEvent, error) {
+	return s.Internal.SectorFaultHistory(p0, p1)
+}
+
+func (s *StorageMinerStub) SectorFaultHistory(p0 context.Context, p1 abi.SectorNumber) ([]SectorFaultEvent, error) {
+	return *new([]SectorFaultEvent), xerrors.New("method not supported")
+}
+
+func (s *StorageMinerStruct) SectorFinalizeFlush(p0 context.Context) ([]abi.SectorID, error) {
+	return s.Internal.SectorFinalizeFlush(p0)
+}
+
+func (s *StorageMinerStub) SectorFinalizeFlush(p0 context.Context) ([]abi.SectorID, error) {
+	return *new([]abi.SectorID), xerrors.New("method not supported")
+}
+
+func (s *StorageMinerStruct) SectorFinalizePending(p0 context.Context) ([]abi.SectorID, error) {
+	return s.Internal.SectorFinalizePending(p0)
+}
+
+func (s *StorageMinerStub) SectorFinalizePending(p0 context.Context) ([]abi.SectorID, error) {
+	return *new([]abi.SectorID), xerrors.New("method not supported")
+}
+
 func (s *StorageMinerStruct) SectorGetExpectedSealDuration(p0 context.Context) (time.Duration, error) {
 	return s.Internal.SectorGetExpectedSealDuration(p0)
 }
@@ -3193,6 +3555,14 @@ func (s *StorageMinerStub) SectorMarkForUpgrade(p0 context.Context, p1 abi.Secto
 	return xerrors.New("method not supported")
 }
 
+func (s *StorageMinerStruct) SectorMatchPendingPiecesToOpenSectors(p0 context.Context) error {
+	return s.Internal.SectorMatchPendingPiecesToOpenSectors(p0)
+}
+
+func (s *StorageMinerStub) SectorMatchPendingPiecesToOpenSectors(p0 context.Context) error {
+	return xerrors.New("method not supported")
+}
+
 func (s *StorageMinerStruct) SectorPreCommitFlush(p0 context.Context) ([]sealiface.PreCommitBatchRes, error) {
 	return s.Internal.SectorPreCommitFlush(p0)
 }
@@ -3241,6 +3611,14 @@ func (s *StorageMinerStub) SectorStartSealing(p0 context.Context, p1 abi.SectorN
 	return xerrors.New("method not supported")
 }
 
+func (s *StorageMinerStruct) SectorStorageLocations(p0 context.Context, p1 abi.SectorNumber) ([]stores.StorageLocation, error) {
+	return s.Internal.SectorStorageLocations(p0, p1)
+}
+
+func (s *StorageMinerStub) SectorStorageLocations(p0 context.Context, p1 abi.SectorNumber) ([]stores.StorageLocation, error) {
+	return *new([]stores.StorageLocation), xerrors.New("method not supported")
+}
+
 func (s *StorageMinerStruct) SectorTerminate(p0 context.Context, p1 abi.SectorNumber) error {
 	return s.Internal.SectorTerminate(p0, p1)
 }
@@ -3265,6 +3643,14 @@ func (s *StorageMinerStub) SectorTerminatePending(p0 context.Context) ([]abi.Sec
 	return *new([]abi.SectorID), xerrors.New("method not supported")
 }
 
+func (s *StorageMinerStruct) SectorVerifyCommR(p0 context.Context, p1 abi.SectorNumber) (bool, error) {
+	return s.Internal.SectorVerifyCommR(p0, p1)
+}
+
+func (s *StorageMinerStub) SectorVerifyCommR(p0 context.Context, p1 abi.SectorNumber) (bool, error) {
+	return false, xerrors.New("method not supported")
+}
+
 func (s *StorageMinerStruct) SectorsList(p0 context.Context) ([]abi.SectorNumber, error) {
 	return s.Internal.SectorsList(p0)
 }
@@ -3281,6 +3667,22 @@ func (s *StorageMinerStub) SectorsListInStates(p0 context.Context, p1 []SectorSt
 	return *new([]abi.SectorNumber), xerrors.New("method not supported")
 }
 
+func (s *StorageMinerStruct) SectorsOpen(p0 context.Context) ([]sealiface.OpenSector, error) {
+	return s.Internal.SectorsOpen(p0)
+}
+
+func (s *StorageMinerStub) SectorsOpen(p0 context.Context) ([]sealiface.OpenSector, error) {
+	return *new([]sealiface.OpenSector), xerrors.New("method not supported")
+}
+
+func (s *StorageMinerStruct) SectorsReconcileReport(p0 context.Context) (sealiface.ReconcileReport, error) {
+	return s.Internal.SectorsReconcileReport(p0)
+}
+
+func (s *StorageMinerStub) SectorsReconcileReport(p0 context.Context) (sealiface.ReconcileReport, error) {
+	return *new(sealiface.ReconcileReport), xerrors.New("method not supported")
+}
+
 func (s *StorageMinerStruct) SectorsRefs(p0 context.Context) (map[string][]SealedRef, error) {
 	return s.Internal.SectorsRefs(p0)
 }
@@ -3313,6 +3715,22 @@ func (s *StorageMinerStub) SectorsUpdate(p0 context.Context, p1 abi.SectorNumber
 	return xerrors.New("method not supported")
 }
 
+func (s *StorageMinerStruct) SlashFilterHistory(p0 context.Context, p1 abi.ChainEpoch) ([]SlashFilterRecord, error) {
+	return s.Internal.SlashFilterHistory(p0, p1)
+}
+
+func (s *StorageMinerStub) SlashFilterHistory(p0 context.Context, p1 abi.ChainEpoch) ([]SlashFilterRecord, error) {
+	return *new([]SlashFilterRecord), xerrors.New("method not supported")
+}
+
+func (s *StorageMinerStruct) SlashFilterPrune(p0 context.Context, p1 abi.ChainEpoch, p2 bool) (int, error) {
+	return s.Internal.SlashFilterPrune(p0, p1, p2)
+}
+
+func (s *StorageMinerStub) SlashFilterPrune(p0 context.Context, p1 abi.ChainEpoch, p2 bool) (int, error) {
+	return 0, xerrors.New("method not supported")
+}
+
 func (s *StorageMinerStruct) StorageAddLocal(p0 context.Context, p1 string) error {
 	return s.Internal.StorageAddLocal(p0, p1)
 }
@@ -3409,6 +3827,14 @@ func (s *StorageMinerStub) StorageStat(p0 context.Context, p1 stores.ID) (fsutil
 	return *new(fsutil.FsStat), xerrors.New("method not supported")
 }
 
+func (s *StorageMinerStruct) StorageTransferStats(p0 context.Context) (storiface.TransferStats, error) {
+	return s.Internal.StorageTransferStats(p0)
+}
+
+func (s *StorageMinerStub) StorageTransferStats(p0 context.Context) (storiface.TransferStats, error) {
+	return *new(storiface.TransferStats), xerrors.New("method not supported")
+}
+
 func (s *StorageMinerStruct) StorageTryLock(p0 context.Context, p1 abi.SectorID, p2 storiface.SectorFileType, p3 storiface.SectorFileType) (bool, error) {
 	return s.Internal.StorageTryLock(p0, p1, p2, p3)
 }
@@ -3433,6 +3859,14 @@ func (s *StorageMinerStub) WorkerJobs(p0 context.Context) (map[uuid.UUID][]stori
 	return *new(map[uuid.UUID][]storiface.WorkerJob), xerrors.New("method not supported")
 }
 
+func (s *StorageMinerStruct) WorkerResourceUtilization(p0 context.Context) (map[uuid.UUID]storiface.WorkerUtilization, error) {
+	return s.Internal.WorkerResourceUtilization(p0)
+}
+
+func (s *StorageMinerStub) WorkerResourceUtilization(p0 context.Context) (map[uuid.UUID]storiface.WorkerUtilization, error) {
+	return *new(map[uuid.UUID]storiface.WorkerUtilization), xerrors.New("method not supported")
+}
+
 func (s *StorageMinerStruct) WorkerStats(p0 context.Context) (map[uuid.UUID]storiface.WorkerStats, error) {
 	return s.Internal.WorkerStats(p0)
 }
@@ -3505,6 +3939,14 @@ func (s *WorkerStub) AddPiece(p0 context.Context, p1 storage.SectorRef, p2 []abi
 	return *new(storiface.CallID), xerrors.New("method not supported")
 }
 
+func (s *WorkerStruct) CachedResult(p0 context.Context, p1 string) ([]byte, error) {
+	return s.Internal.CachedResult(p0, p1)
+}
+
+func (s *WorkerStub) CachedResult(p0 context.Context, p1 string) ([]byte, error) {
+	return *new([]byte), xerrors.New("method not supported")
+}
+
 func (s *WorkerStruct) Enabled(p0 context.Context) (bool, error) {
 	return s.Internal.Enabled(p0)
 }
@@ -3593,11 +4035,11 @@ func (s *WorkerStub) SealCommit2(p0 context.Context, p1 storage.SectorRef, p2 st
 	return *new(storiface.CallID), xerrors.New("method not supported")
 }
 
-func (s *WorkerStruct) SealPreCommit1(p0 context.Context, p1 storage.SectorRef, p2 abi.SealRandomness, p3 []abi.PieceInfo) (storiface.CallID, error) {
-	return s.Internal.SealPreCommit1(p0, p1, p2, p3)
+func (s *WorkerStruct) SealPreCommit1(p0 context.Context, p1 storage.SectorRef, p2 abi.SealRandomness, p3 []abi.PieceInfo, p4 storiface.PreCommit1Options) (storiface.CallID, error) {
+	return s.Internal.SealPreCommit1(p0, p1, p2, p3, p4)
 }
 
-func (s *WorkerStub) SealPreCommit1(p0 context.Context, p1 storage.SectorRef, p2 abi.SealRandomness, p3 []abi.PieceInfo) (storiface.CallID, error) {
+func (s *WorkerStub) SealPreCommit1(p0 context.Context, p1 storage.SectorRef, p2 abi.SealRandomness, p3 []abi.PieceInfo, p4 storiface.PreCommit1Options) (storiface.CallID, error) {
 	return *new(storiface.CallID), xerrors.New("method not supported")
 }
 
@@ -3665,6 +4107,14 @@ func (s *WorkerStub) UnsealPiece(p0 context.Context, p1 storage.SectorRef, p2 st
 	return *new(storiface.CallID), xerrors.New("method not supported")
 }
 
+func (s *WorkerStruct) Utilization(p0 context.Context) (storiface.WorkerUtilization, error) {
+	return s.Internal.Utilization(p0)
+}
+
+func (s *WorkerStub) Utilization(p0 context.Context) (storiface.WorkerUtilization, error) {
+	return *new(storiface.WorkerUtilization), xerrors.New("method not supported")
+}
+
 func (s *WorkerStruct) Version(p0 context.Context) (Version, error) {
 	return s.Internal.Version(p0)
 }
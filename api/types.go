@@ -3,6 +3,7 @@ package api
 import (
 	"encoding/json"
 	"fmt"
+	"sort"
 	"time"
 
 	"github.com/filecoin-project/go-fil-markets/retrievalmarket"
@@ -67,6 +68,50 @@ type DataTransferChannel struct {
 	Stages      *datatransfer.ChannelStages
 }
 
+// TransferStatus is the live state of the data transfer for a storage deal
+type TransferStatus struct {
+	ChannelID datatransfer.ChannelID
+	Status    datatransfer.Status
+	Message   string
+
+	// Bytes sent if this node is sending the data, received otherwise
+	Transferred uint64
+	// Bytes read and queued for sending, only set on the sending side
+	Queued uint64
+	// Total size of the data, 0 if not known yet
+	Total uint64
+
+	LastUpdate time.Time
+}
+
+// NewTransferStatus constructs a TransferStatus from a channel state snapshot
+// and a host id
+func NewTransferStatus(hostID peer.ID, channelState datatransfer.ChannelState) TransferStatus {
+	st := TransferStatus{
+		ChannelID: channelState.ChannelID(),
+		Status:    channelState.Status(),
+		Message:   channelState.Message(),
+		Total:     channelState.TotalSize(),
+	}
+
+	if channelState.Sender() == hostID {
+		st.Transferred = channelState.Sent()
+		st.Queued = channelState.Queued()
+	} else {
+		st.Transferred = channelState.Received()
+	}
+
+	if stages := channelState.Stages(); stages != nil {
+		for _, stage := range stages.Stages {
+			if t := stage.UpdatedTime.Time(); t.After(st.LastUpdate) {
+				st.LastUpdate = t
+			}
+		}
+	}
+
+	return st
+}
+
 // NewDataTransferChannel constructs an API DataTransferChannel type from full channel state snapshot and a host id
 func NewDataTransferChannel(hostID peer.ID, channelState datatransfer.ChannelState) DataTransferChannel {
 	channel := DataTransferChannel{
@@ -76,17 +121,7 @@ func NewDataTransferChannel(hostID peer.ID, channelState datatransfer.ChannelSta
 		IsSender:   channelState.Sender() == hostID,
 		Message:    channelState.Message(),
 	}
-	stringer, ok := channelState.Voucher().(fmt.Stringer)
-	if ok {
-		channel.Voucher = stringer.String()
-	} else {
-		voucherJSON, err := json.Marshal(channelState.Voucher())
-		if err != nil {
-			channel.Voucher = fmt.Errorf("Voucher Serialization: %w", err).Error()
-		} else {
-			channel.Voucher = string(voucherJSON)
-		}
-	}
+	channel.Voucher = voucherString(channelState.Voucher())
 	if channel.IsSender {
 		channel.IsInitiator = !channelState.IsPull()
 		channel.Transferred = channelState.Sent()
@@ -99,6 +134,149 @@ func NewDataTransferChannel(hostID peer.ID, channelState datatransfer.ChannelSta
 	return channel
 }
 
+func voucherString(v interface{}) string {
+	if stringer, ok := v.(fmt.Stringer); ok {
+		return stringer.String()
+	}
+
+	voucherJSON, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("Voucher Serialization: %w", err).Error()
+	}
+	return string(voucherJSON)
+}
+
+// channelDebugEvents is how many of the latest channel events ChannelDebug
+// includes
+const channelDebugEvents = 50
+
+// ChannelEvent is a log entry of a data transfer channel stage
+type ChannelEvent struct {
+	Stage   string
+	Time    time.Time
+	Message string
+}
+
+// ChannelDebug is the full internal state of a data transfer channel, for
+// debugging stuck transfers
+type ChannelDebug struct {
+	ChannelID datatransfer.ChannelID
+	Status    datatransfer.Status
+	Message   string
+	BaseCID   cid.Cid
+	IsPull    bool
+	Sender    peer.ID
+	Recipient peer.ID
+
+	TotalSize uint64
+	Sent      uint64
+	Received  uint64
+	// Bytes read and queued for sending, only set on the sending side
+	Queued uint64
+	// Number of blocks received over the channel
+	ReceivedCids int
+
+	Vouchers       []string
+	VoucherResults []string
+
+	// Requests on the channel waiting for the other peer: the transfer request
+	// before it's accepted, a voucher not answered with a result yet, a
+	// voucher result asking for a new voucher, e.g. for a retrieval payment,
+	// and pauses
+	PendingRequests []string
+
+	// The latest events on the channel, oldest first
+	Events []ChannelEvent
+}
+
+// NewChannelDebug constructs a ChannelDebug from a channel state snapshot
+func NewChannelDebug(channelState datatransfer.ChannelState) ChannelDebug {
+	dbg := ChannelDebug{
+		ChannelID:    channelState.ChannelID(),
+		Status:       channelState.Status(),
+		Message:      channelState.Message(),
+		BaseCID:      channelState.BaseCID(),
+		IsPull:       channelState.IsPull(),
+		Sender:       channelState.Sender(),
+		Recipient:    channelState.Recipient(),
+		TotalSize:    channelState.TotalSize(),
+		Sent:         channelState.Sent(),
+		Received:     channelState.Received(),
+		Queued:       channelState.Queued(),
+		ReceivedCids: len(channelState.ReceivedCids()),
+	}
+
+	for _, v := range channelState.Vouchers() {
+		dbg.Vouchers = append(dbg.Vouchers, voucherString(v))
+	}
+	for _, vr := range channelState.VoucherResults() {
+		dbg.VoucherResults = append(dbg.VoucherResults, voucherString(vr))
+	}
+	dbg.PendingRequests = pendingRequests(channelState, dbg.Vouchers, dbg.VoucherResults)
+
+	if stages := channelState.Stages(); stages != nil {
+		for _, stage := range stages.Stages {
+			for _, l := range stage.Logs {
+				dbg.Events = append(dbg.Events, ChannelEvent{
+					Stage:   stage.Name,
+					Time:    l.UpdatedTime.Time(),
+					Message: l.Log,
+				})
+			}
+		}
+	}
+
+	sort.SliceStable(dbg.Events, func(i, j int) bool {
+		return dbg.Events[i].Time.Before(dbg.Events[j].Time)
+	})
+	if len(dbg.Events) > channelDebugEvents {
+		dbg.Events = dbg.Events[len(dbg.Events)-channelDebugEvents:]
+	}
+
+	return dbg
+}
+
+// pendingRequests lists the requests the channel is waiting on the other
+// peer for. Every voucher sent over a channel is answered with a voucher
+// result, and voucher results asking for funds are answered with a voucher.
+func pendingRequests(channelState datatransfer.ChannelState, vouchers, results []string) []string {
+	var out []string
+
+	switch channelState.Status() {
+	case datatransfer.Requested:
+		out = append(out, fmt.Sprintf("transfer request awaiting acceptance by %s", channelState.ChannelID().Responder))
+	case datatransfer.ResponderPaused:
+		out = append(out, fmt.Sprintf("paused, awaiting resume by responder %s", channelState.ChannelID().Responder))
+	case datatransfer.InitiatorPaused:
+		out = append(out, fmt.Sprintf("paused, awaiting resume by initiator %s", channelState.ChannelID().Initiator))
+	case datatransfer.BothPaused:
+		out = append(out, "paused, awaiting resume by both peers")
+	}
+
+	if isTerminal(channelState.Status()) {
+		return out
+	}
+
+	switch {
+	case len(vouchers) > len(results):
+		for _, v := range vouchers[len(results):] {
+			out = append(out, fmt.Sprintf("voucher awaiting result: %s", v))
+		}
+	case len(results) > len(vouchers):
+		out = append(out, fmt.Sprintf("voucher result awaiting voucher: %s", results[len(results)-1]))
+	}
+
+	return out
+}
+
+func isTerminal(st datatransfer.Status) bool {
+	switch st {
+	case datatransfer.Completed, datatransfer.Failed, datatransfer.Cancelled:
+		return true
+	}
+	return false
+}
+
 type NetBlockList struct {
 	Peers     []peer.ID
 	IPAddrs   []string
@@ -0,0 +1,160 @@
+package api
+
+import (
+	"testing"
+	"time"
+
+	datatransfer "github.com/filecoin-project/go-data-transfer"
+	"github.com/ipfs/go-cid"
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/stretchr/testify/require"
+	cbg "github.com/whyrusleeping/cbor-gen"
+)
+
+type testChannelState struct {
+	datatransfer.ChannelState
+
+	chid     datatransfer.ChannelID
+	status   datatransfer.Status
+	sender   peer.ID
+	sent     uint64
+	received uint64
+	queued   uint64
+	total    uint64
+	stages   *datatransfer.ChannelStages
+
+	baseCid      cid.Cid
+	recipient    peer.ID
+	receivedCids []cid.Cid
+	vouchers     []datatransfer.Voucher
+	results      []datatransfer.VoucherResult
+}
+
+type testVoucher string
+
+func (v testVoucher) Type() datatransfer.TypeIdentifier { return "testVoucher" }
+func (v testVoucher) String() string                    { return string(v) }
+
+func (s *testChannelState) ChannelID() datatransfer.ChannelID   { return s.chid }
+func (s *testChannelState) Status() datatransfer.Status         { return s.status }
+func (s *testChannelState) Message() string                     { return "" }
+func (s *testChannelState) Sender() peer.ID                     { return s.sender }
+func (s *testChannelState) Sent() uint64                        { return s.sent }
+func (s *testChannelState) Received() uint64                    { return s.received }
+func (s *testChannelState) Queued() uint64                      { return s.queued }
+func (s *testChannelState) TotalSize() uint64                   { return s.total }
+func (s *testChannelState) Stages() *datatransfer.ChannelStages { return s.stages }
+func (s *testChannelState) BaseCID() cid.Cid                    { return s.baseCid }
+func (s *testChannelState) IsPull() bool                        { return false }
+func (s *testChannelState) Recipient() peer.ID                  { return s.recipient }
+func (s *testChannelState) ReceivedCids() []cid.Cid             { return s.receivedCids }
+func (s *testChannelState) Vouchers() []datatransfer.Voucher    { return s.vouchers }
+func (s *testChannelState) VoucherResults() []datatransfer.VoucherResult {
+	return s.results
+}
+
+func TestNewTransferStatus(t *testing.T) {
+	client := peer.ID("client")
+	provider := peer.ID("provider")
+
+	updated := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	st := &testChannelState{
+		chid:     datatransfer.ChannelID{Initiator: client, Responder: provider, ID: 3},
+		status:   datatransfer.Ongoing,
+		sender:   client,
+		sent:     1 << 20,
+		received: 512 << 10,
+		queued:   768 << 10,
+		total:    4 << 20,
+		stages: &datatransfer.ChannelStages{Stages: []*datatransfer.ChannelStage{
+			{Name: "Requested", CreatedTime: cbg.CborTime(updated.Add(-time.Minute)), UpdatedTime: cbg.CborTime(updated.Add(-time.Minute))},
+			{Name: "Ongoing", CreatedTime: cbg.CborTime(updated.Add(-time.Minute)), UpdatedTime: cbg.CborTime(updated)},
+		}},
+	}
+
+	// the provider is receiving an in-progress transfer
+	ts := NewTransferStatus(provider, st)
+	require.Equal(t, st.chid, ts.ChannelID)
+	require.Equal(t, datatransfer.Ongoing, ts.Status)
+	require.Equal(t, uint64(512<<10), ts.Transferred)
+	require.Zero(t, ts.Queued)
+	require.Equal(t, uint64(4<<20), ts.Total)
+	require.True(t, updated.Equal(ts.LastUpdate))
+
+	// the client is sending
+	ts = NewTransferStatus(client, st)
+	require.Equal(t, uint64(1<<20), ts.Transferred)
+	require.Equal(t, uint64(768<<10), ts.Queued)
+}
+
+func TestNewChannelDebug(t *testing.T) {
+	client := peer.ID("client")
+	provider := peer.ID("provider")
+
+	start := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	logAt := func(d time.Duration, msg string) *datatransfer.Log {
+		return &datatransfer.Log{Log: msg, UpdatedTime: cbg.CborTime(start.Add(d))}
+	}
+
+	root, err := cid.Parse("bafy2bzacea3wsdh6y3a36tb3skempjoxqpuyompjbmfeyf34fi3uy6uue42v4")
+	require.NoError(t, err)
+
+	ongoing := &datatransfer.ChannelStage{Name: "Ongoing"}
+	for i := 0; i < channelDebugEvents-1; i++ {
+		ongoing.Logs = append(ongoing.Logs, logAt(time.Duration(i+2)*time.Second, "block received"))
+	}
+
+	st := &testChannelState{
+		chid:         datatransfer.ChannelID{Initiator: client, Responder: provider, ID: 7},
+		status:       datatransfer.Ongoing,
+		sender:       client,
+		recipient:    provider,
+		sent:         1 << 20,
+		received:     512 << 10,
+		queued:       768 << 10,
+		total:        4 << 20,
+		baseCid:      root,
+		receivedCids: []cid.Cid{root, root},
+		vouchers:     []datatransfer.Voucher{testVoucher("proposal"), testVoucher("payment")},
+		results:      []datatransfer.VoucherResult{testVoucher("accepted")},
+		stages: &datatransfer.ChannelStages{Stages: []*datatransfer.ChannelStage{
+			ongoing,
+			{Name: "Requested", Logs: []*datatransfer.Log{logAt(0, "requested"), logAt(time.Second, "accepted")}},
+		}},
+	}
+
+	dbg := NewChannelDebug(st)
+	require.Equal(t, st.chid, dbg.ChannelID)
+	require.Equal(t, datatransfer.Ongoing, dbg.Status)
+	require.Equal(t, root, dbg.BaseCID)
+	require.Equal(t, client, dbg.Sender)
+	require.Equal(t, provider, dbg.Recipient)
+	require.Equal(t, uint64(1<<20), dbg.Sent)
+	require.Equal(t, uint64(512<<10), dbg.Received)
+	require.Equal(t, uint64(768<<10), dbg.Queued)
+	require.Equal(t, uint64(4<<20), dbg.TotalSize)
+	require.Equal(t, 2, dbg.ReceivedCids)
+
+	// only the latest events are kept, ordered across stages
+	require.Len(t, dbg.Events, channelDebugEvents)
+	require.Equal(t, "Requested", dbg.Events[0].Stage)
+	require.Equal(t, "accepted", dbg.Events[0].Message)
+	require.True(t, start.Add(time.Second).Equal(dbg.Events[0].Time))
+	require.Equal(t, "Ongoing", dbg.Events[1].Stage)
+	require.True(t, start.Add(time.Duration(channelDebugEvents)*time.Second).Equal(dbg.Events[len(dbg.Events)-1].Time))
+
+	// the payment voucher hasn't been answered yet
+	require.Equal(t, []string{"proposal", "payment"}, dbg.Vouchers)
+	require.Equal(t, []string{"voucher awaiting result: payment"}, dbg.PendingRequests)
+
+	// the provider asks for the next payment
+	st.results = append(st.results, testVoucher("accepted payment"), testVoucher("funds needed"))
+	require.Equal(t, []string{"voucher result awaiting voucher: funds needed"}, NewChannelDebug(st).PendingRequests)
+
+	st.status = datatransfer.ResponderPaused
+	require.Contains(t, NewChannelDebug(st).PendingRequests, "paused, awaiting resume by responder "+provider.String())
+
+	// finished channels don't wait on anything
+	st.status = datatransfer.Completed
+	require.Empty(t, NewChannelDebug(st).PendingRequests)
+}
@@ -48,6 +48,18 @@ func (w *WrapperV1Full) StateGetReceipt(ctx context.Context, msg cid.Cid, from t
 	return &ml.Receipt, nil
 }
 
+func (w *WrapperV1Full) ClientCalcCommP(ctx context.Context, inpath string) (*api.CommPRet, error) {
+	ret, err := w.FullNode.ClientCalcCommP(ctx, inpath)
+	if err != nil {
+		return nil, err
+	}
+
+	return &api.CommPRet{
+		Root: ret.PieceCID,
+		Size: ret.PieceSize.Unpadded(),
+	}, nil
+}
+
 func (w *WrapperV1Full) Version(ctx context.Context) (api.APIVersion, error) {
 	ver, err := w.FullNode.Version(ctx)
 	if err != nil {
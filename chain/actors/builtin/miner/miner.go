@@ -204,6 +204,17 @@ type Partition interface {
 	RecoveringSectors() (bitfield.BitField, error)
 	LiveSectors() (bitfield.BitField, error)
 	ActiveSectors() (bitfield.BitField, error)
+
+	// ActivePower is the power of live sectors which are neither faulty nor unproven
+	ActivePower() PowerPair
+	FaultyPower() PowerPair
+	// RecoveringPower is the power of faulty sectors declared as recovering
+	RecoveringPower() PowerPair
+}
+
+type PowerPair struct {
+	Raw abi.StoragePower
+	QA  abi.StoragePower
 }
 
 type SectorOnChainInfo struct {
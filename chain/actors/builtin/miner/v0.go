@@ -491,6 +491,19 @@ func (p *partition0) RecoveringSectors() (bitfield.BitField, error) {
 	return p.Partition.Recoveries, nil
 }
 
+func (p *partition0) ActivePower() PowerPair {
+	active := p.Partition.LivePower.Sub(p.Partition.FaultyPower)
+	return PowerPair{Raw: active.Raw, QA: active.QA}
+}
+
+func (p *partition0) FaultyPower() PowerPair {
+	return PowerPair{Raw: p.Partition.FaultyPower.Raw, QA: p.Partition.FaultyPower.QA}
+}
+
+func (p *partition0) RecoveringPower() PowerPair {
+	return PowerPair{Raw: p.Partition.RecoveringPower.Raw, QA: p.Partition.RecoveringPower.QA}
+}
+
 func fromV0SectorOnChainInfo(v0 miner0.SectorOnChainInfo) SectorOnChainInfo {
 
 	return (SectorOnChainInfo)(v0)
@@ -519,6 +519,19 @@ func (p *partition2) RecoveringSectors() (bitfield.BitField, error) {
 	return p.Partition.Recoveries, nil
 }
 
+func (p *partition2) ActivePower() PowerPair {
+	active := p.Partition.LivePower.Sub(p.Partition.FaultyPower).Sub(p.Partition.UnprovenPower)
+	return PowerPair{Raw: active.Raw, QA: active.QA}
+}
+
+func (p *partition2) FaultyPower() PowerPair {
+	return PowerPair{Raw: p.Partition.FaultyPower.Raw, QA: p.Partition.FaultyPower.QA}
+}
+
+func (p *partition2) RecoveringPower() PowerPair {
+	return PowerPair{Raw: p.Partition.RecoveringPower.Raw, QA: p.Partition.RecoveringPower.QA}
+}
+
 func fromV2SectorOnChainInfo(v2 miner2.SectorOnChainInfo) SectorOnChainInfo {
 
 	return SectorOnChainInfo{
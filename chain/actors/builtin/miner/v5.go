@@ -520,6 +520,19 @@ func (p *partition5) RecoveringSectors() (bitfield.BitField, error) {
 	return p.Partition.Recoveries, nil
 }
 
+func (p *partition5) ActivePower() PowerPair {
+	active := p.Partition.LivePower.Sub(p.Partition.FaultyPower).Sub(p.Partition.UnprovenPower)
+	return PowerPair{Raw: active.Raw, QA: active.QA}
+}
+
+func (p *partition5) FaultyPower() PowerPair {
+	return PowerPair{Raw: p.Partition.FaultyPower.Raw, QA: p.Partition.FaultyPower.QA}
+}
+
+func (p *partition5) RecoveringPower() PowerPair {
+	return PowerPair{Raw: p.Partition.RecoveringPower.Raw, QA: p.Partition.RecoveringPower.QA}
+}
+
 func fromV5SectorOnChainInfo(v5 miner5.SectorOnChainInfo) SectorOnChainInfo {
 
 	return SectorOnChainInfo{
@@ -127,6 +127,36 @@ func GetPreCommitChallengeDelay() abi.ChainEpoch {
 	return miner5.PreCommitChallengeDelay
 }
 
+// None of the network versions known to this version of lotus accept synthetic
+// PoRep sectors, so support has to be enabled explicitly.
+var (
+	syntheticPoRepEnabled bool
+	syntheticPoRepVersion network.Version
+)
+
+// SetSyntheticPoRepVersion sets the first network version which supports
+// synthetic PoRep. This should only be used for testing.
+func SetSyntheticPoRepVersion(nv network.Version) {
+	syntheticPoRepEnabled = true
+	syntheticPoRepVersion = nv
+}
+
+// ResetSyntheticPoRep disables synthetic PoRep support enabled with
+// SetSyntheticPoRepVersion. This should only be used for testing.
+func ResetSyntheticPoRep() {
+	syntheticPoRepEnabled = false
+	syntheticPoRepVersion = 0
+}
+
+// SyntheticPoRepSupported returns whether sectors sealed with synthetic PoRep
+// can be committed in the given network version. It's false for all network
+// versions unless enabled with SetSyntheticPoRepVersion, and the proofs
+// library can't seal synthetic PoRep sectors yet, so the synthetic PoRep
+// sealing path can only run with mock proofs for now.
+func SyntheticPoRepSupported(nwVer network.Version) bool {
+	return syntheticPoRepEnabled && nwVer >= syntheticPoRepVersion
+}
+
 // SetConsensusMinerMinPower sets the minimum power of an individual miner must
 // meet for leader election, across all actor versions. This should only be used
 // for testing.
@@ -15,6 +15,7 @@ import (
 // Mock beacon assumes that filecoin rounds are 1:1 mapped with the beacon rounds
 type mockBeacon struct {
 	interval time.Duration
+	seed     []byte
 }
 
 func NewMockBeacon(interval time.Duration) RandomBeacon {
@@ -23,14 +24,22 @@ func NewMockBeacon(interval time.Duration) RandomBeacon {
 	return mb
 }
 
+// NewSeededMockBeacon creates a mock beacon with entries derived from the
+// seed, mock beacons created with the same seed produce the same entries
+func NewSeededMockBeacon(interval time.Duration, seed []byte) RandomBeacon {
+	mb := &mockBeacon{interval: interval, seed: seed}
+
+	return mb
+}
+
 func (mb *mockBeacon) RoundTime() time.Duration {
 	return mb.interval
 }
 
 func (mb *mockBeacon) entryForIndex(index uint64) types.BeaconEntry {
-	buf := make([]byte, 8)
+	buf := make([]byte, 8, 8+len(mb.seed))
 	binary.BigEndian.PutUint64(buf, index)
-	rval := blake2b.Sum256(buf)
+	rval := blake2b.Sum256(append(buf, mb.seed...))
 	return types.BeaconEntry{
 		Round: index,
 		Data:  rval[:],
@@ -1,11 +1,20 @@
 package gen
 
 import (
+	"context"
 	"testing"
+	"time"
 
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-address"
 	"github.com/filecoin-project/go-state-types/abi"
 
+	"github.com/filecoin-project/lotus/api"
 	"github.com/filecoin-project/lotus/chain/actors/policy"
+	"github.com/filecoin-project/lotus/chain/beacon"
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/lotus/chain/wallet"
 	_ "github.com/filecoin-project/lotus/lib/sigs/bls"
 	_ "github.com/filecoin-project/lotus/lib/sigs/secp"
 )
@@ -55,3 +64,48 @@ func BenchmarkChainGeneration(b *testing.B) {
 		testGeneration(b, b.N, 1000, 1)
 	})
 }
+
+func TestSeededBeaconElection(t *testing.T) {
+	ctx := context.Background()
+
+	w, err := wallet.NewWallet(wallet.NewMemKeyStore())
+	require.NoError(t, err)
+
+	// fixed worker key, so that the VRF output only depends on the beacon
+	pk := make([]byte, 32)
+	pk[0] = 1
+	worker, err := w.WalletImport(ctx, &types.KeyInfo{Type: types.KTBLS, PrivateKey: pk})
+	require.NoError(t, err)
+
+	maddr, err := address.NewIDAddress(1000)
+	require.NoError(t, err)
+
+	mbi := &api.MiningBaseInfo{
+		MinerPower:   types.NewInt(1),
+		NetworkPower: types.NewInt(5),
+		WorkerKey:    worker,
+	}
+
+	elect := func(seed []byte) []int64 {
+		bcn := beacon.NewSeededMockBeacon(time.Second, seed)
+
+		var wins []int64
+		for round := abi.ChainEpoch(1); round <= 50; round++ {
+			resp := <-bcn.Entry(ctx, uint64(round))
+			require.NoError(t, resp.Err)
+
+			ep, err := IsRoundWinner(ctx, nil, round, maddr, resp.Entry, mbi, mca{w: w})
+			require.NoError(t, err)
+
+			var winCount int64
+			if ep != nil {
+				winCount = ep.WinCount
+			}
+			wins = append(wins, winCount)
+		}
+		return wins
+	}
+
+	require.Equal(t, elect([]byte("seed")), elect([]byte("seed")))
+	require.NotEqual(t, elect([]byte("seed")), elect([]byte("other seed")))
+}
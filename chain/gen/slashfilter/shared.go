@@ -0,0 +1,129 @@
+package slashfilter
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	levelds "github.com/ipfs/go-ds-leveldb"
+	fslock "github.com/ipfs/go-fs-lock"
+	ldbopts "github.com/syndtr/goleveldb/leveldb/opt"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-state-types/abi"
+
+	"github.com/filecoin-project/lotus/chain/types"
+)
+
+const (
+	sharedLockFile    = "slashfilter.lock"
+	sharedDatastore   = "datastore"
+	sharedLockRetry   = 10 * time.Millisecond
+	sharedLockTimeout = 30 * time.Second
+)
+
+// SharedFilter is a SlashFilter keeping its state in a directory shared by
+// the block producers of a miner actor, e.g. on a network filesystem. The
+// datastore in the directory is opened for each operation while holding a
+// lock file in the directory, so MinedBlock checks and records blocks
+// atomically across processes. The lock is a POSIX record lock, so the
+// filesystem must support those across hosts, e.g. NFSv4, or NFSv3 with
+// lockd. NFS mounted with nolock only locks on each host, which doesn't
+// protect anything.
+//
+// Block producers only call MinedBlock once per mined block, so opening the
+// datastore for each call costs little compared to the block time, and it
+// lets any producer take over when another one goes down. The directory
+// stands in for a networked store shared by the producers, which would need
+// a client library lotus doesn't depend on; another backend only has to
+// implement SlashFilter.
+type SharedFilter struct {
+	dir string
+
+	// serializes operations within the process, the lock file is only
+	// contended by other processes
+	lk sync.Mutex
+}
+
+var _ SlashFilter = (*SharedFilter)(nil)
+
+func NewShared(dir string) (*SharedFilter, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, xerrors.Errorf("creating shared slash filter dir: %w", err)
+	}
+
+	return &SharedFilter{dir: dir}, nil
+}
+
+func (f *SharedFilter) MinedBlock(bh *types.BlockHeader, parentEpoch abi.ChainEpoch) error {
+	return f.with(func(df *DatastoreFilter) error {
+		return df.MinedBlock(bh, parentEpoch)
+	})
+}
+
+func (f *SharedFilter) History(since abi.ChainEpoch) (out []Record, err error) {
+	err = f.with(func(df *DatastoreFilter) error {
+		out, err = df.History(since)
+		return err
+	})
+	return out, err
+}
+
+func (f *SharedFilter) Prune(before abi.ChainEpoch) (n int, err error) {
+	err = f.with(func(df *DatastoreFilter) error {
+		n, err = df.Prune(before)
+		return err
+	})
+	return n, err
+}
+
+// with calls cb with a filter on the shared datastore, holding the lock of
+// the directory. Only one process can open the leveldb datastore at a time,
+// so it's closed again before the lock is released.
+func (f *SharedFilter) with(cb func(*DatastoreFilter) error) error {
+	f.lk.Lock()
+	defer f.lk.Unlock()
+
+	unlock, err := f.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock.Close() //nolint:errcheck
+
+	dstore, err := levelds.NewDatastore(filepath.Join(f.dir, sharedDatastore), &levelds.Options{
+		Compression: ldbopts.NoCompression,
+		NoSync:      false,
+		Strict:      ldbopts.StrictAll,
+	})
+	if err != nil {
+		return xerrors.Errorf("opening shared slash filter datastore: %w", err)
+	}
+
+	err = cb(New(dstore))
+	if cerr := dstore.Close(); cerr != nil && err == nil {
+		err = xerrors.Errorf("closing shared slash filter datastore: %w", cerr)
+	}
+	return err
+}
+
+func (f *SharedFilter) lock() (io.Closer, error) {
+	deadline := time.Now().Add(sharedLockTimeout)
+	for {
+		unlock, err := fslock.Lock(f.dir, sharedLockFile)
+		if err == nil {
+			return unlock, nil
+		}
+
+		le := fslock.LockedError("")
+		if !xerrors.As(err, &le) {
+			return nil, xerrors.Errorf("locking shared slash filter: %w", err)
+		}
+		if time.Now().After(deadline) {
+			return nil, xerrors.Errorf("locking shared slash filter: timed out after %s: %w", sharedLockTimeout, err)
+		}
+
+		time.Sleep(sharedLockRetry)
+	}
+}
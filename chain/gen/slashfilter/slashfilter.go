@@ -2,6 +2,9 @@ package slashfilter
 
 import (
 	"fmt"
+	"sort"
+	"strconv"
+	"sync"
 
 	"github.com/filecoin-project/lotus/build"
 
@@ -10,29 +13,54 @@ import (
 	"github.com/ipfs/go-cid"
 	ds "github.com/ipfs/go-datastore"
 	"github.com/ipfs/go-datastore/namespace"
+	"github.com/ipfs/go-datastore/query"
 
+	"github.com/filecoin-project/go-address"
 	"github.com/filecoin-project/go-state-types/abi"
 	"github.com/filecoin-project/lotus/chain/types"
 )
 
-type SlashFilter struct {
+// SlashFilter keeps track of the blocks mined by a miner, and refuses blocks
+// which would get the miner slashed for a consensus fault. Block producers
+// sharing a miner actor must share the state of the filter, and MinedBlock
+// must check and record blocks atomically for all of them.
+type SlashFilter interface {
+	// MinedBlock checks that the block doesn't form a consensus fault with a
+	// block mined before, and records it
+	MinedBlock(bh *types.BlockHeader, parentEpoch abi.ChainEpoch) error
+	// History returns the recorded blocks mined at or after the given epoch
+	History(since abi.ChainEpoch) ([]Record, error)
+	// Prune removes the recorded blocks mined before the given epoch
+	Prune(before abi.ChainEpoch) (int, error)
+}
+
+// DatastoreFilter is a SlashFilter keeping its state in a local datastore
+type DatastoreFilter struct {
+	// guards the checks and updates of the datastores
+	lk sync.Mutex
+
 	byEpoch   ds.Datastore // double-fork mining faults, parent-grinding fault
 	byParents ds.Datastore // time-offset mining faults
 }
 
-func New(dstore ds.Batching) *SlashFilter {
-	return &SlashFilter{
+var _ SlashFilter = (*DatastoreFilter)(nil)
+
+func New(dstore ds.Batching) *DatastoreFilter {
+	return &DatastoreFilter{
 		byEpoch:   namespace.Wrap(dstore, ds.NewKey("/slashfilter/epoch")),
 		byParents: namespace.Wrap(dstore, ds.NewKey("/slashfilter/parents")),
 	}
 }
 
-func (f *SlashFilter) MinedBlock(bh *types.BlockHeader, parentEpoch abi.ChainEpoch) error {
+func (f *DatastoreFilter) MinedBlock(bh *types.BlockHeader, parentEpoch abi.ChainEpoch) error {
 	if build.IsNearUpgrade(bh.Height, build.UpgradeOrangeHeight) {
 		return nil
 	}
 
-	epochKey := ds.NewKey(fmt.Sprintf("/%s/%d", bh.Miner, bh.Height))
+	f.lk.Lock()
+	defer f.lk.Unlock()
+
+	epochKey := byEpochKey(bh.Miner, bh.Height)
 	{
 		// double-fork mining (2 blocks at one epoch)
 		if err := checkFault(f.byEpoch, epochKey, bh, "double-fork mining faults"); err != nil {
@@ -52,7 +80,7 @@ func (f *SlashFilter) MinedBlock(bh *types.BlockHeader, parentEpoch abi.ChainEpo
 		// parent-grinding fault (didn't mine on top of our own block)
 
 		// First check if we have mined a block on the parent epoch
-		parentEpochKey := ds.NewKey(fmt.Sprintf("/%s/%d", bh.Miner, parentEpoch))
+		parentEpochKey := byEpochKey(bh.Miner, parentEpoch)
 		have, err := f.byEpoch.Has(parentEpochKey)
 		if err != nil {
 			return err
@@ -120,3 +148,147 @@ func checkFault(t ds.Datastore, key ds.Key, bh *types.BlockHeader, faultType str
 
 	return nil
 }
+
+// Record is a block recorded as mined by the slash filter
+type Record struct {
+	Miner  address.Address
+	Height abi.ChainEpoch
+	Block  cid.Cid
+}
+
+// History returns the recorded blocks mined at or after the given epoch,
+// ordered by height
+func (f *DatastoreFilter) History(since abi.ChainEpoch) ([]Record, error) {
+	f.lk.Lock()
+	defer f.lk.Unlock()
+
+	recs, err := f.records()
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]Record, 0, len(recs))
+	for _, r := range recs {
+		if r.Height >= since {
+			out = append(out, r)
+		}
+	}
+
+	return out, nil
+}
+
+// Prune removes the recorded blocks mined before the given epoch, returning
+// the number of removed blocks. Blocks mined after pruned blocks are no longer
+// checked against them, callers must make sure that the pruned epochs can't
+// be mined on again (e.g. that they are past finality)
+func (f *DatastoreFilter) Prune(before abi.ChainEpoch) (int, error) {
+	f.lk.Lock()
+	defer f.lk.Unlock()
+
+	recs, err := f.records()
+	if err != nil {
+		return 0, err
+	}
+
+	pruned := map[cid.Cid]struct{}{}
+	for _, r := range recs {
+		if r.Height >= before {
+			continue
+		}
+
+		if err := f.byEpoch.Delete(byEpochKey(r.Miner, r.Height)); err != nil {
+			return 0, xerrors.Errorf("deleting byEpoch entry: %w", err)
+		}
+		pruned[r.Block] = struct{}{}
+	}
+
+	if len(pruned) == 0 {
+		return 0, nil
+	}
+
+	// byParents entries are keyed by the parent tipset, match them by the
+	// recorded block instead
+	res, err := f.byParents.Query(query.Query{})
+	if err != nil {
+		return 0, xerrors.Errorf("querying byParents entries: %w", err)
+	}
+	defer res.Close() //nolint:errcheck
+
+	var parentKeys []ds.Key
+	for r := range res.Next() {
+		if r.Error != nil {
+			return 0, xerrors.Errorf("iterating byParents entries: %w", r.Error)
+		}
+
+		_, c, err := cid.CidFromBytes(r.Value)
+		if err != nil {
+			return 0, xerrors.Errorf("parsing byParents entry %s: %w", r.Key, err)
+		}
+
+		if _, ok := pruned[c]; ok {
+			parentKeys = append(parentKeys, ds.NewKey(r.Key))
+		}
+	}
+
+	for _, k := range parentKeys {
+		if err := f.byParents.Delete(k); err != nil {
+			return 0, xerrors.Errorf("deleting byParents entry: %w", err)
+		}
+	}
+
+	return len(pruned), nil
+}
+
+func (f *DatastoreFilter) records() ([]Record, error) {
+	res, err := f.byEpoch.Query(query.Query{})
+	if err != nil {
+		return nil, xerrors.Errorf("querying byEpoch entries: %w", err)
+	}
+	defer res.Close() //nolint:errcheck
+
+	var out []Record
+	for r := range res.Next() {
+		if r.Error != nil {
+			return nil, xerrors.Errorf("iterating byEpoch entries: %w", r.Error)
+		}
+
+		ns := ds.NewKey(r.Key).Namespaces()
+		if len(ns) != 2 {
+			return nil, xerrors.Errorf("unexpected byEpoch key %s", r.Key)
+		}
+
+		maddr, err := address.NewFromString(ns[0])
+		if err != nil {
+			return nil, xerrors.Errorf("parsing miner address in key %s: %w", r.Key, err)
+		}
+
+		h, err := strconv.ParseInt(ns[1], 10, 64)
+		if err != nil {
+			return nil, xerrors.Errorf("parsing height in key %s: %w", r.Key, err)
+		}
+
+		_, c, err := cid.CidFromBytes(r.Value)
+		if err != nil {
+			return nil, xerrors.Errorf("parsing byEpoch entry %s: %w", r.Key, err)
+		}
+
+		out = append(out, Record{
+			Miner:  maddr,
+			Height: abi.ChainEpoch(h),
+			Block:  c,
+		})
+	}
+
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Height != out[j].Height {
+			return out[i].Height < out[j].Height
+		}
+		return out[i].Miner.String() < out[j].Miner.String()
+	})
+
+	return out, nil
+}
+
+func byEpochKey(maddr address.Address, height abi.ChainEpoch) ds.Key {
+	return ds.NewKey(fmt.Sprintf("/%s/%d", maddr, height))
+}
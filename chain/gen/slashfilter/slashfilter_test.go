@@ -0,0 +1,230 @@
+package slashfilter
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	ds "github.com/ipfs/go-datastore"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-state-types/abi"
+
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/lotus/chain/types/mock"
+)
+
+func TestSlashFilterHistoryPrune(t *testing.T) {
+	sf := New(ds.NewMapDatastore())
+
+	// mine a chain of blocks at heights 0-4
+	var blks []*types.BlockHeader
+	var parent *types.TipSet
+	for i := 0; i < 5; i++ {
+		blk := mock.MkBlock(parent, 1, uint64(i))
+
+		var parentEpoch abi.ChainEpoch
+		if parent != nil {
+			parentEpoch = parent.Height()
+		}
+		require.NoError(t, sf.MinedBlock(blk, parentEpoch))
+
+		blks = append(blks, blk)
+		parent = mock.TipSet(blk)
+	}
+
+	recs, err := sf.History(0)
+	require.NoError(t, err)
+	require.Len(t, recs, 5)
+	for i, r := range recs {
+		require.Equal(t, blks[i].Miner, r.Miner)
+		require.Equal(t, abi.ChainEpoch(i), r.Height)
+		require.Equal(t, blks[i].Cid(), r.Block)
+	}
+
+	recs, err = sf.History(3)
+	require.NoError(t, err)
+	require.Len(t, recs, 2)
+	require.Equal(t, blks[3].Cid(), recs[0].Block)
+
+	n, err := sf.Prune(3)
+	require.NoError(t, err)
+	require.Equal(t, 3, n)
+
+	recs, err = sf.History(0)
+	require.NoError(t, err)
+	require.Len(t, recs, 2)
+	require.Equal(t, abi.ChainEpoch(3), recs[0].Height)
+	require.Equal(t, abi.ChainEpoch(4), recs[1].Height)
+
+	// faults against the remaining blocks are still detected
+	fork := mock.MkBlock(mock.TipSet(blks[3]), 1, 100)
+	require.Error(t, sf.MinedBlock(fork, 3))
+
+	// the same block can still be submitted again
+	require.NoError(t, sf.MinedBlock(blks[4], 3))
+
+	// pruned blocks aren't checked anymore
+	pruned := mock.MkBlock(mock.TipSet(blks[0]), 1, 100)
+	require.NoError(t, sf.MinedBlock(pruned, 0))
+
+	n, err = sf.Prune(0)
+	require.NoError(t, err)
+	require.Equal(t, 0, n)
+}
+
+func TestSharedFilter(t *testing.T) {
+	dir := t.TempDir()
+
+	// two block producers of the same miner sharing the filter directory
+	a, err := NewShared(dir)
+	require.NoError(t, err)
+	b, err := NewShared(dir)
+	require.NoError(t, err)
+
+	blk := mock.MkBlock(nil, 1, 1)
+	require.NoError(t, a.MinedBlock(blk, 0))
+
+	// a different block at the same epoch from the other producer is refused
+	fork := mock.MkBlock(nil, 1, 2)
+	require.Error(t, b.MinedBlock(fork, 0))
+
+	// the same block can be submitted by both
+	require.NoError(t, b.MinedBlock(blk, 0))
+
+	recs, err := b.History(0)
+	require.NoError(t, err)
+	require.Len(t, recs, 1)
+	require.Equal(t, blk.Cid(), recs[0].Block)
+
+	n, err := a.Prune(1)
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+
+	recs, err = b.History(0)
+	require.NoError(t, err)
+	require.Empty(t, recs)
+}
+
+const (
+	raceHelperDirEnv = "LOTUS_SLASHFILTER_RACE_DIR"
+	raceEpochs       = 50
+)
+
+// raceMinedBlocks tries to mine a block at each of raceEpochs epochs through
+// a shared filter in dir, and returns the epochs mined. The blocks conflict
+// with the ones of racers passing a different nonce.
+func raceMinedBlocks(dir string, nonce uint64) ([]abi.ChainEpoch, error) {
+	f, err := NewShared(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var mined []abi.ChainEpoch
+	for i := 1; i <= raceEpochs; i++ {
+		// every other epoch, so the parent epochs are never mined
+		h := abi.ChainEpoch(2 * i)
+		parent := mock.MkBlock(nil, 1, uint64(1000+i))
+		parent.Height = h - 1
+
+		blk := mock.MkBlock(mock.TipSet(parent), 1, nonce)
+		err := f.MinedBlock(blk, h-1)
+		if err == nil {
+			mined = append(mined, h)
+			continue
+		}
+		if !strings.Contains(err.Error(), "double-fork mining faults") {
+			return nil, xerrors.Errorf("epoch %d: %w", h, err)
+		}
+	}
+
+	return mined, nil
+}
+
+// TestSharedFilterRaceHelper is run in a separate process by
+// TestSharedFilterRace
+func TestSharedFilterRaceHelper(t *testing.T) {
+	dir := os.Getenv(raceHelperDirEnv)
+	if dir == "" {
+		t.Skip("helper process of TestSharedFilterRace")
+	}
+
+	// wait for the test to start the race
+	fmt.Println("ready")
+	_, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	require.NoError(t, err)
+
+	mined, err := raceMinedBlocks(dir, 3)
+	require.NoError(t, err)
+	for _, h := range mined {
+		fmt.Println("mined", h)
+	}
+}
+
+// TestSharedFilterRace races block producers with conflicting blocks, two in
+// this process and one in another, and checks that exactly one block is mined
+// at every epoch
+func TestSharedFilterRace(t *testing.T) {
+	dir := t.TempDir()
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestSharedFilterRaceHelper$")
+	cmd.Env = append(os.Environ(), raceHelperDirEnv+"="+dir)
+	cmd.Stderr = os.Stderr
+	stdin, err := cmd.StdinPipe()
+	require.NoError(t, err)
+	stdout, err := cmd.StdoutPipe()
+	require.NoError(t, err)
+	require.NoError(t, cmd.Start())
+
+	out := bufio.NewScanner(stdout)
+	require.True(t, out.Scan(), "helper process didn't start")
+	require.Equal(t, "ready", out.Text())
+
+	type result struct {
+		mined []abi.ChainEpoch
+		err   error
+	}
+	results := make([]result, 2)
+	var wg sync.WaitGroup
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i].mined, results[i].err = raceMinedBlocks(dir, uint64(i+1))
+		}(i)
+	}
+
+	_, err = stdin.Write([]byte("\n"))
+	require.NoError(t, err)
+
+	minedBy := map[abi.ChainEpoch]int{}
+	for out.Scan() {
+		line := out.Text()
+		if !strings.HasPrefix(line, "mined ") {
+			continue
+		}
+		h, err := strconv.ParseInt(strings.TrimPrefix(line, "mined "), 10, 64)
+		require.NoError(t, err)
+		minedBy[abi.ChainEpoch(h)]++
+	}
+	require.NoError(t, cmd.Wait())
+
+	wg.Wait()
+	for _, r := range results {
+		require.NoError(t, r.err)
+		for _, h := range r.mined {
+			minedBy[h]++
+		}
+	}
+
+	require.Len(t, minedBy, raceEpochs)
+	for h, n := range minedBy {
+		require.Equal(t, 1, n, "blocks mined at epoch %d", h)
+	}
+}
@@ -351,6 +351,14 @@ The minimum value is 518400 (6 months).`,
 			Name:  "provider-collateral",
 			Usage: "specify the requested provider collateral the miner should put up",
 		},
+		&cli.StringFlag{
+			Name:  "retrieval-price-per-byte",
+			Usage: "pre-set the price (FIL/byte) you retrieve the data of this deal at, overriding the miner's retrieval ask; waits for the deal to be accepted",
+		},
+		&cli.StringFlag{
+			Name:  "retrieval-unseal-price",
+			Usage: "pre-set the unseal price (FIL) for your retrievals of the data of this deal; requires --retrieval-price-per-byte",
+		},
 		&CidBaseFlag,
 	},
 	Action: func(cctx *cli.Context) error {
@@ -467,6 +475,26 @@ The minimum value is 518400 (6 months).`,
 			isVerified = verifiedDealParam
 		}
 
+		var retrievalPrice, unsealPrice *types.FIL
+		if cctx.IsSet("retrieval-price-per-byte") {
+			p, err := types.ParseFIL(cctx.String("retrieval-price-per-byte"))
+			if err != nil {
+				return xerrors.Errorf("parsing retrieval-price-per-byte: %w", err)
+			}
+			retrievalPrice = &p
+
+			u := types.FIL(big.Zero())
+			if cctx.IsSet("retrieval-unseal-price") {
+				u, err = types.ParseFIL(cctx.String("retrieval-unseal-price"))
+				if err != nil {
+					return xerrors.Errorf("parsing retrieval-unseal-price: %w", err)
+				}
+			}
+			unsealPrice = &u
+		} else if cctx.IsSet("retrieval-unseal-price") {
+			return xerrors.New("--retrieval-unseal-price requires --retrieval-price-per-byte")
+		}
+
 		sdParams := &lapi.StartDealParams{
 			Data:               ref,
 			Wallet:             a,
@@ -493,6 +521,23 @@ The minimum value is 518400 (6 months).`,
 			return err
 		}
 
+		if retrievalPrice != nil {
+			apiv1, closerv1, err := GetFullNodeAPIV1(cctx)
+			if err != nil {
+				return err
+			}
+			defer closerv1()
+
+			// the miner only takes retrieval terms for deals it accepted
+			if err := waitDealAccepted(ctx, api, *proposal); err != nil {
+				return xerrors.Errorf("waiting for deal %s to be accepted to set its retrieval terms: %w", *proposal, err)
+			}
+
+			if err := apiv1.ClientSetDealRetrievalTerms(ctx, *proposal, abi.TokenAmount(*retrievalPrice), abi.TokenAmount(*unsealPrice)); err != nil {
+				return xerrors.Errorf("setting retrieval terms of deal %s: %w", *proposal, err)
+			}
+		}
+
 		encoder, err := GetCidEncoder(cctx)
 		if err != nil {
 			return err
@@ -504,6 +549,51 @@ The minimum value is 518400 (6 months).`,
 	},
 }
 
+// waitDealAccepted waits until the miner accepted the deal
+func waitDealAccepted(ctx context.Context, api v0api.FullNode, propCid cid.Cid) error {
+	updates, err := api.ClientGetDealUpdates(ctx)
+	if err != nil {
+		return err
+	}
+
+	accepted := func(di lapi.DealInfo) (bool, error) {
+		switch di.State {
+		case storagemarket.StorageDealProposalAccepted, storagemarket.StorageDealAwaitingPreCommit,
+			storagemarket.StorageDealSealing, storagemarket.StorageDealActive:
+			return true, nil
+		case storagemarket.StorageDealProposalRejected, storagemarket.StorageDealFailing, storagemarket.StorageDealError:
+			return false, xerrors.Errorf("deal in state %s: %s", storagemarket.DealStates[di.State], di.Message)
+		}
+		return false, nil
+	}
+
+	// the deal may have been accepted before subscribing to updates
+	di, err := api.ClientGetDealInfo(ctx, propCid)
+	if err != nil {
+		return err
+	}
+	if ok, err := accepted(*di); ok || err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case di, ok := <-updates:
+			if !ok {
+				return xerrors.New("deal updates closed")
+			}
+			if di.ProposalCid != propCid {
+				continue
+			}
+			if ok, err := accepted(di); ok || err != nil {
+				return err
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
 func interactiveDeal(cctx *cli.Context) error {
 	api, closer, err := GetFullNodeAPI(cctx)
 	if err != nil {
@@ -12,6 +12,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/docker/go-units"
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 	"github.com/ipfs/go-datastore/namespace"
@@ -39,6 +40,7 @@ import (
 	"github.com/filecoin-project/lotus/metrics"
 	"github.com/filecoin-project/lotus/node/modules"
 	"github.com/filecoin-project/lotus/node/repo"
+	"github.com/filecoin-project/lotus/storage"
 )
 
 var log = logging.Logger("main")
@@ -152,11 +154,21 @@ var runCmd = &cli.Command{
 			Usage: "maximum fetch operations to run in parallel",
 			Value: 5,
 		},
+		&cli.StringFlag{
+			Name:  "fetch-bandwidth-limit",
+			Usage: "maximum combined bandwidth of sector file fetches in bytes per second, e.g. 100MiB, 0 means no limit",
+			Value: "0",
+		},
 		&cli.StringFlag{
 			Name:  "timeout",
 			Usage: "used when 'listen' is unspecified. must be a valid duration recognized by golang's time.ParseDuration function",
 			Value: "30m",
 		},
+		&cli.DurationFlag{
+			Name:  "params-verify-interval",
+			Usage: "how often to verify the checksums of the proof parameter files and download corrupted ones again, 0 means never",
+			Value: 0,
+		},
 	},
 	Before: func(cctx *cli.Context) error {
 		if cctx.IsSet("address") {
@@ -231,6 +243,16 @@ var runCmd = &cli.Command{
 			if err := paramfetch.GetParams(ctx, build.ParametersJSON(), build.SrsJSON(), uint64(ssize)); err != nil {
 				return xerrors.Errorf("get params: %w", err)
 			}
+
+			if interval := cctx.Duration("params-verify-interval"); interval > 0 {
+				pv, err := storage.NewParamsVerifier(build.ParametersJSON(), uint64(ssize), func() (time.Duration, error) {
+					return interval, nil
+				})
+				if err != nil {
+					return xerrors.Errorf("creating params verifier: %w", err)
+				}
+				go pv.Run(ctx)
+			}
 		}
 
 		var taskTypes []sealtasks.TaskType
@@ -365,6 +387,14 @@ var runCmd = &cli.Command{
 		remote := stores.NewRemote(localStore, nodeApi, sminfo.AuthHeader(), cctx.Int("parallel-fetch-limit"),
 			&stores.DefaultPartialFileHandler{})
 
+		// the miner's Storage.FetchBandwidthLimit only applies to fetches made
+		// by the miner process
+		bwLimit, err := units.RAMInBytes(cctx.String("fetch-bandwidth-limit"))
+		if err != nil {
+			return xerrors.Errorf("parsing fetch-bandwidth-limit: %w", err)
+		}
+		remote.SetBandwidthLimit(bwLimit)
+
 		fh := &stores.FetchHandler{Local: localStore, PfHandler: &stores.DefaultPartialFileHandler{}}
 		remoteHandler := func(w http.ResponseWriter, r *http.Request) {
 			if !auth.HasPerm(r.Context(), nil, api.PermAdmin) {
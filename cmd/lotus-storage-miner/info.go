@@ -410,6 +410,7 @@ var stateList = []stateMeta{
 	{col: color.FgYellow, state: sealing.SubmitCommitAggregate},
 	{col: color.FgYellow, state: sealing.CommitAggregateWait},
 	{col: color.FgYellow, state: sealing.FinalizeSector},
+	{col: color.FgYellow, state: sealing.FinalizeReady},
 
 	{col: color.FgCyan, state: sealing.Terminating},
 	{col: color.FgCyan, state: sealing.TerminateWait},
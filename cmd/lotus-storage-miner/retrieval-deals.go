@@ -182,6 +182,14 @@ var retrievalSetAskCmd = &cli.Command{
 			Usage:       "Set the payment interval increase (in bytes) for retrieval",
 			DefaultText: "1MiB",
 		},
+		&cli.StringFlag{
+			Name:  "free-bytes",
+			Usage: "Set the number of bytes of the payload of each piece retrieved for free",
+		},
+		&cli.StringFlag{
+			Name:  "sealed-surcharge",
+			Usage: "Set the price added to the unseal price of retrievals of pieces without an unsealed copy",
+		},
 	},
 	Action: func(cctx *cli.Context) error {
 		ctx := lcli.DaemonContext(cctx)
@@ -229,6 +237,33 @@ var retrievalSetAskCmd = &cli.Command{
 			ask.PaymentIntervalIncrease = uint64(v)
 		}
 
+		if cctx.IsSet("free-bytes") || cctx.IsSet("sealed-surcharge") {
+			tiers, err := api.MarketGetRetrievalAskTiers(ctx)
+			if err != nil {
+				return err
+			}
+
+			if cctx.IsSet("free-bytes") {
+				v, err := units.RAMInBytes(cctx.String("free-bytes"))
+				if err != nil {
+					return err
+				}
+				tiers.FreeBytes = uint64(v)
+			}
+
+			if cctx.IsSet("sealed-surcharge") {
+				v, err := types.ParseFIL(cctx.String("sealed-surcharge"))
+				if err != nil {
+					return err
+				}
+				tiers.SealedSurcharge = abi.TokenAmount(v)
+			}
+
+			if err := api.MarketSetRetrievalAskTiers(ctx, tiers); err != nil {
+				return err
+			}
+		}
+
 		return api.MarketSetRetrievalAsk(ctx, ask)
 	},
 }
@@ -264,7 +299,18 @@ var retrievalGetAskCmd = &cli.Command{
 			units.BytesSize(float64(ask.PaymentInterval)),
 			units.BytesSize(float64(ask.PaymentIntervalIncrease)),
 		)
-		return w.Flush()
+		if err := w.Flush(); err != nil {
+			return err
+		}
+
+		tiers, err := api.MarketGetRetrievalAskTiers(ctx)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("\nFree bytes per piece: %s\n", units.BytesSize(float64(tiers.FreeBytes)))
+		fmt.Printf("Sealed surcharge: %s\n", types.FIL(tiers.SealedSurcharge))
+		return nil
 
 	},
 }
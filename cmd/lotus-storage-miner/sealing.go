@@ -30,6 +30,7 @@ var sealingCmd = &cli.Command{
 		sealingWorkersCmd,
 		sealingSchedDiagCmd,
 		sealingAbortCmd,
+		sealingLimitsCmd,
 	},
 }
 
@@ -314,3 +315,34 @@ var sealingAbortCmd = &cli.Command{
 		return nodeApi.SealingAbort(ctx, job.ID)
 	},
 }
+
+var sealingLimitsCmd = &cli.Command{
+	Name:  "limits",
+	Usage: "show the effective sealing pipeline limits and current counts against them",
+	Action: func(cctx *cli.Context) error {
+		nodeApi, closer, err := lcli.GetStorageMinerAPI(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+
+		ctx := lcli.ReqContext(cctx)
+
+		limits, err := nodeApi.SealingPipelineLimits(ctx)
+		if err != nil {
+			return xerrors.Errorf("getting pipeline limits: %w", err)
+		}
+
+		tw := tabwriter.NewWriter(os.Stdout, 2, 4, 2, ' ', 0)
+		_, _ = fmt.Fprintf(tw, "Limit\tCurrent\tMax\n")
+		for _, l := range limits {
+			max := "none"
+			if l.Limit > 0 {
+				max = fmt.Sprint(l.Limit)
+			}
+			_, _ = fmt.Fprintf(tw, "%s\t%d\t%s\n", l.Name, l.Current, max)
+		}
+
+		return tw.Flush()
+	},
+}
@@ -992,6 +992,56 @@ var sectorsBatching = &cli.Command{
 	Subcommands: []*cli.Command{
 		sectorsBatchingPendingCommit,
 		sectorsBatchingPendingPreCommit,
+		sectorsBatchingPendingFinalize,
+	},
+}
+
+var sectorsBatchingPendingFinalize = &cli.Command{
+	Name:  "finalize",
+	Usage: "list sectors waiting to be finalized and moved to long-term storage",
+	Flags: []cli.Flag{
+		&cli.BoolFlag{
+			Name:  "flush",
+			Usage: "finalize all waiting sectors now",
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		api, closer, err := lcli.GetStorageMinerAPI(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+		ctx := lcli.ReqContext(cctx)
+
+		if cctx.Bool("flush") {
+			res, err := api.SectorFinalizeFlush(ctx)
+			if err != nil {
+				return xerrors.Errorf("flush: %w", err)
+			}
+			if len(res) == 0 {
+				return xerrors.Errorf("no sectors to finalize")
+			}
+
+			for _, sector := range res {
+				fmt.Printf("%d\tfinalizing\n", sector.Number)
+			}
+			return nil
+		}
+
+		pending, err := api.SectorFinalizePending(ctx)
+		if err != nil {
+			return xerrors.Errorf("getting pending sectors: %w", err)
+		}
+
+		if len(pending) > 0 {
+			for _, sector := range pending {
+				fmt.Println(sector.Number)
+			}
+			return nil
+		}
+
+		fmt.Println("No sectors waiting to be finalized")
+		return nil
 	},
 }
 
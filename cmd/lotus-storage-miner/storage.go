@@ -96,6 +96,14 @@ over time
 			Name:  "max-storage",
 			Usage: "(for init) limit storage space for sectors (expensive for very large paths!)",
 		},
+		&cli.StringFlag{
+			Name:  "reserve",
+			Usage: "(for init) free space to keep on the path, e.g. 100GiB",
+		},
+		&cli.Uint64Flag{
+			Name:  "reserve-percent",
+			Usage: "(for init) percentage of the path capacity to keep free",
+		},
 	},
 	Action: func(cctx *cli.Context) error {
 		nodeApi, closer, err := lcli.GetStorageMinerAPI(cctx)
@@ -137,12 +145,23 @@ over time
 				}
 			}
 
+			var reserve int64
+			if cctx.IsSet("reserve") {
+				reserve, err = units.RAMInBytes(cctx.String("reserve"))
+				if err != nil {
+					return xerrors.Errorf("parsing reserve: %w", err)
+				}
+			}
+
 			cfg := &stores.LocalStorageMeta{
 				ID:         stores.ID(uuid.New().String()),
 				Weight:     cctx.Uint64("weight"),
 				CanSeal:    cctx.Bool("seal"),
 				CanStore:   cctx.Bool("store"),
 				MaxStorage: uint64(maxStor),
+
+				ReserveBytes:   uint64(reserve),
+				ReservePercent: cctx.Uint64("reserve-percent"),
 			}
 
 			if !(cfg.CanStore || cfg.CanSeal) {
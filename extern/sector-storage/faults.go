@@ -7,6 +7,7 @@ import (
 	"os"
 	"path/filepath"
 
+	"github.com/ipfs/go-cid"
 	"golang.org/x/xerrors"
 
 	ffi "github.com/filecoin-project/filecoin-ffi"
@@ -20,6 +21,7 @@ import (
 // FaultTracker TODO: Track things more actively
 type FaultTracker interface {
 	CheckProvable(ctx context.Context, pp abi.RegisteredPoStProof, sectors []storage.SectorRef, rg storiface.RGetter) (map[abi.SectorID]string, error)
+	VerifyCommR(ctx context.Context, sector storage.SectorRef, commR cid.Cid) (bool, error)
 }
 
 // CheckProvable returns unprovable sectors
@@ -139,6 +141,41 @@ func (m *Manager) CheckProvable(ctx context.Context, pp abi.RegisteredPoStProof,
 	return bad, nil
 }
 
+// commRCheckRounds is the number of random challenge sets VerifyCommR proves
+const commRCheckRounds = 16
+
+// VerifyCommR checks the sealed replica and cache of the sector against the
+// replica commitment commR, e.g. the one on chain, to catch bit-rot before it
+// fails PoSt. The proofs library doesn't expose rebuilding tree_r_last over
+// the whole replica, so replica nodes are checked by proving many sets of
+// random PoSt challenges against commR, each walking the challenged replica
+// nodes up to the commitment. Damage to replica nodes which weren't challenged
+// goes unnoticed. The files have to be in the storage of this node, sectors
+// stored only on workers are reported as not matching.
+func (m *Manager) VerifyCommR(ctx context.Context, sector storage.SectorRef, commR cid.Cid) (bool, error) {
+	wpp, err := sector.ProofType.RegisteredWindowPoStProof()
+	if err != nil {
+		return false, err
+	}
+
+	rg := func(ctx context.Context, id abi.SectorID) (cid.Cid, error) {
+		return commR, nil
+	}
+
+	for i := 0; i < commRCheckRounds; i++ {
+		bad, err := m.CheckProvable(ctx, wpp, []storage.SectorRef{sector}, rg)
+		if err != nil {
+			return false, xerrors.Errorf("checking sector: %w", err)
+		}
+		if reason, ok := bad[sector.ID]; ok {
+			log.Warnw("sector doesn't match commR", "sector", sector.ID, "commR", commR, "reason", reason)
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
 func addCachePathsForSectorSize(chk map[string]int64, cacheDir string, ssize abi.SectorSize) {
 	switch ssize {
 	case 2 << 10:
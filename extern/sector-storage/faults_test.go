@@ -0,0 +1,55 @@
+package sectorstorage
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/lotus/extern/sector-storage/storiface"
+)
+
+func TestVerifyCommR(t *testing.T) {
+	sealerCfg := SealerConfig{
+		ParallelFetchLimit: 10,
+		AllowAddPiece:      true,
+		AllowPreCommit1:    true,
+		AllowPreCommit2:    true,
+		AllowCommit:        true,
+		AllowUnseal:        true,
+	}
+
+	ppt := newPieceProviderTestHarness(t, sealerCfg, abi.RegisteredSealProof_StackedDrg2KiBV1)
+	defer ppt.shutdown(t)
+
+	ppt.addPiece(t, generatePieceData(2032))
+	pc1 := ppt.preCommit1(t)
+
+	cids, err := ppt.mgr.SealPreCommit2(ppt.ctx, ppt.sector, pc1)
+	require.NoError(t, err)
+
+	ppt.finalizeSector(t, nil)
+
+	ok, err := ppt.mgr.VerifyCommR(ppt.ctx, ppt.sector, cids.Sealed)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	// a different commitment doesn't match
+	ok, err = ppt.mgr.VerifyCommR(ppt.ctx, ppt.sector, cids.Unsealed)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	// corrupt the sealed file
+	paths, _, err := ppt.localStores[0].AcquireSector(ppt.ctx, ppt.sector, storiface.FTSealed, storiface.FTNone, storiface.PathStorage, storiface.AcquireMove)
+	require.NoError(t, err)
+
+	st, err := os.Stat(paths.Sealed)
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(paths.Sealed, generatePieceData(uint64(st.Size())), 0644))
+
+	ok, err = ppt.mgr.VerifyCommR(ppt.ctx, ppt.sector, cids.Sealed)
+	require.NoError(t, err)
+	require.False(t, ok)
+}
@@ -41,8 +41,15 @@ type Worker interface {
 
 	Info(context.Context) (storiface.WorkerInfo, error)
 
+	// Utilization returns a sample of current resource usage on the worker
+	Utilization(context.Context) (storiface.WorkerUtilization, error)
+
 	Session(context.Context) (uuid.UUID, error)
 
+	// CachedResult returns the json-encoded result of a completed idempotent
+	// task, identified by its WorkID, if the worker still has it; nil otherwise
+	CachedResult(ctx context.Context, workID string) ([]byte, error)
+
 	Close() error // TODO: do we need this?
 }
 
@@ -68,6 +75,7 @@ type Manager struct {
 	index      stores.SectorIndex
 
 	sched *scheduler
+	moves *transferTracker
 
 	storage.Prover
 
@@ -104,6 +112,16 @@ const (
 type SealerConfig struct {
 	ParallelFetchLimit int
 
+	// Maximum number of sectors moved to long-term storage at the same time
+	// after finalization, 0 = no limit
+	ParallelMoveLimit int
+
+	// Maximum combined bandwidth used by the miner process for fetching sector
+	// files from workers and remote storage, in bytes per second, 0 = no limit.
+	// Fetches made by lotus-worker are limited with its --fetch-bandwidth-limit
+	// flag
+	FetchBandwidthLimit int64
+
 	// Local worker config
 	AllowAddPiece   bool
 	AllowPreCommit1 bool
@@ -115,6 +133,21 @@ type SealerConfig struct {
 	// to use when evaluating tasks against this worker. An empty value defaults
 	// to "hardware".
 	ResourceFiltering ResourceFilteringStrategy
+
+	// UtilizationAwareScheduling makes the scheduler factor in resource
+	// utilization sampled on workers when picking between equally capable
+	// workers, instead of only looking at resources reserved by tasks
+	UtilizationAwareScheduling bool
+
+	// Fraction of the CPU threads and memory of each worker which can only be
+	// used by tasks of sectors with deals, so that pledging CC sectors can't
+	// take up all workers while deals wait to be sealed. 0 disables.
+	DealCapacityReservation float64
+
+	// Path of a JSON file with overrides of the resource requirements of
+	// tasks the scheduler assigns to workers, see ResourceTableOverride.
+	// Empty uses the built-in resource table.
+	ResourceTableOverride string
 }
 
 type StorageAuth http.Header
@@ -128,6 +161,10 @@ func New(ctx context.Context, lstor *stores.Local, stor *stores.Remote, ls store
 		return nil, xerrors.Errorf("creating prover instance: %w", err)
 	}
 
+	if sc.DealCapacityReservation < 0 || sc.DealCapacityReservation >= 1 {
+		return nil, xerrors.Errorf("DealCapacityReservation must be at least 0 and less than 1, got %f", sc.DealCapacityReservation)
+	}
+
 	m := &Manager{
 		ls:         ls,
 		storage:    stor,
@@ -136,6 +173,7 @@ func New(ctx context.Context, lstor *stores.Local, stor *stores.Remote, ls store
 		index:      si,
 
 		sched: newScheduler(),
+		moves: newTransferTracker(sc.ParallelMoveLimit),
 
 		Prover: prover,
 
@@ -146,6 +184,20 @@ func New(ctx context.Context, lstor *stores.Local, stor *stores.Remote, ls store
 		waitRes:    map[WorkID]chan struct{}{},
 	}
 
+	m.sched.utilizationAware = sc.UtilizationAwareScheduling
+	m.sched.dealReservation = sc.DealCapacityReservation
+	if sc.ResourceTableOverride != "" {
+		o, err := LoadResourceTableOverride(sc.ResourceTableOverride)
+		if err != nil {
+			return nil, err
+		}
+		if m.sched.resources, err = MergeResourceTable(ResourceTable, o); err != nil {
+			return nil, xerrors.Errorf("applying resource table override: %w", err)
+		}
+		log.Infow("using resource table override", "path", sc.ResourceTableOverride)
+	}
+	stor.SetBandwidthLimit(sc.FetchBandwidthLimit)
+
 	m.setupWorkTracker()
 
 	go m.sched.runSched()
@@ -317,7 +369,15 @@ func (m *Manager) SealPreCommit1(ctx context.Context, sector storage.SectorRef,
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
-	wk, wait, cancel, err := m.getWork(ctx, sealtasks.TTPreCommit1, sector, ticket, pieces)
+	opts := storiface.PreCommit1OptionsFromContext(ctx)
+
+	params := []interface{}{sector, ticket, pieces}
+	if opts.SyntheticPoRep {
+		// keep work IDs of standard sectors the same as before the option existed
+		params = append(params, opts)
+	}
+
+	wk, wait, cancel, err := m.getWork(ctx, sealtasks.TTPreCommit1, params...)
 	if err != nil {
 		return nil, xerrors.Errorf("getWork: %w", err)
 	}
@@ -349,7 +409,7 @@ func (m *Manager) SealPreCommit1(ctx context.Context, sector storage.SectorRef,
 	selector := newAllocSelector(m.index, storiface.FTCache|storiface.FTSealed, storiface.PathSealing)
 
 	err = m.sched.Schedule(ctx, sector, sealtasks.TTPreCommit1, selector, m.schedFetch(sector, storiface.FTUnsealed, storiface.PathSealing, storiface.AcquireMove), func(ctx context.Context, w Worker) error {
-		err := m.startWork(ctx, w, wk)(w.SealPreCommit1(ctx, sector, ticket, pieces))
+		err := m.startWork(ctx, w, wk)(w.SealPreCommit1(ctx, sector, ticket, pieces, opts))
 		if err != nil {
 			return err
 		}
@@ -391,6 +451,11 @@ func (m *Manager) SealPreCommit2(ctx context.Context, sector storage.SectorRef,
 		return out, waitErr
 	}
 
+	// the result may be cached on a worker if we restarted after it was returned
+	if m.cachedResult(ctx, wk, &out) {
+		return out, nil
+	}
+
 	if err := m.index.StorageLock(ctx, sector.ID, storiface.FTSealed, storiface.FTCache); err != nil {
 		return storage.SectorCids{}, xerrors.Errorf("acquiring sector lock: %w", err)
 	}
@@ -440,6 +505,10 @@ func (m *Manager) SealCommit1(ctx context.Context, sector storage.SectorRef, tic
 		return out, waitErr
 	}
 
+	if m.cachedResult(ctx, wk, &out) {
+		return out, nil
+	}
+
 	if err := m.index.StorageLock(ctx, sector.ID, storiface.FTSealed, storiface.FTCache); err != nil {
 		return storage.Commit1Out{}, xerrors.Errorf("acquiring sector lock: %w", err)
 	}
@@ -489,6 +558,10 @@ func (m *Manager) SealCommit2(ctx context.Context, sector storage.SectorRef, pha
 		return out, waitErr
 	}
 
+	if m.cachedResult(ctx, wk, &out) {
+		return out, nil
+	}
+
 	selector := newTaskSelector()
 
 	err = m.sched.Schedule(ctx, sector, sealtasks.TTCommit2, selector, schedNop, func(ctx context.Context, w Worker) error {
@@ -563,12 +636,14 @@ func (m *Manager) FinalizeSector(ctx context.Context, sector storage.SectorRef,
 		}
 	}
 
-	err = m.sched.Schedule(ctx, sector, sealtasks.TTFetch, fetchSel,
-		m.schedFetch(sector, storiface.FTCache|storiface.FTSealed|moveUnsealed, storiface.PathStorage, storiface.AcquireMove),
-		func(ctx context.Context, w Worker) error {
-			_, err := m.waitSimpleCall(ctx)(w.MoveStorage(ctx, sector, storiface.FTCache|storiface.FTSealed|moveUnsealed))
-			return err
-		})
+	err = m.moves.run(ctx, sector.ID, func() error {
+		return m.sched.Schedule(ctx, sector, sealtasks.TTFetch, fetchSel,
+			m.schedFetch(sector, storiface.FTCache|storiface.FTSealed|moveUnsealed, storiface.PathStorage, storiface.AcquireMove),
+			func(ctx context.Context, w Worker) error {
+				_, err := m.waitSimpleCall(ctx)(w.MoveStorage(ctx, sector, storiface.FTCache|storiface.FTSealed|moveUnsealed))
+				return err
+			})
+	})
 	if err != nil {
 		return xerrors.Errorf("moving sector to storage: %w", err)
 	}
@@ -666,6 +741,22 @@ func (m *Manager) FsStat(ctx context.Context, id stores.ID) (fsutil.FsStat, erro
 	return m.storage.FsStat(ctx, id)
 }
 
+// SchedQueueDepth returns how many tasks of each type are queued in the
+// scheduler waiting to be assigned to a worker. Tasks already assigned to a
+// worker, running or not, aren't counted.
+func (m *Manager) SchedQueueDepth(ctx context.Context) (map[sealtasks.TaskType]int, error) {
+	si, err := m.sched.Info(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	out := map[sealtasks.TaskType]int{}
+	for _, req := range si.(SchedDiagInfo).Requests {
+		out[req.TaskType]++
+	}
+	return out, nil
+}
+
 func (m *Manager) SchedDiag(ctx context.Context, doSched bool) (interface{}, error) {
 	if doSched {
 		select {
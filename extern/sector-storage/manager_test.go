@@ -19,6 +19,7 @@ import (
 	logging "github.com/ipfs/go-log/v2"
 	"github.com/stretchr/testify/require"
 
+	commcid "github.com/filecoin-project/go-fil-commcid"
 	"github.com/filecoin-project/go-state-types/abi"
 	"github.com/filecoin-project/go-statestore"
 	"github.com/filecoin-project/specs-storage/storage"
@@ -108,6 +109,7 @@ func newTestMgr(ctx context.Context, t *testing.T, ds datastore.Datastore) (*Man
 		index:      si,
 
 		sched: newScheduler(),
+		moves: newTransferTracker(0),
 
 		Prover: prover,
 
@@ -209,6 +211,41 @@ func TestRedoPC1(t *testing.T) {
 	require.Equal(t, 2, tw.pc1s)
 }
 
+func TestPC1SyntheticPoRepOption(t *testing.T) {
+	ctx := context.Background()
+	m, lstor, _, _, cleanup := newTestMgr(ctx, t, datastore.NewMapDatastore())
+	defer cleanup()
+
+	tw := newTestWorker(WorkerConfig{
+		TaskTypes: []sealtasks.TaskType{sealtasks.TTAddPiece, sealtasks.TTPreCommit1, sealtasks.TTFetch},
+	}, lstor, m)
+	require.NoError(t, m.AddWorker(ctx, tw))
+
+	ticket := abi.SealRandomness{9, 9, 9, 9, 9, 9, 9, 9}
+
+	for i, synthetic := range []bool{false, true} {
+		sid := storage.SectorRef{
+			ID:        abi.SectorID{Miner: 1000, Number: abi.SectorNumber(i + 1)},
+			ProofType: abi.RegisteredSealProof_StackedDrg2KiBV1,
+		}
+
+		pi, err := m.AddPiece(ctx, sid, nil, 2032, bytes.NewReader(make([]byte, 2032)))
+		require.NoError(t, err)
+
+		sctx := ctx
+		if synthetic {
+			sctx = storiface.WithSyntheticPoRep(ctx)
+		}
+
+		_, err = m.SealPreCommit1(sctx, sid, ticket, []abi.PieceInfo{pi})
+		require.NoError(t, err)
+	}
+
+	// the mode reaches the worker as a call parameter, context values don't
+	// survive the worker RPC
+	require.Equal(t, []storiface.PreCommit1Options{{}, {SyntheticPoRep: true}}, tw.pc1opts)
+}
+
 // Manager restarts in the middle of a task, restarts it, it completes
 func TestRestartManager(t *testing.T) {
 	test := func(returnBeforeCall bool) func(*testing.T) {
@@ -309,6 +346,156 @@ func TestRestartManager(t *testing.T) {
 	t.Run("returnThenCall", test(true))
 }
 
+type pc2Exec struct {
+	testExec
+
+	pc2s int64
+}
+
+func (e *pc2Exec) SealPreCommit2(ctx context.Context, sector storage.SectorRef, pc1o storage.PreCommit1Out) (storage.SectorCids, error) {
+	atomic.AddInt64(&e.pc2s, 1)
+
+	commd, err := commcid.DataCommitmentV1ToCID(make([]byte, 32))
+	if err != nil {
+		return storage.SectorCids{}, err
+	}
+	commr, err := commcid.ReplicaCommitmentV1ToCID(make([]byte, 32))
+	if err != nil {
+		return storage.SectorCids{}, err
+	}
+
+	return storage.SectorCids{
+		Unsealed: commd,
+		Sealed:   commr,
+	}, nil
+}
+
+// Manager restarts after a worker returned a PC2 result, but before the caller
+// persisted it; the result cached on the worker is reused
+func TestRestartManagerCachedResult(t *testing.T) {
+	logging.SetAllLoggers(logging.LevelDebug)
+
+	ctx, done := context.WithCancel(context.Background())
+	defer done()
+
+	ds := datastore.NewMapDatastore()
+
+	m, lstor, stor, idx, cleanup := newTestMgr(ctx, t, ds)
+	defer cleanup()
+
+	exec := &pc2Exec{}
+	w := newLocalWorker(func() (ffiwrapper.Storage, error) {
+		return exec, nil
+	}, WorkerConfig{
+		TaskTypes:               []sealtasks.TaskType{sealtasks.TTPreCommit2, sealtasks.TTFetch},
+		IgnoreResourceFiltering: true,
+	}, stor, lstor, idx, m, statestore.New(datastore.NewMapDatastore()))
+
+	err := m.AddWorker(ctx, w)
+	require.NoError(t, err)
+
+	sid := storage.SectorRef{
+		ID:        abi.SectorID{Miner: 1000, Number: 1},
+		ProofType: abi.RegisteredSealProof_StackedDrg2KiBV1,
+	}
+
+	// PC1 output is in the worker's sealing storage
+	paths, err := lstor.Local(ctx)
+	require.NoError(t, err)
+	require.NoError(t, idx.StorageDeclareSector(ctx, paths[0].ID, sid.ID, storiface.FTSealed, true))
+	require.NoError(t, idx.StorageDeclareSector(ctx, paths[0].ID, sid.ID, storiface.FTCache, true))
+
+	pc1o := storage.PreCommit1Out("pc1 output")
+
+	cids, err := m.SealPreCommit2(ctx, sid, pc1o)
+	require.NoError(t, err)
+	require.EqualValues(t, 1, atomic.LoadInt64(&exec.pc2s))
+
+	// restart the manager with the same work tracking datastore
+	require.NoError(t, m.Close(ctx))
+
+	m, _, _, _, cleanup2 := newTestMgr(ctx, t, ds)
+	defer cleanup2()
+
+	w.ret = m // simulate jsonrpc auto-reconnect
+	err = m.AddWorker(ctx, w)
+	require.NoError(t, err)
+
+	// the sealing state machine didn't persist the result, and asks again
+	cids2, err := m.SealPreCommit2(ctx, sid, pc1o)
+	require.NoError(t, err)
+	require.Equal(t, cids, cids2)
+	require.EqualValues(t, 1, atomic.LoadInt64(&exec.pc2s), "PC2 shouldn't be recomputed")
+
+	require.Empty(t, m.WorkerJobs())
+
+	var ws []WorkState
+	require.NoError(t, m.work.List(&ws))
+	require.Empty(t, ws)
+
+	// results are dropped when the sector files are recreated
+	w.results.drop(sid.ID)
+	wid, err := newWorkID(sealtasks.TTPreCommit2, []interface{}{sid, pc1o})
+	require.NoError(t, err)
+	rb, err := w.CachedResult(ctx, wid.String())
+	require.NoError(t, err)
+	require.Nil(t, rb)
+}
+
+type cachedResultWorker struct {
+	Worker
+	res []byte
+}
+
+func (w *cachedResultWorker) CachedResult(ctx context.Context, workID string) ([]byte, error) {
+	if w.res == nil {
+		// unresponsive worker
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	return w.res, nil
+}
+
+// Workers are asked for cached results in parallel, unresponsive workers
+// don't add up
+func TestCachedResultParallel(t *testing.T) {
+	old := cachedResultTimeout
+	cachedResultTimeout = time.Second
+	defer func() {
+		cachedResultTimeout = old
+	}()
+
+	m := &Manager{
+		sched: &scheduler{workers: map[WorkerID]*workerHandle{}},
+		work:  statestore.New(datastore.NewMapDatastore()),
+	}
+
+	for i := 0; i < 5; i++ {
+		m.sched.workers[WorkerID(uuid.New())] = &workerHandle{workerRpc: &cachedResultWorker{}, enabled: true}
+	}
+
+	wid, err := newWorkID(sealtasks.TTPreCommit2, []interface{}{"params"})
+	require.NoError(t, err)
+
+	// none of the workers has the result
+	start := time.Now()
+	var out storage.SectorCids
+	require.False(t, m.cachedResult(context.Background(), wid, &out))
+	require.Less(t, int64(time.Since(start)), int64(3*cachedResultTimeout))
+
+	expect, err := (&pc2Exec{}).SealPreCommit2(context.Background(), storage.SectorRef{}, nil)
+	require.NoError(t, err)
+	rb, err := json.Marshal(expect)
+	require.NoError(t, err)
+	m.sched.workers[WorkerID(uuid.New())] = &workerHandle{workerRpc: &cachedResultWorker{res: rb}, enabled: true}
+
+	// the result is returned without waiting for the unresponsive workers
+	start = time.Now()
+	require.True(t, m.cachedResult(context.Background(), wid, &out))
+	require.Less(t, int64(time.Since(start)), int64(cachedResultTimeout))
+	require.Equal(t, expect, out)
+}
+
 // Worker restarts in the middle of a task, task fails after restart
 func TestRestartWorker(t *testing.T) {
 	logging.SetAllLoggers(logging.LevelDebug)
@@ -453,3 +640,44 @@ func TestReenableWorker(t *testing.T) {
 	i, _ = m.sched.Info(ctx)
 	require.Len(t, i.(SchedDiagInfo).OpenWindows, 2)
 }
+
+func TestSchedQueueDepth(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	m, lstor, _, _, cleanup := newTestMgr(ctx, t, datastore.NewMapDatastore())
+	defer cleanup()
+
+	// the only worker can't run C2
+	localTasks := []sealtasks.TaskType{
+		sealtasks.TTAddPiece, sealtasks.TTPreCommit1, sealtasks.TTCommit1, sealtasks.TTFinalize, sealtasks.TTFetch,
+	}
+
+	err := m.AddWorker(ctx, newTestWorker(WorkerConfig{
+		TaskTypes: localTasks,
+	}, lstor, m))
+	require.NoError(t, err)
+
+	depth, err := m.SchedQueueDepth(ctx)
+	require.NoError(t, err)
+	require.Empty(t, depth)
+
+	for i := 0; i < 3; i++ {
+		sid := storage.SectorRef{
+			ID:        abi.SectorID{Miner: 1000, Number: abi.SectorNumber(i)},
+			ProofType: abi.RegisteredSealProof_StackedDrg2KiBV1,
+		}
+
+		go func() {
+			_, _ = m.SealCommit2(ctx, sid, storage.Commit1Out{1, 2, 3})
+		}()
+	}
+
+	require.Eventually(t, func() bool {
+		depth, err = m.SchedQueueDepth(ctx)
+		require.NoError(t, err)
+		return depth[sealtasks.TTCommit2] == 3
+	}, 5*time.Second, 10*time.Millisecond)
+
+	require.Len(t, depth, 1)
+}
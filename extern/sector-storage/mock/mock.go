@@ -65,6 +65,7 @@ type sectorState struct {
 	pieces    []cid.Cid
 	failed    bool
 	corrupted bool
+	synthetic bool
 
 	state int
 
@@ -172,6 +173,7 @@ func (mgr *SectorMgr) SealPreCommit1(ctx context.Context, sid storage.SectorRef,
 	opFinishWait(ctx)
 
 	ss.state = statePreCommit
+	ss.synthetic = storiface.IsSyntheticPoRep(ctx)
 
 	pis := make([]abi.PieceInfo, len(ss.pieces))
 	for i, piece := range ss.pieces {
@@ -452,6 +454,18 @@ func (mgr *SectorMgr) CheckProvable(ctx context.Context, pp abi.RegisteredPoStPr
 	return bad, nil
 }
 
+func (mgr *SectorMgr) VerifyCommR(ctx context.Context, sector storage.SectorRef, commR cid.Cid) (bool, error) {
+	mgr.lk.Lock()
+	defer mgr.lk.Unlock()
+
+	ss, ok := mgr.sectors[sector.ID]
+	if !ok {
+		return false, fmt.Errorf("no such sector in storage")
+	}
+
+	return !ss.failed && !ss.corrupted, nil
+}
+
 func (mgr *SectorMgr) ReturnAddPiece(ctx context.Context, callID storiface.CallID, pi abi.PieceInfo, err *storiface.CallError) error {
 	panic("not supported")
 }
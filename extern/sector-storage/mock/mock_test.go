@@ -5,7 +5,11 @@ import (
 	"testing"
 	"time"
 
+	"github.com/stretchr/testify/require"
+
 	"github.com/filecoin-project/go-state-types/abi"
+
+	"github.com/filecoin-project/lotus/extern/sector-storage/storiface"
 )
 
 func TestOpFinish(t *testing.T) {
@@ -43,3 +47,21 @@ func TestOpFinish(t *testing.T) {
 		t.Fatal("should finish after we tell it to")
 	}
 }
+
+func TestSyntheticPoRep(t *testing.T) {
+	sb := NewMockSectorMgr(nil)
+
+	std, pieces, err := sb.StageFakeData(123, abi.RegisteredSealProof_StackedDrg2KiBV1_1)
+	require.NoError(t, err)
+
+	_, err = sb.SealPreCommit1(context.TODO(), std, abi.SealRandomness{}, pieces)
+	require.NoError(t, err)
+	require.False(t, sb.sectors[std.ID].synthetic)
+
+	synth, pieces, err := sb.StageFakeData(123, abi.RegisteredSealProof_StackedDrg2KiBV1_1)
+	require.NoError(t, err)
+
+	_, err = sb.SealPreCommit1(storiface.WithSyntheticPoRep(context.TODO()), synth, abi.SealRandomness{}, pieces)
+	require.NoError(t, err)
+	require.True(t, sb.sectors[synth.ID].synthetic)
+}
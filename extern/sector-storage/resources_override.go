@@ -0,0 +1,104 @@
+package sectorstorage
+
+import (
+	"encoding/json"
+	"io/ioutil"
+
+	"github.com/docker/go-units"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-state-types/abi"
+
+	"github.com/filecoin-project/lotus/extern/sector-storage/sealtasks"
+)
+
+// ResourceOverride overrides the resource requirements of a task, fields
+// which aren't set keep their defaults
+type ResourceOverride struct {
+	MinMemory      *uint64
+	MaxMemory      *uint64
+	MaxParallelism *int
+	CanGPU         *bool
+	BaseMinMemory  *uint64
+}
+
+// ResourceTableOverride holds resource overrides by task type and sector size,
+// e.g. {"seal/v0/precommit/1": {"32GiB": {"MaxMemory": 137438953472}}}. The
+// overrides of a sector size apply to all seal proofs with that sector size.
+type ResourceTableOverride map[sealtasks.TaskType]map[string]ResourceOverride
+
+// LoadResourceTableOverride reads a resource table override from a JSON file
+func LoadResourceTableOverride(path string) (ResourceTableOverride, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, xerrors.Errorf("reading resource table override: %w", err)
+	}
+
+	var o ResourceTableOverride
+	if err := json.Unmarshal(b, &o); err != nil {
+		return nil, xerrors.Errorf("decoding resource table override %s: %w", path, err)
+	}
+	return o, nil
+}
+
+// MergeResourceTable returns a copy of the resource table with the overrides
+// applied. Overrides for unknown task types or sector sizes are an error.
+func MergeResourceTable(table map[sealtasks.TaskType]map[abi.RegisteredSealProof]Resources, o ResourceTableOverride) (map[sealtasks.TaskType]map[abi.RegisteredSealProof]Resources, error) {
+	out := make(map[sealtasks.TaskType]map[abi.RegisteredSealProof]Resources, len(table))
+	for tt, byProof := range table {
+		out[tt] = make(map[abi.RegisteredSealProof]Resources, len(byProof))
+		for spt, res := range byProof {
+			out[tt][spt] = res
+		}
+	}
+
+	for tt, bySize := range o {
+		byProof, ok := out[tt]
+		if !ok {
+			return nil, xerrors.Errorf("resource override for unknown task type %s", tt)
+		}
+
+		for sizeStr, ro := range bySize {
+			size, err := units.RAMInBytes(sizeStr)
+			if err != nil {
+				return nil, xerrors.Errorf("parsing sector size %s of %s resource override: %w", sizeStr, tt, err)
+			}
+
+			var found bool
+			for spt, res := range byProof {
+				ssize, err := spt.SectorSize()
+				if err != nil || uint64(ssize) != uint64(size) {
+					continue
+				}
+				found = true
+
+				if ro.MinMemory != nil {
+					res.MinMemory = *ro.MinMemory
+				}
+				if ro.MaxMemory != nil {
+					res.MaxMemory = *ro.MaxMemory
+				}
+				if ro.MaxParallelism != nil {
+					res.MaxParallelism = *ro.MaxParallelism
+				}
+				if ro.CanGPU != nil {
+					res.CanGPU = *ro.CanGPU
+				}
+				if ro.BaseMinMemory != nil {
+					res.BaseMinMemory = *ro.BaseMinMemory
+				}
+
+				if res.MinMemory > res.MaxMemory {
+					return nil, xerrors.Errorf("%s resource override for %s sectors: MinMemory %d above MaxMemory %d", tt, sizeStr, res.MinMemory, res.MaxMemory)
+				}
+
+				byProof[spt] = res
+			}
+			if !found {
+				return nil, xerrors.Errorf("%s resource override for unknown sector size %s", tt, sizeStr)
+			}
+		}
+	}
+
+	return out, nil
+}
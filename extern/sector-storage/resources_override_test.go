@@ -0,0 +1,52 @@
+package sectorstorage
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-state-types/abi"
+
+	"github.com/filecoin-project/lotus/extern/sector-storage/sealtasks"
+)
+
+func TestMergeResourceTable(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "resources.json")
+	require.NoError(t, ioutil.WriteFile(path, []byte(`{
+		"seal/v0/precommit/1": {"32GiB": {"MaxMemory": 137438953472, "MaxParallelism": 2}},
+		"seal/v0/commit/2": {"2KiB": {"CanGPU": false}}
+	}`), 0644))
+
+	o, err := LoadResourceTableOverride(path)
+	require.NoError(t, err)
+
+	merged, err := MergeResourceTable(ResourceTable, o)
+	require.NoError(t, err)
+
+	for _, spt := range []abi.RegisteredSealProof{abi.RegisteredSealProof_StackedDrg32GiBV1, abi.RegisteredSealProof_StackedDrg32GiBV1_1} {
+		res := merged[sealtasks.TTPreCommit1][spt]
+		def := ResourceTable[sealtasks.TTPreCommit1][spt]
+		require.Equal(t, uint64(128<<30), res.MaxMemory)
+		require.Equal(t, 2, res.MaxParallelism)
+		require.Equal(t, def.MinMemory, res.MinMemory, "fields without override keep their defaults")
+		require.Equal(t, def.BaseMinMemory, res.BaseMinMemory)
+	}
+	require.False(t, merged[sealtasks.TTCommit2][abi.RegisteredSealProof_StackedDrg2KiBV1].CanGPU)
+
+	// other entries and the defaults are left alone
+	require.Equal(t, ResourceTable[sealtasks.TTPreCommit1][abi.RegisteredSealProof_StackedDrg64GiBV1], merged[sealtasks.TTPreCommit1][abi.RegisteredSealProof_StackedDrg64GiBV1])
+	require.Equal(t, ResourceTable[sealtasks.TTUnseal][abi.RegisteredSealProof_StackedDrg32GiBV1], merged[sealtasks.TTUnseal][abi.RegisteredSealProof_StackedDrg32GiBV1])
+	require.NotEqual(t, uint64(128<<30), ResourceTable[sealtasks.TTPreCommit1][abi.RegisteredSealProof_StackedDrg32GiBV1].MaxMemory)
+
+	_, err = MergeResourceTable(ResourceTable, ResourceTableOverride{"seal/v0/nope": {"32GiB": {}}})
+	require.Error(t, err)
+
+	_, err = MergeResourceTable(ResourceTable, ResourceTableOverride{sealtasks.TTPreCommit1: {"3GiB": {}}})
+	require.Error(t, err)
+
+	small := uint64(1)
+	_, err = MergeResourceTable(ResourceTable, ResourceTableOverride{sealtasks.TTPreCommit1: {"32GiB": {MaxMemory: &small}}})
+	require.Error(t, err)
+}
@@ -41,6 +41,11 @@ func WithPriority(ctx context.Context, priority int) context.Context {
 	return context.WithValue(ctx, SchedPriorityKey, priority)
 }
 
+// ReservedCapacityPriority is the minimum priority of tasks which can use the
+// worker capacity reserved with SealerConfig.DealCapacityReservation. The
+// sealing FSM schedules tasks of sectors with deals at this priority.
+var ReservedCapacityPriority = 1024
+
 const mib = 1 << 20
 
 type WorkerAction func(ctx context.Context, w Worker) error
@@ -66,6 +71,16 @@ type scheduler struct {
 
 	workTracker *workTracker
 
+	// factor in worker-reported utilization samples when comparing workers
+	utilizationAware bool
+
+	// fraction of worker capacity which only tasks with at least
+	// ReservedCapacityPriority can use
+	dealReservation float64
+
+	// task resource requirements, ResourceTable unless overridden
+	resources map[sealtasks.TaskType]map[abi.RegisteredSealProof]Resources
+
 	info chan func(interface{})
 
 	closing  chan struct{}
@@ -88,6 +103,10 @@ type workerHandle struct {
 
 	enabled bool
 
+	// last resource utilization sample reported by the worker, protected by lk
+	sampled    storiface.WorkerUtilization
+	useSampled bool
+
 	// for sync manager goroutine closing
 	cleanupStarted bool
 	closedMgr      chan struct{}
@@ -153,6 +172,8 @@ func newScheduler() *scheduler {
 
 		schedQueue: &requestQueue{},
 
+		resources: ResourceTable,
+
 		workTracker: &workTracker{
 			done:    map[storiface.CallID]struct{}{},
 			running: map[storiface.CallID]trackedWork{},
@@ -376,7 +397,7 @@ func (sh *scheduler) trySched() {
 			}()
 
 			task := (*sh.schedQueue)[sqi]
-			needRes := ResourceTable[task.taskType][task.sector.ProofType]
+			needRes := sh.resources[task.taskType][task.sector.ProofType]
 
 			task.indexHeap = sqi
 			for wnd, windowRequest := range sh.openWindows {
@@ -455,7 +476,7 @@ func (sh *scheduler) trySched() {
 
 	for sqi := 0; sqi < queueLen; sqi++ {
 		task := (*sh.schedQueue)[sqi]
-		needRes := ResourceTable[task.taskType][task.sector.ProofType]
+		needRes := sh.resources[task.taskType][task.sector.ProofType]
 
 		selectedWindow := -1
 		for _, wnd := range acceptableWindows[task.indexHeap] {
@@ -469,6 +490,10 @@ func (sh *scheduler) trySched() {
 				continue
 			}
 
+			if !sh.fitsUnreserved(task, wid, windows, needRes) {
+				continue
+			}
+
 			log.Debugf("SCHED ASSIGNED sqi:%d sector %d task %s to window %d", sqi, task.sector.ID.Number, task.taskType, wnd)
 
 			windows[wnd].allocated.add(info.Resources, needRes)
@@ -535,6 +560,56 @@ func (sh *scheduler) trySched() {
 	sh.openWindows = newOpenWindows
 }
 
+// fitsUnreserved checks that the task either can use the capacity reserved for
+// sectors with deals, or fits in the unreserved capacity of the worker, taking
+// into account tasks running on the worker and tasks assigned to it so far.
+// Must be called with workersLk held.
+func (sh *scheduler) fitsUnreserved(task *workerRequest, wid WorkerID, windows []schedWindow, needRes Resources) bool {
+	if sh.dealReservation <= 0 || task.priority >= ReservedCapacityPriority {
+		return true
+	}
+
+	w := sh.workers[wid]
+	if w.info.IgnoreResources {
+		return true
+	}
+
+	var used activeResources
+	w.lk.Lock()
+	used.addUsed(w.active)
+	used.addUsed(w.preparing)
+	w.lk.Unlock()
+
+	w.wndLk.Lock()
+	for _, window := range w.activeWindows {
+		used.addUsed(&window.allocated)
+	}
+	w.wndLk.Unlock()
+
+	for wnd, window := range sh.openWindows {
+		if window.worker == wid {
+			used.addUsed(&windows[wnd].allocated)
+		}
+	}
+
+	res := w.info.Resources
+	unreserved := 1 - sh.dealReservation
+
+	needThreads := used.cpuUse + needRes.Threads(res.CPUs)
+	if float64(needThreads) > unreserved*float64(res.CPUs) {
+		log.Debugf("sched: not scheduling %s for sector %d on worker %s; threads reserved for deal sectors, need %d, unreserved %.1f", task.taskType, task.sector.ID.Number, wid, needThreads, unreserved*float64(res.CPUs))
+		return false
+	}
+
+	needMem := res.MemReserved + used.memUsedMin + needRes.MinMemory + needRes.BaseMinMemory
+	if float64(needMem) > unreserved*float64(res.MemPhysical) {
+		log.Debugf("sched: not scheduling %s for sector %d on worker %s; memory reserved for deal sectors, need %dM, unreserved %.0fM", task.taskType, task.sector.ID.Number, wid, needMem/mib, unreserved*float64(res.MemPhysical)/mib)
+		return false
+	}
+
+	return true
+}
+
 func (sh *scheduler) schedClose() {
 	sh.workersLk.Lock()
 	defer sh.workersLk.Unlock()
@@ -35,6 +35,12 @@ func (a *activeResources) add(wr storiface.WorkerResources, r Resources) {
 	a.memUsedMax += r.MaxMemory
 }
 
+func (a *activeResources) addUsed(o *activeResources) {
+	a.cpuUse += o.cpuUse
+	a.memUsedMin += o.memUsedMin
+	a.memUsedMax += o.memUsedMax
+}
+
 func (a *activeResources) free(wr storiface.WorkerResources, r Resources) {
 	if r.CanGPU {
 		a.gpuUsed = false
@@ -105,6 +111,9 @@ func (wh *workerHandle) utilization() float64 {
 	wh.lk.Lock()
 	u := wh.active.utilization(wh.info.Resources)
 	u += wh.preparing.utilization(wh.info.Resources)
+	if wh.useSampled {
+		u += wh.sampled.Max()
+	}
 	wh.lk.Unlock()
 	wh.wndLk.Lock()
 	for _, window := range wh.activeWindows {
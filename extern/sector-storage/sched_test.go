@@ -62,9 +62,10 @@ type schedTestWorker struct {
 
 	resources       storiface.WorkerResources
 	ignoreResources bool
+	utilization     storiface.WorkerUtilization
 }
 
-func (s *schedTestWorker) SealPreCommit1(ctx context.Context, sector storage.SectorRef, ticket abi.SealRandomness, pieces []abi.PieceInfo) (storiface.CallID, error) {
+func (s *schedTestWorker) SealPreCommit1(ctx context.Context, sector storage.SectorRef, ticket abi.SealRandomness, pieces []abi.PieceInfo, opts storiface.PreCommit1Options) (storiface.CallID, error) {
 	panic("implement me")
 }
 
@@ -132,6 +133,10 @@ func (s *schedTestWorker) Info(ctx context.Context) (storiface.WorkerInfo, error
 	}, nil
 }
 
+func (s *schedTestWorker) Utilization(context.Context) (storiface.WorkerUtilization, error) {
+	return s.utilization, nil
+}
+
 func (s *schedTestWorker) Session(context.Context) (uuid.UUID, error) {
 	return s.session, nil
 }
@@ -187,6 +192,166 @@ func TestSchedStartStop(t *testing.T) {
 	require.NoError(t, sched.Close(context.TODO()))
 }
 
+func TestSchedUtilization(t *testing.T) {
+	test := func(aware bool) func(t *testing.T) {
+		return func(t *testing.T) {
+			ctx := context.Background()
+
+			sched := newScheduler()
+			sched.utilizationAware = aware
+			go sched.runSched()
+			defer sched.Close(ctx) // nolint
+
+			addWorker := func(name string, cpu float64) {
+				w := &schedTestWorker{
+					name:      name,
+					taskTypes: map[sealtasks.TaskType]struct{}{sealtasks.TTPreCommit1: {}},
+					session:   uuid.New(),
+					resources: decentWorkerResources,
+					utilization: storiface.WorkerUtilization{
+						Sampled:     time.Now(),
+						CPU:         cpu,
+						MemUsed:     4 << 30,
+						MemPhysical: decentWorkerResources.MemPhysical,
+					},
+				}
+				require.NoError(t, sched.runWorker(ctx, w))
+			}
+
+			addWorker("idle", 0.05)
+			addWorker("busy", 0.95)
+
+			m := &Manager{sched: sched}
+
+			// workers report utilization asynchronously in the heartbeat
+			require.Eventually(t, func() bool {
+				util := m.WorkerResourceUtilization()
+				if len(util) != 2 {
+					return false
+				}
+				for _, u := range util {
+					if u.Sampled.IsZero() {
+						return false
+					}
+				}
+				return true
+			}, 5*time.Second, 10*time.Millisecond)
+
+			var idle, busy *workerHandle
+			sched.workersLk.RLock()
+			for _, w := range sched.workers {
+				switch w.info.Hostname {
+				case "idle":
+					idle = w
+				case "busy":
+					busy = w
+				}
+			}
+			sched.workersLk.RUnlock()
+			require.NotNil(t, idle)
+			require.NotNil(t, busy)
+
+			for id, st := range m.WorkerStats() {
+				require.Equal(t, m.WorkerResourceUtilization()[id], st.Utilization)
+			}
+
+			sel := newTaskSelector()
+			idleFirst, err := sel.Cmp(ctx, sealtasks.TTPreCommit1, idle, busy)
+			require.NoError(t, err)
+			busyFirst, err := sel.Cmp(ctx, sealtasks.TTPreCommit1, busy, idle)
+			require.NoError(t, err)
+
+			require.Equal(t, aware, idleFirst)
+			require.False(t, busyFirst)
+		}
+	}
+
+	t.Run("aware", test(true))
+	t.Run("unaware", test(false))
+}
+
+func TestSchedDealCapacityReservation(t *testing.T) {
+	ctx, done := context.WithTimeout(context.Background(), 30*time.Second)
+	defer done()
+
+	sched := newScheduler()
+	sched.dealReservation = 0.5
+	go sched.runSched()
+	defer sched.Close(ctx) // nolint
+
+	resources := decentWorkerResources
+	resources.CPUs = 4
+
+	require.NoError(t, sched.runWorker(ctx, &schedTestWorker{
+		name:      "fred",
+		taskTypes: map[sealtasks.TaskType]struct{}{sealtasks.TTPreCommit1: {}},
+		session:   uuid.New(),
+		resources: resources,
+	}))
+
+	var lk sync.Mutex
+	running := map[string]int{}
+	release := make(chan struct{})
+
+	var wg sync.WaitGroup
+	schedule := func(kind string, sid abi.SectorNumber, prio int) {
+		sectorRef := storage.SectorRef{
+			ID:        abi.SectorID{Miner: 8, Number: sid},
+			ProofType: abi.RegisteredSealProof_StackedDrg2KiBV1,
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			err := sched.Schedule(WithPriority(ctx, prio), sectorRef, sealtasks.TTPreCommit1, newTaskSelector(), func(ctx context.Context, w Worker) error {
+				return nil
+			}, func(ctx context.Context, w Worker) error {
+				lk.Lock()
+				running[kind]++
+				lk.Unlock()
+
+				<-release
+				return nil
+			})
+			require.NoError(t, err)
+		}()
+	}
+
+	runningCount := func(kind string) int {
+		lk.Lock()
+		defer lk.Unlock()
+		return running[kind]
+	}
+
+	// a CC pledging burst, larger than the whole worker
+	for i := 0; i < 6; i++ {
+		schedule("cc", abi.SectorNumber(i), DefaultSchedPriority)
+	}
+
+	// CC sectors only get the unreserved half of the threads
+	require.Eventually(t, func() bool {
+		return runningCount("cc") == 2
+	}, 5*time.Second, 10*time.Millisecond)
+	time.Sleep(100 * time.Millisecond)
+	require.Equal(t, 2, runningCount("cc"))
+
+	// deal sectors get the reserved capacity, even with CC sectors queued
+	// before them
+	schedule("deal", 100, ReservedCapacityPriority)
+	schedule("deal", 101, ReservedCapacityPriority)
+
+	require.Eventually(t, func() bool {
+		return runningCount("deal") == 2
+	}, 5*time.Second, 10*time.Millisecond)
+	require.Equal(t, 2, runningCount("cc"))
+
+	close(release)
+	wg.Wait()
+
+	require.Equal(t, 6, runningCount("cc"))
+}
+
 func TestSched(t *testing.T) {
 	ctx, done := context.WithTimeout(context.Background(), 30*time.Second)
 	defer done()
@@ -597,7 +762,7 @@ func BenchmarkTrySched(b *testing.B) {
 }
 
 func TestWindowCompact(t *testing.T) {
-	sh := scheduler{}
+	sh := scheduler{resources: ResourceTable}
 	spt := abi.RegisteredSealProof_StackedDrg32GiBV1
 
 	test := func(start [][]sealtasks.TaskType, expect [][]sealtasks.TaskType) func(t *testing.T) {
@@ -20,6 +20,7 @@ type schedWorker struct {
 	taskDone         chan struct{}
 
 	windowsRequested int
+	lastSample       time.Time
 }
 
 // context only used for startup
@@ -41,9 +42,10 @@ func (sh *scheduler) runWorker(ctx context.Context, w Worker) error {
 		workerRpc: w,
 		info:      info,
 
-		preparing: &activeResources{},
-		active:    &activeResources{},
-		enabled:   true,
+		preparing:  &activeResources{},
+		active:     &activeResources{},
+		enabled:    true,
+		useSampled: sh.utilizationAware,
 
 		closingMgr: make(chan struct{}),
 		closedMgr:  make(chan struct{}),
@@ -245,10 +247,33 @@ func (sw *schedWorker) checkSession(ctx context.Context) bool {
 			return false
 		}
 
+		sw.sampleUtilization(ctx)
+
 		return true
 	}
 }
 
+// sampleUtilization fetches resource utilization from the worker, at most
+// once per heartbeat interval
+func (sw *schedWorker) sampleUtilization(ctx context.Context) {
+	if time.Since(sw.lastSample) < stores.HeartbeatInterval {
+		return
+	}
+	sw.lastSample = time.Now()
+
+	sctx, scancel := context.WithTimeout(ctx, stores.HeartbeatInterval/2)
+	u, err := sw.worker.workerRpc.Utilization(sctx)
+	scancel()
+	if err != nil {
+		log.Debugw("failed to sample worker utilization", "worker", sw.wid, "error", err)
+		return
+	}
+
+	sw.worker.lk.Lock()
+	sw.worker.sampled = u
+	sw.worker.lk.Unlock()
+}
+
 func (sw *schedWorker) requestWindows() bool {
 	for ; sw.windowsRequested < SchedWindows; sw.windowsRequested++ {
 		select {
@@ -295,7 +320,7 @@ func (sw *schedWorker) workerCompactWindows() {
 			var moved []int
 
 			for ti, todo := range window.todo {
-				needRes := ResourceTable[todo.taskType][todo.sector.ProofType]
+				needRes := sw.sched.resources[todo.taskType][todo.sector.ProofType]
 				if !lower.allocated.canHandleRequest(needRes, sw.wid, "compactWindows", worker.info) {
 					continue
 				}
@@ -351,7 +376,7 @@ assignLoop:
 
 			worker.lk.Lock()
 			for t, todo := range firstWindow.todo {
-				needRes := ResourceTable[todo.taskType][todo.sector.ProofType]
+				needRes := sw.sched.resources[todo.taskType][todo.sector.ProofType]
 				if worker.preparing.canHandleRequest(needRes, sw.wid, "startPreparing", worker.info) {
 					tidx = t
 					break
@@ -390,7 +415,7 @@ assignLoop:
 func (sw *schedWorker) startProcessingTask(taskDone chan struct{}, req *workerRequest) error {
 	w, sh := sw.worker, sw.sched
 
-	needRes := ResourceTable[req.taskType][req.sector.ProofType]
+	needRes := sh.resources[req.taskType][req.sector.ProofType]
 
 	w.lk.Lock()
 	w.preparing.add(w.info.Resources, needRes)
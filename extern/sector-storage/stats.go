@@ -15,6 +15,10 @@ func (m *Manager) WorkerStats() map[uuid.UUID]storiface.WorkerStats {
 	out := map[uuid.UUID]storiface.WorkerStats{}
 
 	for id, handle := range m.sched.workers {
+		handle.lk.Lock()
+		sampled := handle.sampled
+		handle.lk.Unlock()
+
 		out[uuid.UUID(id)] = storiface.WorkerStats{
 			Info:    handle.info,
 			Enabled: handle.enabled,
@@ -23,12 +27,31 @@ func (m *Manager) WorkerStats() map[uuid.UUID]storiface.WorkerStats {
 			MemUsedMax: handle.active.memUsedMax,
 			GpuUsed:    handle.active.gpuUsed,
 			CpuUse:     handle.active.cpuUse,
+
+			Utilization: sampled,
 		}
 	}
 
 	return out
 }
 
+// WorkerResourceUtilization returns the last resource utilization sample
+// reported by each worker
+func (m *Manager) WorkerResourceUtilization() map[uuid.UUID]storiface.WorkerUtilization {
+	m.sched.workersLk.RLock()
+	defer m.sched.workersLk.RUnlock()
+
+	out := map[uuid.UUID]storiface.WorkerUtilization{}
+
+	for id, handle := range m.sched.workers {
+		handle.lk.Lock()
+		out[uuid.UUID(id)] = handle.sampled
+		handle.lk.Unlock()
+	}
+
+	return out
+}
+
 func (m *Manager) WorkerJobs() map[uuid.UUID][]storiface.WorkerJob {
 	out := map[uuid.UUID][]storiface.WorkerJob{}
 	calls := map[storiface.CallID]struct{}{}
@@ -92,3 +115,8 @@ func (m *Manager) WorkerJobs() map[uuid.UUID][]storiface.WorkerJob {
 
 	return out
 }
+
+// TransferStats returns the state of sector moves to long-term storage
+func (m *Manager) TransferStats() storiface.TransferStats {
+	return m.moves.stats()
+}
@@ -32,6 +32,11 @@ type StorageInfo struct {
 	Weight     uint64
 	MaxStorage uint64
 
+	// free space kept on the path, it's considered full when no more than
+	// the larger of ReserveBytes and ReservePercent of its capacity is free
+	ReserveBytes   uint64
+	ReservePercent uint64
+
 	CanSeal  bool
 	CanStore bool
 }
@@ -52,6 +57,24 @@ type SectorStorageInfo struct {
 	Primary bool
 }
 
+// StorageLocation is a storage path holding files of a sector
+type StorageLocation struct {
+	ID   ID
+	URLs []string
+
+	// the files of the sector the path holds
+	FileTypes storiface.SectorFileType
+	Primary   bool
+
+	CanSeal  bool
+	CanStore bool
+
+	// Online is false when the path isn't attached, didn't send heartbeats
+	// recently, or reported an error, Err says which
+	Online bool
+	Err    string
+}
+
 type SectorIndex interface { // part of storage-miner api
 	StorageAttach(context.Context, StorageInfo, fsutil.FsStat) error
 	StorageInfo(context.Context, ID) (StorageInfo, error)
@@ -86,6 +109,20 @@ type storageEntry struct {
 	heartbeatErr  error
 }
 
+// available returns the space which can be allocated on the path, excluding
+// the configured free space reserve
+func (e *storageEntry) available() int64 {
+	reserve := int64(e.info.ReserveBytes)
+	if pr := int64(uint64(e.fsi.Capacity) * e.info.ReservePercent / 100); pr > reserve {
+		reserve = pr
+	}
+
+	if e.fsi.Available <= reserve {
+		return 0
+	}
+	return e.fsi.Available - reserve
+}
+
 type Index struct {
 	*indexLocks
 	lk sync.RWMutex
@@ -159,6 +196,8 @@ func (i *Index) StorageAttach(ctx context.Context, si StorageInfo, st fsutil.FsS
 
 		i.stores[si.ID].info.Weight = si.Weight
 		i.stores[si.ID].info.MaxStorage = si.MaxStorage
+		i.stores[si.ID].info.ReserveBytes = si.ReserveBytes
+		i.stores[si.ID].info.ReservePercent = si.ReservePercent
 		i.stores[si.ID].info.CanSeal = si.CanSeal
 		i.stores[si.ID].info.CanStore = si.CanStore
 
@@ -320,8 +359,8 @@ func (i *Index) StorageFindSector(ctx context.Context, s abi.SectorID, ft storif
 				continue
 			}
 
-			if spaceReq > uint64(st.fsi.Available) {
-				log.Debugf("not selecting on %s, out of space (available: %d, need: %d)", st.info.ID, st.fsi.Available, spaceReq)
+			if spaceReq > uint64(st.available()) {
+				log.Debugf("not selecting on %s, out of space (available: %d, need: %d)", st.info.ID, st.available(), spaceReq)
 				continue
 			}
 
@@ -366,6 +405,62 @@ func (i *Index) StorageFindSector(ctx context.Context, s abi.SectorID, ft storif
 	return out, nil
 }
 
+// SectorLocations returns all storage paths holding files of the given types
+// for the sector, along with whether they're online
+func (i *Index) SectorLocations(ctx context.Context, s abi.SectorID, ft storiface.SectorFileType) ([]StorageLocation, error) {
+	i.lk.RLock()
+	defer i.lk.RUnlock()
+
+	byID := map[ID]*StorageLocation{}
+	var out []*StorageLocation
+
+	for _, pathType := range storiface.PathTypes {
+		if ft&pathType == 0 {
+			continue
+		}
+
+		for _, decl := range i.sectors[Decl{s, pathType}] {
+			loc, ok := byID[decl.storage]
+			if !ok {
+				loc = &StorageLocation{ID: decl.storage}
+				byID[decl.storage] = loc
+				out = append(out, loc)
+			}
+			loc.FileTypes |= pathType
+			loc.Primary = loc.Primary || decl.primary
+		}
+	}
+
+	res := make([]StorageLocation, 0, len(out))
+	for _, loc := range out {
+		st, ok := i.stores[loc.ID]
+		switch {
+		case !ok:
+			loc.Err = "storage path not attached"
+		case time.Since(st.lastHeartbeat) > SkippedHeartbeatThresh:
+			loc.Err = fmt.Sprintf("no heartbeat for %s", time.Since(st.lastHeartbeat).Truncate(time.Second))
+		case st.heartbeatErr != nil:
+			loc.Err = st.heartbeatErr.Error()
+		default:
+			loc.Online = true
+		}
+
+		if ok {
+			loc.URLs = st.info.URLs
+			loc.CanSeal = st.info.CanSeal
+			loc.CanStore = st.info.CanStore
+		}
+
+		res = append(res, *loc)
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].ID < res[j].ID
+	})
+
+	return res, nil
+}
+
 func (i *Index) StorageInfo(ctx context.Context, id ID) (StorageInfo, error) {
 	i.lk.RLock()
 	defer i.lk.RUnlock()
@@ -406,8 +501,8 @@ func (i *Index) StorageBestAlloc(ctx context.Context, allocate storiface.SectorF
 			continue
 		}
 
-		if spaceReq > uint64(p.fsi.Available) {
-			log.Debugf("not allocating on %s, out of space (available: %d, need: %d)", p.info.ID, p.fsi.Available, spaceReq)
+		if spaceReq > uint64(p.available()) {
+			log.Debugf("not allocating on %s, out of space (available: %d, need: %d)", p.info.ID, p.available(), spaceReq)
 			continue
 		}
 
@@ -429,8 +524,8 @@ func (i *Index) StorageBestAlloc(ctx context.Context, allocate storiface.SectorF
 	}
 
 	sort.Slice(candidates, func(i, j int) bool {
-		iw := big.Mul(big.NewInt(candidates[i].fsi.Available), big.NewInt(int64(candidates[i].info.Weight)))
-		jw := big.Mul(big.NewInt(candidates[j].fsi.Available), big.NewInt(int64(candidates[j].info.Weight)))
+		iw := big.Mul(big.NewInt(candidates[i].available()), big.NewInt(int64(candidates[i].info.Weight)))
+		jw := big.Mul(big.NewInt(candidates[j].available()), big.NewInt(int64(candidates[j].info.Weight)))
 
 		return iw.GreaterThan(jw)
 	})
@@ -0,0 +1,136 @@
+package stores
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-state-types/abi"
+
+	"github.com/filecoin-project/lotus/extern/sector-storage/fsutil"
+	"github.com/filecoin-project/lotus/extern/sector-storage/storiface"
+)
+
+func TestSectorLocations(t *testing.T) {
+	ctx := context.Background()
+	idx := NewIndex()
+
+	for _, si := range []StorageInfo{
+		{ID: "seal", URLs: []string{"http://seal"}, CanSeal: true},
+		{ID: "store", URLs: []string{"http://store"}, CanStore: true},
+	} {
+		require.NoError(t, idx.StorageAttach(ctx, si, fsutil.FsStat{}))
+	}
+
+	require.NoError(t, idx.StorageDeclareSector(ctx, "store", aSector, storiface.FTSealed, true))
+	require.NoError(t, idx.StorageDeclareSector(ctx, "store", aSector, storiface.FTCache, true))
+	require.NoError(t, idx.StorageDeclareSector(ctx, "seal", aSector, storiface.FTCache, false))
+	require.NoError(t, idx.StorageDeclareSector(ctx, "seal", aSector, storiface.FTUnsealed, false))
+
+	locs, err := idx.SectorLocations(ctx, aSector, storiface.FTSealed|storiface.FTCache)
+	require.NoError(t, err)
+	require.Equal(t, []StorageLocation{
+		{
+			ID:        "seal",
+			URLs:      []string{"http://seal"},
+			FileTypes: storiface.FTCache,
+			CanSeal:   true,
+			Online:    true,
+		},
+		{
+			ID:        "store",
+			URLs:      []string{"http://store"},
+			FileTypes: storiface.FTSealed | storiface.FTCache,
+			Primary:   true,
+			CanStore:  true,
+			Online:    true,
+		},
+	}, locs)
+
+	// the path reports an error
+	require.NoError(t, idx.StorageReportHealth(ctx, "store", HealthReport{Err: "disk gone"}))
+
+	locs, err = idx.SectorLocations(ctx, aSector, storiface.FTSealed)
+	require.NoError(t, err)
+	require.Len(t, locs, 1)
+	require.False(t, locs[0].Online)
+	require.Equal(t, "disk gone", locs[0].Err)
+
+	// the path stops sending heartbeats
+	require.NoError(t, idx.StorageReportHealth(ctx, "store", HealthReport{}))
+	idx.stores["store"].lastHeartbeat = time.Now().Add(-2 * SkippedHeartbeatThresh)
+
+	locs, err = idx.SectorLocations(ctx, aSector, storiface.FTSealed)
+	require.NoError(t, err)
+	require.Len(t, locs, 1)
+	require.False(t, locs[0].Online)
+	require.Contains(t, locs[0].Err, "no heartbeat")
+
+	// the sector is declared in a path which isn't attached
+	other := abi.SectorID{Miner: 2, Number: 9001}
+	require.NoError(t, idx.StorageDeclareSector(ctx, "detached", other, storiface.FTSealed, true))
+
+	locs, err = idx.SectorLocations(ctx, other, storiface.FTSealed|storiface.FTCache)
+	require.NoError(t, err)
+	require.Len(t, locs, 1)
+	require.False(t, locs[0].Online)
+	require.Equal(t, "storage path not attached", locs[0].Err)
+
+	// sectors which aren't stored anywhere have no locations
+	locs, err = idx.SectorLocations(ctx, abi.SectorID{Miner: 2, Number: 9002}, storiface.FTSealed|storiface.FTCache)
+	require.NoError(t, err)
+	require.Empty(t, locs)
+}
+
+func TestStorageBestAllocReserve(t *testing.T) {
+	ctx := context.Background()
+	idx := NewIndex()
+
+	ft := storiface.FTUnsealed | storiface.FTSealed | storiface.FTCache
+	req, err := ft.SealSpaceUse(2048)
+	require.NoError(t, err)
+	need := int64(req)
+
+	require.NoError(t, idx.StorageAttach(ctx, StorageInfo{ID: "reserved", Weight: 10, ReserveBytes: uint64(10 * need), CanSeal: true}, fsutil.FsStat{
+		Capacity:  100 * need,
+		Available: 50 * need,
+	}))
+	require.NoError(t, idx.StorageAttach(ctx, StorageInfo{ID: "other", Weight: 10, CanSeal: true}, fsutil.FsStat{
+		Capacity:  100 * need,
+		Available: 5 * need,
+	}))
+
+	best := func() []ID {
+		si, err := idx.StorageBestAlloc(ctx, ft, 2048, storiface.PathSealing)
+		if err != nil {
+			return nil
+		}
+		var ids []ID
+		for _, s := range si {
+			ids = append(ids, s.ID)
+		}
+		return ids
+	}
+
+	require.Equal(t, []ID{"reserved", "other"}, best())
+
+	// the path fills up to its reserve, there's still raw free space for the
+	// sector, but nothing gets allocated there anymore
+	require.NoError(t, idx.StorageReportHealth(ctx, "reserved", HealthReport{Stat: fsutil.FsStat{
+		Capacity:  100 * need,
+		Available: 10*need + need/2,
+	}}))
+	require.Equal(t, []ID{"other"}, best())
+
+	// the reserve percentage applies when it's larger
+	require.NoError(t, idx.StorageAttach(ctx, StorageInfo{ID: "other", Weight: 10, ReservePercent: 5, CanSeal: true}, fsutil.FsStat{}))
+	require.Nil(t, best())
+
+	require.NoError(t, idx.StorageReportHealth(ctx, "other", HealthReport{Stat: fsutil.FsStat{
+		Capacity:  100 * need,
+		Available: 6 * need,
+	}}))
+	require.Equal(t, []ID{"other"}, best())
+}
@@ -46,6 +46,12 @@ type LocalStorageMeta struct {
 	// MaxStorage specifies the maximum number of bytes to use for sector storage
 	// (0 = unlimited)
 	MaxStorage uint64
+
+	// ReserveBytes and ReservePercent specify free space that new sector data
+	// won't be allocated into, the larger of the two, with the percentage
+	// taken of the filesystem capacity, applies (0 = no reserve)
+	ReserveBytes   uint64
+	ReservePercent uint64
 }
 
 // StorageConfig .lotusstorage/storage.json
@@ -199,12 +205,14 @@ func (st *Local) OpenPath(ctx context.Context, p string) error {
 	}
 
 	err = st.index.StorageAttach(ctx, StorageInfo{
-		ID:         meta.ID,
-		URLs:       st.urls,
-		Weight:     meta.Weight,
-		MaxStorage: meta.MaxStorage,
-		CanSeal:    meta.CanSeal,
-		CanStore:   meta.CanStore,
+		ID:             meta.ID,
+		URLs:           st.urls,
+		Weight:         meta.Weight,
+		MaxStorage:     meta.MaxStorage,
+		ReserveBytes:   meta.ReserveBytes,
+		ReservePercent: meta.ReservePercent,
+		CanSeal:        meta.CanSeal,
+		CanStore:       meta.CanStore,
 	}, fst)
 	if err != nil {
 		return xerrors.Errorf("declaring storage in index: %w", err)
@@ -263,12 +271,14 @@ func (st *Local) Redeclare(ctx context.Context) error {
 		}
 
 		err = st.index.StorageAttach(ctx, StorageInfo{
-			ID:         id,
-			URLs:       st.urls,
-			Weight:     meta.Weight,
-			MaxStorage: meta.MaxStorage,
-			CanSeal:    meta.CanSeal,
-			CanStore:   meta.CanStore,
+			ID:             id,
+			URLs:           st.urls,
+			Weight:         meta.Weight,
+			MaxStorage:     meta.MaxStorage,
+			ReserveBytes:   meta.ReserveBytes,
+			ReservePercent: meta.ReservePercent,
+			CanSeal:        meta.CanSeal,
+			CanStore:       meta.CanStore,
 		}, fst)
 		if err != nil {
 			return xerrors.Errorf("redeclaring storage in index: %w", err)
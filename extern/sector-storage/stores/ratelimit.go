@@ -0,0 +1,31 @@
+package stores
+
+import (
+	"context"
+	"io"
+
+	"golang.org/x/time/rate"
+)
+
+// rateLimitedReader limits the rate at which data can be read from the
+// underlying reader. When the same limiter is shared between readers, their
+// combined rate is limited.
+type rateLimitedReader struct {
+	ctx     context.Context
+	r       io.Reader
+	limiter *rate.Limiter
+}
+
+func (r *rateLimitedReader) Read(p []byte) (int, error) {
+	if len(p) > r.limiter.Burst() {
+		p = p[:r.limiter.Burst()]
+	}
+
+	n, err := r.r.Read(p)
+	if n > 0 {
+		if werr := r.limiter.WaitN(r.ctx, n); werr != nil {
+			return n, werr
+		}
+	}
+	return n, err
+}
@@ -24,6 +24,7 @@ import (
 	"github.com/filecoin-project/specs-storage/storage"
 
 	"github.com/hashicorp/go-multierror"
+	"golang.org/x/time/rate"
 	"golang.org/x/xerrors"
 )
 
@@ -38,6 +39,9 @@ type Remote struct {
 
 	limit chan struct{}
 
+	// limits the combined bandwidth of all fetches, nil when unlimited
+	bwLimit *rate.Limiter
+
 	fetchLk  sync.Mutex
 	fetching map[abi.SectorID]chan struct{}
 
@@ -65,6 +69,18 @@ func NewRemote(local Store, index SectorIndex, auth http.Header, fetchLimit int,
 	}
 }
 
+// SetBandwidthLimit limits the combined bandwidth used by sector file fetches,
+// in bytes per second. 0 removes the limit. It must be called before any
+// fetches are started.
+func (r *Remote) SetBandwidthLimit(bytesPerSecond int64) {
+	if bytesPerSecond <= 0 {
+		r.bwLimit = nil
+		return
+	}
+
+	r.bwLimit = rate.NewLimiter(rate.Limit(bytesPerSecond), CopyBuf)
+}
+
 func (r *Remote) AcquireSector(ctx context.Context, s storage.SectorRef, existing storiface.SectorFileType, allocate storiface.SectorFileType, pathType storiface.PathType, op storiface.AcquireMode) (storiface.SectorPaths, storiface.SectorPaths, error) {
 	if existing|allocate != existing^allocate {
 		return storiface.SectorPaths{}, storiface.SectorPaths{}, xerrors.New("can't both find and allocate a sector")
@@ -259,6 +275,11 @@ func (r *Remote) fetch(ctx context.Context, url, outname string) error {
 		return xerrors.Errorf("non-200 code: %d", resp.StatusCode)
 	}
 
+	var body io.Reader = resp.Body
+	if r.bwLimit != nil {
+		body = &rateLimitedReader{ctx: ctx, r: resp.Body, limiter: r.bwLimit}
+	}
+
 	/*bar := pb.New64(w.sizeForType(typ))
 	bar.ShowPercent = true
 	bar.ShowSpeed = true
@@ -280,13 +301,13 @@ func (r *Remote) fetch(ctx context.Context, url, outname string) error {
 
 	switch mediatype {
 	case "application/x-tar":
-		return tarutil.ExtractTar(resp.Body, outname)
+		return tarutil.ExtractTar(body, outname)
 	case "application/octet-stream":
 		f, err := os.Create(outname)
 		if err != nil {
 			return err
 		}
-		_, err = io.CopyBuffer(f, resp.Body, make([]byte, CopyBuf))
+		_, err = io.CopyBuffer(f, body, make([]byte, CopyBuf))
 		if err != nil {
 			f.Close() // nolint
 			return err
@@ -444,6 +465,10 @@ func (r *Remote) checkAllocated(ctx context.Context, url string, spt abi.Registe
 	}
 }
 
+// readRemote returns a reader streaming the given range of the remote file.
+// Only the range is requested, and the response body is read as the returned
+// reader is read, so a slow reader slows down the transfer instead of data
+// being buffered in memory.
 func (r *Remote) readRemote(ctx context.Context, url string, offset, size abi.PaddedPieceSize) (io.ReadCloser, error) {
 	if len(r.limit) >= cap(r.limit) {
 		log.Infof("Throttling remote read, %d already running", len(r.limit))
@@ -481,7 +506,16 @@ func (r *Remote) readRemote(ctx context.Context, url string, offset, size abi.Pa
 		return nil, xerrors.Errorf("non-200 code: %d", resp.StatusCode)
 	}
 
-	return resp.Body, nil
+	// never read past the requested range, even if the remote sends more
+	return &limitedReadCloser{
+		Reader: io.LimitReader(resp.Body, int64(size)),
+		Closer: resp.Body,
+	}, nil
+}
+
+type limitedReadCloser struct {
+	io.Reader
+	io.Closer
 }
 
 // CheckIsUnsealed checks if we have an unsealed piece at the given offset in an already unsealed sector file for the given piece
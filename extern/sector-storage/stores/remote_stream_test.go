@@ -0,0 +1,113 @@
+package stores
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/specs-storage/storage"
+
+	"github.com/filecoin-project/lotus/extern/sector-storage/partialfile"
+	"github.com/filecoin-project/lotus/extern/sector-storage/storiface"
+)
+
+type countingResponseWriter struct {
+	http.ResponseWriter
+	n *int64
+}
+
+func (w *countingResponseWriter) Write(b []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(b)
+	atomic.AddInt64(w.n, int64(n))
+	return n, err
+}
+
+// TestRemoteReaderStreamsRange reads a piece from an unsealed file which only
+// exists in the storage of another node, like a markets node serving
+// retrievals from data stored on a storage worker, and checks that only the
+// range of the piece is transferred
+func TestRemoteReaderStreamsRange(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sector := storage.SectorRef{
+		ID:        abi.SectorID{Miner: 1000, Number: 1},
+		ProofType: abi.RegisteredSealProof_StackedDrg8MiBV1,
+	}
+	ssize, err := sector.ProofType.SectorSize()
+	require.NoError(t, err)
+
+	offset := abi.PaddedPieceSize(1 << 20)
+	size := abi.PaddedPieceSize(512 << 10)
+
+	index := NewIndex()
+
+	// the storage node holding the unsealed file
+	sstor := &TestingLocalStorage{root: t.TempDir()}
+	require.NoError(t, sstor.init("storage"))
+	spath := filepath.Join(sstor.root, "storage")
+	sstor.c.StoragePaths = []LocalPath{{Path: spath}}
+
+	require.NoError(t, os.Mkdir(filepath.Join(spath, storiface.FTUnsealed.String()), 0755))
+	pf, err := partialfile.CreatePartialFile(abi.PaddedPieceSize(ssize), filepath.Join(spath, storiface.FTUnsealed.String(), storiface.SectorName(sector.ID)))
+	require.NoError(t, err)
+
+	data := make([]byte, size)
+	_, _ = rand.New(rand.NewSource(1)).Read(data)
+
+	w, err := pf.Writer(storiface.PaddedByteIndex(offset), size)
+	require.NoError(t, err)
+	_, err = w.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, pf.MarkAllocated(storiface.PaddedByteIndex(offset), size))
+	require.NoError(t, pf.Close())
+
+	var served int64
+	var handler http.Handler
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler.ServeHTTP(&countingResponseWriter{ResponseWriter: w, n: &served}, r)
+	}))
+	defer srv.Close()
+
+	sl, err := NewLocal(ctx, sstor, index, []string{srv.URL + "/remote"})
+	require.NoError(t, err)
+	handler = &FetchHandler{Local: sl, PfHandler: &DefaultPartialFileHandler{}}
+
+	// the markets node, without a local copy
+	mstor := &TestingLocalStorage{root: t.TempDir()}
+	require.NoError(t, mstor.init("markets"))
+	mstor.c.StoragePaths = []LocalPath{{Path: filepath.Join(mstor.root, "markets")}}
+
+	ml, err := NewLocal(ctx, mstor, index, nil)
+	require.NoError(t, err)
+
+	remote := NewRemote(ml, index, nil, 1, &DefaultPartialFileHandler{})
+
+	has, err := remote.CheckIsUnsealed(ctx, sector, offset, size)
+	require.NoError(t, err)
+	require.True(t, has)
+
+	rd, err := remote.Reader(ctx, sector, offset, size)
+	require.NoError(t, err)
+	require.NotNil(t, rd)
+
+	read, err := ioutil.ReadAll(rd)
+	require.NoError(t, err)
+	require.NoError(t, rd.Close())
+
+	require.True(t, bytes.Equal(data, read))
+
+	// only the piece was transferred, not the whole sector file
+	require.EqualValues(t, size, atomic.LoadInt64(&served))
+	require.Less(t, atomic.LoadInt64(&served), int64(ssize))
+}
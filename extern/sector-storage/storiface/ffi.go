@@ -29,3 +29,36 @@ func (i UnpaddedByteIndex) Valid() error {
 type PaddedByteIndex uint64
 
 type RGetter func(ctx context.Context, id abi.SectorID) (cid.Cid, error)
+
+type syntheticPoRepKey struct{}
+
+// WithSyntheticPoRep marks sealing calls made with the returned context as
+// sealing a sector with synthetic PoRep
+func WithSyntheticPoRep(ctx context.Context) context.Context {
+	return context.WithValue(ctx, syntheticPoRepKey{}, true)
+}
+
+func IsSyntheticPoRep(ctx context.Context) bool {
+	synthetic, _ := ctx.Value(syntheticPoRepKey{}).(bool)
+	return synthetic
+}
+
+// PreCommit1Options carries the parts of a PreCommit1 task which callers set
+// on the context, context values aren't passed over the worker RPC
+type PreCommit1Options struct {
+	SyntheticPoRep bool
+}
+
+func PreCommit1OptionsFromContext(ctx context.Context) PreCommit1Options {
+	return PreCommit1Options{
+		SyntheticPoRep: IsSyntheticPoRep(ctx),
+	}
+}
+
+// Apply returns a context for the sealer call carrying the options
+func (o PreCommit1Options) Apply(ctx context.Context) context.Context {
+	if o.SyntheticPoRep {
+		ctx = WithSyntheticPoRep(ctx)
+	}
+	return ctx
+}
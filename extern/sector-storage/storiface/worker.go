@@ -44,6 +44,36 @@ type WorkerStats struct {
 	MemUsedMax uint64
 	GpuUsed    bool   // nolint
 	CpuUse     uint64 // nolint
+
+	// Utilization is the last resource usage sample reported by the worker
+	Utilization WorkerUtilization
+}
+
+// WorkerUtilization is a sample of actual resource usage on a worker, as
+// opposed to the resources reserved by the scheduler for running tasks
+type WorkerUtilization struct {
+	Sampled time.Time
+
+	CPU float64 // fraction of logical cores busy since the previous sample, 0-1
+	GPU float64 // fraction of GPUs with a GPU task running, 0-1
+
+	MemUsed     uint64
+	MemPhysical uint64
+}
+
+// Max returns the highest of the sampled CPU / GPU / memory usage fractions
+func (u WorkerUtilization) Max() float64 {
+	max := u.CPU
+	if u.GPU > max {
+		max = u.GPU
+	}
+	if u.MemPhysical > 0 {
+		mem := float64(u.MemUsed) / float64(u.MemPhysical)
+		if mem > max {
+			max = mem
+		}
+	}
+	return max
 }
 
 const (
@@ -68,6 +98,21 @@ type WorkerJob struct {
 	Hostname string `json:",omitempty"` // optional, set for ret-wait jobs
 }
 
+// TransferStats describes moves of finalized sectors to long-term storage
+type TransferStats struct {
+	// Maximum number of moves running at the same time, 0 if unlimited
+	MaxActive int
+
+	Active []SectorTransfer
+	// Number of moves waiting for other moves to finish
+	Waiting int
+}
+
+type SectorTransfer struct {
+	Sector abi.SectorID
+	Start  time.Time
+}
+
 type CallID struct {
 	Sector abi.SectorID
 	ID     uuid.UUID
@@ -83,7 +128,7 @@ var UndefCall CallID
 
 type WorkerCalls interface {
 	AddPiece(ctx context.Context, sector storage.SectorRef, pieceSizes []abi.UnpaddedPieceSize, newPieceSize abi.UnpaddedPieceSize, pieceData storage.Data) (CallID, error)
-	SealPreCommit1(ctx context.Context, sector storage.SectorRef, ticket abi.SealRandomness, pieces []abi.PieceInfo) (CallID, error)
+	SealPreCommit1(ctx context.Context, sector storage.SectorRef, ticket abi.SealRandomness, pieces []abi.PieceInfo, opts PreCommit1Options) (CallID, error)
 	SealPreCommit2(ctx context.Context, sector storage.SectorRef, pc1o storage.PreCommit1Out) (CallID, error)
 	SealCommit1(ctx context.Context, sector storage.SectorRef, ticket abi.SealRandomness, seed abi.InteractiveSealRandomness, pieces []abi.PieceInfo, cids storage.SectorCids) (CallID, error)
 	SealCommit2(ctx context.Context, sector storage.SectorRef, c1o storage.Commit1Out) (CallID, error)
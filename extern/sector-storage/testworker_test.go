@@ -22,6 +22,7 @@ type testWorker struct {
 	mockSeal *mock.SectorMgr
 
 	pc1s    int
+	pc1opts []storiface.PreCommit1Options
 	pc1lk   sync.Mutex
 	pc1wait *sync.WaitGroup
 
@@ -67,9 +68,10 @@ func (t *testWorker) AddPiece(ctx context.Context, sector storage.SectorRef, pie
 	})
 }
 
-func (t *testWorker) SealPreCommit1(ctx context.Context, sector storage.SectorRef, ticket abi.SealRandomness, pieces []abi.PieceInfo) (storiface.CallID, error) {
+func (t *testWorker) SealPreCommit1(ctx context.Context, sector storage.SectorRef, ticket abi.SealRandomness, pieces []abi.PieceInfo, opts storiface.PreCommit1Options) (storiface.CallID, error) {
 	return t.asyncCall(sector, func(ci storiface.CallID) {
 		t.pc1s++
+		t.pc1opts = append(t.pc1opts, opts)
 
 		if t.pc1wait != nil {
 			t.pc1wait.Done()
@@ -78,7 +80,7 @@ func (t *testWorker) SealPreCommit1(ctx context.Context, sector storage.SectorRe
 		t.pc1lk.Lock()
 		defer t.pc1lk.Unlock()
 
-		p1o, err := t.mockSeal.SealPreCommit1(ctx, sector, ticket, pieces)
+		p1o, err := t.mockSeal.SealPreCommit1(opts.Apply(ctx), sector, ticket, pieces)
 		if err := t.ret.ReturnSealPreCommit1(ctx, ci, p1o, toCallError(err)); err != nil {
 			log.Error(err)
 		}
@@ -116,10 +118,18 @@ func (t *testWorker) Info(ctx context.Context) (storiface.WorkerInfo, error) {
 	}, nil
 }
 
+func (t *testWorker) Utilization(context.Context) (storiface.WorkerUtilization, error) {
+	return storiface.WorkerUtilization{}, nil
+}
+
 func (t *testWorker) Session(context.Context) (uuid.UUID, error) {
 	return t.session, nil
 }
 
+func (t *testWorker) CachedResult(ctx context.Context, workID string) ([]byte, error) {
+	return nil, nil
+}
+
 func (t *testWorker) Close() error {
 	panic("implement me")
 }
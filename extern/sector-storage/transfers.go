@@ -0,0 +1,92 @@
+package sectorstorage
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/filecoin-project/go-state-types/abi"
+
+	"github.com/filecoin-project/lotus/extern/sector-storage/storiface"
+)
+
+// transferTracker limits the number of sectors being moved to long-term
+// storage at the same time, and keeps track of running moves
+type transferTracker struct {
+	throttle chan struct{} // nil when there's no limit
+
+	lk      sync.Mutex
+	active  map[abi.SectorID]time.Time
+	waiting int
+}
+
+func newTransferTracker(limit int) *transferTracker {
+	t := &transferTracker{
+		active: map[abi.SectorID]time.Time{},
+	}
+	if limit > 0 {
+		t.throttle = make(chan struct{}, limit)
+	}
+	return t
+}
+
+func (t *transferTracker) run(ctx context.Context, sector abi.SectorID, cb func() error) error {
+	if t.throttle != nil {
+		t.lk.Lock()
+		t.waiting++
+		t.lk.Unlock()
+
+		if len(t.throttle) >= cap(t.throttle) {
+			log.Infof("Throttling move of sector %d to storage, %d moves already running", sector.Number, len(t.throttle))
+		}
+
+		select {
+		case t.throttle <- struct{}{}:
+		case <-ctx.Done():
+			t.lk.Lock()
+			t.waiting--
+			t.lk.Unlock()
+			return ctx.Err()
+		}
+		defer func() { <-t.throttle }()
+
+		t.lk.Lock()
+		t.waiting--
+		t.lk.Unlock()
+	}
+
+	t.lk.Lock()
+	t.active[sector] = time.Now()
+	t.lk.Unlock()
+
+	defer func() {
+		t.lk.Lock()
+		delete(t.active, sector)
+		t.lk.Unlock()
+	}()
+
+	return cb()
+}
+
+func (t *transferTracker) stats() storiface.TransferStats {
+	t.lk.Lock()
+	defer t.lk.Unlock()
+
+	out := storiface.TransferStats{
+		MaxActive: cap(t.throttle),
+		Active:    make([]storiface.SectorTransfer, 0, len(t.active)),
+		Waiting:   t.waiting,
+	}
+	for sector, start := range t.active {
+		out.Active = append(out.Active, storiface.SectorTransfer{
+			Sector: sector,
+			Start:  start,
+		})
+	}
+	sort.Slice(out.Active, func(i, j int) bool {
+		return out.Active[i].Start.Before(out.Active[j].Start)
+	})
+
+	return out
+}
@@ -0,0 +1,98 @@
+package sectorstorage
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-state-types/abi"
+)
+
+func TestTransferTrackerThrottlesMoves(t *testing.T) {
+	const limit = 2
+	const moves = 5
+
+	tt := newTransferTracker(limit)
+	ctx := context.Background()
+
+	var running, maxRunning int32
+	release := make(chan struct{})
+
+	var wg sync.WaitGroup
+	for i := 0; i < moves; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			err := tt.run(ctx, abi.SectorID{Miner: 1000, Number: abi.SectorNumber(i)}, func() error {
+				n := atomic.AddInt32(&running, 1)
+				for {
+					m := atomic.LoadInt32(&maxRunning)
+					if n <= m || atomic.CompareAndSwapInt32(&maxRunning, m, n) {
+						break
+					}
+				}
+
+				<-release
+				atomic.AddInt32(&running, -1)
+				return nil
+			})
+			require.NoError(t, err)
+		}(i)
+	}
+
+	require.Eventually(t, func() bool {
+		st := tt.stats()
+		return len(st.Active) == limit && st.Waiting == moves-limit
+	}, 5*time.Second, 10*time.Millisecond)
+
+	st := tt.stats()
+	require.Equal(t, limit, st.MaxActive)
+	require.EqualValues(t, limit, atomic.LoadInt32(&running))
+
+	close(release)
+	wg.Wait()
+
+	require.EqualValues(t, limit, atomic.LoadInt32(&maxRunning), "more moves than the limit ran at the same time")
+
+	st = tt.stats()
+	require.Empty(t, st.Active)
+	require.Zero(t, st.Waiting)
+}
+
+func TestTransferTrackerCancelWaiting(t *testing.T) {
+	tt := newTransferTracker(1)
+
+	release := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = tt.run(context.Background(), abi.SectorID{Number: 1}, func() error {
+			<-release
+			return nil
+		})
+	}()
+
+	require.Eventually(t, func() bool {
+		return len(tt.stats().Active) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	ran := false
+	err := tt.run(ctx, abi.SectorID{Number: 2}, func() error {
+		ran = true
+		return nil
+	})
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.False(t, ran)
+	require.Zero(t, tt.stats().Waiting)
+
+	close(release)
+	<-done
+}
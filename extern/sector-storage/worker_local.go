@@ -12,6 +12,7 @@ import (
 	"time"
 
 	"github.com/elastic/go-sysinfo"
+	sysinfotypes "github.com/elastic/go-sysinfo/types"
 	"github.com/google/uuid"
 	"github.com/hashicorp/go-multierror"
 	"github.com/ipfs/go-cid"
@@ -55,10 +56,15 @@ type LocalWorker struct {
 	ignoreResources bool
 
 	ct          *workerCallTracker
+	results     *resultCache
 	acceptTasks map[sealtasks.TaskType]struct{}
 	running     sync.WaitGroup
 	taskLk      sync.Mutex
 
+	gpuTasks int64 // number of running tasks which can use the GPU
+	cpuLk    sync.Mutex
+	lastCPU  sysinfotypes.CPUTimes // cpu times at the last utilization sample
+
 	session     uuid.UUID
 	testDisable int64
 	closing     chan struct{}
@@ -79,6 +85,7 @@ func newLocalWorker(executor ExecutorFunc, wcfg WorkerConfig, store stores.Store
 		ct: &workerCallTracker{
 			st: cst,
 		},
+		results:         newResultCache(),
 		acceptTasks:     acceptTasks,
 		executor:        executor,
 		noSwap:          wcfg.NoSwap,
@@ -206,6 +213,12 @@ func rfunc(in interface{}) func(context.Context, storiface.CallID, storiface.Wor
 	}
 }
 
+// tasks which can make use of a GPU when one is available
+var gpuReturnTypes = map[ReturnType]struct{}{
+	SealPreCommit2: {},
+	SealCommit2:    {},
+}
+
 var returnFunc = map[ReturnType]func(context.Context, storiface.CallID, storiface.WorkerReturn, interface{}, *storiface.CallError) error{
 	AddPiece:        rfunc(storiface.WorkerReturn.ReturnAddPiece),
 	SealPreCommit1:  rfunc(storiface.WorkerReturn.ReturnSealPreCommit1),
@@ -231,8 +244,16 @@ func (l *LocalWorker) asyncCall(ctx context.Context, sector storage.SectorRef, r
 
 	l.running.Add(1)
 
+	_, gpuTask := gpuReturnTypes[rt]
+	if gpuTask {
+		atomic.AddInt64(&l.gpuTasks, 1)
+	}
+
 	go func() {
 		defer l.running.Done()
+		if gpuTask {
+			defer atomic.AddInt64(&l.gpuTasks, -1)
+		}
 
 		ctx := &wctx{
 			vals:    ctx,
@@ -326,10 +347,14 @@ func (l *LocalWorker) Fetch(ctx context.Context, sector storage.SectorRef, fileT
 	})
 }
 
-func (l *LocalWorker) SealPreCommit1(ctx context.Context, sector storage.SectorRef, ticket abi.SealRandomness, pieces []abi.PieceInfo) (storiface.CallID, error) {
+func (l *LocalWorker) SealPreCommit1(ctx context.Context, sector storage.SectorRef, ticket abi.SealRandomness, pieces []abi.PieceInfo, opts storiface.PreCommit1Options) (storiface.CallID, error) {
 	return l.asyncCall(ctx, sector, SealPreCommit1, func(ctx context.Context, ci storiface.CallID) (interface{}, error) {
 
 		{
+			// the sector files are recreated, results of tasks run on the
+			// previous files don't apply anymore
+			l.results.drop(sector.ID)
+
 			// cleanup previous failed attempts if they exist
 			if err := l.storage.Remove(ctx, sector.ID, storiface.FTSealed, true); err != nil {
 				return nil, xerrors.Errorf("cleaning up sealed data: %w", err)
@@ -345,7 +370,7 @@ func (l *LocalWorker) SealPreCommit1(ctx context.Context, sector storage.SectorR
 			return nil, err
 		}
 
-		return sb.SealPreCommit1(ctx, sector, ticket, pieces)
+		return sb.SealPreCommit1(opts.Apply(ctx), sector, ticket, pieces)
 	})
 }
 
@@ -355,9 +380,9 @@ func (l *LocalWorker) SealPreCommit2(ctx context.Context, sector storage.SectorR
 		return storiface.UndefCall, err
 	}
 
-	return l.asyncCall(ctx, sector, SealPreCommit2, func(ctx context.Context, ci storiface.CallID) (interface{}, error) {
+	return l.asyncCall(ctx, sector, SealPreCommit2, l.cachedWork(sector.ID, sealtasks.TTPreCommit2, []interface{}{sector, phase1Out}, &storage.SectorCids{}, func(ctx context.Context, ci storiface.CallID) (interface{}, error) {
 		return sb.SealPreCommit2(ctx, sector, phase1Out)
-	})
+	}))
 }
 
 func (l *LocalWorker) SealCommit1(ctx context.Context, sector storage.SectorRef, ticket abi.SealRandomness, seed abi.InteractiveSealRandomness, pieces []abi.PieceInfo, cids storage.SectorCids) (storiface.CallID, error) {
@@ -366,9 +391,9 @@ func (l *LocalWorker) SealCommit1(ctx context.Context, sector storage.SectorRef,
 		return storiface.UndefCall, err
 	}
 
-	return l.asyncCall(ctx, sector, SealCommit1, func(ctx context.Context, ci storiface.CallID) (interface{}, error) {
+	return l.asyncCall(ctx, sector, SealCommit1, l.cachedWork(sector.ID, sealtasks.TTCommit1, []interface{}{sector, ticket, seed, pieces, cids}, new(storage.Commit1Out), func(ctx context.Context, ci storiface.CallID) (interface{}, error) {
 		return sb.SealCommit1(ctx, sector, ticket, seed, pieces, cids)
-	})
+	}))
 }
 
 func (l *LocalWorker) SealCommit2(ctx context.Context, sector storage.SectorRef, phase1Out storage.Commit1Out) (storiface.CallID, error) {
@@ -377,9 +402,9 @@ func (l *LocalWorker) SealCommit2(ctx context.Context, sector storage.SectorRef,
 		return storiface.UndefCall, err
 	}
 
-	return l.asyncCall(ctx, sector, SealCommit2, func(ctx context.Context, ci storiface.CallID) (interface{}, error) {
+	return l.asyncCall(ctx, sector, SealCommit2, l.cachedWork(sector.ID, sealtasks.TTCommit2, []interface{}{sector, phase1Out}, new(storage.Proof), func(ctx context.Context, ci storiface.CallID) (interface{}, error) {
 		return sb.SealCommit2(ctx, sector, phase1Out)
-	})
+	}))
 }
 
 func (l *LocalWorker) FinalizeSector(ctx context.Context, sector storage.SectorRef, keepUnsealed []storage.Range) (storiface.CallID, error) {
@@ -408,6 +433,8 @@ func (l *LocalWorker) ReleaseUnsealed(ctx context.Context, sector storage.Sector
 }
 
 func (l *LocalWorker) Remove(ctx context.Context, sector abi.SectorID) error {
+	l.results.drop(sector)
+
 	var err error
 
 	if rerr := l.storage.Remove(ctx, sector, storiface.FTSealed, true); rerr != nil {
@@ -521,6 +548,62 @@ func (l *LocalWorker) Info(context.Context) (storiface.WorkerInfo, error) {
 	}, nil
 }
 
+func (l *LocalWorker) Utilization(context.Context) (storiface.WorkerUtilization, error) {
+	h, err := sysinfo.Host()
+	if err != nil {
+		return storiface.WorkerUtilization{}, xerrors.Errorf("getting host info: %w", err)
+	}
+
+	mem, err := h.Memory()
+	if err != nil {
+		return storiface.WorkerUtilization{}, xerrors.Errorf("getting memory info: %w", err)
+	}
+
+	cpu, err := h.CPUTime()
+	if err != nil {
+		return storiface.WorkerUtilization{}, xerrors.Errorf("getting cpu times: %w", err)
+	}
+
+	l.cpuLk.Lock()
+	prev := l.lastCPU
+	l.lastCPU = cpu
+	l.cpuLk.Unlock()
+
+	out := storiface.WorkerUtilization{
+		Sampled:     time.Now(),
+		MemUsed:     mem.Total - mem.Available,
+		MemPhysical: mem.Total,
+	}
+
+	// on the first sample prev is zero, which gives average usage since boot
+	if total := cpuTotal(cpu) - cpuTotal(prev); total > 0 {
+		out.CPU = float64(cpuBusy(cpu)-cpuBusy(prev)) / float64(total)
+	}
+
+	if gpuTasks := atomic.LoadInt64(&l.gpuTasks); gpuTasks > 0 {
+		gpus, err := ffi.GetGPUDevices()
+		if err != nil {
+			log.Errorf("getting gpu devices failed: %+v", err)
+		}
+		if len(gpus) > 0 {
+			out.GPU = float64(gpuTasks) / float64(len(gpus))
+			if out.GPU > 1 {
+				out.GPU = 1
+			}
+		}
+	}
+
+	return out, nil
+}
+
+func cpuBusy(t sysinfotypes.CPUTimes) time.Duration {
+	return t.User + t.System + t.Nice + t.IRQ + t.SoftIRQ + t.Steal
+}
+
+func cpuTotal(t sysinfotypes.CPUTimes) time.Duration {
+	return cpuBusy(t) + t.Idle + t.IOWait
+}
+
 func (l *LocalWorker) Session(ctx context.Context) (uuid.UUID, error) {
 	if atomic.LoadInt64(&l.testDisable) == 1 {
 		return uuid.UUID{}, xerrors.Errorf("disabled")
@@ -0,0 +1,211 @@
+package sectorstorage
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"sync"
+	"time"
+
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-state-types/abi"
+
+	"github.com/filecoin-project/lotus/extern/sector-storage/sealtasks"
+	"github.com/filecoin-project/lotus/extern/sector-storage/storiface"
+)
+
+// ResultCacheTTL is how long workers keep the results of completed tasks
+// which can be handed out again
+var ResultCacheTTL = 24 * time.Hour
+
+// how long the manager waits for each worker when asking for cached results
+var cachedResultTimeout = 5 * time.Second
+
+// resultCache keeps the results of completed idempotent tasks (PC2, C1, C2)
+// on the worker, keyed by the WorkID the manager tracks the task with. A
+// manager which restarted and lost track of a result can get it back instead
+// of running the task again.
+//
+// The results only depend on the task params and the sector files, so entries
+// for a sector are dropped when its files are recreated or removed.
+type resultCache struct {
+	lk  sync.Mutex
+	res map[abi.SectorID]map[string]cachedResult
+}
+
+type cachedResult struct {
+	res []byte // json
+	at  time.Time
+}
+
+func newResultCache() *resultCache {
+	return &resultCache{
+		res: map[abi.SectorID]map[string]cachedResult{},
+	}
+}
+
+func (c *resultCache) put(sector abi.SectorID, wid WorkID, res interface{}) {
+	rb, err := json.Marshal(res)
+	if err != nil {
+		log.Errorf("caching task result (marshaling): %+v", err)
+		return
+	}
+
+	c.lk.Lock()
+	defer c.lk.Unlock()
+
+	c.pruneLocked()
+
+	if c.res[sector] == nil {
+		c.res[sector] = map[string]cachedResult{}
+	}
+	c.res[sector][wid.String()] = cachedResult{
+		res: rb,
+		at:  time.Now(),
+	}
+}
+
+// get returns the json-encoded result of the task, nil if not cached
+func (c *resultCache) get(wid string) []byte {
+	c.lk.Lock()
+	defer c.lk.Unlock()
+
+	c.pruneLocked()
+
+	for _, rs := range c.res {
+		if r, ok := rs[wid]; ok {
+			return r.res
+		}
+	}
+	return nil
+}
+
+func (c *resultCache) drop(sector abi.SectorID) {
+	c.lk.Lock()
+	defer c.lk.Unlock()
+
+	delete(c.res, sector)
+}
+
+func (c *resultCache) pruneLocked() {
+	for sector, rs := range c.res {
+		for wid, r := range rs {
+			if time.Since(r.at) > ResultCacheTTL {
+				delete(rs, wid)
+			}
+		}
+		if len(rs) == 0 {
+			delete(c.res, sector)
+		}
+	}
+}
+
+// cachedWork wraps the work of an idempotent task, so that when the result for
+// the same task is cached, it's handed out instead of running the work again.
+// out is a pointer to the result type.
+func (l *LocalWorker) cachedWork(sector abi.SectorID, method sealtasks.TaskType, params []interface{}, out interface{}, work func(ctx context.Context, ci storiface.CallID) (interface{}, error)) func(ctx context.Context, ci storiface.CallID) (interface{}, error) {
+	// same as the WorkID computed in Manager.getWork
+	wid, err := newWorkID(method, params)
+	if err != nil {
+		log.Errorf("computing task WorkID: %+v", err)
+		return work
+	}
+
+	return func(ctx context.Context, ci storiface.CallID) (interface{}, error) {
+		if rb := l.results.get(wid.String()); rb != nil {
+			err := json.Unmarshal(rb, out)
+			if err == nil {
+				log.Infow("returning cached task result", "sector", sector, "task", method)
+				return reflect.ValueOf(out).Elem().Interface(), nil
+			}
+			log.Errorf("unmarshaling cached task result: %+v", err)
+		}
+
+		res, err := work(ctx, ci)
+		if err == nil {
+			l.results.put(sector, wid, res)
+		}
+		return res, err
+	}
+}
+
+func (l *LocalWorker) CachedResult(ctx context.Context, workID string) ([]byte, error) {
+	return l.results.get(workID), nil
+}
+
+// cachedResult asks the workers whether any of them holds the result of the
+// work, which can happen when the manager restarted after a worker returned
+// the result, but before the result was persisted by the caller. If a result
+// is found, it's decoded into out, and work tracking is ended.
+//
+// Workers are asked in parallel, so a slow or unreachable worker delays the
+// task by at most cachedResultTimeout.
+func (m *Manager) cachedResult(ctx context.Context, wid WorkID, out interface{}) bool {
+	m.sched.workersLk.RLock()
+	workers := make([]*workerHandle, 0, len(m.sched.workers))
+	for _, w := range m.sched.workers {
+		if w.enabled {
+			workers = append(workers, w)
+		}
+	}
+	m.sched.workersLk.RUnlock()
+
+	if len(workers) == 0 {
+		return false
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type workerResult struct {
+		w  *workerHandle
+		rb []byte
+	}
+
+	results := make(chan workerResult, len(workers))
+	for _, w := range workers {
+		go func(w *workerHandle) {
+			rb, err := m.queryCachedResult(ctx, w.workerRpc, wid)
+			if err != nil {
+				log.Debugw("asking worker for cached result", "worker", w.info.Hostname, "work", wid, "error", err)
+			}
+			results <- workerResult{w: w, rb: rb}
+		}(w)
+	}
+
+	for range workers {
+		r := <-results
+		if r.rb == nil {
+			continue
+		}
+
+		if err := json.Unmarshal(r.rb, out); err != nil {
+			log.Errorw("unmarshaling cached result", "worker", r.w.info.Hostname, "work", wid, "error", err)
+			continue
+		}
+
+		log.Infow("reusing task result cached on worker", "worker", r.w.info.Hostname, "work", wid)
+
+		m.workLk.Lock()
+		if err := m.work.Get(wid).End(); err != nil {
+			log.Errorf("marking work as done: %+v", err)
+		}
+		m.workLk.Unlock()
+
+		return true
+	}
+
+	return false
+}
+
+func (m *Manager) queryCachedResult(ctx context.Context, w Worker, wid WorkID) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, cachedResultTimeout)
+	defer cancel()
+
+	rb, err := w.CachedResult(ctx, wid.String())
+	if err != nil {
+		return nil, xerrors.Errorf("getting cached result: %w", err)
+	}
+	return rb, nil
+}
@@ -519,7 +519,7 @@ func (t *SectorInfo) MarshalCBOR(w io.Writer) error {
 		_, err := w.Write(cbg.CborNull)
 		return err
 	}
-	if _, err := w.Write([]byte{184, 26}); err != nil {
+	if _, err := w.Write([]byte{184, 27}); err != nil {
 		return err
 	}
 
@@ -586,6 +586,22 @@ func (t *SectorInfo) MarshalCBOR(w io.Writer) error {
 		}
 	}
 
+	// t.SyntheticPoRep (bool) (bool)
+	if len("SyntheticPoRep") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"SyntheticPoRep\" was too long")
+	}
+
+	if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajTextString, uint64(len("SyntheticPoRep"))); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, string("SyntheticPoRep")); err != nil {
+		return err
+	}
+
+	if err := cbg.WriteBool(w, t.SyntheticPoRep); err != nil {
+		return err
+	}
+
 	// t.CreationTime (int64) (int64)
 	if len("CreationTime") > cbg.MaxLength {
 		return xerrors.Errorf("Value in field \"CreationTime\" was too long")
@@ -1173,6 +1189,24 @@ func (t *SectorInfo) UnmarshalCBOR(r io.Reader) error {
 
 				t.SectorType = abi.RegisteredSealProof(extraI)
 			}
+			// t.SyntheticPoRep (bool) (bool)
+		case "SyntheticPoRep":
+
+			maj, extra, err = cbg.CborReadHeaderBuf(br, scratch)
+			if err != nil {
+				return err
+			}
+			if maj != cbg.MajOther {
+				return fmt.Errorf("booleans must be major type 7")
+			}
+			switch extra {
+			case 20:
+				t.SyntheticPoRep = false
+			case 21:
+				t.SyntheticPoRep = true
+			default:
+				return fmt.Errorf("booleans are either major type 7, value 20 or 21 (got %d)", extra)
+			}
 			// t.CreationTime (int64) (int64)
 		case "CreationTime":
 			{
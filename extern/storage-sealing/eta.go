@@ -0,0 +1,169 @@
+package sealing
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-state-types/abi"
+
+	"github.com/filecoin-project/lotus/build"
+)
+
+// DefaultSealDuration is the assumed time it takes to take a sector from
+// WaitDeals to Proving when there isn't any sealing history to go by
+const DefaultSealDuration = 8 * time.Hour
+
+// number of recently sealed sectors used to estimate sealing throughput
+const etaHistorySectors = 20
+
+var ErrNoSealETA = xerrors.New("sector isn't in the sealing pipeline")
+var ErrDealNotFound = xerrors.New("deal not found in any sector")
+
+// stageProgress is a rough measure of how far along the sealing pipeline a
+// sector in a given state is, as a fraction of total sealing time
+var stageProgress = map[SectorState]float64{
+	WaitDeals: 0,
+	AddPiece:  0,
+	Packing:   0.01,
+	GetTicket: 0.02,
+
+	PreCommit1: 0.02,
+	PreCommit2: 0.55,
+
+	PreCommitting:        0.65,
+	PreCommitWait:        0.66,
+	SubmitPreCommitBatch: 0.65,
+	PreCommitBatchWait:   0.66,
+
+	WaitSeed:              0.7,
+	Committing:            0.85,
+	CommitFinalize:        0.93,
+	SubmitCommit:          0.94,
+	CommitWait:            0.95,
+	SubmitCommitAggregate: 0.94,
+	CommitAggregateWait:   0.95,
+
+	FinalizeSector: 0.98,
+	FinalizeReady:  0.98,
+	Proving:        1,
+
+	// failed states are usually retried, so assume the sector is roughly
+	// where it was when it failed
+	AddPieceFailed:       0,
+	SealPreCommit1Failed: 0.02,
+	SealPreCommit2Failed: 0.55,
+	PreCommitFailed:      0.65,
+	ComputeProofFailed:   0.85,
+	CommitFinalizeFailed: 0.93,
+	CommitFailed:         0.94,
+	FinalizeFailed:       0.98,
+}
+
+// SectorSealETA returns the epoch at which the sector is expected to reach
+// the Proving state, based on its current state and the time it took to seal
+// recent sectors
+func (m *Sealing) SectorSealETA(ctx context.Context, sid abi.SectorNumber) (abi.ChainEpoch, error) {
+	sectors, err := m.ListSectors()
+	if err != nil {
+		return 0, xerrors.Errorf("listing sectors: %w", err)
+	}
+
+	for _, sector := range sectors {
+		if sector.SectorNumber == sid {
+			return m.sealETA(ctx, sector, sectors)
+		}
+	}
+
+	return 0, xerrors.Errorf("sector %d not found", sid)
+}
+
+// DealSealETA finds the sector the deal was placed in, and returns the sector
+// number along with the sealing ETA of that sector
+func (m *Sealing) DealSealETA(ctx context.Context, deal abi.DealID) (abi.SectorNumber, abi.ChainEpoch, error) {
+	sectors, err := m.ListSectors()
+	if err != nil {
+		return 0, 0, xerrors.Errorf("listing sectors: %w", err)
+	}
+
+	for _, sector := range sectors {
+		for _, id := range sector.dealIDs() {
+			if id != deal {
+				continue
+			}
+
+			eta, err := m.sealETA(ctx, sector, sectors)
+			return sector.SectorNumber, eta, err
+		}
+	}
+
+	return 0, 0, xerrors.Errorf("deal %d: %w", deal, ErrDealNotFound)
+}
+
+func (m *Sealing) sealETA(ctx context.Context, sector SectorInfo, all []SectorInfo) (abi.ChainEpoch, error) {
+	_, curEpoch, err := m.api.ChainHead(ctx)
+	if err != nil {
+		return 0, xerrors.Errorf("getting chain head: %w", err)
+	}
+
+	return estimateSealETA(curEpoch, SectorState(sector.State), sealDuration(all))
+}
+
+// estimateSealETA estimates the epoch at which a sector in the given state
+// will be proving, given the time it takes to seal a sector end-to-end
+func estimateSealETA(curEpoch abi.ChainEpoch, state SectorState, sealTime time.Duration) (abi.ChainEpoch, error) {
+	progress, ok := stageProgress[state]
+	if !ok {
+		return 0, xerrors.Errorf("sector in state %s: %w", state, ErrNoSealETA)
+	}
+
+	remaining := time.Duration(float64(sealTime) * (1 - progress))
+	return curEpoch + abi.ChainEpoch(remaining/(time.Duration(build.BlockDelaySecs)*time.Second)), nil
+}
+
+// sealDuration returns the average time it took to seal the most recently
+// finished sectors, falling back to DefaultSealDuration without any history
+func sealDuration(sectors []SectorInfo) time.Duration {
+	type sealed struct {
+		finished uint64
+		took     uint64
+	}
+	var history []sealed
+
+	for _, sector := range sectors {
+		for _, l := range sector.Log {
+			if l.Kind != sectorFinalizedKind {
+				continue
+			}
+
+			history = append(history, sealed{
+				finished: l.Timestamp,
+				took:     l.Timestamp - sector.Log[0].Timestamp,
+			})
+			break
+		}
+	}
+
+	if len(history) == 0 {
+		return DefaultSealDuration
+	}
+
+	sort.Slice(history, func(i, j int) bool {
+		return history[i].finished > history[j].finished
+	})
+	if len(history) > etaHistorySectors {
+		history = history[:etaHistorySectors]
+	}
+
+	var total uint64
+	for _, h := range history {
+		total += h.took
+	}
+
+	return time.Duration(total/uint64(len(history))) * time.Second
+}
+
+var sectorFinalizedKind = fmt.Sprintf("event;%T", SectorFinalized{})
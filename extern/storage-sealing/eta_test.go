@@ -0,0 +1,65 @@
+package sealing
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-state-types/abi"
+)
+
+func TestSealETADecreases(t *testing.T) {
+	const cur = abi.ChainEpoch(1000)
+
+	pipeline := []SectorState{
+		WaitDeals,
+		Packing,
+		PreCommit1,
+		PreCommit2,
+		PreCommitting,
+		WaitSeed,
+		Committing,
+		CommitWait,
+		FinalizeSector,
+		Proving,
+	}
+
+	last := abi.ChainEpoch(-1)
+	for i, st := range pipeline {
+		eta, err := estimateSealETA(cur, st, DefaultSealDuration)
+		require.NoError(t, err)
+
+		if i > 0 {
+			require.Less(t, int64(eta), int64(last), "ETA in %s should be before ETA in %s", st, pipeline[i-1])
+		}
+		last = eta
+	}
+
+	require.Equal(t, cur, last, "proving sectors are sealed")
+
+	_, err := estimateSealETA(cur, Removed, DefaultSealDuration)
+	require.ErrorIs(t, err, ErrNoSealETA)
+}
+
+func TestSealDuration(t *testing.T) {
+	require.Equal(t, DefaultSealDuration, sealDuration(nil))
+
+	sector := func(start, took uint64) SectorInfo {
+		return SectorInfo{
+			Log: []Log{
+				{Timestamp: start, Kind: "event;sealing.SectorStart"},
+				{Timestamp: start + took/2, Kind: "event;sealing.SectorPreCommit2"},
+				{Timestamp: start + took, Kind: sectorFinalizedKind},
+			},
+		}
+	}
+
+	sectors := []SectorInfo{
+		sector(100, 3600),
+		sector(200, 7200),
+		{Log: []Log{{Timestamp: 300, Kind: "event;sealing.SectorStart"}}}, // still sealing
+	}
+
+	require.Equal(t, 90*time.Minute, sealDuration(sectors))
+}
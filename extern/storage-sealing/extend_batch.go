@@ -0,0 +1,314 @@
+package sealing
+
+import (
+	"bytes"
+	"context"
+	"sort"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-bitfield"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
+	"github.com/filecoin-project/go-state-types/dline"
+	"github.com/filecoin-project/go-state-types/network"
+	miner5 "github.com/filecoin-project/specs-actors/v5/actors/builtin/miner"
+
+	"github.com/filecoin-project/lotus/build"
+	"github.com/filecoin-project/lotus/chain/actors/builtin/miner"
+	"github.com/filecoin-project/lotus/chain/actors/policy"
+	"github.com/filecoin-project/lotus/extern/storage-sealing/sealiface"
+	"github.com/filecoin-project/lotus/node/config"
+)
+
+// used when AutoExtendCheckInterval isn't set
+const defaultExtendCheckInterval = time.Hour
+
+type SectorExtenderApi interface {
+	ChainHead(ctx context.Context) (TipSetToken, abi.ChainEpoch, error)
+	StateNetworkVersion(ctx context.Context, tok TipSetToken) (network.Version, error)
+	StateMinerActiveSectors(ctx context.Context, maddr address.Address, tok TipSetToken) ([]*miner.SectorOnChainInfo, error)
+	StateSectorGetInfo(ctx context.Context, maddr address.Address, sectorNumber abi.SectorNumber, tok TipSetToken) (*miner.SectorOnChainInfo, error)
+	StateSectorPartition(ctx context.Context, maddr address.Address, sectorNumber abi.SectorNumber, tok TipSetToken) (*SectorLocation, error)
+	StateMinerProvingDeadline(context.Context, address.Address, TipSetToken) (*dline.Info, error)
+	StateMinerInfo(context.Context, address.Address, TipSetToken) (miner.MinerInfo, error)
+	SendMsg(ctx context.Context, from, to address.Address, method abi.MethodNum, value, maxFee abi.TokenAmount, params []byte) (cid.Cid, error)
+}
+
+// SectorExtender periodically extends the expiration of sectors which are
+// about to expire, so that the power they provide doesn't drop off
+type SectorExtender struct {
+	api       SectorExtenderApi
+	maddr     address.Address
+	mctx      context.Context
+	feeCfg    config.MinerFeeConfig
+	getConfig GetSealingConfigFunc
+
+	stop, stopped chan struct{}
+}
+
+func NewSectorExtender(mctx context.Context, maddr address.Address, api SectorExtenderApi, feeCfg config.MinerFeeConfig, getConfig GetSealingConfigFunc) *SectorExtender {
+	e := &SectorExtender{
+		api:       api,
+		maddr:     maddr,
+		mctx:      mctx,
+		feeCfg:    feeCfg,
+		getConfig: getConfig,
+
+		stop:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+
+	go e.run()
+
+	return e
+}
+
+func (e *SectorExtender) run() {
+	for {
+		cfg, err := e.getConfig()
+		if err != nil {
+			log.Warnw("SectorExtender getconfig error", "error", err)
+		}
+
+		interval := cfg.AutoExtendCheckInterval
+		if interval <= 0 {
+			interval = defaultExtendCheckInterval
+		}
+
+		select {
+		case <-e.stop:
+			close(e.stopped)
+			return
+		case <-time.After(interval):
+		}
+
+		if !cfg.AutoExtendSectors {
+			continue
+		}
+
+		if _, err := e.extendSectors(cfg); err != nil {
+			log.Warnw("SectorExtender extendSectors error", "error", err)
+		}
+	}
+}
+
+func durationEpochs(d time.Duration) abi.ChainEpoch {
+	return abi.ChainEpoch(d / (time.Duration(build.BlockDelaySecs) * time.Second))
+}
+
+// extendSectors sends ExtendSectorExpiration messages for all sectors which
+// expire within the configured window
+func (e *SectorExtender) extendSectors(cfg sealiface.Config) ([]cid.Cid, error) {
+	tok, head, err := e.api.ChainHead(e.mctx)
+	if err != nil {
+		return nil, xerrors.Errorf("getting chain head: %w", err)
+	}
+
+	nv, err := e.api.StateNetworkVersion(e.mctx, tok)
+	if err != nil {
+		return nil, xerrors.Errorf("getting network version: %w", err)
+	}
+
+	di, err := e.api.StateMinerProvingDeadline(e.mctx, e.maddr, tok)
+	if err != nil {
+		return nil, xerrors.Errorf("getting proving deadline info: %w", err)
+	}
+
+	sectors, err := e.api.StateMinerActiveSectors(e.mctx, e.maddr, tok)
+	if err != nil {
+		return nil, xerrors.Errorf("getting active sectors: %w", err)
+	}
+
+	target := head + durationEpochs(cfg.AutoExtendDuration)
+	if maxExp := head + policy.GetMaxSectorExpirationExtension(); target > maxExp {
+		target = maxExp
+	}
+
+	type extension struct {
+		loc    SectorLocation
+		newExp abi.ChainEpoch
+	}
+	todo := map[extension][]uint64{}
+
+	for _, si := range sectors {
+		if si.Expiration > head+durationEpochs(cfg.AutoExtendWindow) {
+			continue
+		}
+
+		if len(si.DealIDs) > 0 && !cfg.AutoExtendDealSectors {
+			continue
+		}
+
+		newExp := target
+		if maxExp := si.Activation + policy.GetSectorMaxLifetime(si.SealProof, nv); newExp > maxExp {
+			newExp = maxExp
+		}
+		if newExp <= si.Expiration {
+			continue // already at max lifetime
+		}
+
+		loc, err := e.api.StateSectorPartition(e.mctx, e.maddr, si.SectorNumber, tok)
+		if err != nil {
+			return nil, xerrors.Errorf("getting sector location for sector %d: %w", si.SectorNumber, err)
+		}
+		if loc == nil {
+			continue
+		}
+
+		// the current and next deadlines can't be modified, sectors in them
+		// will get extended in a later pass
+		if loc.Deadline == di.Index || loc.Deadline == (di.Index+1)%miner.WPoStPeriodDeadlines {
+			continue
+		}
+
+		ext := extension{loc: *loc, newExp: newExp}
+		todo[ext] = append(todo[ext], uint64(si.SectorNumber))
+	}
+
+	if len(todo) == 0 {
+		return nil, nil
+	}
+
+	exts := make([]extension, 0, len(todo))
+	for ext := range todo {
+		exts = append(exts, ext)
+	}
+	sort.Slice(exts, func(i, j int) bool {
+		if exts[i].loc.Deadline != exts[j].loc.Deadline {
+			return exts[i].loc.Deadline < exts[j].loc.Deadline
+		}
+		if exts[i].loc.Partition != exts[j].loc.Partition {
+			return exts[i].loc.Partition < exts[j].loc.Partition
+		}
+		return exts[i].newExp < exts[j].newExp
+	})
+
+	var params []miner5.ExtendSectorExpirationParams
+	var p miner5.ExtendSectorExpirationParams
+	var scount int
+	for _, ext := range exts {
+		numbers := todo[ext]
+		// a message holds at least one extension, even if the sectors of the
+		// extension alone are above the limit
+		full := scount+len(numbers) > policy.GetAddressedSectorsMax(nv) || len(p.Extensions) == policy.GetDeclarationsMax(nv)
+		if full && len(p.Extensions) > 0 {
+			params = append(params, p)
+			p = miner5.ExtendSectorExpirationParams{}
+			scount = 0
+		}
+		scount += len(numbers)
+
+		p.Extensions = append(p.Extensions, miner5.ExpirationExtension{
+			Deadline:      ext.loc.Deadline,
+			Partition:     ext.loc.Partition,
+			Sectors:       bitfield.NewFromSet(numbers),
+			NewExpiration: ext.newExp,
+		})
+	}
+	params = append(params, p)
+
+	return e.sendExtensions(e.mctx, tok, params)
+}
+
+// ExtendSector sends an ExtendSectorExpiration message extending the
+// expiration of a single sector to newExp. Sectors in the current or next
+// deadline can't be extended until the deadline has been proven.
+func (e *SectorExtender) ExtendSector(ctx context.Context, sid abi.SectorNumber, newExp abi.ChainEpoch) (cid.Cid, error) {
+	tok, head, err := e.api.ChainHead(ctx)
+	if err != nil {
+		return cid.Undef, xerrors.Errorf("getting chain head: %w", err)
+	}
+
+	nv, err := e.api.StateNetworkVersion(ctx, tok)
+	if err != nil {
+		return cid.Undef, xerrors.Errorf("getting network version: %w", err)
+	}
+
+	si, err := e.api.StateSectorGetInfo(ctx, e.maddr, sid, tok)
+	if err != nil {
+		return cid.Undef, xerrors.Errorf("getting sector info: %w", err)
+	}
+	if si == nil {
+		return cid.Undef, xerrors.Errorf("sector %d not found on chain", sid)
+	}
+
+	if newExp <= si.Expiration {
+		return cid.Undef, xerrors.Errorf("new expiration %d must be after the current expiration %d of sector %d", newExp, si.Expiration, sid)
+	}
+	if maxExp := si.Activation + policy.GetSectorMaxLifetime(si.SealProof, nv); newExp > maxExp {
+		return cid.Undef, xerrors.Errorf("new expiration %d exceeds the max lifetime of sector %d, which expires at %d at the latest", newExp, sid, maxExp)
+	}
+	if maxExp := head + policy.GetMaxSectorExpirationExtension(); newExp > maxExp {
+		return cid.Undef, xerrors.Errorf("new expiration %d is more than the max extension of %d epochs after the current epoch %d", newExp, policy.GetMaxSectorExpirationExtension(), head)
+	}
+
+	loc, err := e.api.StateSectorPartition(ctx, e.maddr, sid, tok)
+	if err != nil {
+		return cid.Undef, xerrors.Errorf("getting sector location for sector %d: %w", sid, err)
+	}
+	if loc == nil {
+		return cid.Undef, xerrors.Errorf("sector %d not found in any partition", sid)
+	}
+
+	di, err := e.api.StateMinerProvingDeadline(ctx, e.maddr, tok)
+	if err != nil {
+		return cid.Undef, xerrors.Errorf("getting proving deadline info: %w", err)
+	}
+	if loc.Deadline == di.Index || loc.Deadline == (di.Index+1)%miner.WPoStPeriodDeadlines {
+		return cid.Undef, xerrors.Errorf("sector %d is in deadline %d, which is being proven and can't be modified, retry after it's proven", sid, loc.Deadline)
+	}
+
+	msgs, err := e.sendExtensions(ctx, tok, []miner5.ExtendSectorExpirationParams{{
+		Extensions: []miner5.ExpirationExtension{{
+			Deadline:      loc.Deadline,
+			Partition:     loc.Partition,
+			Sectors:       bitfield.NewFromSet([]uint64{uint64(sid)}),
+			NewExpiration: newExp,
+		}},
+	}})
+	if err != nil {
+		return cid.Undef, err
+	}
+
+	return msgs[0], nil
+}
+
+func (e *SectorExtender) sendExtensions(ctx context.Context, tok TipSetToken, params []miner5.ExtendSectorExpirationParams) ([]cid.Cid, error) {
+	mi, err := e.api.StateMinerInfo(ctx, e.maddr, tok)
+	if err != nil {
+		return nil, xerrors.Errorf("couldn't get miner info: %w", err)
+	}
+
+	var out []cid.Cid
+	for i := range params {
+		enc := new(bytes.Buffer)
+		if err := params[i].MarshalCBOR(enc); err != nil {
+			return out, xerrors.Errorf("couldn't serialize ExtendSectorExpiration params: %w", err)
+		}
+
+		mcid, err := e.api.SendMsg(ctx, mi.Worker, e.maddr, miner.Methods.ExtendSectorExpiration, big.Zero(), big.Int(e.feeCfg.MaxExtendGasFee), enc.Bytes())
+		if err != nil {
+			return out, xerrors.Errorf("sending message failed: %w", err)
+		}
+		log.Infow("Sent ExtendSectorExpiration message", "cid", mcid, "extensions", len(params[i].Extensions))
+
+		out = append(out, mcid)
+	}
+
+	return out, nil
+}
+
+func (e *SectorExtender) Stop(ctx context.Context) error {
+	close(e.stop)
+
+	select {
+	case <-e.stopped:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,274 @@
+package sealing
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/dline"
+	"github.com/filecoin-project/go-state-types/network"
+	miner5 "github.com/filecoin-project/specs-actors/v5/actors/builtin/miner"
+
+	"github.com/filecoin-project/lotus/chain/actors/builtin/miner"
+	"github.com/filecoin-project/lotus/chain/actors/policy"
+	"github.com/filecoin-project/lotus/extern/storage-sealing/sealiface"
+	"github.com/filecoin-project/lotus/node/config"
+)
+
+type testExtenderApi struct {
+	head    abi.ChainEpoch
+	dlIdx   uint64
+	sectors []*miner.SectorOnChainInfo
+	locs    map[abi.SectorNumber]SectorLocation
+
+	sent []miner5.ExtendSectorExpirationParams
+}
+
+func (a *testExtenderApi) ChainHead(ctx context.Context) (TipSetToken, abi.ChainEpoch, error) {
+	return nil, a.head, nil
+}
+
+func (a *testExtenderApi) StateNetworkVersion(ctx context.Context, tok TipSetToken) (network.Version, error) {
+	return network.Version13, nil
+}
+
+func (a *testExtenderApi) StateMinerActiveSectors(ctx context.Context, maddr address.Address, tok TipSetToken) ([]*miner.SectorOnChainInfo, error) {
+	return a.sectors, nil
+}
+
+func (a *testExtenderApi) StateSectorGetInfo(ctx context.Context, maddr address.Address, sectorNumber abi.SectorNumber, tok TipSetToken) (*miner.SectorOnChainInfo, error) {
+	for _, si := range a.sectors {
+		if si.SectorNumber == sectorNumber {
+			return si, nil
+		}
+	}
+	return nil, nil
+}
+
+func (a *testExtenderApi) StateSectorPartition(ctx context.Context, maddr address.Address, sectorNumber abi.SectorNumber, tok TipSetToken) (*SectorLocation, error) {
+	loc, ok := a.locs[sectorNumber]
+	if !ok {
+		return nil, nil
+	}
+	return &loc, nil
+}
+
+func (a *testExtenderApi) StateMinerProvingDeadline(context.Context, address.Address, TipSetToken) (*dline.Info, error) {
+	return &dline.Info{Index: a.dlIdx}, nil
+}
+
+func (a *testExtenderApi) StateMinerInfo(context.Context, address.Address, TipSetToken) (miner.MinerInfo, error) {
+	return miner.MinerInfo{}, nil
+}
+
+func (a *testExtenderApi) SendMsg(ctx context.Context, from, to address.Address, method abi.MethodNum, value, maxFee abi.TokenAmount, params []byte) (cid.Cid, error) {
+	var p miner5.ExtendSectorExpirationParams
+	if err := p.UnmarshalCBOR(bytes.NewReader(params)); err != nil {
+		return cid.Undef, err
+	}
+	a.sent = append(a.sent, p)
+	return cid.Undef, nil
+}
+
+func TestAutoExtendSectors(t *testing.T) {
+	const head = abi.ChainEpoch(100000)
+	spt := abi.RegisteredSealProof_StackedDrg32GiBV1_1
+	maxLifetime := policy.GetSectorMaxLifetime(spt, network.Version13)
+
+	cfg := sealiface.Config{
+		AutoExtendSectors:  true,
+		AutoExtendWindow:   24 * time.Hour,
+		AutoExtendDuration: 180 * 24 * time.Hour,
+	}
+	window := durationEpochs(cfg.AutoExtendWindow)
+	target := head + durationEpochs(cfg.AutoExtendDuration)
+
+	api := &testExtenderApi{
+		head:  head,
+		dlIdx: 10,
+		sectors: []*miner.SectorOnChainInfo{
+			// expiring CC sector
+			{SectorNumber: 1, SealProof: spt, Activation: 0, Expiration: head + window/2},
+			// CC sector expiring after the window
+			{SectorNumber: 2, SealProof: spt, Activation: 0, Expiration: head + 2*window},
+			// expiring deal sector
+			{SectorNumber: 3, SealProof: spt, Activation: 0, Expiration: head + window/2, DealIDs: []abi.DealID{1}},
+			// expiring CC sector in the current deadline
+			{SectorNumber: 4, SealProof: spt, Activation: 0, Expiration: head + window/2},
+			// expiring CC sector which is already at its max lifetime
+			{SectorNumber: 5, SealProof: spt, Activation: head + window/2 - maxLifetime, Expiration: head + window/2},
+			// expiring CC sector which can't reach the target expiration
+			{SectorNumber: 6, SealProof: spt, Activation: head + 1000 - maxLifetime, Expiration: head + 500},
+		},
+		locs: map[abi.SectorNumber]SectorLocation{
+			1: {Deadline: 3, Partition: 0},
+			2: {Deadline: 3, Partition: 0},
+			3: {Deadline: 3, Partition: 0},
+			4: {Deadline: 10, Partition: 0},
+			5: {Deadline: 3, Partition: 0},
+			6: {Deadline: 4, Partition: 1},
+		},
+	}
+
+	e := &SectorExtender{
+		api:    api,
+		mctx:   context.Background(),
+		feeCfg: config.MinerFeeConfig{},
+	}
+
+	msgs, err := e.extendSectors(cfg)
+	require.NoError(t, err)
+	require.Len(t, msgs, 1)
+	require.Len(t, api.sent, 1)
+
+	exts := api.sent[0].Extensions
+	require.Len(t, exts, 2)
+
+	require.Equal(t, uint64(3), exts[0].Deadline)
+	require.Equal(t, target, exts[0].NewExpiration)
+	sectors, err := exts[0].Sectors.All(10)
+	require.NoError(t, err)
+	require.Equal(t, []uint64{1}, sectors)
+
+	require.Equal(t, uint64(4), exts[1].Deadline)
+	require.Equal(t, uint64(1), exts[1].Partition)
+	require.Equal(t, head+1000, exts[1].NewExpiration, "extension must be capped at max sector lifetime")
+	sectors, err = exts[1].Sectors.All(10)
+	require.NoError(t, err)
+	require.Equal(t, []uint64{6}, sectors)
+
+	// deal sectors only get extended when enabled
+	api.sent = nil
+	cfg.AutoExtendDealSectors = true
+
+	_, err = e.extendSectors(cfg)
+	require.NoError(t, err)
+	require.Len(t, api.sent, 1)
+
+	sectors, err = api.sent[0].Extensions[0].Sectors.All(10)
+	require.NoError(t, err)
+	require.Equal(t, []uint64{1, 3}, sectors)
+}
+
+func TestAutoExtendSectorsBatching(t *testing.T) {
+	const head = abi.ChainEpoch(100000)
+	spt := abi.RegisteredSealProof_StackedDrg32GiBV1_1
+	maxSectors := policy.GetAddressedSectorsMax(network.Version13)
+
+	cfg := sealiface.Config{
+		AutoExtendSectors:  true,
+		AutoExtendWindow:   24 * time.Hour,
+		AutoExtendDuration: 180 * 24 * time.Hour,
+	}
+
+	api := &testExtenderApi{
+		head:  head,
+		dlIdx: 10,
+		locs:  map[abi.SectorNumber]SectorLocation{},
+	}
+
+	// the first extension alone addresses more sectors than a message may
+	add := func(sn abi.SectorNumber, loc SectorLocation) {
+		api.sectors = append(api.sectors, &miner.SectorOnChainInfo{SectorNumber: sn, SealProof: spt, Expiration: head + 1000})
+		api.locs[sn] = loc
+	}
+	for sn := abi.SectorNumber(0); sn <= abi.SectorNumber(maxSectors); sn++ {
+		add(sn, SectorLocation{Deadline: 3, Partition: 0})
+	}
+	add(abi.SectorNumber(maxSectors+1), SectorLocation{Deadline: 4, Partition: 0})
+
+	e := &SectorExtender{
+		api:    api,
+		mctx:   context.Background(),
+		feeCfg: config.MinerFeeConfig{},
+	}
+
+	msgs, err := e.extendSectors(cfg)
+	require.NoError(t, err)
+	require.Len(t, msgs, 2)
+	require.Len(t, api.sent, 2)
+
+	// no message is sent without extensions
+	require.Len(t, api.sent[0].Extensions, 1)
+	require.Equal(t, uint64(3), api.sent[0].Extensions[0].Deadline)
+	count, err := api.sent[0].Extensions[0].Sectors.Count()
+	require.NoError(t, err)
+	require.EqualValues(t, maxSectors+1, count)
+
+	require.Len(t, api.sent[1].Extensions, 1)
+	require.Equal(t, uint64(4), api.sent[1].Extensions[0].Deadline)
+}
+
+func TestExtendSector(t *testing.T) {
+	const head = abi.ChainEpoch(100000)
+	spt := abi.RegisteredSealProof_StackedDrg32GiBV1_1
+	maxLifetime := policy.GetSectorMaxLifetime(spt, network.Version13)
+
+	api := &testExtenderApi{
+		head:  head,
+		dlIdx: 10,
+		sectors: []*miner.SectorOnChainInfo{
+			{SectorNumber: 1, SealProof: spt, Activation: 0, Expiration: head + 1000},
+			// in the current deadline
+			{SectorNumber: 2, SealProof: spt, Activation: 0, Expiration: head + 1000},
+			// close to its max lifetime
+			{SectorNumber: 3, SealProof: spt, Activation: head + 2000 - maxLifetime, Expiration: head + 1000},
+		},
+		locs: map[abi.SectorNumber]SectorLocation{
+			1: {Deadline: 3, Partition: 1},
+			2: {Deadline: 11, Partition: 0},
+			3: {Deadline: 3, Partition: 0},
+		},
+	}
+
+	e := &SectorExtender{
+		api:    api,
+		mctx:   context.Background(),
+		feeCfg: config.MinerFeeConfig{},
+	}
+	ctx := context.Background()
+
+	_, err := e.ExtendSector(ctx, 1, head+5000)
+	require.NoError(t, err)
+	require.Len(t, api.sent, 1)
+	require.Len(t, api.sent[0].Extensions, 1)
+
+	ext := api.sent[0].Extensions[0]
+	require.Equal(t, uint64(3), ext.Deadline)
+	require.Equal(t, uint64(1), ext.Partition)
+	require.Equal(t, head+5000, ext.NewExpiration)
+	sectors, err := ext.Sectors.All(10)
+	require.NoError(t, err)
+	require.Equal(t, []uint64{1}, sectors)
+
+	// the expiration can't be reduced
+	_, err = e.ExtendSector(ctx, 1, head+500)
+	require.Error(t, err)
+
+	// or go past the max extension
+	_, err = e.ExtendSector(ctx, 1, head+policy.GetMaxSectorExpirationExtension()+1)
+	require.Error(t, err)
+
+	// the next deadline can't be modified
+	_, err = e.ExtendSector(ctx, 2, head+5000)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "is being proven")
+
+	// or the sector's max lifetime exceeded
+	_, err = e.ExtendSector(ctx, 3, head+2001)
+	require.Error(t, err)
+	_, err = e.ExtendSector(ctx, 3, head+2000)
+	require.NoError(t, err)
+
+	// unknown sectors
+	_, err = e.ExtendSector(ctx, 4, head+5000)
+	require.Error(t, err)
+
+	require.Len(t, api.sent, 2)
+}
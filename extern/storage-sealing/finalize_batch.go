@@ -0,0 +1,203 @@
+package sealing
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+)
+
+// how often the config is re-read when sectors aren't released on a schedule
+const finalizeConfigRecheck = time.Minute
+
+// FinalizeBatcher tracks sectors in the FinalizeReady state when
+// DeferFinalize is enabled, and releases them to be finalized and moved to
+// long-term storage in batches, by sending them SectorFinalizeReleased. Until
+// a sector is released it's proven from the sealing storage it was sealed in.
+type FinalizeBatcher struct {
+	maddr     address.Address
+	getConfig GetSealingConfigFunc
+	send      func(abi.SectorNumber) error
+
+	lk       sync.Mutex
+	waiting  map[abi.SectorNumber]struct{}
+	released map[abi.SectorNumber]struct{}
+
+	force         chan chan []abi.SectorNumber
+	stop, stopped chan struct{}
+}
+
+func NewFinalizeBatcher(maddr address.Address, getConfig GetSealingConfigFunc, send func(abi.SectorNumber) error) *FinalizeBatcher {
+	b := &FinalizeBatcher{
+		maddr:     maddr,
+		getConfig: getConfig,
+		send:      send,
+
+		waiting:  map[abi.SectorNumber]struct{}{},
+		released: map[abi.SectorNumber]struct{}{},
+
+		force:   make(chan chan []abi.SectorNumber),
+		stop:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+
+	go b.run()
+
+	return b
+}
+
+func (b *FinalizeBatcher) run() {
+	for {
+		cfg, err := b.getConfig()
+		if err != nil {
+			log.Warnw("FinalizeBatcher getconfig error", "error", err)
+		}
+
+		// with no interval set sectors are only released by Flush, the config
+		// is still re-read periodically to pick up changes
+		interval := cfg.FinalizeBatchInterval
+		scheduled := cfg.DeferFinalize && interval > 0
+		if !scheduled {
+			interval = finalizeConfigRecheck
+		}
+
+		select {
+		case <-b.stop:
+			close(b.stopped)
+			return
+		case fr := <-b.force:
+			fr <- b.release(0)
+		case <-time.After(interval):
+			if !scheduled {
+				continue
+			}
+			if released := b.release(cfg.FinalizeBatchSize); len(released) > 0 {
+				log.Infow("releasing batch of sectors to finalize", "sectors", released)
+			}
+		}
+	}
+}
+
+// release lets up to max (0 = all) waiting sectors, lowest numbers first,
+// continue to FinalizeSector
+func (b *FinalizeBatcher) release(max int) []abi.SectorNumber {
+	b.lk.Lock()
+
+	sns := make([]abi.SectorNumber, 0, len(b.waiting))
+	for sn := range b.waiting {
+		sns = append(sns, sn)
+	}
+	sort.Slice(sns, func(i, j int) bool {
+		return sns[i] < sns[j]
+	})
+
+	if max > 0 && len(sns) > max {
+		sns = sns[:max]
+	}
+
+	for _, sn := range sns {
+		delete(b.waiting, sn)
+		b.released[sn] = struct{}{}
+	}
+
+	b.lk.Unlock()
+
+	for _, sn := range sns {
+		if err := b.send(sn); err != nil {
+			log.Errorw("releasing sector to finalize", "sector", sn, "error", err)
+		}
+	}
+
+	return sns
+}
+
+// TakeReleased returns whether the sector was released to be finalized, and
+// clears the release, so that a later finalize, e.g. when retrying a failed
+// one, waits for the next batch
+func (b *FinalizeBatcher) TakeReleased(sn abi.SectorNumber) bool {
+	b.lk.Lock()
+	defer b.lk.Unlock()
+
+	_, ok := b.released[sn]
+	delete(b.released, sn)
+	return ok
+}
+
+// Add adds the sector to the ones waiting to be released to be finalized
+func (b *FinalizeBatcher) Add(sn abi.SectorNumber) {
+	b.lk.Lock()
+	defer b.lk.Unlock()
+
+	b.waiting[sn] = struct{}{}
+}
+
+// Remove removes the sector from the waiting ones, when it left FinalizeReady
+// without being released
+func (b *FinalizeBatcher) Remove(sn abi.SectorNumber) {
+	b.lk.Lock()
+	defer b.lk.Unlock()
+
+	delete(b.waiting, sn)
+}
+
+// Flush releases all waiting sectors to be finalized right away
+func (b *FinalizeBatcher) Flush(ctx context.Context) ([]abi.SectorID, error) {
+	resCh := make(chan []abi.SectorNumber, 1)
+	select {
+	case b.force <- resCh:
+		select {
+		case res := <-resCh:
+			return b.sectorIDs(res)
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Pending returns the sectors waiting to be finalized
+func (b *FinalizeBatcher) Pending(ctx context.Context) ([]abi.SectorID, error) {
+	b.lk.Lock()
+	sns := make([]abi.SectorNumber, 0, len(b.waiting))
+	for sn := range b.waiting {
+		sns = append(sns, sn)
+	}
+	b.lk.Unlock()
+
+	sort.Slice(sns, func(i, j int) bool {
+		return sns[i] < sns[j]
+	})
+
+	return b.sectorIDs(sns)
+}
+
+func (b *FinalizeBatcher) sectorIDs(sns []abi.SectorNumber) ([]abi.SectorID, error) {
+	mid, err := address.IDFromAddress(b.maddr)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]abi.SectorID, 0, len(sns))
+	for _, sn := range sns {
+		res = append(res, abi.SectorID{
+			Miner:  abi.ActorID(mid),
+			Number: sn,
+		})
+	}
+	return res, nil
+}
+
+func (b *FinalizeBatcher) Stop(ctx context.Context) error {
+	close(b.stop)
+
+	select {
+	case <-b.stopped:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,117 @@
+package sealing
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-state-types/abi"
+
+	"github.com/filecoin-project/lotus/extern/storage-sealing/sealiface"
+)
+
+func TestDeferredFinalize(t *testing.T) {
+	ctx := context.Background()
+
+	sealer := &finalizeSealer{finalized: make(chan abi.SectorID, 4)}
+
+	m := newSnapshotTestSealing(t)
+	m.sealer = sealer
+	m.getConfig = func() (sealiface.Config, error) {
+		return sealiface.Config{
+			DeferFinalize:         true,
+			FinalizeBatchSize:     2,
+			FinalizeBatchInterval: time.Hour,
+		}, nil
+	}
+	m.finalizer = NewFinalizeBatcher(m.maddr, m.getConfig, func(sn abi.SectorNumber) error {
+		return m.sectors.Send(uint64(sn), SectorFinalizeReleased{})
+	})
+	t.Cleanup(func() {
+		require.NoError(t, m.finalizer.Stop(ctx))
+	})
+
+	// sectors which just landed on chain
+	for _, sn := range []abi.SectorNumber{1, 2, 3, 4} {
+		require.NoError(t, m.sectors.Begin(uint64(sn), &SectorInfo{
+			SectorNumber: sn,
+			State:        FinalizeSector,
+			SectorType:   abi.RegisteredSealProof_StackedDrg2KiBV1_1,
+			CommD:        &cid.Undef,
+			CommR:        &cid.Undef,
+		}))
+		require.NoError(t, m.sectors.Send(uint64(sn), SectorRestart{}))
+	}
+
+	pending := func() []abi.SectorNumber {
+		ids, err := m.finalizer.Pending(ctx)
+		require.NoError(t, err)
+		var out []abi.SectorNumber
+		for _, id := range ids {
+			out = append(out, id.Number)
+		}
+		return out
+	}
+
+	requireFinalized := func(expect ...abi.SectorNumber) {
+		var got []abi.SectorNumber
+		for range expect {
+			select {
+			case id := <-sealer.finalized:
+				got = append(got, id.Number)
+			case <-time.After(5 * time.Second):
+				t.Fatal("sector wasn't finalized")
+			}
+		}
+		require.ElementsMatch(t, expect, got)
+	}
+
+	require.Eventually(t, func() bool {
+		return len(pending()) == 4
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// waiting doesn't hold up the state machines of the sectors, a sector which
+	// leaves FinalizeReady isn't waiting anymore
+	require.NoError(t, m.ForceSectorState(ctx, 4, Proving))
+	require.Eventually(t, func() bool {
+		si, err := m.GetSectorInfo(4)
+		require.NoError(t, err)
+		return si.State == Proving
+	}, 5*time.Second, 10*time.Millisecond)
+	require.Equal(t, []abi.SectorNumber{1, 2, 3}, pending())
+
+	// sectors stay in sealing storage, where they are proven from, until they
+	// are released in a batch, see TestDeferredFinalizeProvesFromSealingStorage
+	for _, sn := range []abi.SectorNumber{1, 2, 3} {
+		si, err := m.GetSectorInfo(sn)
+		require.NoError(t, err)
+		require.Equal(t, FinalizeReady, si.State)
+	}
+	require.Empty(t, sealer.finalized)
+
+	// scheduled batch releases up to FinalizeBatchSize sectors
+	m.finalizer.release(2)
+	requireFinalized(1, 2)
+	require.Equal(t, []abi.SectorNumber{3}, pending())
+	require.Empty(t, sealer.finalized)
+
+	// manual flush releases the rest
+	flushed, err := m.finalizer.Flush(ctx)
+	require.NoError(t, err)
+	require.Len(t, flushed, 1)
+	require.Equal(t, abi.SectorNumber(3), flushed[0].Number)
+	requireFinalized(3)
+
+	require.Eventually(t, func() bool {
+		for _, sn := range []abi.SectorNumber{1, 2, 3} {
+			si, err := m.GetSectorInfo(sn)
+			if err != nil || si.State != Proving {
+				return false
+			}
+		}
+		return true
+	}, 5*time.Second, 10*time.Millisecond)
+}
@@ -17,7 +17,9 @@ import (
 )
 
 func (m *Sealing) Plan(events []statemachine.Event, user interface{}) (interface{}, uint64, error) {
+	m.snapshotLk.RLock()
 	next, processed, err := m.plan(events, user.(*SectorInfo))
+	m.snapshotLk.RUnlock()
 	if err != nil || next == nil {
 		return nil, processed, err
 	}
@@ -60,6 +62,7 @@ var fsmPlanners = map[SectorState]func(events []statemachine.Event, state *Secto
 		on(SectorCommitFailed{}, CommitFailed),
 	),
 	PreCommit1: planOne(
+		on(SectorPhaseAdmitted{}, PreCommit1),
 		on(SectorPreCommit1{}, PreCommit2),
 		on(SectorSealPreCommit1Failed{}, SealPreCommit1Failed),
 		on(SectorDealsExpired{}, DealsExpired),
@@ -111,11 +114,13 @@ var fsmPlanners = map[SectorState]func(events []statemachine.Event, state *Secto
 		on(SectorCommitSubmitted{}, CommitWait),
 		on(SectorSubmitCommitAggregate{}, SubmitCommitAggregate),
 		on(SectorCommitFailed{}, CommitFailed),
+		on(SectorProving{}, FinalizeSector), // already committed on chain
 	),
 	SubmitCommitAggregate: planOne(
 		on(SectorCommitAggregateSent{}, CommitWait),
 		on(SectorCommitFailed{}, CommitFailed),
 		on(SectorRetrySubmitCommit{}, SubmitCommit),
+		on(SectorProving{}, FinalizeSector), // already committed on chain
 	),
 	CommitWait: planOne(
 		on(SectorProving{}, FinalizeSector),
@@ -131,6 +136,10 @@ var fsmPlanners = map[SectorState]func(events []statemachine.Event, state *Secto
 	FinalizeSector: planOne(
 		on(SectorFinalized{}, Proving),
 		on(SectorFinalizeFailed{}, FinalizeFailed),
+		on(SectorFinalizeDeferred{}, FinalizeReady),
+	),
+	FinalizeReady: planOne(
+		on(SectorFinalizeReleased{}, FinalizeSector),
 	),
 
 	// Sealing errors
@@ -292,11 +301,25 @@ func (m *Sealing) plan(events []statemachine.Event, state *SectorInfo) (func(sta
 		}
 	}
 
+	finalizeReady := state.State == FinalizeReady
+	_, phaseEntry, _ := phaseOf(state.State)
+	prevState := state.State
+
 	processed, err := p(events, state)
 	if err != nil {
 		return nil, 0, xerrors.Errorf("running planner for state %s failed: %w", state.State, err)
 	}
 
+	if finalizeReady && state.State != FinalizeReady {
+		// e.g. removed while waiting for its finalize batch
+		m.finalizer.Remove(state.SectorNumber)
+	}
+
+	if phaseEntry && state.State != prevState {
+		// e.g. removed while waiting for room in the phase
+		m.phases.remove(state.SectorNumber)
+	}
+
 	/////
 	// Now decide what to do next
 
@@ -338,6 +361,8 @@ func (m *Sealing) plan(events []statemachine.Event, state *SectorInfo) (func(sta
 				|   |
 				|   v
 				|   FinalizeSector <--> FinalizeFailed
+				|   |           ^
+				|   |           \--> FinalizeReady (DeferFinalize)
 				|   |
 				|   v
 				*<- Proving
@@ -391,6 +416,8 @@ func (m *Sealing) plan(events []statemachine.Event, state *SectorInfo) (func(sta
 		fallthrough
 	case FinalizeSector:
 		return m.handleFinalizeSector, processed, nil
+	case FinalizeReady:
+		return m.handleFinalizeReady, processed, nil
 
 	// Handled failure modes
 	case SealPreCommit1Failed:
@@ -453,6 +480,8 @@ func (m *Sealing) plan(events []statemachine.Event, state *SectorInfo) (func(sta
 }
 
 func (m *Sealing) onUpdateSector(ctx context.Context, state *SectorInfo) error {
+	m.phases.sectorState(state.SectorNumber, state.State)
+
 	if m.getConfig == nil {
 		return nil // tests
 	}
@@ -515,6 +544,8 @@ func planCommitting(events []statemachine.Event, state *SectorInfo) (uint64, err
 			state.State = CommitFailed
 		case SectorRetryCommitWait:
 			state.State = CommitWait
+		case SectorPhaseAdmitted:
+			state.State = Committing
 		default:
 			return uint64(i), xerrors.Errorf("planCommitting got event of unknown type %T, events: %+v", event.User, events)
 		}
@@ -58,23 +58,27 @@ func (evt SectorForceState) applyGlobal(state *SectorInfo) bool {
 // Normal path
 
 type SectorStart struct {
-	ID         abi.SectorNumber
-	SectorType abi.RegisteredSealProof
+	ID             abi.SectorNumber
+	SectorType     abi.RegisteredSealProof
+	SyntheticPoRep bool
 }
 
 func (evt SectorStart) apply(state *SectorInfo) {
 	state.SectorNumber = evt.ID
 	state.SectorType = evt.SectorType
+	state.SyntheticPoRep = evt.SyntheticPoRep
 }
 
 type SectorStartCC struct {
-	ID         abi.SectorNumber
-	SectorType abi.RegisteredSealProof
+	ID             abi.SectorNumber
+	SectorType     abi.RegisteredSealProof
+	SyntheticPoRep bool
 }
 
 func (evt SectorStartCC) apply(state *SectorInfo) {
 	state.SectorNumber = evt.ID
 	state.SectorType = evt.SectorType
+	state.SyntheticPoRep = evt.SyntheticPoRep
 }
 
 type SectorAddPiece struct{}
@@ -282,6 +286,28 @@ type SectorFinalized struct{}
 
 func (evt SectorFinalized) apply(*SectorInfo) {}
 
+type SectorFinalizeDeferred struct{}
+
+func (evt SectorFinalizeDeferred) apply(*SectorInfo) {}
+
+type SectorFinalizeReleased struct{}
+
+func (evt SectorFinalizeReleased) apply(*SectorInfo) {}
+
+// the sector may have left FinalizeReady, e.g. when it was removed, by the
+// time its batch is released
+func (evt SectorFinalizeReleased) Ignore() {}
+
+// SectorPhaseAdmitted is sent by the phase limiter once there is room for a
+// sector waiting to enter the precommit or commit phase
+type SectorPhaseAdmitted struct{}
+
+func (evt SectorPhaseAdmitted) apply(*SectorInfo) {}
+
+// the sector may have left the entry state of the phase by the time there is
+// room
+func (evt SectorPhaseAdmitted) Ignore() {}
+
 type SectorRetryFinalize struct{}
 
 func (evt SectorRetryFinalize) apply(*SectorInfo) {}
@@ -267,6 +267,43 @@ func TestPlanCommittingHandlesSectorCommitFailed(t *testing.T) {
 	require.Equal(t, CommitFailed, m.state.State)
 }
 
+func TestPhaseAdmitted(t *testing.T) {
+	ma, _ := address.NewIDAddress(55151)
+	m := test{
+		s: &Sealing{
+			maddr: ma,
+			stats: SectorStats{
+				bySector: map[abi.SectorID]statSectorState{},
+			},
+		},
+		t:     t,
+		state: &SectorInfo{SectorNumber: 1, State: PreCommit1},
+	}
+
+	// sectors waiting for room re-run the entry state once admitted
+	m.planSingle(SectorPhaseAdmitted{})
+	require.Equal(m.t, PreCommit1, m.state.State)
+
+	m.state.State = Committing
+	m.planSingle(SectorPhaseAdmitted{})
+	require.Equal(m.t, Committing, m.state.State)
+
+	// a sector which left the state while waiting isn't waiting anymore
+	ok, err := m.s.phases.enter(1, phaseCommit)
+	require.NoError(t, err)
+	require.True(t, ok, "no limit")
+	m.s.phases.waiting[2] = phaseCommit
+
+	m.state = &SectorInfo{SectorNumber: 2, State: Committing}
+	m.planSingle(SectorRemove{})
+	require.Equal(m.t, Removing, m.state.State)
+	require.NotContains(t, m.s.phases.waiting, abi.SectorNumber(2))
+
+	// and the admission sent late is ignored
+	m.planSingle(SectorPhaseAdmitted{})
+	require.Equal(m.t, Removing, m.state.State)
+}
+
 func TestPlannerList(t *testing.T) {
 	for state := range ExistSectorStateList {
 		_, ok := fsmPlanners[state]
@@ -30,6 +30,11 @@ func (m *Sealing) PledgeSector(ctx context.Context) (storage.SectorRef, error) {
 		return storage.SectorRef{}, xerrors.Errorf("getting seal proof type: %w", err)
 	}
 
+	synthetic, err := m.useSyntheticPoRep(ctx, cfg)
+	if err != nil {
+		return storage.SectorRef{}, err
+	}
+
 	sid, err := m.createSector(ctx, cfg, spt)
 	if err != nil {
 		return storage.SectorRef{}, err
@@ -37,7 +42,8 @@ func (m *Sealing) PledgeSector(ctx context.Context) (storage.SectorRef, error) {
 
 	log.Infof("Creating CC sector %d", sid)
 	return m.minerSector(spt, sid), m.sectors.Send(uint64(sid), SectorStartCC{
-		ID:         sid,
-		SectorType: spt,
+		ID:             sid,
+		SectorType:     spt,
+		SyntheticPoRep: synthetic,
 	})
 }
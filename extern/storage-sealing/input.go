@@ -305,51 +305,21 @@ func (m *Sealing) updateInput(ctx context.Context, sp abi.RegisteredSealProof) e
 		return err
 	}
 
-	type match struct {
-		sector abi.SectorID
-		deal   cid.Cid
-
-		size    abi.UnpaddedPieceSize
-		padding abi.UnpaddedPieceSize
-	}
-
-	var matches []match
-	toAssign := map[cid.Cid]struct{}{} // used to maybe create new sectors
-
-	// todo: this is distinctly O(n^2), may need to be optimized for tiny deals and large scale miners
-	//  (unlikely to be a problem now)
+	toAssign := map[cid.Cid]abi.UnpaddedPieceSize{} // used to maybe create new sectors
 	for proposalCid, piece := range m.pendingPieces {
 		if piece.assigned {
 			continue // already assigned to a sector, skip
 		}
 
-		toAssign[proposalCid] = struct{}{}
-
-		for id, sector := range m.openSectors {
-			avail := abi.PaddedPieceSize(ssize).Unpadded() - sector.used
-
-			if piece.size <= avail { // (note: if we have enough space for the piece, we also have enough space for inter-piece padding)
-				matches = append(matches, match{
-					sector: id,
-					deal:   proposalCid,
-
-					size:    piece.size,
-					padding: avail % piece.size,
-				})
-			}
-		}
+		toAssign[proposalCid] = piece.size
 	}
-	sort.Slice(matches, func(i, j int) bool {
-		if matches[i].padding != matches[j].padding { // less padding is better
-			return matches[i].padding < matches[j].padding
-		}
 
-		if matches[i].size != matches[j].size { // larger pieces are better
-			return matches[i].size < matches[j].size
-		}
+	used := make(map[abi.SectorID]abi.UnpaddedPieceSize, len(m.openSectors))
+	for id, sector := range m.openSectors {
+		used[id] = sector.used
+	}
 
-		return matches[i].sector.Number < matches[j].sector.Number // prefer older sectors
-	})
+	matches := packingMatches(ssize, toAssign, used)
 
 	var assigned int
 	for _, mt := range matches {
@@ -385,6 +355,17 @@ func (m *Sealing) updateInput(ctx context.Context, sp abi.RegisteredSealProof) e
 	}
 
 	if len(toAssign) > 0 {
+		cfg, err := m.getConfig()
+		if err != nil {
+			return xerrors.Errorf("getting storage config: %w", err)
+		}
+
+		if cfg.ConservativePacking && len(m.openSectors) > 0 {
+			// matched again once one of the open sectors starts sealing
+			log.Infow("not creating a deal sector, waiting for open sectors to start sealing", "pending", len(toAssign), "open", len(m.openSectors))
+			return nil
+		}
+
 		if err := m.tryCreateDealSector(ctx, sp); err != nil {
 			log.Errorw("Failed to create a new sector for deals", "error", err)
 		}
@@ -393,6 +374,103 @@ func (m *Sealing) updateInput(ctx context.Context, sp abi.RegisteredSealProof) e
 	return nil
 }
 
+// MatchPendingPiecesToOpenSectors matches the pieces waiting for a sector to
+// the open sectors, and creates a new deal sector for pieces which don't fit,
+// the same way as when a piece is added or a sector starts accepting deals.
+// With ConservativePacking no sector is created while sectors are open.
+func (m *Sealing) MatchPendingPiecesToOpenSectors(ctx context.Context) error {
+	sp, err := m.currentSealProof(ctx)
+	if err != nil {
+		return xerrors.Errorf("getting current seal proof type: %w", err)
+	}
+
+	m.inputLk.Lock()
+	defer m.inputLk.Unlock()
+
+	return m.updateInput(ctx, sp)
+}
+
+type packingMatch struct {
+	sector abi.SectorID
+	deal   cid.Cid
+
+	size    abi.UnpaddedPieceSize
+	padding abi.UnpaddedPieceSize
+}
+
+// packingMatches returns all assignments of the pieces to the open sectors
+// with enough space left for them, best first. used is the space used in each
+// of the open sectors.
+func packingMatches(ssize abi.SectorSize, pieces map[cid.Cid]abi.UnpaddedPieceSize, used map[abi.SectorID]abi.UnpaddedPieceSize) []packingMatch {
+	var matches []packingMatch
+
+	// todo: this is distinctly O(n^2), may need to be optimized for tiny deals and large scale miners
+	//  (unlikely to be a problem now)
+	for proposalCid, size := range pieces {
+		for id, sectorUsed := range used {
+			avail := abi.PaddedPieceSize(ssize).Unpadded() - sectorUsed
+
+			if size <= avail { // (note: if we have enough space for the piece, we also have enough space for inter-piece padding)
+				matches = append(matches, packingMatch{
+					sector: id,
+					deal:   proposalCid,
+
+					size:    size,
+					padding: avail % size,
+				})
+			}
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].padding != matches[j].padding { // less padding is better
+			return matches[i].padding < matches[j].padding
+		}
+
+		if matches[i].size != matches[j].size { // larger pieces are better
+			return matches[i].size < matches[j].size
+		}
+
+		if matches[i].sector.Number != matches[j].sector.Number {
+			return matches[i].sector.Number < matches[j].sector.Number // prefer older sectors
+		}
+
+		return matches[i].deal.KeyString() < matches[j].deal.KeyString()
+	})
+
+	return matches
+}
+
+// OpenSectors returns the sectors currently accepting deals, along with the
+// amount of space already used in each of them
+func (m *Sealing) OpenSectors(ctx context.Context) ([]sealiface.OpenSector, error) {
+	sp, err := m.currentSealProof(ctx)
+	if err != nil {
+		return nil, xerrors.Errorf("getting current seal proof type: %w", err)
+	}
+
+	ssize, err := sp.SectorSize()
+	if err != nil {
+		return nil, err
+	}
+
+	m.inputLk.Lock()
+	out := make([]sealiface.OpenSector, 0, len(m.openSectors))
+	for id, sector := range m.openSectors {
+		out = append(out, sealiface.OpenSector{
+			Number: id.Number,
+			Size:   ssize,
+			Used:   sector.used,
+		})
+	}
+	m.inputLk.Unlock()
+
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Number < out[j].Number
+	})
+
+	return out, nil
+}
+
 func (m *Sealing) tryCreateDealSector(ctx context.Context, sp abi.RegisteredSealProof) error {
 	m.startupWait.Wait()
 
@@ -413,6 +491,11 @@ func (m *Sealing) tryCreateDealSector(ctx context.Context, sp abi.RegisteredSeal
 		return nil
 	}
 
+	synthetic, err := m.useSyntheticPoRep(ctx, cfg)
+	if err != nil {
+		return err
+	}
+
 	sid, err := m.createSector(ctx, cfg, sp)
 	if err != nil {
 		return err
@@ -420,10 +503,11 @@ func (m *Sealing) tryCreateDealSector(ctx context.Context, sp abi.RegisteredSeal
 
 	m.creating = &sid
 
-	log.Infow("Creating sector", "number", sid, "type", "deal", "proofType", sp)
+	log.Infow("Creating sector", "number", sid, "type", "deal", "proofType", sp, "synthetic", synthetic)
 	return m.sectors.Send(uint64(sid), SectorStart{
-		ID:         sid,
-		SectorType: sp,
+		ID:             sid,
+		SectorType:     sp,
+		SyntheticPoRep: synthetic,
 	})
 }
 
@@ -454,6 +538,42 @@ func (m *Sealing) StartPacking(sid abi.SectorNumber) error {
 	return m.sectors.Send(uint64(sid), SectorStartPacking{})
 }
 
+// AbortWaitDeals removes a sector waiting for deals which doesn't hold any
+// deals yet. No more deals are assigned to the sector once it's aborted.
+func (m *Sealing) AbortWaitDeals(sid abi.SectorNumber) error {
+	m.startupWait.Wait()
+
+	m.inputLk.Lock()
+
+	si, err := m.GetSectorInfo(sid)
+	if err != nil {
+		m.inputLk.Unlock()
+		return xerrors.Errorf("getting sector info: %w", err)
+	}
+
+	if si.State != WaitDeals {
+		m.inputLk.Unlock()
+		return xerrors.Errorf("sector %d is in state %s, not %s", sid, si.State, WaitDeals)
+	}
+
+	id := m.minerSectorID(sid)
+	if n := len(si.dealIDs()) + len(m.assignedPieces[id]); n > 0 {
+		m.inputLk.Unlock()
+		return xerrors.Errorf("sector %d already has %d deals, start sealing it instead", sid, n)
+	}
+
+	delete(m.openSectors, id)
+	if st, ok := m.sectorTimers[id]; ok {
+		st.Stop()
+		delete(m.sectorTimers, id)
+	}
+
+	m.inputLk.Unlock()
+
+	log.Infow("aborting deal sector", "sector", sid)
+	return m.sectors.Send(uint64(sid), SectorRemove{})
+}
+
 func proposalCID(deal DealInfo) cid.Cid {
 	pc, err := deal.DealProposal.Cid()
 	if err != nil {
@@ -0,0 +1,53 @@
+package sealing
+
+import (
+	"context"
+
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/lotus/extern/storage-sealing/sealiface"
+)
+
+// PipelineLimits returns the effective limits of the sealing pipeline, read
+// from the current config, along with the current counts against them
+func (m *Sealing) PipelineLimits(ctx context.Context) ([]sealiface.PipelineLimit, error) {
+	cfg, err := m.getConfig()
+	if err != nil {
+		return nil, xerrors.Errorf("getting config: %w", err)
+	}
+
+	precommits, err := m.precommiter.Pending(ctx)
+	if err != nil {
+		return nil, xerrors.Errorf("getting pending precommits: %w", err)
+	}
+
+	commits, err := m.commiter.Pending(ctx)
+	if err != nil {
+		return nil, xerrors.Errorf("getting pending commits: %w", err)
+	}
+
+	terminations, err := m.terminator.Pending(ctx)
+	if err != nil {
+		return nil, xerrors.Errorf("getting pending terminations: %w", err)
+	}
+
+	finalizes, err := m.finalizer.Pending(ctx)
+	if err != nil {
+		return nil, xerrors.Errorf("getting pending finalizes: %w", err)
+	}
+
+	sealing := m.stats.curSealing()
+	staging := m.stats.curStaging()
+
+	return []sealiface.PipelineLimit{
+		{Name: "MaxSealingSectors", Limit: cfg.MaxSealingSectors, Current: sealing},
+		{Name: "MaxSealingSectorsForDeals", Limit: cfg.MaxSealingSectorsForDeals, Current: sealing},
+		{Name: "MaxWaitDealsSectors", Limit: cfg.MaxWaitDealsSectors, Current: staging},
+		{Name: "MaxPreCommitBatch", Limit: uint64(cfg.MaxPreCommitBatch), Current: uint64(len(precommits))},
+		{Name: "MaxCommitBatch", Limit: uint64(cfg.MaxCommitBatch), Current: uint64(len(commits))},
+		{Name: "TerminateBatchMax", Limit: cfg.TerminateBatchMax, Current: uint64(len(terminations))},
+		{Name: "FinalizeBatchSize", Limit: uint64(cfg.FinalizeBatchSize), Current: uint64(len(finalizes))},
+		{Name: "MaxPreCommitting", Limit: cfg.MaxPreCommitting, Current: m.phases.current(phasePreCommit)},
+		{Name: "MaxCommitting", Limit: cfg.MaxCommitting, Current: m.phases.current(phaseCommit)},
+	}, nil
+}
@@ -0,0 +1,96 @@
+package sealing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-bitfield"
+	"github.com/filecoin-project/go-state-types/abi"
+
+	"github.com/filecoin-project/lotus/extern/storage-sealing/sealiface"
+
+	miner0 "github.com/filecoin-project/specs-actors/actors/builtin/miner"
+	proof5 "github.com/filecoin-project/specs-actors/v5/actors/runtime/proof"
+)
+
+func TestPipelineLimits(t *testing.T) {
+	ctx := context.TODO()
+
+	cfg := sealiface.Config{
+		MaxWaitDealsSectors:       2,
+		MaxSealingSectors:         50,
+		MaxSealingSectorsForDeals: 3,
+		MaxPreCommitBatch:         256,
+		MaxCommitBatch:            819,
+		TerminateBatchMax:         100,
+		FinalizeBatchSize:         0,
+	}
+
+	m := newSnapshotTestSealing(t)
+	m.getConfig = func() (sealiface.Config, error) {
+		return cfg, nil
+	}
+	m.precommiter = &PreCommitBatcher{
+		maddr: m.maddr,
+		todo: map[abi.SectorNumber]*preCommitEntry{
+			5: {pci: &miner0.SectorPreCommitInfo{SectorNumber: 5}},
+		},
+	}
+	m.commiter = &CommitBatcher{
+		maddr: m.maddr,
+		todo: map[abi.SectorNumber]AggregateInput{
+			6: {Info: proof5.AggregateSealVerifyInfo{Number: 6}},
+			8: {Info: proof5.AggregateSealVerifyInfo{Number: 8}},
+		},
+	}
+	m.terminator = &TerminateBatcher{
+		maddr: m.maddr,
+		todo:  map[SectorLocation]*bitfield.BitField{},
+	}
+	m.finalizer = &FinalizeBatcher{
+		maddr: m.maddr,
+		waiting: map[abi.SectorNumber]struct{}{
+			7: {},
+		},
+	}
+
+	// two sectors sealing, one waiting for deals, one proving
+	for sn, st := range map[abi.SectorNumber]SectorState{
+		1: PreCommit1,
+		2: Committing,
+		3: WaitDeals,
+		4: Proving,
+	} {
+		m.stats.updateSector(cfg, m.minerSectorID(sn), st)
+	}
+
+	limits, err := m.PipelineLimits(ctx)
+	require.NoError(t, err)
+
+	byName := map[string]sealiface.PipelineLimit{}
+	for _, l := range limits {
+		byName[l.Name] = l
+	}
+
+	require.Equal(t, sealiface.PipelineLimit{Name: "MaxSealingSectors", Limit: 50, Current: 3}, byName["MaxSealingSectors"])
+	require.Equal(t, sealiface.PipelineLimit{Name: "MaxSealingSectorsForDeals", Limit: 3, Current: 3}, byName["MaxSealingSectorsForDeals"])
+	require.Equal(t, sealiface.PipelineLimit{Name: "MaxWaitDealsSectors", Limit: 2, Current: 1}, byName["MaxWaitDealsSectors"])
+	require.Equal(t, sealiface.PipelineLimit{Name: "MaxPreCommitBatch", Limit: 256, Current: 1}, byName["MaxPreCommitBatch"])
+	require.Equal(t, sealiface.PipelineLimit{Name: "MaxCommitBatch", Limit: 819, Current: 2}, byName["MaxCommitBatch"])
+	require.Equal(t, sealiface.PipelineLimit{Name: "TerminateBatchMax", Limit: 100, Current: 0}, byName["TerminateBatchMax"])
+	require.Equal(t, sealiface.PipelineLimit{Name: "FinalizeBatchSize", Limit: 0, Current: 1}, byName["FinalizeBatchSize"])
+
+	// limits follow config changes
+	cfg.MaxSealingSectors = 48
+	limits, err = m.PipelineLimits(ctx)
+	require.NoError(t, err)
+	require.Equal(t, uint64(48), limits[0].Limit)
+
+	// and counts follow sectors moving through the pipeline
+	m.stats.updateSector(cfg, m.minerSectorID(1), Proving)
+	limits, err = m.PipelineLimits(ctx)
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), limits[0].Current)
+}
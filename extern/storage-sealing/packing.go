@@ -0,0 +1,178 @@
+package sealing
+
+import (
+	"context"
+	"sort"
+
+	"golang.org/x/xerrors"
+
+	"github.com/ipfs/go-cid"
+
+	"github.com/filecoin-project/go-state-types/abi"
+
+	"github.com/filecoin-project/lotus/extern/sector-storage/ffiwrapper"
+	"github.com/filecoin-project/lotus/extern/storage-sealing/sealiface"
+)
+
+type simSector struct {
+	id  abi.SectorID
+	new bool
+
+	used      abi.UnpaddedPieceSize // space used as tracked by updateInput
+	offset    abi.UnpaddedPieceSize // end of the last piece in the sector
+	dealCount int
+	closed    bool
+
+	deals   []cid.Cid
+	padding abi.UnpaddedPieceSize
+}
+
+// SimulatePacking runs the packing of pieces added with AddPieceToAnySector on
+// the deals, starting from the currently open sectors, and returns the sectors
+// the deals would end up in. Nothing is assigned or created. New sectors are
+// assumed to be created whenever deals don't fit into the open sectors,
+// regardless of the sealing pipeline limits.
+func (m *Sealing) SimulatePacking(ctx context.Context, deals []sealiface.PackingDeal) (sealiface.PackingPlan, error) {
+	sp, err := m.currentSealProof(ctx)
+	if err != nil {
+		return sealiface.PackingPlan{}, xerrors.Errorf("getting current seal proof type: %w", err)
+	}
+
+	ssize, err := sp.SectorSize()
+	if err != nil {
+		return sealiface.PackingPlan{}, err
+	}
+
+	maxDeals, err := getDealPerSectorLimit(ssize)
+	if err != nil {
+		return sealiface.PackingPlan{}, xerrors.Errorf("getting per-sector deal limit: %w", err)
+	}
+
+	m.inputLk.Lock()
+	var sectors []*simSector
+	for id, sector := range m.openSectors {
+		sectors = append(sectors, &simSector{
+			id:        id,
+			used:      sector.used,
+			dealCount: len(m.assignedPieces[id]),
+		})
+	}
+	m.inputLk.Unlock()
+
+	for _, s := range sectors {
+		si, err := m.GetSectorInfo(s.id.Number)
+		if err != nil {
+			return sealiface.PackingPlan{}, xerrors.Errorf("getting info of sector %d: %w", s.id.Number, err)
+		}
+
+		for _, p := range si.Pieces {
+			s.offset += p.Piece.Size.Unpadded()
+		}
+		s.dealCount += len(si.dealIDs())
+	}
+
+	return simulatePacking(m.minerSectorID(0).Miner, ssize, maxDeals, sectors, deals), nil
+}
+
+// simulatePacking assigns the deals to sectors the way updateInput does.
+// Deals are matched to sectors in rounds. Sectors which are full or hold the
+// maximum number of deals after a round start sealing, and a new sector is
+// created when deals are left.
+func simulatePacking(miner abi.ActorID, ssize abi.SectorSize, maxDeals int, sectors []*simSector, deals []sealiface.PackingDeal) sealiface.PackingPlan {
+	var plan sealiface.PackingPlan
+
+	pieces := map[cid.Cid]abi.UnpaddedPieceSize{}
+	for _, d := range deals {
+		if d.Size.Validate() != nil || d.Size > abi.PaddedPieceSize(ssize) {
+			plan.Unassigned = append(plan.Unassigned, d.ProposalCid)
+			continue
+		}
+		pieces[d.ProposalCid] = d.Size.Unpadded()
+	}
+
+	byID := map[abi.SectorID]*simSector{}
+	next := abi.SectorNumber(1)
+	for _, s := range sectors {
+		byID[s.id] = s
+		if s.id.Number >= next {
+			next = s.id.Number + 1
+		}
+	}
+
+	for len(pieces) > 0 {
+		used := map[abi.SectorID]abi.UnpaddedPieceSize{}
+		for _, s := range sectors {
+			if !s.closed {
+				used[s.id] = s.used
+			}
+		}
+
+		for _, mt := range packingMatches(ssize, pieces, used) {
+			if _, pending := pieces[mt.deal]; !pending {
+				continue
+			}
+
+			s := byID[mt.sector]
+			if mt.size > abi.PaddedPieceSize(ssize).Unpadded()-s.used {
+				continue
+			}
+
+			s.used += mt.padding + mt.size
+
+			// padding added in handleAddPiece
+			_, padLength := ffiwrapper.GetRequiredPadding(s.offset.Padded(), mt.size.Padded())
+			s.padding += padLength.Unpadded()
+			s.offset += padLength.Unpadded() + mt.size
+
+			s.deals = append(s.deals, mt.deal)
+			s.dealCount++
+
+			delete(pieces, mt.deal)
+		}
+
+		for _, s := range sectors {
+			if s.offset.Padded() == abi.PaddedPieceSize(ssize) || s.dealCount >= maxDeals {
+				s.closed = true
+			}
+		}
+
+		if len(pieces) > 0 {
+			s := &simSector{
+				id:  abi.SectorID{Miner: miner, Number: next},
+				new: true,
+			}
+			next++
+
+			sectors = append(sectors, s)
+			byID[s.id] = s
+		}
+	}
+
+	sort.SliceStable(sectors, func(i, j int) bool {
+		if sectors[i].new != sectors[j].new {
+			return !sectors[i].new
+		}
+		return sectors[i].id.Number < sectors[j].id.Number
+	})
+
+	for _, s := range sectors {
+		if len(s.deals) == 0 {
+			continue
+		}
+
+		ps := sealiface.PackedSector{
+			New:     s.new,
+			Deals:   s.deals,
+			Padding: s.padding,
+			Free:    abi.PaddedPieceSize(ssize).Unpadded() - s.offset,
+		}
+		if !s.new {
+			ps.Number = s.id.Number
+		}
+
+		plan.Sectors = append(plan.Sectors, ps)
+		plan.Wasted += ps.Padding + ps.Free
+	}
+
+	return plan
+}
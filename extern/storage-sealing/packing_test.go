@@ -0,0 +1,176 @@
+package sealing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ipfs/go-cid"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	tutils "github.com/filecoin-project/specs-actors/v2/support/testing"
+
+	"github.com/filecoin-project/lotus/extern/storage-sealing/sealiface"
+)
+
+// TestSimulatePackingMatchesInput verifies that simulated packing assigns
+// pieces to open sectors the same way updateInput does
+func TestSimulatePackingMatchesInput(t *testing.T) {
+	sp := abi.RegisteredSealProof_StackedDrg2KiBV1
+	ssize, err := sp.SectorSize()
+	require.NoError(t, err)
+
+	sectorA := abi.SectorID{Miner: 1000, Number: 1}
+	sectorB := abi.SectorID{Miner: 1000, Number: 2}
+
+	deals := []sealiface.PackingDeal{
+		{ProposalCid: tutils.MakeCID("deal-1", nil), Size: 1024},
+		{ProposalCid: tutils.MakeCID("deal-2", nil), Size: 512},
+		{ProposalCid: tutils.MakeCID("deal-3", nil), Size: 256},
+		{ProposalCid: tutils.MakeCID("deal-4", nil), Size: 256},
+		{ProposalCid: tutils.MakeCID("deal-5", nil), Size: 512},
+	}
+
+	m := &Sealing{
+		openSectors:    map[abi.SectorID]*openSector{},
+		pendingPieces:  map[cid.Cid]*pendingPiece{},
+		assignedPieces: map[abi.SectorID][]cid.Cid{},
+	}
+
+	actual := map[abi.SectorID][]cid.Cid{}
+	for _, id := range []abi.SectorID{sectorA, sectorB} {
+		id := id
+		m.openSectors[id] = &openSector{
+			maybeAccept: func(c cid.Cid) error {
+				actual[id] = append(actual[id], c)
+				return nil
+			},
+		}
+	}
+	m.openSectors[sectorB].used = 1016
+
+	for _, d := range deals {
+		m.pendingPieces[d.ProposalCid] = &pendingPiece{
+			size: d.Size.Unpadded(),
+			accepted: func(abi.SectorNumber, abi.UnpaddedPieceSize, error) {
+				t.Fatal("deal not accepted")
+			},
+		}
+	}
+
+	require.NoError(t, m.updateInput(context.Background(), sp))
+
+	plan := simulatePacking(1000, ssize, 256, []*simSector{
+		{id: sectorA},
+		{id: sectorB, used: 1016, offset: 1016},
+	}, deals)
+
+	expected := map[abi.SectorID][]cid.Cid{}
+	for _, s := range plan.Sectors {
+		require.False(t, s.New)
+		expected[abi.SectorID{Miner: 1000, Number: s.Number}] = s.Deals
+	}
+
+	require.Equal(t, actual, expected)
+	require.Empty(t, plan.Unassigned)
+
+	var planned int
+	for _, s := range plan.Sectors {
+		planned += len(s.Deals)
+	}
+	require.Equal(t, len(deals), planned)
+}
+
+func TestSimulatePackingNewSectors(t *testing.T) {
+	ssize, err := abi.RegisteredSealProof_StackedDrg2KiBV1.SectorSize()
+	require.NoError(t, err)
+
+	deals := []sealiface.PackingDeal{
+		{ProposalCid: tutils.MakeCID("deal-1", nil), Size: 1024},
+		{ProposalCid: tutils.MakeCID("deal-2", nil), Size: 1024},
+		{ProposalCid: tutils.MakeCID("deal-3", nil), Size: 1024},
+		{ProposalCid: tutils.MakeCID("invalid", nil), Size: 1000},
+		{ProposalCid: tutils.MakeCID("too-large", nil), Size: 4096},
+	}
+
+	plan := simulatePacking(1000, ssize, 256, nil, deals)
+
+	require.Len(t, plan.Sectors, 2)
+	require.True(t, plan.Sectors[0].New)
+	require.Len(t, plan.Sectors[0].Deals, 2)
+	require.EqualValues(t, 0, plan.Sectors[0].Free)
+	require.True(t, plan.Sectors[1].New)
+	require.Len(t, plan.Sectors[1].Deals, 1)
+	require.EqualValues(t, 1016, plan.Sectors[1].Free)
+	require.EqualValues(t, 1016, plan.Wasted)
+
+	require.Equal(t, []cid.Cid{deals[3].ProposalCid, deals[4].ProposalCid}, plan.Unassigned)
+}
+
+type countingSectorIDCounter struct {
+	calls int
+}
+
+func (c *countingSectorIDCounter) Next() (abi.SectorNumber, error) {
+	c.calls++
+	return 0, xerrors.New("no sector numbers in tests")
+}
+
+// TestConservativePacking checks that with ConservativePacking no deal sector
+// is created for pieces which don't fit into the open sectors, until the open
+// sectors start sealing
+func TestConservativePacking(t *testing.T) {
+	sp := abi.RegisteredSealProof_StackedDrg2KiBV1
+	sector := abi.SectorID{Miner: 1000, Number: 1}
+
+	fits := tutils.MakeCID("fits", nil)
+	large := tutils.MakeCID("large", nil)
+
+	for _, conservative := range []bool{true, false} {
+		sc := &countingSectorIDCounter{}
+		m := &Sealing{
+			sc:             sc,
+			openSectors:    map[abi.SectorID]*openSector{},
+			pendingPieces:  map[cid.Cid]*pendingPiece{},
+			assignedPieces: map[abi.SectorID][]cid.Cid{},
+			getConfig: func() (sealiface.Config, error) {
+				return sealiface.Config{ConservativePacking: conservative}, nil
+			},
+		}
+
+		var accepted []cid.Cid
+		m.openSectors[sector] = &openSector{
+			used: 1016,
+			maybeAccept: func(c cid.Cid) error {
+				accepted = append(accepted, c)
+				return nil
+			},
+		}
+
+		for c, size := range map[cid.Cid]abi.UnpaddedPieceSize{fits: 508, large: 2032} {
+			m.pendingPieces[c] = &pendingPiece{
+				size: size,
+				accepted: func(abi.SectorNumber, abi.UnpaddedPieceSize, error) {
+					t.Fatal("deal not accepted")
+				},
+			}
+		}
+
+		require.NoError(t, m.updateInput(context.Background(), sp))
+		require.Equal(t, []cid.Cid{fits}, accepted)
+		require.False(t, m.pendingPieces[large].assigned)
+
+		if !conservative {
+			require.Equal(t, 1, sc.calls, "a sector should be created for the large piece")
+			continue
+		}
+		require.Zero(t, sc.calls, "no sector should be created while a sector is open")
+
+		// the open sector starts sealing
+		delete(m.openSectors, sector)
+
+		require.NoError(t, m.updateInput(context.Background(), sp))
+		require.Equal(t, 1, sc.calls, "a sector should be created for the large piece")
+	}
+}
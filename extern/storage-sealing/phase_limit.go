@@ -0,0 +1,216 @@
+package sealing
+
+import (
+	"sort"
+	"sync"
+
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-state-types/abi"
+)
+
+type sealPhase int
+
+const (
+	phasePreCommit sealPhase = iota
+	phaseCommit
+	nphase
+)
+
+func (p sealPhase) String() string {
+	switch p {
+	case phasePreCommit:
+		return "precommit"
+	case phaseCommit:
+		return "commit"
+	default:
+		return "unknown"
+	}
+}
+
+// phaseOf returns the phase sectors in the state are in. Sectors enter the
+// precommit phase in PreCommit1 and the commit phase in Committing.
+func phaseOf(st SectorState) (p sealPhase, entry bool, ok bool) {
+	switch st {
+	case PreCommit1:
+		return phasePreCommit, true, true
+	case PreCommit2, PreCommitting, SubmitPreCommitBatch, PreCommitBatchWait, PreCommitWait:
+		return phasePreCommit, false, true
+	case Committing:
+		return phaseCommit, true, true
+	case CommitFinalize, SubmitCommit, CommitWait, SubmitCommitAggregate, CommitAggregateWait:
+		return phaseCommit, false, true
+	}
+	return 0, false, false
+}
+
+// phaseLimiter bounds how many sectors are in the precommit and commit phases
+// at once. Sectors wait in the entry state of a phase until there is room,
+// and take up room until they move to a state outside of the phase.
+//
+// Waiting doesn't hold up the state machine of the sector: the entry state
+// handler returns when there is no room, and the limiter sends the sector
+// SectorPhaseAdmitted once another sector leaving the phase made room for it.
+type phaseLimiter struct {
+	// getMax returns the current limit of a phase, 0 = no limit
+	getMax func(sealPhase) (uint64, error)
+	send   func(abi.SectorNumber) error
+
+	lk       sync.Mutex
+	admitted map[abi.SectorNumber]sealPhase
+	waiting  map[abi.SectorNumber]sealPhase
+	counts   [nphase]uint64
+}
+
+func (l *phaseLimiter) initLocked() {
+	if l.admitted == nil {
+		l.admitted = map[abi.SectorNumber]sealPhase{}
+		l.waiting = map[abi.SectorNumber]sealPhase{}
+	}
+}
+
+// enter returns whether the sector can enter the phase. When there is no room
+// the sector is added to the waiting ones, and is sent SectorPhaseAdmitted
+// once there is.
+func (l *phaseLimiter) enter(sn abi.SectorNumber, p sealPhase) (bool, error) {
+	max, err := l.max(p)
+	if err != nil {
+		return false, err
+	}
+
+	l.lk.Lock()
+	defer l.lk.Unlock()
+
+	l.initLocked()
+
+	if ap, ok := l.admitted[sn]; ok && ap == p {
+		return true, nil
+	}
+
+	if max == 0 || l.counts[p] < max {
+		delete(l.waiting, sn)
+		l.admitLocked(sn, p)
+		return true, nil
+	}
+
+	l.waiting[sn] = p
+	return false, nil
+}
+
+func (l *phaseLimiter) max(p sealPhase) (uint64, error) {
+	if l.getMax == nil {
+		return 0, nil // tests
+	}
+	return l.getMax(p)
+}
+
+// remove removes the sector from the waiting ones, when it left the entry
+// state of a phase without being admitted
+func (l *phaseLimiter) remove(sn abi.SectorNumber) {
+	l.lk.Lock()
+	defer l.lk.Unlock()
+
+	l.initLocked()
+
+	delete(l.waiting, sn)
+}
+
+func (l *phaseLimiter) admitLocked(sn abi.SectorNumber, p sealPhase) {
+	l.admitted[sn] = p
+	l.counts[p]++
+}
+
+// sectorState updates the phase the sector is in. Sectors which moved to a
+// non-entry state of a phase without waiting for room, e.g. when the
+// sector was already in the phase before a restart, are counted as well.
+//
+// Every update also admits waiting sectors there is room for, lowest numbers
+// first, which picks up room made by sectors leaving a phase as well as
+// raised limits.
+func (l *phaseLimiter) sectorState(sn abi.SectorNumber, st SectorState) {
+	l.lk.Lock()
+	l.initLocked()
+
+	p, entry, in := phaseOf(st)
+
+	if ap, ok := l.admitted[sn]; ok {
+		if !in || ap != p {
+			delete(l.admitted, sn)
+			l.counts[ap]--
+		}
+	} else if in && !entry {
+		l.admitLocked(sn, p)
+	}
+
+	var waiting [nphase][]abi.SectorNumber
+	for wsn, wp := range l.waiting {
+		waiting[wp] = append(waiting[wp], wsn)
+	}
+	l.lk.Unlock()
+
+	var admitted []abi.SectorNumber
+	for p, sns := range waiting {
+		if len(sns) == 0 {
+			continue
+		}
+
+		max, err := l.max(sealPhase(p))
+		if err != nil {
+			log.Errorw("getting sealing phase limit", "phase", sealPhase(p), "error", err)
+			continue
+		}
+
+		sort.Slice(sns, func(i, j int) bool {
+			return sns[i] < sns[j]
+		})
+
+		l.lk.Lock()
+		for _, wsn := range sns {
+			if max != 0 && l.counts[p] >= max {
+				break
+			}
+			if wp, ok := l.waiting[wsn]; !ok || wp != sealPhase(p) {
+				continue // removed in the meantime
+			}
+
+			delete(l.waiting, wsn)
+			l.admitLocked(wsn, sealPhase(p))
+			admitted = append(admitted, wsn)
+		}
+		l.lk.Unlock()
+	}
+
+	if len(admitted) == 0 || l.send == nil {
+		return
+	}
+
+	// not sent from the state machine of the updated sector, as sending to
+	// a busy state machine blocks
+	go func() {
+		for _, wsn := range admitted {
+			if err := l.send(wsn); err != nil {
+				log.Errorw("admitting sector to sealing phase", "sector", wsn, "error", err)
+			}
+		}
+	}()
+}
+
+func (l *phaseLimiter) current(p sealPhase) uint64 {
+	l.lk.Lock()
+	defer l.lk.Unlock()
+
+	return l.counts[p]
+}
+
+// phaseLimit returns the limit of the phase from the current config
+func (m *Sealing) phaseLimit(p sealPhase) (uint64, error) {
+	cfg, err := m.getConfig()
+	if err != nil {
+		return 0, xerrors.Errorf("getting config: %w", err)
+	}
+
+	if p == phasePreCommit {
+		return cfg.MaxPreCommitting, nil
+	}
+	return cfg.MaxCommitting, nil
+}
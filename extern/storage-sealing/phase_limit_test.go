@@ -0,0 +1,93 @@
+package sealing
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-state-types/abi"
+)
+
+func TestPhaseLimiter(t *testing.T) {
+	max := uint64(2)
+	admitted := make(chan abi.SectorNumber, 10)
+
+	l := phaseLimiter{
+		getMax: func(sealPhase) (uint64, error) { return max, nil },
+		send: func(sn abi.SectorNumber) error {
+			admitted <- sn
+			return nil
+		},
+	}
+
+	enter := func(sn abi.SectorNumber) bool {
+		ok, err := l.enter(sn, phasePreCommit)
+		require.NoError(t, err)
+		if ok {
+			l.sectorState(sn, PreCommit1)
+		}
+		return ok
+	}
+
+	expectAdmitted := func(sn abi.SectorNumber) {
+		select {
+		case a := <-admitted:
+			require.Equal(t, sn, a)
+		case <-time.After(time.Second):
+			t.Fatalf("sector %d wasn't admitted", sn)
+		}
+	}
+
+	require.True(t, enter(1))
+	require.True(t, enter(2))
+	require.Equal(t, uint64(2), l.current(phasePreCommit))
+
+	// entering again while in the phase doesn't take up more room
+	require.True(t, enter(2))
+	require.Equal(t, uint64(2), l.current(phasePreCommit))
+
+	// sectors wait without blocking
+	require.False(t, enter(3))
+	require.False(t, enter(6))
+
+	// moving through the phase keeps the room taken
+	l.sectorState(1, PreCommit2)
+	l.sectorState(1, PreCommitWait)
+	require.Empty(t, admitted)
+	require.Equal(t, uint64(2), l.current(phasePreCommit))
+
+	// leaving the phase makes room for the lowest waiting sector, which is
+	// counted right away
+	l.sectorState(1, WaitSeed)
+	expectAdmitted(3)
+	require.Equal(t, uint64(2), l.current(phasePreCommit))
+
+	// once admitted the sector enters when its handler runs again
+	require.True(t, enter(3))
+	require.Equal(t, uint64(2), l.current(phasePreCommit))
+
+	// phases are limited separately
+	ok, err := l.enter(1, phaseCommit)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, uint64(1), l.current(phaseCommit))
+
+	// sectors found past the entry state, e.g. after a restart, are counted
+	l.sectorState(4, PreCommitWait)
+	require.Equal(t, uint64(3), l.current(phasePreCommit))
+
+	// sectors which left the entry state aren't admitted anymore
+	l.remove(6)
+	l.sectorState(2, WaitSeed)
+	l.sectorState(4, WaitSeed)
+	require.Equal(t, uint64(1), l.current(phasePreCommit))
+	require.Empty(t, admitted)
+
+	// raised limits are picked up on the next update
+	max = 1
+	require.False(t, enter(7))
+	max = 2
+	l.sectorState(1, Committing)
+	expectAdmitted(7)
+}
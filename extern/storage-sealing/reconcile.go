@@ -0,0 +1,243 @@
+package sealing
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+
+	"github.com/filecoin-project/lotus/chain/actors/builtin/miner"
+	"github.com/filecoin-project/lotus/extern/storage-sealing/sealiface"
+)
+
+// how often the config is re-read when reconciling is disabled
+const reconcileConfigRecheck = time.Minute
+
+// on-chain states of a sector
+const (
+	chainNone         = "none"         // the sector number isn't used on chain
+	chainAllocated    = "allocated"    // the sector number is allocated, but there's no precommit or sector
+	chainPreCommitted = "precommitted" // there's a precommit for the sector
+	chainActive       = "active"       // the sector is committed
+)
+
+// expectedChainStates returns the on-chain states a sector in the state can
+// be in. States in which the sector can be waiting for a message changing its
+// on-chain state to land aren't checked.
+func expectedChainStates(st SectorState) []string {
+	switch st {
+	case UndefinedSectorState, Empty, WaitDeals, AddPiece, AddPieceFailed, Packing, GetTicket,
+		PreCommit1, PreCommit2, SealPreCommit1Failed, SealPreCommit2Failed:
+		return []string{chainNone}
+	case WaitSeed, Committing:
+		return []string{chainPreCommitted}
+	case FinalizeSector, FinalizeReady, Proving, Faulty, FaultReported:
+		return []string{chainActive}
+	case Removed:
+		return []string{chainNone, chainAllocated, chainPreCommitted}
+	}
+	return nil
+}
+
+// correction returns the state a sector in the state can be moved to when it's
+// in the on-chain state, or an empty state when it's not safe to move the
+// sector automatically. The only safe case is a sector committed on chain
+// while it still waits for the seed or computes the commit proof: its sealed
+// files exist, so it can go on like after its commit message landed.
+func correction(st SectorState, onChain string) SectorState {
+	switch st {
+	case WaitSeed, Committing:
+		if onChain == chainActive {
+			return FinalizeSector
+		}
+	}
+	return ""
+}
+
+type SectorReconcilerApi interface {
+	ChainHead(ctx context.Context) (TipSetToken, abi.ChainEpoch, error)
+	StateSectorPreCommitInfo(ctx context.Context, maddr address.Address, sectorNumber abi.SectorNumber, tok TipSetToken) (*miner.SectorPreCommitOnChainInfo, error)
+	StateSectorGetInfo(ctx context.Context, maddr address.Address, sectorNumber abi.SectorNumber, tok TipSetToken) (*miner.SectorOnChainInfo, error)
+}
+
+// SectorReconciler periodically compares the state of each sector in the
+// sealing state machine to its state on chain, and logs the discrepancies it
+// finds. Only the discrepancies with a safe correction are corrected, see
+// correction; in the other cases which side is wrong depends on how they came
+// about, and sectors can be moved to the right state with
+// `lotus-miner sectors update-state`.
+type SectorReconciler struct {
+	api       SectorReconcilerApi
+	maddr     address.Address
+	mctx      context.Context
+	getConfig GetSealingConfigFunc
+	sectors   func() ([]SectorInfo, error)
+	move      func(abi.SectorNumber, SectorState) error
+
+	stop, stopped chan struct{}
+}
+
+func NewSectorReconciler(mctx context.Context, maddr address.Address, api SectorReconcilerApi, getConfig GetSealingConfigFunc, sectors func() ([]SectorInfo, error), move func(abi.SectorNumber, SectorState) error) *SectorReconciler {
+	r := &SectorReconciler{
+		api:       api,
+		maddr:     maddr,
+		mctx:      mctx,
+		getConfig: getConfig,
+		sectors:   sectors,
+		move:      move,
+
+		stop:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+
+	go r.run()
+
+	return r
+}
+
+func (r *SectorReconciler) run() {
+	for {
+		cfg, err := r.getConfig()
+		if err != nil {
+			log.Warnw("SectorReconciler getconfig error", "error", err)
+		}
+
+		interval := cfg.ReconcileInterval
+		enabled := interval > 0
+		if !enabled {
+			interval = reconcileConfigRecheck
+		}
+
+		select {
+		case <-r.stop:
+			close(r.stopped)
+			return
+		case <-time.After(interval):
+		}
+
+		if !enabled {
+			continue
+		}
+
+		rep, err := r.Reconcile(r.mctx)
+		if err != nil {
+			log.Warnw("SectorReconciler reconcile error", "error", err)
+			continue
+		}
+
+		r.correct(rep)
+	}
+}
+
+// correct moves the sectors with a safe correction to the corrected state, and
+// logs the other discrepancies
+func (r *SectorReconciler) correct(rep sealiface.ReconcileReport) {
+	for _, d := range rep.Discrepancies {
+		if d.Correction == "" {
+			log.Warnw("sector state doesn't match chain state, fix the state with `lotus-miner sectors update-state`", "sector", d.Sector, "state", d.State, "expected", d.Expected, "chain", d.OnChain)
+			continue
+		}
+
+		if err := r.move(d.Sector, SectorState(d.Correction)); err != nil {
+			log.Errorw("correcting sector state", "sector", d.Sector, "state", d.State, "correction", d.Correction, "error", err)
+			continue
+		}
+
+		log.Warnw("sector state didn't match chain state, corrected", "sector", d.Sector, "state", d.State, "chain", d.OnChain, "corrected", d.Correction)
+	}
+}
+
+// Reconcile compares the state of all sectors to their state on chain at the
+// current head
+func (r *SectorReconciler) Reconcile(ctx context.Context) (sealiface.ReconcileReport, error) {
+	sectors, err := r.sectors()
+	if err != nil {
+		return sealiface.ReconcileReport{}, xerrors.Errorf("listing sectors: %w", err)
+	}
+
+	tok, head, err := r.api.ChainHead(ctx)
+	if err != nil {
+		return sealiface.ReconcileReport{}, xerrors.Errorf("getting chain head: %w", err)
+	}
+
+	sort.Slice(sectors, func(i, j int) bool {
+		return sectors[i].SectorNumber < sectors[j].SectorNumber
+	})
+
+	rep := sealiface.ReconcileReport{
+		Time:   time.Now(),
+		Height: head,
+	}
+
+	for _, si := range sectors {
+		expect := expectedChainStates(si.State)
+		if len(expect) == 0 {
+			continue
+		}
+
+		cs, err := r.chainState(ctx, si.SectorNumber, tok)
+		if err != nil {
+			return sealiface.ReconcileReport{}, xerrors.Errorf("getting chain state of sector %d: %w", si.SectorNumber, err)
+		}
+		rep.Checked++
+
+		var ok bool
+		for _, e := range expect {
+			if e == cs {
+				ok = true
+				break
+			}
+		}
+		if ok {
+			continue
+		}
+
+		rep.Discrepancies = append(rep.Discrepancies, sealiface.SectorDiscrepancy{
+			Sector:     si.SectorNumber,
+			State:      string(si.State),
+			Expected:   expect,
+			OnChain:    cs,
+			Correction: string(correction(si.State, cs)),
+		})
+	}
+
+	return rep, nil
+}
+
+func (r *SectorReconciler) chainState(ctx context.Context, sn abi.SectorNumber, tok TipSetToken) (string, error) {
+	onChain, err := r.api.StateSectorGetInfo(ctx, r.maddr, sn, tok)
+	if err != nil {
+		return "", xerrors.Errorf("getting sector info: %w", err)
+	}
+	if onChain != nil {
+		return chainActive, nil
+	}
+
+	pci, err := r.api.StateSectorPreCommitInfo(ctx, r.maddr, sn, tok)
+	if err == ErrSectorAllocated {
+		return chainAllocated, nil
+	}
+	if err != nil {
+		return "", xerrors.Errorf("getting precommit info: %w", err)
+	}
+	if pci != nil {
+		return chainPreCommitted, nil
+	}
+
+	return chainNone, nil
+}
+
+func (r *SectorReconciler) Stop(ctx context.Context) error {
+	close(r.stop)
+
+	select {
+	case <-r.stopped:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,105 @@
+package sealing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+
+	"github.com/filecoin-project/lotus/chain/actors/builtin/miner"
+	"github.com/filecoin-project/lotus/extern/storage-sealing/sealiface"
+)
+
+type testReconcilerApi struct {
+	active       map[abi.SectorNumber]bool
+	precommitted map[abi.SectorNumber]bool
+	allocated    map[abi.SectorNumber]bool
+}
+
+func (a *testReconcilerApi) ChainHead(ctx context.Context) (TipSetToken, abi.ChainEpoch, error) {
+	return nil, 1000, nil
+}
+
+func (a *testReconcilerApi) StateSectorPreCommitInfo(ctx context.Context, maddr address.Address, sectorNumber abi.SectorNumber, tok TipSetToken) (*miner.SectorPreCommitOnChainInfo, error) {
+	if a.precommitted[sectorNumber] {
+		return &miner.SectorPreCommitOnChainInfo{}, nil
+	}
+	if a.allocated[sectorNumber] {
+		return nil, ErrSectorAllocated
+	}
+	return nil, nil
+}
+
+func (a *testReconcilerApi) StateSectorGetInfo(ctx context.Context, maddr address.Address, sectorNumber abi.SectorNumber, tok TipSetToken) (*miner.SectorOnChainInfo, error) {
+	if a.active[sectorNumber] {
+		return &miner.SectorOnChainInfo{SectorNumber: sectorNumber}, nil
+	}
+	return nil, nil
+}
+
+func TestSectorReconciler(t *testing.T) {
+	api := &testReconcilerApi{
+		active:       map[abi.SectorNumber]bool{1: true, 4: true, 6: true, 9: true},
+		precommitted: map[abi.SectorNumber]bool{2: true},
+		allocated:    map[abi.SectorNumber]bool{5: true},
+	}
+
+	sectors := []SectorInfo{
+		{SectorNumber: 6, State: Removed},       // removed locally, but still active on chain
+		{SectorNumber: 1, State: Proving},       // matches
+		{SectorNumber: 2, State: WaitSeed},      // matches
+		{SectorNumber: 3, State: Proving},       // missing on chain
+		{SectorNumber: 4, State: PreCommit2},    // committed on chain while still sealing
+		{SectorNumber: 5, State: Removed},       // matches, the precommit expired
+		{SectorNumber: 7, State: PreCommitWait}, // the precommit can still land, not checked
+		{SectorNumber: 8, State: PreCommit1},    // matches
+		{SectorNumber: 9, State: Committing},    // committed on chain while computing the proof
+	}
+
+	moved := map[abi.SectorNumber]SectorState{}
+	r := &SectorReconciler{
+		api:   api,
+		maddr: address.TestAddress,
+		sectors: func() ([]SectorInfo, error) {
+			return sectors, nil
+		},
+		move: func(sn abi.SectorNumber, st SectorState) error {
+			moved[sn] = st
+			return nil
+		},
+	}
+
+	rep, err := r.Reconcile(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, abi.ChainEpoch(1000), rep.Height)
+	require.Equal(t, 8, rep.Checked)
+	require.Equal(t, []sealiface.SectorDiscrepancy{
+		{Sector: 3, State: string(Proving), Expected: []string{chainActive}, OnChain: chainNone},
+		{Sector: 4, State: string(PreCommit2), Expected: []string{chainNone}, OnChain: chainActive},
+		{Sector: 6, State: string(Removed), Expected: []string{chainNone, chainAllocated, chainPreCommitted}, OnChain: chainActive},
+		{Sector: 9, State: string(Committing), Expected: []string{chainPreCommitted}, OnChain: chainActive, Correction: string(FinalizeSector)},
+	}, rep.Discrepancies)
+
+	// Reconcile only reports, the periodic run moves the sectors with a safe
+	// correction
+	require.Empty(t, moved)
+	r.correct(rep)
+	require.Equal(t, map[abi.SectorNumber]SectorState{9: FinalizeSector}, moved)
+
+	// the discrepancies are resolved
+	api.active[3] = true
+	delete(api.active, 4)
+	delete(api.active, 6)
+	for i := range sectors {
+		if sectors[i].SectorNumber == 9 {
+			sectors[i].State = FinalizeSector
+		}
+	}
+
+	rep, err = r.Reconcile(context.Background())
+	require.NoError(t, err)
+	require.Empty(t, rep.Discrepancies)
+}
@@ -18,12 +18,42 @@ type Config struct {
 	// includes failed, 0 = no limit
 	MaxSealingSectorsForDeals uint64
 
+	// maximum number of sectors in PreCommit1 up to the precommit landing on
+	// chain at once, 0 = no limit
+	MaxPreCommitting uint64
+	// maximum number of sectors computing or submitting the seal proof at
+	// once, 0 = no limit
+	MaxCommitting uint64
+
 	WaitDealsDelay time.Duration
 
+	// don't create deal sectors while there are sectors accepting deals
+	ConservativePacking bool
+
 	AlwaysKeepUnsealedCopy bool
 
+	// keep the unsealed copy of pieces in verified deals
+	KeepUnsealedVerifiedDeals bool
+	// keep the unsealed copy of deal pieces of at least this size, 0 = disabled
+	KeepUnsealedMinPieceSize abi.PaddedPieceSize
+
 	FinalizeEarly bool
 
+	// DeferFinalize keeps sealed sectors in the FinalizeReady state, proven
+	// from sealing storage, until they are released to be finalized and
+	// moved to long-term storage in batches
+	DeferFinalize bool
+	// FinalizeBatchSize is the maximum number of sectors released every
+	// FinalizeBatchInterval, 0 = no limit
+	FinalizeBatchSize int
+	// FinalizeBatchInterval is how often a batch of sectors is released,
+	// 0 = only when flushed manually
+	FinalizeBatchInterval time.Duration
+
+	// UseSyntheticPoRep only works with mock proofs for now, see
+	// policy.SyntheticPoRepSupported
+	UseSyntheticPoRep bool
+
 	BatchPreCommits     bool
 	MaxPreCommitBatch   int
 	PreCommitBatchWait  time.Duration
@@ -40,4 +70,14 @@ type Config struct {
 	TerminateBatchMax  uint64
 	TerminateBatchMin  uint64
 	TerminateBatchWait time.Duration
+
+	AutoExtendSectors       bool
+	AutoExtendDealSectors   bool
+	AutoExtendWindow        time.Duration
+	AutoExtendDuration      time.Duration
+	AutoExtendCheckInterval time.Duration
+
+	// ReconcileInterval is how often the state of sectors is compared to
+	// their state on chain, 0 = never
+	ReconcileInterval time.Duration
 }
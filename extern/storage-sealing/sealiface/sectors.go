@@ -0,0 +1,90 @@
+package sealiface
+
+import (
+	"time"
+
+	"github.com/ipfs/go-cid"
+
+	"github.com/filecoin-project/go-state-types/abi"
+)
+
+// OpenSector describes a sector which is currently accepting deals
+type OpenSector struct {
+	Number abi.SectorNumber
+	Size   abi.SectorSize
+
+	// Used is the space already taken up by pieces and padding
+	Used abi.UnpaddedPieceSize
+}
+
+// PipelineLimit is an effective limit of the sealing pipeline together with
+// the current count against it
+type PipelineLimit struct {
+	// Name is the name of the config option setting the limit
+	Name string
+	// 0 = no limit
+	Limit   uint64
+	Current uint64
+}
+
+// SectorDiscrepancy describes a sector whose state in the sealing state
+// machine doesn't match its state on chain
+type SectorDiscrepancy struct {
+	Sector abi.SectorNumber
+	State  string
+
+	// Expected are the on-chain states the sector can be in given its state
+	// machine state, OnChain is the state it's actually in
+	Expected []string
+	OnChain  string
+
+	// Correction is the state the sector can safely be moved to, which the
+	// periodic reconciler does, empty when the discrepancy has to be resolved
+	// by hand
+	Correction string
+}
+
+// ReconcileReport is the result of comparing the state machine state of all
+// sectors to their state on chain
+type ReconcileReport struct {
+	Time   time.Time
+	Height abi.ChainEpoch
+
+	// Checked is the number of sectors in states which were compared
+	Checked       int
+	Discrepancies []SectorDiscrepancy
+}
+
+// PackingDeal is a deal piece to simulate packing into sectors
+type PackingDeal struct {
+	ProposalCid cid.Cid
+	Size        abi.PaddedPieceSize
+}
+
+// PackedSector is a sector deals are assigned to by a packing simulation
+type PackedSector struct {
+	// New is set when the sector would be created for the deals, Number is
+	// only set for sectors which are already open
+	New    bool
+	Number abi.SectorNumber
+
+	Deals []cid.Cid
+
+	// Padding is the space taken up by padding added in front of the deals,
+	// Free the space left in the sector after them
+	Padding abi.UnpaddedPieceSize
+	Free    abi.UnpaddedPieceSize
+}
+
+// PackingPlan is the result of simulating packing deals into sectors
+type PackingPlan struct {
+	Sectors []PackedSector
+
+	// Wasted is the total padding and free space in the sectors, which is
+	// filled with zeroes if the sectors are sealed as planned
+	Wasted abi.UnpaddedPieceSize
+
+	// Unassigned are the deals which can't be packed, e.g. because they
+	// don't fit into a sector
+	Unassigned []cid.Cid
+}
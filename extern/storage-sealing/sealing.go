@@ -24,6 +24,7 @@ import (
 	"github.com/filecoin-project/lotus/api"
 	"github.com/filecoin-project/lotus/chain/actors/builtin/market"
 	"github.com/filecoin-project/lotus/chain/actors/builtin/miner"
+	"github.com/filecoin-project/lotus/chain/actors/policy"
 	"github.com/filecoin-project/lotus/chain/types"
 	sectorstorage "github.com/filecoin-project/lotus/extern/sector-storage"
 	"github.com/filecoin-project/lotus/extern/sector-storage/ffiwrapper"
@@ -53,6 +54,7 @@ type SealingAPI interface {
 	StateSectorPreCommitInfo(ctx context.Context, maddr address.Address, sectorNumber abi.SectorNumber, tok TipSetToken) (*miner.SectorPreCommitOnChainInfo, error)
 	StateSectorGetInfo(ctx context.Context, maddr address.Address, sectorNumber abi.SectorNumber, tok TipSetToken) (*miner.SectorOnChainInfo, error)
 	StateSectorPartition(ctx context.Context, maddr address.Address, sectorNumber abi.SectorNumber, tok TipSetToken) (*SectorLocation, error)
+	StateMinerActiveSectors(ctx context.Context, maddr address.Address, tok TipSetToken) ([]*miner.SectorOnChainInfo, error)
 	StateLookupID(context.Context, address.Address, TipSetToken) (address.Address, error)
 	StateMinerSectorSize(context.Context, address.Address, TipSetToken) (abi.SectorSize, error)
 	StateMinerWorkerAddress(ctx context.Context, maddr address.Address, tok TipSetToken) (address.Address, error)
@@ -103,14 +105,21 @@ type Sealing struct {
 	upgradeLk sync.Mutex
 	toUpgrade map[abi.SectorNumber]struct{}
 
+	// held for writing while taking a snapshot, to pause state transitions
+	snapshotLk sync.RWMutex
+
 	notifee SectorStateNotifee
 	addrSel AddrSel
 
-	stats SectorStats
+	stats  SectorStats
+	phases phaseLimiter
 
 	terminator  *TerminateBatcher
 	precommiter *PreCommitBatcher
 	commiter    *CommitBatcher
+	extender    *SectorExtender
+	finalizer   *FinalizeBatcher
+	reconciler  *SectorReconciler
 
 	getConfig GetSealingConfigFunc
 	dealInfo  *CurrentDealInfoManager
@@ -156,17 +165,29 @@ func New(mctx context.Context, api SealingAPI, fc config.MinerFeeConfig, events
 		terminator:  NewTerminationBatcher(mctx, maddr, api, as, fc, gc),
 		precommiter: NewPreCommitBatcher(mctx, maddr, api, as, fc, gc),
 		commiter:    NewCommitBatcher(mctx, maddr, api, as, fc, gc, prov),
+		extender:    NewSectorExtender(mctx, maddr, api, fc, gc),
 
 		getConfig: gc,
 		dealInfo:  &CurrentDealInfoManager{api},
 
 		stats: SectorStats{
 			bySector: map[abi.SectorID]statSectorState{},
+			byState:  map[abi.SectorID]SectorState{},
 		},
 	}
 	s.startupWait.Add(1)
 
 	s.sectors = statemachine.New(namespace.Wrap(ds, datastore.NewKey(SectorStorePrefix)), s, SectorInfo{})
+	s.reconciler = NewSectorReconciler(mctx, maddr, api, gc, s.ListSectors, func(sn abi.SectorNumber, st SectorState) error {
+		return s.sectors.Send(uint64(sn), SectorForceState{st})
+	})
+	s.finalizer = NewFinalizeBatcher(maddr, gc, func(sn abi.SectorNumber) error {
+		return s.sectors.Send(uint64(sn), SectorFinalizeReleased{})
+	})
+	s.phases.getMax = s.phaseLimit
+	s.phases.send = func(sn abi.SectorNumber) error {
+		return s.sectors.Send(uint64(sn), SectorPhaseAdmitted{})
+	}
 
 	return s
 }
@@ -185,6 +206,18 @@ func (m *Sealing) Stop(ctx context.Context) error {
 		return err
 	}
 
+	if err := m.extender.Stop(ctx); err != nil {
+		return err
+	}
+
+	if err := m.finalizer.Stop(ctx); err != nil {
+		return err
+	}
+
+	if err := m.reconciler.Stop(ctx); err != nil {
+		return err
+	}
+
 	if err := m.sectors.Stop(ctx); err != nil {
 		return err
 	}
@@ -203,6 +236,10 @@ func (m *Sealing) Terminate(ctx context.Context, sid abi.SectorNumber) error {
 	return m.sectors.Send(uint64(sid), SectorTerminate{})
 }
 
+func (m *Sealing) ExtendSector(ctx context.Context, sid abi.SectorNumber, newExp abi.ChainEpoch) (cid.Cid, error) {
+	return m.extender.ExtendSector(ctx, sid, newExp)
+}
+
 func (m *Sealing) TerminateFlush(ctx context.Context) (*cid.Cid, error) {
 	return m.terminator.Flush(ctx)
 }
@@ -227,6 +264,18 @@ func (m *Sealing) CommitPending(ctx context.Context) ([]abi.SectorID, error) {
 	return m.commiter.Pending(ctx)
 }
 
+func (m *Sealing) FinalizeFlush(ctx context.Context) ([]abi.SectorID, error) {
+	return m.finalizer.Flush(ctx)
+}
+
+func (m *Sealing) FinalizePending(ctx context.Context) ([]abi.SectorID, error) {
+	return m.finalizer.Pending(ctx)
+}
+
+func (m *Sealing) ReconcileReport(ctx context.Context) (sealiface.ReconcileReport, error) {
+	return m.reconciler.Reconcile(ctx)
+}
+
 func (m *Sealing) currentSealProof(ctx context.Context) (abi.RegisteredSealProof, error) {
 	mi, err := m.api.StateMinerInfo(ctx, m.maddr, nil)
 	if err != nil {
@@ -241,6 +290,26 @@ func (m *Sealing) currentSealProof(ctx context.Context) (abi.RegisteredSealProof
 	return miner.PreferredSealProofTypeFromWindowPoStType(ver, mi.WindowPoStProofType)
 }
 
+// useSyntheticPoRep returns whether new sectors should be sealed with synthetic
+// PoRep. It errors when synthetic PoRep is enabled in the config, but isn't
+// supported by the network, so that we don't seal sectors which can't be committed
+func (m *Sealing) useSyntheticPoRep(ctx context.Context, cfg sealiface.Config) (bool, error) {
+	if !cfg.UseSyntheticPoRep {
+		return false, nil
+	}
+
+	ver, err := m.api.StateNetworkVersion(ctx, nil)
+	if err != nil {
+		return false, xerrors.Errorf("getting network version: %w", err)
+	}
+
+	if !policy.SyntheticPoRepSupported(ver) {
+		return false, xerrors.Errorf("synthetic PoRep is enabled in sealing config, but isn't supported in network version %d", ver)
+	}
+
+	return true, nil
+}
+
 func (m *Sealing) minerSector(spt abi.RegisteredSealProof, num abi.SectorNumber) storage.SectorRef {
 	return storage.SectorRef{
 		ID:        m.minerSectorID(num),
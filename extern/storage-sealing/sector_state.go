@@ -24,6 +24,7 @@ var ExistSectorStateList = map[SectorState]struct{}{
 	SubmitCommitAggregate: {},
 	CommitAggregateWait:   {},
 	FinalizeSector:        {},
+	FinalizeReady:         {},
 	Proving:               {},
 	FailedUnrecoverable:   {},
 	SealPreCommit1Failed:  {},
@@ -78,6 +79,7 @@ const (
 	CommitAggregateWait   SectorState = "CommitAggregateWait"
 
 	FinalizeSector SectorState = "FinalizeSector"
+	FinalizeReady  SectorState = "FinalizeReady" // waiting to be finalized and moved to long-term storage in a batch (DeferFinalize)
 	Proving        SectorState = "Proving"
 	// error modes
 	FailedUnrecoverable  SectorState = "FailedUnrecoverable"
@@ -110,7 +112,7 @@ func toStatState(st SectorState) statSectorState {
 	switch st {
 	case UndefinedSectorState, Empty, WaitDeals, AddPiece:
 		return sstStaging
-	case Packing, GetTicket, PreCommit1, PreCommit2, PreCommitting, PreCommitWait, SubmitPreCommitBatch, PreCommitBatchWait, WaitSeed, Committing, CommitFinalize, SubmitCommit, CommitWait, SubmitCommitAggregate, CommitAggregateWait, FinalizeSector:
+	case Packing, GetTicket, PreCommit1, PreCommit2, PreCommitting, PreCommitWait, SubmitPreCommitBatch, PreCommitBatchWait, WaitSeed, Committing, CommitFinalize, SubmitCommit, CommitWait, SubmitCommitAggregate, CommitAggregateWait, FinalizeSector, FinalizeReady:
 		return sstSealing
 	case Proving, Removed, Removing, Terminating, TerminateWait, TerminateFinality, TerminateFailed:
 		return sstProving
@@ -0,0 +1,85 @@
+package sealing
+
+import (
+	"context"
+	"time"
+
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-state-types/abi"
+)
+
+// Snapshot is a consistent view of all sectors tracked by the sealing state
+// machine, which can be restored into a sealing instance on another host
+type Snapshot struct {
+	Taken   time.Time
+	Sectors []SectorInfo
+}
+
+// Snapshot briefly pauses sector state transitions, and captures the state
+// of all sectors. Sector work which is already running isn't interrupted, its
+// results are applied after the snapshot is taken.
+func (m *Sealing) Snapshot(ctx context.Context) (Snapshot, error) {
+	m.startupWait.Wait()
+
+	m.snapshotLk.Lock()
+	defer m.snapshotLk.Unlock()
+
+	sectors, err := m.ListSectors()
+	if err != nil {
+		return Snapshot{}, xerrors.Errorf("listing sectors: %w", err)
+	}
+
+	return Snapshot{
+		Taken:   time.Now(),
+		Sectors: sectors,
+	}, nil
+}
+
+// Restore starts tracking all sectors from the snapshot, and restarts their
+// state machines. It can only be used on an instance which doesn't track any
+// sectors yet.
+func (m *Sealing) Restore(ctx context.Context, snap Snapshot) error {
+	m.startupWait.Wait()
+
+	existing, err := m.ListSectors()
+	if err != nil {
+		return xerrors.Errorf("listing sectors: %w", err)
+	}
+	if len(existing) > 0 {
+		return xerrors.Errorf("can't restore a snapshot, already tracking %d sectors", len(existing))
+	}
+
+	var maxNum abi.SectorNumber
+	for i := range snap.Sectors {
+		si := snap.Sectors[i]
+		if err := m.sectors.Begin(uint64(si.SectorNumber), &si); err != nil {
+			return xerrors.Errorf("restoring sector %d: %w", si.SectorNumber, err)
+		}
+
+		if si.SectorNumber > maxNum {
+			maxNum = si.SectorNumber
+		}
+	}
+
+	// make sure that new sectors don't reuse numbers of restored ones
+	if len(snap.Sectors) > 0 {
+		for {
+			n, err := m.sc.Next()
+			if err != nil {
+				return xerrors.Errorf("advancing sector number counter: %w", err)
+			}
+			if n > maxNum {
+				break
+			}
+		}
+	}
+
+	for _, si := range snap.Sectors {
+		if err := m.sectors.Send(uint64(si.SectorNumber), SectorRestart{}); err != nil {
+			return xerrors.Errorf("restarting sector %d: %w", si.SectorNumber, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,83 @@
+package sealing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ipfs/go-datastore"
+	dssync "github.com/ipfs/go-datastore/sync"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-statemachine"
+)
+
+type testCounter struct {
+	next abi.SectorNumber
+}
+
+func (c *testCounter) Next() (abi.SectorNumber, error) {
+	c.next++
+	return c.next, nil
+}
+
+func newSnapshotTestSealing(t *testing.T) *Sealing {
+	ma, _ := address.NewIDAddress(55151)
+	s := &Sealing{
+		maddr: ma,
+		stats: SectorStats{
+			bySector: map[abi.SectorID]statSectorState{},
+			byState:  map[abi.SectorID]SectorState{},
+		},
+		sc: &testCounter{},
+	}
+	s.sectors = statemachine.New(dssync.MutexWrap(datastore.NewMapDatastore()), s, SectorInfo{})
+	t.Cleanup(func() {
+		require.NoError(t, s.sectors.Stop(context.TODO()))
+	})
+	return s
+}
+
+func TestSnapshotRestore(t *testing.T) {
+	ctx := context.TODO()
+
+	// use final states, so that the state machines don't try to do any work
+	src := newSnapshotTestSealing(t)
+	require.NoError(t, src.sectors.Begin(3, &SectorInfo{
+		SectorNumber: 3,
+		State:        Removed,
+		SectorType:   abi.RegisteredSealProof_StackedDrg2KiBV1_1,
+		Retries:      2,
+	}))
+	require.NoError(t, src.sectors.Begin(7, &SectorInfo{
+		SectorNumber:   7,
+		State:          FailedUnrecoverable,
+		SectorType:     abi.RegisteredSealProof_StackedDrg2KiBV1_1,
+		SyntheticPoRep: true,
+	}))
+
+	snap, err := src.Snapshot(ctx)
+	require.NoError(t, err)
+	require.Len(t, snap.Sectors, 2)
+
+	dst := newSnapshotTestSealing(t)
+	require.NoError(t, dst.Restore(ctx, snap))
+
+	for _, si := range snap.Sectors {
+		restored, err := dst.GetSectorInfo(si.SectorNumber)
+		require.NoError(t, err)
+		require.Equal(t, si.State, restored.State)
+		require.Equal(t, si.SectorType, restored.SectorType)
+		require.Equal(t, si.Retries, restored.Retries)
+		require.Equal(t, si.SyntheticPoRep, restored.SyntheticPoRep)
+	}
+
+	// new sectors must not reuse restored sector numbers
+	next, err := dst.sc.Next()
+	require.NoError(t, err)
+	require.Greater(t, uint64(next), uint64(7))
+
+	// restoring into an instance which already tracks sectors isn't allowed
+	require.Error(t, dst.Restore(ctx, snap))
+}
@@ -42,7 +42,15 @@ func (m *Sealing) handlePacking(ctx statemachine.Context, sector SectorInfo) err
 
 	delete(m.openSectors, m.minerSectorID(sector.SectorNumber))
 	delete(m.assignedPieces, m.minerSectorID(sector.SectorNumber))
-	m.inputLk.Unlock()
+
+	// pieces may be waiting for open sectors to start sealing, see
+	// ConservativePacking
+	go func() {
+		defer m.inputLk.Unlock()
+		if err := m.updateInput(ctx.Context(), sector.SectorType); err != nil {
+			log.Errorf("%+v", err)
+		}
+	}()
 
 	log.Infow("performing filling up rest of the sector...", "sector", sector.SectorNumber)
 
@@ -185,6 +193,14 @@ func (m *Sealing) handleGetTicket(ctx statemachine.Context, sector SectorInfo) e
 }
 
 func (m *Sealing) handlePreCommit1(ctx statemachine.Context, sector SectorInfo) error {
+	if ok, err := m.phases.enter(sector.SectorNumber, phasePreCommit); err != nil {
+		return xerrors.Errorf("entering precommit phase: %w", err)
+	} else if !ok {
+		// the limiter sends SectorPhaseAdmitted once there is room
+		log.Infow("waiting for room in sealing phase", "sector", sector.SectorNumber, "phase", phasePreCommit)
+		return nil
+	}
+
 	if err := checkPieces(ctx.Context(), m.maddr, sector, m.api); err != nil { // Sanity check state
 		switch err.(type) {
 		case *ErrApi:
@@ -517,6 +533,14 @@ func (m *Sealing) handleCommitting(ctx statemachine.Context, sector SectorInfo)
 		}
 	}
 
+	if ok, err := m.phases.enter(sector.SectorNumber, phaseCommit); err != nil {
+		return xerrors.Errorf("entering commit phase: %w", err)
+	} else if !ok {
+		// the limiter sends SectorPhaseAdmitted once there is room
+		log.Infow("waiting for room in sealing phase", "sector", sector.SectorNumber, "phase", phaseCommit)
+		return nil
+	}
+
 	cfg, err := m.getConfig()
 	if err != nil {
 		return xerrors.Errorf("getting config: %w", err)
@@ -567,12 +591,43 @@ func (m *Sealing) handleCommitting(ctx statemachine.Context, sector SectorInfo)
 	})
 }
 
+// committedOnChain checks whether the sector was already committed on chain,
+// e.g. when a commit message sent earlier landed after the sector got moved
+// back to SubmitCommit. Sending another ProveCommit would only waste gas.
+func (m *Sealing) committedOnChain(ctx context.Context, sector SectorInfo, tok TipSetToken) (bool, error) {
+	si, err := m.api.StateSectorGetInfo(ctx, m.maddr, sector.SectorNumber, tok)
+	if err != nil {
+		return false, xerrors.Errorf("getting sector info: %w", err)
+	}
+	if si == nil {
+		return false, nil
+	}
+
+	log.Warnw("sector is already committed on chain, not sending another commit message", "sector", sector.SectorNumber, "activation", si.Activation)
+	return true, nil
+}
+
 func (m *Sealing) handleSubmitCommit(ctx statemachine.Context, sector SectorInfo) error {
 	cfg, err := m.getConfig()
 	if err != nil {
 		return xerrors.Errorf("getting config: %w", err)
 	}
 
+	tok, _, err := m.api.ChainHead(ctx.Context())
+	if err != nil {
+		log.Errorf("handleSubmitCommit: api error, not proceeding: %+v", err)
+		return nil
+	}
+
+	committed, err := m.committedOnChain(ctx.Context(), sector, tok)
+	if err != nil {
+		log.Errorf("handleSubmitCommit: api error, not proceeding: %+v", err)
+		return nil
+	}
+	if committed {
+		return ctx.Send(SectorProving{})
+	}
+
 	if cfg.AggregateCommits {
 		nv, err := m.api.StateNetworkVersion(ctx.Context(), nil)
 		if err != nil {
@@ -584,12 +639,6 @@ func (m *Sealing) handleSubmitCommit(ctx statemachine.Context, sector SectorInfo
 		}
 	}
 
-	tok, _, err := m.api.ChainHead(ctx.Context())
-	if err != nil {
-		log.Errorf("handleSubmitCommit: api error, not proceeding: %+v", err)
-		return nil
-	}
-
 	if err := m.checkCommit(ctx.Context(), sector, sector.Proof, tok); err != nil {
 		return ctx.Send(SectorCommitFailed{xerrors.Errorf("commit check error: %w", err)})
 	}
@@ -651,6 +700,21 @@ func (m *Sealing) handleSubmitCommitAggregate(ctx statemachine.Context, sector S
 		return ctx.Send(SectorCommitFailed{xerrors.Errorf("sector had nil commR or commD")})
 	}
 
+	tok, _, err := m.api.ChainHead(ctx.Context())
+	if err != nil {
+		log.Errorf("handleSubmitCommitAggregate: api error, not proceeding: %+v", err)
+		return nil
+	}
+
+	committed, err := m.committedOnChain(ctx.Context(), sector, tok)
+	if err != nil {
+		log.Errorf("handleSubmitCommitAggregate: api error, not proceeding: %+v", err)
+		return nil
+	}
+	if committed {
+		return ctx.Send(SectorProving{})
+	}
+
 	res, err := m.commiter.AddCommit(ctx.Context(), sector, AggregateInput{
 		Info: proof.AggregateSealVerifyInfo{
 			Number:                sector.SectorNumber,
@@ -733,13 +797,35 @@ func (m *Sealing) handleFinalizeSector(ctx statemachine.Context, sector SectorIn
 		return xerrors.Errorf("getting sealing config: %w", err)
 	}
 
-	if err := m.sealer.FinalizeSector(sector.sealingCtx(ctx.Context()), m.minerSector(sector.SectorType, sector.SectorNumber), sector.keepUnsealedRanges(false, cfg.AlwaysKeepUnsealedCopy)); err != nil {
+	// early finalize happens before the sector is committed, it can't wait
+	if sector.State == FinalizeSector && cfg.DeferFinalize && !m.finalizer.TakeReleased(sector.SectorNumber) {
+		return ctx.Send(SectorFinalizeDeferred{})
+	}
+
+	if err := m.sealer.FinalizeSector(sector.sealingCtx(ctx.Context()), m.minerSector(sector.SectorType, sector.SectorNumber), sector.keepUnsealedRanges(false, keepUnsealedPolicyFromConfig(cfg))); err != nil {
 		return ctx.Send(SectorFinalizeFailed{xerrors.Errorf("finalize sector: %w", err)})
 	}
 
 	return ctx.Send(SectorFinalized{})
 }
 
+func (m *Sealing) handleFinalizeReady(ctx statemachine.Context, sector SectorInfo) error {
+	cfg, err := m.getConfig()
+	if err != nil {
+		return xerrors.Errorf("getting sealing config: %w", err)
+	}
+
+	// deferring was disabled while the sector waited
+	if !cfg.DeferFinalize {
+		return ctx.Send(SectorFinalizeReleased{})
+	}
+
+	// the batcher sends SectorFinalizeReleased when the sector's batch is
+	// released
+	m.finalizer.Add(sector.SectorNumber)
+	return nil
+}
+
 func (m *Sealing) handleProvingSector(ctx statemachine.Context, sector SectorInfo) error {
 	// TODO: track sector health / expiration
 	log.Infof("Proving sector %d", sector.SectorNumber)
@@ -749,7 +835,7 @@ func (m *Sealing) handleProvingSector(ctx statemachine.Context, sector SectorInf
 		return xerrors.Errorf("getting sealing config: %w", err)
 	}
 
-	if err := m.sealer.ReleaseUnsealed(ctx.Context(), m.minerSector(sector.SectorType, sector.SectorNumber), sector.keepUnsealedRanges(true, cfg.AlwaysKeepUnsealedCopy)); err != nil {
+	if err := m.sealer.ReleaseUnsealed(ctx.Context(), m.minerSector(sector.SectorType, sector.SectorNumber), sector.keepUnsealedRanges(true, keepUnsealedPolicyFromConfig(cfg))); err != nil {
 		log.Error(err)
 	}
 
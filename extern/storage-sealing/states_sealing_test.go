@@ -0,0 +1,88 @@
+package sealing
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/specs-storage/storage"
+
+	"github.com/filecoin-project/lotus/chain/actors/builtin/miner"
+	sectorstorage "github.com/filecoin-project/lotus/extern/sector-storage"
+	"github.com/filecoin-project/lotus/extern/storage-sealing/sealiface"
+)
+
+type committedSectorAPI struct {
+	SealingAPI
+
+	sent int
+}
+
+func (a *committedSectorAPI) ChainHead(ctx context.Context) (TipSetToken, abi.ChainEpoch, error) {
+	return nil, 1000, nil
+}
+
+func (a *committedSectorAPI) StateSectorGetInfo(ctx context.Context, maddr address.Address, sectorNumber abi.SectorNumber, tok TipSetToken) (*miner.SectorOnChainInfo, error) {
+	return &miner.SectorOnChainInfo{SectorNumber: sectorNumber, Activation: 900}, nil
+}
+
+func (a *committedSectorAPI) SendMsg(ctx context.Context, from, to address.Address, method abi.MethodNum, value, maxFee abi.TokenAmount, params []byte) (cid.Cid, error) {
+	a.sent++
+	return cid.Undef, nil
+}
+
+type finalizeSealer struct {
+	sectorstorage.SectorManager
+
+	finalized chan abi.SectorID
+}
+
+func (s *finalizeSealer) FinalizeSector(ctx context.Context, sector storage.SectorRef, keepUnsealed []storage.Range) error {
+	s.finalized <- sector.ID
+	return nil
+}
+
+func (s *finalizeSealer) ReleaseUnsealed(ctx context.Context, sector storage.SectorRef, safeToFree []storage.Range) error {
+	return nil
+}
+
+func TestSubmitCommitAlreadyCommitted(t *testing.T) {
+	for _, state := range []SectorState{SubmitCommit, SubmitCommitAggregate} {
+		state := state
+		t.Run(string(state), func(t *testing.T) {
+			api := &committedSectorAPI{}
+			sealer := &finalizeSealer{finalized: make(chan abi.SectorID, 1)}
+
+			m := newSnapshotTestSealing(t)
+			m.api = api
+			m.sealer = sealer
+			m.getConfig = func() (sealiface.Config, error) {
+				return sealiface.Config{AggregateCommits: true}, nil
+			}
+
+			// a sector which got back to submitting its commit after the
+			// commit message already landed on chain
+			require.NoError(t, m.sectors.Begin(uint64(5), &SectorInfo{
+				SectorNumber: 5,
+				State:        state,
+				SectorType:   abi.RegisteredSealProof_StackedDrg2KiBV1_1,
+				CommD:        &cid.Undef,
+				CommR:        &cid.Undef,
+			}))
+			require.NoError(t, m.sectors.Send(uint64(5), SectorRestart{}))
+
+			select {
+			case <-sealer.finalized:
+			case <-time.After(5 * time.Second):
+				t.Fatal("sector wasn't moved to finalize")
+			}
+
+			require.Equal(t, 0, api.sent, "no commit message should be sent")
+		})
+	}
+}
@@ -21,6 +21,7 @@ type SectorStats struct {
 	lk sync.Mutex
 
 	bySector map[abi.SectorID]statSectorState
+	byState  map[abi.SectorID]SectorState
 	totals   [nsst]uint64
 }
 
@@ -39,6 +40,7 @@ func (ss *SectorStats) updateSector(cfg sealiface.Config, id abi.SectorID, st Se
 
 	sst := toStatState(st)
 	ss.bySector[id] = sst
+	ss.byState[id] = st
 	ss.totals[sst]++
 
 	// check if we may need be able to process more deals
@@ -85,3 +87,21 @@ func (ss *SectorStats) curStaging() uint64 {
 
 	return ss.curStagingLocked()
 }
+
+// return the number of sectors in each state, as last seen by the fsm planner
+func (ss *SectorStats) summary() map[SectorState]int {
+	ss.lk.Lock()
+	defer ss.lk.Unlock()
+
+	out := make(map[SectorState]int)
+	for _, st := range ss.byState {
+		if st == UndefinedSectorState {
+			// sectors which are just being created, the planner will pick
+			// them up shortly
+			continue
+		}
+		out[st]++
+	}
+
+	return out
+}
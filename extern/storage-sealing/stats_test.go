@@ -0,0 +1,35 @@
+package sealing
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-state-types/abi"
+
+	"github.com/filecoin-project/lotus/extern/storage-sealing/sealiface"
+)
+
+func TestSectorStatsSummary(t *testing.T) {
+	ss := SectorStats{
+		bySector: map[abi.SectorID]statSectorState{},
+		byState:  map[abi.SectorID]SectorState{},
+	}
+
+	var cfg sealiface.Config
+	sid := func(n abi.SectorNumber) abi.SectorID {
+		return abi.SectorID{Miner: 1000, Number: n}
+	}
+
+	ss.updateSector(cfg, sid(1), UndefinedSectorState)
+	ss.updateSector(cfg, sid(2), WaitDeals)
+	ss.updateSector(cfg, sid(3), PreCommit1)
+	ss.updateSector(cfg, sid(4), Proving)
+	require.Equal(t, map[SectorState]int{WaitDeals: 1, PreCommit1: 1, Proving: 1}, ss.summary())
+
+	// sectors moving through the pipeline are only counted in their current state
+	ss.updateSector(cfg, sid(1), WaitDeals)
+	ss.updateSector(cfg, sid(3), PreCommit2)
+	ss.updateSector(cfg, sid(2), Packing)
+	require.Equal(t, map[SectorState]int{WaitDeals: 1, Packing: 1, PreCommit2: 1, Proving: 1}, ss.summary())
+}
@@ -13,6 +13,7 @@ import (
 
 	"github.com/filecoin-project/lotus/chain/actors/builtin/miner"
 	sectorstorage "github.com/filecoin-project/lotus/extern/sector-storage"
+	"github.com/filecoin-project/lotus/extern/sector-storage/storiface"
 	"github.com/filecoin-project/lotus/extern/storage-sealing/sealiface"
 	"github.com/filecoin-project/specs-actors/v2/actors/builtin/market"
 )
@@ -69,7 +70,8 @@ type SectorInfo struct {
 	State        SectorState
 	SectorNumber abi.SectorNumber
 
-	SectorType abi.RegisteredSealProof
+	SectorType     abi.RegisteredSealProof
+	SyntheticPoRep bool
 
 	// Packing
 	CreationTime int64 // unix seconds
@@ -158,15 +160,41 @@ func (t *SectorInfo) sealingCtx(ctx context.Context) context.Context {
 	//  we need sealed sooner
 
 	if t.hasDeals() {
-		return sectorstorage.WithPriority(ctx, DealSectorPriority)
+		ctx = sectorstorage.WithPriority(ctx, DealSectorPriority)
+	}
+
+	if t.SyntheticPoRep {
+		ctx = storiface.WithSyntheticPoRep(ctx)
 	}
 
 	return ctx
 }
 
+// keepUnsealedPolicy returns whether the unsealed copy of a deal piece should be
+// kept at finalize, in addition to pieces for which the client requested it
+type keepUnsealedPolicy func(piece Piece) bool
+
+func keepUnsealedPolicyFromConfig(cfg sealiface.Config) keepUnsealedPolicy {
+	return func(piece Piece) bool {
+		if cfg.AlwaysKeepUnsealedCopy {
+			return true
+		}
+
+		if cfg.KeepUnsealedVerifiedDeals && piece.DealInfo.DealProposal != nil && piece.DealInfo.DealProposal.VerifiedDeal {
+			return true
+		}
+
+		if cfg.KeepUnsealedMinPieceSize > 0 && piece.Piece.Size >= cfg.KeepUnsealedMinPieceSize {
+			return true
+		}
+
+		return false
+	}
+}
+
 // Returns list of offset/length tuples of sector data ranges which clients
-// requested to keep unsealed
-func (t *SectorInfo) keepUnsealedRanges(invert, alwaysKeep bool) []storage.Range {
+// requested to keep unsealed, or which the policy decided to keep
+func (t *SectorInfo) keepUnsealedRanges(invert bool, policy keepUnsealedPolicy) []storage.Range {
 	var out []storage.Range
 
 	var at abi.UnpaddedPieceSize
@@ -178,7 +206,7 @@ func (t *SectorInfo) keepUnsealedRanges(invert, alwaysKeep bool) []storage.Range
 			continue
 		}
 
-		keep := piece.DealInfo.KeepUnsealed || alwaysKeep
+		keep := piece.DealInfo.KeepUnsealed || policy(piece)
 
 		if keep == invert {
 			continue
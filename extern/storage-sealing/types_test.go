@@ -12,6 +12,9 @@ import (
 	"github.com/filecoin-project/go-state-types/abi"
 	market2 "github.com/filecoin-project/specs-actors/v2/actors/builtin/market"
 	tutils "github.com/filecoin-project/specs-actors/v2/support/testing"
+	"github.com/filecoin-project/specs-storage/storage"
+
+	"github.com/filecoin-project/lotus/extern/storage-sealing/sealiface"
 )
 
 func TestSectorInfoSerialization(t *testing.T) {
@@ -83,3 +86,58 @@ func TestSectorInfoSerialization(t *testing.T) {
 	assert.Equal(t, si.TicketEpoch, si2.TicketEpoch)
 	assert.Equal(t, si.TicketEpoch, si2.TicketEpoch)
 }
+
+func TestKeepUnsealedPolicy(t *testing.T) {
+	dummyCid, err := cid.Parse("bafkqaaa")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deal := func(size abi.PaddedPieceSize, verified bool) Piece {
+		return Piece{
+			Piece: abi.PieceInfo{Size: size, PieceCID: dummyCid},
+			DealInfo: &DealInfo{
+				DealProposal: &market2.DealProposal{
+					PieceCID:     dummyCid,
+					PieceSize:    size,
+					VerifiedDeal: verified,
+				},
+			},
+		}
+	}
+
+	si := &SectorInfo{
+		Pieces: []Piece{
+			deal(1024, true),  // verified
+			deal(1024, false), // small, not verified
+			{Piece: abi.PieceInfo{Size: 2048, PieceCID: dummyCid}}, // filler
+			deal(4096, false), // large
+		},
+	}
+
+	verified := storage.Range{Offset: 0, Size: abi.PaddedPieceSize(1024).Unpadded()}
+	small := storage.Range{Offset: abi.PaddedPieceSize(1024).Unpadded(), Size: abi.PaddedPieceSize(1024).Unpadded()}
+	large := storage.Range{Offset: abi.PaddedPieceSize(4096).Unpadded(), Size: abi.PaddedPieceSize(4096).Unpadded()}
+
+	// only verified deals are kept, the rest is dropped
+	policy := keepUnsealedPolicyFromConfig(sealiface.Config{KeepUnsealedVerifiedDeals: true})
+	assert.DeepEqual(t, []storage.Range{verified}, si.keepUnsealedRanges(false, policy))
+	assert.DeepEqual(t, []storage.Range{small, large}, si.keepUnsealedRanges(true, policy))
+
+	// only deals above the size threshold are kept
+	policy = keepUnsealedPolicyFromConfig(sealiface.Config{KeepUnsealedMinPieceSize: 2048})
+	assert.DeepEqual(t, []storage.Range{large}, si.keepUnsealedRanges(false, policy))
+
+	// an explicit client request is always honored
+	si.Pieces[1].DealInfo.KeepUnsealed = true
+	assert.DeepEqual(t, []storage.Range{small, large}, si.keepUnsealedRanges(false, policy))
+
+	// nothing matches
+	si.Pieces[1].DealInfo.KeepUnsealed = false
+	policy = keepUnsealedPolicyFromConfig(sealiface.Config{})
+	assert.Equal(t, 0, len(si.keepUnsealedRanges(false, policy)))
+
+	// everything is kept
+	policy = keepUnsealedPolicyFromConfig(sealiface.Config{AlwaysKeepUnsealedCopy: true})
+	assert.DeepEqual(t, []storage.Range{verified, small, large}, si.keepUnsealedRanges(false, policy))
+}
@@ -50,6 +50,13 @@ func (m *Sealing) ListSectors() ([]SectorInfo, error) {
 	return sectors, nil
 }
 
+// SectorsSummary returns the number of sectors in each state. Unlike
+// ListSectors it doesn't read the sector store, the counts are kept by the
+// fsm planner as sectors change states.
+func (m *Sealing) SectorsSummary() map[SectorState]int {
+	return m.stats.summary()
+}
+
 func (m *Sealing) GetSectorInfo(sid abi.SectorNumber) (SectorInfo, error) {
 	var out SectorInfo
 	err := m.sectors.Get(uint64(sid)).Get(&out)
@@ -12,6 +12,9 @@ import (
 	"github.com/filecoin-project/lotus/chain/types"
 	sectorstorage "github.com/filecoin-project/lotus/extern/sector-storage"
 	"github.com/filecoin-project/lotus/extern/sector-storage/storiface"
+	"github.com/filecoin-project/lotus/markets/retrievalterms"
+	"github.com/filecoin-project/lotus/markets/retrievaltransport"
+	"github.com/filecoin-project/lotus/markets/sealeta"
 	"github.com/filecoin-project/lotus/node/hello"
 	"github.com/filecoin-project/lotus/paychmgr"
 )
@@ -68,6 +71,35 @@ func main() {
 		os.Exit(1)
 	}
 
+	err = gen.WriteTupleEncodersToFile("./markets/sealeta/cbor_gen.go", "sealeta",
+		sealeta.Request{},
+		sealeta.Response{},
+	)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	err = gen.WriteTupleEncodersToFile("./markets/retrievalterms/cbor_gen.go", "retrievalterms",
+		retrievalterms.Terms{},
+		retrievalterms.Request{},
+		retrievalterms.Response{},
+		retrievalterms.StoredTerms{},
+	)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	err = gen.WriteTupleEncodersToFile("./markets/retrievaltransport/cbor_gen.go", "retrievaltransport",
+		retrievaltransport.Transport{},
+		retrievaltransport.Response{},
+	)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
 	err = gen.WriteTupleEncodersToFile("./chain/market/cbor_gen.go", "market",
 		market.FundedAddressState{},
 	)
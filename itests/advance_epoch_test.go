@@ -0,0 +1,42 @@
+package itests
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/lotus/itests/kit"
+)
+
+func TestAdvanceToEpoch(t *testing.T) {
+	ctx := context.Background()
+
+	kit.QuietMiningLogs()
+
+	client, miner, ens := kit.EnsembleMinimal(t, kit.MockProofs())
+	ens.InterconnectAll()
+	dh := kit.NewDealHarness(t, client, miner)
+
+	head, err := client.ChainHead(ctx)
+	require.NoError(t, err)
+
+	// the miner can lose a round now and then, which leaves a null round
+	mined := dh.MineBlocks(ctx, 3)
+	require.GreaterOrEqual(t, int64(mined), int64(head.Height()+3))
+
+	// skipping ahead mines a single block after null rounds
+	h := dh.AdvanceToEpoch(ctx, mined+100)
+	require.GreaterOrEqual(t, int64(h), int64(mined+100))
+
+	ts, err := client.ChainHead(ctx)
+	require.NoError(t, err)
+	require.Equal(t, h, ts.Height())
+
+	parent, err := client.ChainGetTipSet(ctx, ts.Parents())
+	require.NoError(t, err)
+	require.Equal(t, mined, parent.Height(), "blocks were mined in the skipped epochs")
+
+	// epochs in the past are already reached
+	require.Equal(t, h, dh.AdvanceToEpoch(ctx, h-10))
+}
@@ -7,6 +7,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/filecoin-project/go-fil-markets/storagemarket"
 	"github.com/filecoin-project/go-state-types/abi"
 	"github.com/filecoin-project/go-state-types/big"
 	"github.com/filecoin-project/lotus/extern/storage-sealing/sealiface"
@@ -85,32 +86,18 @@ func TestBatchDealInput(t *testing.T) {
 				}
 			}
 
-			// Starts a deal and waits until it's published
-			runDealTillSeal := func(rseed int) {
-				res, _, _, err := kit.CreateImportFile(ctx, client, rseed, piece)
-				require.NoError(t, err)
-
-				deal := dh.StartDeal(ctx, res.Root, false, dealStartEpoch)
-				dh.WaitDealSealed(ctx, deal, false, true, checkNoPadding)
-			}
-
-			// Run maxDealsPerMsg deals in parallel
-			done := make(chan struct{}, maxDealsPerMsg)
-			for rseed := 0; rseed < int(maxDealsPerMsg); rseed++ {
-				rseed := rseed
-				go func() {
-					runDealTillSeal(rseed)
-					done <- struct{}{}
-				}()
-			}
-
-			// Wait for maxDealsPerMsg of the deals to be published
-			for i := 0; i < int(maxDealsPerMsg); i++ {
-				<-done
-			}
+			// Run maxDealsPerMsg deals in parallel, and wait for them to be
+			// sealed in the sectors they filled
+			dealCids, _ := dh.MakeOnlineDealsConcurrent(ctx, int(maxDealsPerMsg), kit.MakeFullDealParams{
+				FileSize:   piece,
+				StartEpoch: dealStartEpoch,
+			})
+			dh.WaitDealStates(ctx, dealCids, storagemarket.StorageDealActive)
 
 			checkNoPadding()
 
+			require.Len(t, dh.SectorsForDeals(ctx, dealCids), expectSectors)
+
 			sl, err := miner.SectorsList(ctx)
 			require.NoError(t, err)
 			require.Equal(t, len(sl), expectSectors)
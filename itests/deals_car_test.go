@@ -0,0 +1,30 @@
+package itests
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/itests/kit"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDealFromCAR(t *testing.T) {
+	ctx := context.Background()
+
+	kit.QuietMiningLogs()
+
+	client, miner, ens := kit.EnsembleMinimal(t, kit.MockProofs())
+	ens.InterconnectAll().BeginMining(50 * time.Millisecond)
+	dh := kit.NewDealHarness(t, client, miner)
+
+	// build the CAR the deal is made from outside of the client import flow
+	carPath := filepath.Join(t.TempDir(), "data.car")
+	require.NoError(t, client.ClientGenCar(ctx, api.FileRef{Path: kit.CreateRandomFile(t, 7, 0)}, carPath))
+
+	deal, res := dh.MakeOnlineDealFromCAR(ctx, carPath, kit.MakeFullDealParams{})
+
+	dh.PerformCARRetrieval(ctx, deal, res.Root, carPath)
+}
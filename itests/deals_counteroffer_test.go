@@ -0,0 +1,71 @@
+package itests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-fil-markets/storagemarket"
+
+	"github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/itests/kit"
+	"github.com/filecoin-project/lotus/node/config"
+)
+
+func TestDealStartEpochCounterOffer(t *testing.T) {
+	ctx := context.Background()
+
+	kit.QuietMiningLogs()
+
+	client, miner, ens := kit.EnsembleMinimal(t, kit.MockProofs(), kit.MinerConfig(func(cfg *config.StorageMiner) {
+		cfg.Dealmaking.StartEpochCounterOfferSlack = config.Duration(time.Hour)
+	}))
+	ens.InterconnectAll().BeginMining(50 * time.Millisecond)
+	dh := kit.NewDealHarness(t, client, miner)
+
+	res, _ := client.CreateImportFile(ctx, 5, 0)
+
+	// a deal starting right away can't be sealed in time
+	head, err := client.ChainHead(ctx)
+	require.NoError(t, err)
+	tooSoon := dh.StartDeal(ctx, res.Root, false, head.Height()+10)
+
+	var rejected *api.DealInfo
+	require.Eventually(t, func() bool {
+		di, err := client.ClientGetDealInfo(ctx, *tooSoon)
+		require.NoError(t, err)
+
+		switch di.State {
+		case storagemarket.StorageDealProposalRejected, storagemarket.StorageDealError:
+			require.Contains(t, di.Message, "cannot seal a sector before")
+			rejected = di
+			return true
+		}
+		return false
+	}, time.Minute, 100*time.Millisecond)
+
+	start := rejected.StartEpochCounterOffer
+	require.NotZero(t, start, "rejection carries a counter-offer: %s", rejected.Message)
+	require.Greater(t, int64(start), int64(head.Height()+10))
+
+	// accepting the counter-offer proposes the deal again, which proceeds
+	deal, err := client.ClientAcceptStartEpochCounterOffer(ctx, *tooSoon)
+	require.NoError(t, err)
+	require.NotEqual(t, *tooSoon, *deal)
+
+	dh.WaitDealPublished(ctx, deal)
+
+	mds, err := miner.MarketListIncompleteDeals(ctx)
+	require.NoError(t, err)
+
+	var found bool
+	for _, md := range mds {
+		if md.ProposalCid.Equals(*deal) {
+			require.Equal(t, start, md.Proposal.StartEpoch)
+			found = true
+		}
+	}
+	require.True(t, found, "provider has the re-proposed deal")
+}
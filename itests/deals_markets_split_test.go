@@ -0,0 +1,50 @@
+package itests
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/itests/kit"
+	"github.com/filecoin-project/lotus/node/config"
+)
+
+func TestDisabledStorageMarket(t *testing.T) {
+	ctx := context.Background()
+
+	kit.QuietMiningLogs()
+
+	client, miner, ens := kit.EnsembleMinimal(t, kit.MockProofs(), kit.MinerConfig(func(cfg *config.StorageMiner) {
+		cfg.Subsystems.EnableStorageMarket = false
+		// an unreachable markets node doesn't keep the miner from starting
+		cfg.Subsystems.MarketsApiInfo = "token:/ip4/127.0.0.1/tcp/1/http"
+	}))
+	ens.InterconnectAll().BeginMining(50 * time.Millisecond)
+
+	hasProtocol := func(protos []string, prefix string) bool {
+		for _, p := range protos {
+			if strings.HasPrefix(p, prefix) {
+				return true
+			}
+		}
+		return false
+	}
+
+	// the miner still serves retrievals, but not the storage deal protocols
+	var info *api.ExtendedPeerInfo
+	require.Eventually(t, func() bool {
+		var err error
+		info, err = client.NetPeerInfo(ctx, miner.Libp2p.PeerID)
+		return err == nil && hasProtocol(info.Protocols, "/fil/retrieval/qry/")
+	}, 10*time.Second, 100*time.Millisecond)
+
+	require.False(t, hasProtocol(info.Protocols, "/fil/storage/mk/"), info.Protocols)
+	require.False(t, hasProtocol(info.Protocols, "/fil/storage/ask/"), info.Protocols)
+
+	// sealing isn't affected
+	miner.PledgeSectors(ctx, 1, 0, nil)
+}
@@ -0,0 +1,74 @@
+package itests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-fil-markets/storagemarket"
+
+	"github.com/filecoin-project/lotus/itests/kit"
+)
+
+func TestDealsWithSeveralMiners(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test in short mode")
+	}
+
+	ctx := context.Background()
+
+	kit.QuietMiningLogs()
+
+	var (
+		client         kit.TestFullNode
+		minerA, minerB kit.TestMiner
+	)
+
+	ens := kit.NewEnsemble(t, kit.MockProofs())
+	ens.FullNode(&client)
+	ens.Miner(&minerA, &client)
+	ens.Miner(&minerB, &client)
+	ens.Start().InterconnectAll().BeginMining(50 * time.Millisecond)
+
+	dh := kit.NewMultiMinerDealHarness(t, &client, &minerA, &minerB)
+
+	t.Run("distribute", func(t *testing.T) {
+		dealA, _, _ := dh.MakeOnlineDealWithMiner(ctx, 0, kit.MakeFullDealParams{Rseed: 1})
+		dealB, _, _ := dh.MakeOnlineDealWithMiner(ctx, 1, kit.MakeFullDealParams{Rseed: 2})
+
+		di, err := client.ClientGetDealInfo(ctx, *dealA)
+		require.NoError(t, err)
+		require.Equal(t, minerA.ActorAddr, di.Provider)
+
+		di, err = client.ClientGetDealInfo(ctx, *dealB)
+		require.NoError(t, err)
+		require.Equal(t, minerB.ActorAddr, di.Provider)
+	})
+
+	t.Run("retry on another miner", func(t *testing.T) {
+		require.NoError(t, minerA.DealsSetConsiderOnlineStorageDeals(ctx, false))
+		defer func() {
+			require.NoError(t, minerA.DealsSetConsiderOnlineStorageDeals(ctx, true))
+		}()
+
+		res, _ := client.CreateImportFile(ctx, 3, 0)
+
+		rejected := dh.WithMiner(0).StartDeal(ctx, res.Root, false, 0)
+		require.Eventually(t, func() bool {
+			di, err := client.ClientGetDealInfo(ctx, *rejected)
+			require.NoError(t, err)
+			return di.State == storagemarket.StorageDealProposalRejected
+		}, time.Minute, 100*time.Millisecond)
+
+		deal := dh.WithMiner(1).StartDeal(ctx, res.Root, false, 0)
+		// the harness waits on the deal state reported by the provider of the
+		// deal
+		dh.WaitDealSealed(ctx, deal, false, false, nil)
+
+		di, err := client.ClientGetDealInfo(ctx, *deal)
+		require.NoError(t, err)
+		require.Equal(t, minerB.ActorAddr, di.Provider)
+	})
+}
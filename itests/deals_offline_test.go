@@ -73,6 +73,19 @@ func TestOfflineDealFlow(t *testing.T) {
 			return cd.State == storagemarket.StorageDealCheckForAcceptance
 		}, 30*time.Second, 1*time.Second, "actual deal status is %s", storagemarket.DealStates[cd.State])
 
+		// The miner should list the deal as waiting for data
+		var awaiting []api.AwaitingDataDeal
+		require.Eventually(t, func() bool {
+			awaiting, err = miner.MarketListDealsAwaitingData(ctx)
+			require.NoError(t, err)
+			return len(awaiting) == 1
+		}, 30*time.Second, 1*time.Second)
+		require.Equal(t, *proposalCid, awaiting[0].ProposalCid)
+		require.Equal(t, pieceInfo.PieceCID, awaiting[0].PieceCid)
+		require.Equal(t, pieceInfo.PieceSize, awaiting[0].PieceSize)
+		require.Equal(t, startEpoch, awaiting[0].StartEpoch)
+		require.Less(t, int64(awaiting[0].ImportDeadline), int64(startEpoch))
+
 		// Create a CAR file from the raw file
 		carFileDir := t.TempDir()
 		carFilePath := filepath.Join(carFileDir, "out.car")
@@ -87,6 +100,10 @@ func TestOfflineDealFlow(t *testing.T) {
 		// Wait for the deal to be published
 		dh.WaitDealPublished(ctx, proposalCid)
 
+		awaiting, err = miner.MarketListDealsAwaitingData(ctx)
+		require.NoError(t, err)
+		require.Empty(t, awaiting)
+
 		t.Logf("deal published, retrieving")
 
 		// Retrieve the deal
@@ -99,3 +116,87 @@ func TestOfflineDealFlow(t *testing.T) {
 	t.Run("stdretrieval", func(t *testing.T) { runTest(t, false) })
 	t.Run("fastretrieval", func(t *testing.T) { runTest(t, true) })
 }
+
+// TestOfflineDealClientCommP makes an offline deal with a commP calculated by
+// the client from a CAR file, which the provider must agree with when the same
+// CAR file is imported
+func TestOfflineDealClientCommP(t *testing.T) {
+	blocktime := 10 * time.Millisecond
+	startEpoch := abi.ChainEpoch(2 << 12)
+
+	ctx := context.Background()
+	client, miner, ens := kit.EnsembleMinimal(t, kit.MockProofs())
+	ens.InterconnectAll().BeginMining(blocktime)
+
+	dh := kit.NewDealHarness(t, client, miner)
+
+	res, carFilePath, commP := client.CreateImportCar(ctx, 1, 0)
+
+	// The client-side commP of the CAR matches the commP of the imported DAG
+	pieceInfo, err := client.ClientDealPieceCID(ctx, res.Root)
+	require.NoError(t, err)
+	require.Equal(t, pieceInfo.PieceCID, commP.PieceCID)
+	require.Equal(t, pieceInfo.PieceSize, commP.PieceSize)
+
+	maddr, err := miner.ActorAddress(ctx)
+	require.NoError(t, err)
+
+	addr, err := client.WalletDefaultAddress(ctx)
+	require.NoError(t, err)
+
+	proposalCid, err := client.ClientStartDeal(ctx, &api.StartDealParams{
+		Data: &storagemarket.DataRef{
+			TransferType: storagemarket.TTManual,
+			Root:         res.Root,
+			PieceCid:     &commP.PieceCID,
+			PieceSize:    commP.PieceSize.Unpadded(),
+		},
+		Wallet:            addr,
+		Miner:             maddr,
+		EpochPrice:        types.NewInt(1000000),
+		DealStartEpoch:    startEpoch,
+		MinBlocksDuration: uint64(build.MinDealDuration),
+	})
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		awaiting, err := miner.MarketListDealsAwaitingData(ctx)
+		require.NoError(t, err)
+		return len(awaiting) == 1
+	}, 30*time.Second, 1*time.Second)
+
+	// The provider computes commP of the imported CAR, and fails the deal if
+	// it doesn't match the proposal
+	err = miner.DealsImportData(ctx, *proposalCid, carFilePath)
+	require.NoError(t, err)
+
+	dh.WaitDealPublished(ctx, proposalCid)
+
+	deals, err := miner.MarketListIncompleteDeals(ctx)
+	require.NoError(t, err)
+	var found bool
+	for _, d := range deals {
+		if d.ProposalCid == *proposalCid {
+			require.Equal(t, commP.PieceCID, d.Proposal.PieceCID)
+			require.NotEqual(t, storagemarket.StorageDealFailing, d.State)
+			found = true
+		}
+	}
+	require.True(t, found)
+}
+
+func TestOfflineDealHarness(t *testing.T) {
+	ctx := context.Background()
+	client, miner, ens := kit.EnsembleMinimal(t, kit.MockProofs())
+	ens.InterconnectAll().BeginMining(10 * time.Millisecond)
+
+	dh := kit.NewDealHarness(t, client, miner)
+
+	deal, res, inFile := dh.MakeOfflineDeal(ctx, kit.MakeFullDealParams{
+		Rseed:      3,
+		StartEpoch: abi.ChainEpoch(2 << 12),
+	})
+
+	outFile := dh.PerformRetrieval(ctx, deal, res.Root, false)
+	kit.AssertFilesEqual(t, inFile, outFile)
+}
@@ -52,9 +52,6 @@ func TestFirstDealEnablesMining(t *testing.T) {
 	// now perform the deal.
 	deal := dh.StartDeal(ctx, ref.Root, false, 0)
 
-	// TODO: this sleep is only necessary because deals don't immediately get logged in the dealstore, we should fix this
-	time.Sleep(time.Second)
-
 	dh.WaitDealSealed(ctx, deal, false, false, nil)
 
 	<-providerMined
@@ -6,8 +6,10 @@ import (
 	"testing"
 	"time"
 
+	"github.com/filecoin-project/go-address"
 	"github.com/filecoin-project/go-fil-markets/storagemarket"
 	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
 	"github.com/filecoin-project/lotus/api"
 	"github.com/filecoin-project/lotus/chain/actors/builtin/market"
 	"github.com/filecoin-project/lotus/chain/types"
@@ -129,3 +131,65 @@ func TestPublishDealsBatching(t *testing.T) {
 	case <-done: // Success
 	}
 }
+
+// TestDealCollateralFromPublishControl checks that provider collateral is
+// added to the market escrow of the miner from the deal publish control
+// address, when DealCollateralFromPublishControl is set
+func TestDealCollateralFromPublishControl(t *testing.T) {
+	ctx := context.Background()
+
+	kit.QuietMiningLogs()
+
+	publisherKey, err := wallet.GenerateKey(types.KTSecp256k1)
+	require.NoError(t, err)
+
+	opts := node.Options(
+		node.Override(new(*storage.AddressSelector), modules.AddressSelector(&config.MinerAddressConfig{
+			DealPublishControl: []string{
+				publisherKey.Address.String(),
+			},
+			DealCollateralFromPublishControl: true,
+		})),
+		kit.LatestActorsAt(-1),
+	)
+
+	client, miner, ens := kit.EnsembleMinimal(t, kit.Account(publisherKey, types.FromFil(10)), kit.MockProofs(), kit.ConstructorOpts(opts))
+	ens.InterconnectAll().BeginMining(10 * time.Millisecond)
+
+	_, err = client.WalletImport(ctx, &publisherKey.KeyInfo)
+	require.NoError(t, err)
+
+	miner.SetControlAddresses(publisherKey.Address)
+
+	dh := kit.NewDealHarness(t, client, miner)
+
+	// the kit sets the minimum collateral to zero, propose some so that the
+	// miner has to add funds to its escrow
+	res, _ := client.CreateImportFile(ctx, 1, 0)
+	deal := dh.StartDealWithParams(ctx, res.Root, kit.MakeFullDealParams{ProviderCollateral: big.NewInt(12345)})
+	dh.WaitDealPublished(ctx, deal)
+
+	msgCids, err := client.StateListMessages(ctx, &api.MessageMatch{To: market.Address}, types.EmptyTSK, 1)
+	require.NoError(t, err)
+
+	count := 0
+	for _, msgCid := range msgCids {
+		msg, err := client.ChainGetMessage(ctx, msgCid)
+		require.NoError(t, err)
+
+		if msg.Method != market.Methods.AddBalance {
+			continue
+		}
+
+		// clients add balance for themselves too
+		var escrow address.Address
+		require.NoError(t, escrow.UnmarshalCBOR(bytes.NewReader(msg.Params)))
+		if escrow != miner.ActorAddr {
+			continue
+		}
+
+		count++
+		require.Equal(t, publisherKey.Address.String(), msg.From.String())
+	}
+	require.NotZero(t, count, "no market balance added for the miner")
+}
@@ -0,0 +1,74 @@
+package itests
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	"github.com/ipld/go-car"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-fil-markets/storagemarket"
+
+	"github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/itests/kit"
+	"github.com/filecoin-project/lotus/node/config"
+)
+
+func TestFailedDealDataQuarantined(t *testing.T) {
+	ctx := context.Background()
+
+	kit.QuietMiningLogs()
+
+	client, miner, ens := kit.EnsembleMinimal(t, kit.MockProofs(), kit.MinerConfig(func(cfg *config.StorageMiner) {
+		cfg.Dealmaking.FailedDealRetention = config.Duration(time.Hour)
+	}))
+	ens.InterconnectAll().BeginMining(50 * time.Millisecond)
+	dh := kit.NewDealHarness(t, client, miner)
+
+	res, _ := client.CreateImportFile(ctx, 5, 0)
+	other, _ := client.CreateImportFile(ctx, 6, 0)
+
+	// propose the data with the piece CID of other data, the provider fails the
+	// deal when it checks the piece CID after the transfer
+	wrong, err := client.ClientDealPieceCID(ctx, other.Root)
+	require.NoError(t, err)
+
+	deal := dh.StartDealWithData(ctx, &storagemarket.DataRef{
+		TransferType: storagemarket.TTGraphsync,
+		Root:         res.Root,
+		PieceCid:     &wrong.PieceCID,
+		PieceSize:    wrong.PieceSize.Unpadded(),
+	}, kit.MakeFullDealParams{})
+
+	var ents []api.QuarantinedData
+	require.Eventually(t, func() bool {
+		ents, err = miner.MarketListQuarantinedData(ctx)
+		require.NoError(t, err)
+		return len(ents) > 0
+	}, time.Minute, 100*time.Millisecond)
+
+	require.Len(t, ents, 1)
+	require.Equal(t, *deal, ents[0].ProposalCid)
+	require.Equal(t, wrong.PieceCID, ents[0].PieceCid)
+	require.NotEmpty(t, ents[0].Message)
+	require.Len(t, ents[0].Files, 1)
+
+	// the transferred blocks are kept, rooted at the proposed data
+	f, err := os.Open(ents[0].Files[0])
+	require.NoError(t, err)
+	defer f.Close() //nolint:errcheck
+
+	cr, err := car.NewCarReader(f)
+	require.NoError(t, err)
+	require.Equal(t, []cid.Cid{res.Root}, cr.Header.Roots)
+	_, err = cr.Next()
+	require.NoError(t, err)
+
+	require.NoError(t, miner.MarketPurgeQuarantinedData(ctx, *deal))
+	ents, err = miner.MarketListQuarantinedData(ctx)
+	require.NoError(t, err)
+	require.Empty(t, ents)
+}
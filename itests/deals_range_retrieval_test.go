@@ -0,0 +1,96 @@
+package itests
+
+import (
+	"bytes"
+	"context"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	chunker "github.com/ipfs/go-ipfs-chunker"
+	"github.com/ipfs/go-merkledag"
+	dstest "github.com/ipfs/go-merkledag/test"
+	"github.com/ipfs/go-unixfs/importer/balanced"
+	ihelper "github.com/ipfs/go-unixfs/importer/helpers"
+	"github.com/ipld/go-car"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/itests/kit"
+)
+
+// importChunkedCAR imports data chunked into small leaves, so that a file
+// fitting a test sector still has a few of them
+func importChunkedCAR(ctx context.Context, t *testing.T, client *kit.TestFullNode, data []byte, chunkSize int64) cid.Cid {
+	dserv := dstest.Mock()
+
+	prefix, err := merkledag.PrefixForCidVersion(1)
+	require.NoError(t, err)
+
+	db, err := ihelper.DagBuilderParams{
+		Maxlinks:   ihelper.DefaultLinksPerBlock,
+		RawLeaves:  true,
+		CidBuilder: prefix,
+		Dagserv:    dserv,
+	}.New(chunker.NewSizeSplitter(bytes.NewReader(data), chunkSize))
+	require.NoError(t, err)
+
+	nd, err := balanced.Layout(db)
+	require.NoError(t, err)
+
+	carPath := filepath.Join(t.TempDir(), "chunked.car")
+	f, err := os.Create(carPath)
+	require.NoError(t, err)
+	require.NoError(t, car.WriteCar(ctx, dserv, []cid.Cid{nd.Cid()}, f))
+	require.NoError(t, f.Close())
+
+	res, err := client.ClientImport(ctx, api.FileRef{Path: carPath, IsCAR: true})
+	require.NoError(t, err)
+	require.Equal(t, nd.Cid(), res.Root)
+
+	return res.Root
+}
+
+func TestDealRangeRetrieval(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test in short mode")
+	}
+
+	ctx := context.Background()
+
+	kit.QuietMiningLogs()
+
+	client, miner, ens := kit.EnsembleMinimal(t, kit.MockProofs())
+	ens.InterconnectAll().BeginMining(50 * time.Millisecond)
+	dh := kit.NewDealHarness(t, client, miner)
+
+	data := make([]byte, 1000)
+	rand.New(rand.NewSource(5)).Read(data)
+
+	// four leaves of 256, 256, 256 and 232 bytes
+	root := importChunkedCAR(ctx, t, client, data, 256)
+
+	deal := dh.StartDeal(ctx, root, false, 0)
+	dh.WaitDealSealed(ctx, deal, false, false, nil)
+
+	t.Run("byte range", func(t *testing.T) {
+		// covers the second and third leaf
+		got := dh.PerformRangeRetrieval(ctx, deal, root, kit.RangeRetrievalParams{Offset: 300, Length: 300})
+		require.Equal(t, data[300:600], got)
+	})
+
+	t.Run("last bytes", func(t *testing.T) {
+		got := dh.PerformRangeRetrieval(ctx, deal, root, kit.RangeRetrievalParams{Offset: 900, Length: 100})
+		require.Equal(t, data[900:], got)
+	})
+
+	t.Run("selector", func(t *testing.T) {
+		// the whole DAG
+		sel := api.Selector(`{"R":{"l":{"none":{}},":>":{"a":{">":{"@":{}}}}}}`)
+		got := dh.PerformRangeRetrieval(ctx, deal, root, kit.RangeRetrievalParams{Selector: &sel})
+		require.Equal(t, data, got)
+	})
+}
@@ -0,0 +1,35 @@
+package itests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/filecoin-project/lotus/itests/kit"
+	"github.com/filecoin-project/lotus/node/config"
+)
+
+func TestDealRetrievalHTTP(t *testing.T) {
+	ctx := context.Background()
+
+	kit.QuietMiningLogs()
+
+	client, miner, ens := kit.EnsembleMinimal(t, kit.MockProofs(), kit.MinerConfig(func(cfg *config.StorageMiner) {
+		cfg.Dealmaking.HTTPRetrieval.ListenAddress = "127.0.0.1:0"
+	}))
+	ens.InterconnectAll().BeginMining(10 * time.Millisecond)
+
+	dh := kit.NewDealHarness(t, client, miner)
+
+	deal, res, inPath := dh.MakeOnlineDeal(ctx, kit.MakeFullDealParams{Rseed: 6})
+
+	t.Run("file", func(t *testing.T) {
+		outPath := dh.PerformRetrievalHTTP(ctx, deal, res.Root, false)
+		kit.AssertFilesEqual(t, inPath, outPath)
+	})
+
+	t.Run("car", func(t *testing.T) {
+		outPath := dh.PerformRetrievalHTTP(ctx, deal, res.Root, true)
+		kit.AssertFilesEqual(t, inPath, outPath)
+	})
+}
@@ -0,0 +1,65 @@
+package itests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-fil-markets/storagemarket"
+	"github.com/filecoin-project/go-state-types/abi"
+
+	"github.com/filecoin-project/lotus/itests/kit"
+	"github.com/filecoin-project/lotus/node/config"
+)
+
+func TestDealRetrievalTerms(t *testing.T) {
+	var (
+		ctx       = context.Background()
+		blocktime = 10 * time.Millisecond
+	)
+
+	kit.QuietMiningLogs()
+
+	client, miner, ens := kit.EnsembleMinimal(t, kit.MockProofs(), kit.MinerConfig(func(cfg *config.StorageMiner) {
+		cfg.Dealmaking.ClientRetrievalTerms.Accept = true
+	}))
+	ens.InterconnectAll().BeginMining(blocktime)
+
+	ask, err := miner.MarketGetRetrievalAsk(ctx)
+	require.NoError(t, err)
+	ask.PricePerByte = abi.NewTokenAmount(1)
+	ask.UnsealPrice = abi.NewTokenAmount(0)
+	require.NoError(t, miner.MarketSetRetrievalAsk(ctx, ask))
+
+	dh := kit.NewDealHarness(t, client, miner)
+
+	res, _ := client.CreateImportFile(ctx, 5, 0)
+	var (
+		termsPPB    = abi.NewTokenAmount(3)
+		termsUnseal = abi.NewTokenAmount(11)
+	)
+
+	// the client pre-sets retrieval terms once the deal is accepted, before
+	// it's sealed
+	deal := dh.StartDeal(ctx, res.Root, true, 0)
+	dh.WaitDealStates(ctx, []*cid.Cid{deal}, storagemarket.StorageDealProposalAccepted)
+	require.NoError(t, client.ClientSetDealRetrievalTerms(ctx, *deal, termsPPB, termsUnseal))
+
+	dh.WaitDealSealed(ctx, deal, false, false, nil)
+
+	dealInfo, err := client.ClientGetDealInfo(ctx, *deal)
+	require.NoError(t, err)
+
+	// the quote uses the terms set for the deal, not the retrieval ask; the
+	// unseal price is zero as a fast-retrieval copy is kept unsealed
+	offers, err := client.ClientFindData(ctx, res.Root, &dealInfo.PieceCID)
+	require.NoError(t, err)
+	require.Len(t, offers, 1)
+	require.Empty(t, offers[0].Err)
+	require.Equal(t, dealInfo.Size*termsPPB.Uint64(), offers[0].MinPrice.Uint64())
+
+	dh.PerformRetrieval(ctx, deal, res.Root, false)
+}
@@ -0,0 +1,77 @@
+package itests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-state-types/big"
+
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/lotus/itests/kit"
+)
+
+func TestMarketRevenue(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test in short mode")
+	}
+
+	ctx := context.Background()
+
+	kit.QuietMiningLogs()
+
+	client, miner, ens := kit.EnsembleMinimal(t, kit.MockProofs())
+	ens.InterconnectAll().BeginMining(50 * time.Millisecond)
+	dh := kit.NewDealHarness(t, client, miner)
+
+	var dealIDs []uint64
+	for _, rseed := range []int{1, 2} {
+		deal, _, _ := dh.MakeOnlineDeal(ctx, kit.MakeFullDealParams{Rseed: rseed})
+
+		di, err := client.ClientGetDealInfo(ctx, *deal)
+		require.NoError(t, err)
+		dealIDs = append(dealIDs, uint64(di.DealID))
+	}
+
+	// let some payments be released
+	head, err := client.ChainHead(ctx)
+	require.NoError(t, err)
+	client.WaitTillChain(ctx, kit.HeightAtLeast(head.Height()+20))
+
+	rep, err := miner.MarketRevenue(ctx, 0)
+	require.NoError(t, err)
+	require.Len(t, rep.Deals, len(dealIDs))
+
+	realized, locked := big.Zero(), big.Zero()
+	for i, dr := range rep.Deals {
+		require.EqualValues(t, dealIDs[i], dr.DealID)
+		require.True(t, dr.Active)
+
+		md, err := client.StateMarketStorageDeal(ctx, dr.DealID, types.EmptyTSK)
+		require.NoError(t, err)
+		require.Equal(t, md.Proposal.StoragePricePerEpoch, dr.PricePerEpoch)
+
+		// what was paid and what is still due adds up to the deal price
+		total := big.Mul(md.Proposal.StoragePricePerEpoch, big.NewInt(int64(md.Proposal.EndEpoch-md.Proposal.StartEpoch)))
+		require.Equal(t, total, big.Add(dr.Realized, dr.Locked))
+
+		// the realized revenue matches the epochs the deal was paid for
+		if md.State.LastUpdatedEpoch > md.Proposal.StartEpoch {
+			require.Equal(t, big.Mul(md.Proposal.StoragePricePerEpoch, big.NewInt(int64(md.State.LastUpdatedEpoch-md.Proposal.StartEpoch))), dr.Realized)
+		} else {
+			require.True(t, dr.Realized.IsZero())
+		}
+
+		realized = big.Add(realized, dr.Realized)
+		locked = big.Add(locked, dr.Locked)
+	}
+	require.Equal(t, realized, rep.Realized)
+	require.Equal(t, locked, rep.Locked)
+
+	// nothing was paid for epochs after the head
+	rep, err = miner.MarketRevenue(ctx, rep.Epoch+1000)
+	require.NoError(t, err)
+	require.True(t, rep.Realized.IsZero())
+}
@@ -0,0 +1,33 @@
+package itests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-fil-markets/storagemarket"
+
+	"github.com/filecoin-project/lotus/itests/kit"
+)
+
+func TestDealSlashedOnTermination(t *testing.T) {
+	kit.Expensive(t)
+
+	ctx := context.Background()
+
+	kit.QuietMiningLogs()
+
+	client, miner, ens := kit.EnsembleMinimal(t, kit.MockProofs(), kit.ConstructorOpts(kit.LatestActorsAt(-1)))
+	ens.InterconnectAll().BeginMining(10 * time.Millisecond)
+	dh := kit.NewDealHarness(t, client, miner)
+
+	deal, _, _ := dh.MakeOnlineDeal(ctx, kit.MakeFullDealParams{Rseed: 1})
+
+	dh.TerminateSectorForDeal(ctx, deal)
+
+	di, err := client.ClientGetDealInfo(ctx, *deal)
+	require.NoError(t, err)
+	require.Equal(t, storagemarket.StorageDealSlashed, di.State)
+}
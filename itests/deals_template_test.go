@@ -0,0 +1,52 @@
+package itests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/lotus/itests/kit"
+)
+
+func TestDealFromTemplate(t *testing.T) {
+	ctx := context.Background()
+
+	kit.QuietMiningLogs()
+
+	client, miner, ens := kit.EnsembleMinimal(t, kit.MockProofs())
+	ens.InterconnectAll().BeginMining(10 * time.Millisecond)
+
+	dh := kit.NewDealHarness(t, client, miner)
+
+	res, _ := client.CreateImportFile(ctx, 5, 0)
+
+	_, err := client.ClientStartDealFromTemplate(ctx, "mytemplate", res.Root)
+	require.Error(t, err, "template wasn't saved yet")
+
+	tmpl := dh.SaveDealTemplate(ctx, "mytemplate", true)
+
+	fromTemplate, err := client.ClientStartDealFromTemplate(ctx, "mytemplate", res.Root)
+	require.NoError(t, err)
+
+	// a deal started directly with the same parameters
+	direct := dh.StartDeal(ctx, res.Root, true, 0)
+
+	tmplInfo, err := client.ClientGetDealInfo(ctx, *fromTemplate)
+	require.NoError(t, err)
+	directInfo, err := client.ClientGetDealInfo(ctx, *direct)
+	require.NoError(t, err)
+
+	require.Equal(t, tmpl.Miner, tmplInfo.Provider)
+	require.Equal(t, res.Root, tmplInfo.DataRef.Root)
+	require.Equal(t, tmpl.VerifiedDeal, tmplInfo.Verified)
+	require.Equal(t, directInfo.PricePerEpoch, tmplInfo.PricePerEpoch)
+	// deal end is aligned to the proving period, so the duration may be
+	// slightly longer than the minimum
+	md, err := client.StateMinerProvingDeadline(ctx, tmpl.Miner, types.EmptyTSK)
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, tmplInfo.Duration, tmpl.MinBlocksDuration)
+	require.Less(t, tmplInfo.Duration, tmpl.MinBlocksDuration+uint64(md.WPoStProvingPeriod))
+}
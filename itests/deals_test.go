@@ -1,9 +1,22 @@
 package itests
 
 import (
+	"context"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-fil-markets/retrievalmarket"
+	"github.com/filecoin-project/go-fil-markets/storagemarket"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
+
+	"github.com/filecoin-project/lotus/chain/actors/policy"
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/lotus/chain/wallet"
 	"github.com/filecoin-project/lotus/itests/kit"
 )
 
@@ -33,3 +46,366 @@ func TestDealsWithSealingAndRPC(t *testing.T) {
 		dh.RunConcurrentDeals(kit.RunConcurrentDealsOpts{N: 1, FastRetrieval: true})
 	})
 }
+
+// TestDealFastRetrievalUnsealedCopy checks that an unsealed copy of the sector
+// is only kept for deals made with fast retrieval
+func TestDealFastRetrievalUnsealedCopy(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test in short mode")
+	}
+
+	ctx := context.Background()
+
+	kit.QuietMiningLogs()
+
+	client, miner, ens := kit.EnsembleMinimal(t) // no mock proofs.
+	ens.InterconnectAll().BeginMining(50 * time.Millisecond)
+	dh := kit.NewDealHarness(t, client, miner)
+
+	deal, _, _ := dh.MakeOnlineDeal(ctx, kit.MakeFullDealParams{Rseed: 6, FastRet: false})
+	dh.AssertUnsealedCopy(ctx, deal, false)
+
+	deal, _, _ = dh.MakeOnlineDeal(ctx, kit.MakeFullDealParams{Rseed: 7, FastRet: true})
+	dh.AssertUnsealedCopy(ctx, deal, true)
+}
+
+// TestDealSectorCorrupted corrupts the sealed sector of a deal on the miner,
+// and checks that the WindowPoSt of the miner marks the sector faulty
+func TestDealSectorCorrupted(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test in short mode")
+	}
+
+	ctx := context.Background()
+
+	kit.QuietMiningLogs()
+
+	client, miner, ens := kit.EnsembleMinimal(t) // no mock proofs.
+	ens.InterconnectAll().BeginMining(50 * time.Millisecond)
+	dh := kit.NewDealHarness(t, client, miner)
+
+	deal, _, _ := dh.MakeOnlineDeal(ctx, kit.MakeFullDealParams{Rseed: 13})
+	snum := dh.CorruptSectorForDeal(ctx, deal)
+	t.Logf("corrupted sector %d", snum)
+
+	dh.WaitSectorFaultyForDeal(ctx, deal)
+}
+
+// TestDealImportContent makes deals on imported content, and checks that the
+// content imports to the same root, and is retrieved unchanged
+func TestDealImportContent(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test in short mode")
+	}
+
+	ctx := context.Background()
+
+	kit.QuietMiningLogs()
+
+	client, miner, ens := kit.EnsembleMinimal(t, kit.MockProofs())
+	ens.InterconnectAll().BeginMining(50 * time.Millisecond)
+	dh := kit.NewDealHarness(t, client, miner)
+
+	pattern := make([]byte, 2032) // the unpadded size of a 2KiB piece
+	for i := range pattern {
+		pattern[i] = byte(i)
+	}
+
+	for name, data := range map[string][]byte{
+		"zeros":   make([]byte, 1600),
+		"aligned": pattern,
+	} {
+		root, inPath := dh.ImportContent(ctx, data)
+
+		again, _ := dh.ImportContent(ctx, data)
+		require.Equal(t, root, again, "%s: the same content should import to the same root", name)
+
+		dcs, err := client.ClientDealPieceCID(ctx, root)
+		require.NoError(t, err)
+
+		deal := dh.StartDealWithParams(ctx, root, kit.MakeFullDealParams{})
+		dh.WaitDealSealed(ctx, deal, false, false, nil)
+		dh.AssertDealPieceCID(ctx, deal, dcs.PieceCID)
+
+		outPath := dh.PerformRetrieval(ctx, deal, root, false)
+		kit.AssertFilesEqual(t, inPath, outPath)
+	}
+}
+
+func TestDealFileSize(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test in short mode")
+	}
+
+	ctx := context.Background()
+
+	kit.QuietMiningLogs()
+
+	client, miner, ens := kit.EnsembleMinimal(t, kit.MockProofs())
+	ens.InterconnectAll().BeginMining(50 * time.Millisecond)
+	dh := kit.NewDealHarness(t, client, miner)
+
+	for _, size := range []int{0, 200, 1200} {
+		deal, res, _ := dh.MakeOnlineDeal(ctx, kit.MakeFullDealParams{Rseed: 5, FileSize: size, CheckPieceCID: true})
+
+		ds, err := client.ClientDealSize(ctx, res.Root)
+		require.NoError(t, err)
+
+		di, err := client.ClientGetDealInfo(ctx, *deal)
+		require.NoError(t, err)
+		require.EqualValues(t, ds.PieceSize, di.Size, "file size %d", size)
+	}
+}
+
+func TestDealPriceAndVerified(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test in short mode")
+	}
+
+	ctx := context.Background()
+
+	kit.QuietMiningLogs()
+
+	rootKey, err := wallet.GenerateKey(types.KTSecp256k1)
+	require.NoError(t, err)
+
+	client, miner, ens := kit.EnsembleMinimal(t, kit.MockProofs(), kit.RootVerifier(rootKey, types.FromFil(100)))
+	ens.InterconnectAll().BeginMining(50 * time.Millisecond)
+	dh := kit.NewDealHarness(t, client, miner)
+
+	t.Run("free", func(t *testing.T) {
+		err := miner.MarketSetAsk(ctx, big.Zero(), big.Zero(), 100000, 128, 32<<30)
+		require.NoError(t, err)
+
+		deal, _, _ := dh.MakeOnlineDeal(ctx, kit.MakeFullDealParams{Rseed: 1, Price: big.Zero()})
+
+		di, err := client.ClientGetDealInfo(ctx, *deal)
+		require.NoError(t, err)
+		require.True(t, di.PricePerEpoch.IsZero())
+	})
+
+	t.Run("verified", func(t *testing.T) {
+		dh.GrantDatacap(ctx, rootKey, abi.NewStoragePower(1<<20))
+
+		deal, _, _ := dh.MakeOnlineDeal(ctx, kit.MakeFullDealParams{Rseed: 2, Verified: true})
+
+		di, err := client.ClientGetDealInfo(ctx, *deal)
+		require.NoError(t, err)
+		require.True(t, di.Verified)
+		require.Equal(t, kit.DefaultEpochPrice, di.PricePerEpoch)
+	})
+}
+
+func TestDealStateDeadlines(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test in short mode")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	kit.QuietMiningLogs()
+
+	client, miner, ens := kit.EnsembleMinimal(t, kit.MockProofs())
+	ens.InterconnectAll().BeginMining(50 * time.Millisecond)
+	dh := kit.NewDealHarness(t, client, miner)
+
+	res, _ := client.CreateImportFile(ctx, 6, 0)
+	deal := dh.StartDeal(ctx, res.Root, false, 0)
+
+	// mock sealing is fast, so sealing must start soon
+	dh.WaitDealSealedWithDeadlines(ctx, deal, false, false, nil, kit.DealStateDeadlines{
+		storagemarket.StorageDealSealing: 2 * time.Minute,
+	})
+}
+
+func TestRetrievalEvents(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test in short mode")
+	}
+
+	ctx := context.Background()
+
+	kit.QuietMiningLogs()
+
+	client, miner, ens := kit.EnsembleMinimal(t, kit.MockProofs())
+	ens.InterconnectAll().BeginMining(50 * time.Millisecond)
+	dh := kit.NewDealHarness(t, client, miner)
+
+	deal, res, inPath := dh.MakeOnlineDeal(ctx, kit.MakeFullDealParams{Rseed: 7})
+
+	events, outPath, err := dh.PerformRetrievalWithEvents(ctx, deal, res.Root, false)
+	require.NoError(t, err)
+	kit.AssertFilesEqual(t, inPath, outPath)
+
+	require.NotEmpty(t, events)
+	require.Equal(t, retrievalmarket.DealStatusCompleted, events[len(events)-1].Status)
+
+	ongoing := -1
+	for i, evt := range events {
+		require.Empty(t, evt.Err)
+		if evt.Status == retrievalmarket.DealStatusOngoing && ongoing < 0 {
+			ongoing = i
+		}
+	}
+	require.True(t, ongoing >= 0 && ongoing < len(events)-1, "retrieval didn't pass through DealStatusOngoing before completing")
+}
+
+func TestRetrievalRestart(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test in short mode")
+	}
+
+	ctx := context.Background()
+
+	kit.QuietMiningLogs()
+
+	// 8MiB sectors, for deal data large enough to interrupt the transfer part
+	// way through
+	client, miner, ens := kit.EnsembleMinimal(t, kit.MockProofs(), kit.ProofType(abi.RegisteredSealProof_StackedDrg8MiBV1_1))
+	ens.InterconnectAll().BeginMining(50 * time.Millisecond)
+	dh := kit.NewDealHarness(t, client, miner)
+
+	deal, res, inPath := dh.MakeOnlineDeal(ctx, kit.MakeFullDealParams{Rseed: 8, FileSize: 4 << 20})
+
+	outPath := dh.PerformRetrievalWithRestart(ctx, deal, res.Root, 1<<20)
+	kit.AssertFilesEqual(t, inPath, outPath)
+
+	// a cancelled retrieval fails instead of completing
+	err := dh.PerformRetrievalWithCancel(ctx, deal, res.Root, 1<<20)
+	require.Contains(t, err.Error(), "cancelled")
+}
+
+func TestDealSectorExtension(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test in short mode")
+	}
+
+	ctx := context.Background()
+
+	kit.QuietMiningLogs()
+
+	client, miner, ens := kit.EnsembleMinimal(t, kit.MockProofs())
+	ens.InterconnectAll().BeginMining(10 * time.Millisecond)
+	dh := kit.NewDealHarness(t, client, miner)
+
+	deal, _, _ := dh.MakeOnlineDeal(ctx, kit.MakeFullDealParams{Rseed: 9})
+
+	snum := dh.SectorForDeal(ctx, deal)
+	si, err := client.StateSectorGetInfo(ctx, miner.ActorAddr, snum, types.EmptyTSK)
+	require.NoError(t, err)
+
+	newExp := dh.ExtendSectorForDeal(ctx, deal, 10000)
+	require.Equal(t, si.Expiration+10000, newExp)
+
+	// the expiration can't be reduced
+	_, err = miner.SectorExtend(ctx, snum, si.Expiration)
+	require.Error(t, err)
+}
+
+func TestDealRejectedByFilter(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test in short mode")
+	}
+
+	ctx := context.Background()
+
+	kit.QuietMiningLogs()
+
+	// reject deals of the piece of the second file only
+	var rejected atomic.Value
+	filter := func(ctx context.Context, deal storagemarket.MinerDeal) (bool, string, error) {
+		if deal.Proposal.PieceCID == rejected.Load() {
+			return false, "piece rejected by test", nil
+		}
+		return true, "", nil
+	}
+
+	client, miner, ens := kit.EnsembleMinimal(t, kit.MockProofs(), kit.StorageDealFilter(filter))
+	ens.InterconnectAll().BeginMining(50 * time.Millisecond)
+	dh := kit.NewDealHarness(t, client, miner)
+
+	res, _ := client.CreateImportFile(ctx, 10, 0)
+	pc, err := client.ClientDealPieceCID(ctx, res.Root)
+	require.NoError(t, err)
+	rejected.Store(pc.PieceCID)
+
+	deal := dh.StartDeal(ctx, res.Root, false, 0)
+	require.Contains(t, dh.ExpectRejected(ctx, deal), "piece rejected by test")
+
+	// the rejection shows up in the deal diagnostics of the miner
+	diag, err := miner.MarketDealDiagnostics(ctx)
+	require.NoError(t, err)
+
+	var found bool
+	for _, d := range diag {
+		if d.ProposalCid == *deal && strings.Contains(d.Message, "piece rejected by test") {
+			found = true
+		}
+	}
+	require.True(t, found, "no diagnostic record of the rejection: %+v", diag)
+
+	// other deals are still accepted
+	dh.MakeOnlineDeal(ctx, kit.MakeFullDealParams{Rseed: 11})
+}
+
+func TestDealProviderCollateral(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test in short mode")
+	}
+
+	ctx := context.Background()
+
+	kit.QuietMiningLogs()
+
+	client, miner, ens := kit.EnsembleMinimal(t, kit.MockProofs())
+	ens.InterconnectAll().BeginMining(50 * time.Millisecond)
+	dh := kit.NewDealHarness(t, client, miner)
+
+	// the proposed collateral ends up on chain
+	collateral := big.NewInt(12345)
+	deal, _, _ := dh.MakeOnlineDeal(ctx, kit.MakeFullDealParams{Rseed: 12, ProviderCollateral: collateral})
+
+	md := dh.WaitOnChainDealActive(ctx, deal)
+	require.Equal(t, collateral.String(), md.Proposal.ProviderCollateral.String())
+
+	// the kit sets the collateral supply target to zero, restore a target so
+	// that there is a minimum collateral
+	policy.SetProviderCollateralSupplyTarget(big.NewInt(1), big.NewInt(100))
+	t.Cleanup(func() {
+		policy.SetProviderCollateralSupplyTarget(big.Zero(), big.NewInt(1))
+	})
+
+	res, _ := client.CreateImportFile(ctx, 13, 0)
+	ds, err := client.ClientDealSize(ctx, res.Root)
+	require.NoError(t, err)
+
+	bounds := dh.ProviderCollateralBounds(ctx, ds.PieceSize, false)
+	below := big.Div(bounds.Min, big.NewInt(2))
+	require.False(t, below.IsZero(), "minimum collateral too low: %s", bounds.Min)
+
+	deal = dh.StartDealWithParams(ctx, res.Root, kit.MakeFullDealParams{ProviderCollateral: below})
+	require.Contains(t, dh.ExpectRejected(ctx, deal), "collateral")
+}
+
+func TestDealDuration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test in short mode")
+	}
+
+	ctx := context.Background()
+
+	kit.QuietMiningLogs()
+
+	client, miner, ens := kit.EnsembleMinimal(t, kit.MockProofs())
+	ens.InterconnectAll().BeginMining(50 * time.Millisecond)
+	dh := kit.NewDealHarness(t, client, miner)
+
+	min, max := dh.DealDurationBounds(0)
+	duration := min + (max-min)/2
+
+	deal, _, _ := dh.MakeOnlineDeal(ctx, kit.MakeFullDealParams{Rseed: 14, Duration: duration})
+
+	md := dh.WaitOnChainDealActive(ctx, deal)
+	require.GreaterOrEqual(t, int64(md.Proposal.EndEpoch-md.Proposal.StartEpoch), int64(duration))
+}
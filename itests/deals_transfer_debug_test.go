@@ -0,0 +1,49 @@
+package itests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	datatransfer "github.com/filecoin-project/go-data-transfer"
+
+	"github.com/filecoin-project/lotus/itests/kit"
+)
+
+func TestDataTransferChannelDebug(t *testing.T) {
+	ctx := context.Background()
+
+	kit.QuietMiningLogs()
+
+	client, miner, ens := kit.EnsembleMinimal(t, kit.MockProofs())
+	ens.InterconnectAll().BeginMining(50 * time.Millisecond)
+	dh := kit.NewDealHarness(t, client, miner)
+
+	deal, res, _ := dh.MakeOnlineDeal(ctx, kit.MakeFullDealParams{Rseed: 7})
+
+	ts, err := miner.MarketDealTransferStatus(ctx, *deal)
+	require.NoError(t, err)
+
+	dbg, err := miner.MarketDataTransferChannelDebug(ctx, ts.ChannelID)
+	require.NoError(t, err)
+
+	// the client pushed the data of the deal to the provider
+	require.Equal(t, ts.ChannelID, dbg.ChannelID)
+	require.Equal(t, datatransfer.Completed, dbg.Status)
+	require.Equal(t, res.Root, dbg.BaseCID)
+	clientPeer, err := client.ID(ctx)
+	require.NoError(t, err)
+	require.Equal(t, clientPeer, dbg.Sender)
+	require.NotZero(t, dbg.Received)
+	require.NotZero(t, dbg.ReceivedCids)
+	require.NotEmpty(t, dbg.Vouchers)
+	require.NotEmpty(t, dbg.Events)
+	require.Empty(t, dbg.PendingRequests)
+
+	// events are ordered
+	for i := 1; i < len(dbg.Events); i++ {
+		require.False(t, dbg.Events[i].Time.Before(dbg.Events[i-1].Time))
+	}
+}
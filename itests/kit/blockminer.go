@@ -70,6 +70,13 @@ func (bm *BlockMiner) InjectNulls(rounds abi.ChainEpoch) {
 }
 
 func (bm *BlockMiner) MineUntilBlock(ctx context.Context, fn *TestFullNode, cb func(abi.ChainEpoch)) {
+	bm.MineUntilBlockAfterNulls(ctx, fn, 0, cb)
+}
+
+// MineUntilBlockAfterNulls is MineUntilBlock, mining the first round after
+// the given number of null rounds, which skips epochs without mining a block
+// for each of them.
+func (bm *BlockMiner) MineUntilBlockAfterNulls(ctx context.Context, fn *TestFullNode, nulls abi.ChainEpoch, cb func(abi.ChainEpoch)) {
 	for i := 0; i < 1000; i++ {
 		var (
 			success bool
@@ -85,7 +92,8 @@ func (bm *BlockMiner) MineUntilBlock(ctx context.Context, fn *TestFullNode, cb f
 			wait <- struct{}{}
 		}
 
-		mineErr := bm.miner.MineOne(ctx, miner.MineReq{Done: doneFn})
+		mineErr := bm.miner.MineOne(ctx, miner.MineReq{InjectNulls: nulls, Done: doneFn})
+		nulls = 0
 		require.NoError(bm.t, mineErr)
 		<-wait
 
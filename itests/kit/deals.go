@@ -1,41 +1,90 @@
 package kit
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"io/ioutil"
 	"os"
+	"path/filepath"
+	"sort"
 	"testing"
 	"time"
 
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-fil-markets/retrievalmarket"
 	"github.com/filecoin-project/go-fil-markets/storagemarket"
 	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
 	"github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/blockstore"
 	"github.com/filecoin-project/lotus/build"
+	"github.com/filecoin-project/lotus/chain/actors/policy"
 	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/lotus/extern/sector-storage/storiface"
 	sealing "github.com/filecoin-project/lotus/extern/storage-sealing"
+	marketevents "github.com/filecoin-project/lotus/markets/loggers"
+	blocks "github.com/ipfs/go-block-format"
 	"github.com/ipfs/go-cid"
 	files "github.com/ipfs/go-ipfs-files"
 	ipld "github.com/ipfs/go-ipld-format"
 	dag "github.com/ipfs/go-merkledag"
 	dstest "github.com/ipfs/go-merkledag/test"
+	"github.com/ipfs/go-unixfs"
 	unixfile "github.com/ipfs/go-unixfs/file"
 	"github.com/ipld/go-car"
+	ipldprime "github.com/ipld/go-ipld-prime"
+	"github.com/ipld/go-ipld-prime/codec/dagjson"
+	basicnode "github.com/ipld/go-ipld-prime/node/basic"
+	"github.com/ipld/go-ipld-prime/traversal/selector/builder"
 	"github.com/stretchr/testify/require"
 	"golang.org/x/sync/errgroup"
+	"golang.org/x/xerrors"
 )
 
 type DealHarness struct {
 	t      *testing.T
 	client *TestFullNode
-	miner  *TestMiner
+	// the miner deals are made with
+	miner *TestMiner
+	// all miners deals can be made with, the first one is the default
+	miners []*TestMiner
 }
 
+// DefaultEpochPrice is the epoch price of deals the harness makes
+var DefaultEpochPrice = types.NewInt(1000000)
+
 type MakeFullDealParams struct {
 	Rseed      int
 	FastRet    bool
 	StartEpoch abi.ChainEpoch
 
+	// Price is the epoch price of the deal, DefaultEpochPrice if unset. Set
+	// it to big.Zero() for free deals.
+	Price abi.TokenAmount
+	// Verified makes the deal a verified deal, the client needs datacap, see
+	// GrantDatacap
+	Verified bool
+	// Duration is the minimum duration of the deal in epochs,
+	// build.MinDealDuration if unset. It has to be within the deal duration
+	// bounds of the market actor, see DealDurationBounds.
+	Duration abi.ChainEpoch
+
+	// ProviderCollateral is the provider collateral of the deal proposal. If
+	// unset the client proposes the minimum collateral of the chain state,
+	// see ProviderCollateralBounds.
+	ProviderCollateral abi.TokenAmount
+
+	// FileSize is the size of the generated file in bytes, 0 uses the default
+	// size of CreateRandomFile. The padded piece size of the deal data can be
+	// checked with ClientDealSize on the returned import root.
+	FileSize int
+
+	// CheckPieceCID makes MakeOnlineDeal compute the piece CID of the data on
+	// the client before the deal is started, and check that the deal ends up
+	// with the same piece CID, see AssertDealPieceCID.
+	CheckPieceCID bool
+
 	// SuspendUntilCryptoeconStable suspends deal-making, until cryptoecon
 	// parameters are stabilised. This affects projected collateral, and tests
 	// will fail in network version 13 and higher if deals are started too soon
@@ -63,20 +112,71 @@ type MakeFullDealParams struct {
 
 // NewDealHarness creates a test harness that contains testing utilities for deals.
 func NewDealHarness(t *testing.T, client *TestFullNode, miner *TestMiner) *DealHarness {
+	return NewMultiMinerDealHarness(t, client, miner)
+}
+
+// NewMultiMinerDealHarness creates a deal test harness for a client making
+// deals with several miners. Deals are made with the first miner, unless
+// another one is picked with MakeOnlineDealWithMiner or WithMiner.
+func NewMultiMinerDealHarness(t *testing.T, client *TestFullNode, miners ...*TestMiner) *DealHarness {
+	require.NotEmpty(t, miners, "no miners")
+
 	return &DealHarness{
 		t:      t,
 		client: client,
-		miner:  miner,
+		miner:  miners[0],
+		miners: miners,
 	}
 }
 
+// WithMiner returns a harness making deals with the miner at index idx of the
+// miners of this harness.
+func (dh *DealHarness) WithMiner(idx int) *DealHarness {
+	require.True(dh.t, idx >= 0 && idx < len(dh.miners), "no miner at index %d", idx)
+
+	cp := *dh
+	cp.miner = dh.miners[idx]
+	return &cp
+}
+
+// MakeOnlineDealWithMiner is MakeOnlineDeal with the miner at index idx of
+// the miners of this harness.
+func (dh *DealHarness) MakeOnlineDealWithMiner(ctx context.Context, idx int, params MakeFullDealParams) (deal *cid.Cid, res *api.ImportRes, path string) {
+	return dh.WithMiner(idx).MakeOnlineDeal(ctx, params)
+}
+
+// minerForDeal returns the miner of the harness which is the provider of the
+// deal
+func (dh *DealHarness) minerForDeal(ctx context.Context, deal *cid.Cid) *TestMiner {
+	if len(dh.miners) == 1 {
+		return dh.miner
+	}
+
+	di, err := dh.client.ClientGetDealInfo(ctx, *deal)
+	require.NoError(dh.t, err)
+
+	for _, m := range dh.miners {
+		if m.ActorAddr == di.Provider {
+			return m
+		}
+	}
+
+	dh.t.Fatalf("provider %s of deal %s isn't a miner of the harness", di.Provider, deal)
+	return nil
+}
+
+// withDealMiner returns a harness for the provider of the deal
+func (dh *DealHarness) withDealMiner(ctx context.Context, deal *cid.Cid) *DealHarness {
+	cp := *dh
+	cp.miner = dh.minerForDeal(ctx, deal)
+	return &cp
+}
+
 // MakeOnlineDeal makes an online deal, generating a random file with the
-// supplied seed, and setting the specified fast retrieval flag and start epoch
-// on the storage deal. It returns when the deal is sealed.
-//
-// TODO: convert input parameters to struct, and add size as an input param.
+// supplied seed and size, and setting the specified fast retrieval flag and
+// start epoch on the storage deal. It returns when the deal is sealed.
 func (dh *DealHarness) MakeOnlineDeal(ctx context.Context, params MakeFullDealParams) (deal *cid.Cid, res *api.ImportRes, path string) {
-	res, path = dh.client.CreateImportFile(ctx, params.Rseed, 0)
+	res, path = dh.client.CreateImportFile(ctx, params.Rseed, params.FileSize)
 
 	dh.t.Logf("FILE CID: %s", res.Root)
 
@@ -86,10 +186,98 @@ func (dh *DealHarness) MakeOnlineDeal(ctx context.Context, params MakeFullDealPa
 		dh.t.Logf("deal-making continuing; current height is %d", ts.Height())
 	}
 
-	deal = dh.StartDeal(ctx, res.Root, params.FastRet, params.StartEpoch)
+	var commP cid.Cid
+	if params.CheckPieceCID {
+		dcs, err := dh.client.ClientDealPieceCID(ctx, res.Root)
+		require.NoError(dh.t, err)
+		commP = dcs.PieceCID
+	}
+
+	deal = dh.StartDealWithParams(ctx, res.Root, params)
+	dh.WaitDealSealed(ctx, deal, false, false, nil)
+
+	if params.CheckPieceCID {
+		dh.AssertDealPieceCID(ctx, deal, commP)
+	}
+
+	return deal, res, path
+}
+
+// ImportContent imports a file with the given content into the client, for
+// deals on data which isn't random, like known CIDs or edge-case data. It
+// returns the root CID of the imported data and the path of the file.
+func (dh *DealHarness) ImportContent(ctx context.Context, data []byte) (root cid.Cid, path string) {
+	path = CreateFile(dh.t, data)
+
+	res, err := dh.client.ClientImport(ctx, api.FileRef{Path: path})
+	require.NoError(dh.t, err)
+
+	dh.t.Logf("FILE CID: %s", res.Root)
+
+	return res.Root, path
+}
+
+// MakeOnlineDealFromCAR makes an online deal for the data in a pre-built CAR
+// file, which is imported on the client as a CAR, setting the fast retrieval
+// flag and start epoch of the params on the storage deal. It returns when the
+// deal is sealed.
+func (dh *DealHarness) MakeOnlineDealFromCAR(ctx context.Context, carPath string, params MakeFullDealParams) (deal *cid.Cid, res *api.ImportRes) {
+	res, err := dh.client.ClientImport(ctx, api.FileRef{Path: carPath, IsCAR: true})
+	require.NoError(dh.t, err)
+
+	dh.t.Logf("CAR ROOT: %s", res.Root)
+
+	if params.SuspendUntilCryptoeconStable {
+		dh.t.Logf("deal-making suspending until cryptecon parameters have stabilised")
+		ts := dh.client.WaitTillChain(ctx, HeightAtLeast(300))
+		dh.t.Logf("deal-making continuing; current height is %d", ts.Height())
+	}
+
+	deal = dh.StartDealWithParams(ctx, res.Root, params)
+	dh.WaitDealSealed(ctx, deal, false, false, nil)
+
+	return deal, res
+}
+
+// MakeOfflineDeal makes an offline (manual transfer) deal, generating a random
+// file with the supplied seed and size, and setting the specified fast
+// retrieval flag and start epoch on the storage deal. The piece CID is
+// computed on the client, and the data is imported on the miner as a CAR file
+// once the deal waits for it. It returns when the deal is sealed.
+func (dh *DealHarness) MakeOfflineDeal(ctx context.Context, params MakeFullDealParams) (deal *cid.Cid, res *api.ImportRes, path string) {
+	res, path = dh.client.CreateImportFile(ctx, params.Rseed, params.FileSize)
+
+	dh.t.Logf("FILE CID: %s", res.Root)
+
+	if params.SuspendUntilCryptoeconStable {
+		dh.t.Logf("deal-making suspending until cryptecon parameters have stabilised")
+		ts := dh.client.WaitTillChain(ctx, HeightAtLeast(300))
+		dh.t.Logf("deal-making continuing; current height is %d", ts.Height())
+	}
+
+	pieceInfo, err := dh.client.ClientDealPieceCID(ctx, res.Root)
+	require.NoError(dh.t, err)
+
+	deal = dh.startDeal(ctx, &storagemarket.DataRef{
+		TransferType: storagemarket.TTManual,
+		Root:         res.Root,
+		PieceCid:     &pieceInfo.PieceCID,
+		PieceSize:    pieceInfo.PieceSize.Unpadded(),
+	}, params)
+
+	// wait for the provider to ask for the data
+	require.Eventually(dh.t, func() bool {
+		di, err := dh.client.ClientGetDealInfo(ctx, *deal)
+		require.NoError(dh.t, err)
+		return di.State == storagemarket.StorageDealCheckForAcceptance
+	}, 30*time.Second, 100*time.Millisecond, "deal didn't reach StorageDealCheckForAcceptance")
+
+	// importing the CAR file on the miner is the equivalent of transferring
+	// the data across the wire in an online deal
+	carPath := filepath.Join(dh.t.TempDir(), "deal.car")
+	require.NoError(dh.t, dh.client.ClientGenCar(ctx, api.FileRef{Path: path}, carPath))
+	require.NoError(dh.t, dh.minerForDeal(ctx, deal).DealsImportData(ctx, *deal, carPath))
 
-	// TODO: this sleep is only necessary because deals don't immediately get logged in the dealstore, we should fix this
-	time.Sleep(time.Second)
 	dh.WaitDealSealed(ctx, deal, false, false, nil)
 
 	return deal, res, path
@@ -97,36 +285,330 @@ func (dh *DealHarness) MakeOnlineDeal(ctx context.Context, params MakeFullDealPa
 
 // StartDeal starts a storage deal between the client and the miner.
 func (dh *DealHarness) StartDeal(ctx context.Context, fcid cid.Cid, fastRet bool, startEpoch abi.ChainEpoch) *cid.Cid {
+	return dh.StartDealWithParams(ctx, fcid, MakeFullDealParams{
+		FastRet:    fastRet,
+		StartEpoch: startEpoch,
+	})
+}
+
+// StartDealWithParams starts a storage deal between the client and the miner,
+// with the fast retrieval flag, start epoch, price, verified flag and provider
+// collateral of the params.
+func (dh *DealHarness) StartDealWithParams(ctx context.Context, fcid cid.Cid, params MakeFullDealParams) *cid.Cid {
+	return dh.startDeal(ctx, &storagemarket.DataRef{
+		TransferType: storagemarket.TTGraphsync,
+		Root:         fcid,
+	}, params)
+}
+
+// StartDealWithData starts a storage deal for the data as referenced, without
+// the client computing the piece CID if it's set. This lets tests propose deals
+// the provider fails after the data is transferred.
+func (dh *DealHarness) StartDealWithData(ctx context.Context, data *storagemarket.DataRef, params MakeFullDealParams) *cid.Cid {
+	return dh.startDeal(ctx, data, params)
+}
+
+func (dh *DealHarness) startDeal(ctx context.Context, data *storagemarket.DataRef, params MakeFullDealParams) *cid.Cid {
+	deal, err := dh.client.ClientStartDeal(ctx, dh.startDealParams(ctx, data, params))
+	require.NoError(dh.t, err)
+
+	return deal
+}
+
+func (dh *DealHarness) startDealParams(ctx context.Context, data *storagemarket.DataRef, params MakeFullDealParams) *api.StartDealParams {
 	maddr, err := dh.miner.ActorAddress(ctx)
 	require.NoError(dh.t, err)
 
 	addr, err := dh.client.WalletDefaultAddress(ctx)
 	require.NoError(dh.t, err)
 
-	deal, err := dh.client.ClientStartDeal(ctx, &api.StartDealParams{
-		Data: &storagemarket.DataRef{
+	price := params.Price
+	if price.Int == nil {
+		price = DefaultEpochPrice
+	}
+
+	duration := params.Duration
+	if duration == 0 {
+		duration = build.MinDealDuration
+	}
+	min, max := dh.DealDurationBounds(data.PieceSize.Padded())
+	require.True(dh.t, duration >= min && duration <= max, "deal duration %d out of the bounds of the market actor [%d, %d]", duration, min, max)
+
+	return &api.StartDealParams{
+		Data:               data,
+		Wallet:             addr,
+		Miner:              maddr,
+		EpochPrice:         price,
+		DealStartEpoch:     params.StartEpoch,
+		MinBlocksDuration:  uint64(duration),
+		FastRetrieval:      params.FastRet,
+		VerifiedDeal:       params.Verified,
+		ProviderCollateral: params.ProviderCollateral,
+	}
+}
+
+// DealDurationBounds returns the bounds of the duration of deals of the padded
+// piece size which the market actor accepts. They are read from the actors
+// policy when called, unlike build.MinDealDuration and build.MaxDealDuration
+// which are set when the build package is initialized.
+func (dh *DealHarness) DealDurationBounds(size abi.PaddedPieceSize) (min, max abi.ChainEpoch) {
+	return policy.DealDurationBounds(size)
+}
+
+// ProviderCollateralBounds returns the bounds of the provider collateral of a
+// deal of the padded piece size, as computed from the chain state at the
+// current head. The miner rejects deal proposals below the minimum.
+func (dh *DealHarness) ProviderCollateralBounds(ctx context.Context, size abi.PaddedPieceSize, verified bool) api.DealCollateralBounds {
+	bounds, err := dh.client.StateDealProviderCollateralBounds(ctx, size, verified, types.EmptyTSK)
+	require.NoError(dh.t, err)
+
+	return bounds
+}
+
+// MakeOnlineDealsConcurrent imports n random files of params.FileSize,
+// generated with the seeds params.Rseed to params.Rseed+n-1, and starts an
+// online deal for each of them with the other params, all at once. It returns
+// when all deals were started, they can be waited on with WaitDealStates.
+func (dh *DealHarness) MakeOnlineDealsConcurrent(ctx context.Context, n int, params MakeFullDealParams) (deals []*cid.Cid, res []*api.ImportRes) {
+	res = make([]*api.ImportRes, n)
+	sdps := make([]*api.StartDealParams, n)
+	for i := range res {
+		res[i], _ = dh.client.CreateImportFile(ctx, params.Rseed+i, params.FileSize)
+		sdps[i] = dh.startDealParams(ctx, &storagemarket.DataRef{
 			TransferType: storagemarket.TTGraphsync,
-			Root:         fcid,
-		},
-		Wallet:            addr,
-		Miner:             maddr,
-		EpochPrice:        types.NewInt(1000000),
-		DealStartEpoch:    startEpoch,
-		MinBlocksDuration: uint64(build.MinDealDuration),
-		FastRetrieval:     fastRet,
+			Root:         res[i].Root,
+		}, params)
+	}
+
+	deals = make([]*cid.Cid, n)
+	var errgrp errgroup.Group
+	for i := range sdps {
+		i := i
+		errgrp.Go(func() (err error) {
+			deals[i], err = dh.client.ClientStartDeal(ctx, sdps[i])
+			if err != nil {
+				return xerrors.Errorf("starting deal %d: %w", i, err)
+			}
+			return nil
+		})
+	}
+	require.NoError(dh.t, errgrp.Wait())
+
+	return deals, res
+}
+
+// WaitDealStates waits until all deals reached the client deal state, or a
+// later one on the happy path, failing the test when any of the deals fails.
+// Unlike WaitDealSealed it doesn't start sealing sectors waiting for deals,
+// so that tests can check how deals fill sectors, see StartSealingWaiting.
+func (dh *DealHarness) WaitDealStates(ctx context.Context, deals []*cid.Cid, state storagemarket.StorageDealStatus) {
+	target := dealStateIndex(state)
+	require.True(dh.t, target >= 0, "can't wait for deal state %s", storagemarket.DealStates[state])
+
+	pending := map[cid.Cid]struct{}{}
+	for _, deal := range deals {
+		pending[*deal] = struct{}{}
+	}
+
+	for len(pending) > 0 {
+		for deal := range pending {
+			di, err := dh.client.ClientGetDealInfo(ctx, deal)
+			require.NoError(dh.t, err)
+
+			switch di.State {
+			case storagemarket.StorageDealProposalRejected:
+				dh.t.Fatalf("deal %s rejected: %s", deal, di.Message)
+			case storagemarket.StorageDealFailing, storagemarket.StorageDealError:
+				dh.t.Fatalf("deal %s failed: %s", deal, di.Message)
+			}
+
+			if dealStateIndex(di.State) >= target {
+				delete(pending, deal)
+			}
+		}
+
+		if len(pending) == 0 {
+			break
+		}
+
+		dh.t.Logf("waiting for %d of %d deals to reach %s", len(pending), len(deals), storagemarket.DealStates[state])
+		select {
+		case <-time.After(time.Second / 2):
+		case <-ctx.Done():
+			dh.t.Fatalf("context done while waiting for %d deals to reach %s: %s", len(pending), storagemarket.DealStates[state], ctx.Err())
+		}
+	}
+}
+
+// SectorsForDeals returns the sectors which the deals were packed into, in
+// ascending order. Tests can compare the number of sectors with the number
+// expected from the sizes of the deals and sealing config.
+func (dh *DealHarness) SectorsForDeals(ctx context.Context, deals []*cid.Cid) []abi.SectorNumber {
+	sectors := map[abi.SectorNumber]struct{}{}
+	for _, deal := range deals {
+		sectors[dh.SectorForDeal(ctx, deal)] = struct{}{}
+	}
+
+	out := make([]abi.SectorNumber, 0, len(sectors))
+	for snum := range sectors {
+		out = append(out, snum)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i] < out[j]
 	})
+
+	return out
+}
+
+// MineBlocks mines n blocks with the miner of the harness, and returns the
+// epoch of the last one once it's the head of the client. Blocks are mined one
+// at a time, the chain must not be mined on with BeginMining at the same time.
+func (dh *DealHarness) MineBlocks(ctx context.Context, n int) abi.ChainEpoch {
+	bm := NewBlockMiner(dh.t, dh.miner)
+
+	var last abi.ChainEpoch
+	for i := 0; i < n; i++ {
+		bm.MineUntilBlock(ctx, dh.client, func(epoch abi.ChainEpoch) {
+			last = epoch
+		})
+	}
+	return last
+}
+
+// AdvanceToEpoch advances the chain to the given epoch with null rounds and a
+// single block mined by the miner of the harness, and returns the head epoch
+// of the client, which is at or after epoch. Like with MineBlocks, the chain
+// must not be mined on with BeginMining at the same time. Skipping proving
+// deadlines with null rounds makes the sectors in them faulty.
+func (dh *DealHarness) AdvanceToEpoch(ctx context.Context, epoch abi.ChainEpoch) abi.ChainEpoch {
+	head, err := dh.client.ChainHead(ctx)
 	require.NoError(dh.t, err)
+	if head.Height() >= epoch {
+		return head.Height()
+	}
 
-	return deal
+	reached := head.Height()
+	NewBlockMiner(dh.t, dh.miner).MineUntilBlockAfterNulls(ctx, dh.client, epoch-head.Height()-1, func(e abi.ChainEpoch) {
+		reached = e
+	})
+	require.GreaterOrEqual(dh.t, int64(reached), int64(epoch))
+
+	return reached
+}
+
+// SaveDealTemplate saves a deal template with deals between the client and
+// the miner, with the same parameters StartDeal uses.
+func (dh *DealHarness) SaveDealTemplate(ctx context.Context, name string, fastRet bool) api.DealTemplate {
+	maddr, err := dh.miner.ActorAddress(ctx)
+	require.NoError(dh.t, err)
+
+	addr, err := dh.client.WalletDefaultAddress(ctx)
+	require.NoError(dh.t, err)
+
+	tmpl := api.DealTemplate{
+		Wallet:             addr,
+		Miner:              maddr,
+		EpochPrice:         DefaultEpochPrice,
+		MinBlocksDuration:  uint64(build.MinDealDuration),
+		ProviderCollateral: big.Zero(),
+		FastRetrieval:      fastRet,
+	}
+	require.NoError(dh.t, dh.client.ClientSaveDealTemplate(ctx, name, tmpl))
+
+	return tmpl
 }
 
 // WaitDealSealed waits until the deal is sealed.
 func (dh *DealHarness) WaitDealSealed(ctx context.Context, deal *cid.Cid, noseal, noSealStart bool, cb func()) {
+	dh.WaitDealSealedWithDeadlines(ctx, deal, noseal, noSealStart, cb, nil)
+}
+
+// DealStateDeadlines maps client deal states to how long, from the start of
+// the wait, the deal may take to reach them
+type DealStateDeadlines map[storagemarket.StorageDealStatus]time.Duration
+
+// dealStateOrder is the order client deal states are passed through on the
+// happy path. A deal which was seen in a state is considered to have reached
+// all states before it, even if it moved through them between polls.
+var dealStateOrder = []storagemarket.StorageDealStatus{
+	storagemarket.StorageDealUnknown,
+	storagemarket.StorageDealReserveClientFunds,
+	storagemarket.StorageDealFundsReserved,
+	storagemarket.StorageDealStartDataTransfer,
+	storagemarket.StorageDealTransferring,
+	storagemarket.StorageDealCheckForAcceptance,
+	storagemarket.StorageDealProposalAccepted,
+	storagemarket.StorageDealAwaitingPreCommit,
+	storagemarket.StorageDealSealing,
+	storagemarket.StorageDealActive,
+}
+
+func dealStateIndex(st storagemarket.StorageDealStatus) int {
+	for i, s := range dealStateOrder {
+		if s == st {
+			return i
+		}
+	}
+	return -1
+}
+
+// WaitDealSealedWithDeadlines is WaitDealSealed, additionally failing the test
+// when the deal doesn't reach a state within its deadline, or when the
+// context expires.
+func (dh *DealHarness) WaitDealSealedWithDeadlines(ctx context.Context, deal *cid.Cid, noseal, noSealStart bool, cb func(), deadlines DealStateDeadlines) {
+	dh = dh.withDealMiner(ctx, deal)
+
+	start := time.Now()
+	seen := map[storagemarket.StorageDealStatus]struct{}{}
+	furthest := -1
+
+	reached := func(st storagemarket.StorageDealStatus) bool {
+		if _, ok := seen[st]; ok {
+			return true
+		}
+		idx := dealStateIndex(st)
+		return idx >= 0 && idx <= furthest
+	}
+
+	var lastClient, lastProvider storagemarket.StorageDealStatus
+	var lastDeal storagemarket.MinerDeal
+	var haveDeal bool
+
+	fail := func(format string, args ...interface{}) {
+		msg := fmt.Sprintf(format, args...)
+		msg += fmt.Sprintf("; last seen client state: %s, provider state: %s", storagemarket.DealStates[lastClient], storagemarket.DealStates[lastProvider])
+		if haveDeal {
+			// the wait context may be expired already
+			si, err := dh.miner.SectorsStatus(context.Background(), lastDeal.SectorNumber, false)
+			if err != nil {
+				msg += fmt.Sprintf(", sector %d state: unknown (%s)", lastDeal.SectorNumber, err)
+			} else {
+				msg += fmt.Sprintf(", sector %d state: %s", lastDeal.SectorNumber, si.State)
+			}
+		}
+		dh.t.Fatal(msg)
+	}
+
 loop:
 	for {
+		select {
+		case <-ctx.Done():
+			fail("context done while waiting for deal %s to be sealed: %s", deal, ctx.Err())
+		default:
+		}
+
 		di, err := dh.client.ClientGetDealInfo(ctx, *deal)
+		if err != nil && ctx.Err() != nil {
+			fail("context done while waiting for deal %s to be sealed: %s", deal, ctx.Err())
+		}
 		require.NoError(dh.t, err)
 
+		lastClient = di.State
+		seen[di.State] = struct{}{}
+		if idx := dealStateIndex(di.State); idx > furthest {
+			furthest = idx
+		}
+
 		switch di.State {
 		case storagemarket.StorageDealAwaitingPreCommit, storagemarket.StorageDealSealing:
 			if noseal {
@@ -153,20 +635,59 @@ loop:
 		for _, md := range mds {
 			if md.DealID == di.DealID {
 				minerState = md.State
+				lastDeal, haveDeal = md, true
 				break
 			}
 		}
+		lastProvider = minerState
+
+		for st, d := range deadlines {
+			if time.Since(start) > d && !reached(st) {
+				fail("deal %s didn't reach state %s within %s", deal, storagemarket.DealStates[st], d)
+			}
+		}
 
 		dh.t.Logf("Deal %d state: client:%s provider:%s\n", di.DealID, storagemarket.DealStates[di.State], storagemarket.DealStates[minerState])
-		time.Sleep(time.Second / 2)
+		select {
+		case <-time.After(time.Second / 2):
+		case <-ctx.Done():
+		}
 		if cb != nil {
 			cb()
 		}
 	}
 }
 
+// ExpectRejected waits until the client sees the deal proposal rejected and
+// returns the rejection message. The deal getting past the proposal stage
+// fails the test.
+func (dh *DealHarness) ExpectRejected(ctx context.Context, deal *cid.Cid) string {
+	for {
+		di, err := dh.client.ClientGetDealInfo(ctx, *deal)
+		require.NoError(dh.t, err)
+
+		switch di.State {
+		case storagemarket.StorageDealProposalRejected:
+			return di.Message
+		case storagemarket.StorageDealFailing, storagemarket.StorageDealError:
+			dh.t.Fatalf("deal failed instead of being rejected: %s", di.Message)
+		}
+		if dealStateIndex(di.State) >= dealStateIndex(storagemarket.StorageDealProposalAccepted) {
+			dh.t.Fatalf("deal %s not rejected, got to state %s", deal, storagemarket.DealStates[di.State])
+		}
+
+		select {
+		case <-time.After(100 * time.Millisecond):
+		case <-ctx.Done():
+			dh.t.Fatalf("context done while waiting for deal %s to be rejected: %s", deal, ctx.Err())
+		}
+	}
+}
+
 // WaitDealPublished waits until the deal is published.
 func (dh *DealHarness) WaitDealPublished(ctx context.Context, deal *cid.Cid) {
+	dh = dh.withDealMiner(ctx, deal)
+
 	subCtx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
@@ -196,7 +717,31 @@ func (dh *DealHarness) WaitDealPublished(ctx context.Context, deal *cid.Cid) {
 	}
 }
 
+// StartSealingWaiting matches the pieces of pending deals to the sectors
+// accepting deals, and starts sealing all sectors waiting for deals
 func (dh *DealHarness) StartSealingWaiting(ctx context.Context) {
+	// assign pending pieces first, so that they don't wait for a new sector
+	require.NoError(dh.t, dh.miner.SectorMatchPendingPiecesToOpenSectors(ctx))
+
+	snums, err := dh.miner.SectorsListInStates(ctx, []api.SectorState{api.SectorState(sealing.WaitDeals)})
+	require.NoError(dh.t, err)
+
+	for _, snum := range snums {
+		dh.t.Logf("Starting to seal sector %d waiting for deals", snum)
+		require.NoError(dh.t, dh.miner.SectorStartSealing(ctx, snum))
+	}
+
+	dh.miner.FlushSealingBatches(ctx)
+}
+
+// SectorForDeal returns the sector of the miner of the deal which the deal
+// was packed into
+func (dh *DealHarness) SectorForDeal(ctx context.Context, deal *cid.Cid) abi.SectorNumber {
+	dh = dh.withDealMiner(ctx, deal)
+
+	di, err := dh.client.ClientGetDealInfo(ctx, *deal)
+	require.NoError(dh.t, err)
+
 	snums, err := dh.miner.SectorsList(ctx)
 	require.NoError(dh.t, err)
 
@@ -204,23 +749,513 @@ func (dh *DealHarness) StartSealingWaiting(ctx context.Context) {
 		si, err := dh.miner.SectorsStatus(ctx, snum, false)
 		require.NoError(dh.t, err)
 
-		dh.t.Logf("Sector state: %s", si.State)
-		if si.State == api.SectorState(sealing.WaitDeals) {
-			require.NoError(dh.t, dh.miner.SectorStartSealing(ctx, snum))
+		for _, id := range si.Deals {
+			if id == di.DealID {
+				return snum
+			}
+		}
+	}
+
+	dh.t.Fatalf("no sector hosts deal %s (deal id %d)", deal, di.DealID)
+	return 0
+}
+
+// AssertDealPieceCID checks that the piece CID of the deal is the expected
+// one, in the client deal info, in the deal proposal published on chain, and
+// on the provider, in its deal and in the piecestore entry of the piece. The
+// deal has to be handed off to sealing, see WaitDealSealed.
+func (dh *DealHarness) AssertDealPieceCID(ctx context.Context, deal *cid.Cid, expected cid.Cid) {
+	dh = dh.withDealMiner(ctx, deal)
+
+	di, err := dh.client.ClientGetDealInfo(ctx, *deal)
+	require.NoError(dh.t, err)
+
+	require.Equal(dh.t, expected, di.PieceCID, "client piece CID of deal %s", deal)
+	require.NotZero(dh.t, di.DealID, "deal %s not published", deal)
+
+	md, err := dh.client.StateMarketStorageDeal(ctx, di.DealID, types.EmptyTSK)
+	require.NoError(dh.t, err)
+
+	require.Equal(dh.t, expected, md.Proposal.PieceCID, "on-chain piece CID of deal %d", di.DealID)
+
+	pdeals, err := dh.miner.MarketListIncompleteDeals(ctx)
+	require.NoError(dh.t, err)
+
+	var found bool
+	for _, pd := range pdeals {
+		if pd.ProposalCid == *deal {
+			require.Equal(dh.t, expected, pd.Proposal.PieceCID, "provider piece CID of deal %s", deal)
+			found = true
+		}
+	}
+	require.True(dh.t, found, "provider doesn't track deal %s", deal)
+
+	pi, err := dh.miner.PiecesGetPieceInfo(ctx, expected)
+	require.NoError(dh.t, err, "piece %s not in the provider piecestore", expected)
+
+	found = false
+	for _, d := range pi.Deals {
+		if d.DealID == di.DealID {
+			found = true
+		}
+	}
+	require.True(dh.t, found, "deal %d not recorded for piece %s in the provider piecestore", di.DealID, expected)
+}
+
+// AssertUnsealedCopy waits for the sector hosting the deal to be finalized,
+// and checks whether the storage index of the miner of the deal has an
+// unsealed copy of the sector. Deals made without fast retrieval shouldn't
+// have one. Needs real sector storage, it doesn't work with MockProofs.
+func (dh *DealHarness) AssertUnsealedCopy(ctx context.Context, deal *cid.Cid, present bool) {
+	dh = dh.withDealMiner(ctx, deal)
+
+	snum := dh.SectorForDeal(ctx, deal)
+	dh.waitSectorFinalized(ctx, snum)
+
+	mid, err := address.IDFromAddress(dh.miner.ActorAddr)
+	require.NoError(dh.t, err)
+
+	sid := abi.SectorID{Miner: abi.ActorID(mid), Number: snum}
+	infos, err := dh.miner.StorageFindSector(ctx, sid, storiface.FTUnsealed, 0, false)
+	require.NoError(dh.t, err)
+
+	if present {
+		require.NotEmpty(dh.t, infos, "expected an unsealed copy of sector %d hosting deal %s", snum, deal)
+	} else {
+		require.Empty(dh.t, infos, "expected no unsealed copy of sector %d hosting deal %s", snum, deal)
+	}
+}
+
+// waitSectorFinalized waits for the sector of the harness miner to reach the
+// Proving state, that is for its files to be moved to long term storage
+func (dh *DealHarness) waitSectorFinalized(ctx context.Context, snum abi.SectorNumber) {
+	for {
+		si, err := dh.miner.SectorsStatus(ctx, snum, false)
+		require.NoError(dh.t, err)
+
+		if sealing.SectorState(si.State) == sealing.Proving {
+			return
+		}
+
+		select {
+		case <-time.After(100 * time.Millisecond):
+		case <-ctx.Done():
+			dh.t.Fatalf("context done while waiting for sector %d to be finalized: %s", snum, ctx.Err())
+		}
+	}
+}
+
+// CorruptSectorForDeal waits for the sector hosting the deal to be finalized,
+// and corrupts its sealed replica on the miner of the deal, see
+// TestMiner.CorruptSector. It returns the number of the corrupted sector.
+func (dh *DealHarness) CorruptSectorForDeal(ctx context.Context, deal *cid.Cid) abi.SectorNumber {
+	dh = dh.withDealMiner(ctx, deal)
+
+	snum := dh.SectorForDeal(ctx, deal)
+	dh.waitSectorFinalized(ctx, snum)
+
+	dh.miner.CorruptSector(ctx, snum)
+	return snum
+}
+
+// WaitSectorFaultyForDeal waits for the sector hosting the deal to be faulty
+// on chain, either declared faulty by the miner or skipped in the WindowPoSt
+// of its deadline, see CorruptSectorForDeal. It fails the test when the
+// sector isn't faulty a proving period and a challenge window from now.
+func (dh *DealHarness) WaitSectorFaultyForDeal(ctx context.Context, deal *cid.Cid) {
+	dh = dh.withDealMiner(ctx, deal)
+
+	snum := dh.SectorForDeal(ctx, deal)
+
+	di, err := dh.client.StateMinerProvingDeadline(ctx, dh.miner.ActorAddr, types.EmptyTSK)
+	require.NoError(dh.t, err)
+
+	// the deadline of the sector comes up within a proving period, and the
+	// PoSt or fault declaration for it lands within its challenge window
+	waitUntil := di.CurrentEpoch + di.WPoStProvingPeriod + di.WPoStChallengeWindow
+
+	for {
+		faults, err := dh.client.StateMinerFaults(ctx, dh.miner.ActorAddr, types.EmptyTSK)
+		require.NoError(dh.t, err)
+
+		faulty, err := faults.IsSet(uint64(snum))
+		require.NoError(dh.t, err)
+		if faulty {
+			return
+		}
+
+		head, err := dh.client.ChainHead(ctx)
+		require.NoError(dh.t, err)
+		if head.Height() > waitUntil {
+			dh.t.Fatalf("sector %d hosting deal %s not faulty at epoch %d", snum, deal, head.Height())
+		}
+
+		select {
+		case <-time.After(time.Second / 2):
+		case <-ctx.Done():
+			dh.t.Fatalf("context done while waiting for sector %d to be faulty: %s", snum, ctx.Err())
+		}
+	}
+}
+
+// TerminateSectorForDeal terminates the sector hosting the deal, and waits
+// for the deal to be slashed
+func (dh *DealHarness) TerminateSectorForDeal(ctx context.Context, deal *cid.Cid) {
+	dh = dh.withDealMiner(ctx, deal)
+
+	snum := dh.SectorForDeal(ctx, deal)
+	dh.t.Logf("terminating sector %d hosting deal %s", snum, deal)
+	require.NoError(dh.t, dh.miner.SectorTerminate(ctx, snum))
+
+loop:
+	for {
+		si, err := dh.miner.SectorsStatus(ctx, snum, false)
+		require.NoError(dh.t, err)
+
+		switch sealing.SectorState(si.State) {
+		case sealing.Terminating:
+			_, err := dh.miner.SectorTerminateFlush(ctx)
+			require.NoError(dh.t, err)
+		case sealing.TerminateWait, sealing.TerminateFinality, sealing.Removed:
+			break loop
+		case sealing.TerminateFailed:
+			dh.t.Fatalf("terminating sector %d failed", snum)
 		}
 
-		dh.miner.FlushSealingBatches(ctx)
+		select {
+		case <-time.After(100 * time.Millisecond):
+		case <-ctx.Done():
+			dh.t.Fatalf("context done while terminating sector %d: %s", snum, ctx.Err())
+		}
+	}
+
+	dh.WaitDealSlashed(ctx, deal)
+}
+
+// ExtendSectorForDeal extends the expiration of the sector hosting the deal by
+// extraEpochs, waits for the extension to land on chain, and returns the new
+// expiration. Extending is retried while the deadline of the sector is being
+// proven.
+func (dh *DealHarness) ExtendSectorForDeal(ctx context.Context, deal *cid.Cid, extraEpochs abi.ChainEpoch) abi.ChainEpoch {
+	dh = dh.withDealMiner(ctx, deal)
+
+	snum := dh.SectorForDeal(ctx, deal)
+	si, err := dh.client.StateSectorGetInfo(ctx, dh.miner.ActorAddr, snum, types.EmptyTSK)
+	require.NoError(dh.t, err)
+	require.NotNil(dh.t, si, "sector %d not found on chain", snum)
+
+	newExp := si.Expiration + extraEpochs
+	dh.t.Logf("extending sector %d hosting deal %s from %d to %d", snum, deal, si.Expiration, newExp)
+
+	var msg cid.Cid
+	for {
+		msg, err = dh.miner.SectorExtend(ctx, snum, newExp)
+		if err == nil {
+			break
+		}
+		require.Contains(dh.t, err.Error(), "is being proven")
+
+		select {
+		case <-time.After(100 * time.Millisecond):
+		case <-ctx.Done():
+			dh.t.Fatalf("context done while extending sector %d: %s", snum, ctx.Err())
+		}
+	}
+
+	res, err := dh.client.StateWaitMsg(ctx, msg, build.MessageConfidence, api.LookbackNoLimit, true)
+	require.NoError(dh.t, err)
+	require.True(dh.t, res.Receipt.ExitCode.IsSuccess(), "extension of sector %d failed: %s", snum, res.Receipt.ExitCode)
+
+	si, err = dh.client.StateSectorGetInfo(ctx, dh.miner.ActorAddr, snum, types.EmptyTSK)
+	require.NoError(dh.t, err)
+	require.Equal(dh.t, newExp, si.Expiration)
+
+	return newExp
+}
+
+// WaitDealSlashed waits until the client sees the deal slashed, and checks
+// that the deal was slashed on chain
+func (dh *DealHarness) WaitDealSlashed(ctx context.Context, deal *cid.Cid) {
+	for {
+		di, err := dh.client.ClientGetDealInfo(ctx, *deal)
+		require.NoError(dh.t, err)
+
+		switch di.State {
+		case storagemarket.StorageDealSlashed:
+			// the market actor removes slashed deals in cron, once that ran
+			// the deal can't be found on chain any more
+			md, err := dh.client.StateMarketStorageDeal(ctx, di.DealID, types.EmptyTSK)
+			if err == nil {
+				require.NotEqual(dh.t, abi.ChainEpoch(-1), md.State.SlashEpoch, "deal %d not slashed on chain", di.DealID)
+			}
+			dh.t.Logf("deal %d slashed", di.DealID)
+			return
+		case storagemarket.StorageDealExpired:
+			dh.t.Fatal("deal expired instead of being slashed")
+		case storagemarket.StorageDealError:
+			dh.t.Fatal("deal errored", di.Message)
+		}
+
+		dh.t.Logf("Deal %d state: client:%s", di.DealID, storagemarket.DealStates[di.State])
+		select {
+		case <-time.After(time.Second / 2):
+		case <-ctx.Done():
+			dh.t.Fatalf("context done while waiting for deal %s to be slashed: %s", deal, ctx.Err())
+		}
+	}
+}
+
+// WaitOnChainDealActive waits until the deal was activated in the market
+// actor state, that is the sector hosting it was proven, and returns the
+// on-chain deal. It fails the test when the deal fails, or is slashed on
+// chain. Like WaitDealStates it doesn't start sealing sectors waiting for
+// deals, see StartSealingWaiting.
+func (dh *DealHarness) WaitOnChainDealActive(ctx context.Context, deal *cid.Cid) *api.MarketDeal {
+	for {
+		di, err := dh.client.ClientGetDealInfo(ctx, *deal)
+		require.NoError(dh.t, err)
+
+		switch di.State {
+		case storagemarket.StorageDealProposalRejected:
+			dh.t.Fatalf("deal %s rejected: %s", deal, di.Message)
+		case storagemarket.StorageDealFailing, storagemarket.StorageDealError:
+			dh.t.Fatalf("deal %s failed: %s", deal, di.Message)
+		}
+
+		// the deal ID is assigned once the deal was published
+		if di.DealID != 0 {
+			md, err := dh.client.StateMarketStorageDeal(ctx, di.DealID, types.EmptyTSK)
+			require.NoError(dh.t, err)
+
+			require.Equal(dh.t, abi.ChainEpoch(-1), md.State.SlashEpoch, "deal %d slashed on chain at epoch %d", di.DealID, md.State.SlashEpoch)
+			if md.State.SectorStartEpoch >= 0 {
+				return md
+			}
+		}
+
+		dh.t.Logf("waiting for deal %d to be activated on chain, client state: %s", di.DealID, storagemarket.DealStates[di.State])
+		select {
+		case <-time.After(time.Second / 2):
+		case <-ctx.Done():
+			dh.t.Fatalf("context done while waiting for deal %s to be activated on chain: %s", deal, ctx.Err())
+		}
 	}
 }
 
 func (dh *DealHarness) PerformRetrieval(ctx context.Context, deal *cid.Cid, root cid.Cid, carExport bool) (path string) {
+	return dh.retrieve(ctx, deal, root, nil, carExport)
+}
+
+// PerformCARRetrieval retrieves the data of a deal made from a CAR file as a
+// CAR, and checks that it matches the CAR the deal was made from byte for
+// byte. The CAR has to hold a UnixFS DAG, whose file contents are compared
+// first, to tell data corruption apart from differences in the CAR layout.
+func (dh *DealHarness) PerformCARRetrieval(ctx context.Context, deal *cid.Cid, root cid.Cid, carPath string) (path string) {
+	path = dh.PerformRetrieval(ctx, deal, root, true)
+
+	extract := func(p string) string {
+		f, err := os.Open(p)
+		require.NoError(dh.t, err)
+		defer f.Close() //nolint:errcheck
+
+		return dh.ExtractFileFromCAR(ctx, f).Name()
+	}
+	AssertFilesEqual(dh.t, extract(carPath), extract(path))
+
+	AssertFilesEqual(dh.t, carPath, path)
+
+	return path
+}
+
+// PerformRetrievalWithEvents is PerformRetrieval, returning the events of the
+// retrieval in the order they were received. A failed retrieval doesn't fail
+// the test, it's returned as an error along with the events received up to
+// the failure.
+func (dh *DealHarness) PerformRetrievalWithEvents(ctx context.Context, deal *cid.Cid, root cid.Cid, carExport bool) ([]marketevents.RetrievalEvent, string, error) {
+	var events []marketevents.RetrievalEvent
+	path, err := dh.retrieveWithEvents(ctx, deal, root, nil, carExport, func(evt marketevents.RetrievalEvent) {
+		events = append(events, evt)
+	})
+	return events, path, err
+}
+
+// PerformRetrievalHTTP is PerformRetrieval from a miner serving retrievals
+// over HTTP, see Dealmaking.HTTPRetrieval. It checks that the miner advertises
+// the HTTP transport in its offer, and that the data was fetched over HTTP,
+// without making a graphsync retrieval deal.
+func (dh *DealHarness) PerformRetrievalHTTP(ctx context.Context, deal *cid.Cid, root cid.Cid, carExport bool) (path string) {
+	info, err := dh.client.ClientGetDealInfo(ctx, *deal)
+	require.NoError(dh.t, err)
+
+	offer, err := dh.client.ClientMinerQueryOffer(ctx, info.Provider, root, &info.PieceCID)
+	require.NoError(dh.t, err)
+	require.Empty(dh.t, offer.Err)
+
+	var advertised bool
+	for _, t := range offer.Transports {
+		advertised = advertised || t.Name == "http"
+	}
+	require.True(dh.t, advertised, "miner doesn't advertise HTTP retrievals: %v", offer.Transports)
+
+	events, path, err := dh.PerformRetrievalWithEvents(ctx, deal, root, carExport)
+	require.NoError(dh.t, err)
+
+	require.Len(dh.t, events, 1)
+	require.Equal(dh.t, retrievalmarket.DealStatusCompleted, events[0].Status)
+	require.NotZero(dh.t, events[0].BytesReceived)
+	require.True(dh.t, events[0].FundsSpent.IsZero(), "paid %s for an HTTP retrieval", events[0].FundsSpent)
+
+	rets, err := dh.client.ClientListRetrievals(ctx)
+	require.NoError(dh.t, err)
+	for _, ret := range rets {
+		require.False(dh.t, ret.PayloadCID.Equals(root), "retrieval deal %d made for an HTTP retrieval", ret.ID)
+	}
+
+	return path
+}
+
+// PerformRetrievalWithRestart is PerformRetrieval, interrupting the data
+// transfer once interruptAfterBytes have been received and restarting it
+// through ClientRestartDataTransfer.
+//
+// The restart replaces the graphsync request of the transfer channel with one
+// resuming from the blocks already received. The retrieved file is imported
+// again to check it's complete, its root must match the root of the deal
+// data. The deal data has to be large enough for the transfer to still be
+// ongoing once interruptAfterBytes are received, a few MiB for 1MiB.
+func (dh *DealHarness) PerformRetrievalWithRestart(ctx context.Context, deal *cid.Cid, root cid.Cid, interruptAfterBytes int64) (path string) {
+	path, err := dh.retrieveInterrupted(ctx, deal, root, interruptAfterBytes, func(ret api.RetrievalInfo) error {
+		chid := ret.TransferChannelID
+		dh.t.Logf("restarting transfer %d of retrieval %d after %d bytes", chid.ID, ret.ID, ret.BytesReceived)
+		// the client pulls the data, so it's the initiator of the channel
+		return dh.client.ClientRestartDataTransfer(ctx, chid.ID, chid.Responder, true)
+	})
+	require.NoError(dh.t, err)
+
+	res, err := dh.client.ClientImport(ctx, api.FileRef{Path: path})
+	require.NoError(dh.t, err)
+	require.Equal(dh.t, root, res.Root, "retrieved file is incomplete or corrupted")
+
+	return path
+}
+
+// PerformRetrievalWithCancel starts a retrieval of the deal data and cancels
+// it through ClientCancelRetrievalDeal once interruptAfterBytes have been
+// received, checking that the retrieval ends up cancelled. It returns the
+// error the retrieval failed with.
+func (dh *DealHarness) PerformRetrievalWithCancel(ctx context.Context, deal *cid.Cid, root cid.Cid, interruptAfterBytes int64) error {
+	var id retrievalmarket.DealID
+	_, retErr := dh.retrieveInterrupted(ctx, deal, root, interruptAfterBytes, func(ret api.RetrievalInfo) error {
+		id = ret.ID
+		dh.t.Logf("cancelling retrieval %d after %d bytes", ret.ID, ret.BytesReceived)
+		return dh.client.ClientCancelRetrievalDeal(ctx, ret.ID)
+	})
+	require.Error(dh.t, retErr, "cancelled retrieval succeeded")
+
+	rets, err := dh.client.ClientListRetrievals(ctx)
+	require.NoError(dh.t, err)
+	for _, ret := range rets {
+		if ret.ID == id {
+			require.Equal(dh.t, retrievalmarket.DealStatusCancelled, ret.Status, retrievalmarket.DealStatuses[ret.Status])
+			return retErr
+		}
+	}
+	dh.t.Fatalf("cancelled retrieval %d not listed", id)
+	return retErr
+}
+
+// retrieveInterrupted retrieves the deal data, calling interrupt with the
+// ongoing retrieval once interruptAfterBytes have been received. It fails the
+// test if the retrieval completed before it could be interrupted, and
+// returns the result of the retrieval.
+func (dh *DealHarness) retrieveInterrupted(ctx context.Context, deal *cid.Cid, root cid.Cid, interruptAfterBytes int64, interrupt func(api.RetrievalInfo) error) (path string, err error) {
+	var interrupted bool
+	interruptErr := make(chan error, 1)
+	path, err = dh.retrieveWithEvents(ctx, deal, root, nil, false, func(evt marketevents.RetrievalEvent) {
+		if interrupted || evt.BytesReceived < uint64(interruptAfterBytes) {
+			return
+		}
+		if retrievalmarket.IsTerminalSuccess(evt.Status) || retrievalmarket.IsTerminalError(evt.Status) {
+			return
+		}
+
+		interrupted = true
+		// don't hold up the events of the retrieval while interrupting it
+		go func() {
+			ret, err := dh.ongoingRetrieval(ctx, root)
+			if err == nil {
+				err = interrupt(ret)
+			}
+			interruptErr <- err
+		}()
+	})
+	require.True(dh.t, interrupted, "retrieval completed before %d bytes were received", interruptAfterBytes)
+	require.NoError(dh.t, <-interruptErr)
+
+	return path, err
+}
+
+// ongoingRetrieval returns the latest ongoing retrieval of root, waiting for
+// its transfer channel to be listed, which happens asynchronously once the
+// provider accepts the deal
+func (dh *DealHarness) ongoingRetrieval(ctx context.Context, root cid.Cid) (api.RetrievalInfo, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	for {
+		rets, err := dh.client.ClientListRetrievals(ctx)
+		if err != nil {
+			return api.RetrievalInfo{}, xerrors.Errorf("listing retrievals: %w", err)
+		}
+
+		// retrievals are sorted by deal ID
+		for i := len(rets) - 1; i >= 0; i-- {
+			ret := rets[i]
+			if ret.PayloadCID != root {
+				continue
+			}
+			if retrievalmarket.IsTerminalSuccess(ret.Status) || retrievalmarket.IsTerminalError(ret.Status) {
+				return api.RetrievalInfo{}, xerrors.Errorf("retrieval %d of %s completed before it was interrupted, with status %s", ret.ID, root, retrievalmarket.DealStatuses[ret.Status])
+			}
+			if ret.TransferChannelID != nil {
+				return ret, nil
+			}
+			break
+		}
+
+		select {
+		case <-time.After(10 * time.Millisecond):
+		case <-ctx.Done():
+			return api.RetrievalInfo{}, xerrors.Errorf("no ongoing retrieval of %s with a transfer channel: %w", root, ctx.Err())
+		}
+	}
+}
+
+func (dh *DealHarness) retrieve(ctx context.Context, deal *cid.Cid, root cid.Cid, sel *api.Selector, carExport bool) (path string) {
+	path, err := dh.retrieveWithEvents(ctx, deal, root, sel, carExport, nil)
+	require.NoError(dh.t, err)
+	return path
+}
+
+func (dh *DealHarness) retrieveWithEvents(ctx context.Context, deal *cid.Cid, root cid.Cid, sel *api.Selector, carExport bool, cb func(marketevents.RetrievalEvent)) (path string, err error) {
 	// perform retrieval.
 	info, err := dh.client.ClientGetDealInfo(ctx, *deal)
 	require.NoError(dh.t, err)
 
-	offers, err := dh.client.ClientFindData(ctx, root, &info.PieceCID)
+	offers, err := dh.client.ClientFindDataAsync(ctx, root, &info.PieceCID)
 	require.NoError(dh.t, err)
-	require.NotEmpty(dh.t, offers, "no offers")
+
+	// use the first usable offer that arrives
+	var offer *api.QueryOffer
+	for o := range offers {
+		if o.Err == "" {
+			o := o
+			offer = &o
+			break
+		}
+		dh.t.Logf("query offer error from %s: %s", o.MinerPeer.ID, o.Err)
+	}
+	require.NotNil(dh.t, offer, "no offers")
 
 	carFile, err := ioutil.TempFile(dh.t.TempDir(), "ret-car")
 	require.NoError(dh.t, err)
@@ -235,21 +1270,161 @@ func (dh *DealHarness) PerformRetrieval(ctx context.Context, deal *cid.Cid, root
 		IsCAR: carExport,
 	}
 
-	updates, err := dh.client.ClientRetrieveWithEvents(ctx, offers[0].Order(caddr), ref)
+	order := offer.Order(caddr)
+	order.DataSelector = sel
+
+	updates, err := dh.client.ClientRetrieveWithEvents(ctx, order, ref)
 	require.NoError(dh.t, err)
 
+	var retErr error
 	for update := range updates {
-		require.Emptyf(dh.t, update.Err, "retrieval failed: %s", update.Err)
+		if cb != nil {
+			cb(update)
+		}
+		if update.Err != "" && retErr == nil {
+			retErr = xerrors.Errorf("retrieval failed: %s", update.Err)
+		}
+	}
+	if retErr != nil {
+		return "", retErr
 	}
 
 	ret := carFile.Name()
-	if carExport {
+	if carExport && sel == nil {
 		actualFile := dh.ExtractFileFromCAR(ctx, carFile)
 		ret = actualFile.Name()
 		_ = actualFile.Close() //nolint:errcheck
 	}
 
-	return ret
+	return ret, nil
+}
+
+// RangeRetrievalParams select the part of the data of a deal
+// PerformRangeRetrieval retrieves.
+type RangeRetrievalParams struct {
+	// Selector, if set, selects the retrieved part of the DAG
+	Selector *api.Selector
+
+	// otherwise the leaves of the file covering the byte range are retrieved
+	Offset, Length uint64
+}
+
+// PerformRangeRetrieval retrieves part of the data of a deal, exported as a
+// CAR, and returns the file bytes of the retrieved leaves in order. The root of
+// the data must be a file with a single layer of leaves. With a byte range,
+// it's checked that no leaves outside of the range were retrieved, and only
+// the bytes of the range are returned.
+func (dh *DealHarness) PerformRangeRetrieval(ctx context.Context, deal *cid.Cid, root cid.Cid, params RangeRetrievalParams) []byte {
+	sel := params.Selector
+	var first, last int
+	if sel == nil {
+		require.NotZero(dh.t, params.Length, "no selector or byte range")
+
+		// get the root node first to find out which leaves cover the range
+		rootOnly := selectorString(dh.t, builder.NewSelectorSpecBuilder(basicnode.Prototype.Any).Matcher().Node())
+		nd, _ := dh.loadRetrievedRoot(dh.retrieve(ctx, deal, root, &rootOnly, true), root)
+		fsn, err := unixfs.FSNodeFromBytes(nd.Data())
+		require.NoError(dh.t, err)
+		if fsn.NumChildren() == 0 {
+			// the file fits in the root
+			require.LessOrEqual(dh.t, params.Offset+params.Length, uint64(len(fsn.Data())), "byte range outside of the file")
+			return fsn.Data()[params.Offset : params.Offset+params.Length]
+		}
+
+		first, last = -1, -1
+		var off uint64
+		for i := 0; i < fsn.NumChildren(); i++ {
+			end := off + fsn.BlockSize(i)
+			if end > params.Offset && off < params.Offset+params.Length {
+				if first < 0 {
+					first = i
+				}
+				last = i
+			}
+			off = end
+		}
+		require.True(dh.t, first >= 0 && params.Offset+params.Length <= off, "byte range outside of the file")
+
+		ssb := builder.NewSelectorSpecBuilder(basicnode.Prototype.Any)
+		s := selectorString(dh.t, ssb.ExploreFields(func(efsb builder.ExploreFieldsSpecBuilder) {
+			efsb.Insert("Links", ssb.ExploreRange(first, last+1,
+				ssb.ExploreFields(func(efsb builder.ExploreFieldsSpecBuilder) {
+					efsb.Insert("Hash", ssb.Matcher())
+				})))
+		}).Node())
+		sel = &s
+	}
+
+	nd, bs := dh.loadRetrievedRoot(dh.retrieve(ctx, deal, root, sel, true), root)
+	fsn, err := unixfs.FSNodeFromBytes(nd.Data())
+	require.NoError(dh.t, err)
+	if len(nd.Links()) == 0 {
+		return fsn.Data()
+	}
+
+	var start uint64
+	var data []byte
+	for i, l := range nd.Links() {
+		if params.Selector == nil && i < first {
+			start += fsn.BlockSize(i)
+		}
+
+		has, err := bs.Has(l.Cid)
+		require.NoError(dh.t, err)
+		if params.Selector == nil && (i < first || i > last) {
+			require.False(dh.t, has, "leaf %d outside of the byte range retrieved", i)
+			continue
+		}
+		if !has {
+			require.NotNil(dh.t, params.Selector, "leaf %d in the byte range not retrieved", i)
+			continue
+		}
+
+		blk, err := bs.Get(l.Cid)
+		require.NoError(dh.t, err)
+		data = append(data, leafData(dh.t, blk)...)
+	}
+
+	if params.Selector == nil {
+		data = data[params.Offset-start : params.Offset-start+params.Length]
+	}
+	return data
+}
+
+// loadRetrievedRoot loads a retrieved CAR into a new blockstore, returning the
+// root node
+func (dh *DealHarness) loadRetrievedRoot(carPath string, root cid.Cid) (*dag.ProtoNode, blockstore.Blockstore) {
+	f, err := os.Open(carPath)
+	require.NoError(dh.t, err)
+	defer f.Close() //nolint:errcheck
+
+	bs := blockstore.NewMemory()
+	_, err = car.LoadCar(bs, f)
+	require.NoError(dh.t, err)
+
+	blk, err := bs.Get(root)
+	require.NoError(dh.t, err)
+	nd, err := dag.DecodeProtobuf(blk.RawData())
+	require.NoError(dh.t, err)
+	return nd, bs
+}
+
+func leafData(t *testing.T, blk blocks.Block) []byte {
+	if blk.Cid().Prefix().Codec == cid.Raw {
+		return blk.RawData()
+	}
+
+	nd, err := dag.DecodeProtobuf(blk.RawData())
+	require.NoError(t, err)
+	fsn, err := unixfs.FSNodeFromBytes(nd.Data())
+	require.NoError(t, err)
+	return fsn.Data()
+}
+
+func selectorString(t *testing.T, sel ipldprime.Node) api.Selector {
+	var buf bytes.Buffer
+	require.NoError(t, dagjson.Encoder(sel, &buf))
+	return api.Selector(buf.String())
 }
 
 func (dh *DealHarness) ExtractFileFromCAR(ctx context.Context, file *os.File) (out *os.File) {
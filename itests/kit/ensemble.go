@@ -195,7 +195,10 @@ func (n *Ensemble) Miner(miner *TestMiner, full *TestFullNode, opts ...NodeOpt)
 	require.NoError(n.t, err)
 
 	ownerKey := options.ownerKey
-	if !n.bootstrapped {
+	if s := options.sealer; s != nil {
+		require.True(n.t, n.bootstrapped, "retrieval-only miners are started after their sealing miner")
+		actorAddr, ownerKey = s.ActorAddr, s.OwnerKey
+	} else if !n.bootstrapped {
 		var (
 			sectors = options.sectors
 			k       *types.KeyInfo
@@ -333,7 +336,7 @@ func (n *Ensemble) Start() *Ensemble {
 
 	// Create all inactive miners.
 	for i, m := range n.inactive.miners {
-		if n.bootstrapped {
+		if n.bootstrapped && m.options.sealer == nil {
 			// this is a miner created after genesis, so it won't have a preseal.
 			// we need to create it on chain.
 			params, aerr := actors.SerializeParams(&power2.CreateMinerParams{
@@ -406,6 +409,19 @@ func (n *Ensemble) Start() *Ensemble {
 		err = ds.Put(datastore.NewKey("miner-address"), m.ActorAddr.Bytes())
 		require.NoError(n.t, err)
 
+		err = lr.SetConfig(func(raw interface{}) {
+			cfg := raw.(*config.StorageMiner)
+			for _, mutate := range m.options.minerCfgOpts {
+				mutate(cfg)
+			}
+			if s := m.options.sealer; s != nil {
+				require.NotNil(n.t, s.ListenAddr, "sealing miner must be started through RPC")
+				cfg.Subsystems.RetrievalOnly = true
+				cfg.Subsystems.SealerApiInfo = s.ListenAddr.String()
+			}
+		})
+		require.NoError(n.t, err)
+
 		nic := storedcounter.New(ds, datastore.NewKey(modules.StorageCounterDSPrefix))
 		for i := 0; i < m.options.sectors; i++ {
 			_, err := nic.Next()
@@ -428,8 +444,12 @@ func (n *Ensemble) Start() *Ensemble {
 			Value:  types.NewInt(0),
 		}
 
-		_, err = m.FullNode.MpoolPushMessage(ctx, msg, nil)
-		require.NoError(n.t, err)
+		// the peer ID of the sealing miner stays on chain for retrieval-only
+		// miners
+		if m.options.sealer == nil {
+			_, err = m.FullNode.MpoolPushMessage(ctx, msg, nil)
+			require.NoError(n.t, err)
+		}
 
 		var mineBlock = make(chan lotusminer.MineReq)
 		opts := []node.Option{
@@ -455,6 +475,10 @@ func (n *Ensemble) Start() *Ensemble {
 		// append any node builder options.
 		opts = append(opts, m.options.extraNodeOpts...)
 
+		if m.options.dealFilter != nil {
+			opts = append(opts, node.Override(new(dtypes.StorageDealFilter), modules.BasicDealFilter(m.options.dealFilter)))
+		}
+
 		idAddr, err := address.IDFromAddress(m.ActorAddr)
 		require.NoError(n.t, err)
 
@@ -473,7 +497,11 @@ func (n *Ensemble) Start() *Ensemble {
 		if n.options.mockProofs {
 			opts = append(opts,
 				node.Override(new(*mock.SectorMgr), func() (*mock.SectorMgr, error) {
-					return mock.NewMockSectorMgr(presealSectors), nil
+					if s := m.options.sealer; s != nil {
+						return s.mockSectors, nil
+					}
+					m.mockSectors = mock.NewMockSectorMgr(presealSectors)
+					return m.mockSectors, nil
 				}),
 				node.Override(new(sectorstorage.SectorManager), node.From(new(*mock.SectorMgr))),
 				node.Override(new(sectorstorage.Unsealer), node.From(new(*mock.SectorMgr))),
@@ -605,6 +633,9 @@ func (n *Ensemble) BeginMining(blocktime time.Duration, miners ...*TestMiner) []
 			if _, ok := n.active.bms[m]; ok {
 				continue // skip, already have a block miner
 			}
+			if m.options.sealer != nil {
+				continue // retrieval-only miners don't mine
+			}
 			miners = append(miners, m)
 		}
 	}
@@ -31,6 +31,19 @@ func CreateRandomFile(t *testing.T, rseed, size int) (path string) {
 	return file.Name()
 }
 
+// CreateFile writes the data to a new file in the temporary directory of the
+// test, and returns its path
+func CreateFile(t *testing.T, data []byte) (path string) {
+	file, err := os.CreateTemp(t.TempDir(), "sourcefile.dat")
+	require.NoError(t, err)
+
+	_, err = file.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+
+	return file.Name()
+}
+
 // AssertFilesEqual compares two files by blake2b hash equality and
 // fails the test if unequal.
 func AssertFilesEqual(t *testing.T, left, right string) {
@@ -17,7 +17,7 @@ func init() {
 	policy.SetProviderCollateralSupplyTarget(big.Zero(), big.NewInt(1))
 
 	policy.SetConsensusMinerMinPower(abi.NewStoragePower(2048))
-	policy.SetSupportedProofTypes(abi.RegisteredSealProof_StackedDrg2KiBV1)
+	policy.SetSupportedProofTypes(abi.RegisteredSealProof_StackedDrg2KiBV1, abi.RegisteredSealProof_StackedDrg8MiBV1)
 	policy.SetMinVerifiedDealSize(abi.NewStoragePower(256))
 
 	build.InsecurePoStValidation = true
@@ -5,15 +5,42 @@ import (
 	logging "github.com/ipfs/go-log/v2"
 )
 
+// quietSubsystems are the noisy subsystems QuietMiningLogs sets to ERROR
+var quietSubsystems = []string{
+	"miner", // set this to INFO to watch mining happen.
+	"chainstore",
+	"chain",
+	"sub",
+	"storageminer",
+	"pubsub",
+	"gen",
+	"dht/RtRefreshManager",
+}
+
 func QuietMiningLogs() {
+	QuietMiningLogsExcept()
+}
+
+// QuietMiningLogsExcept is QuietMiningLogs, setting the named subsystems to
+// DEBUG instead of quieting them.
+func QuietMiningLogsExcept(subsystems ...string) {
+	levels := make(map[string]string, len(quietSubsystems)+len(subsystems))
+	for _, s := range quietSubsystems {
+		levels[s] = "ERROR"
+	}
+	for _, s := range subsystems {
+		levels[s] = "DEBUG"
+	}
+
+	SetTestLogLevels(levels)
+}
+
+// SetTestLogLevels sets up the default log levels, then sets each subsystem
+// in levels to the given level.
+func SetTestLogLevels(levels map[string]string) {
 	lotuslog.SetupLogLevels()
 
-	_ = logging.SetLogLevel("miner", "ERROR") // set this to INFO to watch mining happen.
-	_ = logging.SetLogLevel("chainstore", "ERROR")
-	_ = logging.SetLogLevel("chain", "ERROR")
-	_ = logging.SetLogLevel("sub", "ERROR")
-	_ = logging.SetLogLevel("storageminer", "ERROR")
-	_ = logging.SetLogLevel("pubsub", "ERROR")
-	_ = logging.SetLogLevel("gen", "ERROR")
-	_ = logging.SetLogLevel("dht/RtRefreshManager", "ERROR")
+	for s, level := range levels {
+		_ = logging.SetLogLevel(s, level)
+	}
 }
@@ -2,6 +2,7 @@ package kit
 
 import (
 	"context"
+	"path/filepath"
 	"testing"
 
 	"github.com/filecoin-project/go-address"
@@ -37,6 +38,22 @@ func (f *TestFullNode) CreateImportFile(ctx context.Context, rseed int, size int
 	return res, path
 }
 
+// CreateImportCar creates a random file with the specified seed and size,
+// imports it into the full node, and exports it as a CAR file. It returns the
+// CAR path together with its commP, as calculated by the client.
+func (f *TestFullNode) CreateImportCar(ctx context.Context, rseed int, size int) (res *api.ImportRes, carPath string, commP api.DataCIDSize) {
+	res, path := f.CreateImportFile(ctx, rseed, size)
+
+	carPath = filepath.Join(f.t.TempDir(), "out.car")
+	err := f.ClientGenCar(ctx, api.FileRef{Path: path}, carPath)
+	require.NoError(f.t, err)
+
+	commP, err = f.ClientCalcCommP(ctx, carPath)
+	require.NoError(f.t, err)
+
+	return res, carPath, commP
+}
+
 // WaitTillChain waits until a specified chain condition is met. It returns
 // the first tipset where the condition is met.
 func (f *TestFullNode) WaitTillChain(ctx context.Context, pred ChainPredicate) *types.TipSet {
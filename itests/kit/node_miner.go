@@ -18,8 +18,11 @@ import (
 	"github.com/filecoin-project/go-state-types/abi"
 	"github.com/filecoin-project/lotus/api"
 	"github.com/filecoin-project/lotus/build"
+	"github.com/filecoin-project/lotus/chain/types"
 	"github.com/filecoin-project/lotus/chain/wallet"
+	"github.com/filecoin-project/lotus/extern/sector-storage/mock"
 	"github.com/filecoin-project/lotus/extern/sector-storage/stores"
+	"github.com/filecoin-project/lotus/extern/sector-storage/storiface"
 	sealing "github.com/filecoin-project/lotus/extern/storage-sealing"
 	"github.com/filecoin-project/lotus/miner"
 	libp2pcrypto "github.com/libp2p/go-libp2p-core/crypto"
@@ -51,6 +54,9 @@ type TestMiner struct {
 	}
 
 	options nodeOpts
+
+	// the mock sector manager, with mock proofs
+	mockSectors *mock.SectorMgr
 }
 
 func (tm *TestMiner) PledgeSectors(ctx context.Context, n, existing int, blockNotif <-chan struct{}) {
@@ -90,10 +96,16 @@ func (tm *TestMiner) StartPledge(ctx context.Context, n, existing int, blockNoti
 	}
 
 	for {
-		s, err := tm.StorageMiner.SectorsList(ctx) // Note - the test builder doesn't import genesis sectors into FSM
+		summary, err := tm.StorageMiner.SectorsSummary(ctx) // Note - the test builder doesn't import genesis sectors into FSM
 		require.NoError(tm.t, err)
-		fmt.Printf("Sectors: %d\n", len(s))
-		if len(s) >= n+existing {
+
+		var total int
+		for _, cnt := range summary {
+			total += cnt
+		}
+
+		fmt.Printf("Sectors: %d\n", total)
+		if total >= n+existing {
 			break
 		}
 
@@ -113,6 +125,59 @@ func (tm *TestMiner) StartPledge(ctx context.Context, n, existing int, blockNoti
 	return toCheck
 }
 
+// AssertNoWindowPoStFaults checks that none of the sectors of the miner are
+// faulty on chain, and that the WindowPoSt scheduler wouldn't declare any
+// sectors faulty in any of the deadlines, see ComputeWindowPoStDryRun
+func (tm *TestMiner) AssertNoWindowPoStFaults(ctx context.Context) {
+	faults, err := tm.FullNode.StateMinerFaults(ctx, tm.ActorAddr, types.EmptyTSK)
+	require.NoError(tm.t, err)
+	n, err := faults.Count()
+	require.NoError(tm.t, err)
+	require.Zero(tm.t, n, "miner has %d faulty sectors on chain", n)
+
+	di, err := tm.FullNode.StateMinerProvingDeadline(ctx, tm.ActorAddr, types.EmptyTSK)
+	require.NoError(tm.t, err)
+
+	for dl := uint64(0); dl < di.WPoStPeriodDeadlines; dl++ {
+		dry, err := tm.StorageMiner.ComputeWindowPoStDryRun(ctx, dl)
+		require.NoError(tm.t, err)
+		require.Empty(tm.t, dry.Faults, "sectors in deadline %d would be declared faulty", dl)
+	}
+}
+
+// CorruptSector truncates the sealed replica of the sector in the local
+// storage paths of the miner, so that the sector fails the WindowPoSt checks.
+// The sealed file is looked up in the storage index, and has to be stored in
+// a local path of the miner. Needs real sector storage, the MockProofs sector
+// manager has no files, see mock.SectorMgr.MarkCorrupted instead.
+func (tm *TestMiner) CorruptSector(ctx context.Context, num abi.SectorNumber) {
+	mid, err := address.IDFromAddress(tm.ActorAddr)
+	require.NoError(tm.t, err)
+
+	sid := abi.SectorID{Miner: abi.ActorID(mid), Number: num}
+
+	infos, err := tm.StorageMiner.StorageFindSector(ctx, sid, storiface.FTSealed, 0, false)
+	require.NoError(tm.t, err)
+	require.NotEmpty(tm.t, infos, "sector %d has no sealed copy in the storage index", num)
+
+	local, err := tm.StorageMiner.StorageLocal(ctx)
+	require.NoError(tm.t, err)
+
+	var corrupted int
+	for _, info := range infos {
+		root, ok := local[info.ID]
+		if !ok {
+			continue
+		}
+
+		sealed := filepath.Join(root, storiface.FTSealed.String(), storiface.SectorName(sid))
+		require.NoError(tm.t, os.Truncate(sealed, 0), "truncating sealed file of sector %d", num)
+		corrupted++
+	}
+
+	require.NotZero(tm.t, corrupted, "sector %d has no sealed copy in the local storage", num)
+}
+
 func (tm *TestMiner) FlushSealingBatches(ctx context.Context) {
 	pcb, err := tm.StorageMiner.SectorPreCommitFlush(ctx)
 	require.NoError(tm.t, err)
@@ -7,6 +7,8 @@ import (
 	"github.com/filecoin-project/lotus/chain/types"
 	"github.com/filecoin-project/lotus/chain/wallet"
 	"github.com/filecoin-project/lotus/node"
+	"github.com/filecoin-project/lotus/node/config"
+	"github.com/filecoin-project/lotus/node/modules/dtypes"
 )
 
 // DefaultPresealsPerBootstrapMiner is the number of preseals that every
@@ -27,6 +29,9 @@ type nodeOpts struct {
 	extraNodeOpts []node.Option
 	optBuilders   []OptBuilder
 	proofType     abi.RegisteredSealProof
+	minerCfgOpts  []func(cfg *config.StorageMiner)
+	dealFilter    dtypes.StorageDealFilter
+	sealer        *TestMiner
 }
 
 // DefaultNodeOpts are the default options that will be applied to test nodes.
@@ -105,3 +110,34 @@ func ProofType(proofType abi.RegisteredSealProof) NodeOpt {
 		return nil
 	}
 }
+
+// MinerConfig mutates the config of a miner repo before the miner is
+// started. Only relevant when creating a miner.
+func MinerConfig(mutate func(cfg *config.StorageMiner)) NodeOpt {
+	return func(opts *nodeOpts) error {
+		opts.minerCfgOpts = append(opts.minerCfgOpts, mutate)
+		return nil
+	}
+}
+
+// StorageDealFilter makes a miner consult the filter for storage deal
+// proposals, after the checks of the miner config, which lets tests force the
+// rejection of proposals. Only relevant when creating a miner.
+func StorageDealFilter(filter dtypes.StorageDealFilter) NodeOpt {
+	return func(opts *nodeOpts) error {
+		opts.dealFilter = filter
+		return nil
+	}
+}
+
+// RetrievalOnlyFor runs the miner as a retrieval-only node for the actor of
+// the sealing miner, which has to be started through RPC first. The miner
+// doesn't mine blocks. With mock proofs, the nodes share the mock sector
+// manager in place of the sector storage. Only relevant when creating a
+// miner.
+func RetrievalOnlyFor(sealer *TestMiner) NodeOpt {
+	return func(opts *nodeOpts) error {
+		opts.sealer = sealer
+		return nil
+	}
+}
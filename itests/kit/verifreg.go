@@ -0,0 +1,89 @@
+package kit
+
+import (
+	"context"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
+	verifreg4 "github.com/filecoin-project/specs-actors/v4/actors/builtin/verifreg"
+
+	"github.com/filecoin-project/lotus/chain/actors"
+	"github.com/filecoin-project/lotus/chain/actors/builtin/verifreg"
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/lotus/chain/wallet"
+)
+
+// AddVerifiedClient grants datacap to clientAddr through the verified
+// registry. rootKey is the verified registry root key set with the
+// RootVerifier ensemble option, which needs a balance to send messages. The
+// root key adds a new verifier with an allowance of datacap, funded by the
+// default wallet of this node, which then adds the client.
+func (f *TestFullNode) AddVerifiedClient(ctx context.Context, rootKey *wallet.Key, clientAddr address.Address, datacap abi.StoragePower) {
+	// the root key is imported by the first call for the node
+	rootAddr := rootKey.Address
+	has, err := f.WalletHas(ctx, rootAddr)
+	require.NoError(f.t, err)
+	if !has {
+		_, err = f.WalletImport(ctx, &rootKey.KeyInfo)
+		require.NoError(f.t, err)
+	}
+
+	verifierAddr, err := f.WalletNew(ctx, types.KTSecp256k1)
+	require.NoError(f.t, err)
+	SendFunds(ctx, f.t, f, verifierAddr, types.FromFil(10))
+
+	params, err := actors.SerializeParams(&verifreg4.AddVerifierParams{Address: verifierAddr, Allowance: datacap})
+	require.NoError(f.t, err)
+
+	sm, err := f.MpoolPushMessage(ctx, &types.Message{
+		From:   rootAddr,
+		To:     verifreg.Address,
+		Method: verifreg.Methods.AddVerifier,
+		Params: params,
+		Value:  big.Zero(),
+	}, nil)
+	require.NoError(f.t, err, "AddVerifier failed")
+	f.WaitMsg(ctx, sm.Cid())
+
+	params, err = actors.SerializeParams(&verifreg4.AddVerifiedClientParams{Address: clientAddr, Allowance: datacap})
+	require.NoError(f.t, err)
+
+	sm, err = f.MpoolPushMessage(ctx, &types.Message{
+		From:   verifierAddr,
+		To:     verifreg.Address,
+		Method: verifreg.Methods.AddVerifiedClient,
+		Params: params,
+		Value:  big.Zero(),
+	}, nil)
+	require.NoError(f.t, err, "AddVerifiedClient failed")
+	f.WaitMsg(ctx, sm.Cid())
+}
+
+// GetDatacap returns the remaining datacap of a verified client, zero if the
+// address isn't a verified client, or its datacap was used up.
+func (f *TestFullNode) GetDatacap(ctx context.Context, addr address.Address) abi.StoragePower {
+	dcap, err := f.StateVerifiedClientStatus(ctx, addr, types.EmptyTSK)
+	require.NoError(f.t, err)
+
+	if dcap == nil {
+		return big.Zero()
+	}
+	return *dcap
+}
+
+// GrantDatacap grants datacap to the default wallet of the client, so that it
+// can make verified deals, see TestFullNode.AddVerifiedClient.
+func (dh *DealHarness) GrantDatacap(ctx context.Context, rootKey *wallet.Key, datacap abi.StoragePower) {
+	clientAddr, err := dh.client.WalletDefaultAddress(ctx)
+	require.NoError(dh.t, err)
+
+	before := dh.client.GetDatacap(ctx, clientAddr)
+	dh.client.AddVerifiedClient(ctx, rootKey, clientAddr, datacap)
+
+	dcap := dh.client.GetDatacap(ctx, clientAddr)
+	expected := big.Add(before, datacap)
+	require.True(dh.t, dcap.Equals(expected), "client datacap %s, expected %s", dcap, expected)
+}
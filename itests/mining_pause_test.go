@@ -0,0 +1,61 @@
+package itests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/lotus/itests/kit"
+)
+
+func TestMiningPause(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	kit.QuietMiningLogs()
+
+	blockTime := 10 * time.Millisecond
+	client, miner, ens := kit.EnsembleMinimal(t, kit.MockProofs())
+	ens.InterconnectAll().BeginMining(blockTime)
+
+	st, err := miner.MiningStatus(ctx)
+	require.NoError(t, err)
+	require.True(t, st.Enabled)
+
+	headHeight := func() abi.ChainEpoch {
+		head, err := client.ChainHead(ctx)
+		require.NoError(t, err)
+		return head.Height()
+	}
+
+	// wait for the chain to make some progress
+	start := headHeight()
+	time.Sleep(20 * blockTime)
+	require.Greater(t, headHeight(), start)
+
+	require.NoError(t, miner.MiningSetEnabled(ctx, false))
+
+	st, err = miner.MiningStatus(ctx)
+	require.NoError(t, err)
+	require.False(t, st.Enabled)
+	require.False(t, st.Since.IsZero())
+
+	// let any in-flight block land, then make sure the chain stops
+	time.Sleep(10 * blockTime)
+	paused := headHeight()
+
+	time.Sleep(50 * blockTime)
+	require.Equal(t, paused, headHeight(), "chain progressed while block production was paused")
+
+	require.NoError(t, miner.MiningSetEnabled(ctx, true))
+
+	st, err = miner.MiningStatus(ctx)
+	require.NoError(t, err)
+	require.True(t, st.Enabled)
+
+	time.Sleep(50 * blockTime)
+	require.Greater(t, headHeight(), paused)
+}
@@ -0,0 +1,78 @@
+package itests
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/itests/kit"
+)
+
+func TestRetrievalOnlyMiner(t *testing.T) {
+	ctx := context.Background()
+
+	kit.QuietMiningLogs()
+
+	client, miner, ens := kit.EnsembleMinimal(t, kit.MockProofs(), kit.ThroughRPC())
+	ens.InterconnectAll().BeginMining(50 * time.Millisecond)
+	dh := kit.NewDealHarness(t, client, miner)
+
+	// the sealing miner takes and seals the deal
+	deal, res, inPath := dh.MakeOnlineDeal(ctx, kit.MakeFullDealParams{Rseed: 5})
+
+	// the retrieval-only miner for the same actor reads the pieces and sectors
+	// of the sealing miner
+	var retrievalMiner kit.TestMiner
+	ens.Miner(&retrievalMiner, client, kit.RetrievalOnlyFor(miner)).Start().InterconnectAll()
+
+	info, err := client.ClientGetDealInfo(ctx, *deal)
+	require.NoError(t, err)
+
+	offer, err := client.ClientMinerQueryOffer(ctx, miner.ActorAddr, res.Root, &info.PieceCID)
+	require.NoError(t, err)
+	require.Empty(t, offer.Err)
+
+	// retrieve from the retrieval-only miner instead of the peer on chain
+	offer.MinerPeer.ID = retrievalMiner.Libp2p.PeerID
+
+	caddr, err := client.WalletDefaultAddress(ctx)
+	require.NoError(t, err)
+
+	outPath := filepath.Join(t.TempDir(), "retrieved")
+	updates, err := client.ClientRetrieveWithEvents(ctx, offer.Order(caddr), &api.FileRef{Path: outPath})
+	require.NoError(t, err)
+	for update := range updates {
+		require.Empty(t, update.Err)
+	}
+
+	kit.AssertFilesEqual(t, inPath, outPath)
+
+	served, err := retrievalMiner.MarketListRetrievalDeals(ctx)
+	require.NoError(t, err)
+	require.Len(t, served, 1)
+
+	sealerServed, err := miner.MarketListRetrievalDeals(ctx)
+	require.NoError(t, err)
+	require.Empty(t, sealerServed)
+
+	// sealing operations are rejected
+	_, err = retrievalMiner.PledgeSector(ctx)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "sealing is disabled")
+
+	_, err = retrievalMiner.SectorsList(ctx)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "sealing is disabled")
+
+	// sector info is read from the sealing miner
+	sector := dh.SectorForDeal(ctx, deal)
+	si, err := retrievalMiner.SectorsStatus(ctx, sector, false)
+	require.NoError(t, err)
+	expect, err := miner.SectorsStatus(ctx, sector, false)
+	require.NoError(t, err)
+	require.Equal(t, expect.CommD, si.CommD)
+}
@@ -0,0 +1,94 @@
+package itests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+
+	"github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/extern/sector-storage/stores"
+	"github.com/filecoin-project/lotus/extern/sector-storage/storiface"
+	sealing "github.com/filecoin-project/lotus/extern/storage-sealing"
+	"github.com/filecoin-project/lotus/itests/kit"
+	"github.com/filecoin-project/lotus/node/config"
+)
+
+func TestDeferredFinalizeProvesFromSealingStorage(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test in short mode")
+	}
+
+	kit.QuietMiningLogs()
+
+	ctx := context.Background()
+
+	_, miner, ens := kit.EnsembleMinimal(t, kit.MinerConfig(func(cfg *config.StorageMiner) {
+		cfg.Sealing.DeferFinalize = true
+		cfg.Sealing.FinalizeBatchInterval = 0 // only released when flushed
+	})) // no mock proofs, the sealed files are checked for proving
+	ens.InterconnectAll().BeginMining(50 * time.Millisecond)
+
+	// sectors are sealed in the seal-only path, and moved to the store-only
+	// path when finalized
+	miner.AddStorage(ctx, t, 1000000000, true, false)
+	miner.AddStorage(ctx, t, 1000000000, false, true)
+
+	mid, err := address.IDFromAddress(miner.ActorAddr)
+	require.NoError(t, err)
+	ssize, err := miner.ActorSectorSize(ctx, miner.ActorAddr)
+	require.NoError(t, err)
+
+	var sn abi.SectorNumber
+	for n := range miner.StartPledge(ctx, 1, 0, nil) {
+		sn = n
+	}
+
+	waitState := func(state sealing.SectorState) {
+		require.Eventually(t, func() bool {
+			miner.FlushSealingBatches(ctx)
+
+			st, err := miner.SectorsStatus(ctx, sn, false)
+			require.NoError(t, err)
+			require.NotContains(t, string(st.State), "Fail")
+			return st.State == api.SectorState(state)
+		}, 5*time.Minute, 100*time.Millisecond)
+	}
+
+	sealedIn := func() []stores.SectorStorageInfo {
+		infos, err := miner.StorageFindSector(ctx, abi.SectorID{Miner: abi.ActorID(mid), Number: sn}, storiface.FTSealed, ssize, false)
+		require.NoError(t, err)
+		require.NotEmpty(t, infos)
+		return infos
+	}
+
+	waitState(sealing.FinalizeReady)
+
+	pending, err := miner.SectorFinalizePending(ctx)
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	require.Equal(t, sn, pending[0].Number)
+
+	// while the sector waits it stays in the sealing storage, and is provable
+	// from there
+	for _, si := range sealedIn() {
+		require.True(t, si.CanSeal)
+		require.False(t, si.CanStore)
+	}
+	miner.AssertNoWindowPoStFaults(ctx)
+
+	flushed, err := miner.SectorFinalizeFlush(ctx)
+	require.NoError(t, err)
+	require.Len(t, flushed, 1)
+
+	waitState(sealing.Proving)
+
+	for _, si := range sealedIn() {
+		require.True(t, si.CanStore)
+	}
+	miner.AssertNoWindowPoStFaults(ctx)
+}
@@ -0,0 +1,57 @@
+package itests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-state-types/network"
+
+	"github.com/filecoin-project/lotus/chain/actors/policy"
+	"github.com/filecoin-project/lotus/extern/storage-sealing/sealiface"
+	"github.com/filecoin-project/lotus/itests/kit"
+	"github.com/filecoin-project/lotus/node"
+	"github.com/filecoin-project/lotus/node/config"
+	"github.com/filecoin-project/lotus/node/modules"
+	"github.com/filecoin-project/lotus/node/modules/dtypes"
+	"github.com/filecoin-project/lotus/node/repo"
+)
+
+func TestSyntheticPoRepSectors(t *testing.T) {
+	kit.QuietMiningLogs()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	blockTime := 50 * time.Millisecond
+
+	_, miner, ens := kit.EnsembleMinimal(t, kit.MockProofs(), kit.ConstructorOpts(
+		node.ApplyIf(node.IsType(repo.StorageMiner), node.Override(new(dtypes.GetSealingConfigFunc), func() (dtypes.GetSealingConfigFunc, error) {
+			return func() (sealiface.Config, error) {
+				cf := config.DefaultStorageMiner()
+				cf.Sealing.UseSyntheticPoRep = true
+				return modules.ToSealingConfig(cf), nil
+			}, nil
+		}))))
+	ens.InterconnectAll().BeginMining(blockTime)
+
+	// no network supports synthetic PoRep yet, so sealing must not start
+	_, err := miner.PledgeSector(ctx)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "synthetic PoRep")
+
+	policy.SetSyntheticPoRepVersion(network.Version0)
+	t.Cleanup(policy.ResetSyntheticPoRep)
+
+	miner.PledgeSectors(ctx, 1, 0, nil)
+
+	sectors, err := miner.SectorsList(ctx)
+	require.NoError(t, err)
+	require.Len(t, sectors, 1)
+
+	si, err := miner.SectorsStatus(ctx, sectors[0], false)
+	require.NoError(t, err)
+	require.True(t, si.SyntheticPoRep)
+}
@@ -0,0 +1,57 @@
+package itests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/specs-storage/storage"
+
+	"github.com/filecoin-project/lotus/extern/sector-storage/mock"
+	"github.com/filecoin-project/lotus/itests/kit"
+	"github.com/filecoin-project/lotus/node/impl"
+)
+
+func TestSectorVerifyCommR(t *testing.T) {
+	ctx := context.Background()
+
+	kit.QuietMiningLogs()
+
+	_, miner, ens := kit.EnsembleMinimal(t, kit.MockProofs())
+	ens.InterconnectAll().BeginMining(50 * time.Millisecond)
+
+	maddr, err := miner.ActorAddress(ctx)
+	require.NoError(t, err)
+	mid, err := address.IDFromAddress(maddr)
+	require.NoError(t, err)
+
+	// preseal sectors match their on-chain CommR
+	ok, err := miner.SectorVerifyCommR(ctx, 0)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	err = miner.StorageMiner.(*impl.StorageMinerAPI).IStorageMgr.(*mock.SectorMgr).MarkCorrupted(storage.SectorRef{
+		ID: abi.SectorID{
+			Miner:  abi.ActorID(mid),
+			Number: 0,
+		},
+	}, true)
+	require.NoError(t, err)
+
+	ok, err = miner.SectorVerifyCommR(ctx, 0)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	// other sectors are unaffected
+	ok, err = miner.SectorVerifyCommR(ctx, 1)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	// sectors which aren't on chain can't be checked
+	_, err = miner.SectorVerifyCommR(ctx, 100)
+	require.Error(t, err)
+}
@@ -7,6 +7,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/filecoin-project/go-fil-markets/storagemarket"
 	"github.com/filecoin-project/go-state-types/abi"
 	"github.com/filecoin-project/go-state-types/big"
 	"github.com/filecoin-project/go-state-types/network"
@@ -142,3 +143,65 @@ func TestVerifiedClientTopUp(t *testing.T) {
 	t.Run("nv12", test(network.Version12, false))
 	t.Run("nv13", test(network.Version13, true))
 }
+
+func TestVerifiedDealDatacap(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test in short mode")
+	}
+
+	ctx := context.Background()
+
+	kit.QuietMiningLogs()
+
+	rootKey, err := wallet.GenerateKey(types.KTSecp256k1)
+	require.NoError(t, err)
+
+	client, miner, ens := kit.EnsembleMinimal(t, kit.MockProofs(), kit.RootVerifier(rootKey, types.FromFil(100)))
+	ens.InterconnectAll().BeginMining(50 * time.Millisecond)
+	dh := kit.NewDealHarness(t, client, miner)
+
+	clientAddr, err := client.WalletDefaultAddress(ctx)
+	require.NoError(t, err)
+	require.True(t, client.GetDatacap(ctx, clientAddr).IsZero())
+
+	res, _ := client.CreateImportFile(ctx, 1, 0)
+	ds, err := client.ClientDealSize(ctx, res.Root)
+	require.NoError(t, err)
+	psize := big.NewInt(int64(ds.PieceSize))
+
+	// grant enough datacap for one and a half deals
+	client.AddVerifiedClient(ctx, rootKey, clientAddr, big.Add(psize, big.Div(psize, big.NewInt(2))))
+
+	deal := dh.StartDealWithParams(ctx, res.Root, kit.MakeFullDealParams{Verified: true})
+	dh.WaitDealPublished(ctx, deal)
+
+	// the deal only consumed the datacap for its piece
+	require.True(t, client.GetDatacap(ctx, clientAddr).Equals(big.Div(psize, big.NewInt(2))))
+
+	// the client doesn't have enough datacap left for another deal of the
+	// same size, which is rejected without consuming any datacap
+	res, _ = client.CreateImportFile(ctx, 2, 0)
+	deal = dh.StartDealWithParams(ctx, res.Root, kit.MakeFullDealParams{Verified: true})
+
+	require.Eventually(t, func() bool {
+		di, err := client.ClientGetDealInfo(ctx, *deal)
+		require.NoError(t, err)
+
+		switch di.State {
+		case storagemarket.StorageDealProposalRejected, storagemarket.StorageDealError, storagemarket.StorageDealFailing:
+			require.Contains(t, strings.ToLower(di.Message), "datacap")
+			return true
+		}
+		return false
+	}, time.Minute, 100*time.Millisecond)
+
+	require.True(t, client.GetDatacap(ctx, clientAddr).Equals(big.Div(psize, big.NewInt(2))))
+
+	// topping up lets the client make the deal
+	dh.GrantDatacap(ctx, rootKey, psize)
+
+	deal = dh.StartDealWithParams(ctx, res.Root, kit.MakeFullDealParams{Verified: true})
+	dh.WaitDealPublished(ctx, deal)
+
+	require.True(t, client.GetDatacap(ctx, clientAddr).Equals(big.Div(psize, big.NewInt(2))))
+}
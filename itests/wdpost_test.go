@@ -52,6 +52,7 @@ func testWindowPostUpgrade(t *testing.T, blocktime time.Duration, nSectors int,
 	ens.InterconnectAll().BeginMining(blocktime)
 
 	miner.PledgeSectors(ctx, nSectors, 0, nil)
+	miner.AssertNoWindowPoStFaults(ctx)
 
 	maddr, err := miner.ActorAddress(ctx)
 	require.NoError(t, err)
@@ -153,6 +154,14 @@ func testWindowPostUpgrade(t *testing.T, blocktime time.Duration, nSectors int,
 	sectors := p.MinerPower.RawBytePower.Uint64() / uint64(ssz)
 	require.Equal(t, nSectors+kit.DefaultPresealsPerBootstrapMiner-3, int(sectors)) // -3 just removed sectors
 
+	// the removed sectors should show up as faulty power
+	pb, err := miner.MinerPowerBreakdown(ctx)
+	require.NoError(t, err)
+	require.Equal(t, p.MinerPower.RawBytePower, pb.Active.RawBytePower)
+	require.Equal(t, p.MinerPower.QualityAdjPower, pb.Active.QualityAdjPower)
+	require.Equal(t, 3*uint64(ssz), pb.Faulty.RawBytePower.Uint64())
+	require.True(t, pb.Recovering.RawBytePower.IsZero())
+
 	t.Log("Recover one sector")
 
 	err = miner.StorageMiner.(*impl.StorageMinerAPI).IStorageMgr.(*mock.SectorMgr).MarkFailed(s, false)
@@ -175,6 +184,14 @@ func testWindowPostUpgrade(t *testing.T, blocktime time.Duration, nSectors int,
 	sectors = p.MinerPower.RawBytePower.Uint64() / uint64(ssz)
 	require.Equal(t, nSectors+kit.DefaultPresealsPerBootstrapMiner-2, int(sectors)) // -2 not recovered sectors
 
+	// the fault history should record the sector faulting, then recovering
+	hist, err := miner.SectorFaultHistory(ctx, s.ID.Number)
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, len(hist), 2)
+	require.Equal(t, api.SectorFaultSkipped, hist[0].Kind)
+	require.Equal(t, api.SectorRecoveryDeclared, hist[len(hist)-1].Kind)
+	require.Less(t, int64(hist[0].Epoch), int64(hist[len(hist)-1].Epoch))
+
 	// pledge a sector after recovery
 
 	miner.PledgeSectors(ctx, 1, nSectors, nil)
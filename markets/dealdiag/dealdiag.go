@@ -0,0 +1,76 @@
+// Package dealdiag records the state changes of storage deals on the provider
+// in a bounded buffer, so that operators can see why deals failed without
+// digging through the logs.
+package dealdiag
+
+import (
+	"sync"
+	"time"
+
+	"github.com/filecoin-project/go-fil-markets/storagemarket"
+
+	"github.com/filecoin-project/lotus/api"
+)
+
+// DefaultSize is the number of records kept by the recorder of the miner
+const DefaultSize = 4096
+
+// Recorder keeps the last records of storage provider events in a ring
+// buffer. Subscribe OnEvent to the events of the storage provider.
+type Recorder struct {
+	lk sync.Mutex
+
+	buf  []api.DealDiagnostic
+	next int
+	full bool
+
+	now func() time.Time
+}
+
+func NewRecorder(size int) *Recorder {
+	if size <= 0 {
+		size = DefaultSize
+	}
+
+	return &Recorder{
+		buf: make([]api.DealDiagnostic, size),
+		now: time.Now,
+	}
+}
+
+// OnEvent records a storage provider event, it has the signature of
+// storagemarket.ProviderSubscriber
+func (r *Recorder) OnEvent(event storagemarket.ProviderEvent, deal storagemarket.MinerDeal) {
+	rec := api.DealDiagnostic{
+		Time:        r.now(),
+		ProposalCid: deal.ProposalCid,
+		DealID:      deal.DealID,
+		Event:       storagemarket.ProviderEvents[event],
+		State:       storagemarket.DealStates[deal.State],
+		Message:     deal.Message,
+	}
+
+	r.lk.Lock()
+	defer r.lk.Unlock()
+
+	r.buf[r.next] = rec
+	r.next++
+	if r.next == len(r.buf) {
+		r.next = 0
+		r.full = true
+	}
+}
+
+// Records returns the recorded events, oldest first
+func (r *Recorder) Records() []api.DealDiagnostic {
+	r.lk.Lock()
+	defer r.lk.Unlock()
+
+	if !r.full {
+		return append([]api.DealDiagnostic{}, r.buf[:r.next]...)
+	}
+
+	out := make([]api.DealDiagnostic, 0, len(r.buf))
+	out = append(out, r.buf[r.next:]...)
+	return append(out, r.buf[:r.next]...)
+}
@@ -0,0 +1,44 @@
+package dealdiag
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-fil-markets/storagemarket"
+	"github.com/filecoin-project/go-state-types/abi"
+)
+
+func TestRecorder(t *testing.T) {
+	r := NewRecorder(3)
+
+	var tick int64
+	r.now = func() time.Time {
+		tick++
+		return time.Unix(tick, 0)
+	}
+
+	require.Empty(t, r.Records())
+
+	r.OnEvent(storagemarket.ProviderEventOpen, storagemarket.MinerDeal{State: storagemarket.StorageDealValidating})
+	r.OnEvent(storagemarket.ProviderEventDealDeciding, storagemarket.MinerDeal{State: storagemarket.StorageDealAcceptWait})
+
+	recs := r.Records()
+	require.Len(t, recs, 2)
+	require.Equal(t, "ProviderEventOpen", recs[0].Event)
+	require.Equal(t, "StorageDealValidating", recs[0].State)
+	require.Equal(t, time.Unix(1, 0), recs[0].Time)
+	require.Equal(t, "StorageDealAcceptWait", recs[1].State)
+
+	// the oldest records are dropped once the buffer is full
+	r.OnEvent(storagemarket.ProviderEventDealPublished, storagemarket.MinerDeal{State: storagemarket.StorageDealStaged, DealID: abi.DealID(7)})
+	r.OnEvent(storagemarket.ProviderEventDealRejected, storagemarket.MinerDeal{State: storagemarket.StorageDealFailing, Message: "no space"})
+
+	recs = r.Records()
+	require.Len(t, recs, 3)
+	require.Equal(t, "StorageDealAcceptWait", recs[0].State)
+	require.Equal(t, abi.DealID(7), recs[1].DealID)
+	require.Equal(t, "no space", recs[2].Message)
+	require.Equal(t, time.Unix(4, 0), recs[2].Time)
+}
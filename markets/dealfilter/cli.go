@@ -4,7 +4,9 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"io"
 	"os/exec"
+	"sync"
 
 	"github.com/filecoin-project/go-fil-markets/retrievalmarket"
 	"github.com/filecoin-project/go-fil-markets/storagemarket"
@@ -12,19 +14,34 @@ import (
 	"github.com/filecoin-project/lotus/node/modules/dtypes"
 )
 
+// FilterResult is the decision of a deal filter command, with what it wrote to
+// stderr
+type FilterResult struct {
+	Accept bool
+	Reason string
+	Stderr string
+}
+
 func CliStorageDealFilter(cmd string) dtypes.StorageDealFilter {
 	return func(ctx context.Context, deal storagemarket.MinerDeal) (bool, string, error) {
-		d := struct {
-			storagemarket.MinerDeal
-			DealType string
-		}{
-			MinerDeal: deal,
-			DealType:  "storage",
-		}
-		return runDealFilter(ctx, cmd, d)
+		res, err := RunStorageDealFilter(ctx, cmd, deal)
+		return res.Accept, res.Reason, err
 	}
 }
 
+// RunStorageDealFilter runs the storage deal filter command against the deal
+// the same way CliStorageDealFilter does
+func RunStorageDealFilter(ctx context.Context, cmd string, deal storagemarket.MinerDeal) (FilterResult, error) {
+	d := struct {
+		storagemarket.MinerDeal
+		DealType string
+	}{
+		MinerDeal: deal,
+		DealType:  "storage",
+	}
+	return runDealFilter(ctx, cmd, d)
+}
+
 func CliRetrievalDealFilter(cmd string) dtypes.RetrievalDealFilter {
 	return func(ctx context.Context, deal retrievalmarket.ProviderDealState) (bool, string, error) {
 		d := struct {
@@ -34,29 +51,44 @@ func CliRetrievalDealFilter(cmd string) dtypes.RetrievalDealFilter {
 			ProviderDealState: deal,
 			DealType:          "retrieval",
 		}
-		return runDealFilter(ctx, cmd, d)
+		res, err := runDealFilter(ctx, cmd, d)
+		return res.Accept, res.Reason, err
 	}
 }
 
-func runDealFilter(ctx context.Context, cmd string, deal interface{}) (bool, string, error) {
+// lockedWriter serializes writes from the goroutines copying the stdout and
+// stderr of the filter command
+type lockedWriter struct {
+	lk sync.Mutex
+	w  io.Writer
+}
+
+func (w *lockedWriter) Write(p []byte) (int, error) {
+	w.lk.Lock()
+	defer w.lk.Unlock()
+	return w.w.Write(p)
+}
+
+func runDealFilter(ctx context.Context, cmd string, deal interface{}) (FilterResult, error) {
 	j, err := json.MarshalIndent(deal, "", "  ")
 	if err != nil {
-		return false, "", err
+		return FilterResult{}, err
 	}
 
-	var out bytes.Buffer
+	var out, stderr bytes.Buffer
+	combined := &lockedWriter{w: &out}
 
 	c := exec.Command("sh", "-c", cmd)
 	c.Stdin = bytes.NewReader(j)
-	c.Stdout = &out
-	c.Stderr = &out
+	c.Stdout = combined
+	c.Stderr = io.MultiWriter(combined, &stderr)
 
 	switch err := c.Run().(type) {
 	case nil:
-		return true, "", nil
+		return FilterResult{Accept: true, Stderr: stderr.String()}, nil
 	case *exec.ExitError:
-		return false, out.String(), nil
+		return FilterResult{Reason: out.String(), Stderr: stderr.String()}, nil
 	default:
-		return false, "filter cmd run error", err
+		return FilterResult{Reason: "filter cmd run error", Stderr: stderr.String()}, err
 	}
 }
@@ -0,0 +1,43 @@
+package dealfilter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-fil-markets/storagemarket"
+	"github.com/filecoin-project/go-state-types/abi"
+)
+
+func TestRunStorageDealFilter(t *testing.T) {
+	ctx := context.Background()
+
+	var deal storagemarket.MinerDeal
+	deal.Proposal.PieceSize = abi.PaddedPieceSize(2048)
+
+	// accepts deals, the filter gets the deal as JSON on stdin
+	res, err := RunStorageDealFilter(ctx, `grep -q '"DealType": "storage"' && echo checked >&2`, deal)
+	require.NoError(t, err)
+	require.True(t, res.Accept)
+	require.Equal(t, "checked\n", res.Stderr)
+
+	// rejects deals
+	res, err = RunStorageDealFilter(ctx, `echo "too small"; echo "rejecting" >&2; exit 1`, deal)
+	require.NoError(t, err)
+	require.False(t, res.Accept)
+	require.Contains(t, res.Reason, "too small")
+	require.Contains(t, res.Reason, "rejecting")
+	require.Equal(t, "rejecting\n", res.Stderr)
+
+	// a misconfigured command rejects all deals, stderr says why
+	res, err = RunStorageDealFilter(ctx, "/nonexistent/deal-filter", deal)
+	require.NoError(t, err)
+	require.False(t, res.Accept)
+	require.Contains(t, res.Stderr, "/nonexistent/deal-filter")
+
+	ok, reason, err := CliStorageDealFilter("exit 1")(ctx, deal)
+	require.NoError(t, err)
+	require.False(t, ok)
+	require.Empty(t, reason)
+}
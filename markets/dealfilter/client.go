@@ -0,0 +1,232 @@
+package dealfilter
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ipfs/go-cid"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-fil-markets/storagemarket"
+	"github.com/filecoin-project/go-state-types/abi"
+)
+
+type ClientFilterConfig struct {
+	// when not empty only deals from these clients are accepted
+	Allow []address.Address
+	Deny  []address.Address
+
+	// MaxBytes is the maximum padded size of deals accepted from a single
+	// client within Window, 0 disables the cap
+	MaxBytes uint64
+	Window   time.Duration
+}
+
+// AddressLookupFunc resolves an address to the ID address of its actor
+type AddressLookupFunc func(ctx context.Context, addr address.Address) (address.Address, error)
+
+type clientDeal struct {
+	size    abi.PaddedPieceSize
+	created time.Time
+}
+
+// ClientFilter rejects deals based on the address of the client proposing
+// them, and caps the cumulative size of deals from each client over a rolling
+// window.
+//
+// Client addresses are compared by their ID address, so that a client can't
+// get around the lists or the cap by proposing with another address of the
+// same actor.
+type ClientFilter struct {
+	getConfig func() (ClientFilterConfig, error)
+	lookup    AddressLookupFunc
+	now       func() time.Time
+
+	lk  sync.Mutex
+	ids map[address.Address]address.Address
+	// deals counted against the size cap, by client ID address
+	deals map[address.Address]map[cid.Cid]clientDeal
+	// client ID address of each tracked deal
+	dealClients map[cid.Cid]address.Address
+}
+
+func NewClientFilter(getConfig func() (ClientFilterConfig, error), lookup AddressLookupFunc) *ClientFilter {
+	return &ClientFilter{
+		getConfig:   getConfig,
+		lookup:      lookup,
+		now:         time.Now,
+		ids:         map[address.Address]address.Address{},
+		deals:       map[address.Address]map[cid.Cid]clientDeal{},
+		dealClients: map[cid.Cid]address.Address{},
+	}
+}
+
+// resolve returns the ID address of the actor, ID addresses are cached as they
+// don't change once assigned
+func (f *ClientFilter) resolve(ctx context.Context, addr address.Address) (address.Address, error) {
+	if addr.Protocol() == address.ID {
+		return addr, nil
+	}
+
+	f.lk.Lock()
+	id, ok := f.ids[addr]
+	f.lk.Unlock()
+	if ok {
+		return id, nil
+	}
+
+	id, err := f.lookup(ctx, addr)
+	if err != nil {
+		return address.Undef, err
+	}
+
+	f.lk.Lock()
+	f.ids[addr] = id
+	f.lk.Unlock()
+
+	return id, nil
+}
+
+// contains returns whether the list has an address of the client actor.
+// Addresses of actors which don't exist (yet) can't match any client.
+func (f *ClientFilter) contains(ctx context.Context, list []address.Address, client address.Address) bool {
+	for _, a := range list {
+		id, err := f.resolve(ctx, a)
+		if err != nil {
+			log.Debugw("client filter address not resolved", "address", a, "error", err)
+			continue
+		}
+		if id == client {
+			return true
+		}
+	}
+	return false
+}
+
+// Track updates the deals counted against the size cap from the state of a
+// deal, it's called with all deals on startup and on every provider event
+func (f *ClientFilter) Track(ctx context.Context, deal storagemarket.MinerDeal) {
+	f.lk.Lock()
+	client, ok := f.dealClients[deal.ProposalCid]
+	f.lk.Unlock()
+
+	if !ok {
+		if !countedState(deal.State) {
+			return
+		}
+
+		var err error
+		client, err = f.resolve(ctx, deal.Proposal.Client)
+		if err != nil {
+			log.Warnw("resolving deal client, not counting the deal against the client size cap", "proposal", deal.ProposalCid, "client", deal.Proposal.Client, "error", err)
+			return
+		}
+	}
+
+	f.lk.Lock()
+	defer f.lk.Unlock()
+
+	if !countedState(deal.State) {
+		f.untrack(client, deal.ProposalCid)
+		return
+	}
+
+	f.track(client, deal.ProposalCid, clientDeal{
+		size:    deal.Proposal.PieceSize,
+		created: deal.CreationTime.Time(),
+	})
+}
+
+func (f *ClientFilter) track(client address.Address, proposal cid.Cid, d clientDeal) {
+	cd, ok := f.deals[client]
+	if !ok {
+		cd = map[cid.Cid]clientDeal{}
+		f.deals[client] = cd
+	}
+	cd[proposal] = d
+	f.dealClients[proposal] = client
+}
+
+func (f *ClientFilter) untrack(client address.Address, proposal cid.Cid) {
+	delete(f.dealClients, proposal)
+	delete(f.deals[client], proposal)
+	if len(f.deals[client]) == 0 {
+		delete(f.deals, client)
+	}
+}
+
+// Check returns whether the deal should be considered, accepted deals are
+// counted against the size cap right away so that concurrent proposals can't
+// exceed it
+func (f *ClientFilter) Check(ctx context.Context, deal storagemarket.MinerDeal) (bool, string, error) {
+	cfg, err := f.getConfig()
+	if err != nil {
+		return false, "miner error", err
+	}
+
+	if len(cfg.Deny) == 0 && len(cfg.Allow) == 0 && (cfg.MaxBytes == 0 || cfg.Window <= 0) {
+		return true, "", nil
+	}
+
+	client, err := f.resolve(ctx, deal.Proposal.Client)
+	if err != nil {
+		return false, "miner error", err
+	}
+
+	if f.contains(ctx, cfg.Deny, client) {
+		return false, fmt.Sprintf("miner doesn't accept deals from client %s", deal.Proposal.Client), nil
+	}
+
+	if len(cfg.Allow) > 0 && !f.contains(ctx, cfg.Allow, client) {
+		return false, fmt.Sprintf("miner doesn't accept deals from client %s", deal.Proposal.Client), nil
+	}
+
+	if cfg.MaxBytes == 0 || cfg.Window <= 0 {
+		return true, "", nil
+	}
+
+	f.lk.Lock()
+	defer f.lk.Unlock()
+
+	now := f.now()
+	since := now.Add(-cfg.Window)
+	used := uint64(deal.Proposal.PieceSize)
+	for prop, d := range f.deals[client] {
+		if d.created.Before(since) {
+			// out of the window for good
+			f.untrack(client, prop)
+			continue
+		}
+		if prop == deal.ProposalCid {
+			continue
+		}
+		used += uint64(d.size)
+	}
+
+	if used > cfg.MaxBytes {
+		return false, fmt.Sprintf("client deals would exceed the limit of %d bytes per %s", cfg.MaxBytes, cfg.Window), nil
+	}
+
+	created := deal.CreationTime.Time()
+	if created.IsZero() {
+		created = now
+	}
+	f.track(client, deal.ProposalCid, clientDeal{size: deal.Proposal.PieceSize, created: created})
+
+	return true, "", nil
+}
+
+// countedState returns whether deals in the state count against the size cap
+// of their client, deals which failed or were rejected don't
+func countedState(st storagemarket.StorageDealStatus) bool {
+	switch st {
+	case storagemarket.StorageDealProposalRejected,
+		storagemarket.StorageDealRejecting,
+		storagemarket.StorageDealFailing,
+		storagemarket.StorageDealError:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,125 @@
+package dealfilter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	cbg "github.com/whyrusleeping/cbor-gen"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-fil-markets/storagemarket"
+	"github.com/filecoin-project/go-state-types/abi"
+	tutils "github.com/filecoin-project/specs-actors/v2/support/testing"
+)
+
+func TestClientFilter(t *testing.T) {
+	good, err := address.NewIDAddress(1001)
+	require.NoError(t, err)
+	bad, err := address.NewIDAddress(1002)
+	require.NoError(t, err)
+	other, err := address.NewIDAddress(1003)
+	require.NoError(t, err)
+
+	// robust addresses of the good and bad clients
+	goodKey := tutils.NewSECP256K1Addr(t, "good")
+	badKey := tutils.NewSECP256K1Addr(t, "bad")
+
+	ids := map[address.Address]address.Address{goodKey: good, badKey: bad}
+	var lookups int
+	lookup := func(ctx context.Context, addr address.Address) (address.Address, error) {
+		lookups++
+		id, ok := ids[addr]
+		if !ok {
+			return address.Undef, xerrors.New("actor not found")
+		}
+		return id, nil
+	}
+
+	ctx := context.Background()
+	now := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	cfg := ClientFilterConfig{Deny: []address.Address{bad}}
+
+	f := NewClientFilter(func() (ClientFilterConfig, error) {
+		return cfg, nil
+	}, lookup)
+	f.now = func() time.Time { return now }
+
+	var n int
+	mkDeal := func(client address.Address, size abi.PaddedPieceSize, created time.Time, st storagemarket.StorageDealStatus) storagemarket.MinerDeal {
+		n++
+		var d storagemarket.MinerDeal
+		d.ProposalCid = tutils.MakeCID(string(rune('a'+n)), nil)
+		d.Proposal.Client = client
+		d.Proposal.PieceSize = size
+		d.CreationTime = cbg.CborTime(created)
+		d.State = st
+		return d
+	}
+
+	check := func(deal storagemarket.MinerDeal) bool {
+		ok, reason, err := f.Check(ctx, deal)
+		require.NoError(t, err)
+		if !ok {
+			require.NotEmpty(t, reason)
+		}
+		return ok
+	}
+
+	// denylist
+	require.False(t, check(mkDeal(bad, 1024, now, storagemarket.StorageDealUnknown)))
+	require.False(t, check(mkDeal(badKey, 1024, now, storagemarket.StorageDealUnknown)), "other address of a denied client")
+	require.True(t, check(mkDeal(good, 1024, now, storagemarket.StorageDealUnknown)))
+
+	// allowlist
+	cfg.Allow = []address.Address{goodKey}
+	require.True(t, check(mkDeal(good, 1024, now, storagemarket.StorageDealUnknown)))
+	require.True(t, check(mkDeal(goodKey, 1024, now, storagemarket.StorageDealUnknown)))
+	require.False(t, check(mkDeal(other, 1024, now, storagemarket.StorageDealUnknown)))
+	cfg.Allow = nil
+
+	// resolved addresses are cached
+	require.Equal(t, 2, lookups)
+
+	// cumulative size cap
+	cfg.MaxBytes = 4096
+	cfg.Window = time.Hour
+
+	// deals restored on startup
+	for _, d := range []storagemarket.MinerDeal{
+		mkDeal(good, 1024, now.Add(-10*time.Minute), storagemarket.StorageDealActive),
+		// stored with another address of the same client
+		mkDeal(goodKey, 1024, now.Add(-10*time.Minute), storagemarket.StorageDealActive),
+		// outside the window
+		mkDeal(good, 2048, now.Add(-2*time.Hour), storagemarket.StorageDealActive),
+		// failed deals aren't counted
+		mkDeal(good, 2048, now.Add(-5*time.Minute), storagemarket.StorageDealError),
+		// other clients aren't counted
+		mkDeal(other, 2048, now.Add(-5*time.Minute), storagemarket.StorageDealActive),
+	} {
+		f.Track(ctx, d)
+	}
+
+	require.False(t, check(mkDeal(good, 4096, now, storagemarket.StorageDealUnknown)), "client over the cap")
+	require.True(t, check(mkDeal(other, 2048, now, storagemarket.StorageDealUnknown)), "other clients are not affected")
+
+	// the proposed deal is counted once, even when checked again
+	proposed := mkDeal(goodKey, 2048, now, storagemarket.StorageDealValidating)
+	require.True(t, check(proposed))
+	require.True(t, check(proposed))
+	f.Track(ctx, proposed)
+
+	// accepted deals count right away
+	require.False(t, check(mkDeal(good, 1024, now, storagemarket.StorageDealUnknown)), "client at the cap")
+
+	// once a deal fails it no longer counts
+	proposed.State = storagemarket.StorageDealFailing
+	f.Track(ctx, proposed)
+	require.True(t, check(mkDeal(good, 1024, now, storagemarket.StorageDealUnknown)))
+
+	// once the window moves on the client can make deals again
+	now = now.Add(2 * time.Hour)
+	require.True(t, check(mkDeal(good, 4096, now, storagemarket.StorageDealUnknown)))
+}
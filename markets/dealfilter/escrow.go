@@ -0,0 +1,21 @@
+package dealfilter
+
+import (
+	"fmt"
+
+	"github.com/filecoin-project/go-fil-markets/storagemarket"
+	"github.com/filecoin-project/go-state-types/abi"
+
+	"github.com/filecoin-project/lotus/chain/types"
+)
+
+// ClientEscrow checks that the client has enough funds available in market
+// escrow to cover what it will owe for a deal, which is the total storage
+// fee and the client collateral
+func ClientEscrow(required abi.TokenAmount, bal storagemarket.Balance) (bool, string) {
+	if bal.Available.LessThan(required) {
+		return false, fmt.Sprintf("client has %s available in market escrow, deal requires %s", types.FIL(bal.Available), types.FIL(required))
+	}
+
+	return true, ""
+}
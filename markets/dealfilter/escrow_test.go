@@ -0,0 +1,36 @@
+package dealfilter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-fil-markets/storagemarket"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
+)
+
+func TestClientEscrow(t *testing.T) {
+	required := abi.NewTokenAmount(1000)
+
+	// under-escrowed client, part of the escrow is locked in other deals
+	ok, reason := ClientEscrow(required, storagemarket.Balance{
+		Locked:    abi.NewTokenAmount(5000),
+		Available: abi.NewTokenAmount(999),
+	})
+	require.False(t, ok)
+	require.Contains(t, reason, "market escrow")
+
+	ok, _ = ClientEscrow(required, storagemarket.Balance{
+		Locked:    big.Zero(),
+		Available: big.Zero(),
+	})
+	require.False(t, ok)
+
+	// funded client
+	ok, _ = ClientEscrow(required, storagemarket.Balance{
+		Locked:    big.Zero(),
+		Available: abi.NewTokenAmount(1000),
+	})
+	require.True(t, ok)
+}
@@ -0,0 +1,45 @@
+package dealfilter
+
+import (
+	"fmt"
+
+	"github.com/filecoin-project/go-state-types/abi"
+
+	"github.com/filecoin-project/lotus/extern/storage-sealing/sealiface"
+)
+
+// SectorFill checks whether a piece of the given size would pack well into one
+// of the sectors currently accepting deals. A piece is acceptable when it fits
+// at least one open sector with the fraction of consumed space which is piece
+// data (as opposed to alignment padding) at or above the target ratio, or when
+// it doesn't fit into any open sector, in which case it will get a new sector.
+func SectorFill(size abi.PaddedPieceSize, open []sealiface.OpenSector, target float64) (bool, string) {
+	if target <= 0 {
+		return true, ""
+	}
+
+	piece := size.Unpadded()
+
+	var fits bool
+	var best float64
+	for _, sector := range open {
+		avail := abi.PaddedPieceSize(sector.Size).Unpadded() - sector.Used
+		if piece > avail {
+			continue
+		}
+		fits = true
+
+		// same padding estimate as used when matching pieces to sectors
+		padding := avail % piece
+		fill := float64(piece) / float64(piece+padding)
+		if fill > best {
+			best = fill
+		}
+	}
+
+	if !fits || best >= target {
+		return true, ""
+	}
+
+	return false, fmt.Sprintf("deal would only fill %.2f of the space it takes up in open sectors, target is %.2f", best, target)
+}
@@ -0,0 +1,49 @@
+package dealfilter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-state-types/abi"
+
+	"github.com/filecoin-project/lotus/extern/storage-sealing/sealiface"
+)
+
+func TestSectorFill(t *testing.T) {
+	ssize := abi.SectorSize(32 << 30)
+
+	// 23GiB used, leaving a 9GiB gap
+	open := []sealiface.OpenSector{{
+		Number: 1,
+		Size:   ssize,
+		Used:   abi.PaddedPieceSize(23 << 30).Unpadded(),
+	}}
+
+	t.Run("gap-filling", func(t *testing.T) {
+		ok, reason := SectorFill(8<<30, open, 0.8)
+		require.True(t, ok, reason)
+	})
+
+	t.Run("wasteful", func(t *testing.T) {
+		ok, reason := SectorFill(2<<30, open, 0.8)
+		require.False(t, ok)
+		require.Contains(t, reason, "target is 0.80")
+	})
+
+	t.Run("no-fit", func(t *testing.T) {
+		// doesn't fit into the open sector, will go into a new one
+		ok, reason := SectorFill(16<<30, open, 0.8)
+		require.True(t, ok, reason)
+	})
+
+	t.Run("no-open-sectors", func(t *testing.T) {
+		ok, reason := SectorFill(2<<30, nil, 0.8)
+		require.True(t, ok, reason)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		ok, reason := SectorFill(2<<30, open, 0)
+		require.True(t, ok, reason)
+	})
+}
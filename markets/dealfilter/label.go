@@ -0,0 +1,37 @@
+package dealfilter
+
+import (
+	"fmt"
+	"regexp"
+
+	"golang.org/x/xerrors"
+)
+
+// CompileLabelPatterns compiles the required label patterns from the config
+func CompileLabelPatterns(patterns []string) ([]*regexp.Regexp, error) {
+	out := make([]*regexp.Regexp, len(patterns))
+	for i, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, xerrors.Errorf("compiling required label pattern %q: %w", p, err)
+		}
+		out[i] = re
+	}
+	return out, nil
+}
+
+// Label checks that the label of a deal proposal matches at least one of the
+// required patterns, e.g. an allowed region tag. No patterns accept any label.
+func Label(label string, patterns []*regexp.Regexp) (bool, string) {
+	if len(patterns) == 0 {
+		return true, ""
+	}
+
+	for _, re := range patterns {
+		if re.MatchString(label) {
+			return true, ""
+		}
+	}
+
+	return false, fmt.Sprintf("deal label %q doesn't match any of the patterns required by the miner", label)
+}
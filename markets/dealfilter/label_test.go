@@ -0,0 +1,40 @@
+package dealfilter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLabel(t *testing.T) {
+	patterns, err := CompileLabelPatterns([]string{`(^|;)region=(eu|ch)(;|$)`, `^residency:eu-`})
+	require.NoError(t, err)
+
+	for _, label := range []string{
+		"region=eu",
+		"bafyqaaa;region=ch;tier=hot",
+		"residency:eu-west-1",
+	} {
+		ok, _ := Label(label, patterns)
+		require.True(t, ok, label)
+	}
+
+	for _, label := range []string{
+		"",
+		"region=us",
+		"region=europe",
+		"bafyqaaa",
+		"data residency:eu-west-1",
+	} {
+		ok, reason := Label(label, patterns)
+		require.False(t, ok, label)
+		require.Contains(t, reason, "doesn't match")
+	}
+
+	// no patterns configured
+	ok, _ := Label("anything", nil)
+	require.True(t, ok)
+
+	_, err = CompileLabelPatterns([]string{"region=(eu"})
+	require.Error(t, err)
+}
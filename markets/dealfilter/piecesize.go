@@ -0,0 +1,56 @@
+package dealfilter
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/filecoin-project/go-fil-markets/storagemarket"
+	"github.com/filecoin-project/go-state-types/abi"
+
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/lotus/node/modules/dtypes"
+)
+
+// PieceSize checks that the padded piece size of a deal is within the bounds
+// accepted by the miner. A zero bound isn't checked.
+func PieceSize(size, min, max abi.PaddedPieceSize) (bool, string) {
+	if min != 0 && size < min {
+		return false, fmt.Sprintf("deal piece size %s is below the minimum piece size of %s accepted by the miner",
+			types.SizeStr(types.NewInt(uint64(size))), types.SizeStr(types.NewInt(uint64(min))))
+	}
+
+	if max != 0 && size > max {
+		return false, fmt.Sprintf("deal piece size %s is above the maximum piece size of %s accepted by the miner",
+			types.SizeStr(types.NewInt(uint64(size))), types.SizeStr(types.NewInt(uint64(max))))
+	}
+
+	return true, ""
+}
+
+// PieceSizeStorageDealFilter rejects deals with a padded piece size outside of
+// the bounds, see PieceSize
+func PieceSizeStorageDealFilter(min, max abi.PaddedPieceSize) dtypes.StorageDealFilter {
+	return func(ctx context.Context, deal storagemarket.MinerDeal) (bool, string, error) {
+		ok, reason := PieceSize(deal.Proposal.PieceSize, min, max)
+		return ok, reason, nil
+	}
+}
+
+// ComposeStorageDealFilters runs the filters in order, a deal is accepted when
+// all of them accept it. The first rejection or error is returned without
+// running the remaining filters. Nil filters are skipped.
+func ComposeStorageDealFilters(filters ...dtypes.StorageDealFilter) dtypes.StorageDealFilter {
+	return func(ctx context.Context, deal storagemarket.MinerDeal) (bool, string, error) {
+		for _, f := range filters {
+			if f == nil {
+				continue
+			}
+
+			if ok, reason, err := f(ctx, deal); !ok || err != nil {
+				return ok, reason, err
+			}
+		}
+
+		return true, "", nil
+	}
+}
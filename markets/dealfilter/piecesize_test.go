@@ -0,0 +1,69 @@
+package dealfilter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-fil-markets/storagemarket"
+	"github.com/filecoin-project/go-state-types/abi"
+)
+
+func TestPieceSize(t *testing.T) {
+	min, max := abi.PaddedPieceSize(1<<20), abi.PaddedPieceSize(1<<30)
+
+	for _, size := range []abi.PaddedPieceSize{1 << 20, 1 << 25, 1 << 30} {
+		ok, _ := PieceSize(size, min, max)
+		require.True(t, ok, size)
+	}
+
+	ok, reason := PieceSize(512<<10, min, max)
+	require.False(t, ok)
+	require.Equal(t, "deal piece size 512 KiB is below the minimum piece size of 1 MiB accepted by the miner", reason)
+
+	ok, reason = PieceSize(2<<30, min, max)
+	require.False(t, ok)
+	require.Equal(t, "deal piece size 2 GiB is above the maximum piece size of 1 GiB accepted by the miner", reason)
+
+	// zero bounds aren't checked
+	ok, _ = PieceSize(128, 0, 0)
+	require.True(t, ok)
+	ok, _ = PieceSize(32<<30, min, 0)
+	require.True(t, ok)
+}
+
+func TestComposeStorageDealFilters(t *testing.T) {
+	var ran []string
+	filter := func(name string, accept bool) func(context.Context, storagemarket.MinerDeal) (bool, string, error) {
+		return func(context.Context, storagemarket.MinerDeal) (bool, string, error) {
+			ran = append(ran, name)
+			if !accept {
+				return false, name + " rejected", nil
+			}
+			return true, "", nil
+		}
+	}
+
+	var deal storagemarket.MinerDeal
+	deal.Proposal.PieceSize = 512 << 10
+
+	ok, _, err := ComposeStorageDealFilters(filter("a", true), nil, filter("b", true))(context.Background(), deal)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, []string{"a", "b"}, ran)
+
+	// the first rejection wins, later filters aren't run
+	ran = nil
+	ok, reason, err := ComposeStorageDealFilters(PieceSizeStorageDealFilter(1<<20, 0), filter("a", true))(context.Background(), deal)
+	require.NoError(t, err)
+	require.False(t, ok)
+	require.Contains(t, reason, "below the minimum piece size")
+	require.Empty(t, ran)
+
+	ok, reason, err = ComposeStorageDealFilters(filter("a", true), filter("b", false), filter("c", false))(context.Background(), deal)
+	require.NoError(t, err)
+	require.False(t, ok)
+	require.Equal(t, "b rejected", reason)
+	require.Equal(t, []string{"a", "b"}, ran)
+}
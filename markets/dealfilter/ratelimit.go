@@ -0,0 +1,118 @@
+package dealfilter
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/filecoin-project/go-address"
+
+	"github.com/filecoin-project/lotus/node/config"
+)
+
+// ClientLimit is the current usage of the deal rate limit of a client
+type ClientLimit struct {
+	Client   address.Address
+	Deals    int
+	MaxDeals int
+	ResetsAt time.Time
+}
+
+type clientBucket struct {
+	start time.Time
+	deals int
+}
+
+// ClientRateLimiter limits how many deals each client can get accepted within
+// a time window
+type ClientRateLimiter struct {
+	getConfig func() (config.ClientRateLimitConfig, error)
+	now       func() time.Time
+
+	lk      sync.Mutex
+	buckets map[address.Address]*clientBucket
+}
+
+func NewClientRateLimiter(getConfig func() (config.ClientRateLimitConfig, error)) *ClientRateLimiter {
+	return &ClientRateLimiter{
+		getConfig: getConfig,
+		now:       time.Now,
+		buckets:   map[address.Address]*clientBucket{},
+	}
+}
+
+// Take counts a deal from the client against its limit, returns false if the
+// client has reached the limit
+func (l *ClientRateLimiter) Take(client address.Address) (bool, string, error) {
+	cfg, err := l.getConfig()
+	if err != nil {
+		return false, "miner error", err
+	}
+
+	window := time.Duration(cfg.Window)
+	if cfg.MaxDeals <= 0 || window <= 0 {
+		return true, "", nil
+	}
+
+	l.lk.Lock()
+	defer l.lk.Unlock()
+
+	now := l.now()
+	b, ok := l.buckets[client]
+	if !ok || now.Sub(b.start) >= window {
+		b = &clientBucket{start: now}
+		l.buckets[client] = b
+	}
+
+	if b.deals >= cfg.MaxDeals {
+		return false, fmt.Sprintf("client reached the limit of %d deals per %s, try again after %s", cfg.MaxDeals, window, b.start.Add(window).Format(time.RFC3339)), nil
+	}
+
+	b.deals++
+	return true, "", nil
+}
+
+// Limits returns the limit usage of all clients which had deals accepted in
+// their current window
+func (l *ClientRateLimiter) Limits() ([]ClientLimit, error) {
+	cfg, err := l.getConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	window := time.Duration(cfg.Window)
+
+	l.lk.Lock()
+	defer l.lk.Unlock()
+
+	now := l.now()
+	out := make([]ClientLimit, 0, len(l.buckets))
+	for client, b := range l.buckets {
+		if now.Sub(b.start) >= window {
+			delete(l.buckets, client)
+			continue
+		}
+
+		out = append(out, ClientLimit{
+			Client:   client,
+			Deals:    b.deals,
+			MaxDeals: cfg.MaxDeals,
+			ResetsAt: b.start.Add(window),
+		})
+	}
+
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Client.String() < out[j].Client.String()
+	})
+
+	return out, nil
+}
+
+// Reset clears the limit usage of the client
+func (l *ClientRateLimiter) Reset(client address.Address) {
+	l.lk.Lock()
+	defer l.lk.Unlock()
+
+	delete(l.buckets, client)
+}
@@ -0,0 +1,56 @@
+package dealfilter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-address"
+
+	"github.com/filecoin-project/lotus/node/config"
+)
+
+func TestClientRateLimiter(t *testing.T) {
+	noisy, err := address.NewIDAddress(1001)
+	require.NoError(t, err)
+	quiet, err := address.NewIDAddress(1002)
+	require.NoError(t, err)
+
+	now := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	l := NewClientRateLimiter(func() (config.ClientRateLimitConfig, error) {
+		return config.ClientRateLimitConfig{MaxDeals: 2, Window: config.Duration(time.Hour)}, nil
+	})
+	l.now = func() time.Time { return now }
+
+	take := func(client address.Address) bool {
+		ok, reason, err := l.Take(client)
+		require.NoError(t, err)
+		if !ok {
+			require.NotEmpty(t, reason)
+		}
+		return ok
+	}
+
+	require.True(t, take(noisy))
+	require.True(t, take(noisy))
+	require.False(t, take(noisy), "client over the limit")
+	require.True(t, take(quiet), "other clients are not affected")
+
+	limits, err := l.Limits()
+	require.NoError(t, err)
+	require.Equal(t, []ClientLimit{
+		{Client: noisy, Deals: 2, MaxDeals: 2, ResetsAt: now.Add(time.Hour)},
+		{Client: quiet, Deals: 1, MaxDeals: 2, ResetsAt: now.Add(time.Hour)},
+	}, limits)
+
+	l.Reset(noisy)
+	require.True(t, take(noisy), "deals accepted again after reset")
+
+	// the window expires
+	now = now.Add(time.Hour)
+	limits, err = l.Limits()
+	require.NoError(t, err)
+	require.Empty(t, limits)
+	require.True(t, take(quiet))
+}
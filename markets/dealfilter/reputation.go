@@ -0,0 +1,134 @@
+package dealfilter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-address"
+)
+
+type ReputationConfig struct {
+	Endpoint         string
+	MinScore         float64
+	CacheTTL         time.Duration
+	TrustedDealCount int
+}
+
+// ClientDealCountFunc returns the number of deals from the client stored in
+// local sectors
+type ClientDealCountFunc func(ctx context.Context, client address.Address) (int, error)
+
+type cachedScore struct {
+	score   float64
+	fetched time.Time
+}
+
+// ReputationFilter rejects deals from clients with a low score in an external
+// reputation service. Clients with enough deals stored locally are trusted
+// without asking the service.
+type ReputationFilter struct {
+	getConfig  func() (ReputationConfig, error)
+	localDeals ClientDealCountFunc
+	client     *http.Client
+
+	lk    sync.Mutex
+	cache map[address.Address]cachedScore
+}
+
+func NewReputationFilter(getConfig func() (ReputationConfig, error), localDeals ClientDealCountFunc) *ReputationFilter {
+	return &ReputationFilter{
+		getConfig:  getConfig,
+		localDeals: localDeals,
+		client:     &http.Client{Timeout: 30 * time.Second},
+		cache:      map[address.Address]cachedScore{},
+	}
+}
+
+// Check returns whether deals from the client should be considered
+func (f *ReputationFilter) Check(ctx context.Context, client address.Address) (bool, string, error) {
+	cfg, err := f.getConfig()
+	if err != nil {
+		return false, "miner error", err
+	}
+
+	if cfg.Endpoint == "" {
+		return true, "", nil
+	}
+
+	if cfg.TrustedDealCount > 0 {
+		n, err := f.localDeals(ctx, client)
+		if err != nil {
+			return false, "miner error", xerrors.Errorf("getting local deal count: %w", err)
+		}
+		if n >= cfg.TrustedDealCount {
+			return true, "", nil
+		}
+	}
+
+	score, err := f.score(ctx, cfg, client)
+	if err != nil {
+		return false, "failed to get client reputation", err
+	}
+
+	if score < cfg.MinScore {
+		return false, fmt.Sprintf("client reputation score %f is below the minimum %f", score, cfg.MinScore), nil
+	}
+
+	return true, "", nil
+}
+
+func (f *ReputationFilter) score(ctx context.Context, cfg ReputationConfig, client address.Address) (float64, error) {
+	f.lk.Lock()
+	cached, ok := f.cache[client]
+	f.lk.Unlock()
+
+	if ok && time.Since(cached.fetched) < cfg.CacheTTL {
+		return cached.score, nil
+	}
+
+	u, err := url.Parse(cfg.Endpoint)
+	if err != nil {
+		return 0, xerrors.Errorf("parsing reputation endpoint: %w", err)
+	}
+	q := u.Query()
+	q.Set("client", client.String())
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return 0, xerrors.Errorf("creating request: %w", err)
+	}
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return 0, xerrors.Errorf("querying reputation endpoint: %w", err)
+	}
+	defer resp.Body.Close() //nolint:errcheck
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, xerrors.Errorf("reputation endpoint returned status %d", resp.StatusCode)
+	}
+
+	var out struct {
+		Score *float64
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return 0, xerrors.Errorf("decoding reputation response: %w", err)
+	}
+	if out.Score == nil {
+		return 0, xerrors.Errorf("reputation response is missing the score")
+	}
+
+	f.lk.Lock()
+	f.cache[client] = cachedScore{score: *out.Score, fetched: time.Now()}
+	f.lk.Unlock()
+
+	return *out.Score, nil
+}
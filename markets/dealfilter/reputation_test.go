@@ -0,0 +1,87 @@
+package dealfilter
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-address"
+)
+
+func TestReputationFilter(t *testing.T) {
+	good, _ := address.NewIDAddress(1000)
+	bad, _ := address.NewIDAddress(1001)
+	local, _ := address.NewIDAddress(1002)
+
+	scores := map[string]float64{
+		good.String():  0.9,
+		bad.String():   0.2,
+		local.String(): 0.1,
+	}
+
+	var queries int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&queries, 1)
+		score, ok := scores[r.URL.Query().Get("client")]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]float64{"Score": score})
+	}))
+	defer srv.Close()
+
+	cfg := ReputationConfig{
+		Endpoint:         srv.URL,
+		MinScore:         0.5,
+		CacheTTL:         time.Hour,
+		TrustedDealCount: 3,
+	}
+	localDeals := map[address.Address]int{local: 5}
+
+	f := NewReputationFilter(func() (ReputationConfig, error) {
+		return cfg, nil
+	}, func(ctx context.Context, client address.Address) (int, error) {
+		return localDeals[client], nil
+	})
+
+	ctx := context.Background()
+
+	ok, _, err := f.Check(ctx, good)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	ok, reason, err := f.Check(ctx, bad)
+	require.NoError(t, err)
+	require.False(t, ok)
+	require.Contains(t, reason, "below the minimum")
+
+	// clients with enough local deals don't need a good score
+	ok, _, err = f.Check(ctx, local)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.EqualValues(t, 2, atomic.LoadInt32(&queries))
+
+	// scores are cached
+	ok, _, err = f.Check(ctx, bad)
+	require.NoError(t, err)
+	require.False(t, ok)
+	require.EqualValues(t, 2, atomic.LoadInt32(&queries))
+
+	unknown, _ := address.NewIDAddress(1003)
+	ok, _, err = f.Check(ctx, unknown)
+	require.Error(t, err)
+	require.False(t, ok)
+
+	// raising the threshold applies to cached scores too
+	cfg.MinScore = 0.95
+	ok, _, err = f.Check(ctx, good)
+	require.NoError(t, err)
+	require.False(t, ok)
+}
@@ -0,0 +1,96 @@
+package dealfilter
+
+import (
+	"context"
+	"fmt"
+
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-fil-markets/piecestore"
+	"github.com/filecoin-project/go-fil-markets/retrievalmarket"
+	"github.com/filecoin-project/go-state-types/abi"
+)
+
+type RetrievalFilterConfig struct {
+	// retrievals offering a lower price per byte are rejected
+	MinPricePerByte abi.TokenAmount
+	// reject retrievals of pieces which aren't in an unsealed sector file,
+	// and would require unsealing a sector to serve
+	RequireUnsealed bool
+}
+
+// PieceLookupFunc returns the piece a retrieval deal is for
+type PieceLookupFunc func(deal retrievalmarket.ProviderDealState) (piecestore.PieceInfo, error)
+
+// IsUnsealedFunc returns whether the range of a sector is unsealed
+type IsUnsealedFunc func(ctx context.Context, sector abi.SectorNumber, offset, length abi.UnpaddedPieceSize) (bool, error)
+
+// RetrievalFilter rejects retrievals offering less than a minimum price per
+// byte and, optionally, retrievals which would require unsealing a sector
+type RetrievalFilter struct {
+	getConfig  func() (RetrievalFilterConfig, error)
+	lookup     PieceLookupFunc
+	isUnsealed IsUnsealedFunc
+}
+
+func NewRetrievalFilter(getConfig func() (RetrievalFilterConfig, error), lookup PieceLookupFunc, isUnsealed IsUnsealedFunc) *RetrievalFilter {
+	return &RetrievalFilter{
+		getConfig:  getConfig,
+		lookup:     lookup,
+		isUnsealed: isUnsealed,
+	}
+}
+
+// Check returns whether the retrieval deal should be accepted
+func (f *RetrievalFilter) Check(ctx context.Context, deal retrievalmarket.ProviderDealState) (bool, string, error) {
+	cfg, err := f.getConfig()
+	if err != nil {
+		return false, "miner error", err
+	}
+
+	if !cfg.MinPricePerByte.Nil() && deal.PricePerByte.LessThan(cfg.MinPricePerByte) {
+		return false, fmt.Sprintf("price per byte %s is below the minimum of %s", deal.PricePerByte, cfg.MinPricePerByte), nil
+	}
+
+	if !cfg.RequireUnsealed {
+		return true, "", nil
+	}
+
+	unsealed, err := f.hasUnsealed(ctx, deal)
+	if err != nil {
+		return false, "miner error", err
+	}
+	if !unsealed {
+		return false, "miner doesn't unseal sectors for retrievals, and has no unsealed copy of the piece", nil
+	}
+
+	return true, "", nil
+}
+
+// hasUnsealed returns whether any of the sectors containing the piece has it
+// unsealed
+func (f *RetrievalFilter) hasUnsealed(ctx context.Context, deal retrievalmarket.ProviderDealState) (bool, error) {
+	var pi piecestore.PieceInfo
+	if deal.PieceInfo != nil {
+		pi = *deal.PieceInfo
+	} else {
+		var err error
+		pi, err = f.lookup(deal)
+		if err != nil {
+			return false, xerrors.Errorf("looking up piece: %w", err)
+		}
+	}
+
+	for _, di := range pi.Deals {
+		ok, err := f.isUnsealed(ctx, di.SectorID, di.Offset.Unpadded(), di.Length.Unpadded())
+		if err != nil {
+			log.Warnw("checking if piece is unsealed", "piece", pi.PieceCID, "sector", di.SectorID, "error", err)
+			continue
+		}
+		if ok {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
@@ -0,0 +1,66 @@
+package dealfilter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-fil-markets/piecestore"
+	"github.com/filecoin-project/go-fil-markets/retrievalmarket"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
+)
+
+func TestRetrievalFilter(t *testing.T) {
+	cfg := RetrievalFilterConfig{MinPricePerByte: big.NewInt(10)}
+
+	pi := piecestore.PieceInfo{
+		Deals: []piecestore.DealInfo{
+			{SectorID: 1, Offset: 0, Length: 2048},
+			{SectorID: 2, Offset: 2048, Length: 2048},
+		},
+	}
+	unsealed := map[abi.SectorNumber]bool{}
+
+	f := NewRetrievalFilter(func() (RetrievalFilterConfig, error) {
+		return cfg, nil
+	}, func(deal retrievalmarket.ProviderDealState) (piecestore.PieceInfo, error) {
+		return pi, nil
+	}, func(ctx context.Context, sector abi.SectorNumber, offset, length abi.UnpaddedPieceSize) (bool, error) {
+		if sector == 1 {
+			return false, xerrors.New("sector not found")
+		}
+		return unsealed[sector], nil
+	})
+
+	check := func(price int64) bool {
+		var deal retrievalmarket.ProviderDealState
+		deal.PricePerByte = big.NewInt(price)
+
+		ok, reason, err := f.Check(context.Background(), deal)
+		require.NoError(t, err)
+		if !ok {
+			require.NotEmpty(t, reason)
+		}
+		return ok
+	}
+
+	// price floor
+	require.False(t, check(9))
+	require.True(t, check(10))
+	require.True(t, check(11))
+
+	// no unsealed copy, the error checking sector 1 is skipped
+	cfg.RequireUnsealed = true
+	require.False(t, check(10))
+
+	unsealed[2] = true
+	require.True(t, check(10))
+	require.False(t, check(9))
+
+	// no price floor
+	cfg.MinPricePerByte = big.Zero()
+	require.True(t, check(0))
+}
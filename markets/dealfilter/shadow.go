@@ -0,0 +1,75 @@
+package dealfilter
+
+import (
+	"context"
+
+	"github.com/ipfs/go-cid"
+	logging "github.com/ipfs/go-log/v2"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-fil-markets/storagemarket"
+
+	"github.com/filecoin-project/lotus/node/modules/dtypes"
+)
+
+var log = logging.Logger("dealfilter")
+
+// ShadowDivergence describes a deal the shadow filter decided differently on
+// than the active filter
+type ShadowDivergence struct {
+	ProposalCid cid.Cid
+	PieceCid    cid.Cid
+	Client      address.Address
+
+	ActiveAccept bool
+	ActiveReason string
+	ShadowAccept bool
+	ShadowReason string
+}
+
+// ShadowStorageDealFilter runs the shadow filter command against every deal
+// the active filter decides on, for observation only. The decision of the
+// active filter is always returned; when the shadow command decides
+// differently the divergence is logged, and passed to onDivergence if it's
+// set. A nil active filter accepts all deals.
+func ShadowStorageDealFilter(active dtypes.StorageDealFilter, shadowCmd string, onDivergence func(ShadowDivergence)) dtypes.StorageDealFilter {
+	return func(ctx context.Context, deal storagemarket.MinerDeal) (bool, string, error) {
+		accept, reason := true, ""
+		if active != nil {
+			var err error
+			accept, reason, err = active(ctx, deal)
+			if err != nil {
+				return accept, reason, err
+			}
+		}
+
+		res, err := RunStorageDealFilter(ctx, shadowCmd, deal)
+		if err != nil {
+			log.Warnw("running shadow deal filter", "proposal", deal.ProposalCid, "error", err)
+			return accept, reason, nil
+		}
+
+		if res.Accept != accept {
+			div := ShadowDivergence{
+				ProposalCid:  deal.ProposalCid,
+				PieceCid:     deal.Proposal.PieceCID,
+				Client:       deal.Proposal.Client,
+				ActiveAccept: accept,
+				ActiveReason: reason,
+				ShadowAccept: res.Accept,
+				ShadowReason: res.Reason,
+			}
+
+			log.Warnw("shadow deal filter decision diverges from active filter",
+				"proposal", div.ProposalCid, "piece_cid", div.PieceCid, "client", div.Client,
+				"active_accept", div.ActiveAccept, "active_reason", div.ActiveReason,
+				"shadow_accept", div.ShadowAccept, "shadow_reason", div.ShadowReason)
+
+			if onDivergence != nil {
+				onDivergence(div)
+			}
+		}
+
+		return accept, reason, nil
+	}
+}
@@ -0,0 +1,55 @@
+package dealfilter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-fil-markets/storagemarket"
+	tutils "github.com/filecoin-project/specs-actors/v2/support/testing"
+)
+
+func TestShadowStorageDealFilter(t *testing.T) {
+	ctx := context.Background()
+
+	var deal storagemarket.MinerDeal
+	deal.ProposalCid = tutils.MakeCID("deal", nil)
+	deal.Proposal.PieceCID = tutils.MakeCID("piece", nil)
+	deal.Proposal.Client = tutils.NewIDAddr(t, 1000)
+
+	var divs []ShadowDivergence
+	record := func(d ShadowDivergence) {
+		divs = append(divs, d)
+	}
+
+	// active accepts, shadow rejects: the deal is accepted, the divergence recorded
+	f := ShadowStorageDealFilter(CliStorageDealFilter("exit 0"), `echo "too risky"; exit 1`, record)
+	ok, reason, err := f(ctx, deal)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Empty(t, reason)
+
+	require.Len(t, divs, 1)
+	require.Equal(t, deal.ProposalCid, divs[0].ProposalCid)
+	require.Equal(t, deal.Proposal.PieceCID, divs[0].PieceCid)
+	require.Equal(t, deal.Proposal.Client, divs[0].Client)
+	require.True(t, divs[0].ActiveAccept)
+	require.False(t, divs[0].ShadowAccept)
+	require.Contains(t, divs[0].ShadowReason, "too risky")
+
+	// no active filter accepts all deals
+	divs = nil
+	ok, _, err = ShadowStorageDealFilter(nil, "exit 1", record)(ctx, deal)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Len(t, divs, 1)
+
+	// matching decisions aren't divergences
+	divs = nil
+	ok, reason, err = ShadowStorageDealFilter(CliStorageDealFilter(`echo "no"; exit 1`), "exit 1", record)(ctx, deal)
+	require.NoError(t, err)
+	require.False(t, ok)
+	require.Contains(t, reason, "no")
+	require.Empty(t, divs)
+}
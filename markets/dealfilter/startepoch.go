@@ -0,0 +1,69 @@
+package dealfilter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/filecoin-project/go-state-types/abi"
+)
+
+const counterOfferMarker = "counter-offer start epoch: "
+
+// StartEpochCounterOffer is a rejection of a deal proposal which would start
+// before the deal can be sealed, with a start epoch the provider would accept
+// the deal with
+type StartEpochCounterOffer struct {
+	Reason     string
+	StartEpoch abi.ChainEpoch
+}
+
+// CounterOfferStartEpoch returns the start epoch to counter-offer for a deal
+// proposed at height ht which can't be sealed before earliest. The
+// counter-offer is slack epochs after earliest to leave the client time to
+// re-propose the deal, capped at maxDelay epochs after ht. Returns false when
+// the deal can't be sealed within maxDelay.
+func CounterOfferStartEpoch(ht, earliest, slack, maxDelay abi.ChainEpoch) (abi.ChainEpoch, bool) {
+	maxStart := ht + maxDelay
+	if earliest > maxStart {
+		return 0, false
+	}
+
+	start := earliest + slack
+	if start > maxStart {
+		start = maxStart
+	}
+	return start, true
+}
+
+// Message returns the rejection message carrying the counter-offer. The
+// message is all the client gets back in the proposal response, it can be
+// decoded with ParseStartEpochCounterOffer.
+func (o StartEpochCounterOffer) Message() string {
+	return fmt.Sprintf("%s; %s%d", o.Reason, counterOfferMarker, o.StartEpoch)
+}
+
+// ParseStartEpochCounterOffer returns the counter-offer in the rejection
+// message of a deal, false if there is none
+func ParseStartEpochCounterOffer(msg string) (StartEpochCounterOffer, bool) {
+	idx := strings.LastIndex(msg, counterOfferMarker)
+	if idx < 0 {
+		return StartEpochCounterOffer{}, false
+	}
+
+	s := msg[idx+len(counterOfferMarker):]
+	end := 0
+	for end < len(s) && s[end] >= '0' && s[end] <= '9' {
+		end++
+	}
+
+	start, err := strconv.ParseInt(s[:end], 10, 64)
+	if err != nil {
+		return StartEpochCounterOffer{}, false
+	}
+
+	return StartEpochCounterOffer{
+		Reason:     strings.TrimSuffix(msg[:idx], "; "),
+		StartEpoch: abi.ChainEpoch(start),
+	}, true
+}
@@ -0,0 +1,41 @@
+package dealfilter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-state-types/abi"
+)
+
+func TestStartEpochCounterOffer(t *testing.T) {
+	msg := StartEpochCounterOffer{Reason: "cannot seal a sector before 100", StartEpoch: 2500}.Message()
+
+	// the client sees the reason wrapped in the rejection message
+	offer, ok := ParseStartEpochCounterOffer("deal rejected: " + msg + "\n")
+	require.True(t, ok)
+	require.Equal(t, abi.ChainEpoch(2500), offer.StartEpoch)
+	require.Equal(t, "deal rejected: cannot seal a sector before 100", offer.Reason)
+
+	_, ok = ParseStartEpochCounterOffer("cannot seal a sector before 100")
+	require.False(t, ok)
+
+	_, ok = ParseStartEpochCounterOffer(counterOfferMarker)
+	require.False(t, ok)
+}
+
+func TestCounterOfferStartEpoch(t *testing.T) {
+	// slack after the earliest sealing epoch
+	start, ok := CounterOfferStartEpoch(100, 200, 50, 1000)
+	require.True(t, ok)
+	require.Equal(t, abi.ChainEpoch(250), start)
+
+	// capped at the max start delay from the current height
+	start, ok = CounterOfferStartEpoch(100, 200, 5000, 1000)
+	require.True(t, ok)
+	require.Equal(t, abi.ChainEpoch(1100), start)
+
+	// sealing takes longer than the max start delay
+	_, ok = CounterOfferStartEpoch(100, 1200, 50, 1000)
+	require.False(t, ok)
+}
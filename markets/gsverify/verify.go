@@ -0,0 +1,70 @@
+// Package gsverify checks blocks received over graphsync as they arrive, so
+// that inbound transfers of malformed data are aborted right away, instead
+// of after the whole piece was received and failed commP verification.
+//
+// Graphsync itself already makes sure that block data matches the CID it was
+// requested by, the checks here additionally make sure that blocks look like
+// they belong to a deal payload DAG.
+package gsverify
+
+import (
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-graphsync"
+	logging "github.com/ipfs/go-log/v2"
+	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
+	"github.com/libp2p/go-libp2p-core/peer"
+	mh "github.com/multiformats/go-multihash"
+	"golang.org/x/xerrors"
+)
+
+var log = logging.Logger("gsverify")
+
+// MaxBlockSize is the largest block accepted in a transfer. It matches the
+// largest block bitswap will transfer, which is also what common DAG builders
+// limit themselves to.
+const MaxBlockSize = 2 << 20
+
+// codecs deal payload DAGs can be made of
+var allowedCodecs = map[uint64]struct{}{
+	cid.Raw:         {},
+	cid.DagProtobuf: {},
+	cid.DagCBOR:     {},
+}
+
+var allowedHashes = map[uint64]struct{}{
+	mh.IDENTITY:         {},
+	mh.SHA2_256:         {},
+	mh.BLAKE2B_MIN + 31: {},
+}
+
+// VerifyBlock checks that a received block is something that can be part of
+// a deal payload DAG
+func VerifyBlock(blk graphsync.BlockData) error {
+	lnk, ok := blk.Link().(cidlink.Link)
+	if !ok {
+		return xerrors.Errorf("unexpected link type %T", blk.Link())
+	}
+
+	pref := lnk.Cid.Prefix()
+	if _, ok := allowedCodecs[pref.Codec]; !ok {
+		return xerrors.Errorf("block %s: unexpected codec 0x%x", lnk.Cid, pref.Codec)
+	}
+	if _, ok := allowedHashes[pref.MhType]; !ok {
+		return xerrors.Errorf("block %s: unexpected multihash type 0x%x", lnk.Cid, pref.MhType)
+	}
+
+	if blk.BlockSize() > MaxBlockSize {
+		return xerrors.Errorf("block %s: size %d exceeds max block size %d", lnk.Cid, blk.BlockSize(), MaxBlockSize)
+	}
+
+	return nil
+}
+
+// IncomingBlockHook is a graphsync hook which terminates the request as soon
+// as a block fails verification
+func IncomingBlockHook(p peer.ID, resp graphsync.ResponseData, blk graphsync.BlockData, actions graphsync.IncomingBlockHookActions) {
+	if err := VerifyBlock(blk); err != nil {
+		log.Warnw("aborting transfer, received block failed verification", "peer", p, "request", resp.RequestID(), "error", err)
+		actions.TerminateWithError(xerrors.Errorf("received block failed verification: %w", err))
+	}
+}
@@ -0,0 +1,79 @@
+package gsverify
+
+import (
+	"testing"
+
+	blocks "github.com/ipfs/go-block-format"
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-graphsync"
+	ipld "github.com/ipld/go-ipld-prime"
+	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
+	"github.com/stretchr/testify/require"
+)
+
+type testBlock struct {
+	c    cid.Cid
+	size uint64
+}
+
+func (b testBlock) Link() ipld.Link         { return cidlink.Link{Cid: b.c} }
+func (b testBlock) BlockSize() uint64       { return b.size }
+func (b testBlock) BlockSizeOnWire() uint64 { return b.size }
+
+type testResponse struct{}
+
+func (testResponse) RequestID() graphsync.RequestID { return 1 }
+func (testResponse) Status() graphsync.ResponseStatusCode {
+	return graphsync.PartialResponse
+}
+func (testResponse) Extension(graphsync.ExtensionName) ([]byte, bool) { return nil, false }
+
+type testActions struct {
+	err error
+}
+
+func (a *testActions) TerminateWithError(err error)                           { a.err = err }
+func (a *testActions) UpdateRequestWithExtensions(...graphsync.ExtensionData) {}
+
+func rawBlock(data string) testBlock {
+	b := blocks.NewBlock([]byte(data))
+	return testBlock{c: b.Cid(), size: uint64(len(data))}
+}
+
+func TestVerifyAbortsMidTransfer(t *testing.T) {
+	corrupt := rawBlock("corrupt")
+	corrupt.c = cid.NewCidV1(cid.EthBlock, corrupt.c.Hash())
+
+	transfer := []testBlock{
+		rawBlock("block 0"),
+		rawBlock("block 1"),
+		corrupt,
+		rawBlock("block 3"),
+		rawBlock("block 4"),
+	}
+
+	actions := &testActions{}
+	received := 0
+	for _, blk := range transfer {
+		IncomingBlockHook("", testResponse{}, blk, actions)
+		if actions.err != nil {
+			break
+		}
+		received++
+	}
+
+	require.Error(t, actions.err)
+	require.Equal(t, 2, received, "transfer should be aborted at the corrupt block")
+}
+
+func TestVerifyBlock(t *testing.T) {
+	require.NoError(t, VerifyBlock(rawBlock("good")))
+
+	big := rawBlock("big")
+	big.size = MaxBlockSize + 1
+	require.Error(t, VerifyBlock(big))
+
+	badHash := rawBlock("hash")
+	badHash.c = cid.NewCidV1(cid.Raw, []byte{0x13, 0x01, 0x00}) // sha2-512, 1 byte digest
+	require.Error(t, VerifyBlock(badHash))
+}
@@ -0,0 +1,184 @@
+package pricing
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-fil-markets/piecestore"
+	"github.com/filecoin-project/go-fil-markets/retrievalmarket"
+	"github.com/filecoin-project/go-state-types/big"
+
+	"github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/node/modules/dtypes"
+)
+
+var tiersKey = datastore.NewKey("/retrieval-ask-tiers")
+
+// TiersStore keeps the retrieval ask tiers of the provider
+type TiersStore struct {
+	lk    sync.Mutex
+	ds    datastore.Datastore
+	tiers *api.RetrievalAskTiers
+
+	// why the tiers aren't applied, if they aren't
+	unused string
+}
+
+func NewTiersStore(ds datastore.Datastore) *TiersStore {
+	return &TiersStore{ds: ds}
+}
+
+// NewUnusedTiersStore returns a store refusing to set tiers, for nodes whose
+// retrieval pricing doesn't apply them
+func NewUnusedTiersStore(ds datastore.Datastore, reason string) *TiersStore {
+	return &TiersStore{ds: ds, unused: reason}
+}
+
+// Get returns the retrieval ask tiers, the zero tiers if none were set
+func (s *TiersStore) Get() (api.RetrievalAskTiers, error) {
+	s.lk.Lock()
+	defer s.lk.Unlock()
+
+	if s.tiers != nil {
+		return *s.tiers, nil
+	}
+
+	tiers := api.RetrievalAskTiers{SealedSurcharge: big.Zero()}
+	b, err := s.ds.Get(tiersKey)
+	switch {
+	case xerrors.Is(err, datastore.ErrNotFound):
+	case err != nil:
+		return api.RetrievalAskTiers{}, xerrors.Errorf("getting retrieval ask tiers: %w", err)
+	default:
+		if err := json.Unmarshal(b, &tiers); err != nil {
+			return api.RetrievalAskTiers{}, xerrors.Errorf("decoding retrieval ask tiers: %w", err)
+		}
+	}
+
+	s.tiers = &tiers
+	return tiers, nil
+}
+
+func (s *TiersStore) Set(tiers api.RetrievalAskTiers) error {
+	if s.unused != "" {
+		return xerrors.Errorf("retrieval ask tiers aren't applied: %s", s.unused)
+	}
+	if tiers.SealedSurcharge.Nil() {
+		tiers.SealedSurcharge = big.Zero()
+	}
+	if tiers.SealedSurcharge.LessThan(big.Zero()) {
+		return xerrors.Errorf("sealed surcharge can't be negative")
+	}
+
+	b, err := json.Marshal(&tiers)
+	if err != nil {
+		return xerrors.Errorf("encoding retrieval ask tiers: %w", err)
+	}
+
+	s.lk.Lock()
+	defer s.lk.Unlock()
+
+	if err := s.ds.Put(tiersKey, b); err != nil {
+		return xerrors.Errorf("storing retrieval ask tiers: %w", err)
+	}
+	s.tiers = &tiers
+	return nil
+}
+
+// PayloadSizeFunc returns the number of bytes of the blocks stored in the
+// piece, which is what a retrieval of the whole payload transfers and is
+// charged for
+type PayloadSizeFunc func(ctx context.Context, pieceCid cid.Cid) (uint64, error)
+
+// PiecePayloadSize computes the payload size of pieces from the block
+// locations in the piecestore. The first retrieval query of each piece scans
+// the block locations of all pieces, the sizes are cached as the payload of a
+// piece doesn't change.
+func PiecePayloadSize(ps piecestore.PieceStore) PayloadSizeFunc {
+	var lk sync.Mutex
+	sizes := map[cid.Cid]uint64{}
+
+	return func(ctx context.Context, pieceCid cid.Cid) (uint64, error) {
+		lk.Lock()
+		defer lk.Unlock()
+
+		if size, ok := sizes[pieceCid]; ok {
+			return size, nil
+		}
+
+		keys, err := ps.ListCidInfoKeys()
+		if err != nil {
+			return 0, xerrors.Errorf("listing block locations: %w", err)
+		}
+
+		var size uint64
+		for _, k := range keys {
+			ci, err := ps.GetCIDInfo(k)
+			if err != nil {
+				return 0, xerrors.Errorf("getting block locations of %s: %w", k, err)
+			}
+			// blocks are transferred once, even if the piece has several copies
+			for _, loc := range ci.PieceBlockLocations {
+				if loc.PieceCID.Equals(pieceCid) {
+					size += loc.BlockSize
+					break
+				}
+			}
+		}
+		if size == 0 {
+			return 0, xerrors.Errorf("no block locations of piece %s", pieceCid)
+		}
+
+		sizes[pieceCid] = size
+		return size, nil
+	}
+}
+
+// TieredPricingFunc applies the retrieval ask tiers to the asks priced by the
+// fallback pricing function. The first FreeBytes of the payload of a piece
+// aren't charged for. Retrievals are charged by the transferred bytes, so for
+// retrievals of the whole payload the price per byte is lowered to charge
+// the same total as for the remaining bytes at the full price; retrievals of
+// part of the payload are charged the lowered price for all bytes. Retrievals
+// of pieces without an unsealed copy are charged the sealed surcharge on top
+// of the unseal price.
+//
+// The tiers only apply to the default pricing strategy, external pricing
+// scripts price retrievals on their own.
+func TieredPricingFunc(store *TiersStore, payloadSize PayloadSizeFunc, fallback dtypes.RetrievalPricingFunc) dtypes.RetrievalPricingFunc {
+	return func(ctx context.Context, input retrievalmarket.PricingInput) (retrievalmarket.Ask, error) {
+		ask, err := fallback(ctx, input)
+		if err != nil {
+			return ask, err
+		}
+
+		tiers, err := store.Get()
+		if err != nil {
+			return retrievalmarket.Ask{}, err
+		}
+
+		if tiers.FreeBytes > 0 && !ask.PricePerByte.IsZero() {
+			size, err := payloadSize(ctx, input.PieceCID)
+			if err != nil {
+				return retrievalmarket.Ask{}, xerrors.Errorf("getting payload size of piece %s: %w", input.PieceCID, err)
+			}
+
+			if size <= tiers.FreeBytes {
+				ask.PricePerByte = big.Zero()
+			} else {
+				ask.PricePerByte = big.Div(big.Mul(ask.PricePerByte, big.NewIntUnsigned(size-tiers.FreeBytes)), big.NewIntUnsigned(size))
+			}
+		}
+
+		if !input.Unsealed && !tiers.SealedSurcharge.IsZero() {
+			ask.UnsealPrice = big.Add(ask.UnsealPrice, tiers.SealedSurcharge)
+		}
+
+		return ask, nil
+	}
+}
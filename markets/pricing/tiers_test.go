@@ -0,0 +1,130 @@
+package pricing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	dssync "github.com/ipfs/go-datastore/sync"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-fil-markets/piecestore"
+	"github.com/filecoin-project/go-fil-markets/retrievalmarket"
+	testnet "github.com/filecoin-project/go-fil-markets/shared_testutil"
+	"github.com/filecoin-project/go-state-types/big"
+
+	"github.com/filecoin-project/lotus/api"
+)
+
+type testPieceStore struct {
+	piecestore.PieceStore
+	cids map[cid.Cid]piecestore.CIDInfo
+}
+
+func (ps *testPieceStore) ListCidInfoKeys() ([]cid.Cid, error) {
+	var out []cid.Cid
+	for c := range ps.cids {
+		out = append(out, c)
+	}
+	return out, nil
+}
+
+func (ps *testPieceStore) GetCIDInfo(c cid.Cid) (piecestore.CIDInfo, error) {
+	return ps.cids[c], nil
+}
+
+func TestTieredPricingFunc(t *testing.T) {
+	ds := dssync.MutexWrap(datastore.NewMapDatastore())
+	store := NewTiersStore(ds)
+
+	cids := testnet.GenerateCids(2)
+	bigPiece, smallPiece := cids[0], cids[1]
+	payloads := map[cid.Cid]uint64{bigPiece: 1000, smallPiece: 200}
+	payloadSize := func(ctx context.Context, pieceCid cid.Cid) (uint64, error) {
+		return payloads[pieceCid], nil
+	}
+
+	fallback := func(ctx context.Context, input retrievalmarket.PricingInput) (retrievalmarket.Ask, error) {
+		return retrievalmarket.Ask{
+			PricePerByte: big.NewInt(10),
+			UnsealPrice:  big.NewInt(100),
+		}, nil
+	}
+	pricing := TieredPricingFunc(store, payloadSize, fallback)
+
+	price := func(piece cid.Cid, unsealed bool) retrievalmarket.Ask {
+		ask, err := pricing(context.Background(), retrievalmarket.PricingInput{PieceCID: piece, PieceSize: 2032, Unsealed: unsealed})
+		require.NoError(t, err)
+		return ask
+	}
+
+	// no tiers set
+	ask := price(bigPiece, false)
+	require.Equal(t, "10", ask.PricePerByte.String())
+	require.Equal(t, "100", ask.UnsealPrice.String())
+
+	require.NoError(t, store.Set(api.RetrievalAskTiers{
+		FreeBytes:       250,
+		SealedSurcharge: big.NewInt(50),
+	}))
+
+	// a quarter of the payload is free, regardless of the piece size
+	ask = price(bigPiece, true)
+	require.Equal(t, "7", ask.PricePerByte.String())
+	require.Equal(t, "100", ask.UnsealPrice.String())
+
+	// small payloads are free
+	ask = price(smallPiece, false)
+	require.True(t, ask.PricePerByte.IsZero())
+	// and the surcharge applies without an unsealed copy
+	require.Equal(t, "150", ask.UnsealPrice.String())
+
+	// the tiers persist
+	tiers, err := NewTiersStore(ds).Get()
+	require.NoError(t, err)
+	require.Equal(t, uint64(250), tiers.FreeBytes)
+	require.Equal(t, "50", tiers.SealedSurcharge.String())
+
+	require.Error(t, store.Set(api.RetrievalAskTiers{SealedSurcharge: big.NewInt(-1)}))
+
+	// tiers can't be set when they aren't applied
+	require.Error(t, NewUnusedTiersStore(ds, "external pricing").Set(api.RetrievalAskTiers{FreeBytes: 1}))
+}
+
+func TestPiecePayloadSize(t *testing.T) {
+	cids := testnet.GenerateCids(5)
+	piece, other := cids[0], cids[1]
+
+	loc := func(pieceCid cid.Cid, size uint64) piecestore.PieceBlockLocation {
+		return piecestore.PieceBlockLocation{
+			BlockLocation: piecestore.BlockLocation{BlockSize: size},
+			PieceCID:      pieceCid,
+		}
+	}
+
+	ps := &testPieceStore{cids: map[cid.Cid]piecestore.CIDInfo{
+		cids[2]: {PieceBlockLocations: []piecestore.PieceBlockLocation{loc(piece, 100)}},
+		// a block in both pieces
+		cids[3]: {PieceBlockLocations: []piecestore.PieceBlockLocation{loc(piece, 50), loc(other, 50)}},
+		cids[4]: {PieceBlockLocations: []piecestore.PieceBlockLocation{loc(other, 7)}},
+	}}
+	size := PiecePayloadSize(ps)
+
+	s, err := size(context.Background(), piece)
+	require.NoError(t, err)
+	require.EqualValues(t, 150, s)
+
+	s, err = size(context.Background(), other)
+	require.NoError(t, err)
+	require.EqualValues(t, 57, s)
+
+	// sizes are cached
+	ps.cids = nil
+	s, err = size(context.Background(), piece)
+	require.NoError(t, err)
+	require.EqualValues(t, 150, s)
+
+	_, err = size(context.Background(), cids[2])
+	require.Error(t, err)
+}
@@ -0,0 +1,488 @@
+// Package quarantine keeps the staged data of storage deals which failed,
+// instead of deleting it right away, so that the data can be inspected when
+// diagnosing failures like transfer corruption.
+package quarantine
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/query"
+	dshelp "github.com/ipfs/go-ipfs-ds-help"
+	logging "github.com/ipfs/go-log/v2"
+	"github.com/ipld/go-car"
+	carutil "github.com/ipld/go-car/util"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-fil-markets/filestore"
+	"github.com/filecoin-project/go-fil-markets/storagemarket"
+	"github.com/filecoin-project/go-multistore"
+)
+
+var log = logging.Logger("quarantine")
+
+const infoFile = "info.json"
+
+// DealGetter returns the provider's current view of the deal with the given
+// proposal CID
+type DealGetter func(propCid cid.Cid) (storagemarket.MinerDeal, error)
+
+// Entry describes the quarantined data of a failed deal
+type Entry struct {
+	ProposalCid cid.Cid
+	PieceCid    cid.Cid
+	Client      address.Address
+	Message     string
+
+	// paths of the quarantined files
+	Files []string
+	Size  int64
+
+	QuarantinedAt time.Time
+	ExpiresAt     time.Time
+}
+
+// Store wraps the file store deal data is staged in. When files staged for a
+// deal which failed are deleted, they are moved into the quarantine directory
+// instead, and only removed once the retention period expires. Data
+// transferred over graphsync is staged in the multistore instead, the
+// datastore of which is wrapped with WrapStaging.
+type Store struct {
+	filestore.FileStore
+
+	base      string
+	dir       string
+	retention func() (time.Duration, error)
+
+	lk      sync.Mutex
+	getDeal DealGetter
+
+	// the deals staged files and stores belong to, maintained with Track
+	files  map[filestore.Path]cid.Cid
+	stores map[multistore.StoreID]cid.Cid
+}
+
+// New wraps fs, which stores files relative to base. Quarantined files are
+// kept in dir.
+func New(fs filestore.FileStore, base, dir string, retention func() (time.Duration, error)) (*Store, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, xerrors.Errorf("creating quarantine dir: %w", err)
+	}
+
+	return &Store{
+		FileStore: fs,
+		base:      base,
+		dir:       dir,
+		retention: retention,
+		files:     map[filestore.Path]cid.Cid{},
+		stores:    map[multistore.StoreID]cid.Cid{},
+	}, nil
+}
+
+// SetDealGetter sets the function used to get the state of the deal a deleted
+// file or store belongs to. Until it's set, data is deleted right away.
+func (s *Store) SetDealGetter(get DealGetter) {
+	s.lk.Lock()
+	defer s.lk.Unlock()
+
+	s.getDeal = get
+}
+
+// Track records the staged files and store of the deal, so that the deal they
+// belong to is known when they're deleted. It's called for deals being
+// restored on startup, and on every provider event.
+func (s *Store) Track(deal storagemarket.MinerDeal) {
+	s.lk.Lock()
+	defer s.lk.Unlock()
+
+	switch deal.State {
+	case storagemarket.StorageDealActive, storagemarket.StorageDealError,
+		storagemarket.StorageDealExpired, storagemarket.StorageDealSlashed:
+		// the staged data was cleaned up already
+		for _, p := range []filestore.Path{deal.PiecePath, deal.MetadataPath} {
+			delete(s.files, p)
+		}
+		if deal.StoreID != nil {
+			delete(s.stores, *deal.StoreID)
+		}
+		return
+	}
+
+	for _, p := range []filestore.Path{deal.PiecePath, deal.MetadataPath} {
+		if p != "" {
+			s.files[p] = deal.ProposalCid
+		}
+	}
+	if deal.StoreID != nil {
+		s.stores[*deal.StoreID] = deal.ProposalCid
+	}
+}
+
+// failedDeal returns the deal with the given proposal CID if it failed and its
+// data should be quarantined. Must be called with s.lk held.
+func (s *Store) failedDeal(propCid cid.Cid) (storagemarket.MinerDeal, time.Duration, bool) {
+	retention, err := s.retention()
+	if err != nil {
+		log.Errorw("getting quarantine retention, deleting data", "deal", propCid, "error", err)
+		return storagemarket.MinerDeal{}, 0, false
+	}
+	if retention <= 0 || s.getDeal == nil {
+		return storagemarket.MinerDeal{}, 0, false
+	}
+
+	deal, err := s.getDeal(propCid)
+	if err != nil {
+		log.Errorw("getting deal of staged data, deleting data", "deal", propCid, "error", err)
+		return storagemarket.MinerDeal{}, 0, false
+	}
+
+	return deal, retention, deal.State == storagemarket.StorageDealFailing
+}
+
+func (s *Store) Delete(p filestore.Path) error {
+	s.lk.Lock()
+	defer s.lk.Unlock()
+
+	propCid, ok := s.files[p]
+	if !ok {
+		return s.FileStore.Delete(p)
+	}
+	delete(s.files, p)
+
+	deal, retention, ok := s.failedDeal(propCid)
+	if !ok {
+		return s.FileStore.Delete(p)
+	}
+
+	if err := s.quarantineFile(deal, p, retention); err != nil {
+		log.Errorw("quarantining failed deal data, deleting", "deal", deal.ProposalCid, "path", p, "error", err)
+		return s.FileStore.Delete(p)
+	}
+
+	log.Infow("quarantined failed deal data", "deal", deal.ProposalCid, "path", p, "retention", retention)
+	return nil
+}
+
+func (s *Store) quarantineFile(deal storagemarket.MinerDeal, p filestore.Path, retention time.Duration) error {
+	src := string(p)
+	if !filepath.IsAbs(src) {
+		src = filepath.Join(s.base, src)
+	}
+
+	return s.addFile(deal, retention, func(dealDir string) (string, int64, error) {
+		fi, err := os.Stat(src)
+		if err != nil {
+			return "", 0, xerrors.Errorf("stat staged file: %w", err)
+		}
+
+		dst := filepath.Join(dealDir, filepath.Base(src))
+		if err := os.Rename(src, dst); err != nil {
+			return "", 0, xerrors.Errorf("moving staged file: %w", err)
+		}
+		return dst, fi.Size(), nil
+	})
+}
+
+// addFile records a file which write puts into the quarantine directory of the
+// deal, creating the quarantine entry of the deal if needed
+func (s *Store) addFile(deal storagemarket.MinerDeal, retention time.Duration, write func(dealDir string) (string, int64, error)) error {
+	dealDir := filepath.Join(s.dir, deal.ProposalCid.String())
+	if err := os.MkdirAll(dealDir, 0755); err != nil {
+		return xerrors.Errorf("creating deal quarantine dir: %w", err)
+	}
+
+	ent, err := readEntry(dealDir)
+	if err != nil {
+		if !os.IsNotExist(xerrors.Unwrap(err)) {
+			return err
+		}
+
+		now := time.Now()
+		ent = Entry{
+			ProposalCid:   deal.ProposalCid,
+			PieceCid:      deal.Proposal.PieceCID,
+			Client:        deal.Client,
+			Message:       deal.Message,
+			QuarantinedAt: now,
+			ExpiresAt:     now.Add(retention),
+		}
+	}
+
+	dst, size, err := write(dealDir)
+	if err != nil {
+		return err
+	}
+
+	ent.Files = append(ent.Files, dst)
+	ent.Size += size
+
+	return writeEntry(dealDir, ent)
+}
+
+// WrapStaging wraps the datastore the staging multistore keeps its stores in.
+// When the store of a failed deal is deleted, its blocks are written to a CAR
+// file in the quarantine directory of the deal first.
+func (s *Store) WrapStaging(ds datastore.Batching) datastore.Batching {
+	return &stagingDatastore{Batching: ds, s: s}
+}
+
+type stagingDatastore struct {
+	datastore.Batching
+	s *Store
+}
+
+func (d *stagingDatastore) Delete(k datastore.Key) error {
+	d.s.storeDeleting(d.Batching, k)
+	return d.Batching.Delete(k)
+}
+
+func (d *stagingDatastore) Batch() (datastore.Batch, error) {
+	b, err := d.Batching.Batch()
+	if err != nil {
+		return nil, err
+	}
+	return &stagingBatch{Batch: b, ds: d}, nil
+}
+
+type stagingBatch struct {
+	datastore.Batch
+	ds *stagingDatastore
+}
+
+func (b *stagingBatch) Delete(k datastore.Key) error {
+	b.ds.s.storeDeleting(b.ds.Batching, k)
+	return b.Batch.Delete(k)
+}
+
+// storeDeleting is called before a key of the staging multistore is deleted.
+// The multistore keeps each store under /<store id>, when the first key of the
+// store of a failed deal is deleted the store is quarantined, while all of its
+// blocks are still there.
+func (s *Store) storeDeleting(ds datastore.Read, k datastore.Key) {
+	ns := k.List()
+	if len(ns) == 0 {
+		return
+	}
+	id, err := strconv.ParseUint(ns[0], 10, 64)
+	if err != nil {
+		return
+	}
+
+	s.lk.Lock()
+	defer s.lk.Unlock()
+
+	propCid, ok := s.stores[multistore.StoreID(id)]
+	if !ok {
+		return
+	}
+	// the rest of the store is deleted right away
+	delete(s.stores, multistore.StoreID(id))
+
+	deal, retention, ok := s.failedDeal(propCid)
+	if !ok {
+		return
+	}
+
+	if err := s.quarantineStore(ds, deal, multistore.StoreID(id), retention); err != nil {
+		log.Errorw("quarantining failed deal store", "deal", deal.ProposalCid, "store", id, "error", err)
+		return
+	}
+
+	log.Infow("quarantined failed deal store", "deal", deal.ProposalCid, "store", id, "retention", retention)
+}
+
+// quarantineStore writes the blocks of the store to a CAR file. Blocks are
+// keyed by multihash in the store, the codecs of their CIDs can't be recovered
+// so they're written as raw blocks.
+func (s *Store) quarantineStore(ds datastore.Read, deal storagemarket.MinerDeal, id multistore.StoreID, retention time.Duration) error {
+	res, err := ds.Query(query.Query{Prefix: fmt.Sprintf("/%d/blocks", id)})
+	if err != nil {
+		return xerrors.Errorf("querying store blocks: %w", err)
+	}
+	defer res.Close() //nolint:errcheck
+
+	return s.addFile(deal, retention, func(dealDir string) (string, int64, error) {
+		dst := filepath.Join(dealDir, fmt.Sprintf("store-%d.car", id))
+		f, err := os.Create(dst)
+		if err != nil {
+			return "", 0, xerrors.Errorf("creating store CAR: %w", err)
+		}
+		defer f.Close() //nolint:errcheck
+
+		w := bufio.NewWriter(f)
+
+		var roots []cid.Cid
+		if deal.Ref != nil {
+			roots = append(roots, deal.Ref.Root)
+		}
+		if err := car.WriteHeader(&car.CarHeader{Roots: roots, Version: 1}, w); err != nil {
+			return "", 0, xerrors.Errorf("writing CAR header: %w", err)
+		}
+
+		for r := range res.Next() {
+			if r.Error != nil {
+				return "", 0, xerrors.Errorf("reading store blocks: %w", r.Error)
+			}
+
+			key := datastore.NewKey(r.Key)
+			mh, err := dshelp.DsKeyToMultihash(datastore.NewKey(key.BaseNamespace()))
+			if err != nil {
+				log.Warnw("skipping store entry which isn't a block", "key", r.Key, "error", err)
+				continue
+			}
+
+			if err := carutil.LdWrite(w, cid.NewCidV1(cid.Raw, mh).Bytes(), r.Value); err != nil {
+				return "", 0, xerrors.Errorf("writing block: %w", err)
+			}
+		}
+
+		if err := w.Flush(); err != nil {
+			return "", 0, xerrors.Errorf("flushing store CAR: %w", err)
+		}
+
+		fi, err := f.Stat()
+		if err != nil {
+			return "", 0, xerrors.Errorf("stat store CAR: %w", err)
+		}
+		return dst, fi.Size(), f.Close()
+	})
+}
+
+// List returns all quarantined deal data, removing expired entries first
+func (s *Store) List() ([]Entry, error) {
+	if err := s.PurgeExpired(); err != nil {
+		return nil, err
+	}
+
+	s.lk.Lock()
+	defer s.lk.Unlock()
+
+	ents, err := s.entries()
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(ents, func(i, j int) bool {
+		return ents[i].QuarantinedAt.Before(ents[j].QuarantinedAt)
+	})
+	return ents, nil
+}
+
+// Purge removes the quarantined data of the deal
+func (s *Store) Purge(propCid cid.Cid) error {
+	s.lk.Lock()
+	defer s.lk.Unlock()
+
+	dealDir := filepath.Join(s.dir, propCid.String())
+	if _, err := os.Stat(dealDir); err != nil {
+		if os.IsNotExist(err) {
+			return xerrors.Errorf("no quarantined data for deal %s", propCid)
+		}
+		return err
+	}
+
+	return os.RemoveAll(dealDir)
+}
+
+// PurgeExpired removes quarantined data past its retention period
+func (s *Store) PurgeExpired() error {
+	s.lk.Lock()
+	defer s.lk.Unlock()
+
+	ents, err := s.entries()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, ent := range ents {
+		if now.Before(ent.ExpiresAt) {
+			continue
+		}
+
+		log.Infow("removing expired quarantined deal data", "deal", ent.ProposalCid)
+		if err := os.RemoveAll(filepath.Join(s.dir, ent.ProposalCid.String())); err != nil {
+			return xerrors.Errorf("removing quarantined data of deal %s: %w", ent.ProposalCid, err)
+		}
+	}
+
+	return nil
+}
+
+// Run periodically removes expired quarantined data until the context is
+// cancelled
+func (s *Store) Run(ctx context.Context, interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-t.C:
+			if err := s.PurgeExpired(); err != nil {
+				log.Errorw("purging expired quarantined deal data", "error", err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (s *Store) entries() ([]Entry, error) {
+	dirs, err := ioutil.ReadDir(s.dir)
+	if err != nil {
+		return nil, xerrors.Errorf("reading quarantine dir: %w", err)
+	}
+
+	var out []Entry
+	for _, d := range dirs {
+		if !d.IsDir() {
+			continue
+		}
+
+		ent, err := readEntry(filepath.Join(s.dir, d.Name()))
+		if err != nil {
+			log.Warnw("skipping quarantine entry", "dir", d.Name(), "error", err)
+			continue
+		}
+		out = append(out, ent)
+	}
+
+	return out, nil
+}
+
+func readEntry(dealDir string) (Entry, error) {
+	b, err := ioutil.ReadFile(filepath.Join(dealDir, infoFile))
+	if err != nil {
+		return Entry{}, xerrors.Errorf("reading quarantine info: %w", err)
+	}
+
+	var ent Entry
+	if err := json.Unmarshal(b, &ent); err != nil {
+		return Entry{}, xerrors.Errorf("decoding quarantine info: %w", err)
+	}
+	return ent, nil
+}
+
+func writeEntry(dealDir string, ent Entry) error {
+	b, err := json.MarshalIndent(ent, "", "  ")
+	if err != nil {
+		return xerrors.Errorf("encoding quarantine info: %w", err)
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(dealDir, infoFile), b, 0644); err != nil {
+		return xerrors.Errorf("writing quarantine info: %w", err)
+	}
+	return nil
+}
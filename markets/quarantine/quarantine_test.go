@@ -0,0 +1,221 @@
+package quarantine
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	blocks "github.com/ipfs/go-block-format"
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	dss "github.com/ipfs/go-datastore/sync"
+	"github.com/ipld/go-car"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-fil-markets/filestore"
+	"github.com/filecoin-project/go-fil-markets/storagemarket"
+	"github.com/filecoin-project/go-multistore"
+	tutils "github.com/filecoin-project/specs-actors/v2/support/testing"
+)
+
+func newTestStore(t *testing.T, retention time.Duration) (*Store, string) {
+	base := t.TempDir()
+	fs, err := filestore.NewLocalFileStore(filestore.OsPath(base))
+	require.NoError(t, err)
+
+	qs, err := New(fs, base, filepath.Join(base, "deal-quarantine"), func() (time.Duration, error) {
+		return retention, nil
+	})
+	require.NoError(t, err)
+
+	return qs, base
+}
+
+func stageFile(t *testing.T, qs *Store, name string) filestore.Path {
+	f, err := qs.Create(filestore.Path(name))
+	require.NoError(t, err)
+	_, err = f.Write([]byte("deal data"))
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	return f.Path()
+}
+
+// trackDeal makes the store see the deal in the state it's in
+func trackDeal(qs *Store, deal storagemarket.MinerDeal) {
+	qs.SetDealGetter(func(propCid cid.Cid) (storagemarket.MinerDeal, error) {
+		return deal, nil
+	})
+	qs.Track(deal)
+}
+
+func TestFailedDealQuarantined(t *testing.T) {
+	qs, base := newTestStore(t, time.Hour)
+
+	piecePath := stageFile(t, qs, "piece")
+	deal := storagemarket.MinerDeal{
+		ProposalCid: tutils.MakeCID("deal", nil),
+		State:       storagemarket.StorageDealFailing,
+		Message:     "commP mismatch",
+		PiecePath:   piecePath,
+	}
+	deal.Proposal.PieceCID = tutils.MakeCID("piece", nil)
+
+	qs.SetDealGetter(func(propCid cid.Cid) (storagemarket.MinerDeal, error) {
+		require.Equal(t, deal.ProposalCid, propCid)
+		return deal, nil
+	})
+	qs.Track(deal)
+
+	// the provider deletes the staged data when the deal fails after transfer
+	require.NoError(t, qs.Delete(piecePath))
+
+	_, err := os.Stat(filepath.Join(base, "piece"))
+	require.True(t, os.IsNotExist(err), "staged file should be moved out")
+
+	ents, err := qs.List()
+	require.NoError(t, err)
+	require.Len(t, ents, 1)
+	require.Equal(t, deal.ProposalCid, ents[0].ProposalCid)
+	require.Equal(t, deal.Proposal.PieceCID, ents[0].PieceCid)
+	require.Equal(t, "commP mismatch", ents[0].Message)
+	require.EqualValues(t, len("deal data"), ents[0].Size)
+	require.Len(t, ents[0].Files, 1)
+
+	b, err := ioutil.ReadFile(ents[0].Files[0])
+	require.NoError(t, err)
+	require.Equal(t, "deal data", string(b))
+
+	require.NoError(t, qs.Purge(deal.ProposalCid))
+	ents, err = qs.List()
+	require.NoError(t, err)
+	require.Empty(t, ents)
+
+	require.Error(t, qs.Purge(deal.ProposalCid))
+}
+
+func TestNonFailedDealDeleted(t *testing.T) {
+	qs, base := newTestStore(t, time.Hour)
+
+	piecePath := stageFile(t, qs, "piece")
+	trackDeal(qs, storagemarket.MinerDeal{
+		ProposalCid: tutils.MakeCID("deal", nil),
+		State:       storagemarket.StorageDealFinalizing,
+		PiecePath:   piecePath,
+	})
+
+	require.NoError(t, qs.Delete(piecePath))
+
+	_, err := os.Stat(filepath.Join(base, "piece"))
+	require.True(t, os.IsNotExist(err))
+
+	ents, err := qs.List()
+	require.NoError(t, err)
+	require.Empty(t, ents)
+}
+
+func TestQuarantineDisabled(t *testing.T) {
+	qs, _ := newTestStore(t, 0)
+
+	piecePath := stageFile(t, qs, "piece")
+	trackDeal(qs, storagemarket.MinerDeal{
+		ProposalCid: tutils.MakeCID("deal", nil),
+		State:       storagemarket.StorageDealFailing,
+		PiecePath:   piecePath,
+	})
+
+	require.NoError(t, qs.Delete(piecePath))
+
+	ents, err := qs.List()
+	require.NoError(t, err)
+	require.Empty(t, ents)
+}
+
+func TestQuarantineExpires(t *testing.T) {
+	qs, _ := newTestStore(t, time.Nanosecond)
+
+	piecePath := stageFile(t, qs, "piece")
+	trackDeal(qs, storagemarket.MinerDeal{
+		ProposalCid: tutils.MakeCID("deal", nil),
+		State:       storagemarket.StorageDealFailing,
+		PiecePath:   piecePath,
+	})
+
+	require.NoError(t, qs.Delete(piecePath))
+	time.Sleep(time.Millisecond)
+
+	ents, err := qs.List()
+	require.NoError(t, err)
+	require.Empty(t, ents)
+}
+
+func TestFailedDealStoreQuarantined(t *testing.T) {
+	qs, _ := newTestStore(t, time.Hour)
+
+	mds, err := multistore.NewMultiDstore(qs.WrapStaging(dss.MutexWrap(datastore.NewMapDatastore())))
+	require.NoError(t, err)
+
+	// data transferred over graphsync is staged in a store of the multistore
+	storeID := mds.Next()
+	st, err := mds.Get(storeID)
+	require.NoError(t, err)
+
+	blk := blocks.NewBlock([]byte("deal data"))
+	require.NoError(t, st.Bstore.Put(blk))
+
+	deal := storagemarket.MinerDeal{
+		ProposalCid: tutils.MakeCID("deal", nil),
+		State:       storagemarket.StorageDealFailing,
+		StoreID:     &storeID,
+		Ref:         &storagemarket.DataRef{Root: blk.Cid()},
+	}
+	trackDeal(qs, deal)
+
+	// the provider deletes the store when the deal fails
+	require.NoError(t, mds.Delete(storeID))
+
+	ents, err := qs.List()
+	require.NoError(t, err)
+	require.Len(t, ents, 1)
+	require.Equal(t, deal.ProposalCid, ents[0].ProposalCid)
+	require.Len(t, ents[0].Files, 1)
+
+	f, err := os.Open(ents[0].Files[0])
+	require.NoError(t, err)
+	defer f.Close() //nolint:errcheck
+
+	cr, err := car.NewCarReader(f)
+	require.NoError(t, err)
+	require.Equal(t, []cid.Cid{blk.Cid()}, cr.Header.Roots)
+
+	qblk, err := cr.Next()
+	require.NoError(t, err)
+	require.True(t, bytes.Equal(blk.RawData(), qblk.RawData()))
+	require.Equal(t, blk.Cid().Hash(), qblk.Cid().Hash())
+}
+
+func TestUntrackedStoreDeleted(t *testing.T) {
+	qs, _ := newTestStore(t, time.Hour)
+
+	mds, err := multistore.NewMultiDstore(qs.WrapStaging(dss.MutexWrap(datastore.NewMapDatastore())))
+	require.NoError(t, err)
+
+	storeID := mds.Next()
+	st, err := mds.Get(storeID)
+	require.NoError(t, err)
+	require.NoError(t, st.Bstore.Put(blocks.NewBlock([]byte("deal data"))))
+
+	// the deal completed, the store is cleaned up
+	trackDeal(qs, storagemarket.MinerDeal{
+		ProposalCid: tutils.MakeCID("deal", nil),
+		State:       storagemarket.StorageDealActive,
+		StoreID:     &storeID,
+	})
+	require.NoError(t, mds.Delete(storeID))
+
+	ents, err := qs.List()
+	require.NoError(t, err)
+	require.Empty(t, ents)
+}
@@ -0,0 +1,106 @@
+package retrievaladapter
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/ipfs/go-cid"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-fil-markets/piecestore"
+	"github.com/filecoin-project/go-fil-markets/retrievalmarket"
+	"github.com/filecoin-project/go-fil-markets/shared"
+)
+
+// PiecesAPI is the part of the miner API exposing the piecestore of the node
+// taking storage deals
+type PiecesAPI interface {
+	PiecesListPieces(ctx context.Context) ([]cid.Cid, error)
+	PiecesListCidInfos(ctx context.Context) ([]cid.Cid, error)
+	PiecesGetPieceInfo(ctx context.Context, pieceCid cid.Cid) (*piecestore.PieceInfo, error)
+	PiecesGetCIDInfo(ctx context.Context, payloadCid cid.Cid) (*piecestore.CIDInfo, error)
+}
+
+// remotePieceStore is a read-only piecestore backed by the piecestore of
+// another miner node, which lets retrieval-only nodes serve the deals sealed
+// by the sealing node for the miner actor
+type remotePieceStore struct {
+	ctx context.Context
+	api PiecesAPI
+
+	lk      sync.Mutex
+	started bool
+	ready   []shared.ReadyFunc
+}
+
+// NewRemotePieceStore returns a piecestore reading pieces from the miner API
+func NewRemotePieceStore(ctx context.Context, api PiecesAPI) piecestore.PieceStore {
+	return &remotePieceStore{ctx: ctx, api: api}
+}
+
+func (ps *remotePieceStore) Start(ctx context.Context) error {
+	ps.lk.Lock()
+	ps.started = true
+	ready := ps.ready
+	ps.ready = nil
+	ps.lk.Unlock()
+
+	for _, r := range ready {
+		r(nil)
+	}
+	return nil
+}
+
+func (ps *remotePieceStore) OnReady(ready shared.ReadyFunc) {
+	ps.lk.Lock()
+	if !ps.started {
+		ps.ready = append(ps.ready, ready)
+		ps.lk.Unlock()
+		return
+	}
+	ps.lk.Unlock()
+
+	ready(nil)
+}
+
+func (ps *remotePieceStore) AddDealForPiece(pieceCID cid.Cid, dealInfo piecestore.DealInfo) error {
+	return xerrors.New("remote piecestore is read-only")
+}
+
+func (ps *remotePieceStore) AddPieceBlockLocations(pieceCID cid.Cid, blockLocations map[cid.Cid]piecestore.BlockLocation) error {
+	return xerrors.New("remote piecestore is read-only")
+}
+
+func (ps *remotePieceStore) GetPieceInfo(pieceCID cid.Cid) (piecestore.PieceInfo, error) {
+	pi, err := ps.api.PiecesGetPieceInfo(ps.ctx, pieceCID)
+	if err != nil {
+		return piecestore.PieceInfo{}, notFound(err)
+	}
+	return *pi, nil
+}
+
+func (ps *remotePieceStore) GetCIDInfo(payloadCID cid.Cid) (piecestore.CIDInfo, error) {
+	ci, err := ps.api.PiecesGetCIDInfo(ps.ctx, payloadCID)
+	if err != nil {
+		return piecestore.CIDInfo{}, notFound(err)
+	}
+	return *ci, nil
+}
+
+func (ps *remotePieceStore) ListCidInfoKeys() ([]cid.Cid, error) {
+	return ps.api.PiecesListCidInfos(ps.ctx)
+}
+
+func (ps *remotePieceStore) ListPieceInfoKeys() ([]cid.Cid, error) {
+	return ps.api.PiecesListPieces(ps.ctx)
+}
+
+// notFound restores the not found error of the piecestore, which the
+// retrieval provider checks for, and which doesn't survive the RPC
+func notFound(err error) error {
+	if strings.Contains(err.Error(), retrievalmarket.ErrNotFound.Error()) {
+		return retrievalmarket.ErrNotFound
+	}
+	return err
+}
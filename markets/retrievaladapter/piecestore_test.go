@@ -0,0 +1,71 @@
+package retrievaladapter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/filecoin-project/go-fil-markets/piecestore"
+	"github.com/filecoin-project/go-fil-markets/retrievalmarket"
+	testnet "github.com/filecoin-project/go-fil-markets/shared_testutil"
+	"github.com/ipfs/go-cid"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/xerrors"
+)
+
+type testPiecesAPI struct {
+	pieces map[cid.Cid]piecestore.PieceInfo
+}
+
+func (a *testPiecesAPI) PiecesListPieces(ctx context.Context) ([]cid.Cid, error) {
+	var out []cid.Cid
+	for c := range a.pieces {
+		out = append(out, c)
+	}
+	return out, nil
+}
+
+func (a *testPiecesAPI) PiecesListCidInfos(ctx context.Context) ([]cid.Cid, error) {
+	return nil, nil
+}
+
+func (a *testPiecesAPI) PiecesGetPieceInfo(ctx context.Context, pieceCid cid.Cid) (*piecestore.PieceInfo, error) {
+	pi, ok := a.pieces[pieceCid]
+	if !ok {
+		// the error as it arrives through the RPC
+		return nil, xerrors.New(retrievalmarket.ErrNotFound.Error())
+	}
+	return &pi, nil
+}
+
+func (a *testPiecesAPI) PiecesGetCIDInfo(ctx context.Context, payloadCid cid.Cid) (*piecestore.CIDInfo, error) {
+	return nil, xerrors.New("connection refused")
+}
+
+func TestRemotePieceStore(t *testing.T) {
+	cids := testnet.GenerateCids(2)
+	api := &testPiecesAPI{pieces: map[cid.Cid]piecestore.PieceInfo{
+		cids[0]: {PieceCID: cids[0], Deals: []piecestore.DealInfo{{SectorID: 3}}},
+	}}
+	ps := NewRemotePieceStore(context.Background(), api)
+
+	var ready []error
+	ps.OnReady(func(err error) { ready = append(ready, err) })
+	require.Empty(t, ready)
+	require.NoError(t, ps.Start(context.Background()))
+	ps.OnReady(func(err error) { ready = append(ready, err) })
+	require.Equal(t, []error{nil, nil}, ready)
+
+	pi, err := ps.GetPieceInfo(cids[0])
+	require.NoError(t, err)
+	require.Equal(t, api.pieces[cids[0]], pi)
+
+	_, err = ps.GetPieceInfo(cids[1])
+	require.Equal(t, retrievalmarket.ErrNotFound, err)
+
+	// other errors are passed on
+	_, err = ps.GetCIDInfo(cids[1])
+	require.Error(t, err)
+	require.NotEqual(t, retrievalmarket.ErrNotFound, err)
+
+	require.Error(t, ps.AddDealForPiece(cids[1], piecestore.DealInfo{}))
+}
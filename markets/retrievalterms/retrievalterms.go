@@ -0,0 +1,368 @@
+// Package retrievalterms implements a small libp2p protocol through which the
+// client of a storage deal can set the terms it will later retrieve the deal
+// data at, and the provider side store and pricing function honoring those
+// terms.
+package retrievalterms
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/query"
+	logging "github.com/ipfs/go-log/v2"
+	"github.com/libp2p/go-libp2p-core/host"
+	inet "github.com/libp2p/go-libp2p-core/network"
+	"github.com/libp2p/go-libp2p-core/peer"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-address"
+	cborutil "github.com/filecoin-project/go-cbor-util"
+	"github.com/filecoin-project/go-fil-markets/retrievalmarket"
+	"github.com/filecoin-project/go-fil-markets/storagemarket"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/crypto"
+
+	"github.com/filecoin-project/lotus/node/modules/dtypes"
+)
+
+var log = logging.Logger("retrievalterms")
+
+const ProtocolID = "/fil/storage/retrievalterms/1.0.0"
+
+const streamDeadline = 30 * time.Second
+
+type Status uint64
+
+const (
+	StatusOk Status = iota
+	// the provider doesn't have the deal
+	StatusNotFound
+	// the terms weren't signed by the client of the deal, the deal isn't
+	// accepted or active, or the provider doesn't accept the terms
+	StatusRejected
+	StatusError
+)
+
+func (s Status) String() string {
+	switch s {
+	case StatusOk:
+		return "Ok"
+	case StatusNotFound:
+		return "NotFound"
+	case StatusRejected:
+		return "Rejected"
+	case StatusError:
+		return "Error"
+	default:
+		return "Unknown"
+	}
+}
+
+// Terms are the prices the data of a storage deal is retrieved at, overriding
+// the retrieval ask of the provider
+type Terms struct {
+	ProposalCid  cid.Cid
+	PricePerByte abi.TokenAmount
+	UnsealPrice  abi.TokenAmount
+}
+
+// SigningBytes returns the bytes the client of the deal signs
+func (t *Terms) SigningBytes() ([]byte, error) {
+	buf := new(bytes.Buffer)
+	if err := t.MarshalCBOR(buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+type Request struct {
+	Terms     Terms
+	Signature *crypto.Signature
+}
+
+type Response struct {
+	Status       Status
+	ErrorMessage string
+}
+
+// StoredTerms are the retrieval terms of a deal, which apply until the deal
+// ends
+type StoredTerms struct {
+	Terms   Terms
+	DealEnd abi.ChainEpoch
+}
+
+// Store keeps the retrieval terms set for storage deals, by the piece of the
+// deal and the peer of the deal client
+type Store struct {
+	ds datastore.Batching
+}
+
+func NewStore(ds datastore.Batching) *Store {
+	return &Store{ds: ds}
+}
+
+func termsPrefix(piece cid.Cid, client peer.ID) datastore.Key {
+	return datastore.NewKey(piece.String()).ChildString(peer.Encode(client))
+}
+
+// Put sets the retrieval terms of the deal for the piece, for retrievals by
+// the deal client
+func (s *Store) Put(piece cid.Cid, client peer.ID, terms Terms, dealEnd abi.ChainEpoch) error {
+	b, err := cborutil.Dump(&StoredTerms{Terms: terms, DealEnd: dealEnd})
+	if err != nil {
+		return xerrors.Errorf("encoding retrieval terms: %w", err)
+	}
+	return s.ds.Put(termsPrefix(piece, client).ChildString(terms.ProposalCid.String()), b)
+}
+
+// Get returns the retrieval terms the client set for the piece, nil if there
+// are none. Terms of deals which ended by the given height are removed. When
+// the client set terms in multiple deals for the piece, the ones with the
+// lowest price per byte apply.
+func (s *Store) Get(piece cid.Cid, client peer.ID, height abi.ChainEpoch) (*Terms, error) {
+	res, err := s.ds.Query(query.Query{Prefix: termsPrefix(piece, client).String()})
+	if err != nil {
+		return nil, xerrors.Errorf("querying retrieval terms: %w", err)
+	}
+	defer res.Close() //nolint:errcheck
+
+	var out *Terms
+	for r := range res.Next() {
+		if r.Error != nil {
+			return nil, xerrors.Errorf("reading retrieval terms: %w", r.Error)
+		}
+
+		var st StoredTerms
+		if err := st.UnmarshalCBOR(bytes.NewReader(r.Value)); err != nil {
+			return nil, xerrors.Errorf("decoding retrieval terms: %w", err)
+		}
+
+		if st.DealEnd <= height {
+			if err := s.ds.Delete(datastore.NewKey(r.Key)); err != nil {
+				return nil, xerrors.Errorf("removing expired retrieval terms: %w", err)
+			}
+			continue
+		}
+
+		if out == nil || st.Terms.PricePerByte.LessThan(out.PricePerByte) {
+			terms := st.Terms
+			out = &terms
+		}
+	}
+
+	return out, nil
+}
+
+// ChainHeight returns the current chain height
+type ChainHeight func(ctx context.Context) (abi.ChainEpoch, error)
+
+// PricingFunc prices retrievals of pieces by clients which set retrieval terms
+// for the piece in their deals at those terms, and all other retrievals with
+// the fallback pricing function
+func PricingFunc(store *Store, height ChainHeight, fallback dtypes.RetrievalPricingFunc) dtypes.RetrievalPricingFunc {
+	return func(ctx context.Context, input retrievalmarket.PricingInput) (retrievalmarket.Ask, error) {
+		ask, err := fallback(ctx, input)
+		if err != nil {
+			return ask, err
+		}
+
+		h, err := height(ctx)
+		if err != nil {
+			return retrievalmarket.Ask{}, xerrors.Errorf("getting chain height: %w", err)
+		}
+
+		terms, err := store.Get(input.PieceCID, input.Client, h)
+		if err != nil {
+			return retrievalmarket.Ask{}, err
+		}
+		if terms == nil {
+			return ask, nil
+		}
+
+		ask.PricePerByte = terms.PricePerByte
+		ask.UnsealPrice = terms.UnsealPrice
+		return ask, nil
+	}
+}
+
+// DealLookup returns the provider deal with the given proposal CID
+type DealLookup func(propCid cid.Cid) (storagemarket.MinerDeal, error)
+
+// SignatureVerifier checks that data was signed by addr, resolving addr to a
+// key address if needed
+type SignatureVerifier func(ctx context.Context, addr address.Address, data []byte, sig *crypto.Signature) (bool, error)
+
+// Policy is the provider's policy for accepting retrieval terms
+type Policy struct {
+	Accept bool
+
+	// terms priced below the minimums are rejected
+	MinPricePerByte abi.TokenAmount
+	MinUnsealPrice  abi.TokenAmount
+}
+
+// PolicyFunc returns the current retrieval terms policy
+type PolicyFunc func() (Policy, error)
+
+// Service accepts retrieval terms from storage clients on the provider side
+type Service struct {
+	store  *Store
+	deals  DealLookup
+	verify SignatureVerifier
+	policy PolicyFunc
+}
+
+func NewService(store *Store, deals DealLookup, verify SignatureVerifier, policy PolicyFunc) *Service {
+	return &Service{
+		store:  store,
+		deals:  deals,
+		verify: verify,
+		policy: policy,
+	}
+}
+
+// dealAccepted returns whether the deal was accepted by the provider, and
+// hasn't failed or ended
+func dealAccepted(st storagemarket.StorageDealStatus) bool {
+	switch st {
+	case storagemarket.StorageDealUnknown,
+		storagemarket.StorageDealProposalNotFound,
+		storagemarket.StorageDealProposalRejected,
+		storagemarket.StorageDealValidating,
+		storagemarket.StorageDealAcceptWait,
+		storagemarket.StorageDealRejecting,
+		storagemarket.StorageDealFailing,
+		storagemarket.StorageDealError,
+		storagemarket.StorageDealExpired,
+		storagemarket.StorageDealSlashed:
+		return false
+	default:
+		return true
+	}
+}
+
+func (s *Service) HandleStream(stream inet.Stream) {
+	defer stream.Close() //nolint:errcheck
+
+	_ = stream.SetDeadline(time.Now().Add(streamDeadline))
+
+	var req Request
+	if err := cborutil.ReadCborRPC(stream, &req); err != nil {
+		log.Warnw("failed to read retrieval terms request", "peer", stream.Conn().RemotePeer(), "error", err)
+		return
+	}
+
+	resp := s.processRequest(context.TODO(), &req)
+
+	if err := cborutil.WriteCborRPC(stream, resp); err != nil {
+		log.Warnw("failed to write retrieval terms response", "peer", stream.Conn().RemotePeer(), "error", err)
+	}
+}
+
+func (s *Service) processRequest(ctx context.Context, req *Request) *Response {
+	policy, err := s.policy()
+	if err != nil {
+		log.Errorw("getting retrieval terms policy", "error", err)
+		return &Response{
+			Status:       StatusError,
+			ErrorMessage: "internal error",
+		}
+	}
+	if !policy.Accept {
+		return &Response{
+			Status:       StatusRejected,
+			ErrorMessage: "provider doesn't accept retrieval terms",
+		}
+	}
+
+	deal, err := s.deals(req.Terms.ProposalCid)
+	if err != nil {
+		return &Response{
+			Status:       StatusNotFound,
+			ErrorMessage: xerrors.Errorf("deal %s not found: %w", req.Terms.ProposalCid, err).Error(),
+		}
+	}
+
+	if req.Signature == nil {
+		return &Response{
+			Status:       StatusRejected,
+			ErrorMessage: "retrieval terms not signed",
+		}
+	}
+
+	data, err := req.Terms.SigningBytes()
+	if err != nil {
+		return &Response{
+			Status:       StatusError,
+			ErrorMessage: "internal error",
+		}
+	}
+
+	ok, err := s.verify(ctx, deal.Proposal.Client, data, req.Signature)
+	if err != nil {
+		log.Errorw("verifying retrieval terms signature", "deal", req.Terms.ProposalCid, "error", err)
+		return &Response{
+			Status:       StatusError,
+			ErrorMessage: "internal error",
+		}
+	}
+	if !ok {
+		return &Response{
+			Status:       StatusRejected,
+			ErrorMessage: "retrieval terms not signed by the deal client",
+		}
+	}
+
+	if !dealAccepted(deal.State) {
+		return &Response{
+			Status:       StatusRejected,
+			ErrorMessage: fmt.Sprintf("deal is in state %s, not accepted or active", storagemarket.DealStates[deal.State]),
+		}
+	}
+
+	if req.Terms.PricePerByte.LessThan(policy.MinPricePerByte) || req.Terms.UnsealPrice.LessThan(policy.MinUnsealPrice) {
+		return &Response{
+			Status:       StatusRejected,
+			ErrorMessage: fmt.Sprintf("retrieval terms below the provider minimum of %s per byte, %s to unseal", policy.MinPricePerByte, policy.MinUnsealPrice),
+		}
+	}
+
+	if err := s.store.Put(deal.Proposal.PieceCID, deal.Client, req.Terms, deal.Proposal.EndEpoch); err != nil {
+		log.Errorw("storing retrieval terms", "deal", req.Terms.ProposalCid, "error", err)
+		return &Response{
+			Status:       StatusError,
+			ErrorMessage: "internal error",
+		}
+	}
+
+	log.Infow("set retrieval terms", "deal", req.Terms.ProposalCid, "piece", deal.Proposal.PieceCID, "pricePerByte", req.Terms.PricePerByte, "unsealPrice", req.Terms.UnsealPrice)
+
+	return &Response{Status: StatusOk}
+}
+
+// Send sends signed retrieval terms of a deal to its provider
+func Send(ctx context.Context, h host.Host, p peer.ID, req *Request) (*Response, error) {
+	stream, err := h.NewStream(ctx, p, ProtocolID)
+	if err != nil {
+		return nil, xerrors.Errorf("opening retrieval terms stream: %w", err)
+	}
+	defer stream.Close() //nolint:errcheck
+
+	_ = stream.SetDeadline(time.Now().Add(streamDeadline))
+
+	if err := cborutil.WriteCborRPC(stream, req); err != nil {
+		return nil, xerrors.Errorf("writing retrieval terms request: %w", err)
+	}
+
+	var resp Response
+	if err := cborutil.ReadCborRPC(stream, &resp); err != nil {
+		return nil, xerrors.Errorf("reading retrieval terms response: %w", err)
+	}
+
+	return &resp, nil
+}
@@ -0,0 +1,218 @@
+package retrievalterms
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	dssync "github.com/ipfs/go-datastore/sync"
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-fil-markets/retrievalmarket"
+	"github.com/filecoin-project/go-fil-markets/storagemarket"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
+	"github.com/filecoin-project/go-state-types/crypto"
+	tutils "github.com/filecoin-project/specs-actors/v2/support/testing"
+)
+
+const clientPeer = peer.ID("client")
+
+func testDeal(t *testing.T) storagemarket.MinerDeal {
+	deal := storagemarket.MinerDeal{
+		ProposalCid: tutils.MakeCID("deal", nil),
+		Client:      clientPeer,
+		State:       storagemarket.StorageDealProposalAccepted,
+	}
+	deal.Proposal.PieceCID = tutils.MakeCID("piece", nil)
+	deal.Proposal.Client = tutils.NewIDAddr(t, 1000)
+	deal.Proposal.EndEpoch = 1000
+	return deal
+}
+
+var acceptAll = Policy{
+	Accept:          true,
+	MinPricePerByte: big.Zero(),
+	MinUnsealPrice:  big.Zero(),
+}
+
+func testService(deal storagemarket.MinerDeal, policy Policy) (*Service, *Store) {
+	store := NewStore(dssync.MutexWrap(datastore.NewMapDatastore()))
+
+	deals := func(propCid cid.Cid) (storagemarket.MinerDeal, error) {
+		if propCid != deal.ProposalCid {
+			return storagemarket.MinerDeal{}, xerrors.New("not found")
+		}
+		return deal, nil
+	}
+
+	// a signature is valid if it's the signed data prefixed with the signer
+	verify := func(ctx context.Context, addr address.Address, data []byte, sig *crypto.Signature) (bool, error) {
+		return bytes.Equal(sig.Data, append(addr.Bytes(), data...)), nil
+	}
+
+	return NewService(store, deals, verify, func() (Policy, error) {
+		return policy, nil
+	}), store
+}
+
+func sign(t *testing.T, addr address.Address, terms Terms) *Request {
+	data, err := terms.SigningBytes()
+	require.NoError(t, err)
+	return &Request{
+		Terms: terms,
+		Signature: &crypto.Signature{
+			Type: crypto.SigTypeSecp256k1,
+			Data: append(addr.Bytes(), data...),
+		},
+	}
+}
+
+func TestSetRetrievalTerms(t *testing.T) {
+	deal := testDeal(t)
+	svc, store := testService(deal, acceptAll)
+
+	terms := Terms{
+		ProposalCid:  deal.ProposalCid,
+		PricePerByte: abi.NewTokenAmount(3),
+		UnsealPrice:  abi.NewTokenAmount(11),
+	}
+
+	resp := svc.processRequest(context.Background(), sign(t, deal.Proposal.Client, terms))
+	require.Equal(t, StatusOk, resp.Status, resp.ErrorMessage)
+
+	got, err := store.Get(deal.Proposal.PieceCID, clientPeer, 10)
+	require.NoError(t, err)
+	require.NotNil(t, got)
+	require.Equal(t, terms, *got)
+
+	// the terms only apply to retrievals by the deal client
+	got, err = store.Get(deal.Proposal.PieceCID, peer.ID("other"), 10)
+	require.NoError(t, err)
+	require.Nil(t, got)
+
+	fallback := func(ctx context.Context, input retrievalmarket.PricingInput) (retrievalmarket.Ask, error) {
+		return retrievalmarket.Ask{
+			PricePerByte:            abi.NewTokenAmount(1),
+			UnsealPrice:             abi.NewTokenAmount(2),
+			PaymentInterval:         1 << 20,
+			PaymentIntervalIncrease: 1 << 20,
+		}, nil
+	}
+	height := abi.ChainEpoch(10)
+	pricing := PricingFunc(store, func(context.Context) (abi.ChainEpoch, error) {
+		return height, nil
+	}, fallback)
+
+	// retrievals of the deal's piece by the client are priced at its terms
+	ask, err := pricing(context.Background(), retrievalmarket.PricingInput{PieceCID: deal.Proposal.PieceCID, Client: clientPeer})
+	require.NoError(t, err)
+	require.Equal(t, terms.PricePerByte, ask.PricePerByte)
+	require.Equal(t, terms.UnsealPrice, ask.UnsealPrice)
+	require.EqualValues(t, 1<<20, ask.PaymentInterval)
+
+	// other clients and pieces use the fallback
+	for _, input := range []retrievalmarket.PricingInput{
+		{PieceCID: deal.Proposal.PieceCID, Client: peer.ID("other")},
+		{PieceCID: tutils.MakeCID("other", nil), Client: clientPeer},
+	} {
+		ask, err = pricing(context.Background(), input)
+		require.NoError(t, err)
+		require.Equal(t, abi.NewTokenAmount(1), ask.PricePerByte)
+		require.Equal(t, abi.NewTokenAmount(2), ask.UnsealPrice)
+	}
+
+	// the terms expire with the deal
+	height = deal.Proposal.EndEpoch
+	ask, err = pricing(context.Background(), retrievalmarket.PricingInput{PieceCID: deal.Proposal.PieceCID, Client: clientPeer})
+	require.NoError(t, err)
+	require.Equal(t, abi.NewTokenAmount(1), ask.PricePerByte)
+
+	got, err = store.Get(deal.Proposal.PieceCID, clientPeer, 0)
+	require.NoError(t, err)
+	require.Nil(t, got, "expired terms are removed")
+}
+
+func TestRetrievalTermsPerDeal(t *testing.T) {
+	store := NewStore(dssync.MutexWrap(datastore.NewMapDatastore()))
+	piece := tutils.MakeCID("piece", nil)
+
+	set := func(deal string, ppb int64, end abi.ChainEpoch) {
+		require.NoError(t, store.Put(piece, clientPeer, Terms{
+			ProposalCid:  tutils.MakeCID(deal, nil),
+			PricePerByte: abi.NewTokenAmount(ppb),
+			UnsealPrice:  abi.NewTokenAmount(0),
+		}, end))
+	}
+
+	// terms of another deal for the same piece don't replace each other, the
+	// cheapest terms of deals which haven't ended apply
+	set("deal1", 5, 100)
+	set("deal2", 3, 50)
+
+	got, err := store.Get(piece, clientPeer, 10)
+	require.NoError(t, err)
+	require.Equal(t, abi.NewTokenAmount(3), got.PricePerByte)
+
+	got, err = store.Get(piece, clientPeer, 50)
+	require.NoError(t, err)
+	require.Equal(t, abi.NewTokenAmount(5), got.PricePerByte)
+}
+
+func TestRejectRetrievalTerms(t *testing.T) {
+	deal := testDeal(t)
+	svc, store := testService(deal, acceptAll)
+
+	terms := Terms{
+		ProposalCid:  deal.ProposalCid,
+		PricePerByte: abi.NewTokenAmount(0),
+		UnsealPrice:  abi.NewTokenAmount(0),
+	}
+
+	// unsigned
+	resp := svc.processRequest(context.Background(), &Request{Terms: terms})
+	require.Equal(t, StatusRejected, resp.Status)
+
+	// signed by someone other than the client
+	resp = svc.processRequest(context.Background(), sign(t, tutils.NewIDAddr(t, 1001), terms))
+	require.Equal(t, StatusRejected, resp.Status)
+
+	// unknown deal
+	unknown := terms
+	unknown.ProposalCid = tutils.MakeCID("unknown", nil)
+	resp = svc.processRequest(context.Background(), sign(t, deal.Proposal.Client, unknown))
+	require.Equal(t, StatusNotFound, resp.Status)
+
+	// deals which weren't accepted, or failed
+	for _, st := range []storagemarket.StorageDealStatus{storagemarket.StorageDealValidating, storagemarket.StorageDealError} {
+		notAccepted := deal
+		notAccepted.State = st
+		svc, _ := testService(notAccepted, acceptAll)
+		resp = svc.processRequest(context.Background(), sign(t, deal.Proposal.Client, terms))
+		require.Equal(t, StatusRejected, resp.Status)
+	}
+
+	// the provider doesn't accept terms
+	svc, _ = testService(deal, Policy{})
+	resp = svc.processRequest(context.Background(), sign(t, deal.Proposal.Client, terms))
+	require.Equal(t, StatusRejected, resp.Status)
+
+	// below the provider minimum
+	svc, _ = testService(deal, Policy{
+		Accept:          true,
+		MinPricePerByte: abi.NewTokenAmount(1),
+		MinUnsealPrice:  big.Zero(),
+	})
+	resp = svc.processRequest(context.Background(), sign(t, deal.Proposal.Client, terms))
+	require.Equal(t, StatusRejected, resp.Status)
+	require.Contains(t, resp.ErrorMessage, "minimum")
+
+	got, err := store.Get(deal.Proposal.PieceCID, clientPeer, 0)
+	require.NoError(t, err)
+	require.Nil(t, got)
+}
@@ -0,0 +1,262 @@
+package retrievaltransport
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/ipfs/go-cid"
+	ipld "github.com/ipfs/go-ipld-format"
+	"github.com/ipld/go-car"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-fil-markets/piecestore"
+	"github.com/filecoin-project/go-state-types/abi"
+
+	"github.com/filecoin-project/lotus/node/modules/dtypes"
+)
+
+// PayloadPath is the path the HTTP transport serves the CAR of a payload at,
+// followed by the payload root CID
+const PayloadPath = "/payload/"
+
+// maxSectionSize bounds the size of a single CAR section read from a piece,
+// which is far larger than any block
+const maxSectionSize = 32 << 20
+
+// PieceStore looks up the pieces holding a payload
+type PieceStore interface {
+	GetPieceInfo(pieceCID cid.Cid) (piecestore.PieceInfo, error)
+	GetCIDInfo(payloadCID cid.Cid) (piecestore.CIDInfo, error)
+}
+
+// Unsealer reads the unsealed data of a piece from a sector, unsealing the
+// sector when needed
+type Unsealer interface {
+	UnsealSector(ctx context.Context, sectorID abi.SectorNumber, offset abi.UnpaddedPieceSize, length abi.UnpaddedPieceSize) (io.ReadCloser, error)
+}
+
+// HTTPHandler serves the CARs of payloads stored in deals over HTTP. The
+// payloads are served to anyone, free of charge: retrieval pricing and
+// retrieval deal filters don't apply.
+type HTTPHandler struct {
+	pieces    PieceStore
+	unsealer  Unsealer
+	accepting dtypes.ConsiderOnlineRetrievalDealsConfigFunc
+}
+
+func NewHTTPHandler(pieces PieceStore, unsealer Unsealer, accepting dtypes.ConsiderOnlineRetrievalDealsConfigFunc) *HTTPHandler {
+	return &HTTPHandler{
+		pieces:    pieces,
+		unsealer:  unsealer,
+		accepting: accepting,
+	}
+}
+
+func (h *HTTPHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "only GET is supported", http.StatusMethodNotAllowed)
+		return
+	}
+	if !strings.HasPrefix(r.URL.Path, PayloadPath) {
+		http.NotFound(w, r)
+		return
+	}
+
+	root, err := cid.Parse(strings.TrimPrefix(r.URL.Path, PayloadPath))
+	if err != nil {
+		http.Error(w, xerrors.Errorf("parsing payload CID: %w", err).Error(), http.StatusBadRequest)
+		return
+	}
+
+	accepting, err := h.accepting()
+	if err != nil {
+		log.Errorw("getting online retrieval config", "error", err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	if !accepting {
+		http.Error(w, "provider isn't accepting online retrievals", http.StatusForbidden)
+		return
+	}
+
+	rd, status, err := h.openPayload(r.Context(), root)
+	if err != nil {
+		log.Warnw("serving payload over HTTP", "root", root, "error", err)
+		http.Error(w, err.Error(), status)
+		return
+	}
+	defer rd.Close() //nolint:errcheck
+
+	w.Header().Set("Content-Type", "application/vnd.ipld.car")
+
+	n, err := copyCAR(w, rd)
+	if err != nil {
+		// the response is already under way, all that can be done is
+		// cutting it short
+		log.Warnw("streaming payload over HTTP", "root", root, "bytes", n, "error", err)
+		return
+	}
+
+	log.Infow("served payload over HTTP", "root", root, "bytes", n, "remote", r.RemoteAddr)
+}
+
+// openPayload returns a reader of the unsealed data of a piece holding the
+// payload, trying every deal for every piece the payload is stored in. On
+// failure, it returns the HTTP status to respond with.
+func (h *HTTPHandler) openPayload(ctx context.Context, root cid.Cid) (io.ReadCloser, int, error) {
+	ci, err := h.pieces.GetCIDInfo(root)
+	if err != nil {
+		return nil, http.StatusNotFound, xerrors.Errorf("payload %s not found: %w", root, err)
+	}
+
+	var merr error
+	seen := map[cid.Cid]struct{}{}
+	for _, loc := range ci.PieceBlockLocations {
+		if _, ok := seen[loc.PieceCID]; ok {
+			continue
+		}
+		seen[loc.PieceCID] = struct{}{}
+
+		pi, err := h.pieces.GetPieceInfo(loc.PieceCID)
+		if err != nil {
+			merr = multierror.Append(merr, xerrors.Errorf("getting piece info for %s: %w", loc.PieceCID, err))
+			continue
+		}
+
+		for _, deal := range pi.Deals {
+			rd, err := h.unsealer.UnsealSector(ctx, deal.SectorID, deal.Offset.Unpadded(), deal.Length.Unpadded())
+			if err != nil {
+				merr = multierror.Append(merr, xerrors.Errorf("reading piece %s from sector %d: %w", loc.PieceCID, deal.SectorID, err))
+				continue
+			}
+			return rd, 0, nil
+		}
+	}
+
+	if merr == nil {
+		return nil, http.StatusNotFound, xerrors.Errorf("no deals hold payload %s", root)
+	}
+	return nil, http.StatusInternalServerError, xerrors.Errorf("reading payload %s: %w", root, merr)
+}
+
+// copyCAR copies the CAR at the start of the unsealed piece data in r to w,
+// stopping at the zero padding filling the rest of the piece. No CAR section,
+// including the header, is empty, so the first zero section length marks the
+// end of the CAR.
+func copyCAR(w io.Writer, r io.Reader) (int64, error) {
+	br := bufio.NewReader(r)
+	buf := make([]byte, binary.MaxVarintLen64)
+
+	var n int64
+	for {
+		l, err := binary.ReadUvarint(br)
+		if err == io.EOF || (err == nil && l == 0) {
+			return n, nil
+		}
+		if err != nil {
+			return n, xerrors.Errorf("reading CAR section length: %w", err)
+		}
+		if l > maxSectionSize {
+			return n, xerrors.Errorf("CAR section of %d bytes is larger than the maximum of %d", l, maxSectionSize)
+		}
+
+		vn, err := w.Write(buf[:binary.PutUvarint(buf, l)])
+		n += int64(vn)
+		if err != nil {
+			return n, err
+		}
+
+		cn, err := io.CopyN(w, br, int64(l))
+		n += cn
+		if err != nil {
+			return n, xerrors.Errorf("copying CAR section: %w", err)
+		}
+	}
+}
+
+// Fetch retrieves the payload with the given root from an HTTP transport at
+// url into dag, checking that every block matches its CID. It returns the
+// number of bytes received.
+func Fetch(ctx context.Context, url string, root cid.Cid, dag ipld.DAGService) (uint64, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(url, "/")+PayloadPath+root.String(), nil)
+	if err != nil {
+		return 0, xerrors.Errorf("creating request: %w", err)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, xerrors.Errorf("requesting payload: %w", err)
+	}
+	defer resp.Body.Close() //nolint:errcheck
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return 0, xerrors.Errorf("provider responded with status %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+
+	cr := &countingReader{r: resp.Body}
+	rd, err := car.NewCarReader(cr)
+	if err != nil {
+		return cr.n, xerrors.Errorf("reading CAR header: %w", err)
+	}
+
+	hasRoot := false
+	for _, c := range rd.Header.Roots {
+		hasRoot = hasRoot || c.Equals(root)
+	}
+	if !hasRoot {
+		return cr.n, xerrors.Errorf("CAR roots %v don't include payload root %s", rd.Header.Roots, root)
+	}
+
+	gotRoot := false
+	for {
+		blk, err := rd.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return cr.n, xerrors.Errorf("reading block: %w", err)
+		}
+
+		c, err := blk.Cid().Prefix().Sum(blk.RawData())
+		if err != nil {
+			return cr.n, xerrors.Errorf("hashing block %s: %w", blk.Cid(), err)
+		}
+		if !c.Equals(blk.Cid()) {
+			return cr.n, xerrors.Errorf("block data doesn't match its CID %s", blk.Cid())
+		}
+
+		nd, err := ipld.Decode(blk)
+		if err != nil {
+			return cr.n, xerrors.Errorf("decoding block %s: %w", blk.Cid(), err)
+		}
+		if err := dag.Add(ctx, nd); err != nil {
+			return cr.n, xerrors.Errorf("storing block %s: %w", blk.Cid(), err)
+		}
+
+		gotRoot = gotRoot || blk.Cid().Equals(root)
+	}
+
+	if !gotRoot {
+		return cr.n, xerrors.Errorf("CAR doesn't hold the root block %s", root)
+	}
+
+	return cr.n, nil
+}
+
+type countingReader struct {
+	r io.Reader
+	n uint64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += uint64(n)
+	return n, err
+}
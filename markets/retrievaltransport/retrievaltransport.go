@@ -0,0 +1,78 @@
+// Package retrievaltransport implements retrievals over transports other than
+// graphsync: a small libp2p protocol through which retrieval providers
+// advertise the transports they serve, and the HTTP transport, which streams
+// the CAR of a payload straight from the unsealed piece holding it.
+package retrievaltransport
+
+import (
+	"context"
+	"time"
+
+	logging "github.com/ipfs/go-log/v2"
+	"github.com/libp2p/go-libp2p-core/host"
+	inet "github.com/libp2p/go-libp2p-core/network"
+	"github.com/libp2p/go-libp2p-core/peer"
+	"golang.org/x/xerrors"
+
+	cborutil "github.com/filecoin-project/go-cbor-util"
+)
+
+var log = logging.Logger("retrievaltransport")
+
+const ProtocolID = "/fil/retrieval/transports/1.0.0"
+
+const streamDeadline = 30 * time.Second
+
+// HTTP is the name of the HTTP transport
+const HTTP = "http"
+
+// Transport is a transport a provider serves retrievals over
+type Transport struct {
+	// Name of the transport, e.g. "http"
+	Name string
+	// URL the transport is reachable at
+	URL string
+}
+
+type Response struct {
+	Transports []Transport
+}
+
+// Service answers transport queries of retrieval clients on the provider
+// side
+type Service struct {
+	transports []Transport
+}
+
+func NewService(transports []Transport) *Service {
+	return &Service{transports: transports}
+}
+
+func (s *Service) HandleStream(stream inet.Stream) {
+	defer stream.Close() //nolint:errcheck
+
+	_ = stream.SetDeadline(time.Now().Add(streamDeadline))
+
+	if err := cborutil.WriteCborRPC(stream, &Response{Transports: s.transports}); err != nil {
+		log.Warnw("failed to write retrieval transports response", "peer", stream.Conn().RemotePeer(), "error", err)
+	}
+}
+
+// Query returns the transports the provider serves retrievals over besides
+// graphsync
+func Query(ctx context.Context, h host.Host, p peer.ID) ([]Transport, error) {
+	stream, err := h.NewStream(ctx, p, ProtocolID)
+	if err != nil {
+		return nil, xerrors.Errorf("opening retrieval transports stream: %w", err)
+	}
+	defer stream.Close() //nolint:errcheck
+
+	_ = stream.SetDeadline(time.Now().Add(streamDeadline))
+
+	var resp Response
+	if err := cborutil.ReadCborRPC(stream, &resp); err != nil {
+		return nil, xerrors.Errorf("reading retrieval transports response: %w", err)
+	}
+
+	return resp.Transports, nil
+}
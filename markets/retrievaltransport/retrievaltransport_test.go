@@ -0,0 +1,150 @@
+package retrievaltransport
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ipfs/go-cid"
+	ipld "github.com/ipfs/go-ipld-format"
+	"github.com/ipfs/go-merkledag"
+	dstest "github.com/ipfs/go-merkledag/test"
+	"github.com/ipld/go-car"
+	mocknet "github.com/libp2p/go-libp2p/p2p/net/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-fil-markets/piecestore"
+	"github.com/filecoin-project/go-state-types/abi"
+	tutils "github.com/filecoin-project/specs-actors/v2/support/testing"
+)
+
+type testPieces struct {
+	root  cid.Cid
+	piece cid.Cid
+}
+
+func (p *testPieces) GetPieceInfo(pieceCID cid.Cid) (piecestore.PieceInfo, error) {
+	if !pieceCID.Equals(p.piece) {
+		return piecestore.PieceInfo{}, xerrors.New("not found")
+	}
+	return piecestore.PieceInfo{
+		PieceCID: p.piece,
+		Deals:    []piecestore.DealInfo{{DealID: 1, SectorID: 2, Offset: 0, Length: 2048}},
+	}, nil
+}
+
+func (p *testPieces) GetCIDInfo(payloadCID cid.Cid) (piecestore.CIDInfo, error) {
+	if !payloadCID.Equals(p.root) {
+		return piecestore.CIDInfo{}, xerrors.New("not found")
+	}
+	return piecestore.CIDInfo{
+		CID:                 p.root,
+		PieceBlockLocations: []piecestore.PieceBlockLocation{{PieceCID: p.piece}},
+	}, nil
+}
+
+type testUnsealer []byte
+
+func (u testUnsealer) UnsealSector(ctx context.Context, sectorID abi.SectorNumber, offset abi.UnpaddedPieceSize, length abi.UnpaddedPieceSize) (io.ReadCloser, error) {
+	return ioutil.NopCloser(bytes.NewReader(u)), nil
+}
+
+// testPayload returns a small DAG with its root, the CAR of the DAG, and the
+// unsealed data of a piece holding the CAR
+func testPayload(t *testing.T) (cid.Cid, []byte, []byte) {
+	ctx := context.Background()
+	src := dstest.Mock()
+
+	a := merkledag.NewRawNode([]byte("leaf a"))
+	b := merkledag.NewRawNode([]byte("leaf b"))
+	root := &merkledag.ProtoNode{}
+	require.NoError(t, root.AddNodeLink("a", a))
+	require.NoError(t, root.AddNodeLink("b", b))
+	require.NoError(t, src.AddMany(ctx, []ipld.Node{a, b, root}))
+
+	var buf bytes.Buffer
+	require.NoError(t, car.WriteCar(ctx, src, []cid.Cid{root.Cid()}, &buf))
+	carData := buf.Bytes()
+
+	// the rest of the piece is zero padding
+	piece := append(append([]byte{}, carData...), make([]byte, 1024)...)
+
+	return root.Cid(), carData, piece
+}
+
+func testServer(t *testing.T, root cid.Cid, piece []byte, accepting bool) *httptest.Server {
+	pieces := &testPieces{root: root, piece: tutils.MakeCID("piece", nil)}
+	srv := httptest.NewServer(NewHTTPHandler(pieces, testUnsealer(piece), func() (bool, error) {
+		return accepting, nil
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestHTTPFetch(t *testing.T) {
+	ctx := context.Background()
+	root, carData, piece := testPayload(t)
+	srv := testServer(t, root, piece, true)
+
+	dst := dstest.Mock()
+	n, err := Fetch(ctx, srv.URL, root, dst)
+	require.NoError(t, err)
+	require.EqualValues(t, len(carData), n, "the padding of the piece was served")
+
+	nd, err := dst.Get(ctx, root)
+	require.NoError(t, err)
+	for _, l := range nd.Links() {
+		_, err := dst.Get(ctx, l.Cid)
+		require.NoError(t, err)
+	}
+}
+
+func TestHTTPFetchErrors(t *testing.T) {
+	ctx := context.Background()
+	root, _, piece := testPayload(t)
+
+	t.Run("unknown payload", func(t *testing.T) {
+		srv := testServer(t, root, piece, true)
+		_, err := Fetch(ctx, srv.URL, tutils.MakeCID("other", nil), dstest.Mock())
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "status 404")
+	})
+
+	t.Run("not accepting", func(t *testing.T) {
+		srv := testServer(t, root, piece, false)
+		_, err := Fetch(ctx, srv.URL, root, dstest.Mock())
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "status 403")
+	})
+
+	t.Run("corrupted block", func(t *testing.T) {
+		root, carData, piece := testPayload(t)
+		// the CAR ends with the data of a block
+		piece[len(carData)-1] ^= 0xff
+
+		srv := testServer(t, root, piece, true)
+		_, err := Fetch(ctx, srv.URL, root, dstest.Mock())
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "doesn't match its CID")
+	})
+}
+
+func TestQuery(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	mn, err := mocknet.FullMeshConnected(ctx, 2)
+	require.NoError(t, err)
+	provider, client := mn.Hosts()[0], mn.Hosts()[1]
+
+	transports := []Transport{{Name: HTTP, URL: "http://127.0.0.1:1234"}}
+	provider.SetStreamHandler(ProtocolID, NewService(transports).HandleStream)
+
+	got, err := Query(ctx, client, provider.ID())
+	require.NoError(t, err)
+	require.Equal(t, transports, got)
+}
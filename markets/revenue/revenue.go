@@ -0,0 +1,104 @@
+// Package revenue computes the storage payments a provider has been paid, and
+// is still due, for its deals from the market actor state.
+package revenue
+
+import (
+	"sort"
+	"strconv"
+
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
+
+	"github.com/filecoin-project/lotus/api"
+)
+
+// ForDeal returns the payments released to the provider of the deal since the
+// given epoch, and the payments still due for the rest of the deal.
+//
+// The market actor pays providers in arrears, up to the epoch the state of
+// the deal was last updated in, so payments for epochs after that are counted
+// as locked until cron processes the deal again. Nothing is due for the rest
+// of slashed deals.
+func ForDeal(deal api.MarketDeal, since abi.ChainEpoch) (realized, locked abi.TokenAmount) {
+	prop, st := deal.Proposal, deal.State
+	price := prop.StoragePricePerEpoch
+
+	// the epoch the deal has been paid for up to
+	paidUntil := prop.StartEpoch
+	if st.SectorStartEpoch >= 0 && st.LastUpdatedEpoch >= 0 {
+		paidUntil = st.LastUpdatedEpoch
+		if paidUntil > prop.EndEpoch {
+			paidUntil = prop.EndEpoch
+		}
+		if paidUntil < prop.StartEpoch {
+			paidUntil = prop.StartEpoch
+		}
+	}
+
+	from := prop.StartEpoch
+	if since > from {
+		from = since
+	}
+	realized = big.Zero()
+	if paidUntil > from {
+		realized = big.Mul(price, big.NewInt(int64(paidUntil-from)))
+	}
+
+	locked = big.Zero()
+	if st.SlashEpoch < 0 {
+		locked = big.Mul(price, big.NewInt(int64(prop.EndEpoch-paidUntil)))
+	}
+
+	return realized, locked
+}
+
+// Report computes the revenue of the provider from its deals in the market
+// actor state, as returned by StateMarketDeals. Deals which expired or were
+// terminated are removed from the state, so they aren't accounted for.
+func Report(deals map[string]api.MarketDeal, provider address.Address, since, epoch abi.ChainEpoch) (api.RevenueReport, error) {
+	out := api.RevenueReport{
+		Epoch:    epoch,
+		Since:    since,
+		Deals:    []api.DealRevenue{},
+		Realized: big.Zero(),
+		Locked:   big.Zero(),
+	}
+
+	for k, deal := range deals {
+		if deal.Proposal.Provider != provider {
+			continue
+		}
+
+		id, err := strconv.ParseUint(k, 10, 64)
+		if err != nil {
+			return api.RevenueReport{}, xerrors.Errorf("parsing deal id %q: %w", k, err)
+		}
+
+		realized, locked := ForDeal(deal, since)
+
+		out.Deals = append(out.Deals, api.DealRevenue{
+			DealID:        abi.DealID(id),
+			Client:        deal.Proposal.Client,
+			PieceCID:      deal.Proposal.PieceCID,
+			StartEpoch:    deal.Proposal.StartEpoch,
+			EndEpoch:      deal.Proposal.EndEpoch,
+			PricePerEpoch: deal.Proposal.StoragePricePerEpoch,
+			Active:        deal.State.SectorStartEpoch >= 0,
+			Slashed:       deal.State.SlashEpoch >= 0,
+			Realized:      realized,
+			Locked:        locked,
+		})
+
+		out.Realized = big.Add(out.Realized, realized)
+		out.Locked = big.Add(out.Locked, locked)
+	}
+
+	sort.Slice(out.Deals, func(i, j int) bool {
+		return out.Deals[i].DealID < out.Deals[j].DealID
+	})
+
+	return out, nil
+}
@@ -0,0 +1,105 @@
+package revenue
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
+	tutils "github.com/filecoin-project/specs-actors/v2/support/testing"
+
+	"github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/chain/actors/builtin/market"
+)
+
+func mkDeal(provider uint64, start, end, sectorStart, lastUpdated, slash abi.ChainEpoch) api.MarketDeal {
+	return api.MarketDeal{
+		Proposal: market.DealProposal{
+			Provider:             tutils.NewIDAddr(nil, provider),
+			Client:               tutils.NewIDAddr(nil, 200),
+			StartEpoch:           start,
+			EndEpoch:             end,
+			StoragePricePerEpoch: big.NewInt(10),
+		},
+		State: market.DealState{
+			SectorStartEpoch: sectorStart,
+			LastUpdatedEpoch: lastUpdated,
+			SlashEpoch:       slash,
+		},
+	}
+}
+
+func TestForDeal(t *testing.T) {
+	for name, tc := range map[string]struct {
+		deal             api.MarketDeal
+		since            abi.ChainEpoch
+		realized, locked int64
+	}{
+		"not activated": {
+			deal:   mkDeal(100, 100, 200, -1, -1, -1),
+			locked: 1000,
+		},
+		"activated, not paid yet": {
+			deal:   mkDeal(100, 100, 200, 90, -1, -1),
+			locked: 1000,
+		},
+		"paid in part": {
+			deal:     mkDeal(100, 100, 200, 90, 150, -1),
+			realized: 500,
+			locked:   500,
+		},
+		"paid in part since": {
+			deal:     mkDeal(100, 100, 200, 90, 150, -1),
+			since:    120,
+			realized: 300,
+			locked:   500,
+		},
+		"paid after since": {
+			deal:   mkDeal(100, 100, 200, 90, 150, -1),
+			since:  160,
+			locked: 500,
+		},
+		"paid in full": {
+			deal:     mkDeal(100, 100, 200, 90, 250, -1),
+			realized: 1000,
+		},
+		"slashed": {
+			deal:     mkDeal(100, 100, 200, 90, 130, 130),
+			realized: 300,
+		},
+	} {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			realized, locked := ForDeal(tc.deal, tc.since)
+			require.Equal(t, big.NewInt(tc.realized), realized)
+			require.Equal(t, big.NewInt(tc.locked), locked)
+		})
+	}
+}
+
+func TestReport(t *testing.T) {
+	provider := tutils.NewIDAddr(nil, 100)
+
+	deals := map[string]api.MarketDeal{
+		"7": mkDeal(100, 100, 200, 90, 150, -1),
+		"3": mkDeal(100, 100, 200, -1, -1, -1),
+		// another provider's deal
+		"5": mkDeal(101, 100, 200, 90, 150, -1),
+	}
+
+	rep, err := Report(deals, provider, 0, 150)
+	require.NoError(t, err)
+	require.EqualValues(t, 150, rep.Epoch)
+	require.Len(t, rep.Deals, 2)
+	require.EqualValues(t, 3, rep.Deals[0].DealID)
+	require.False(t, rep.Deals[0].Active)
+	require.EqualValues(t, 7, rep.Deals[1].DealID)
+	require.True(t, rep.Deals[1].Active)
+	require.Equal(t, big.NewInt(500), rep.Realized)
+	require.Equal(t, big.NewInt(1500), rep.Locked)
+
+	deals["x"] = mkDeal(100, 100, 200, 90, 150, -1)
+	_, err = Report(deals, provider, 0, 150)
+	require.Error(t, err)
+}
@@ -0,0 +1,149 @@
+// Package sealeta implements a small libp2p protocol through which storage
+// clients can ask a provider when the sector holding their deal is expected to
+// be sealed and proving.
+package sealeta
+
+import (
+	"context"
+	"time"
+
+	logging "github.com/ipfs/go-log/v2"
+	"github.com/libp2p/go-libp2p-core/host"
+	inet "github.com/libp2p/go-libp2p-core/network"
+	"github.com/libp2p/go-libp2p-core/peer"
+	"golang.org/x/xerrors"
+
+	cborutil "github.com/filecoin-project/go-cbor-util"
+	"github.com/filecoin-project/go-state-types/abi"
+
+	sealing "github.com/filecoin-project/lotus/extern/storage-sealing"
+)
+
+var log = logging.Logger("sealeta")
+
+const ProtocolID = "/fil/storage/sealeta/1.0.0"
+
+const streamDeadline = 30 * time.Second
+
+type Status uint64
+
+const (
+	StatusOk Status = iota
+	// the provider doesn't have the deal in any sector
+	StatusNotFound
+	// the deal was found, but the ETA can't be estimated, e.g. because the
+	// sector has failed or is being removed
+	StatusNoETA
+	StatusError
+)
+
+func (s Status) String() string {
+	switch s {
+	case StatusOk:
+		return "Ok"
+	case StatusNotFound:
+		return "NotFound"
+	case StatusNoETA:
+		return "NoETA"
+	case StatusError:
+		return "Error"
+	default:
+		return "Unknown"
+	}
+}
+
+type Request struct {
+	DealID abi.DealID
+}
+
+type Response struct {
+	Status       Status
+	ErrorMessage string
+
+	Sector abi.SectorNumber
+	ETA    abi.ChainEpoch
+}
+
+// ETAProvider estimates when the sector holding a deal will be proving
+type ETAProvider interface {
+	DealSealETA(ctx context.Context, deal abi.DealID) (abi.SectorNumber, abi.ChainEpoch, error)
+}
+
+// Service answers seal ETA queries on the provider side
+type Service struct {
+	eta ETAProvider
+}
+
+func NewService(eta ETAProvider) *Service {
+	return &Service{eta: eta}
+}
+
+func (s *Service) HandleStream(stream inet.Stream) {
+	defer stream.Close() //nolint:errcheck
+
+	_ = stream.SetDeadline(time.Now().Add(streamDeadline))
+
+	var req Request
+	if err := cborutil.ReadCborRPC(stream, &req); err != nil {
+		log.Warnw("failed to read seal ETA request", "peer", stream.Conn().RemotePeer(), "error", err)
+		return
+	}
+
+	resp := s.processRequest(context.TODO(), &req)
+
+	if err := cborutil.WriteCborRPC(stream, resp); err != nil {
+		log.Warnw("failed to write seal ETA response", "peer", stream.Conn().RemotePeer(), "error", err)
+	}
+}
+
+func (s *Service) processRequest(ctx context.Context, req *Request) *Response {
+	sector, eta, err := s.eta.DealSealETA(ctx, req.DealID)
+	switch {
+	case err == nil:
+		return &Response{
+			Status: StatusOk,
+			Sector: sector,
+			ETA:    eta,
+		}
+	case xerrors.Is(err, sealing.ErrDealNotFound):
+		return &Response{
+			Status:       StatusNotFound,
+			ErrorMessage: err.Error(),
+		}
+	case xerrors.Is(err, sealing.ErrNoSealETA):
+		return &Response{
+			Status:       StatusNoETA,
+			ErrorMessage: err.Error(),
+			Sector:       sector,
+		}
+	default:
+		log.Errorw("estimating deal seal ETA", "deal", req.DealID, "error", err)
+		return &Response{
+			Status:       StatusError,
+			ErrorMessage: "internal error",
+		}
+	}
+}
+
+// Query asks the provider for the sealing ETA of the sector containing the
+// specified deal
+func Query(ctx context.Context, h host.Host, p peer.ID, deal abi.DealID) (*Response, error) {
+	stream, err := h.NewStream(ctx, p, ProtocolID)
+	if err != nil {
+		return nil, xerrors.Errorf("opening seal ETA stream: %w", err)
+	}
+	defer stream.Close() //nolint:errcheck
+
+	_ = stream.SetDeadline(time.Now().Add(streamDeadline))
+
+	if err := cborutil.WriteCborRPC(stream, &Request{DealID: deal}); err != nil {
+		return nil, xerrors.Errorf("writing seal ETA request: %w", err)
+	}
+
+	var resp Response
+	if err := cborutil.ReadCborRPC(stream, &resp); err != nil {
+		return nil, xerrors.Errorf("reading seal ETA response: %w", err)
+	}
+
+	return &resp, nil
+}
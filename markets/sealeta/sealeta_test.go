@@ -0,0 +1,49 @@
+package sealeta
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-state-types/abi"
+
+	sealing "github.com/filecoin-project/lotus/extern/storage-sealing"
+)
+
+type testETAProvider struct {
+	sector abi.SectorNumber
+	eta    abi.ChainEpoch
+	err    error
+}
+
+func (p *testETAProvider) DealSealETA(ctx context.Context, deal abi.DealID) (abi.SectorNumber, abi.ChainEpoch, error) {
+	return p.sector, p.eta, p.err
+}
+
+func TestProcessRequest(t *testing.T) {
+	ctx := context.Background()
+
+	p := &testETAProvider{sector: 3, eta: 1234}
+	s := NewService(p)
+
+	resp := s.processRequest(ctx, &Request{DealID: 1})
+	require.Equal(t, StatusOk, resp.Status)
+	require.Equal(t, abi.SectorNumber(3), resp.Sector)
+	require.Equal(t, abi.ChainEpoch(1234), resp.ETA)
+
+	p.err = xerrors.Errorf("deal 1: %w", sealing.ErrDealNotFound)
+	resp = s.processRequest(ctx, &Request{DealID: 1})
+	require.Equal(t, StatusNotFound, resp.Status)
+
+	p.err = xerrors.Errorf("sector in state Removing: %w", sealing.ErrNoSealETA)
+	resp = s.processRequest(ctx, &Request{DealID: 1})
+	require.Equal(t, StatusNoETA, resp.Status)
+	require.Equal(t, abi.SectorNumber(3), resp.Sector)
+
+	p.err = xerrors.New("datastore on fire")
+	resp = s.processRequest(ctx, &Request{DealID: 1})
+	require.Equal(t, StatusError, resp.Status)
+	require.NotContains(t, resp.ErrorMessage, "fire")
+}
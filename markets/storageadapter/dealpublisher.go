@@ -14,9 +14,11 @@ import (
 	"github.com/filecoin-project/go-address"
 	"github.com/filecoin-project/go-state-types/abi"
 	"github.com/filecoin-project/go-state-types/big"
+	"github.com/filecoin-project/go-state-types/exitcode"
 	market2 "github.com/filecoin-project/specs-actors/v2/actors/builtin/market"
 
 	"github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/build"
 	"github.com/filecoin-project/lotus/chain/actors"
 	"github.com/filecoin-project/lotus/chain/actors/builtin/market"
 	"github.com/filecoin-project/lotus/chain/actors/builtin/miner"
@@ -28,12 +30,21 @@ import (
 type dealPublisherAPI interface {
 	ChainHead(context.Context) (*types.TipSet, error)
 	MpoolPushMessage(ctx context.Context, msg *types.Message, spec *api.MessageSendSpec) (*types.SignedMessage, error)
+	GasEstimateGasLimit(context.Context, *types.Message, types.TipSetKey) (int64, error)
+	GasEstimateFeeCap(context.Context, *types.Message, int64, types.TipSetKey) (types.BigInt, error)
 	StateMinerInfo(context.Context, address.Address, types.TipSetKey) (miner.MinerInfo, error)
 
 	WalletBalance(context.Context, address.Address) (types.BigInt, error)
 	WalletHas(context.Context, address.Address) (bool, error)
 	StateAccountKey(context.Context, address.Address, types.TipSetKey) (address.Address, error)
 	StateLookupID(context.Context, address.Address, types.TipSetKey) (address.Address, error)
+	StateMarketBalance(context.Context, address.Address, types.TipSetKey) (api.MarketBalance, error)
+	MpoolGetNonce(context.Context, address.Address) (uint64, error)
+
+	MarketGetReserved(ctx context.Context, addr address.Address) (types.BigInt, error)
+	MarketReserveFunds(ctx context.Context, wallet address.Address, addr address.Address, amt types.BigInt) (cid.Cid, error)
+	MarketReleaseFunds(ctx context.Context, addr address.Address, amt types.BigInt) error
+	StateWaitMsg(ctx context.Context, cid cid.Cid, confidence uint64, limit abi.ChainEpoch, allowReplaced bool) (*api.MsgLookup, error)
 }
 
 // DealPublisher batches deal publishing so that many deals can be included in
@@ -54,11 +65,18 @@ type DealPublisher struct {
 	maxDealsPerPublishMsg uint64
 	publishPeriod         time.Duration
 	publishSpec           *api.MessageSendSpec
+	maxFee                abi.TokenAmount // see storage.CheckMaxFee
+	rejectedDealAction    string
+	providerEscrowAction  string
+	escrowTopUpWallet     address.Address
+	maxPublishRetries     int
+	retryBackoff          time.Duration
 
 	lk                     sync.Mutex
 	pending                []*pendingDeal
 	cancelWaitForMoreDeals context.CancelFunc
 	publishPeriodStart     time.Time
+	topUpInFlight          bool
 }
 
 // A deal that is queued to be published
@@ -66,6 +84,9 @@ type pendingDeal struct {
 	ctx    context.Context
 	deal   market2.ClientDealProposal
 	Result chan publishResult
+
+	// the number of times sending a publish message with the deal failed
+	attempts int
 }
 
 // The result of publishing a deal
@@ -89,8 +110,48 @@ type PublishMsgConfig struct {
 	// The maximum number of deals to include in a single PublishStorageDeals
 	// message
 	MaxDealsPerMsg uint64
+
+	// What to do with deals which would get the publish message rejected,
+	// RejectedDealFail or RejectedDealRetry
+	RejectedDealAction string
+
+	// What to do with deals the provider doesn't have enough funds in market
+	// escrow for, ProviderEscrowReject, ProviderEscrowTopUp or
+	// ProviderEscrowHold
+	ProviderEscrowAction string
+	// The wallet provider escrow is topped up from with ProviderEscrowTopUp,
+	// the worker address of the miner if empty
+	EscrowTopUpWallet string
+
+	// The number of times deals are requeued when sending the publish
+	// message fails, 0 = fail the deals right away
+	MaxPublishRetries int
 }
 
+// the time waited before requeueing deals after the first failed publish
+// message, doubled with every further attempt
+const defaultPublishRetryBackoff = time.Minute
+
+const (
+	// RejectedDealFail fails deals which would be rejected on chain
+	RejectedDealFail = "fail"
+	// RejectedDealRetry keeps deals which would be rejected on chain queued
+	// for the next publish message, until their start epoch passes
+	RejectedDealRetry = "retry"
+)
+
+const (
+	// ProviderEscrowReject handles deals the provider doesn't have enough
+	// escrow for like other deals which would be rejected on chain
+	ProviderEscrowReject = "reject"
+	// ProviderEscrowTopUp adds the missing funds to provider escrow from the
+	// top-up wallet, and keeps the deals queued until the funds land
+	ProviderEscrowTopUp = "topup"
+	// ProviderEscrowHold keeps the deals queued until provider escrow is
+	// topped up manually
+	ProviderEscrowHold = "hold"
+)
+
 func NewDealPublisher(
 	feeConfig *config.MinerFeeConfig,
 	publishMsgCfg PublishMsgConfig,
@@ -102,6 +163,9 @@ func NewDealPublisher(
 		}
 		publishSpec := &api.MessageSendSpec{MaxFee: maxFee}
 		dp := newDealPublisher(full, as, publishMsgCfg, publishSpec)
+		if feeConfig != nil {
+			dp.maxFee = abi.TokenAmount(feeConfig.MaxFee)
+		}
 		lc.Append(fx.Hook{
 			OnStop: func(ctx context.Context) error {
 				dp.Shutdown()
@@ -118,6 +182,36 @@ func newDealPublisher(
 	publishMsgCfg PublishMsgConfig,
 	publishSpec *api.MessageSendSpec,
 ) *DealPublisher {
+	rejectedAction := publishMsgCfg.RejectedDealAction
+	switch rejectedAction {
+	case RejectedDealFail, RejectedDealRetry:
+	case "":
+		rejectedAction = RejectedDealFail
+	default:
+		log.Warnf("unknown publish rejected deal action '%s', using '%s'", rejectedAction, RejectedDealFail)
+		rejectedAction = RejectedDealFail
+	}
+
+	escrowAction := publishMsgCfg.ProviderEscrowAction
+	switch escrowAction {
+	case ProviderEscrowReject, ProviderEscrowTopUp, ProviderEscrowHold:
+	case "":
+		escrowAction = ProviderEscrowReject
+	default:
+		log.Warnf("unknown provider escrow action '%s', using '%s'", escrowAction, ProviderEscrowReject)
+		escrowAction = ProviderEscrowReject
+	}
+
+	var topUpWallet address.Address
+	if publishMsgCfg.EscrowTopUpWallet != "" {
+		var err error
+		topUpWallet, err = address.NewFromString(publishMsgCfg.EscrowTopUpWallet)
+		if err != nil {
+			log.Errorf("parsing escrow top-up wallet '%s', using the worker address: %s", publishMsgCfg.EscrowTopUpWallet, err)
+			topUpWallet = address.Undef
+		}
+	}
+
 	ctx, cancel := context.WithCancel(context.Background())
 	return &DealPublisher{
 		api:                   dpapi,
@@ -127,6 +221,11 @@ func newDealPublisher(
 		maxDealsPerPublishMsg: publishMsgCfg.MaxDealsPerMsg,
 		publishPeriod:         publishMsgCfg.Period,
 		publishSpec:           publishSpec,
+		rejectedDealAction:    rejectedAction,
+		providerEscrowAction:  escrowAction,
+		escrowTopUpWallet:     topUpWallet,
+		maxPublishRetries:     publishMsgCfg.MaxPublishRetries,
+		retryBackoff:          defaultPublishRetryBackoff,
 	}
 }
 
@@ -286,6 +385,10 @@ func (p *DealPublisher) publishReady(ready []*pendingDeal) {
 	// Validate each deal to make sure it can be published
 	validated := make([]*pendingDeal, 0, len(ready))
 	deals := make([]market2.ClientDealProposal, 0, len(ready))
+	var retry, held []*pendingDeal
+	var provider address.Address
+	heldReq := big.Zero()
+	funds := newBatchFunds(p.ctx, p.api)
 	for _, pd := range ready {
 		// Validate the deal
 		if err := p.validateDeal(pd.deal); err != nil {
@@ -294,12 +397,72 @@ func (p *DealPublisher) publishReady(ready []*pendingDeal) {
 			continue
 		}
 
+		// A single deal the market actor rejects fails the whole message, so
+		// leave out deals it would reject
+		if err := funds.reserve(pd.deal.Proposal); err != nil {
+			var ble *balanceLookupError
+			if xerrors.As(err, &ble) {
+				log.Warnw("checking deal funds failed, retrying with the next publish message", "piece", pd.deal.Proposal.PieceCID, "error", err)
+				retry = append(retry, pd)
+				continue
+			}
+
+			var pfe *providerFundsError
+			if xerrors.As(err, &pfe) && p.providerEscrowAction != ProviderEscrowReject {
+				held = append(held, pd)
+				provider = pd.deal.Proposal.Provider
+				heldReq = big.Add(heldReq, pd.deal.Proposal.ProviderBalanceRequirement())
+				continue
+			}
+
+			if p.rejectedDealAction == RejectedDealRetry {
+				log.Warnw("deal would be rejected on chain, retrying with the next publish message", "piece", pd.deal.Proposal.PieceCID, "error", err)
+				retry = append(retry, pd)
+				continue
+			}
+
+			go onComplete(pd, cid.Undef, xerrors.Errorf("deal would be rejected on chain: %w", err))
+			continue
+		}
+
 		validated = append(validated, pd)
 		deals = append(deals, pd.deal)
 	}
 
+	// the provider escrow left after the published deals has to cover all
+	// held deals
+	missing := big.Zero()
+	if len(held) > 0 {
+		avail, err := funds.get(provider)
+		if err != nil {
+			log.Errorw("getting provider market balance", "provider", provider, "error", err)
+			avail = big.Zero()
+		}
+		missing = big.Sub(heldReq, avail)
+
+		log.Errorw("provider doesn't have enough funds in market escrow to publish deals, holding them",
+			"provider", provider, "missing", types.FIL(missing), "deals", len(held), "action", p.providerEscrowAction)
+	}
+
+	if len(retry) > 0 || len(held) > 0 {
+		p.lk.Lock()
+		p.pending = append(p.pending, retry...)
+		p.pending = append(p.pending, held...)
+		p.waitForMoreDeals()
+
+		if len(held) > 0 && p.providerEscrowAction == ProviderEscrowTopUp && !p.topUpInFlight {
+			p.topUpInFlight = true
+			go p.topUpEscrow(provider, heldReq)
+		}
+		p.lk.Unlock()
+	}
+
 	// Send the publish message
 	msgCid, err := p.publishDealProposals(deals)
+	if err != nil && p.maxPublishRetries > 0 {
+		validated = p.retryPublish(validated, err)
+		err = xerrors.Errorf("publishing deals failed after %d attempts: %w", p.maxPublishRetries+1, err)
+	}
 
 	// Signal that each deal has been published
 	for _, pd := range validated {
@@ -307,6 +470,106 @@ func (p *DealPublisher) publishReady(ready []*pendingDeal) {
 	}
 }
 
+// retryPublish requeues deals of a publish message which couldn't be sent,
+// once a backoff which doubles with every attempt elapses. The deals which
+// ran out of retries are returned.
+func (p *DealPublisher) retryPublish(deals []*pendingDeal, err error) []*pendingDeal {
+	var failed []*pendingDeal
+	retry := map[int][]*pendingDeal{}
+	for _, pd := range deals {
+		if pd.attempts >= p.maxPublishRetries {
+			failed = append(failed, pd)
+			continue
+		}
+
+		pd.attempts++
+		retry[pd.attempts] = append(retry[pd.attempts], pd)
+	}
+
+	for attempt, pds := range retry {
+		backoff := p.retryBackoff << (attempt - 1)
+		log.Warnw("sending publish deals message failed, retrying", "deals", len(pds), "attempt", attempt, "max", p.maxPublishRetries, "backoff", backoff, "error", err)
+
+		pds := pds
+		time.AfterFunc(backoff, func() {
+			p.lk.Lock()
+			defer p.lk.Unlock()
+
+			if p.ctx.Err() != nil {
+				return
+			}
+
+			p.pending = append(p.pending, pds...)
+			p.publishAllDeals()
+		})
+	}
+
+	return failed
+}
+
+// topUpEscrow reserves the funds required by the held deals with the funds
+// manager, which adds what's missing to provider escrow, and publishes the
+// deals waiting for them once the funds landed on chain. Reserving instead of
+// adding to escrow directly keeps the top-up in line with the funds reserved
+// for deals by the storage provider.
+func (p *DealPublisher) topUpEscrow(provider address.Address, amt abi.TokenAmount) {
+	defer func() {
+		p.lk.Lock()
+		p.topUpInFlight = false
+		p.lk.Unlock()
+	}()
+
+	wallet := p.escrowTopUpWallet
+	if wallet == address.Undef {
+		mi, err := p.api.StateMinerInfo(p.ctx, provider, types.EmptyTSK)
+		if err != nil {
+			log.Errorw("getting miner info for escrow top-up", "provider", provider, "error", err)
+			return
+		}
+		wallet = mi.Worker
+	}
+
+	if err := storage.CheckReserveMaxFee(p.ctx, p.api, wallet, provider, amt, p.maxFee); err != nil {
+		log.Errorw("topping up provider market escrow", "provider", provider, "wallet", wallet, "reserve", types.FIL(amt), "error", err)
+		return
+	}
+
+	log.Warnw("topping up provider market escrow to publish deals", "provider", provider, "wallet", wallet, "reserve", types.FIL(amt))
+
+	mcid, err := p.api.MarketReserveFunds(p.ctx, wallet, provider, amt)
+	if err != nil {
+		log.Errorw("reserving provider market escrow funds", "provider", provider, "wallet", wallet, "reserve", types.FIL(amt), "error", err)
+		return
+	}
+
+	// once the held deals are sent for publishing the funds are locked by
+	// the deals, or the deals are held again and reserve the funds again
+	defer func() {
+		if err := p.api.MarketReleaseFunds(p.ctx, provider, amt); err != nil {
+			log.Errorw("releasing provider market escrow funds", "provider", provider, "amount", types.FIL(amt), "error", err)
+		}
+	}()
+
+	// an undefined cid means escrow already covers the reserved funds
+	if mcid != cid.Undef {
+		lookup, err := p.api.StateWaitMsg(p.ctx, mcid, build.MessageConfidence, api.LookbackNoLimit, true)
+		if err != nil {
+			log.Errorw("waiting for provider market escrow top-up", "message", mcid, "error", err)
+			return
+		}
+		if lookup.Receipt.ExitCode != exitcode.Ok {
+			log.Errorw("provider market escrow top-up failed", "message", mcid, "exitcode", lookup.Receipt.ExitCode)
+			return
+		}
+	}
+
+	log.Infow("topped up provider market escrow, publishing held deals", "provider", provider, "message", mcid)
+
+	p.lk.Lock()
+	p.publishAllDeals()
+	p.lk.Unlock()
+}
+
 // validateDeal checks that the deal proposal start epoch hasn't already
 // elapsed
 func (p *DealPublisher) validateDeal(deal market2.ClientDealProposal) error {
@@ -322,6 +585,88 @@ func (p *DealPublisher) validateDeal(deal market2.ClientDealProposal) error {
 	return nil
 }
 
+// batchFunds tracks the market escrow funds available to clients and
+// providers while a publish message is being assembled, so that deals which
+// together need more funds than available aren't all included
+type batchFunds struct {
+	ctx context.Context
+	api dealPublisherAPI
+
+	available map[address.Address]abi.TokenAmount
+}
+
+func newBatchFunds(ctx context.Context, dpapi dealPublisherAPI) *batchFunds {
+	return &batchFunds{
+		ctx:       ctx,
+		api:       dpapi,
+		available: map[address.Address]abi.TokenAmount{},
+	}
+}
+
+// providerFundsError is returned by reserve when the provider of the deal
+// doesn't have enough funds left in escrow for it
+type providerFundsError struct {
+	err error
+}
+
+func (e *providerFundsError) Error() string {
+	return e.err.Error()
+}
+
+// balanceLookupError is returned by reserve when the market balances of the
+// deal couldn't be looked up, which says nothing about whether the market
+// actor would accept the deal
+type balanceLookupError struct {
+	err error
+}
+
+func (e *balanceLookupError) Error() string {
+	return e.err.Error()
+}
+
+// reserve checks that the client and provider of the deal have enough funds
+// left in escrow for the deal, and subtracts the deal's requirements
+func (b *batchFunds) reserve(deal market2.DealProposal) error {
+	clientAvail, err := b.get(deal.Client)
+	if err != nil {
+		return &balanceLookupError{err: xerrors.Errorf("getting client market balance: %w", err)}
+	}
+	providerAvail, err := b.get(deal.Provider)
+	if err != nil {
+		return &balanceLookupError{err: xerrors.Errorf("getting provider market balance: %w", err)}
+	}
+
+	clientReq := deal.ClientBalanceRequirement()
+	if clientAvail.LessThan(clientReq) {
+		return xerrors.Errorf("client %s has %s available in escrow, deal requires %s", deal.Client, types.FIL(clientAvail), types.FIL(clientReq))
+	}
+	providerReq := deal.ProviderBalanceRequirement()
+	if providerAvail.LessThan(providerReq) {
+		return &providerFundsError{
+			err: xerrors.Errorf("provider %s has %s available in escrow, deal requires %s", deal.Provider, types.FIL(providerAvail), types.FIL(providerReq)),
+		}
+	}
+
+	b.available[deal.Client] = big.Sub(clientAvail, clientReq)
+	b.available[deal.Provider] = big.Sub(providerAvail, providerReq)
+	return nil
+}
+
+func (b *batchFunds) get(addr address.Address) (abi.TokenAmount, error) {
+	if avail, ok := b.available[addr]; ok {
+		return avail, nil
+	}
+
+	bal, err := b.api.StateMarketBalance(b.ctx, addr, types.EmptyTSK)
+	if err != nil {
+		return abi.TokenAmount{}, err
+	}
+
+	avail := big.Sub(bal.Escrow, bal.Locked)
+	b.available[addr] = avail
+	return avail, nil
+}
+
 // Sends the publish message
 func (p *DealPublisher) publishDealProposals(deals []market2.ClientDealProposal) (cid.Cid, error) {
 	if len(deals) == 0 {
@@ -359,13 +704,19 @@ func (p *DealPublisher) publishDealProposals(deals []market2.ClientDealProposal)
 		return cid.Undef, xerrors.Errorf("selecting address for publishing deals: %w", err)
 	}
 
-	smsg, err := p.api.MpoolPushMessage(p.ctx, &types.Message{
+	msg := &types.Message{
 		To:     market.Address,
 		From:   addr,
 		Value:  types.NewInt(0),
 		Method: market.Methods.PublishStorageDeals,
 		Params: params,
-	}, p.publishSpec)
+	}
+
+	if err := storage.CheckMaxFee(p.ctx, p.api, msg, p.maxFee); err != nil {
+		return cid.Undef, xerrors.Errorf("publishing deals: %w", err)
+	}
+
+	smsg, err := p.api.MpoolPushMessage(p.ctx, msg, p.publishSpec)
 
 	if err != nil {
 		return cid.Undef, err
@@ -3,6 +3,7 @@ package storageadapter
 import (
 	"bytes"
 	"context"
+	"sync"
 	"testing"
 	"time"
 
@@ -11,6 +12,7 @@ import (
 	"github.com/ipfs/go-cid"
 
 	"github.com/stretchr/testify/require"
+	"golang.org/x/xerrors"
 
 	tutils "github.com/filecoin-project/specs-actors/v2/support/testing"
 
@@ -21,7 +23,9 @@ import (
 	market0 "github.com/filecoin-project/specs-actors/actors/builtin/market"
 
 	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
 	"github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/storage"
 )
 
 func TestDealPublisher(t *testing.T) {
@@ -172,6 +176,260 @@ func TestForcePublish(t *testing.T) {
 	checkPublishedDeals(t, dpapi, dealsToPublish, []int{2})
 }
 
+func TestPublishRejectedDeals(t *testing.T) {
+	for _, action := range []string{RejectedDealFail, RejectedDealRetry} {
+		action := action
+		t.Run(action, func(t *testing.T) {
+			dpapi := newDPAPI(t)
+			poorClient := tutils.NewActorAddr(t, "poorclient")
+			dpapi.setBalance(poorClient, api.MarketBalance{Escrow: big.Zero(), Locked: big.Zero()})
+
+			dp := newDealPublisher(dpapi, nil, PublishMsgConfig{
+				Period:             10 * time.Millisecond,
+				MaxDealsPerMsg:     5,
+				RejectedDealAction: action,
+			}, &api.MessageSendSpec{MaxFee: abi.NewTokenAmount(1)})
+
+			good1, good1Res := publishDealFrom(t, dp, getClientActor(t), big.Zero(), big.Zero())
+			bad, badRes := publishDealFrom(t, dp, poorClient, abi.NewTokenAmount(1), big.Zero())
+			good2, good2Res := publishDealFrom(t, dp, getClientActor(t), big.Zero(), big.Zero())
+
+			// the good deals get published without the bad one
+			checkPublishedDeals(t, dpapi, []market.ClientDealProposal{good1, good2}, []int{2})
+			require.NoError(t, <-good1Res)
+			require.NoError(t, <-good2Res)
+
+			switch action {
+			case RejectedDealFail:
+				require.Error(t, <-badRes)
+				require.Len(t, dp.PendingDeals().Deals, 0)
+			case RejectedDealRetry:
+				select {
+				case err := <-badRes:
+					t.Fatalf("deal should stay queued, got result: %v", err)
+				default:
+				}
+
+				// once the client adds funds the deal goes out with the next message
+				dpapi.setBalance(poorClient, api.MarketBalance{Escrow: abi.NewTokenAmount(1), Locked: big.Zero()})
+				checkPublishedDeals(t, dpapi, []market.ClientDealProposal{bad}, []int{1})
+				require.NoError(t, <-badRes)
+			}
+		})
+	}
+}
+
+func TestPublishBalanceLookupFailure(t *testing.T) {
+	dpapi := newDPAPI(t)
+	client := tutils.NewActorAddr(t, "flakyclient")
+	dpapi.setBalanceErr(client, xerrors.Errorf("connection refused"))
+
+	// with the default action a deal the market actor would reject fails
+	dp := newDealPublisher(dpapi, nil, PublishMsgConfig{
+		Period:         10 * time.Millisecond,
+		MaxDealsPerMsg: 5,
+	}, &api.MessageSendSpec{MaxFee: abi.NewTokenAmount(1)})
+
+	good, goodRes := publishDealFrom(t, dp, getClientActor(t), big.Zero(), big.Zero())
+	flaky, flakyRes := publishDealFrom(t, dp, client, big.Zero(), big.Zero())
+
+	checkPublishedDeals(t, dpapi, []market.ClientDealProposal{good}, []int{1})
+	require.NoError(t, <-goodRes)
+
+	// a failed balance lookup doesn't mean the deal would be rejected, it
+	// stays queued
+	select {
+	case err := <-flakyRes:
+		t.Fatalf("deal should stay queued, got result: %v", err)
+	default:
+	}
+
+	dpapi.setBalanceErr(client, nil)
+	checkPublishedDeals(t, dpapi, []market.ClientDealProposal{flaky}, []int{1})
+	require.NoError(t, <-flakyRes)
+}
+
+func TestPublishInsufficientProviderEscrow(t *testing.T) {
+	for _, action := range []string{ProviderEscrowTopUp, ProviderEscrowHold} {
+		action := action
+		t.Run(action, func(t *testing.T) {
+			dpapi := newDPAPI(t)
+			provider := getProviderActor(t)
+			dpapi.setBalance(provider, api.MarketBalance{Escrow: abi.NewTokenAmount(1), Locked: big.Zero()})
+
+			wallet := tutils.NewActorAddr(t, "topupwallet")
+			dp := newDealPublisher(dpapi, nil, PublishMsgConfig{
+				Period:               10 * time.Millisecond,
+				MaxDealsPerMsg:       5,
+				ProviderEscrowAction: action,
+				EscrowTopUpWallet:    wallet.String(),
+			}, &api.MessageSendSpec{MaxFee: abi.NewTokenAmount(1)})
+
+			good, goodRes := publishDealFrom(t, dp, getClientActor(t), big.Zero(), big.Zero())
+			held, heldRes := publishDealFrom(t, dp, getClientActor(t), big.Zero(), abi.NewTokenAmount(3))
+
+			// the deal the provider can't cover is held instead of failing
+			// the message
+			checkPublishedDeals(t, dpapi, []market.ClientDealProposal{good}, []int{1})
+			require.NoError(t, <-goodRes)
+
+			select {
+			case err := <-heldRes:
+				t.Fatalf("deal should be held, got result: %v", err)
+			default:
+			}
+
+			switch action {
+			case ProviderEscrowTopUp:
+				call := <-dpapi.reserveCalls
+				require.Equal(t, wallet, call.wallet)
+				require.Equal(t, provider, call.addr)
+				require.Equal(t, abi.NewTokenAmount(3), call.amt)
+			case ProviderEscrowHold:
+				select {
+				case <-dpapi.reserveCalls:
+					t.Fatal("escrow shouldn't be topped up")
+				default:
+				}
+
+				dpapi.setBalance(provider, api.MarketBalance{Escrow: abi.NewTokenAmount(3), Locked: big.Zero()})
+			}
+
+			checkPublishedDeals(t, dpapi, []market.ClientDealProposal{held}, []int{1})
+			require.NoError(t, <-heldRes)
+
+			if action == ProviderEscrowTopUp {
+				// the reservation is released once the held deals are sent
+				require.Eventually(t, func() bool {
+					return dpapi.reservedFunds(provider).IsZero()
+				}, time.Second, 10*time.Millisecond)
+			}
+		})
+	}
+}
+
+func TestPublishRetry(t *testing.T) {
+	newPublisher := func(dpapi *dpAPI, retries int) *DealPublisher {
+		dp := newDealPublisher(dpapi, nil, PublishMsgConfig{
+			Period:            10 * time.Millisecond,
+			MaxDealsPerMsg:    5,
+			MaxPublishRetries: retries,
+		}, &api.MessageSendSpec{MaxFee: abi.NewTokenAmount(1)})
+		dp.retryBackoff = 10 * time.Millisecond
+		return dp
+	}
+
+	t.Run("recovers", func(t *testing.T) {
+		dpapi := newDPAPI(t)
+		dpapi.setPushFails(2)
+		dp := newPublisher(dpapi, 3)
+
+		deal1, res1 := publishDealFrom(t, dp, getClientActor(t), big.Zero(), big.Zero())
+		deal2, res2 := publishDealFrom(t, dp, getClientActor(t), big.Zero(), big.Zero())
+
+		// the deals are published once sending the message succeeds
+		checkPublishedDeals(t, dpapi, []market.ClientDealProposal{deal1, deal2}, []int{2})
+		require.NoError(t, <-res1)
+		require.NoError(t, <-res2)
+	})
+
+	t.Run("runs out of retries", func(t *testing.T) {
+		dpapi := newDPAPI(t)
+		dpapi.setPushFails(-1)
+		dp := newPublisher(dpapi, 2)
+
+		_, res := publishDealFrom(t, dp, getClientActor(t), big.Zero(), big.Zero())
+
+		select {
+		case err := <-res:
+			require.Error(t, err)
+			require.Contains(t, err.Error(), "after 3 attempts")
+			require.Contains(t, err.Error(), "not enough funds for gas")
+		case <-time.After(5 * time.Second):
+			t.Fatal("deal didn't fail")
+		}
+
+		require.Empty(t, dp.PendingDeals().Deals)
+		require.Empty(t, dpapi.pushedMsgs)
+	})
+
+	t.Run("no retries", func(t *testing.T) {
+		dpapi := newDPAPI(t)
+		dpapi.setPushFails(1)
+		dp := newPublisher(dpapi, 0)
+
+		_, res := publishDealFrom(t, dp, getClientActor(t), big.Zero(), big.Zero())
+		err := <-res
+		require.Error(t, err)
+		require.NotContains(t, err.Error(), "attempts")
+	})
+}
+
+func TestPublishMaxFee(t *testing.T) {
+	newPublisher := func(dpapi *dpAPI, maxFee int64) *DealPublisher {
+		dp := newDealPublisher(dpapi, nil, PublishMsgConfig{
+			Period:         10 * time.Millisecond,
+			MaxDealsPerMsg: 5,
+		}, &api.MessageSendSpec{MaxFee: abi.NewTokenAmount(1)})
+		dp.maxFee = abi.NewTokenAmount(maxFee)
+		return dp
+	}
+
+	// the estimated fee is dpAPIGasLimit * dpAPIBaseFee
+	dpapi := newDPAPI(t)
+	dp := newPublisher(dpapi, dpAPIGasLimit*dpAPIBaseFee-1)
+
+	_, res := publishDealFrom(t, dp, getClientActor(t), big.Zero(), big.Zero())
+
+	select {
+	case err := <-res:
+		require.Error(t, err)
+		require.Contains(t, err.Error(), storage.ErrMaxFeeExceeded.Error())
+	case <-time.After(5 * time.Second):
+		t.Fatal("deal didn't fail")
+	}
+
+	require.Empty(t, dpapi.pushedMsgs)
+
+	// deals are published when the estimated fee is within the limit
+	dpapi = newDPAPI(t)
+	dp = newPublisher(dpapi, dpAPIGasLimit*dpAPIBaseFee)
+
+	deal, res := publishDealFrom(t, dp, getClientActor(t), big.Zero(), big.Zero())
+	checkPublishedDeals(t, dpapi, []market.ClientDealProposal{deal}, []int{1})
+	require.NoError(t, <-res)
+}
+
+func publishDealFrom(t *testing.T, dp *DealPublisher, client address.Address, clientCollateral, providerCollateral abi.TokenAmount) (market.ClientDealProposal, <-chan error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	deal := market.ClientDealProposal{
+		Proposal: market0.DealProposal{
+			PieceCID:             generateCids(1)[0],
+			Client:               client,
+			Provider:             getProviderActor(t),
+			StartEpoch:           abi.ChainEpoch(20),
+			EndEpoch:             abi.ChainEpoch(120),
+			StoragePricePerEpoch: big.Zero(),
+			ProviderCollateral:   providerCollateral,
+			ClientCollateral:     clientCollateral,
+		},
+		ClientSignature: crypto.Signature{
+			Type: crypto.SigTypeSecp256k1,
+			Data: []byte("signature data"),
+		},
+	}
+
+	res := make(chan error, 1)
+	go func() {
+		_, err := dp.Publish(ctx, deal)
+		res <- err
+	}()
+
+	return deal, res
+}
+
 func publishDeal(t *testing.T, dp *DealPublisher, ctxCancelled bool, expired bool) market.ClientDealProposal {
 	ctx, cancel := context.WithCancel(context.Background())
 	t.Cleanup(cancel)
@@ -193,6 +451,10 @@ func publishDeal(t *testing.T, dp *DealPublisher, ctxCancelled bool, expired boo
 			Provider:   getProviderActor(t),
 			StartEpoch: startEpoch,
 			EndEpoch:   abi.ChainEpoch(120),
+
+			StoragePricePerEpoch: big.Zero(),
+			ProviderCollateral:   big.Zero(),
+			ClientCollateral:     big.Zero(),
 		},
 		ClientSignature: crypto.Signature{
 			Type: crypto.SigTypeSecp256k1,
@@ -285,6 +547,16 @@ type dpAPI struct {
 
 	stateMinerInfoCalls chan address.Address
 	pushedMsgs          chan *types.Message
+	reserveCalls        chan reserveCall
+
+	balanceLk   sync.Mutex
+	balances    map[address.Address]api.MarketBalance
+	balanceErrs map[address.Address]error
+	reserved    map[address.Address]abi.TokenAmount
+
+	// the number of upcoming MpoolPushMessage calls to fail, -1 = all
+	pushLk    sync.Mutex
+	pushFails int
 }
 
 func newDPAPI(t *testing.T) *dpAPI {
@@ -293,6 +565,10 @@ func newDPAPI(t *testing.T) *dpAPI {
 		worker:              getWorkerActor(t),
 		stateMinerInfoCalls: make(chan address.Address, 128),
 		pushedMsgs:          make(chan *types.Message, 128),
+		reserveCalls:        make(chan reserveCall, 128),
+		balances:            map[address.Address]api.MarketBalance{},
+		balanceErrs:         map[address.Address]error{},
+		reserved:            map[address.Address]abi.TokenAmount{},
 	}
 }
 
@@ -315,11 +591,121 @@ func (d *dpAPI) StateMinerInfo(ctx context.Context, address address.Address, key
 	return miner.MinerInfo{Worker: d.worker}, nil
 }
 
+func (d *dpAPI) setPushFails(n int) {
+	d.pushLk.Lock()
+	defer d.pushLk.Unlock()
+	d.pushFails = n
+}
+
 func (d *dpAPI) MpoolPushMessage(ctx context.Context, msg *types.Message, spec *api.MessageSendSpec) (*types.SignedMessage, error) {
+	d.pushLk.Lock()
+	fail := d.pushFails != 0
+	if d.pushFails > 0 {
+		d.pushFails--
+	}
+	d.pushLk.Unlock()
+
+	if fail {
+		return nil, xerrors.Errorf("not enough funds for gas")
+	}
+
 	d.pushedMsgs <- msg
 	return &types.SignedMessage{Message: *msg}, nil
 }
 
+const (
+	dpAPIGasLimit = 1000
+	dpAPIBaseFee  = 100
+)
+
+func (d *dpAPI) GasEstimateGasLimit(ctx context.Context, msg *types.Message, tsk types.TipSetKey) (int64, error) {
+	return dpAPIGasLimit, nil
+}
+
+func (d *dpAPI) GasEstimateFeeCap(ctx context.Context, msg *types.Message, maxqueueblks int64, tsk types.TipSetKey) (types.BigInt, error) {
+	return types.NewInt(dpAPIBaseFee), nil
+}
+
+func (d *dpAPI) setBalance(a address.Address, bal api.MarketBalance) {
+	d.balanceLk.Lock()
+	defer d.balanceLk.Unlock()
+	d.balances[a] = bal
+}
+
+func (d *dpAPI) setBalanceErr(a address.Address, err error) {
+	d.balanceLk.Lock()
+	defer d.balanceLk.Unlock()
+	if err == nil {
+		delete(d.balanceErrs, a)
+		return
+	}
+	d.balanceErrs[a] = err
+}
+
+func (d *dpAPI) StateMarketBalance(ctx context.Context, a address.Address, key types.TipSetKey) (api.MarketBalance, error) {
+	d.balanceLk.Lock()
+	defer d.balanceLk.Unlock()
+
+	if err, ok := d.balanceErrs[a]; ok {
+		return api.MarketBalance{}, err
+	}
+	if bal, ok := d.balances[a]; ok {
+		return bal, nil
+	}
+	return api.MarketBalance{Escrow: types.FromFil(1000), Locked: big.Zero()}, nil
+}
+
+type reserveCall struct {
+	wallet, addr address.Address
+	amt          abi.TokenAmount
+}
+
+// MarketReserveFunds adds the reserved funds which aren't available in escrow
+// like the funds manager
+func (d *dpAPI) MarketReserveFunds(ctx context.Context, wallet, addr address.Address, amt types.BigInt) (cid.Cid, error) {
+	d.balanceLk.Lock()
+	reserved := big.Add(d.reserved[addr], amt)
+	d.reserved[addr] = reserved
+
+	mcid := cid.Undef
+	bal, ok := d.balances[addr]
+	if avail := big.Sub(bal.Escrow, bal.Locked); ok && reserved.GreaterThan(avail) {
+		bal.Escrow = big.Add(bal.Escrow, big.Sub(reserved, avail))
+		d.balances[addr] = bal
+		mcid = generateCids(1)[0]
+	}
+	d.balanceLk.Unlock()
+
+	d.reserveCalls <- reserveCall{wallet: wallet, addr: addr, amt: amt}
+	return mcid, nil
+}
+
+func (d *dpAPI) MarketGetReserved(ctx context.Context, addr address.Address) (types.BigInt, error) {
+	return d.reservedFunds(addr), nil
+}
+
+func (d *dpAPI) MarketReleaseFunds(ctx context.Context, addr address.Address, amt types.BigInt) error {
+	d.balanceLk.Lock()
+	defer d.balanceLk.Unlock()
+
+	d.reserved[addr] = big.Sub(d.reserved[addr], amt)
+	return nil
+}
+
+func (d *dpAPI) reservedFunds(addr address.Address) abi.TokenAmount {
+	d.balanceLk.Lock()
+	defer d.balanceLk.Unlock()
+
+	if r, ok := d.reserved[addr]; ok {
+		return r
+	}
+	return big.Zero()
+}
+
+func (d *dpAPI) StateWaitMsg(ctx context.Context, c cid.Cid, confidence uint64, limit abi.ChainEpoch, allowReplaced bool) (*api.MsgLookup, error) {
+	return &api.MsgLookup{Message: c}, nil
+}
+
 func (d *dpAPI) WalletBalance(ctx context.Context, a address.Address) (types.BigInt, error) {
 	panic("don't call me")
 }
@@ -336,6 +722,10 @@ func (d *dpAPI) StateLookupID(ctx context.Context, a address.Address, key types.
 	panic("don't call me")
 }
 
+func (d *dpAPI) MpoolGetNonce(ctx context.Context, a address.Address) (uint64, error) {
+	panic("don't call me")
+}
+
 func getClientActor(t *testing.T) address.Address {
 	return tutils.NewActorAddr(t, "client")
 }
@@ -16,6 +16,7 @@ import (
 	"github.com/filecoin-project/go-fil-markets/shared"
 	"github.com/filecoin-project/go-fil-markets/storagemarket"
 	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
 	"github.com/filecoin-project/go-state-types/crypto"
 	"github.com/filecoin-project/go-state-types/exitcode"
 	market2 "github.com/filecoin-project/specs-actors/v2/actors/builtin/market"
@@ -34,6 +35,7 @@ import (
 	"github.com/filecoin-project/lotus/node/config"
 	"github.com/filecoin-project/lotus/node/modules/dtypes"
 	"github.com/filecoin-project/lotus/node/modules/helpers"
+	"github.com/filecoin-project/lotus/storage"
 	"github.com/filecoin-project/lotus/storage/sectorblocks"
 )
 
@@ -52,15 +54,17 @@ type ProviderNodeAdapter struct {
 	ev   *events.Events
 
 	dealPublisher *DealPublisher
+	as            *storage.AddressSelector
 
 	addBalanceSpec              *api.MessageSendSpec
+	maxFee                      abi.TokenAmount // see storage.CheckMaxFee
 	maxDealCollateralMultiplier uint64
 	dsMatcher                   *dealStateMatcher
 	scMgr                       *SectorCommittedManager
 }
 
-func NewProviderNodeAdapter(fc *config.MinerFeeConfig, dc *config.DealmakingConfig) func(mctx helpers.MetricsCtx, lc fx.Lifecycle, dag dtypes.StagingDAG, secb *sectorblocks.SectorBlocks, full v1api.FullNode, dealPublisher *DealPublisher) storagemarket.StorageProviderNode {
-	return func(mctx helpers.MetricsCtx, lc fx.Lifecycle, dag dtypes.StagingDAG, secb *sectorblocks.SectorBlocks, full v1api.FullNode, dealPublisher *DealPublisher) storagemarket.StorageProviderNode {
+func NewProviderNodeAdapter(fc *config.MinerFeeConfig, dc *config.DealmakingConfig) func(mctx helpers.MetricsCtx, lc fx.Lifecycle, dag dtypes.StagingDAG, secb *sectorblocks.SectorBlocks, full v1api.FullNode, dealPublisher *DealPublisher, as *storage.AddressSelector) storagemarket.StorageProviderNode {
+	return func(mctx helpers.MetricsCtx, lc fx.Lifecycle, dag dtypes.StagingDAG, secb *sectorblocks.SectorBlocks, full v1api.FullNode, dealPublisher *DealPublisher, as *storage.AddressSelector) storagemarket.StorageProviderNode {
 		ctx := helpers.LifecycleCtx(mctx, lc)
 
 		ev := events.NewEvents(ctx, full)
@@ -71,10 +75,12 @@ func NewProviderNodeAdapter(fc *config.MinerFeeConfig, dc *config.DealmakingConf
 			secb:          secb,
 			ev:            ev,
 			dealPublisher: dealPublisher,
+			as:            as,
 			dsMatcher:     newDealStateMatcher(state.NewStatePredicates(state.WrapFastAPI(full))),
 		}
 		if fc != nil {
 			na.addBalanceSpec = &api.MessageSendSpec{MaxFee: abi.TokenAmount(fc.MaxMarketBalanceAddFee)}
+			na.maxFee = abi.TokenAmount(fc.MaxFee)
 		}
 		na.maxDealCollateralMultiplier = defaultMaxProviderCollateralMultiplier
 		if dc != nil {
@@ -190,10 +196,59 @@ func (n *ProviderNodeAdapter) SignBytes(ctx context.Context, signer address.Addr
 	return localSignature, nil
 }
 
+// ReserveFunds reserves provider collateral for a deal in the market actor
+// escrow of the miner, adding the missing funds from the wallet, which is the
+// worker address of the miner. With DealCollateralFromPublishControl set in
+// the address config, the wallet is picked from the deal publish control
+// addresses instead, and reserving fails if none of them can be used, rather
+// than falling back to the worker or owner like the address selector does.
 func (n *ProviderNodeAdapter) ReserveFunds(ctx context.Context, wallet, addr address.Address, amt abi.TokenAmount) (cid.Cid, error) {
+	if n.as != nil && n.as.DealCollateralFromPublishControl {
+		mi, err := n.StateMinerInfo(ctx, addr, types.EmptyTSK)
+		if err != nil {
+			return cid.Undef, xerrors.Errorf("getting miner info: %w", err)
+		}
+
+		wallet, _, err = n.as.AddressFor(ctx, n.FullNode, mi, api.DealPublishAddr, amt, big.Zero())
+		if err != nil {
+			return cid.Undef, xerrors.Errorf("selecting deal collateral address: %w", err)
+		}
+
+		if err := n.checkPublishControl(ctx, wallet); err != nil {
+			return cid.Undef, err
+		}
+	}
+
+	if err := storage.CheckReserveMaxFee(ctx, n.FullNode, wallet, addr, amt, n.maxFee); err != nil {
+		return cid.Undef, xerrors.Errorf("reserving deal collateral: %w", err)
+	}
+
 	return n.MarketReserveFunds(ctx, wallet, addr, amt)
 }
 
+// checkPublishControl checks that the wallet selected for deal collateral is
+// one of the configured deal publish control addresses
+func (n *ProviderNodeAdapter) checkPublishControl(ctx context.Context, wallet address.Address) error {
+	walletID, err := n.StateLookupID(ctx, wallet, types.EmptyTSK)
+	if err != nil {
+		return xerrors.Errorf("looking up deal collateral address %s: %w", wallet, err)
+	}
+
+	for _, ctl := range n.as.DealPublishControl {
+		ctlID, err := n.StateLookupID(ctx, ctl, types.EmptyTSK)
+		if err != nil {
+			log.Warnw("looking up deal publish control address", "address", ctl, "error", err)
+			continue
+		}
+
+		if ctlID == walletID {
+			return nil
+		}
+	}
+
+	return xerrors.Errorf("no deal publish control address can fund deal collateral (selected %s), check the DealPublishControl addresses, their balances, and that their keys are in the wallet", wallet)
+}
+
 func (n *ProviderNodeAdapter) ReleaseFunds(ctx context.Context, addr address.Address, amt abi.TokenAmount) error {
 	return n.MarketReleaseFunds(ctx, addr, amt)
 }
@@ -201,12 +256,18 @@ func (n *ProviderNodeAdapter) ReleaseFunds(ctx context.Context, addr address.Add
 // Adds funds with the StorageMinerActor for a storage participant.  Used by both providers and clients.
 func (n *ProviderNodeAdapter) AddFunds(ctx context.Context, addr address.Address, amount abi.TokenAmount) (cid.Cid, error) {
 	// (Provider Node API)
-	smsg, err := n.MpoolPushMessage(ctx, &types.Message{
+	msg := &types.Message{
 		To:     market.Address,
 		From:   addr,
 		Value:  amount,
 		Method: market.Methods.AddBalance,
-	}, n.addBalanceSpec)
+	}
+
+	if err := storage.CheckMaxFee(ctx, n.FullNode, msg, n.maxFee); err != nil {
+		return cid.Undef, err
+	}
+
+	smsg, err := n.MpoolPushMessage(ctx, msg, n.addBalanceSpec)
 	if err != nil {
 		return cid.Undef, err
 	}
@@ -62,7 +62,7 @@ func randTimeOffset(width time.Duration) time.Duration {
 
 // NewMiner instantiates a miner with a concrete WinningPoStProver and a miner
 // address (which can be different from the worker's address).
-func NewMiner(api v1api.FullNode, epp gen.WinningPoStProver, addr address.Address, sf *slashfilter.SlashFilter, j journal.Journal) *Miner {
+func NewMiner(api v1api.FullNode, epp gen.WinningPoStProver, addr address.Address, sf slashfilter.SlashFilter, j journal.Journal) *Miner {
 	arc, err := lru.NewARC(10000)
 	if err != nil {
 		panic(err)
@@ -115,12 +115,17 @@ type Miner struct {
 	stop     chan struct{}
 	stopping chan struct{}
 
+	// resume is non-nil while block production is paused, and gets closed
+	// when it's resumed
+	resume        chan struct{}
+	enabledChange time.Time
+
 	waitFunc waitFunc
 
 	// lastWork holds the last MiningBase we built upon.
 	lastWork *MiningBase
 
-	sf *slashfilter.SlashFilter
+	sf slashfilter.SlashFilter
 	// minedBlockHeights is a safeguard that caches the last heights we mined.
 	// It is consulted before publishing a newly mined block, for a sanity check
 	// intended to avoid slashings in case of a bug.
@@ -170,6 +175,55 @@ func (m *Miner) Stop(ctx context.Context) error {
 	}
 }
 
+// SetEnabled pauses or resumes block production at runtime. Other miner
+// operations, like proving, are not affected.
+func (m *Miner) SetEnabled(enabled bool) {
+	m.lk.Lock()
+	defer m.lk.Unlock()
+
+	if enabled == (m.resume == nil) {
+		return
+	}
+
+	if enabled {
+		log.Warn("resuming block production")
+		close(m.resume)
+		m.resume = nil
+	} else {
+		log.Warn("pausing block production")
+		m.resume = make(chan struct{})
+	}
+	m.enabledChange = build.Clock.Now()
+}
+
+// Enabled returns whether block production is enabled, and when that was last
+// changed
+func (m *Miner) Enabled() (bool, time.Time) {
+	m.lk.Lock()
+	defer m.lk.Unlock()
+
+	return m.resume == nil, m.enabledChange
+}
+
+// waitResumed blocks while block production is paused. It returns false if
+// the miner was stopped while waiting.
+func (m *Miner) waitResumed() bool {
+	m.lk.Lock()
+	resume := m.resume
+	m.lk.Unlock()
+
+	if resume == nil {
+		return true
+	}
+
+	select {
+	case <-resume:
+		return true
+	case <-m.stop:
+		return false
+	}
+}
+
 func (m *Miner) niceSleep(d time.Duration) bool {
 	select {
 	case <-build.Clock.After(d):
@@ -222,6 +276,10 @@ minerLoop:
 		default:
 		}
 
+		if !m.waitResumed() {
+			continue minerLoop
+		}
+
 		var base *MiningBase
 		var onDone func(bool, abi.ChainEpoch, error)
 		var injectNulls abi.ChainEpoch
@@ -324,6 +382,11 @@ minerLoop:
 					"block-time", btime, "time", build.Clock.Now(), "difference", build.Clock.Since(btime))
 			}
 
+			if enabled, _ := m.Enabled(); !enabled {
+				log.Warnw("block production paused while mining, dropping block", "height", b.Header.Height)
+				continue
+			}
+
 			if err := m.sf.MinedBlock(b.Header, base.TipSet.Height()+base.NullRounds); err != nil {
 				log.Errorf("<!!> SLASH FILTER ERROR: %s", err)
 				if os.Getenv("LOTUS_MINER_NO_SLASHFILTER") != "_yes_i_know_i_can_and_probably_will_lose_all_my_fil_and_power_" {
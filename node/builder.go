@@ -62,7 +62,11 @@ import (
 	"github.com/filecoin-project/lotus/lib/peermgr"
 	_ "github.com/filecoin-project/lotus/lib/sigs/bls"
 	_ "github.com/filecoin-project/lotus/lib/sigs/secp"
+	"github.com/filecoin-project/lotus/markets/dealdiag"
 	"github.com/filecoin-project/lotus/markets/dealfilter"
+	"github.com/filecoin-project/lotus/markets/pricing"
+	"github.com/filecoin-project/lotus/markets/quarantine"
+	"github.com/filecoin-project/lotus/markets/retrievalterms"
 	"github.com/filecoin-project/lotus/markets/storageadapter"
 	"github.com/filecoin-project/lotus/miner"
 	"github.com/filecoin-project/lotus/node/config"
@@ -139,7 +143,11 @@ const (
 	HandleMigrateProviderFundsKey
 	HandleDealsKey
 	HandleRetrievalKey
+	HandleSealETAKey
+	HandleRetrievalTermsKey
+	HandleRetrievalTransportsKey
 	RunSectorServiceKey
+	ConnectMarketsServiceKey
 
 	// daemon
 	ExtractApiKey
@@ -291,7 +299,7 @@ var ChainNode = Options(
 	Override(new(*peermgr.PeerMgr), peermgr.NewPeerMgr),
 
 	// Chain mining API dependencies
-	Override(new(*slashfilter.SlashFilter), modules.NewSlashFilter),
+	Override(new(slashfilter.SlashFilter), modules.NewSlashFilter),
 
 	// Service: Message Pool
 	Override(new(dtypes.DefaultMaxFeeFunc), modules.NewDefaultMaxFeeFunc),
@@ -325,6 +333,7 @@ var ChainNode = Options(
 	// Markets (storage)
 	Override(new(*market.FundManager), market.NewFundManager),
 	Override(new(dtypes.ClientDatastore), modules.NewClientDatastore),
+	Override(new(dtypes.ClientDealTemplatesDS), modules.NewClientDealTemplatesDS),
 	Override(new(storagemarket.StorageClient), modules.StorageClient),
 	Override(new(storagemarket.StorageClientNode), storageadapter.NewClientNodeAdapter),
 	Override(HandleMigrateClientFundsKey, modules.HandleMigrateClientFunds),
@@ -392,8 +401,10 @@ var MinerNode = Options(
 	Override(GetParamsKey, modules.GetParams),
 
 	// Mining / proving
-	Override(new(*slashfilter.SlashFilter), modules.NewSlashFilter),
-	Override(new(*storage.Miner), modules.StorageMiner(config.DefaultStorageMiner().Fees)),
+	Override(new(slashfilter.SlashFilter), modules.NewSlashFilter),
+	Override(new(*storage.FaultHistory), modules.NewFaultHistory),
+	Override(new(*storage.ParamsVerifier), modules.NewParamsVerifier),
+	Override(new(*storage.Miner), modules.StorageMiner(config.DefaultStorageMiner().Fees, config.DefaultStorageMiner().Proving)),
 	Override(new(*miner.Miner), modules.SetupBlockProducer),
 	Override(new(gen.WinningPoStProver), storage.NewWinningPoStProver),
 
@@ -403,12 +414,14 @@ var MinerNode = Options(
 	Override(new(dtypes.StagingMultiDstore), modules.StagingMultiDatastore),
 	Override(new(dtypes.StagingBlockstore), modules.StagingBlockstore),
 	Override(new(dtypes.StagingDAG), modules.StagingDAG),
-	Override(new(dtypes.StagingGraphsync), modules.StagingGraphsync(config.DefaultSimultaneousTransfers)),
+	Override(new(dtypes.StagingGraphsync), modules.StagingGraphsync(config.DefaultSimultaneousTransfers, false)),
 	Override(new(dtypes.ProviderPieceStore), modules.NewProviderPieceStore),
 	Override(new(*sectorblocks.SectorBlocks), sectorblocks.NewSectorBlocks),
 
 	// Markets (retrieval)
 	Override(new(sectorstorage.PieceProvider), sectorstorage.NewPieceProvider),
+	Override(new(*retrievalterms.Store), modules.NewRetrievalTermsStore),
+	Override(new(*pricing.TiersStore), modules.NewRetrievalAskTiersStore(config.DefaultStorageMiner().Dealmaking)),
 	Override(new(dtypes.RetrievalPricingFunc), modules.RetrievalPricingFunc(config.DealmakingConfig{
 		RetrievalPricing: &config.RetrievalPricing{
 			Strategy: config.RetrievalPricingDefaultMode,
@@ -418,18 +431,24 @@ var MinerNode = Options(
 	Override(new(sectorstorage.PieceProvider), sectorstorage.NewPieceProvider),
 	Override(new(retrievalmarket.RetrievalProvider), modules.RetrievalProvider),
 	Override(new(dtypes.RetrievalDealFilter), modules.RetrievalDealFilter(nil)),
+	Override(new(dtypes.RetrievalTransport), dtypes.RetrievalTransport{}),
 
 	Override(HandleRetrievalKey, modules.HandleRetrieval),
+	Override(HandleRetrievalTransportsKey, modules.HandleRetrievalTransports),
 
 	// Markets (storage)
 	Override(new(dtypes.ProviderDataTransfer), modules.NewProviderDAGServiceDataTransfer),
 	Override(new(*storedask.StoredAsk), modules.NewStorageAsk),
 	Override(new(dtypes.StorageDealFilter), modules.BasicDealFilter(nil)),
+	Override(new(*quarantine.Store), modules.NewQuarantineStore),
 	Override(new(storagemarket.StorageProvider), modules.StorageProvider),
 	Override(new(*storageadapter.DealPublisher), storageadapter.NewDealPublisher(nil, storageadapter.PublishMsgConfig{})),
 	Override(new(storagemarket.StorageProviderNode), storageadapter.NewProviderNodeAdapter(nil, nil)),
+	Override(new(*dealdiag.Recorder), modules.NewDealDiagnostics),
 	Override(HandleMigrateProviderFundsKey, modules.HandleMigrateProviderFunds),
 	Override(HandleDealsKey, modules.HandleDeals),
+	Override(HandleSealETAKey, modules.HandleSealETA),
+	Override(HandleRetrievalTermsKey, modules.HandleRetrievalTerms),
 
 	// Config (todo: get a real property system)
 	Override(new(dtypes.ConsiderOnlineStorageDealsConfigFunc), modules.NewConsiderOnlineStorageDealsConfigFunc),
@@ -452,6 +471,16 @@ var MinerNode = Options(
 	Override(new(dtypes.GetExpectedSealDurationFunc), modules.NewGetExpectedSealDurationFunc),
 	Override(new(dtypes.SetMaxDealStartDelayFunc), modules.NewSetMaxDealStartDelayFunc),
 	Override(new(dtypes.GetMaxDealStartDelayFunc), modules.NewGetMaxDealStartDelayFunc),
+	Override(new(dtypes.GetTargetSectorFillRatioFunc), modules.NewGetTargetSectorFillRatioFunc),
+	Override(new(dtypes.GetOpenSectorsFunc), modules.NewGetOpenSectorsFunc),
+	Override(new(dtypes.GetRequireClientEscrowFunc), modules.NewGetRequireClientEscrowFunc),
+	Override(new(dtypes.GetRequiredLabelPatternsFunc), modules.NewGetRequiredLabelPatternsFunc),
+	Override(new(dtypes.GetStartEpochCounterOfferSlackFunc), modules.NewGetStartEpochCounterOfferSlackFunc),
+	Override(new(dtypes.GetDealFilterCmdFunc), modules.NewGetDealFilterCmdFunc),
+	Override(new(*dealfilter.ReputationFilter), modules.NewReputationFilter),
+	Override(new(*dealfilter.ClientRateLimiter), modules.NewClientRateLimiter),
+	Override(new(*dealfilter.ClientFilter), modules.NewClientFilter),
+	Override(new(*dealfilter.RetrievalFilter), modules.NewRetrievalFilter),
 )
 
 // Online sets up basic libp2p node
@@ -550,6 +579,8 @@ func ConfigFullNode(c interface{}) Option {
 			),
 		),
 		Override(new(dtypes.Graphsync), modules.Graphsync(cfg.Client.SimultaneousTransfers)),
+		Override(new(dtypes.ClientRetrievalQueryConfig), modules.ClientRetrievalQueryConfig(cfg.Client)),
+		Override(new(dtypes.ClientImportConfig), modules.ClientImportConfig(cfg.Client)),
 
 		If(cfg.Metrics.HeadNotifs,
 			Override(HeadMetricsKey, metrics.SendHeadNotifs(cfg.Metrics.Nickname)),
@@ -587,30 +618,94 @@ func ConfigStorageMiner(c interface{}) Option {
 		return Error(xerrors.New("retrieval pricing policy must be either default or external"))
 	}
 
+	if cfg.Subsystems.RetrievalOnly && !cfg.Dealmaking.ConsiderOnlineRetrievalDeals && !cfg.Dealmaking.ConsiderOfflineRetrievalDeals {
+		return Error(xerrors.New("retrieval-only node must consider online or offline retrieval deals"))
+	}
+
+	if cfg.Subsystems.RetrievalOnly && cfg.Subsystems.SealerApiInfo == "" {
+		return Error(xerrors.New("retrieval-only node requires the sealer api info"))
+	}
+
+	if cfg.Subsystems.MarketsApiInfo != "" && cfg.Subsystems.EnableStorageMarket {
+		return Error(xerrors.New("markets api info is only used when the storage market is disabled"))
+	}
+
+	var dealFilter dtypes.StorageDealFilter
+	if cfg.Dealmaking.Filter != "" {
+		dealFilter = dealfilter.CliStorageDealFilter(cfg.Dealmaking.Filter)
+	}
+	if cfg.Dealmaking.ShadowFilter != "" {
+		dealFilter = dealfilter.ShadowStorageDealFilter(dealFilter, cfg.Dealmaking.ShadowFilter, nil)
+	}
+	if cfg.Dealmaking.MinPieceSize != 0 || cfg.Dealmaking.MaxPieceSize != 0 {
+		if cfg.Dealmaking.MaxPieceSize != 0 && cfg.Dealmaking.MinPieceSize > cfg.Dealmaking.MaxPieceSize {
+			return Error(xerrors.Errorf("dealmaking MinPieceSize (%d) is larger than MaxPieceSize (%d)", cfg.Dealmaking.MinPieceSize, cfg.Dealmaking.MaxPieceSize))
+		}
+
+		minSize, maxSize := abi.PaddedPieceSize(cfg.Dealmaking.MinPieceSize), abi.PaddedPieceSize(cfg.Dealmaking.MaxPieceSize)
+		dealFilter = dealfilter.ComposeStorageDealFilters(dealfilter.PieceSizeStorageDealFilter(minSize, maxSize), dealFilter)
+	}
+
 	return Options(
 		ConfigCommon(&cfg.Common),
 
-		If(cfg.Dealmaking.Filter != "",
-			Override(new(dtypes.StorageDealFilter), modules.BasicDealFilter(dealfilter.CliStorageDealFilter(cfg.Dealmaking.Filter))),
+		If(dealFilter != nil,
+			Override(new(dtypes.StorageDealFilter), modules.BasicDealFilter(dealFilter)),
 		),
 
 		If(cfg.Dealmaking.RetrievalFilter != "",
 			Override(new(dtypes.RetrievalDealFilter), modules.RetrievalDealFilter(dealfilter.CliRetrievalDealFilter(cfg.Dealmaking.RetrievalFilter))),
 		),
 
+		If(cfg.Dealmaking.HTTPRetrieval.ListenAddress != "",
+			Override(new(dtypes.RetrievalTransport), modules.HTTPRetrievalTransport(cfg.Dealmaking.HTTPRetrieval)),
+		),
+
 		Override(new(dtypes.RetrievalPricingFunc), modules.RetrievalPricingFunc(cfg.Dealmaking)),
+		Override(new(*pricing.TiersStore), modules.NewRetrievalAskTiersStore(cfg.Dealmaking)),
 
 		Override(new(*storageadapter.DealPublisher), storageadapter.NewDealPublisher(&cfg.Fees, storageadapter.PublishMsgConfig{
 			Period:         time.Duration(cfg.Dealmaking.PublishMsgPeriod),
 			MaxDealsPerMsg: cfg.Dealmaking.MaxDealsPerPublishMsg,
+
+			RejectedDealAction: cfg.Dealmaking.PublishRejectedDealAction,
+
+			ProviderEscrowAction: cfg.Dealmaking.PublishInsufficientEscrowAction,
+			EscrowTopUpWallet:    cfg.Dealmaking.EscrowTopUpWallet,
+
+			MaxPublishRetries: cfg.Dealmaking.MaxPublishRetries,
 		})),
 		Override(new(storagemarket.StorageProviderNode), storageadapter.NewProviderNodeAdapter(&cfg.Fees, &cfg.Dealmaking)),
 
-		Override(new(dtypes.StagingGraphsync), modules.StagingGraphsync(cfg.Dealmaking.SimultaneousTransfers)),
+		Override(new(dtypes.StagingGraphsync), modules.StagingGraphsync(cfg.Dealmaking.SimultaneousTransfers, cfg.Dealmaking.VerifyTransferBlocks)),
 
 		Override(new(sectorstorage.SealerConfig), cfg.Storage),
 		Override(new(*storage.AddressSelector), modules.AddressSelector(&cfg.Addresses)),
-		Override(new(*storage.Miner), modules.StorageMiner(cfg.Fees)),
+		Override(new(*storage.Miner), modules.StorageMiner(cfg.Fees, cfg.Proving)),
+
+		If(cfg.Subsystems.RetrievalOnly,
+			Override(new(modules.SealerAPI), modules.ConnectSealerService(cfg.Subsystems.SealerApiInfo)),
+			Override(new(dtypes.ProviderPieceStore), modules.SealerPieceStore),
+			Override(new(stores.SectorIndex), modules.SealerSectorIndex),
+			Override(new(sectorstorage.StorageAuth), modules.SealerStorageAuth(cfg.Subsystems.SealerApiInfo)),
+
+			Override(new(dtypes.StorageDealFilter), modules.RetrievalOnlyDealFilter()),
+			Override(new(sectorstorage.SealerConfig), modules.RetrievalOnlySealerConfig(cfg.Storage)),
+			Override(new(*storage.Miner), modules.RetrievalOnlyStorageMiner(cfg.Fees)),
+			Override(new(*miner.Miner), modules.DisabledBlockProducer),
+		),
+
+		// the storage provider isn't started, so the node doesn't serve the
+		// storage deal protocols
+		If(!cfg.Subsystems.EnableStorageMarket,
+			Unset(HandleDealsKey),
+		),
+		If(cfg.Subsystems.SharedSlashFilterPath != "",
+			Override(new(slashfilter.SlashFilter), modules.NewSharedSlashFilter(cfg.Subsystems.SharedSlashFilterPath)),
+		),
+		If(!cfg.Subsystems.EnableStorageMarket && cfg.Subsystems.MarketsApiInfo != "",
+			Override(ConnectMarketsServiceKey, modules.ConnectMarketsService(cfg.Subsystems.MarketsApiInfo)),
+		),
 	)
 }
 
@@ -767,6 +862,13 @@ func Test() Option {
 	)
 }
 
+// TestBeacon replaces the randomness beacon with a mock beacon seeded with
+// the given seed, so that election outcomes are reproducible. Must be applied
+// after Test().
+func TestBeacon(seed []byte) Option {
+	return Override(new(beacon.Schedule), testing.DeterministicBeacon(seed))
+}
+
 // For 3rd party dep injection.
 
 func WithRepoType(repoType repo.RepoType) func(s *Settings) error {
@@ -55,6 +55,8 @@ type StorageMiner struct {
 	Storage    sectorstorage.SealerConfig
 	Fees       MinerFeeConfig
 	Addresses  MinerAddressConfig
+	Proving    ProvingConfig
+	Subsystems MinerSubsystemConfig
 }
 
 type DealmakingConfig struct {
@@ -66,6 +68,11 @@ type DealmakingConfig struct {
 	ConsiderUnverifiedStorageDeals bool
 	PieceCidBlocklist              []cid.Cid
 	ExpectedSealDuration           Duration
+	// When enabled, the expected seal duration is derived from how long it
+	// took recently sealed sectors to land on chain, instead of being set by
+	// ExpectedSealDuration. ExpectedSealDuration is still used until a few
+	// sectors were sealed, disable to set the duration manually.
+	AutoExpectedSealDuration bool
 	// Maximum amount of time proposed deal StartEpoch can be in future
 	MaxDealStartDelay Duration
 	// The amount of time to wait for more deals to arrive before
@@ -74,6 +81,29 @@ type DealmakingConfig struct {
 	// The maximum number of deals to include in a single PublishStorageDeals
 	// message
 	MaxDealsPerPublishMsg uint64
+	// What to do with deals which would get the whole PublishStorageDeals
+	// message rejected on chain, e.g. because the client doesn't have enough
+	// funds in market escrow. Such deals are always left out of the message,
+	// so that other deals in the batch still get published.
+	// "fail" (default) fails the deal, "retry" keeps it queued for the next
+	// publish message until its start epoch passes.
+	PublishRejectedDealAction string
+	// What to do with deals the provider doesn't have enough funds in market
+	// escrow for when publishing. "reject" (default) handles them like other
+	// deals which would be rejected, see PublishRejectedDealAction. "topup"
+	// adds the missing funds to escrow from EscrowTopUpWallet and publishes
+	// the deals once the funds land. "hold" keeps the deals queued and logs an
+	// error until escrow is topped up manually.
+	PublishInsufficientEscrowAction string
+	// The wallet provider market escrow is topped up from with the "topup"
+	// PublishInsufficientEscrowAction, the worker address if empty
+	EscrowTopUpWallet string
+	// The number of times deals are requeued for the next publish message
+	// when sending the PublishStorageDeals message fails, e.g. because the
+	// publishing wallet doesn't have enough funds for gas. The wait before
+	// each retry doubles, starting at a minute. Deals fail with the reason
+	// once they run out of retries.
+	MaxPublishRetries int
 	// The maximum collateral that the provider will put up against a deal,
 	// as a multiplier of the minimum collateral bound
 	MaxProviderCollateralMultiplier uint64
@@ -81,12 +111,155 @@ type DealmakingConfig struct {
 	// The maximum number of parallel online data transfers (storage+retrieval)
 	SimultaneousTransfers uint64
 
+	// Minimum fraction of the space a deal would take up in a sector accepting
+	// deals that has to be deal data rather than alignment padding. Deals which
+	// only fit open sectors with more waste are rejected, deals which don't fit
+	// any open sector are always considered. 0 disables the check.
+	TargetSectorFillRatio float64
+
+	// When set, proposals which would start before the deal can be sealed are
+	// rejected with a counter-offer of a start epoch the deal would be
+	// accepted with, this much later than the earliest epoch the deal can be
+	// sealed by, to leave the client time to re-propose the deal, but no
+	// later than MaxDealStartDelay from the current epoch. Clients can
+	// accept the counter-offer with ClientAcceptStartEpochCounterOffer.
+	// 0 rejects such proposals without a counter-offer.
+	StartEpochCounterOfferSlack Duration
+
+	// Reject deals from clients which don't have enough funds available in
+	// market escrow to pay for the deal and its client collateral, with a
+	// message naming the available and required amounts. The storage market
+	// already rejects most of these proposals when validating them, before
+	// the deal filters run; this check catches the rest at acceptance.
+	RequireClientEscrow bool
+
+	// Regular expressions the label of a deal proposal has to match, e.g. to
+	// only accept deals tagged with an allowed region for data-residency
+	// compliance. Proposals are accepted when the label matches at least one
+	// of the patterns. Empty accepts any label.
+	RequiredLabelPatterns []string
+
+	// The smallest and largest padded piece size in bytes of deals to
+	// accept, e.g. to reject tiny deals which aren't worth their overhead, or
+	// to reserve sector space by capping the size of deals. 0 disables the
+	// bound.
+	MinPieceSize uint64
+	MaxPieceSize uint64
+
+	// When enabled, blocks received in inbound transfers are checked as they
+	// arrive, aborting the transfer on the first malformed block rather than
+	// failing commP verification once the whole piece was transferred
+	VerifyTransferBlocks bool
+
+	// How long the staged data of deals which failed after the data was
+	// transferred is kept in the deal-quarantine directory for inspection,
+	// instead of being deleted right away. Data transferred over graphsync is
+	// kept as a CAR file of its blocks. 0 disables.
+	FailedDealRetention Duration
+
+	// Reject deals from clients which have a low score in an external
+	// reputation service
+	ClientReputation ClientReputationConfig
+
+	// Limit how many deals are accepted from each client within a time
+	// window
+	ClientRateLimit ClientRateLimitConfig
+
+	// Accept or reject deals based on the client proposing them, and cap the
+	// size of deals accepted from each client
+	ClientFilter ClientFilterConfig
+
 	Filter          string
 	RetrievalFilter string
 
+	// Reject retrievals based on their price and whether serving them needs
+	// a sector to be unsealed
+	RetrievalRules RetrievalRulesConfig
+
+	// Accept retrieval terms which storage clients set for their deals
+	ClientRetrievalTerms ClientRetrievalTermsConfig
+
+	// Serve retrievals over HTTP besides graphsync
+	HTTPRetrieval HTTPRetrievalConfig
+
+	// A deal filter command which is run against storage deals for
+	// observation only. Its decisions are never acted on, but deals it decides
+	// on differently than the active filter are logged, which allows trying
+	// out a new filter before switching to it.
+	ShadowFilter string
+
 	RetrievalPricing *RetrievalPricing
 }
 
+type ClientReputationConfig struct {
+	// URL of the reputation service. It is queried with the client address in
+	// the `client` query parameter, and must respond with a JSON object with a
+	// numeric `Score` field. Empty disables reputation checks.
+	Endpoint string
+	// Deals from clients with a score below this are rejected
+	MinScore float64
+	// How long a score fetched from the reputation service is reused for
+	CacheTTL Duration
+	// Clients with at least this many deals in local sectors are accepted
+	// without querying the reputation service. 0 disables.
+	TrustedDealCount int
+}
+
+type ClientRateLimitConfig struct {
+	// Maximum number of deals accepted from a single client within Window.
+	// 0 disables the limit.
+	MaxDeals int
+	// Length of the window deals are counted in
+	Window Duration
+}
+
+type ClientFilterConfig struct {
+	// Addresses of the only clients deals are accepted from. Empty accepts
+	// deals from all clients not in Denylist.
+	Allowlist []string
+	// Addresses of clients deals are never accepted from
+	Denylist []string
+	// Maximum total padded size in bytes of deals accepted from a single
+	// client within Window, including the proposed deal. Deals which failed
+	// or were rejected aren't counted. 0 disables the cap.
+	MaxBytes uint64
+	// Length of the rolling window deal sizes are summed over
+	Window Duration
+}
+
+type RetrievalRulesConfig struct {
+	// Retrievals offering a lower price per byte are rejected, e.g.
+	// "0.0000000001 FIL". 0 accepts any price.
+	MinPricePerByte types.FIL
+	// Reject retrievals of pieces which don't have an unsealed copy, and
+	// which would require unsealing a sector to serve
+	RequireUnsealed bool
+}
+
+type HTTPRetrievalConfig struct {
+	// Address the HTTP retrieval server listens on, e.g. "0.0.0.0:8080".
+	// Retrieval clients find the server through the provider and fetch the
+	// CAR of a payload from it instead of making a graphsync retrieval deal.
+	// Payloads are served to anyone free of charge: retrieval pricing,
+	// payment channels and retrieval filters don't apply, only
+	// ConsiderOnlineRetrievalDeals does. Empty disables.
+	ListenAddress string
+	// URL advertised to retrieval clients, e.g. when the server is behind a
+	// reverse proxy. Defaults to http:// followed by the listen address.
+	PublicURL string
+}
+
+type ClientRetrievalTermsConfig struct {
+	// Accept retrieval terms from the clients of accepted deals, see the
+	// --retrieval-price-per-byte flag of `lotus client deal`. The terms price the
+	// retrievals of the deal data by the deal client until the deal ends,
+	// overriding the retrieval ask.
+	Accept bool
+	// Terms with a lower price per byte or unseal price are rejected
+	MinPricePerByte types.FIL
+	MinUnsealPrice  types.FIL
+}
+
 type RetrievalPricing struct {
 	Strategy string // possible values: "default", "external"
 
@@ -108,6 +281,94 @@ type RetrievalPricingDefault struct {
 	VerifiedDealsFreeTransfer bool
 }
 
+type ProvingConfig struct {
+	// Start generating the WindowPoSt proof for the next deadline as soon as
+	// its challenge is available, even if the proof for the current deadline
+	// is still being computed. Proofs are still only submitted once their
+	// deadline opens. Needs enough resources to compute two WindowPoSt proofs
+	// at the same time.
+	LookaheadProving bool
+
+	// Don't declare faulty sectors recovered when their files are provable
+	// again. By default, ahead of each deadline the sealed and cache files of
+	// the faulty sectors in it are checked, and the provable ones are
+	// declared recovered. When disabled, sectors stay faulty until this is
+	// enabled again.
+	DisableAutoRecovery bool
+
+	// Maximum number of partitions to prove in a single SubmitWindowPoSt
+	// message, 0 = as many as the network allows. Deadlines with more
+	// partitions are proven in multiple messages. Lower values keep the
+	// messages of large miners well below the block gas limit.
+	//
+	// The partitions of each message are proven together, and messages are
+	// proven one after another, so lowering this also limits how much
+	// proving work runs at once on nodes with few GPUs.
+	MaxPartitionsPerPoStMessage int
+
+	// Maximum number of partitions to declare recoveries in with a single
+	// DeclareFaultsRecovered message, 0 = all in one message
+	MaxPartitionsPerRecoveryMessage int
+
+	// Maximum number of partitions to declare faults in with a single
+	// DeclareFaults message, 0 = all in one message. Declaring many faults at
+	// once, e.g. after a disk failure, only takes one message per this many
+	// partitions.
+	MaxPartitionsPerFaultMessage int
+
+	// Maximum time the files of a single sector are checked for before
+	// WindowPoSt, 0 = no limit. Sectors which take longer, e.g. on stuck
+	// network storage, are considered faulty rather than holding up the
+	// proof of the whole deadline.
+	SingleCheckTimeout Duration
+	// Maximum time the sectors of a partition are checked for, 0 = no
+	// limit. Sectors which weren't checked in time are considered faulty.
+	PartitionCheckTimeout Duration
+
+	// How often to verify the checksums of the proof parameter files, 0 =
+	// never. Files which don't match are downloaded again. Verifying reads
+	// all parameter files, which are tens of GiB for 32GiB sectors. Workers
+	// verify their parameter files when started with --params-verify-interval.
+	ParamsVerifyInterval Duration
+}
+
+type MinerSubsystemConfig struct {
+	// Run the node as a retrieval gateway, which serves retrievals of the data
+	// in the sector storage of the sealing node for the miner actor, set with
+	// SealerApiInfo, but doesn't take storage deals, seal sectors, prove or
+	// mine blocks. Requires online or offline retrieval deals to be
+	// considered.
+	RetrievalOnly bool
+
+	// API info (token:multiaddr) of the sealing node for the miner actor, which
+	// a RetrievalOnly node shares the piecestore and the sector index with.
+	// The local storage paths of the node are attached to the sector index of
+	// the sealing node like the ones of workers. The token needs admin
+	// permissions to read sector data.
+	SealerApiInfo string
+
+	// Take storage deals on this node. When disabled the storage provider
+	// isn't started and the node doesn't serve the storage deal protocols,
+	// deals have to be taken by another node for the miner actor. Deals
+	// already taken by this node aren't processed further.
+	EnableStorageMarket bool
+
+	// API info (token:multiaddr) of the node taking storage deals for this
+	// miner when EnableStorageMarket is disabled. It's checked in the
+	// background to serve the same miner actor, failed checks are logged and
+	// don't keep the miner from starting.
+	MarketsApiInfo string
+
+	// Directory of a slash filter shared by the block producers of the miner
+	// actor, e.g. on a network filesystem supporting POSIX locks across hosts
+	// (NFSv4, or NFSv3 with lockd), which redundant miners for the actor must
+	// all set. Mined blocks are checked and recorded atomically across the
+	// miners, so only one of them produces a block which would be a consensus
+	// fault. When empty, the blocks are recorded in the metadata datastore of
+	// this node. Blocks recorded before switching filters aren't carried over.
+	SharedSlashFilterPath string
+}
+
 type SealingConfig struct {
 	// 0 = no limit
 	MaxWaitDealsSectors uint64
@@ -118,13 +379,44 @@ type SealingConfig struct {
 	// includes failed, 0 = no limit
 	MaxSealingSectorsForDeals uint64
 
+	// Maximum number of sectors in the precommit phase at once, from
+	// PreCommit1 until the precommit message lands on chain, which bounds
+	// the funds locked up in precommit deposits. Other sectors wait in
+	// PreCommit1. 0 = no limit
+	MaxPreCommitting uint64
+	// Maximum number of sectors in the commit phase at once, from computing
+	// the seal proof until the proof lands on chain. Other sectors wait in
+	// Committing. 0 = no limit
+	MaxCommitting uint64
+
 	WaitDealsDelay Duration
 
+	// ConservativePacking makes deals which don't fit into the sectors
+	// accepting deals wait for those sectors to start sealing, instead of
+	// creating a new deal sector right away. Sectors are filled up better, at
+	// the cost of deals waiting longer for a sector, up to WaitDealsDelay.
+	ConservativePacking bool
+
 	AlwaysKeepUnsealedCopy bool
 
+	// When AlwaysKeepUnsealedCopy is disabled, still keep the unsealed copy of
+	// pieces in verified deals
+	KeepUnsealedVerifiedDeals bool
+	// When AlwaysKeepUnsealedCopy is disabled, still keep the unsealed copy of
+	// deal pieces with a padded size of at least this many bytes, 0 = disabled
+	KeepUnsealedMinPieceSize uint64
+
 	// Run sector finalization before submitting sector proof to the chain
 	FinalizeEarly bool
 
+	// Seal new sectors with synthetic PoRep, which lowers the amount of data
+	// which has to be kept around between PreCommit and ProveCommit. Sealing
+	// new sectors will fail if the network doesn't support synthetic PoRep.
+	// No network known to this version of lotus supports it, and the proofs
+	// library can't seal with it yet, so for now this only works with mock
+	// proofs; keep it disabled.
+	UseSyntheticPoRep bool
+
 	// enable / disable precommit batching (takes effect after nv13)
 	BatchPreCommits bool
 	// maximum precommit batch size - batches will be sent immediately above this size
@@ -152,6 +444,38 @@ type SealingConfig struct {
 	TerminateBatchMin  uint64
 	TerminateBatchWait Duration
 
+	// Automatically extend the expiration of CC sectors which expire within
+	// AutoExtendWindow, to keep their power alive
+	AutoExtendSectors bool
+	// Also automatically extend sectors containing deals
+	AutoExtendDealSectors bool
+	// Sectors expiring sooner than this get extended
+	AutoExtendWindow Duration
+	// Extend sector expiration to this far from now, capped by the maximum
+	// sector lifetime and expiration extension allowed by the network
+	AutoExtendDuration Duration
+	// How often to check for sectors which need extending
+	AutoExtendCheckInterval Duration
+
+	// Keep sealed sectors in the FinalizeReady state, proven from sealing
+	// storage, and finalize them and move them to long-term storage in
+	// batches, instead of moving each sector as soon as it's committed
+	DeferFinalize bool
+	// Maximum number of sectors finalized in a batch, 0 = no limit
+	FinalizeBatchSize int
+	// How often a batch of sectors is finalized, 0 = only when flushed with
+	// `lotus-miner sectors batching finalize --flush`
+	FinalizeBatchInterval Duration
+
+	// How often to compare the state of each sector in the sealing state
+	// machine to its state on chain, logging discrepancies, 0 = never. Sectors
+	// committed on chain while still waiting for the seed or computing the
+	// commit proof are moved on to FinalizeSector, other discrepancies are
+	// only logged. Every run looks up each sector on chain, on miners with
+	// many sectors this puts a noticeable load on the chain node. The
+	// SectorsReconcileReport API runs a single check on demand.
+	ReconcileInterval Duration
+
 	// Keep this many sectors in sealing pipeline, start CC if needed
 	// todo TargetSealingSectors uint64
 
@@ -176,9 +500,19 @@ type MinerFeeConfig struct {
 	MaxCommitBatchGasFee    BatchFeeConfig
 
 	MaxTerminateGasFee     types.FIL
+	MaxExtendGasFee        types.FIL
 	MaxWindowPoStGasFee    types.FIL
+	MaxDeclareFaultsGasFee types.FIL
 	MaxPublishDealsFee     types.FIL
 	MaxMarketBalanceAddFee types.FIL
+
+	// MaxFee is a ceiling for the fee of all messages sent by the miner, at
+	// the base fee when the message is sent. Messages which would cost more
+	// fail with an error instead of being sent, the limits above only cap the
+	// fee of each message type. This includes the AddBalance messages the full
+	// node sends when deal collateral is reserved. WindowPoSt messages are
+	// exempt, an error is logged instead. 0 = disabled
+	MaxFee types.FIL
 }
 
 type MinerAddressConfig struct {
@@ -187,6 +521,14 @@ type MinerAddressConfig struct {
 	TerminateControl   []string
 	DealPublishControl []string
 
+	// DealCollateralFromPublishControl makes the markets node lock provider
+	// deal collateral in the market actor with funds from the
+	// DealPublishControl addresses, instead of the worker address. Needs the
+	// keys of the addresses in the wallet of the full node. Reserving funds
+	// for a deal fails when none of the addresses can be used; the worker and
+	// owner aren't used as a fallback.
+	DealCollateralFromPublishControl bool
+
 	// DisableOwnerFallback disables usage of the owner address for messages
 	// sent automatically
 	DisableOwnerFallback bool
@@ -195,6 +537,19 @@ type MinerAddressConfig struct {
 	// A control address that doesn't have enough funds will still be chosen
 	// over the worker address if this flag is set.
 	DisableWorkerFallback bool
+
+	// SelectionStrategy decides in which order the addresses usable for a
+	// message are tried, the first one with enough funds is used:
+	// "ordered" - in the configured order (default)
+	// "round-robin" - starting from the next address for every message
+	// "lowest-nonce" - starting from the address with the fewest sent messages
+	SelectionStrategy string
+	// Per message type overrides of SelectionStrategy, empty = SelectionStrategy
+	PreCommitStrategy   string
+	CommitStrategy      string
+	TerminateStrategy   string
+	DealPublishStrategy string
+	PoStStrategy        string
 }
 
 // API contains configs for API endpoint
@@ -251,6 +606,29 @@ type Client struct {
 	IpfsMAddr             string
 	IpfsUseForRetrieval   bool
 	SimultaneousTransfers uint64
+
+	// Timeout for a single retrieval query to a provider, 0 means no timeout
+	RetrievalQueryTimeout Duration
+	// How many times a retrieval query which failed, e.g. because the provider
+	// was briefly unreachable, is retried
+	RetrievalQueryRetries int
+	// Time to wait before the first retry, doubled for every subsequent one
+	RetrievalQueryBackoff Duration
+	// How many providers are queried at once when finding offers for data,
+	// so that querying many providers doesn't exhaust connections. 0 means
+	// no limit
+	RetrievalQueryMaxConcurrent int
+	// How long finding offers for data waits for providers to answer. The
+	// offers received until then are returned, 0 means waiting for all
+	// providers. Should be longer than a query with all its retries and
+	// backoffs takes, otherwise providers which only answer a retry are
+	// dropped
+	RetrievalFindDataTimeout Duration
+
+	// Number of workers hashing file chunks when importing a file with
+	// ClientImport, 0 or 1 imports serially. The resulting root CID doesn't
+	// depend on this setting
+	ImportParallelism int
 }
 
 type Wallet struct {
@@ -302,6 +680,14 @@ func DefaultFullNode() *FullNode {
 		},
 		Client: Client{
 			SimultaneousTransfers: DefaultSimultaneousTransfers,
+
+			RetrievalQueryTimeout: Duration(30 * time.Second),
+			RetrievalQueryRetries: 2,
+			RetrievalQueryBackoff: Duration(time.Second),
+
+			RetrievalQueryMaxConcurrent: 16,
+			// 3 attempts of 30s each, plus 1s and 2s of backoff
+			RetrievalFindDataTimeout: Duration(2 * time.Minute),
 		},
 		Chainstore: Chainstore{
 			EnableSplitstore: false,
@@ -316,6 +702,10 @@ func DefaultStorageMiner() *StorageMiner {
 	cfg := &StorageMiner{
 		Common: defCommon(),
 
+		Subsystems: MinerSubsystemConfig{
+			EnableStorageMarket: true,
+		},
+
 		Sealing: SealingConfig{
 			MaxWaitDealsSectors:       2, // 64G with 32G sectors
 			MaxSealingSectors:         0,
@@ -323,6 +713,7 @@ func DefaultStorageMiner() *StorageMiner {
 			WaitDealsDelay:            Duration(time.Hour * 6),
 			AlwaysKeepUnsealedCopy:    true,
 			FinalizeEarly:             false,
+			UseSyntheticPoRep:         false,
 
 			BatchPreCommits:     true,
 			MaxPreCommitBatch:   miner5.PreCommitSectorBatchMaxSize, // up to 256 sectors
@@ -340,6 +731,14 @@ func DefaultStorageMiner() *StorageMiner {
 			TerminateBatchMin:  1,
 			TerminateBatchMax:  100,
 			TerminateBatchWait: Duration(5 * time.Minute),
+
+			AutoExtendSectors:       false,
+			AutoExtendDealSectors:   false,
+			AutoExtendWindow:        Duration(14 * 24 * time.Hour),
+			AutoExtendDuration:      Duration(540 * 24 * time.Hour),
+			AutoExtendCheckInterval: Duration(time.Hour),
+
+			FinalizeBatchInterval: Duration(6 * time.Hour),
 		},
 
 		Storage: sectorstorage.SealerConfig{
@@ -370,10 +769,30 @@ func DefaultStorageMiner() *StorageMiner {
 			ExpectedSealDuration:            Duration(time.Hour * 24),
 			PublishMsgPeriod:                Duration(time.Hour),
 			MaxDealsPerPublishMsg:           8,
+			PublishRejectedDealAction:       "fail",
+			PublishInsufficientEscrowAction: "reject",
+			MaxPublishRetries:               3,
 			MaxProviderCollateralMultiplier: 2,
 
 			SimultaneousTransfers: DefaultSimultaneousTransfers,
 
+			ClientReputation: ClientReputationConfig{
+				CacheTTL: Duration(10 * time.Minute),
+			},
+			ClientRateLimit: ClientRateLimitConfig{
+				Window: Duration(time.Hour),
+			},
+			ClientFilter: ClientFilterConfig{
+				Window: Duration(24 * time.Hour),
+			},
+			RetrievalRules: RetrievalRulesConfig{
+				MinPricePerByte: types.MustParseFIL("0"),
+			},
+			ClientRetrievalTerms: ClientRetrievalTermsConfig{
+				MinPricePerByte: types.MustParseFIL("0"),
+				MinUnsealPrice:  types.MustParseFIL("0"),
+			},
+
 			RetrievalPricing: &RetrievalPricing{
 				Strategy: RetrievalPricingDefaultMode,
 				Default: &RetrievalPricingDefault{
@@ -399,9 +818,12 @@ func DefaultStorageMiner() *StorageMiner {
 			},
 
 			MaxTerminateGasFee:     types.MustParseFIL("0.5"),
+			MaxExtendGasFee:        types.MustParseFIL("0.5"),
 			MaxWindowPoStGasFee:    types.MustParseFIL("5"),
+			MaxDeclareFaultsGasFee: types.MustParseFIL("5"),
 			MaxPublishDealsFee:     types.MustParseFIL("0.05"),
 			MaxMarketBalanceAddFee: types.MustParseFIL("0.007"),
+			MaxFee:                 types.MustParseFIL("0"),
 		},
 
 		Addresses: MinerAddressConfig{
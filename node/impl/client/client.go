@@ -3,10 +3,13 @@ package client
 import (
 	"bufio"
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
 	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/filecoin-project/lotus/chain/actors/builtin/miner"
@@ -16,18 +19,23 @@ import (
 	"github.com/filecoin-project/go-padreader"
 	"github.com/filecoin-project/go-state-types/big"
 	"github.com/filecoin-project/go-state-types/dline"
+	blocks "github.com/ipfs/go-block-format"
 	"github.com/ipfs/go-blockservice"
 	"github.com/ipfs/go-cid"
 	"github.com/ipfs/go-cidutil"
+	"github.com/ipfs/go-datastore"
 	chunker "github.com/ipfs/go-ipfs-chunker"
 	offline "github.com/ipfs/go-ipfs-exchange-offline"
 	files "github.com/ipfs/go-ipfs-files"
 	ipld "github.com/ipfs/go-ipld-format"
+	logging "github.com/ipfs/go-log/v2"
 	"github.com/ipfs/go-merkledag"
 	unixfile "github.com/ipfs/go-unixfs/file"
 	"github.com/ipfs/go-unixfs/importer/balanced"
 	ihelper "github.com/ipfs/go-unixfs/importer/helpers"
 	"github.com/ipld/go-car"
+	ipldprime "github.com/ipld/go-ipld-prime"
+	"github.com/ipld/go-ipld-prime/codec/dagjson"
 	basicnode "github.com/ipld/go-ipld-prime/node/basic"
 	"github.com/ipld/go-ipld-prime/traversal/selector"
 	"github.com/ipld/go-ipld-prime/traversal/selector/builder"
@@ -58,6 +66,10 @@ import (
 	"github.com/filecoin-project/lotus/build"
 	"github.com/filecoin-project/lotus/chain/store"
 	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/lotus/markets/dealfilter"
+	"github.com/filecoin-project/lotus/markets/retrievalterms"
+	"github.com/filecoin-project/lotus/markets/retrievaltransport"
+	"github.com/filecoin-project/lotus/markets/sealeta"
 	"github.com/filecoin-project/lotus/markets/utils"
 	"github.com/filecoin-project/lotus/node/impl/full"
 	"github.com/filecoin-project/lotus/node/impl/paych"
@@ -66,11 +78,16 @@ import (
 	"github.com/filecoin-project/lotus/node/repo/retrievalstoremgr"
 )
 
+var log = logging.Logger("client")
+
 var DefaultHashFunction = uint64(mh.BLAKE2B_MIN + 31)
 
 // 8 days ~=  SealDuration + PreCommit + MaxProveCommitDuration + 8 hour buffer
 const dealStartBufferHours uint64 = 8 * 24
 
+// how long to wait for a proposed deal to show up in the local deal store
+const localDealWaitTimeout = 10 * time.Second
+
 type API struct {
 	fx.In
 
@@ -87,10 +104,15 @@ type API struct {
 	Imports dtypes.ClientImportMgr
 	Mds     dtypes.ClientMultiDstore
 
+	DealTemplates dtypes.ClientDealTemplatesDS
+
 	CombinedBstore    dtypes.ClientBlockstore // TODO: try to remove
 	RetrievalStoreMgr dtypes.ClientRetrievalStoreManager
 	DataTransfer      dtypes.ClientDataTransfer
 	Host              host.Host
+
+	QueryConfig  dtypes.ClientRetrievalQueryConfig `optional:"true"`
+	ImportConfig dtypes.ClientImportConfig         `optional:"true"`
 }
 
 func calcDealExpiration(minDuration uint64, md *dline.Info, startEpoch abi.ChainEpoch) abi.ChainEpoch {
@@ -119,6 +141,94 @@ func (a *API) ClientStatelessDeal(ctx context.Context, params *api.StartDealPara
 	return a.dealStarter(ctx, params, true)
 }
 
+func (a *API) ClientSaveDealTemplate(ctx context.Context, name string, params api.DealTemplate) error {
+	if name == "" {
+		return xerrors.New("deal template name must not be empty")
+	}
+
+	b, err := json.Marshal(params)
+	if err != nil {
+		return xerrors.Errorf("marshaling deal template: %w", err)
+	}
+
+	return a.DealTemplates.Put(datastore.NewKey(name), b)
+}
+
+func (a *API) ClientStartDealFromTemplate(ctx context.Context, name string, root cid.Cid) (*cid.Cid, error) {
+	b, err := a.DealTemplates.Get(datastore.NewKey(name))
+	if err != nil {
+		if xerrors.Is(err, datastore.ErrNotFound) {
+			return nil, xerrors.Errorf("deal template %s not found", name)
+		}
+		return nil, xerrors.Errorf("getting deal template %s: %w", name, err)
+	}
+
+	var tmpl api.DealTemplate
+	if err := json.Unmarshal(b, &tmpl); err != nil {
+		return nil, xerrors.Errorf("unmarshaling deal template %s: %w", name, err)
+	}
+
+	return a.ClientStartDeal(ctx, &api.StartDealParams{
+		Data: &storagemarket.DataRef{
+			TransferType: storagemarket.TTGraphsync,
+			Root:         root,
+		},
+		Wallet:             tmpl.Wallet,
+		Miner:              tmpl.Miner,
+		EpochPrice:         tmpl.EpochPrice,
+		MinBlocksDuration:  tmpl.MinBlocksDuration,
+		ProviderCollateral: tmpl.ProviderCollateral,
+		FastRetrieval:      tmpl.FastRetrieval,
+		VerifiedDeal:       tmpl.VerifiedDeal,
+	})
+}
+
+func (a *API) ClientAcceptStartEpochCounterOffer(ctx context.Context, propCid cid.Cid) (*cid.Cid, error) {
+	deal, err := a.SMDealClient.GetLocalDeal(ctx, propCid)
+	if err != nil {
+		return nil, xerrors.Errorf("getting deal %s: %w", propCid, err)
+	}
+
+	if !rejected(deal.State) {
+		return nil, xerrors.Errorf("deal %s wasn't rejected, it's in state %s", propCid, storagemarket.DealStates[deal.State])
+	}
+
+	offer, ok := startEpochCounterOffer(*deal)
+	if !ok {
+		return nil, xerrors.Errorf("provider didn't counter-offer a start epoch for deal %s: %s", propCid, deal.Message)
+	}
+
+	prop := deal.Proposal
+	return a.dealStarter(ctx, &api.StartDealParams{
+		Data:               deal.DataRef,
+		Wallet:             prop.Client,
+		Miner:              prop.Provider,
+		EpochPrice:         prop.StoragePricePerEpoch,
+		MinBlocksDuration:  uint64(prop.EndEpoch - prop.StartEpoch),
+		ProviderCollateral: prop.ProviderCollateral,
+		DealStartEpoch:     offer.StartEpoch,
+		FastRetrieval:      deal.FastRetrieval,
+		VerifiedDeal:       prop.VerifiedDeal,
+	}, false)
+}
+
+func rejected(st storagemarket.StorageDealStatus) bool {
+	switch st {
+	case storagemarket.StorageDealProposalRejected, storagemarket.StorageDealFailing, storagemarket.StorageDealError:
+		return true
+	}
+	return false
+}
+
+// startEpochCounterOffer returns the start epoch the provider counter-offered
+// when rejecting the deal
+func startEpochCounterOffer(deal storagemarket.ClientDeal) (dealfilter.StartEpochCounterOffer, bool) {
+	if !rejected(deal.State) {
+		return dealfilter.StartEpochCounterOffer{}, false
+	}
+	return dealfilter.ParseStartEpochCounterOffer(deal.Message)
+}
+
 func (a *API) dealStarter(ctx context.Context, params *api.StartDealParams, isStateless bool) (*cid.Cid, error) {
 	var storeID *multistore.StoreID
 	if isStateless {
@@ -219,6 +329,12 @@ func (a *API) dealStarter(ctx context.Context, params *api.StartDealParams, isSt
 			return nil, xerrors.Errorf("failed to start deal: %w", err)
 		}
 
+		// make sure the deal can be queried as soon as we return; the deal was
+		// proposed already, so only warn if it doesn't show up in time
+		if err := a.waitLocalDeal(ctx, result.ProposalCid); err != nil {
+			log.Warnw("proposed deal not tracked yet", "proposal", result.ProposalCid, "error", err)
+		}
+
 		return &result.ProposalCid, nil
 	}
 
@@ -347,6 +463,37 @@ func (a *API) transfersByID(ctx context.Context) (map[datatransfer.ChannelID]api
 	return dataTransfersByID, nil
 }
 
+// waitLocalDeal waits until the deal with the proposal CID is in the local
+// deal store. The deal state machine records new deals asynchronously, so
+// they may not be listed right after they're proposed; this waits for the
+// first event of the deal instead of polling the deal store.
+func (a *API) waitLocalDeal(ctx context.Context, propCid cid.Cid) error {
+	ctx, cancel := context.WithTimeout(ctx, localDealWaitTimeout)
+	defer cancel()
+
+	tracked := make(chan struct{})
+	var once sync.Once
+	unsub := a.SMDealClient.SubscribeToEvents(func(_ storagemarket.ClientEvent, deal storagemarket.ClientDeal) {
+		if deal.ProposalCid.Equals(propCid) {
+			once.Do(func() { close(tracked) })
+		}
+	})
+	defer unsub()
+
+	// subscribed before looking the deal up, so either the deal is stored
+	// already, or the event storing it is delivered to the subscriber
+	if _, err := a.SMDealClient.GetLocalDeal(ctx, propCid); err == nil {
+		return nil
+	}
+
+	select {
+	case <-tracked:
+		return nil
+	case <-ctx.Done():
+		return xerrors.Errorf("deal not in local deal store: %w", ctx.Err())
+	}
+}
+
 func (a *API) ClientGetDealInfo(ctx context.Context, d cid.Cid) (*api.DealInfo, error) {
 	v, err := a.SMDealClient.GetLocalDeal(ctx, d)
 	if err != nil {
@@ -357,6 +504,95 @@ func (a *API) ClientGetDealInfo(ctx context.Context, d cid.Cid) (*api.DealInfo,
 	return &di, nil
 }
 
+func (a *API) ClientDealSealETA(ctx context.Context, dealID abi.DealID) (abi.ChainEpoch, error) {
+	deal, err := a.StateMarketStorageDeal(ctx, dealID, types.EmptyTSK)
+	if err != nil {
+		return 0, xerrors.Errorf("getting deal %d: %w", dealID, err)
+	}
+
+	provider := deal.Proposal.Provider
+	mi, err := a.StateMinerInfo(ctx, provider, types.EmptyTSK)
+	if err != nil {
+		return 0, xerrors.Errorf("failed getting miner info: %w", err)
+	}
+	if mi.PeerId == nil || *mi.PeerId == peer.ID("SETME") {
+		return 0, xerrors.Errorf("provider %s has no peer ID set", provider)
+	}
+
+	info := utils.NewStorageProviderInfo(provider, mi.Worker, mi.SectorSize, *mi.PeerId, mi.Multiaddrs)
+	if len(info.Addrs) > 0 {
+		if err := a.Host.Connect(ctx, peer.AddrInfo{ID: info.PeerID, Addrs: info.Addrs}); err != nil {
+			return 0, xerrors.Errorf("connecting to provider %s: %w", provider, err)
+		}
+	}
+
+	resp, err := sealeta.Query(ctx, a.Host, info.PeerID, dealID)
+	if err != nil {
+		return 0, xerrors.Errorf("querying provider %s: %w", provider, err)
+	}
+	if resp.Status != sealeta.StatusOk {
+		return 0, xerrors.Errorf("provider %s returned status %s: %s", provider, resp.Status, resp.ErrorMessage)
+	}
+
+	return resp.ETA, nil
+}
+
+func (a *API) ClientSetDealRetrievalTerms(ctx context.Context, proposalCid cid.Cid, pricePerByte, unsealPrice abi.TokenAmount) error {
+	deal, err := a.SMDealClient.GetLocalDeal(ctx, proposalCid)
+	if err != nil {
+		return xerrors.Errorf("getting deal %s: %w", proposalCid, err)
+	}
+
+	walletKey, err := a.StateAccountKey(ctx, deal.Proposal.Client, types.EmptyTSK)
+	if err != nil {
+		return xerrors.Errorf("failed resolving deal client addr (%s): %w", deal.Proposal.Client, err)
+	}
+
+	req := &retrievalterms.Request{
+		Terms: retrievalterms.Terms{
+			ProposalCid:  proposalCid,
+			PricePerByte: pricePerByte,
+			UnsealPrice:  unsealPrice,
+		},
+	}
+
+	data, err := req.Terms.SigningBytes()
+	if err != nil {
+		return xerrors.Errorf("serializing retrieval terms: %w", err)
+	}
+
+	req.Signature, err = a.WalletSign(ctx, walletKey, data)
+	if err != nil {
+		return xerrors.Errorf("failed to sign retrieval terms: %w", err)
+	}
+
+	provider := deal.Proposal.Provider
+	mi, err := a.StateMinerInfo(ctx, provider, types.EmptyTSK)
+	if err != nil {
+		return xerrors.Errorf("failed getting miner info: %w", err)
+	}
+	if mi.PeerId == nil || *mi.PeerId == peer.ID("SETME") {
+		return xerrors.Errorf("provider %s has no peer ID set", provider)
+	}
+
+	info := utils.NewStorageProviderInfo(provider, mi.Worker, mi.SectorSize, *mi.PeerId, mi.Multiaddrs)
+	if len(info.Addrs) > 0 {
+		if err := a.Host.Connect(ctx, peer.AddrInfo{ID: info.PeerID, Addrs: info.Addrs}); err != nil {
+			return xerrors.Errorf("connecting to provider %s: %w", provider, err)
+		}
+	}
+
+	resp, err := retrievalterms.Send(ctx, a.Host, info.PeerID, req)
+	if err != nil {
+		return xerrors.Errorf("sending retrieval terms to provider %s: %w", provider, err)
+	}
+	if resp.Status != retrievalterms.StatusOk {
+		return xerrors.Errorf("provider %s returned status %s: %s", provider, resp.Status, resp.ErrorMessage)
+	}
+
+	return nil
+}
+
 func (a *API) ClientGetDealUpdates(ctx context.Context) (<-chan api.DealInfo, error) {
 	updates := make(chan api.DealInfo)
 
@@ -393,6 +629,11 @@ func (a *API) newDealInfo(ctx context.Context, v storagemarket.ClientDeal) api.D
 }
 
 func (a *API) newDealInfoWithTransfer(transferCh *api.DataTransferChannel, v storagemarket.ClientDeal) api.DealInfo {
+	var counterOffer abi.ChainEpoch
+	if offer, ok := startEpochCounterOffer(v); ok {
+		counterOffer = offer.StartEpoch
+	}
+
 	return api.DealInfo{
 		ProposalCid:       v.ProposalCid,
 		DataRef:           v.DataRef,
@@ -408,6 +649,8 @@ func (a *API) newDealInfoWithTransfer(transferCh *api.DataTransferChannel, v sto
 		Verified:          v.Proposal.VerifiedDeal,
 		TransferChannelID: v.TransferChannelID,
 		DataTransfer:      transferCh,
+
+		StartEpochCounterOffer: counterOffer,
 	}
 }
 
@@ -426,19 +669,92 @@ func (a *API) ClientHasLocal(ctx context.Context, root cid.Cid) (bool, error) {
 }
 
 func (a *API) ClientFindData(ctx context.Context, root cid.Cid, piece *cid.Cid) ([]api.QueryOffer, error) {
+	parent := ctx
+	if a.QueryConfig.FindDataTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, a.QueryConfig.FindDataTimeout)
+		defer cancel()
+	}
+
+	offers, err := a.ClientFindDataAsync(ctx, root, piece)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]api.QueryOffer, 0, cap(offers))
+	for {
+		// the caller went away, nobody is interested in the partial offers
+		if parent.Err() != nil {
+			return nil, parent.Err()
+		}
+
+		// once timed out, don't pick up offers of the cancelled queries
+		if ctx.Err() != nil {
+			log.Warnw("finding data timed out, returning partial offers", "root", root, "offers", len(out), "providers", cap(offers))
+			return out, nil
+		}
+
+		select {
+		case offer, ok := <-offers:
+			if !ok {
+				return out, nil
+			}
+			out = append(out, offer)
+		case <-ctx.Done():
+		}
+	}
+}
+
+func (a *API) ClientFindDataAsync(ctx context.Context, root cid.Cid, piece *cid.Cid) (<-chan api.QueryOffer, error) {
 	peers, err := a.RetDiscovery.GetPeers(root)
 	if err != nil {
 		return nil, err
 	}
 
-	out := make([]api.QueryOffer, 0, len(peers))
+	matching := make([]rm.RetrievalPeer, 0, len(peers))
 	for _, p := range peers {
 		if piece != nil && !piece.Equals(*p.PieceCID) {
 			continue
 		}
-		out = append(out, a.makeRetrievalQuery(ctx, p, root, piece, rm.QueryParams{}))
+		matching = append(matching, p)
 	}
 
+	// buffered so that queries never block on a reader which went away
+	out := make(chan api.QueryOffer, len(matching))
+
+	// limits how many providers are queried at once
+	var throttle chan struct{}
+	if a.QueryConfig.MaxConcurrent > 0 {
+		throttle = make(chan struct{}, a.QueryConfig.MaxConcurrent)
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(len(matching))
+	for _, p := range matching {
+		go func(p rm.RetrievalPeer) {
+			defer wg.Done()
+
+			if throttle != nil {
+				select {
+				case throttle <- struct{}{}:
+				case <-ctx.Done():
+					out <- api.QueryOffer{Err: xerrors.Errorf("waiting to query provider: %w", ctx.Err()).Error(), Miner: p.Address, MinerPeer: p}
+					return
+				}
+				defer func() {
+					<-throttle
+				}()
+			}
+
+			out <- a.makeRetrievalQuery(ctx, p, root, piece, rm.QueryParams{})
+		}(p)
+	}
+
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+
 	return out, nil
 }
 
@@ -455,7 +771,9 @@ func (a *API) ClientMinerQueryOffer(ctx context.Context, miner address.Address,
 }
 
 func (a *API) makeRetrievalQuery(ctx context.Context, rp rm.RetrievalPeer, payload cid.Cid, piece *cid.Cid, qp rm.QueryParams) api.QueryOffer {
-	queryResponse, err := a.Retrieval.Query(ctx, rp, payload, qp)
+	queryResponse, err := queryWithRetry(ctx, a.QueryConfig, func(ctx context.Context) (rm.QueryResponse, error) {
+		return a.Retrieval.Query(ctx, rp, payload, qp)
+	})
 	if err != nil {
 		return api.QueryOffer{Err: err.Error(), Miner: rp.Address, MinerPeer: rp}
 	}
@@ -469,6 +787,11 @@ func (a *API) makeRetrievalQuery(ctx context.Context, rp rm.RetrievalPeer, paylo
 		errStr = fmt.Sprintf("retrieval query offer errored: %s", queryResponse.Message)
 	}
 
+	var transports []api.RetrievalTransport
+	if errStr == "" {
+		transports = a.queryTransports(ctx, rp)
+	}
+
 	return api.QueryOffer{
 		Root:                    payload,
 		Piece:                   piece,
@@ -480,6 +803,68 @@ func (a *API) makeRetrievalQuery(ctx context.Context, rp rm.RetrievalPeer, paylo
 		Miner:                   queryResponse.PaymentAddress, // TODO: check
 		MinerPeer:               rp,
 		Err:                     errStr,
+		Transports:              transports,
+	}
+}
+
+// queryTransports returns the transports the provider serves retrievals over
+// besides graphsync. Providers which don't advertise any, e.g. because they
+// don't support the protocol, only serve retrievals over graphsync.
+func (a *API) queryTransports(ctx context.Context, rp rm.RetrievalPeer) []api.RetrievalTransport {
+	ts, err := retrievaltransport.Query(ctx, a.Host, rp.ID)
+	if err != nil {
+		log.Debugw("querying retrieval transports", "miner", rp.Address, "peer", rp.ID, "error", err)
+		return nil
+	}
+
+	out := make([]api.RetrievalTransport, 0, len(ts))
+	for _, t := range ts {
+		out = append(out, api.RetrievalTransport{Name: t.Name, URL: t.URL})
+	}
+	return out
+}
+
+// httpTransport returns the HTTP transport listed in the order, nil if there
+// is none or the order retrieves a selected part of the DAG, which graphsync
+// transfers without the rest of the DAG
+func httpTransport(order api.RetrievalOrder) *api.RetrievalTransport {
+	if order.DataSelector != nil {
+		return nil
+	}
+	for _, t := range order.Transports {
+		if t.Name == retrievaltransport.HTTP {
+			t := t
+			return &t
+		}
+	}
+	return nil
+}
+
+// queryWithRetry runs a retrieval query, retrying with exponential backoff
+// when it fails, e.g. because the provider is briefly unreachable. Responses
+// from the provider, including unavailable / error responses, are final.
+func queryWithRetry(ctx context.Context, cfg dtypes.ClientRetrievalQueryConfig, query func(context.Context) (rm.QueryResponse, error)) (rm.QueryResponse, error) {
+	backoff := cfg.Backoff
+	for attempt := 0; ; attempt++ {
+		qctx, cancel := ctx, context.CancelFunc(func() {})
+		if cfg.Timeout > 0 {
+			qctx, cancel = context.WithTimeout(ctx, cfg.Timeout)
+		}
+
+		resp, err := query(qctx)
+		cancel()
+		if err == nil || attempt >= cfg.Retries {
+			return resp, err
+		}
+
+		log.Warnw("retrieval query failed, retrying", "attempt", attempt+1, "backoff", backoff, "error", err)
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return rm.QueryResponse{}, ctx.Err()
+		}
+		backoff *= 2
 	}
 }
 
@@ -699,9 +1084,48 @@ func (a *API) clientRetrieve(ctx context.Context, order api.RetrievalOrder, ref
 		}
 	}
 
+	sel := shared.AllSelector()
+	if order.DataSelector != nil {
+		var err error
+		sel, err = parseSelector(*order.DataSelector)
+		if err != nil {
+			finish(xerrors.Errorf("parsing data selector: %w", err))
+			return
+		}
+	}
+
 	var store retrievalstoremgr.RetrievalStore
 
-	if order.LocalStore == nil {
+	if ht := httpTransport(order); order.LocalStore == nil && ht != nil {
+		var err error
+		store, err = a.RetrievalStoreMgr.NewStore()
+		if err != nil {
+			finish(xerrors.Errorf("Error setting up new store: %w", err))
+			return
+		}
+
+		defer func() {
+			_ = a.RetrievalStoreMgr.ReleaseStore(store)
+		}()
+
+		received, err := retrievaltransport.Fetch(ctx, ht.URL, order.Root, store.DAGService())
+		if err != nil {
+			finish(xerrors.Errorf("retrieving over HTTP from %s: %w", ht.URL, err))
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			finish(xerrors.New("Retrieval Timed Out"))
+			return
+		case events <- marketevents.RetrievalEvent{
+			Event:         rm.ClientEventComplete,
+			Status:        rm.DealStatusCompleted,
+			BytesReceived: received,
+			FundsSpent:    big.Zero(),
+		}:
+		}
+	} else if order.LocalStore == nil {
 		if order.MinerPeer == nil || order.MinerPeer.ID == "" {
 			mi, err := a.StateMinerInfo(ctx, order.Miner, types.EmptyTSK)
 			if err != nil {
@@ -735,7 +1159,7 @@ func (a *API) clientRetrieve(ctx context.Context, order api.RetrievalOrder, ref
 
 		ppb := types.BigDiv(order.Total, types.NewInt(order.Size))
 
-		params, err := rm.NewParamsV1(ppb, order.PaymentInterval, order.PaymentIntervalIncrease, shared.AllSelector(), order.Piece, order.UnsealPrice)
+		params, err := rm.NewParamsV1(ppb, order.PaymentInterval, order.PaymentIntervalIncrease, sel, order.Piece, order.UnsealPrice)
 		if err != nil {
 			finish(xerrors.Errorf("Error in retrieval params: %s", err))
 			return
@@ -810,6 +1234,28 @@ func (a *API) clientRetrieve(ctx context.Context, order api.RetrievalOrder, ref
 
 	rdag := store.DAGService()
 
+	if order.DataSelector != nil {
+		// only the selected part of the DAG was retrieved, which generally
+		// isn't a complete file
+		if !ref.IsCAR {
+			finish(xerrors.Errorf("selective retrievals can only be exported as a CAR"))
+			return
+		}
+
+		f, err := os.OpenFile(ref.Path, os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			finish(err)
+			return
+		}
+		sc := car.NewSelectiveCar(ctx, &dagReadStore{ctx: ctx, dag: rdag}, []car.Dag{{Root: order.Root, Selector: sel}})
+		if err := sc.Write(f); err != nil {
+			finish(xerrors.Errorf("writing selective CAR: %w", err))
+			return
+		}
+		finish(f.Close())
+		return
+	}
+
 	if ref.IsCAR {
 		f, err := os.OpenFile(ref.Path, os.O_CREATE|os.O_WRONLY, 0644)
 		if err != nil {
@@ -929,6 +1375,30 @@ func (mrs *multiStoreRetrievalStore) DAGService() ipld.DAGService {
 	return mrs.store.DAG
 }
 
+// parseSelector decodes a dag-json encoded selector, checking it's valid
+func parseSelector(s api.Selector) (ipldprime.Node, error) {
+	nb := basicnode.Prototype.Any.NewBuilder()
+	if err := dagjson.Decoder(nb, strings.NewReader(string(s))); err != nil {
+		return nil, xerrors.Errorf("decoding selector: %w", err)
+	}
+	sel := nb.Build()
+
+	if _, err := selector.ParseSelector(sel); err != nil {
+		return nil, xerrors.Errorf("invalid selector: %w", err)
+	}
+	return sel, nil
+}
+
+// dagReadStore reads blocks of a retrieval store for writing selective CARs
+type dagReadStore struct {
+	ctx context.Context
+	dag ipld.DAGService
+}
+
+func (s *dagReadStore) Get(c cid.Cid) (blocks.Block, error) {
+	return s.dag.Get(s.ctx, c)
+}
+
 func (a *API) ClientQueryAsk(ctx context.Context, p peer.ID, miner address.Address) (*storagemarket.StorageAsk, error) {
 	mi, err := a.StateMinerInfo(ctx, miner, types.EmptyTSK)
 	if err != nil {
@@ -943,7 +1413,7 @@ func (a *API) ClientQueryAsk(ctx context.Context, p peer.ID, miner address.Addre
 	return ask, nil
 }
 
-func (a *API) ClientCalcCommP(ctx context.Context, inpath string) (*api.CommPRet, error) {
+func (a *API) ClientCalcCommP(ctx context.Context, inpath string) (api.DataCIDSize, error) {
 
 	// Hard-code the sector type to 32GiBV1_1, because:
 	// - ffiwrapper.GeneratePieceCIDFromFile requires a RegisteredSealProof
@@ -957,35 +1427,39 @@ func (a *API) ClientCalcCommP(ctx context.Context, inpath string) (*api.CommPRet
 
 	rdr, err := os.Open(inpath)
 	if err != nil {
-		return nil, err
+		return api.DataCIDSize{}, err
 	}
 	defer rdr.Close() //nolint:errcheck
 
 	stat, err := rdr.Stat()
 	if err != nil {
-		return nil, err
+		return api.DataCIDSize{}, err
 	}
 
 	// check that the data is a car file; if it's not, retrieval won't work
 	_, _, err = car.ReadHeader(bufio.NewReader(rdr))
 	if err != nil {
-		return nil, xerrors.Errorf("not a car file: %w", err)
+		return api.DataCIDSize{}, xerrors.Errorf("not a car file: %w", err)
 	}
 
 	if _, err := rdr.Seek(0, io.SeekStart); err != nil {
-		return nil, xerrors.Errorf("seek to start: %w", err)
+		return api.DataCIDSize{}, xerrors.Errorf("seek to start: %w", err)
 	}
 
+	// Same as the provider does when importing data for an offline deal: the
+	// CAR is padded to the next valid piece size, and commP is computed over
+	// the padded data
 	pieceReader, pieceSize := padreader.New(rdr, uint64(stat.Size()))
 	commP, err := ffiwrapper.GeneratePieceCIDFromFile(arbitraryProofType, pieceReader, pieceSize)
 
 	if err != nil {
-		return nil, xerrors.Errorf("computing commP failed: %w", err)
+		return api.DataCIDSize{}, xerrors.Errorf("computing commP failed: %w", err)
 	}
 
-	return &api.CommPRet{
-		Root: commP,
-		Size: pieceSize,
+	return api.DataCIDSize{
+		PayloadSize: stat.Size(),
+		PieceSize:   pieceSize.Padded(),
+		PieceCID:    commP,
 	}, nil
 }
 
@@ -1114,11 +1588,19 @@ func (a *API) clientImport(ctx context.Context, ref api.FileRef, store *multisto
 	}
 	prefix.MhType = DefaultHashFunction
 
+	var builder cid.Builder = prefix
+	if a.ImportConfig.Parallelism > 1 && stat.Mode().IsRegular() {
+		leaves := startLeafHasher(ctx, f, stat.Size(), int64(build.UnixfsChunkSize), prefix.WithCodec(cid.Raw).(cid.Prefix), a.ImportConfig.Parallelism)
+		defer leaves.stop()
+
+		builder = &parallelLeafBuilder{prefix: prefix, leaves: leaves}
+	}
+
 	params := ihelper.DagBuilderParams{
 		Maxlinks:  build.UnixfsLinksPerLevel,
 		RawLeaves: true,
 		CidBuilder: cidutil.InlineBuilder{
-			Builder: prefix,
+			Builder: builder,
 			Limit:   126,
 		},
 		Dagserv: bufDs,
@@ -1 +1,217 @@
 package client
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-address"
+	rm "github.com/filecoin-project/go-fil-markets/retrievalmarket"
+	"github.com/filecoin-project/go-state-types/big"
+
+	"github.com/filecoin-project/lotus/node/modules/dtypes"
+)
+
+var testQueryConfig = dtypes.ClientRetrievalQueryConfig{
+	Timeout: time.Second,
+	Retries: 2,
+	Backoff: time.Millisecond,
+}
+
+func TestQueryWithRetry(t *testing.T) {
+	ctx := context.Background()
+
+	calls := 0
+	resp, err := queryWithRetry(ctx, testQueryConfig, func(ctx context.Context) (rm.QueryResponse, error) {
+		calls++
+
+		_, hasDeadline := ctx.Deadline()
+		require.True(t, hasDeadline)
+
+		if calls == 1 {
+			return rm.QueryResponse{}, xerrors.New("provider unreachable")
+		}
+		return rm.QueryResponse{Status: rm.QueryResponseAvailable, Size: 1024}, nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, 2, calls)
+	require.Equal(t, uint64(1024), resp.Size)
+
+	// give up after the configured number of retries
+	calls = 0
+	_, err = queryWithRetry(ctx, testQueryConfig, func(ctx context.Context) (rm.QueryResponse, error) {
+		calls++
+		return rm.QueryResponse{}, xerrors.New("provider unreachable")
+	})
+	require.Error(t, err)
+	require.Equal(t, 1+testQueryConfig.Retries, calls)
+}
+
+type testResolver struct {
+	peers []rm.RetrievalPeer
+}
+
+func (r *testResolver) GetPeers(cid.Cid) ([]rm.RetrievalPeer, error) {
+	return r.peers, nil
+}
+
+// flakyRetrievalClient fails the first query to every peer
+type flakyRetrievalClient struct {
+	rm.RetrievalClient
+
+	queried map[address.Address]int
+}
+
+func (c *flakyRetrievalClient) Query(ctx context.Context, p rm.RetrievalPeer, payloadCID cid.Cid, params rm.QueryParams) (rm.QueryResponse, error) {
+	c.queried[p.Address]++
+	if c.queried[p.Address] == 1 {
+		return rm.QueryResponse{}, xerrors.New("stream reset")
+	}
+	return rm.QueryResponse{
+		Status:          rm.QueryResponseAvailable,
+		Size:            1024,
+		MinPricePerByte: big.Zero(),
+		UnsealPrice:     big.Zero(),
+	}, nil
+}
+
+func TestFindDataRetriesTransientErrors(t *testing.T) {
+	ctx := context.Background()
+
+	root, err := cid.Parse("bafy2bzacea3wsdh6y3a36tb3skempjoxqpuyompjbmfeyf34fi3uy6uue42v4")
+	require.NoError(t, err)
+
+	maddr, err := address.NewIDAddress(1000)
+	require.NoError(t, err)
+
+	retrieval := &flakyRetrievalClient{queried: map[address.Address]int{}}
+	a := &API{
+		RetDiscovery: &testResolver{peers: []rm.RetrievalPeer{{Address: maddr}}},
+		Retrieval:    retrieval,
+		QueryConfig:  testQueryConfig,
+	}
+
+	offers, err := a.ClientFindData(ctx, root, nil)
+	require.NoError(t, err)
+	require.Len(t, offers, 1)
+	require.Empty(t, offers[0].Err)
+	require.Equal(t, uint64(1024), offers[0].Size)
+	require.Equal(t, 2, retrieval.queried[maddr])
+}
+
+// slowRetrievalClient answers queries after a delay, tracking how many are in
+// flight at once
+type slowRetrievalClient struct {
+	rm.RetrievalClient
+
+	delay time.Duration
+
+	lk          sync.Mutex
+	inFlight    int
+	maxInFlight int
+}
+
+func (c *slowRetrievalClient) Query(ctx context.Context, p rm.RetrievalPeer, payloadCID cid.Cid, params rm.QueryParams) (rm.QueryResponse, error) {
+	c.lk.Lock()
+	c.inFlight++
+	if c.inFlight > c.maxInFlight {
+		c.maxInFlight = c.inFlight
+	}
+	c.lk.Unlock()
+
+	defer func() {
+		c.lk.Lock()
+		c.inFlight--
+		c.lk.Unlock()
+	}()
+
+	select {
+	case <-time.After(c.delay):
+	case <-ctx.Done():
+		return rm.QueryResponse{}, ctx.Err()
+	}
+
+	return rm.QueryResponse{
+		Status:          rm.QueryResponseAvailable,
+		Size:            1024,
+		MinPricePerByte: big.Zero(),
+		UnsealPrice:     big.Zero(),
+	}, nil
+}
+
+func TestFindDataConcurrencyLimit(t *testing.T) {
+	ctx := context.Background()
+
+	root, err := cid.Parse("bafy2bzacea3wsdh6y3a36tb3skempjoxqpuyompjbmfeyf34fi3uy6uue42v4")
+	require.NoError(t, err)
+
+	var peers []rm.RetrievalPeer
+	for i := 0; i < 20; i++ {
+		maddr, err := address.NewIDAddress(uint64(1000 + i))
+		require.NoError(t, err)
+		peers = append(peers, rm.RetrievalPeer{Address: maddr})
+	}
+
+	newAPI := func(cfg dtypes.ClientRetrievalQueryConfig) (*API, *slowRetrievalClient) {
+		retrieval := &slowRetrievalClient{delay: 20 * time.Millisecond}
+		return &API{
+			RetDiscovery: &testResolver{peers: peers},
+			Retrieval:    retrieval,
+			QueryConfig:  cfg,
+		}, retrieval
+	}
+
+	t.Run("bounded", func(t *testing.T) {
+		cfg := testQueryConfig
+		cfg.MaxConcurrent = 3
+		a, retrieval := newAPI(cfg)
+
+		offers, err := a.ClientFindData(ctx, root, nil)
+		require.NoError(t, err)
+		require.Len(t, offers, len(peers))
+		for _, o := range offers {
+			require.Empty(t, o.Err)
+		}
+		require.LessOrEqual(t, retrieval.maxInFlight, 3)
+	})
+
+	t.Run("partial on timeout", func(t *testing.T) {
+		cfg := testQueryConfig
+		cfg.MaxConcurrent = 2
+		// enough for a few rounds of queries, not for all of them
+		cfg.FindDataTimeout = 100 * time.Millisecond
+		a, retrieval := newAPI(cfg)
+
+		offers, err := a.ClientFindData(ctx, root, nil)
+		require.NoError(t, err)
+		require.NotEmpty(t, offers)
+		require.Less(t, len(offers), len(peers))
+
+		var answered int
+		for _, o := range offers {
+			if o.Err == "" {
+				answered++
+			}
+		}
+		require.NotZero(t, answered)
+		require.LessOrEqual(t, retrieval.maxInFlight, 2)
+	})
+
+	t.Run("caller cancelled", func(t *testing.T) {
+		cfg := testQueryConfig
+		cfg.MaxConcurrent = 2
+		cfg.FindDataTimeout = time.Minute
+		a, _ := newAPI(cfg)
+
+		ctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		defer cancel()
+
+		_, err := a.ClientFindData(ctx, root, nil)
+		require.Equal(t, context.DeadlineExceeded, err)
+	})
+}
@@ -0,0 +1,144 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sync"
+
+	"github.com/ipfs/go-cid"
+)
+
+type hashedChunk struct {
+	data []byte
+	cid  cid.Cid
+	err  error
+}
+
+// leafHasher reads a file in the chunks the size splitter will produce and
+// hashes them with a number of workers ahead of the DAG builder. Results are
+// handed out in file order.
+type leafHasher struct {
+	results chan chan hashedChunk
+	cancel  context.CancelFunc
+	done    chan struct{}
+
+	lk      sync.Mutex
+	pending *hashedChunk
+}
+
+func startLeafHasher(ctx context.Context, r io.ReaderAt, size, chunkSize int64, prefix cid.Prefix, workers int) *leafHasher {
+	ctx, cancel := context.WithCancel(ctx)
+
+	h := &leafHasher{
+		// bounds the number of chunks held in memory
+		results: make(chan chan hashedChunk, 2*workers),
+		cancel:  cancel,
+		done:    make(chan struct{}),
+	}
+
+	go func() {
+		defer close(h.done)
+		defer close(h.results)
+
+		throttle := make(chan struct{}, workers)
+		var wg sync.WaitGroup
+		defer wg.Wait()
+
+		for off := int64(0); off < size; off += chunkSize {
+			n := chunkSize
+			if size-off < n {
+				n = size - off
+			}
+
+			res := make(chan hashedChunk, 1)
+			select {
+			case h.results <- res:
+			case <-ctx.Done():
+				return
+			}
+
+			select {
+			case throttle <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+
+			wg.Add(1)
+			go func(off, n int64) {
+				defer wg.Done()
+				defer func() { <-throttle }()
+
+				buf := make([]byte, n)
+				if _, err := r.ReadAt(buf, off); err != nil && err != io.EOF {
+					res <- hashedChunk{err: err}
+					return
+				}
+
+				c, err := prefix.Sum(buf)
+				res <- hashedChunk{data: buf, cid: c, err: err}
+			}(off, n)
+		}
+	}()
+
+	return h
+}
+
+// next returns the CID of the next chunk of the file if it holds exactly the
+// given data
+func (h *leafHasher) next(data []byte) (cid.Cid, bool) {
+	h.lk.Lock()
+	defer h.lk.Unlock()
+
+	if h.pending == nil {
+		res, ok := <-h.results
+		if !ok {
+			return cid.Undef, false
+		}
+		hc := <-res
+		h.pending = &hc
+	}
+
+	if h.pending.err != nil || !bytes.Equal(h.pending.data, data) {
+		return cid.Undef, false
+	}
+
+	c := h.pending.cid
+	h.pending = nil
+	return c, true
+}
+
+func (h *leafHasher) stop() {
+	h.cancel()
+	<-h.done
+}
+
+// parallelLeafBuilder is a cid.Builder which takes the CIDs of raw leaves from
+// a leafHasher. Every other node, and any leaf which doesn't match the next
+// chunk of the file, is hashed inline, so the resulting DAG is exactly the one
+// built serially.
+type parallelLeafBuilder struct {
+	prefix cid.Prefix
+	leaves *leafHasher
+}
+
+func (b *parallelLeafBuilder) Sum(data []byte) (cid.Cid, error) {
+	if b.prefix.Codec == cid.Raw {
+		if c, ok := b.leaves.next(data); ok {
+			return c, nil
+		}
+	}
+
+	return b.prefix.Sum(data)
+}
+
+func (b *parallelLeafBuilder) GetCodec() uint64 {
+	return b.prefix.GetCodec()
+}
+
+func (b *parallelLeafBuilder) WithCodec(c uint64) cid.Builder {
+	return &parallelLeafBuilder{
+		prefix: b.prefix.WithCodec(c).(cid.Prefix),
+		leaves: b.leaves,
+	}
+}
@@ -0,0 +1,102 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	dss "github.com/ipfs/go-datastore/sync"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-multistore"
+
+	"github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/build"
+	"github.com/filecoin-project/lotus/node/modules/dtypes"
+)
+
+func TestParallelImportDeterministic(t *testing.T) {
+	ctx := context.Background()
+
+	mds, err := multistore.NewMultiDstore(dss.MutexWrap(datastore.NewMapDatastore()))
+	require.NoError(t, err)
+
+	sizes := map[string]int{
+		"small":    1000,
+		"chunks":   8 * int(build.UnixfsChunkSize),
+		"large":    64*int(build.UnixfsChunkSize) + 12345,
+		"tinytail": 3*int(build.UnixfsChunkSize) + 100, // the last leaf is inlined
+	}
+
+	for name, size := range sizes {
+		size := size
+		t.Run(name, func(t *testing.T) {
+			data := make([]byte, size)
+			_, _ = rand.New(rand.NewSource(int64(size))).Read(data)
+
+			path := filepath.Join(t.TempDir(), "data")
+			require.NoError(t, ioutil.WriteFile(path, data, 0644))
+
+			importWith := func(parallelism int) (cid.Cid, time.Duration) {
+				st, err := mds.Get(mds.Next())
+				require.NoError(t, err)
+
+				a := &API{ImportConfig: dtypes.ClientImportConfig{Parallelism: parallelism}}
+
+				start := time.Now()
+				root, err := a.clientImport(ctx, api.FileRef{Path: path}, st)
+				require.NoError(t, err)
+				return root, time.Since(start)
+			}
+
+			serialRoot, serial := importWith(1)
+			parallelRoot, parallel := importWith(8)
+			require.Equal(t, serialRoot, parallelRoot)
+
+			t.Logf("serial import took %s, parallel import took %s", serial, parallel)
+		})
+	}
+}
+
+// BenchmarkClientImport compares serial and parallel imports of a file large
+// enough for the leaf hashing to dominate, e.g.
+//
+//	go test ./node/impl/client -run '^$' -bench ClientImport
+func BenchmarkClientImport(b *testing.B) {
+	ctx := context.Background()
+
+	data := make([]byte, 128*int(build.UnixfsChunkSize))
+	_, _ = rand.New(rand.NewSource(42)).Read(data)
+
+	path := filepath.Join(b.TempDir(), "data")
+	require.NoError(b, ioutil.WriteFile(path, data, 0644))
+
+	for _, parallelism := range []int{1, 2, 4, 8} {
+		parallelism := parallelism
+		b.Run(fmt.Sprintf("parallelism-%d", parallelism), func(b *testing.B) {
+			a := &API{ImportConfig: dtypes.ClientImportConfig{Parallelism: parallelism}}
+
+			b.SetBytes(int64(len(data)))
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				// a fresh store per import, so no blocks are deduplicated
+				mds, err := multistore.NewMultiDstore(dss.MutexWrap(datastore.NewMapDatastore()))
+				require.NoError(b, err)
+				st, err := mds.Get(mds.Next())
+				require.NoError(b, err)
+				b.StartTimer()
+
+				_, err = a.clientImport(ctx, api.FileRef{Path: path}, st)
+				require.NoError(b, err)
+			}
+		})
+	}
+}
@@ -10,6 +10,7 @@ import (
 
 	"github.com/filecoin-project/lotus/chain/actors/builtin"
 	"github.com/filecoin-project/lotus/chain/gen"
+	"github.com/filecoin-project/lotus/chain/gen/slashfilter"
 
 	"github.com/filecoin-project/lotus/build"
 	"github.com/google/uuid"
@@ -19,6 +20,7 @@ import (
 	"golang.org/x/xerrors"
 
 	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-commp-utils/zerocomm"
 	datatransfer "github.com/filecoin-project/go-data-transfer"
 	"github.com/filecoin-project/go-fil-markets/piecestore"
 	retrievalmarket "github.com/filecoin-project/go-fil-markets/retrievalmarket"
@@ -26,9 +28,11 @@ import (
 	"github.com/filecoin-project/go-jsonrpc/auth"
 	"github.com/filecoin-project/go-state-types/abi"
 	"github.com/filecoin-project/go-state-types/big"
+	"github.com/filecoin-project/specs-actors/v2/actors/builtin/market"
 
 	sectorstorage "github.com/filecoin-project/lotus/extern/sector-storage"
 	"github.com/filecoin-project/lotus/extern/sector-storage/fsutil"
+	"github.com/filecoin-project/lotus/extern/sector-storage/sealtasks"
 	"github.com/filecoin-project/lotus/extern/sector-storage/stores"
 	"github.com/filecoin-project/lotus/extern/sector-storage/storiface"
 	sealing "github.com/filecoin-project/lotus/extern/storage-sealing"
@@ -36,7 +40,16 @@ import (
 
 	"github.com/filecoin-project/lotus/api"
 	apitypes "github.com/filecoin-project/lotus/api/types"
+	"github.com/filecoin-project/lotus/blockstore"
+	lminer "github.com/filecoin-project/lotus/chain/actors/builtin/miner"
+	"github.com/filecoin-project/lotus/chain/actors/builtin/power"
+	"github.com/filecoin-project/lotus/chain/store"
 	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/lotus/markets/dealdiag"
+	"github.com/filecoin-project/lotus/markets/dealfilter"
+	"github.com/filecoin-project/lotus/markets/pricing"
+	"github.com/filecoin-project/lotus/markets/quarantine"
+	"github.com/filecoin-project/lotus/markets/revenue"
 	"github.com/filecoin-project/lotus/markets/storageadapter"
 	"github.com/filecoin-project/lotus/miner"
 	"github.com/filecoin-project/lotus/node/impl/common"
@@ -56,6 +69,7 @@ type StorageMinerAPI struct {
 	RetrievalProvider retrievalmarket.RetrievalProvider
 	Miner             *storage.Miner
 	BlockMiner        *miner.Miner
+	SlashFilter       slashfilter.SlashFilter
 	Full              api.FullNode
 	StorageMgr        *sectorstorage.Manager `optional:"true"`
 	IStorageMgr       sectorstorage.SectorManager
@@ -65,6 +79,12 @@ type StorageMinerAPI struct {
 	Host          host.Host
 	AddrSel       *storage.AddressSelector
 	DealPublisher *storageadapter.DealPublisher
+	FaultHistory  *storage.FaultHistory
+	Params        *storage.ParamsVerifier
+	RateLimiter   *dealfilter.ClientRateLimiter
+	Quarantine    *quarantine.Store
+	AskTiers      *pricing.TiersStore
+	DealDiag      *dealdiag.Recorder
 
 	Epp gen.WinningPoStProver
 	DS  dtypes.MetadataDS
@@ -87,6 +107,7 @@ type StorageMinerAPI struct {
 	GetSealingConfigFunc                        dtypes.GetSealingConfigFunc
 	GetExpectedSealDurationFunc                 dtypes.GetExpectedSealDurationFunc
 	SetExpectedSealDurationFunc                 dtypes.SetExpectedSealDurationFunc
+	GetDealFilterCmdFunc                        dtypes.GetDealFilterCmdFunc
 }
 
 func (sm *StorageMinerAPI) ServeRemote(w http.ResponseWriter, r *http.Request) {
@@ -107,6 +128,14 @@ func (sm *StorageMinerAPI) WorkerJobs(ctx context.Context) (map[uuid.UUID][]stor
 	return sm.StorageMgr.WorkerJobs(), nil
 }
 
+func (sm *StorageMinerAPI) WorkerResourceUtilization(ctx context.Context) (map[uuid.UUID]storiface.WorkerUtilization, error) {
+	return sm.StorageMgr.WorkerResourceUtilization(), nil
+}
+
+func (sm *StorageMinerAPI) StorageTransferStats(ctx context.Context) (storiface.TransferStats, error) {
+	return sm.StorageMgr.TransferStats(), nil
+}
+
 func (sm *StorageMinerAPI) ActorAddress(context.Context) (address.Address, error) {
 	return sm.Miner.Address(), nil
 }
@@ -119,6 +148,57 @@ func (sm *StorageMinerAPI) MiningBase(ctx context.Context) (*types.TipSet, error
 	return mb.TipSet, nil
 }
 
+func (sm *StorageMinerAPI) MiningSetEnabled(ctx context.Context, enabled bool) error {
+	sm.BlockMiner.SetEnabled(enabled)
+	return nil
+}
+
+func (sm *StorageMinerAPI) MiningStatus(ctx context.Context) (api.MiningStatus, error) {
+	enabled, since := sm.BlockMiner.Enabled()
+	return api.MiningStatus{
+		Enabled: enabled,
+		Since:   since,
+	}, nil
+}
+
+func (sm *StorageMinerAPI) ParamsStatus(ctx context.Context) (api.ParamsStatus, error) {
+	return sm.Params.Status(), nil
+}
+
+func (sm *StorageMinerAPI) SlashFilterHistory(ctx context.Context, since abi.ChainEpoch) ([]api.SlashFilterRecord, error) {
+	recs, err := sm.SlashFilter.History(since)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]api.SlashFilterRecord, len(recs))
+	for i, r := range recs {
+		out[i] = api.SlashFilterRecord{
+			Miner:  r.Miner,
+			Height: r.Height,
+			Block:  r.Block,
+		}
+	}
+	return out, nil
+}
+
+func (sm *StorageMinerAPI) SlashFilterPrune(ctx context.Context, before abi.ChainEpoch, confirm bool) (int, error) {
+	if !confirm {
+		return 0, xerrors.Errorf("pruning the slash filter removes consensus fault protection for the pruned blocks, confirm to proceed")
+	}
+
+	head, err := sm.Full.ChainHead(ctx)
+	if err != nil {
+		return 0, xerrors.Errorf("getting chain head: %w", err)
+	}
+
+	if before > head.Height()-build.Finality {
+		return 0, xerrors.Errorf("can't prune blocks newer than finality (epoch %d)", head.Height()-build.Finality)
+	}
+
+	return sm.SlashFilter.Prune(before)
+}
+
 func (sm *StorageMinerAPI) ActorSectorSize(ctx context.Context, addr address.Address) (abi.SectorSize, error) {
 	mi, err := sm.Full.StateMinerInfo(ctx, addr, types.EmptyTSK)
 	if err != nil {
@@ -127,6 +207,66 @@ func (sm *StorageMinerAPI) ActorSectorSize(ctx context.Context, addr address.Add
 	return mi.SectorSize, nil
 }
 
+func (sm *StorageMinerAPI) MinerPowerBreakdown(ctx context.Context) (api.PowerBreakdown, error) {
+	act, err := sm.Full.StateGetActor(ctx, sm.Miner.Address(), types.EmptyTSK)
+	if err != nil {
+		return api.PowerBreakdown{}, xerrors.Errorf("getting miner actor: %w", err)
+	}
+
+	mas, err := lminer.Load(store.ActorStore(ctx, blockstore.NewAPIBlockstore(sm.Full)), act)
+	if err != nil {
+		return api.PowerBreakdown{}, xerrors.Errorf("loading miner state: %w", err)
+	}
+
+	out := api.PowerBreakdown{
+		Active:     power.Claim{RawBytePower: big.Zero(), QualityAdjPower: big.Zero()},
+		Faulty:     power.Claim{RawBytePower: big.Zero(), QualityAdjPower: big.Zero()},
+		Recovering: power.Claim{RawBytePower: big.Zero(), QualityAdjPower: big.Zero()},
+	}
+	add := func(c *power.Claim, pp lminer.PowerPair) {
+		c.RawBytePower = big.Add(c.RawBytePower, pp.Raw)
+		c.QualityAdjPower = big.Add(c.QualityAdjPower, pp.QA)
+	}
+
+	err = mas.ForEachDeadline(func(dlIdx uint64, dl lminer.Deadline) error {
+		return dl.ForEachPartition(func(partIdx uint64, part lminer.Partition) error {
+			add(&out.Active, part.ActivePower())
+			add(&out.Faulty, part.FaultyPower())
+			add(&out.Recovering, part.RecoveringPower())
+			return nil
+		})
+	})
+	if err != nil {
+		return api.PowerBreakdown{}, xerrors.Errorf("iterating partitions: %w", err)
+	}
+
+	return out, nil
+}
+
+func (sm *StorageMinerAPI) MinerDeadlineLoad(ctx context.Context) ([]api.DeadlineLoad, error) {
+	head, err := sm.Full.ChainHead(ctx)
+	if err != nil {
+		return nil, xerrors.Errorf("getting chain head: %w", err)
+	}
+
+	act, err := sm.Full.StateGetActor(ctx, sm.Miner.Address(), head.Key())
+	if err != nil {
+		return nil, xerrors.Errorf("getting miner actor: %w", err)
+	}
+
+	mas, err := lminer.Load(store.ActorStore(ctx, blockstore.NewAPIBlockstore(sm.Full)), act)
+	if err != nil {
+		return nil, xerrors.Errorf("loading miner state: %w", err)
+	}
+
+	di, err := sm.Full.StateMinerProvingDeadline(ctx, sm.Miner.Address(), head.Key())
+	if err != nil {
+		return nil, xerrors.Errorf("getting proving deadline: %w", err)
+	}
+
+	return storage.DeadlineLoads(mas, di.Index)
+}
+
 func (sm *StorageMinerAPI) PledgeSector(ctx context.Context) (abi.SectorID, error) {
 	sr, err := sm.Miner.PledgeSector(ctx)
 	if err != nil {
@@ -197,6 +337,8 @@ func (sm *StorageMinerAPI) SectorsStatus(ctx context.Context, sid abi.SectorNumb
 		Retries:      info.InvalidProofs,
 		ToUpgrade:    sm.Miner.IsMarkedForUpgrade(sid),
 
+		SyntheticPoRep: info.SyntheticPoRep,
+
 		LastErr: info.LastErr,
 		Log:     log,
 		// on chain info
@@ -238,6 +380,10 @@ func (sm *StorageMinerAPI) SectorsStatus(ctx context.Context, sid abi.SectorNumb
 	return sInfo, nil
 }
 
+func (sm *StorageMinerAPI) SectorFaultHistory(ctx context.Context, sid abi.SectorNumber) ([]api.SectorFaultEvent, error) {
+	return sm.FaultHistory.Get(sid)
+}
+
 // List all staged sectors
 func (sm *StorageMinerAPI) SectorsList(context.Context) ([]abi.SectorNumber, error) {
 	sectors, err := sm.Miner.ListSectors()
@@ -285,15 +431,9 @@ func (sm *StorageMinerAPI) SectorsListInStates(ctx context.Context, states []api
 }
 
 func (sm *StorageMinerAPI) SectorsSummary(ctx context.Context) (map[api.SectorState]int, error) {
-	sectors, err := sm.Miner.ListSectors()
-	if err != nil {
-		return nil, err
-	}
-
 	out := make(map[api.SectorState]int)
-	for i := range sectors {
-		state := api.SectorState(sectors[i].State)
-		out[state]++
+	for state, n := range sm.Miner.SectorsSummary() {
+		out[api.SectorState(state)] = n
 	}
 
 	return out, nil
@@ -327,6 +467,18 @@ func (sm *StorageMinerAPI) SectorStartSealing(ctx context.Context, number abi.Se
 	return sm.Miner.StartPackingSector(number)
 }
 
+func (sm *StorageMinerAPI) SectorAbortWaitDeals(ctx context.Context, number abi.SectorNumber) error {
+	return sm.Miner.AbortWaitDealsSector(number)
+}
+
+func (sm *StorageMinerAPI) SectorMatchPendingPiecesToOpenSectors(ctx context.Context) error {
+	return sm.Miner.MatchPendingPiecesToOpenSectors(ctx)
+}
+
+func (sm *StorageMinerAPI) SectorsOpen(ctx context.Context) ([]sealiface.OpenSector, error) {
+	return sm.Miner.OpenSectors(ctx)
+}
+
 func (sm *StorageMinerAPI) SectorSetSealDelay(ctx context.Context, delay time.Duration) error {
 	cfg, err := sm.GetSealingConfigFunc()
 	if err != nil {
@@ -366,6 +518,10 @@ func (sm *StorageMinerAPI) SectorTerminate(ctx context.Context, id abi.SectorNum
 	return sm.Miner.TerminateSector(ctx, id)
 }
 
+func (sm *StorageMinerAPI) SectorExtend(ctx context.Context, id abi.SectorNumber, newExpiration abi.ChainEpoch) (cid.Cid, error) {
+	return sm.Miner.ExtendSector(ctx, id, newExpiration)
+}
+
 func (sm *StorageMinerAPI) SectorTerminateFlush(ctx context.Context) (*cid.Cid, error) {
 	return sm.Miner.TerminateFlush(ctx)
 }
@@ -394,6 +550,18 @@ func (sm *StorageMinerAPI) SectorCommitPending(ctx context.Context) ([]abi.Secto
 	return sm.Miner.CommitPending(ctx)
 }
 
+func (sm *StorageMinerAPI) SectorFinalizeFlush(ctx context.Context) ([]abi.SectorID, error) {
+	return sm.Miner.FinalizeFlush(ctx)
+}
+
+func (sm *StorageMinerAPI) SectorFinalizePending(ctx context.Context) ([]abi.SectorID, error) {
+	return sm.Miner.FinalizePending(ctx)
+}
+
+func (sm *StorageMinerAPI) SectorsReconcileReport(ctx context.Context) (sealiface.ReconcileReport, error) {
+	return sm.Miner.ReconcileReport(ctx)
+}
+
 func (sm *StorageMinerAPI) WorkerConnect(ctx context.Context, url string) error {
 	w, err := connectRemoteWorker(ctx, sm, url)
 	if err != nil {
@@ -409,10 +577,26 @@ func (sm *StorageMinerAPI) SealingSchedDiag(ctx context.Context, doSched bool) (
 	return sm.StorageMgr.SchedDiag(ctx, doSched)
 }
 
+func (sm *StorageMinerAPI) SealingQueueDepth(ctx context.Context) (map[sealtasks.TaskType]int, error) {
+	return sm.StorageMgr.SchedQueueDepth(ctx)
+}
+
 func (sm *StorageMinerAPI) SealingAbort(ctx context.Context, call storiface.CallID) error {
 	return sm.StorageMgr.Abort(ctx, call)
 }
 
+func (sm *StorageMinerAPI) SealingPipelineLimits(ctx context.Context) ([]sealiface.PipelineLimit, error) {
+	return sm.Miner.PipelineLimits(ctx)
+}
+
+func (sm *StorageMinerAPI) SealingSnapshot(ctx context.Context) (api.SealingSnapshot, error) {
+	return sm.Miner.SealingSnapshot(ctx)
+}
+
+func (sm *StorageMinerAPI) SealingRestore(ctx context.Context, snap api.SealingSnapshot) error {
+	return sm.Miner.SealingRestore(ctx, snap)
+}
+
 func (sm *StorageMinerAPI) MarketImportDealData(ctx context.Context, propCid cid.Cid, path string) error {
 	fi, err := os.Open(path)
 	if err != nil {
@@ -449,6 +633,23 @@ func (sm *StorageMinerAPI) MarketListDeals(ctx context.Context) ([]api.MarketDea
 	return sm.listDeals(ctx)
 }
 
+func (sm *StorageMinerAPI) MarketRevenue(ctx context.Context, since abi.ChainEpoch) (api.RevenueReport, error) {
+	ts, err := sm.Full.ChainHead(ctx)
+	if err != nil {
+		return api.RevenueReport{}, err
+	}
+	deals, err := sm.Full.StateMarketDeals(ctx, ts.Key())
+	if err != nil {
+		return api.RevenueReport{}, xerrors.Errorf("getting market deals: %w", err)
+	}
+
+	return revenue.Report(deals, sm.Miner.Address(), since, ts.Height())
+}
+
+func (sm *StorageMinerAPI) MarketSimulatePacking(ctx context.Context, deals []sealiface.PackingDeal) (sealiface.PackingPlan, error) {
+	return sm.Miner.SimulatePacking(ctx, deals)
+}
+
 func (sm *StorageMinerAPI) MarketListRetrievalDeals(ctx context.Context) ([]retrievalmarket.ProviderDealState, error) {
 	var out []retrievalmarket.ProviderDealState
 	deals := sm.RetrievalProvider.ListDeals()
@@ -485,6 +686,37 @@ func (sm *StorageMinerAPI) MarketListIncompleteDeals(ctx context.Context) ([]sto
 	return sm.StorageProvider.ListLocalDeals()
 }
 
+func (sm *StorageMinerAPI) MarketListDealsAwaitingData(ctx context.Context) ([]api.AwaitingDataDeal, error) {
+	deals, err := sm.StorageProvider.ListLocalDeals()
+	if err != nil {
+		return nil, err
+	}
+
+	sealDuration, err := sm.GetExpectedSealDurationFunc()
+	if err != nil {
+		return nil, xerrors.Errorf("getting expected seal duration: %w", err)
+	}
+	sealEpochs := abi.ChainEpoch(sealDuration / (time.Duration(build.BlockDelaySecs) * time.Second))
+
+	out := make([]api.AwaitingDataDeal, 0)
+	for _, deal := range deals {
+		if deal.State != storagemarket.StorageDealWaitingForData {
+			continue
+		}
+
+		out = append(out, api.AwaitingDataDeal{
+			ProposalCid:    deal.ProposalCid,
+			Client:         deal.Proposal.Client,
+			PieceCid:       deal.Proposal.PieceCID,
+			PieceSize:      deal.Proposal.PieceSize,
+			StartEpoch:     deal.Proposal.StartEpoch,
+			ImportDeadline: deal.Proposal.StartEpoch - sealEpochs,
+		})
+	}
+
+	return out, nil
+}
+
 func (sm *StorageMinerAPI) MarketSetAsk(ctx context.Context, price types.BigInt, verifiedPrice types.BigInt, duration abi.ChainEpoch, minPieceSize abi.PaddedPieceSize, maxPieceSize abi.PaddedPieceSize) error {
 	options := []storagemarket.StorageAskOption{
 		storagemarket.MinPieceSize(minPieceSize),
@@ -507,6 +739,18 @@ func (sm *StorageMinerAPI) MarketGetRetrievalAsk(ctx context.Context) (*retrieva
 	return sm.RetrievalProvider.GetAsk(), nil
 }
 
+func (sm *StorageMinerAPI) MarketSetRetrievalAskTiers(ctx context.Context, tiers api.RetrievalAskTiers) error {
+	return sm.AskTiers.Set(tiers)
+}
+
+func (sm *StorageMinerAPI) MarketGetRetrievalAskTiers(ctx context.Context) (api.RetrievalAskTiers, error) {
+	return sm.AskTiers.Get()
+}
+
+func (sm *StorageMinerAPI) MarketDealDiagnostics(ctx context.Context) ([]api.DealDiagnostic, error) {
+	return sm.DealDiag.Records(), nil
+}
+
 func (sm *StorageMinerAPI) MarketListDataTransfers(ctx context.Context) ([]api.DataTransferChannel, error) {
 	inProgressChannels, err := sm.DataTransfer.InProgressChannels(ctx)
 	if err != nil {
@@ -521,6 +765,41 @@ func (sm *StorageMinerAPI) MarketListDataTransfers(ctx context.Context) ([]api.D
 	return apiChannels, nil
 }
 
+func (sm *StorageMinerAPI) MarketDealTransferStatus(ctx context.Context, propCid cid.Cid) (api.TransferStatus, error) {
+	deals, err := sm.StorageProvider.ListLocalDeals()
+	if err != nil {
+		return api.TransferStatus{}, err
+	}
+
+	for _, deal := range deals {
+		if deal.ProposalCid != propCid {
+			continue
+		}
+
+		if deal.TransferChannelId == nil {
+			return api.TransferStatus{}, xerrors.Errorf("deal %s doesn't have a data transfer channel", propCid)
+		}
+
+		state, err := sm.DataTransfer.ChannelState(ctx, *deal.TransferChannelId)
+		if err != nil {
+			return api.TransferStatus{}, xerrors.Errorf("getting channel state: %w", err)
+		}
+
+		return api.NewTransferStatus(sm.Host.ID(), state), nil
+	}
+
+	return api.TransferStatus{}, xerrors.Errorf("deal %s not found", propCid)
+}
+
+func (sm *StorageMinerAPI) MarketDataTransferChannelDebug(ctx context.Context, chid datatransfer.ChannelID) (api.ChannelDebug, error) {
+	state, err := sm.DataTransfer.ChannelState(ctx, chid)
+	if err != nil {
+		return api.ChannelDebug{}, xerrors.Errorf("getting channel state: %w", err)
+	}
+
+	return api.NewChannelDebug(state), nil
+}
+
 func (sm *StorageMinerAPI) MarketRestartDataTransfer(ctx context.Context, transferID datatransfer.TransferID, otherPeer peer.ID, isInitiator bool) error {
 	selfPeer := sm.Host.ID()
 	if isInitiator {
@@ -565,6 +844,121 @@ func (sm *StorageMinerAPI) MarketPublishPendingDeals(ctx context.Context) error
 	return nil
 }
 
+func (sm *StorageMinerAPI) MarketClientLimits(ctx context.Context) ([]api.ClientDealLimit, error) {
+	limits, err := sm.RateLimiter.Limits()
+	if err != nil {
+		return nil, xerrors.Errorf("getting client limits: %w", err)
+	}
+
+	out := make([]api.ClientDealLimit, len(limits))
+	for i, l := range limits {
+		out[i] = api.ClientDealLimit{
+			Client:   l.Client,
+			Deals:    l.Deals,
+			MaxDeals: l.MaxDeals,
+			ResetsAt: l.ResetsAt,
+		}
+	}
+	return out, nil
+}
+
+func (sm *StorageMinerAPI) MarketResetClientLimit(ctx context.Context, client address.Address) error {
+	sm.RateLimiter.Reset(client)
+	return nil
+}
+
+func (sm *StorageMinerAPI) MarketListQuarantinedData(ctx context.Context) ([]api.QuarantinedData, error) {
+	ents, err := sm.Quarantine.List()
+	if err != nil {
+		return nil, xerrors.Errorf("listing quarantined data: %w", err)
+	}
+
+	out := make([]api.QuarantinedData, len(ents))
+	for i, ent := range ents {
+		out[i] = api.QuarantinedData{
+			ProposalCid:   ent.ProposalCid,
+			PieceCid:      ent.PieceCid,
+			Client:        ent.Client,
+			Message:       ent.Message,
+			Files:         ent.Files,
+			Size:          ent.Size,
+			QuarantinedAt: ent.QuarantinedAt,
+			ExpiresAt:     ent.ExpiresAt,
+		}
+	}
+	return out, nil
+}
+
+func (sm *StorageMinerAPI) MarketPurgeQuarantinedData(ctx context.Context, propCid cid.Cid) error {
+	return sm.Quarantine.Purge(propCid)
+}
+
+func (sm *StorageMinerAPI) MarketTestDealFilter(ctx context.Context, proposal *market.DealProposal) (*api.DealFilterResult, error) {
+	cmd, err := sm.GetDealFilterCmdFunc()
+	if err != nil {
+		return nil, xerrors.Errorf("getting deal filter config: %w", err)
+	}
+	if cmd == "" {
+		return nil, xerrors.New("no storage deal filter configured in Dealmaking.Filter")
+	}
+
+	if proposal == nil {
+		proposal, err = sm.sampleDealProposal(ctx)
+		if err != nil {
+			return nil, xerrors.Errorf("creating sample deal proposal: %w", err)
+		}
+	}
+
+	propCid, err := proposal.Cid()
+	if err != nil {
+		return nil, xerrors.Errorf("getting proposal cid: %w", err)
+	}
+
+	res, err := dealfilter.RunStorageDealFilter(ctx, cmd, storagemarket.MinerDeal{
+		ClientDealProposal: market.ClientDealProposal{Proposal: *proposal},
+		ProposalCid:        propCid,
+		State:              storagemarket.StorageDealValidating,
+		Ref: &storagemarket.DataRef{
+			TransferType: storagemarket.TTGraphsync,
+			PieceCid:     &proposal.PieceCID,
+			PieceSize:    proposal.PieceSize.Unpadded(),
+		},
+	})
+	if err != nil {
+		return nil, xerrors.Errorf("running deal filter %q: %w", cmd, err)
+	}
+
+	return &api.DealFilterResult{
+		Accept: res.Accept,
+		Reason: res.Reason,
+		Stderr: res.Stderr,
+	}, nil
+}
+
+// sampleDealProposal creates a deal proposal for a small, empty piece, to
+// start sealing within a day and be stored for 180 days
+func (sm *StorageMinerAPI) sampleDealProposal(ctx context.Context) (*market.DealProposal, error) {
+	head, err := sm.Full.ChainHead(ctx)
+	if err != nil {
+		return nil, xerrors.Errorf("getting chain head: %w", err)
+	}
+
+	pieceSize := abi.PaddedPieceSize(2048)
+	start := head.Height() + builtin.EpochsInDay
+	return &market.DealProposal{
+		PieceCID:             zerocomm.ZeroPieceCommitment(pieceSize.Unpadded()),
+		PieceSize:            pieceSize,
+		Client:               sm.Miner.Address(),
+		Provider:             sm.Miner.Address(),
+		Label:                "sample deal",
+		StartEpoch:           start,
+		EndEpoch:             start + 180*builtin.EpochsInDay,
+		StoragePricePerEpoch: big.Zero(),
+		ProviderCollateral:   big.Zero(),
+		ClientCollateral:     big.Zero(),
+	}, nil
+}
+
 func (sm *StorageMinerAPI) DealsList(ctx context.Context) ([]api.MarketDeal, error) {
 	return sm.listDeals(ctx)
 }
@@ -684,6 +1078,41 @@ func (sm *StorageMinerAPI) CreateBackup(ctx context.Context, fpath string) error
 	return backup(sm.DS, fpath)
 }
 
+func (sm *StorageMinerAPI) SectorVerifyCommR(ctx context.Context, sid abi.SectorNumber) (bool, error) {
+	onChainInfo, err := sm.Full.StateSectorGetInfo(ctx, sm.Miner.Address(), sid, types.EmptyTSK)
+	if err != nil {
+		return false, xerrors.Errorf("getting on-chain sector info: %w", err)
+	}
+	if onChainInfo == nil {
+		return false, xerrors.Errorf("sector %d not found on chain", sid)
+	}
+
+	mid, err := address.IDFromAddress(sm.Miner.Address())
+	if err != nil {
+		return false, err
+	}
+
+	return sm.IStorageMgr.VerifyCommR(ctx, sto.SectorRef{
+		ID: abi.SectorID{
+			Miner:  abi.ActorID(mid),
+			Number: sid,
+		},
+		ProofType: onChainInfo.SealProof,
+	}, onChainInfo.SealedCID)
+}
+
+func (sm *StorageMinerAPI) SectorStorageLocations(ctx context.Context, sid abi.SectorNumber) ([]stores.StorageLocation, error) {
+	mid, err := address.IDFromAddress(sm.Miner.Address())
+	if err != nil {
+		return nil, err
+	}
+
+	return sm.Index.SectorLocations(ctx, abi.SectorID{
+		Miner:  abi.ActorID(mid),
+		Number: sid,
+	}, storiface.FTSealed|storiface.FTCache)
+}
+
 func (sm *StorageMinerAPI) CheckProvable(ctx context.Context, pp abi.RegisteredPoStProof, sectors []sto.SectorRef, expensive bool) (map[abi.SectorNumber]string, error) {
 	var rg storiface.RGetter
 	if expensive {
@@ -713,6 +1142,10 @@ func (sm *StorageMinerAPI) CheckProvable(ctx context.Context, pp abi.RegisteredP
 	return out, nil
 }
 
+func (sm *StorageMinerAPI) ComputeWindowPoStDryRun(ctx context.Context, deadline uint64) (api.WindowPoStDryRun, error) {
+	return sm.Miner.WindowPoStDryRun(ctx, deadline)
+}
+
 func (sm *StorageMinerAPI) ActorAddressConfig(ctx context.Context) (api.AddressConfig, error) {
 	return sm.AddrSel.AddressConfig, nil
 }
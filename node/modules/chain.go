@@ -157,6 +157,14 @@ func NewSyncer(params SyncerParams) (*chain.Syncer, error) {
 	return syncer, nil
 }
 
-func NewSlashFilter(ds dtypes.MetadataDS) *slashfilter.SlashFilter {
+func NewSlashFilter(ds dtypes.MetadataDS) slashfilter.SlashFilter {
 	return slashfilter.New(ds)
 }
+
+// NewSharedSlashFilter keeps the slash filter state in a directory shared by
+// the block producers of a miner actor
+func NewSharedSlashFilter(path string) func() (slashfilter.SlashFilter, error) {
+	return func() (slashfilter.SlashFilter, error) {
+		return slashfilter.NewShared(path)
+	}
+}
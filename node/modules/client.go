@@ -35,6 +35,7 @@ import (
 	"github.com/filecoin-project/lotus/markets"
 	marketevents "github.com/filecoin-project/lotus/markets/loggers"
 	"github.com/filecoin-project/lotus/markets/retrievaladapter"
+	"github.com/filecoin-project/lotus/node/config"
 	"github.com/filecoin-project/lotus/node/impl/full"
 	payapi "github.com/filecoin-project/lotus/node/impl/paych"
 	"github.com/filecoin-project/lotus/node/modules/dtypes"
@@ -173,6 +174,12 @@ func NewClientDatastore(ds dtypes.MetadataDS) dtypes.ClientDatastore {
 	return namespace.Wrap(ds, datastore.NewKey("/deals/client"))
 }
 
+// NewClientDealTemplatesDS creates a datastore for the client to store deal
+// templates in
+func NewClientDealTemplatesDS(ds dtypes.MetadataDS) dtypes.ClientDealTemplatesDS {
+	return namespace.Wrap(ds, datastore.NewKey("/client/dealtemplates"))
+}
+
 func StorageClient(lc fx.Lifecycle, h host.Host, ibs dtypes.ClientBlockstore, mds dtypes.ClientMultiDstore, r repo.LockedRepo, dataTransfer dtypes.ClientDataTransfer, discovery *discoveryimpl.Local, deals dtypes.ClientDatastore, scn storagemarket.StorageClientNode, j journal.Journal) (storagemarket.StorageClient, error) {
 	// go-fil-markets protocol retries:
 	// 1s, 5s, 25s, 2m5s, 5m x 11 ~= 1 hour
@@ -222,6 +229,31 @@ func RetrievalClient(lc fx.Lifecycle, h host.Host, mds dtypes.ClientMultiDstore,
 	return client, nil
 }
 
+// ClientRetrievalQueryConfig returns the retrieval query timeouts and retries
+// from the client config
+func ClientRetrievalQueryConfig(cfg config.Client) func() dtypes.ClientRetrievalQueryConfig {
+	return func() dtypes.ClientRetrievalQueryConfig {
+		return dtypes.ClientRetrievalQueryConfig{
+			Timeout: time.Duration(cfg.RetrievalQueryTimeout),
+			Retries: cfg.RetrievalQueryRetries,
+			Backoff: time.Duration(cfg.RetrievalQueryBackoff),
+
+			MaxConcurrent:   cfg.RetrievalQueryMaxConcurrent,
+			FindDataTimeout: time.Duration(cfg.RetrievalFindDataTimeout),
+		}
+	}
+}
+
+// ClientImportConfig returns the number of workers hashing file chunks of
+// client imports from the client config
+func ClientImportConfig(cfg config.Client) func() dtypes.ClientImportConfig {
+	return func() dtypes.ClientImportConfig {
+		return dtypes.ClientImportConfig{
+			Parallelism: cfg.ImportParallelism,
+		}
+	}
+}
+
 // ClientRetrievalStoreManager is the default version of the RetrievalStoreManager that runs on multistore
 func ClientRetrievalStoreManager(imgr dtypes.ClientImportMgr) dtypes.ClientRetrievalStoreManager {
 	return retrievalstoremgr.NewMultiStoreRetrievalStoreManager(imgr)
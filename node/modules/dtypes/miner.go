@@ -2,6 +2,7 @@ package dtypes
 
 import (
 	"context"
+	"regexp"
 	"time"
 
 	"github.com/ipfs/go-cid"
@@ -93,7 +94,45 @@ type GetExpectedSealDurationFunc func() (time.Duration, error)
 type SetMaxDealStartDelayFunc func(time.Duration) error
 type GetMaxDealStartDelayFunc func() (time.Duration, error)
 
+// GetTargetSectorFillRatioFunc is a function which reads from miner config the
+// minimum fraction of the sector space a deal takes up which has to be deal
+// data rather than alignment padding
+type GetTargetSectorFillRatioFunc func() (float64, error)
+
+// GetRequireClientEscrowFunc is a function which reads from miner config
+// whether clients must have the funds for a deal in market escrow before the
+// deal is accepted
+type GetRequireClientEscrowFunc func() (bool, error)
+
+// GetRequiredLabelPatternsFunc is a function which reads from miner config
+// the compiled patterns which the label of a deal proposal has to match
+type GetRequiredLabelPatternsFunc func() ([]*regexp.Regexp, error)
+
+// GetStartEpochCounterOfferSlackFunc is a function which reads from miner
+// config how much later than the earliest feasible start epoch the start
+// epoch counter-offered for too-soon deal proposals is, 0 if no counter-offer
+// is made
+type GetStartEpochCounterOfferSlackFunc func() (time.Duration, error)
+
+// GetDealFilterCmdFunc is a function which reads from miner config the
+// external storage deal filter command
+type GetDealFilterCmdFunc func() (string, error)
+
+// GetOpenSectorsFunc is a function which returns the sectors currently
+// accepting deals
+type GetOpenSectorsFunc func(ctx context.Context) ([]sealiface.OpenSector, error)
+
 type StorageDealFilter func(ctx context.Context, deal storagemarket.MinerDeal) (bool, string, error)
 type RetrievalDealFilter func(ctx context.Context, deal retrievalmarket.ProviderDealState) (bool, string, error)
 
 type RetrievalPricingFunc func(ctx context.Context, dealPricingParams retrievalmarket.PricingInput) (retrievalmarket.Ask, error)
+
+// RetrievalTransport is a transport the retrieval provider serves retrievals
+// over besides graphsync, advertised to retrieval clients. The zero value
+// means no other transport is enabled.
+type RetrievalTransport struct {
+	// Name of the transport, e.g. "http"
+	Name string
+	// URL clients retrieve from
+	URL string
+}
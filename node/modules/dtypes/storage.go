@@ -1,6 +1,8 @@
 package dtypes
 
 import (
+	"time"
+
 	bserv "github.com/ipfs/go-blockservice"
 	"github.com/ipfs/go-datastore"
 	"github.com/ipfs/go-graphsync"
@@ -74,8 +76,30 @@ type ClientBlockstore blockstore.BasicBlockstore
 type ClientDealStore *statestore.StateStore
 type ClientRequestValidator *requestvalidation.UnifiedRequestValidator
 type ClientDatastore datastore.Batching
+
+// ClientDealTemplatesDS stores the deal templates saved by the client
+type ClientDealTemplatesDS datastore.Batching
 type ClientRetrievalStoreManager retrievalstoremgr.RetrievalStoreManager
 
+// ClientRetrievalQueryConfig controls timeouts, retries and concurrency of
+// retrieval queries made by the client
+type ClientRetrievalQueryConfig struct {
+	Timeout time.Duration
+	Retries int
+	Backoff time.Duration
+
+	// how many providers are queried at once when finding data, 0 = no limit
+	MaxConcurrent int
+	// how long finding data waits for offers, 0 = until all providers answer
+	FindDataTimeout time.Duration
+}
+
+// ClientImportConfig controls how files imported by the client are turned
+// into a DAG
+type ClientImportConfig struct {
+	Parallelism int
+}
+
 type Graphsync graphsync.GraphExchange
 
 // ClientDataTransfer is a data transfer manager for the client
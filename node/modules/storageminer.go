@@ -5,9 +5,13 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net"
 	"net/http"
 	"os"
 	"path/filepath"
+	"reflect"
+	"regexp"
+	"sync"
 	"time"
 
 	"github.com/filecoin-project/lotus/markets/pricing"
@@ -33,6 +37,7 @@ import (
 	dtnet "github.com/filecoin-project/go-data-transfer/network"
 	dtgstransport "github.com/filecoin-project/go-data-transfer/transport/graphsync"
 	piecefilestore "github.com/filecoin-project/go-fil-markets/filestore"
+	"github.com/filecoin-project/go-fil-markets/piecestore"
 	piecestoreimpl "github.com/filecoin-project/go-fil-markets/piecestore/impl"
 	"github.com/filecoin-project/go-fil-markets/retrievalmarket"
 	retrievalimpl "github.com/filecoin-project/go-fil-markets/retrievalmarket/impl"
@@ -46,10 +51,12 @@ import (
 	"github.com/filecoin-project/go-multistore"
 	paramfetch "github.com/filecoin-project/go-paramfetch"
 	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/crypto"
 	"github.com/filecoin-project/go-statestore"
 	"github.com/filecoin-project/go-storedcounter"
 
 	"github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/api/client"
 	sectorstorage "github.com/filecoin-project/lotus/extern/sector-storage"
 	"github.com/filecoin-project/lotus/extern/sector-storage/ffiwrapper"
 	"github.com/filecoin-project/lotus/extern/sector-storage/stores"
@@ -64,10 +71,18 @@ import (
 	"github.com/filecoin-project/lotus/chain/gen"
 	"github.com/filecoin-project/lotus/chain/gen/slashfilter"
 	"github.com/filecoin-project/lotus/chain/types"
+	cliutil "github.com/filecoin-project/lotus/cli/util"
 	"github.com/filecoin-project/lotus/journal"
 	"github.com/filecoin-project/lotus/markets"
+	"github.com/filecoin-project/lotus/markets/dealdiag"
+	"github.com/filecoin-project/lotus/markets/dealfilter"
+	"github.com/filecoin-project/lotus/markets/gsverify"
 	marketevents "github.com/filecoin-project/lotus/markets/loggers"
+	"github.com/filecoin-project/lotus/markets/quarantine"
 	"github.com/filecoin-project/lotus/markets/retrievaladapter"
+	"github.com/filecoin-project/lotus/markets/retrievalterms"
+	"github.com/filecoin-project/lotus/markets/retrievaltransport"
+	"github.com/filecoin-project/lotus/markets/sealeta"
 	lotusminer "github.com/filecoin-project/lotus/miner"
 	"github.com/filecoin-project/lotus/node/config"
 	"github.com/filecoin-project/lotus/node/modules/dtypes"
@@ -160,6 +175,7 @@ func AddressSelector(addrConf *config.MinerAddressConfig) func() (*storage.Addre
 
 		as.DisableOwnerFallback = addrConf.DisableOwnerFallback
 		as.DisableWorkerFallback = addrConf.DisableWorkerFallback
+		as.DealCollateralFromPublishControl = addrConf.DealCollateralFromPublishControl
 
 		for _, s := range addrConf.PreCommitControl {
 			addr, err := address.NewFromString(s)
@@ -197,6 +213,30 @@ func AddressSelector(addrConf *config.MinerAddressConfig) func() (*storage.Addre
 			as.DealPublishControl = append(as.DealPublishControl, addr)
 		}
 
+		defStrategy, err := storage.ParseAddrStrategy(addrConf.SelectionStrategy)
+		if err != nil {
+			return nil, xerrors.Errorf("parsing address selection strategy: %w", err)
+		}
+
+		as.Strategies = map[api.AddrUse]storage.AddrStrategy{}
+		for use, s := range map[api.AddrUse]string{
+			api.PreCommitAddr:        addrConf.PreCommitStrategy,
+			api.CommitAddr:           addrConf.CommitStrategy,
+			api.TerminateSectorsAddr: addrConf.TerminateStrategy,
+			api.DealPublishAddr:      addrConf.DealPublishStrategy,
+			api.PoStAddr:             addrConf.PoStStrategy,
+		} {
+			if s == "" {
+				as.Strategies[use] = defStrategy
+				continue
+			}
+
+			as.Strategies[use], err = storage.ParseAddrStrategy(s)
+			if err != nil {
+				return nil, xerrors.Errorf("parsing address selection strategy for message type %d: %w", use, err)
+			}
+		}
+
 		return as, nil
 	}
 }
@@ -216,9 +256,10 @@ type StorageMinerParams struct {
 	GetSealingConfigFn dtypes.GetSealingConfigFunc
 	Journal            journal.Journal
 	AddrSel            *storage.AddressSelector
+	FaultHistory       *storage.FaultHistory
 }
 
-func StorageMiner(fc config.MinerFeeConfig) func(params StorageMinerParams) (*storage.Miner, error) {
+func StorageMiner(fc config.MinerFeeConfig, pc config.ProvingConfig) func(params StorageMinerParams) (*storage.Miner, error) {
 	return func(params StorageMinerParams) (*storage.Miner, error) {
 		var (
 			ds     = params.MetadataDS
@@ -233,6 +274,7 @@ func StorageMiner(fc config.MinerFeeConfig) func(params StorageMinerParams) (*st
 			gsd    = params.GetSealingConfigFn
 			j      = params.Journal
 			as     = params.AddrSel
+			fh     = params.FaultHistory
 		)
 
 		maddr, err := minerAddrFromDS(ds)
@@ -242,7 +284,7 @@ func StorageMiner(fc config.MinerFeeConfig) func(params StorageMinerParams) (*st
 
 		ctx := helpers.LifecycleCtx(mctx, lc)
 
-		fps, err := storage.NewWindowedPoStScheduler(api, fc, as, sealer, verif, sealer, j, maddr)
+		fps, err := storage.NewWindowedPoStScheduler(api, fc, pc, as, sealer, verif, sealer, fh, j, maddr)
 		if err != nil {
 			return nil, err
 		}
@@ -251,6 +293,7 @@ func StorageMiner(fc config.MinerFeeConfig) func(params StorageMinerParams) (*st
 		if err != nil {
 			return nil, err
 		}
+		sm.SetWindowPoStScheduler(fps)
 
 		lc.Append(fx.Hook{
 			OnStart: func(context.Context) error {
@@ -264,6 +307,176 @@ func StorageMiner(fc config.MinerFeeConfig) func(params StorageMinerParams) (*st
 	}
 }
 
+// RetrievalOnlyStorageMiner constructs the miner of retrieval gateway nodes,
+// which doesn't run the sealing state machine or window PoSt, and reads
+// sector info from the sealing node
+func RetrievalOnlyStorageMiner(fc config.MinerFeeConfig) func(params StorageMinerParams, sealer SealerAPI) (*storage.Miner, error) {
+	return func(params StorageMinerParams, sealer SealerAPI) (*storage.Miner, error) {
+		maddr, err := minerAddrFromDS(params.MetadataDS)
+		if err != nil {
+			return nil, err
+		}
+
+		sm, err := storage.NewMiner(params.API, maddr, params.Host, params.MetadataDS, params.Sealer, params.SectorIDCounter, params.Verifier, params.Prover, params.GetSealingConfigFn, fc, params.Journal, params.AddrSel)
+		if err != nil {
+			return nil, err
+		}
+		sm.DisableSealing(sealer)
+
+		return sm, nil
+	}
+}
+
+// SealerAPI is the API of the sealing node for the miner actor, which
+// retrieval gateway nodes share the piecestore and the sector index with
+type SealerAPI api.StorageMiner
+
+// ConnectSealerService connects retrieval gateway nodes to the sealing node
+// for the miner actor
+func ConnectSealerService(apiInfo string) func(mctx helpers.MetricsCtx, lc fx.Lifecycle, maddr dtypes.MinerAddress) (SealerAPI, error) {
+	return func(mctx helpers.MetricsCtx, lc fx.Lifecycle, maddr dtypes.MinerAddress) (SealerAPI, error) {
+		ctx := helpers.LifecycleCtx(mctx, lc)
+
+		info := cliutil.ParseApiInfo(apiInfo)
+		addr, err := info.DialArgs("v0")
+		if err != nil {
+			return nil, xerrors.Errorf("could not get DialArgs: %w", err)
+		}
+
+		log.Infof("Connecting to sealing node at %s", addr)
+		sapi, closer, err := client.NewStorageMinerRPCV0(ctx, addr, info.AuthHeader())
+		if err != nil {
+			return nil, xerrors.Errorf("connecting to sealing node: %w", err)
+		}
+
+		lc.Append(fx.Hook{
+			OnStart: func(ctx context.Context) error {
+				ma, err := sapi.ActorAddress(ctx)
+				if err != nil {
+					return xerrors.Errorf("getting sealing node actor address: %w", err)
+				}
+				if ma != address.Address(maddr) {
+					return xerrors.Errorf("sealing node serves miner %s, expected %s", ma, address.Address(maddr))
+				}
+				return nil
+			},
+			OnStop: func(context.Context) error {
+				closer()
+				return nil
+			},
+		})
+
+		return sapi, nil
+	}
+}
+
+// SealerPieceStore reads the pieces of the deals taken by the sealing node
+func SealerPieceStore(mctx helpers.MetricsCtx, lc fx.Lifecycle, sealer SealerAPI) dtypes.ProviderPieceStore {
+	ps := retrievaladapter.NewRemotePieceStore(helpers.LifecycleCtx(mctx, lc), sealer)
+	ps.OnReady(marketevents.ReadyLogger("piecestore"))
+	lc.Append(fx.Hook{
+		OnStart: func(ctx context.Context) error {
+			return ps.Start(ctx)
+		},
+	})
+	return ps
+}
+
+// SealerSectorIndex locates sectors through the sector index of the sealing
+// node
+func SealerSectorIndex(sealer SealerAPI) stores.SectorIndex {
+	return sealer
+}
+
+// SealerStorageAuth authenticates requests reading sector data from the
+// storage of the sealing node
+func SealerStorageAuth(apiInfo string) func() sectorstorage.StorageAuth {
+	return func() sectorstorage.StorageAuth {
+		return sectorstorage.StorageAuth(cliutil.ParseApiInfo(apiInfo).AuthHeader())
+	}
+}
+
+// RetrievalOnlyDealFilter rejects all storage deals on retrieval gateway
+// nodes
+func RetrievalOnlyDealFilter() func() dtypes.StorageDealFilter {
+	return func() dtypes.StorageDealFilter {
+		return func(ctx context.Context, deal storagemarket.MinerDeal) (bool, string, error) {
+			return false, "miner only serves retrievals", nil
+		}
+	}
+}
+
+// how often the markets node is checked again after a failed check
+const marketsCheckRetry = time.Minute
+
+// ConnectMarketsService checks in the background that the markets node taking
+// storage deals for the miner is reachable and serves the same miner actor.
+// The miner doesn't depend on the markets node, so it starts without it, and
+// failed checks are logged and retried.
+func ConnectMarketsService(apiInfo string) func(mctx helpers.MetricsCtx, lc fx.Lifecycle, maddr dtypes.MinerAddress) error {
+	return func(mctx helpers.MetricsCtx, lc fx.Lifecycle, maddr dtypes.MinerAddress) error {
+		ctx := helpers.LifecycleCtx(mctx, lc)
+
+		info := cliutil.ParseApiInfo(apiInfo)
+		addr, err := info.DialArgs("v0")
+		if err != nil {
+			return xerrors.Errorf("could not get DialArgs: %w", err)
+		}
+
+		lc.Append(fx.Hook{
+			OnStart: func(context.Context) error {
+				go func() {
+					for {
+						err := checkMarketsNode(ctx, addr, info.AuthHeader(), address.Address(maddr))
+						if err == nil {
+							log.Infof("markets node at %s serves miner %s", addr, address.Address(maddr))
+							return
+						}
+						log.Warnw("checking markets node", "addr", addr, "error", err, "retry", marketsCheckRetry)
+
+						select {
+						case <-time.After(marketsCheckRetry):
+						case <-ctx.Done():
+							return
+						}
+					}
+				}()
+				return nil
+			},
+		})
+
+		return nil
+	}
+}
+
+func checkMarketsNode(ctx context.Context, addr string, header http.Header, maddr address.Address) error {
+	mapi, closer, err := client.NewStorageMinerRPCV0(ctx, addr, header)
+	if err != nil {
+		return xerrors.Errorf("connecting to markets node: %w", err)
+	}
+	defer closer()
+
+	ma, err := mapi.ActorAddress(ctx)
+	if err != nil {
+		return xerrors.Errorf("getting markets node actor address: %w", err)
+	}
+	if ma != maddr {
+		return xerrors.Errorf("markets node serves miner %s, expected %s", ma, maddr)
+	}
+	return nil
+}
+
+// RetrievalOnlySealerConfig disables sealing tasks on the local worker of
+// retrieval gateway nodes, keeping only unsealing for the retrieval read path
+func RetrievalOnlySealerConfig(cfg sectorstorage.SealerConfig) sectorstorage.SealerConfig {
+	cfg.AllowAddPiece = false
+	cfg.AllowPreCommit1 = false
+	cfg.AllowPreCommit2 = false
+	cfg.AllowCommit = false
+	cfg.AllowUnseal = true
+	return cfg
+}
+
 func HandleRetrieval(host host.Host, lc fx.Lifecycle, m retrievalmarket.RetrievalProvider, j journal.Journal) {
 	m.OnReady(marketevents.ReadyLogger("retrieval provider"))
 	lc.Append(fx.Hook{
@@ -282,7 +495,7 @@ func HandleRetrieval(host host.Host, lc fx.Lifecycle, m retrievalmarket.Retrieva
 	})
 }
 
-func HandleDeals(mctx helpers.MetricsCtx, lc fx.Lifecycle, host host.Host, h storagemarket.StorageProvider, j journal.Journal) {
+func HandleDeals(mctx helpers.MetricsCtx, lc fx.Lifecycle, host host.Host, h storagemarket.StorageProvider, j journal.Journal, diag *dealdiag.Recorder) {
 	ctx := helpers.LifecycleCtx(mctx, lc)
 	h.OnReady(marketevents.ReadyLogger("storage provider"))
 	lc.Append(fx.Hook{
@@ -291,6 +504,7 @@ func HandleDeals(mctx helpers.MetricsCtx, lc fx.Lifecycle, host host.Host, h sto
 
 			evtType := j.RegisterEventType("markets/storage/provider", "state_change")
 			h.SubscribeToEvents(markets.StorageProviderJournaler(j, evtType))
+			h.SubscribeToEvents(diag.OnEvent)
 
 			return h.Start(ctx)
 		},
@@ -300,6 +514,91 @@ func HandleDeals(mctx helpers.MetricsCtx, lc fx.Lifecycle, host host.Host, h sto
 	})
 }
 
+func NewFaultHistory(ds dtypes.MetadataDS) *storage.FaultHistory {
+	return storage.NewFaultHistory(namespace.Wrap(ds, datastore.NewKey("/sector-fault-history")))
+}
+
+// NewParamsVerifier schedules the verification of the proof parameter files,
+// every Proving.ParamsVerifyInterval
+func NewParamsVerifier(mctx helpers.MetricsCtx, lc fx.Lifecycle, r repo.LockedRepo, spt abi.RegisteredSealProof) (*storage.ParamsVerifier, error) {
+	ssize, err := spt.SectorSize()
+	if err != nil {
+		return nil, err
+	}
+
+	v, err := storage.NewParamsVerifier(build.ParametersJSON(), uint64(ssize), func() (out time.Duration, err error) {
+		err = readCfg(r, func(cfg *config.StorageMiner) {
+			out = time.Duration(cfg.Proving.ParamsVerifyInterval)
+		})
+		return
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := helpers.LifecycleCtx(mctx, lc)
+	lc.Append(fx.Hook{
+		OnStart: func(context.Context) error {
+			go v.Run(ctx)
+			return nil
+		},
+		OnStop: v.Stop,
+	})
+
+	return v, nil
+}
+
+// HandleSealETA serves deal sealing ETA queries from storage clients
+func HandleSealETA(host host.Host, m *storage.Miner) {
+	svc := sealeta.NewService(m)
+	host.SetStreamHandler(sealeta.ProtocolID, svc.HandleStream)
+}
+
+func NewDealDiagnostics() *dealdiag.Recorder {
+	return dealdiag.NewRecorder(dealdiag.DefaultSize)
+}
+
+// NewRetrievalAskTiersStore keeps the retrieval ask tiers, which can't be set
+// when retrievals are priced by an external pricing script
+func NewRetrievalAskTiersStore(cfg config.DealmakingConfig) func(ds dtypes.MetadataDS) *pricing.TiersStore {
+	return func(ds dtypes.MetadataDS) *pricing.TiersStore {
+		if cfg.RetrievalPricing != nil && cfg.RetrievalPricing.Strategy == config.RetrievalPricingExternalMode {
+			return pricing.NewUnusedTiersStore(ds, "retrievals are priced by the external pricing script")
+		}
+		return pricing.NewTiersStore(ds)
+	}
+}
+
+func NewRetrievalTermsStore(ds dtypes.MetadataDS) *retrievalterms.Store {
+	return retrievalterms.NewStore(namespace.Wrap(ds, datastore.NewKey("/retrieval-terms")))
+}
+
+// HandleRetrievalTerms accepts per-deal retrieval terms from storage clients,
+// see Dealmaking.ClientRetrievalTerms
+func HandleRetrievalTerms(host host.Host, sp storagemarket.StorageProvider, node api.FullNode, store *retrievalterms.Store, r repo.LockedRepo) {
+	policy := func() (out retrievalterms.Policy, err error) {
+		err = readCfg(r, func(cfg *config.StorageMiner) {
+			out = retrievalterms.Policy{
+				Accept:          cfg.Dealmaking.ClientRetrievalTerms.Accept,
+				MinPricePerByte: abi.TokenAmount(cfg.Dealmaking.ClientRetrievalTerms.MinPricePerByte),
+				MinUnsealPrice:  abi.TokenAmount(cfg.Dealmaking.ClientRetrievalTerms.MinUnsealPrice),
+			}
+		})
+		return
+	}
+
+	verify := func(ctx context.Context, addr address.Address, data []byte, sig *crypto.Signature) (bool, error) {
+		key, err := node.StateAccountKey(ctx, addr, types.EmptyTSK)
+		if err != nil {
+			return false, xerrors.Errorf("resolving client key address: %w", err)
+		}
+		return node.WalletVerify(ctx, key, data, sig)
+	}
+
+	svc := retrievalterms.NewService(store, sp.GetLocalDeal, verify, policy)
+	host.SetStreamHandler(retrievalterms.ProtocolID, svc.HandleStream)
+}
+
 func HandleMigrateProviderFunds(lc fx.Lifecycle, ds dtypes.MetadataDS, node api.FullNode, minerAddress dtypes.MinerAddress) {
 	lc.Append(fx.Hook{
 		OnStart: func(ctx context.Context) error {
@@ -385,14 +684,15 @@ func NewProviderPieceStore(lc fx.Lifecycle, ds dtypes.MetadataDS) (dtypes.Provid
 	return ps, nil
 }
 
-func StagingMultiDatastore(lc fx.Lifecycle, mctx helpers.MetricsCtx, r repo.LockedRepo) (dtypes.StagingMultiDstore, error) {
+func StagingMultiDatastore(lc fx.Lifecycle, mctx helpers.MetricsCtx, r repo.LockedRepo, qs *quarantine.Store) (dtypes.StagingMultiDstore, error) {
 	ctx := helpers.LifecycleCtx(mctx, lc)
 	ds, err := r.Datastore(ctx, "/staging")
 	if err != nil {
 		return nil, xerrors.Errorf("getting datastore out of reop: %w", err)
 	}
 
-	mds, err := multistore.NewMultiDstore(ds)
+	// stores of failed deals are quarantined when they're deleted
+	mds, err := multistore.NewMultiDstore(qs.WrapStaging(ds))
 	if err != nil {
 		return nil, err
 	}
@@ -440,18 +740,21 @@ func StagingDAG(mctx helpers.MetricsCtx, lc fx.Lifecycle, ibs dtypes.StagingBloc
 
 // StagingGraphsync creates a graphsync instance which reads and writes blocks
 // to the StagingBlockstore
-func StagingGraphsync(parallelTransfers uint64) func(mctx helpers.MetricsCtx, lc fx.Lifecycle, ibs dtypes.StagingBlockstore, h host.Host) dtypes.StagingGraphsync {
+func StagingGraphsync(parallelTransfers uint64, verifyBlocks bool) func(mctx helpers.MetricsCtx, lc fx.Lifecycle, ibs dtypes.StagingBlockstore, h host.Host) dtypes.StagingGraphsync {
 	return func(mctx helpers.MetricsCtx, lc fx.Lifecycle, ibs dtypes.StagingBlockstore, h host.Host) dtypes.StagingGraphsync {
 		graphsyncNetwork := gsnet.NewFromLibp2pHost(h)
 		loader := storeutil.LoaderForBlockstore(ibs)
 		storer := storeutil.StorerForBlockstore(ibs)
 		gs := graphsync.New(helpers.LifecycleCtx(mctx, lc), graphsyncNetwork, loader, storer, graphsync.RejectAllRequestsByDefault(), graphsync.MaxInProgressRequests(parallelTransfers))
+		if verifyBlocks {
+			gs.RegisterIncomingBlockHook(gsverify.IncomingBlockHook)
+		}
 
 		return gs
 	}
 }
 
-func SetupBlockProducer(lc fx.Lifecycle, ds dtypes.MetadataDS, api v1api.FullNode, epp gen.WinningPoStProver, sf *slashfilter.SlashFilter, j journal.Journal) (*lotusminer.Miner, error) {
+func SetupBlockProducer(lc fx.Lifecycle, ds dtypes.MetadataDS, api v1api.FullNode, epp gen.WinningPoStProver, sf slashfilter.SlashFilter, j journal.Journal) (*lotusminer.Miner, error) {
 	minerAddr, err := minerAddrFromDS(ds)
 	if err != nil {
 		return nil, err
@@ -474,6 +777,19 @@ func SetupBlockProducer(lc fx.Lifecycle, ds dtypes.MetadataDS, api v1api.FullNod
 	return m, nil
 }
 
+// DisabledBlockProducer constructs the block producer of nodes which don't
+// mine, like retrieval gateways, without starting it
+func DisabledBlockProducer(ds dtypes.MetadataDS, api v1api.FullNode, epp gen.WinningPoStProver, sf slashfilter.SlashFilter, j journal.Journal) (*lotusminer.Miner, error) {
+	minerAddr, err := minerAddrFromDS(ds)
+	if err != nil {
+		return nil, err
+	}
+
+	m := lotusminer.NewMiner(api, epp, minerAddr, sf, j)
+	m.SetEnabled(false)
+	return m, nil
+}
+
 func NewStorageAsk(ctx helpers.MetricsCtx, fapi v1api.FullNode, ds dtypes.MetadataDS, minerAddress dtypes.MinerAddress, spn storagemarket.StorageProviderNode) (*storedask.StoredAsk, error) {
 
 	mi, err := fapi.StateMinerInfo(ctx, address.Address(minerAddress), types.EmptyTSK)
@@ -498,6 +814,14 @@ func BasicDealFilter(user dtypes.StorageDealFilter) func(onlineOk dtypes.Conside
 	blocklistFunc dtypes.StorageDealPieceCidBlocklistConfigFunc,
 	expectedSealTimeFunc dtypes.GetExpectedSealDurationFunc,
 	startDelay dtypes.GetMaxDealStartDelayFunc,
+	targetFillFunc dtypes.GetTargetSectorFillRatioFunc,
+	openSectorsFunc dtypes.GetOpenSectorsFunc,
+	reputation *dealfilter.ReputationFilter,
+	rateLimiter *dealfilter.ClientRateLimiter,
+	clientFilter *dealfilter.ClientFilter,
+	requireEscrowFunc dtypes.GetRequireClientEscrowFunc,
+	labelPatternsFunc dtypes.GetRequiredLabelPatternsFunc,
+	counterOfferFunc dtypes.GetStartEpochCounterOfferSlackFunc,
 	spn storagemarket.StorageProviderNode) dtypes.StorageDealFilter {
 	return func(onlineOk dtypes.ConsiderOnlineStorageDealsConfigFunc,
 		offlineOk dtypes.ConsiderOfflineStorageDealsConfigFunc,
@@ -506,6 +830,14 @@ func BasicDealFilter(user dtypes.StorageDealFilter) func(onlineOk dtypes.Conside
 		blocklistFunc dtypes.StorageDealPieceCidBlocklistConfigFunc,
 		expectedSealTimeFunc dtypes.GetExpectedSealDurationFunc,
 		startDelay dtypes.GetMaxDealStartDelayFunc,
+		targetFillFunc dtypes.GetTargetSectorFillRatioFunc,
+		openSectorsFunc dtypes.GetOpenSectorsFunc,
+		reputation *dealfilter.ReputationFilter,
+		rateLimiter *dealfilter.ClientRateLimiter,
+		clientFilter *dealfilter.ClientFilter,
+		requireEscrowFunc dtypes.GetRequireClientEscrowFunc,
+		labelPatternsFunc dtypes.GetRequiredLabelPatternsFunc,
+		counterOfferFunc dtypes.GetStartEpochCounterOfferSlackFunc,
 		spn storagemarket.StorageProviderNode) dtypes.StorageDealFilter {
 
 		return func(ctx context.Context, deal storagemarket.MinerDeal) (bool, string, error) {
@@ -561,20 +893,58 @@ func BasicDealFilter(user dtypes.StorageDealFilter) func(onlineOk dtypes.Conside
 				}
 			}
 
+			if ok, reason, err := clientFilter.Check(ctx, deal); !ok {
+				if err == nil {
+					log.Warnw("client filtered out; rejecting storage deal proposal", "piece_cid", deal.Proposal.PieceCID, "client", deal.Proposal.Client, "reason", reason)
+				}
+				return false, reason, err
+			}
+
+			labelPatterns, err := labelPatternsFunc()
+			if err != nil {
+				return false, "miner error", err
+			}
+
+			if ok, reason := dealfilter.Label(deal.Proposal.Label, labelPatterns); !ok {
+				log.Warnw("deal label doesn't match required patterns; rejecting storage deal proposal", "piece_cid", deal.Proposal.PieceCID, "client", deal.Proposal.Client, "label", deal.Proposal.Label)
+				return false, reason, nil
+			}
+
 			sealDuration, err := expectedSealTimeFunc()
 			if err != nil {
 				return false, "miner error", err
 			}
 
 			sealEpochs := sealDuration / (time.Duration(build.BlockDelaySecs) * time.Second)
-			_, ht, err := spn.GetChainHead(ctx)
+			tok, ht, err := spn.GetChainHead(ctx)
 			if err != nil {
 				return false, "failed to get chain head", err
 			}
 			earliest := abi.ChainEpoch(sealEpochs) + ht
 			if deal.Proposal.StartEpoch < earliest {
 				log.Warnw("proposed deal would start before sealing can be completed; rejecting storage deal proposal from client", "piece_cid", deal.Proposal.PieceCID, "client", deal.Client.String(), "seal_duration", sealDuration, "earliest", earliest, "curepoch", ht)
-				return false, fmt.Sprintf("cannot seal a sector before %s", deal.Proposal.StartEpoch), nil
+				reason := fmt.Sprintf("cannot seal a sector before %s", deal.Proposal.StartEpoch)
+
+				slack, err := counterOfferFunc()
+				if err != nil {
+					return false, "miner error", err
+				}
+
+				if slack > 0 {
+					sd, err := startDelay()
+					if err != nil {
+						return false, "miner error", err
+					}
+
+					start, ok := dealfilter.CounterOfferStartEpoch(ht, earliest,
+						abi.ChainEpoch(uint64(slack.Seconds())/build.BlockDelaySecs),
+						abi.ChainEpoch(uint64(sd.Seconds())/build.BlockDelaySecs))
+					if ok {
+						reason = dealfilter.StartEpochCounterOffer{Reason: reason, StartEpoch: start}.Message()
+					}
+				}
+
+				return false, reason, nil
 			}
 
 			sd, err := startDelay()
@@ -589,8 +959,59 @@ func BasicDealFilter(user dtypes.StorageDealFilter) func(onlineOk dtypes.Conside
 				return false, fmt.Sprintf("deal start epoch is too far in the future: %s > %s", deal.Proposal.StartEpoch, maxStartEpoch), nil
 			}
 
+			targetFill, err := targetFillFunc()
+			if err != nil {
+				return false, "miner error", err
+			}
+
+			if targetFill > 0 {
+				open, err := openSectorsFunc(ctx)
+				if err != nil {
+					return false, "miner error", err
+				}
+
+				if ok, reason := dealfilter.SectorFill(deal.Proposal.PieceSize, open, targetFill); !ok {
+					log.Warnw("proposed deal would not fill open sectors well enough; rejecting storage deal proposal from client", "piece_cid", deal.Proposal.PieceCID, "client", deal.Client.String(), "reason", reason)
+					return false, reason, nil
+				}
+			}
+
+			requireEscrow, err := requireEscrowFunc()
+			if err != nil {
+				return false, "miner error", err
+			}
+
+			if requireEscrow {
+				bal, err := spn.GetBalance(ctx, deal.Proposal.Client, tok)
+				if err != nil {
+					return false, "failed to get client market balance", err
+				}
+
+				if ok, reason := dealfilter.ClientEscrow(deal.Proposal.ClientBalanceRequirement(), bal); !ok {
+					log.Warnw("client doesn't have enough funds in market escrow; rejecting storage deal proposal", "piece_cid", deal.Proposal.PieceCID, "client", deal.Proposal.Client, "reason", reason)
+					return false, reason, nil
+				}
+			}
+
+			if ok, reason, err := reputation.Check(ctx, deal.Proposal.Client); !ok {
+				if err == nil {
+					log.Warnw("client reputation too low; rejecting storage deal proposal", "piece_cid", deal.Proposal.PieceCID, "client", deal.Proposal.Client, "reason", reason)
+				}
+				return false, reason, err
+			}
+
 			if user != nil {
-				return user(ctx, deal)
+				if ok, reason, err := user(ctx, deal); !ok || err != nil {
+					return ok, reason, err
+				}
+			}
+
+			// only count deals which would otherwise be accepted
+			if ok, reason, err := rateLimiter.Take(deal.Proposal.Client); !ok {
+				if err == nil {
+					log.Warnw("client reached deal rate limit; rejecting storage deal proposal", "piece_cid", deal.Proposal.PieceCID, "client", deal.Proposal.Client, "reason", reason)
+				}
+				return false, reason, err
 			}
 
 			return true, "", nil
@@ -607,22 +1028,73 @@ func StorageProvider(minerAddress dtypes.MinerAddress,
 	dataTransfer dtypes.ProviderDataTransfer,
 	spn storagemarket.StorageProviderNode,
 	df dtypes.StorageDealFilter,
+	qs *quarantine.Store,
+	cf *dealfilter.ClientFilter,
 ) (storagemarket.StorageProvider, error) {
 	net := smnet.NewFromLibp2pHost(h)
-	store, err := piecefilestore.NewLocalFileStore(piecefilestore.OsPath(r.Path()))
+
+	opt := storageimpl.CustomDealDecisionLogic(storageimpl.DealDeciderFunc(df))
+
+	// TODO: the provider computes CommP of incoming deal data in this process,
+	//  with no option to supply the computation, so it can't be dispatched to
+	//  sealing workers yet
+	p, err := storageimpl.NewProvider(net, namespace.Wrap(ds, datastore.NewKey("/deals/provider")), qs, mds, pieceStore, dataTransfer, spn, address.Address(minerAddress), storedAsk, opt)
 	if err != nil {
 		return nil, err
 	}
 
-	opt := storageimpl.CustomDealDecisionLogic(storageimpl.DealDeciderFunc(df))
+	// the quarantine store tracks which deal staged data belongs to from
+	// provider events, starting with the deals restored on startup
+	deals, err := p.ListLocalDeals()
+	if err != nil {
+		return nil, xerrors.Errorf("listing deals: %w", err)
+	}
+	for _, deal := range deals {
+		qs.Track(deal)
+		cf.Track(context.TODO(), deal)
+	}
+	p.SubscribeToEvents(func(_ storagemarket.ProviderEvent, deal storagemarket.MinerDeal) {
+		qs.Track(deal)
+		cf.Track(context.TODO(), deal)
+	})
+	qs.SetDealGetter(p.GetLocalDeal)
 
-	return storageimpl.NewProvider(net, namespace.Wrap(ds, datastore.NewKey("/deals/provider")), store, mds, pieceStore, dataTransfer, spn, address.Address(minerAddress), storedAsk, opt)
+	return p, nil
+}
+
+// NewQuarantineStore creates the file store staged deal data is kept in, which
+// keeps the data of failed deals for Dealmaking.FailedDealRetention
+func NewQuarantineStore(mctx helpers.MetricsCtx, lc fx.Lifecycle, r repo.LockedRepo) (*quarantine.Store, error) {
+	fs, err := piecefilestore.NewLocalFileStore(piecefilestore.OsPath(r.Path()))
+	if err != nil {
+		return nil, err
+	}
+
+	qs, err := quarantine.New(fs, r.Path(), filepath.Join(r.Path(), "deal-quarantine"), func() (out time.Duration, err error) {
+		err = readCfg(r, func(cfg *config.StorageMiner) {
+			out = time.Duration(cfg.Dealmaking.FailedDealRetention)
+		})
+		return
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := helpers.LifecycleCtx(mctx, lc)
+	lc.Append(fx.Hook{
+		OnStart: func(context.Context) error {
+			go qs.Run(ctx, time.Hour)
+			return nil
+		},
+	})
+
+	return qs, nil
 }
 
 func RetrievalDealFilter(userFilter dtypes.RetrievalDealFilter) func(onlineOk dtypes.ConsiderOnlineRetrievalDealsConfigFunc,
-	offlineOk dtypes.ConsiderOfflineRetrievalDealsConfigFunc) dtypes.RetrievalDealFilter {
+	offlineOk dtypes.ConsiderOfflineRetrievalDealsConfigFunc, rules *dealfilter.RetrievalFilter) dtypes.RetrievalDealFilter {
 	return func(onlineOk dtypes.ConsiderOnlineRetrievalDealsConfigFunc,
-		offlineOk dtypes.ConsiderOfflineRetrievalDealsConfigFunc) dtypes.RetrievalDealFilter {
+		offlineOk dtypes.ConsiderOfflineRetrievalDealsConfigFunc, rules *dealfilter.RetrievalFilter) dtypes.RetrievalDealFilter {
 		return func(ctx context.Context, state retrievalmarket.ProviderDealState) (bool, string, error) {
 			b, err := onlineOk()
 			if err != nil {
@@ -643,6 +1115,10 @@ func RetrievalDealFilter(userFilter dtypes.RetrievalDealFilter) func(onlineOk dt
 				log.Info("offline retrieval has not been implemented yet")
 			}
 
+			if ok, reason, err := rules.Check(ctx, state); err != nil || !ok {
+				return ok, reason, err
+			}
+
 			if userFilter != nil {
 				return userFilter(ctx, state)
 			}
@@ -653,16 +1129,26 @@ func RetrievalDealFilter(userFilter dtypes.RetrievalDealFilter) func(onlineOk dt
 }
 
 // RetrievalPricingFunc configures the pricing function to use for retrieval deals.
+// Retrievals of pieces which the deal client set retrieval terms for are priced
+// at those terms.
 func RetrievalPricingFunc(cfg config.DealmakingConfig) func(_ dtypes.ConsiderOnlineRetrievalDealsConfigFunc,
-	_ dtypes.ConsiderOfflineRetrievalDealsConfigFunc) dtypes.RetrievalPricingFunc {
+	_ dtypes.ConsiderOfflineRetrievalDealsConfigFunc, terms *retrievalterms.Store, tiers *pricing.TiersStore, pieceStore dtypes.ProviderPieceStore, node api.FullNode) dtypes.RetrievalPricingFunc {
 
 	return func(_ dtypes.ConsiderOnlineRetrievalDealsConfigFunc,
-		_ dtypes.ConsiderOfflineRetrievalDealsConfigFunc) dtypes.RetrievalPricingFunc {
+		_ dtypes.ConsiderOfflineRetrievalDealsConfigFunc, terms *retrievalterms.Store, tiers *pricing.TiersStore, pieceStore dtypes.ProviderPieceStore, node api.FullNode) dtypes.RetrievalPricingFunc {
+		height := func(ctx context.Context) (abi.ChainEpoch, error) {
+			ts, err := node.ChainHead(ctx)
+			if err != nil {
+				return 0, err
+			}
+			return ts.Height(), nil
+		}
+
 		if cfg.RetrievalPricing.Strategy == config.RetrievalPricingExternalMode {
-			return pricing.ExternalRetrievalPricingFunc(cfg.RetrievalPricing.External.Path)
+			return retrievalterms.PricingFunc(terms, height, pricing.ExternalRetrievalPricingFunc(cfg.RetrievalPricing.External.Path))
 		}
 
-		return retrievalimpl.DefaultPricingFunc(cfg.RetrievalPricing.Default.VerifiedDealsFreeTransfer)
+		return retrievalterms.PricingFunc(terms, height, pricing.TieredPricingFunc(tiers, pricing.PiecePayloadSize(pieceStore), retrievalimpl.DefaultPricingFunc(cfg.RetrievalPricing.Default.VerifiedDealsFreeTransfer)))
 	}
 }
 
@@ -692,6 +1178,66 @@ func RetrievalProvider(h host.Host,
 		retrievalimpl.RetrievalPricingFunc(pricingFnc), opt)
 }
 
+// HTTPRetrievalTransport serves retrievals over HTTP, see
+// Dealmaking.HTTPRetrieval. The server starts listening right away, so that
+// the URL of a server listening on a random port can be advertised.
+func HTTPRetrievalTransport(cfg config.HTTPRetrievalConfig) func(lc fx.Lifecycle,
+	miner *storage.Miner,
+	full v1api.FullNode,
+	pieceStore dtypes.ProviderPieceStore,
+	pieceProvider sectorstorage.PieceProvider,
+	accepting dtypes.ConsiderOnlineRetrievalDealsConfigFunc,
+) (dtypes.RetrievalTransport, error) {
+	return func(lc fx.Lifecycle,
+		miner *storage.Miner,
+		full v1api.FullNode,
+		pieceStore dtypes.ProviderPieceStore,
+		pieceProvider sectorstorage.PieceProvider,
+		accepting dtypes.ConsiderOnlineRetrievalDealsConfigFunc,
+	) (dtypes.RetrievalTransport, error) {
+		ln, err := net.Listen("tcp", cfg.ListenAddress)
+		if err != nil {
+			return dtypes.RetrievalTransport{}, xerrors.Errorf("listening for HTTP retrievals on %s: %w", cfg.ListenAddress, err)
+		}
+
+		url := cfg.PublicURL
+		if url == "" {
+			url = "http://" + ln.Addr().String()
+		}
+
+		adapter := retrievaladapter.NewRetrievalProviderNode(miner, pieceProvider, full)
+		srv := &http.Server{Handler: retrievaltransport.NewHTTPHandler(pieceStore, adapter, accepting)}
+
+		lc.Append(fx.Hook{
+			OnStart: func(context.Context) error {
+				go func() {
+					if err := srv.Serve(ln); err != nil && err != http.ErrServerClosed {
+						log.Errorw("HTTP retrieval server failed", "error", err)
+					}
+				}()
+				log.Infow("serving retrievals over HTTP", "listen", ln.Addr(), "url", url)
+				return nil
+			},
+			OnStop: func(ctx context.Context) error {
+				return srv.Shutdown(ctx)
+			},
+		})
+
+		return dtypes.RetrievalTransport{Name: retrievaltransport.HTTP, URL: url}, nil
+	}
+}
+
+// HandleRetrievalTransports advertises the transports retrievals are served
+// over besides graphsync to retrieval clients
+func HandleRetrievalTransports(host host.Host, transport dtypes.RetrievalTransport) {
+	var transports []retrievaltransport.Transport
+	if transport.Name != "" {
+		transports = append(transports, retrievaltransport.Transport{Name: transport.Name, URL: transport.URL})
+	}
+
+	host.SetStreamHandler(retrievaltransport.ProtocolID, retrievaltransport.NewService(transports).HandleStream)
+}
+
 var WorkerCallsPrefix = datastore.NewKey("/worker/calls")
 var ManagerWorkPrefix = datastore.NewKey("/stmgr/calls")
 
@@ -866,9 +1412,15 @@ func NewSetSealConfigFunc(r repo.LockedRepo) (dtypes.SetSealingConfigFunc, error
 				MaxWaitDealsSectors:       cfg.MaxWaitDealsSectors,
 				MaxSealingSectors:         cfg.MaxSealingSectors,
 				MaxSealingSectorsForDeals: cfg.MaxSealingSectorsForDeals,
+				MaxPreCommitting:          cfg.MaxPreCommitting,
+				MaxCommitting:             cfg.MaxCommitting,
 				WaitDealsDelay:            config.Duration(cfg.WaitDealsDelay),
+				ConservativePacking:       cfg.ConservativePacking,
 				AlwaysKeepUnsealedCopy:    cfg.AlwaysKeepUnsealedCopy,
+				KeepUnsealedVerifiedDeals: cfg.KeepUnsealedVerifiedDeals,
+				KeepUnsealedMinPieceSize:  uint64(cfg.KeepUnsealedMinPieceSize),
 				FinalizeEarly:             cfg.FinalizeEarly,
+				UseSyntheticPoRep:         cfg.UseSyntheticPoRep,
 
 				BatchPreCommits:     cfg.BatchPreCommits,
 				MaxPreCommitBatch:   cfg.MaxPreCommitBatch,
@@ -885,6 +1437,18 @@ func NewSetSealConfigFunc(r repo.LockedRepo) (dtypes.SetSealingConfigFunc, error
 				TerminateBatchMax:  cfg.TerminateBatchMax,
 				TerminateBatchMin:  cfg.TerminateBatchMin,
 				TerminateBatchWait: config.Duration(cfg.TerminateBatchWait),
+
+				AutoExtendSectors:       cfg.AutoExtendSectors,
+				AutoExtendDealSectors:   cfg.AutoExtendDealSectors,
+				AutoExtendWindow:        config.Duration(cfg.AutoExtendWindow),
+				AutoExtendDuration:      config.Duration(cfg.AutoExtendDuration),
+				AutoExtendCheckInterval: config.Duration(cfg.AutoExtendCheckInterval),
+
+				DeferFinalize:         cfg.DeferFinalize,
+				FinalizeBatchSize:     cfg.FinalizeBatchSize,
+				FinalizeBatchInterval: config.Duration(cfg.FinalizeBatchInterval),
+
+				ReconcileInterval: config.Duration(cfg.ReconcileInterval),
 			}
 		})
 		return
@@ -896,9 +1460,15 @@ func ToSealingConfig(cfg *config.StorageMiner) sealiface.Config {
 		MaxWaitDealsSectors:       cfg.Sealing.MaxWaitDealsSectors,
 		MaxSealingSectors:         cfg.Sealing.MaxSealingSectors,
 		MaxSealingSectorsForDeals: cfg.Sealing.MaxSealingSectorsForDeals,
+		MaxPreCommitting:          cfg.Sealing.MaxPreCommitting,
+		MaxCommitting:             cfg.Sealing.MaxCommitting,
 		WaitDealsDelay:            time.Duration(cfg.Sealing.WaitDealsDelay),
+		ConservativePacking:       cfg.Sealing.ConservativePacking,
 		AlwaysKeepUnsealedCopy:    cfg.Sealing.AlwaysKeepUnsealedCopy,
+		KeepUnsealedVerifiedDeals: cfg.Sealing.KeepUnsealedVerifiedDeals,
+		KeepUnsealedMinPieceSize:  abi.PaddedPieceSize(cfg.Sealing.KeepUnsealedMinPieceSize),
 		FinalizeEarly:             cfg.Sealing.FinalizeEarly,
+		UseSyntheticPoRep:         cfg.Sealing.UseSyntheticPoRep,
 
 		BatchPreCommits:     cfg.Sealing.BatchPreCommits,
 		MaxPreCommitBatch:   cfg.Sealing.MaxPreCommitBatch,
@@ -915,6 +1485,18 @@ func ToSealingConfig(cfg *config.StorageMiner) sealiface.Config {
 		TerminateBatchMax:  cfg.Sealing.TerminateBatchMax,
 		TerminateBatchMin:  cfg.Sealing.TerminateBatchMin,
 		TerminateBatchWait: time.Duration(cfg.Sealing.TerminateBatchWait),
+
+		AutoExtendSectors:       cfg.Sealing.AutoExtendSectors,
+		AutoExtendDealSectors:   cfg.Sealing.AutoExtendDealSectors,
+		AutoExtendWindow:        time.Duration(cfg.Sealing.AutoExtendWindow),
+		AutoExtendDuration:      time.Duration(cfg.Sealing.AutoExtendDuration),
+		AutoExtendCheckInterval: time.Duration(cfg.Sealing.AutoExtendCheckInterval),
+
+		DeferFinalize:         cfg.Sealing.DeferFinalize,
+		FinalizeBatchSize:     cfg.Sealing.FinalizeBatchSize,
+		FinalizeBatchInterval: time.Duration(cfg.Sealing.FinalizeBatchInterval),
+
+		ReconcileInterval: time.Duration(cfg.Sealing.ReconcileInterval),
 	}
 }
 
@@ -936,11 +1518,20 @@ func NewSetExpectedSealDurationFunc(r repo.LockedRepo) (dtypes.SetExpectedSealDu
 	}, nil
 }
 
-func NewGetExpectedSealDurationFunc(r repo.LockedRepo) (dtypes.GetExpectedSealDurationFunc, error) {
+func NewGetExpectedSealDurationFunc(r repo.LockedRepo, m *storage.Miner) (dtypes.GetExpectedSealDurationFunc, error) {
 	return func() (out time.Duration, err error) {
+		var auto bool
 		err = readCfg(r, func(cfg *config.StorageMiner) {
 			out = time.Duration(cfg.Dealmaking.ExpectedSealDuration)
+			auto = cfg.Dealmaking.AutoExpectedSealDuration
 		})
+		if err != nil || !auto {
+			return
+		}
+
+		if recent, ok := m.RecentSealDuration(); ok {
+			out = recent
+		}
 		return
 	}, nil
 }
@@ -963,6 +1554,188 @@ func NewGetMaxDealStartDelayFunc(r repo.LockedRepo) (dtypes.GetMaxDealStartDelay
 	}, nil
 }
 
+func NewGetTargetSectorFillRatioFunc(r repo.LockedRepo) (dtypes.GetTargetSectorFillRatioFunc, error) {
+	return func() (out float64, err error) {
+		err = readCfg(r, func(cfg *config.StorageMiner) {
+			out = cfg.Dealmaking.TargetSectorFillRatio
+		})
+		return
+	}, nil
+}
+
+func NewGetDealFilterCmdFunc(r repo.LockedRepo) (dtypes.GetDealFilterCmdFunc, error) {
+	return func() (out string, err error) {
+		err = readCfg(r, func(cfg *config.StorageMiner) {
+			out = cfg.Dealmaking.Filter
+		})
+		return
+	}, nil
+}
+
+func NewGetRequireClientEscrowFunc(r repo.LockedRepo) (dtypes.GetRequireClientEscrowFunc, error) {
+	return func() (out bool, err error) {
+		err = readCfg(r, func(cfg *config.StorageMiner) {
+			out = cfg.Dealmaking.RequireClientEscrow
+		})
+		return
+	}, nil
+}
+
+func NewGetStartEpochCounterOfferSlackFunc(r repo.LockedRepo) (dtypes.GetStartEpochCounterOfferSlackFunc, error) {
+	return func() (out time.Duration, err error) {
+		err = readCfg(r, func(cfg *config.StorageMiner) {
+			out = time.Duration(cfg.Dealmaking.StartEpochCounterOfferSlack)
+		})
+		return
+	}, nil
+}
+
+func NewGetRequiredLabelPatternsFunc(r repo.LockedRepo) (dtypes.GetRequiredLabelPatternsFunc, error) {
+	var (
+		lk       sync.Mutex
+		patterns []string
+		compiled []*regexp.Regexp
+		loaded   bool
+	)
+
+	get := func() ([]*regexp.Regexp, error) {
+		var cur []string
+		if err := readCfg(r, func(cfg *config.StorageMiner) {
+			cur = cfg.Dealmaking.RequiredLabelPatterns
+		}); err != nil {
+			return nil, err
+		}
+
+		lk.Lock()
+		defer lk.Unlock()
+
+		// the patterns are only compiled when they change in the config
+		if loaded && reflect.DeepEqual(cur, patterns) {
+			return compiled, nil
+		}
+
+		c, err := dealfilter.CompileLabelPatterns(cur)
+		if err != nil {
+			return nil, err
+		}
+		patterns, compiled, loaded = cur, c, true
+		return compiled, nil
+	}
+
+	// fail on startup when the configured patterns are invalid
+	if _, err := get(); err != nil {
+		return nil, err
+	}
+
+	return get, nil
+}
+
+func NewGetOpenSectorsFunc(m *storage.Miner) dtypes.GetOpenSectorsFunc {
+	return m.OpenSectors
+}
+
+func NewReputationFilter(r repo.LockedRepo, m *storage.Miner) *dealfilter.ReputationFilter {
+	return dealfilter.NewReputationFilter(func() (out dealfilter.ReputationConfig, err error) {
+		err = readCfg(r, func(cfg *config.StorageMiner) {
+			rc := cfg.Dealmaking.ClientReputation
+			out = dealfilter.ReputationConfig{
+				Endpoint:         rc.Endpoint,
+				MinScore:         rc.MinScore,
+				CacheTTL:         time.Duration(rc.CacheTTL),
+				TrustedDealCount: rc.TrustedDealCount,
+			}
+		})
+		return
+	}, m.ClientDealCount)
+}
+
+func NewClientRateLimiter(r repo.LockedRepo) *dealfilter.ClientRateLimiter {
+	return dealfilter.NewClientRateLimiter(func() (out config.ClientRateLimitConfig, err error) {
+		err = readCfg(r, func(cfg *config.StorageMiner) {
+			out = cfg.Dealmaking.ClientRateLimit
+		})
+		return
+	})
+}
+
+// NewRetrievalFilter creates the filter applying Dealmaking.RetrievalRules to
+// retrieval deals
+func NewRetrievalFilter(r repo.LockedRepo, pieceStore dtypes.ProviderPieceStore, miner *storage.Miner, pieceProvider sectorstorage.PieceProvider, full v1api.FullNode) *dealfilter.RetrievalFilter {
+	rpn := retrievaladapter.NewRetrievalProviderNode(miner, pieceProvider, full)
+
+	getConfig := func() (out dealfilter.RetrievalFilterConfig, err error) {
+		err = readCfg(r, func(cfg *config.StorageMiner) {
+			out = dealfilter.RetrievalFilterConfig{
+				MinPricePerByte: abi.TokenAmount(cfg.Dealmaking.RetrievalRules.MinPricePerByte),
+				RequireUnsealed: cfg.Dealmaking.RetrievalRules.RequireUnsealed,
+			}
+		})
+		return
+	}
+
+	lookup := func(deal retrievalmarket.ProviderDealState) (piecestore.PieceInfo, error) {
+		if deal.PieceCID != nil {
+			return pieceStore.GetPieceInfo(*deal.PieceCID)
+		}
+
+		ci, err := pieceStore.GetCIDInfo(deal.PayloadCID)
+		if err != nil {
+			return piecestore.PieceInfo{}, xerrors.Errorf("getting pieces containing payload %s: %w", deal.PayloadCID, err)
+		}
+
+		// any piece containing the payload can serve the retrieval
+		var out piecestore.PieceInfo
+		for _, loc := range ci.PieceBlockLocations {
+			pi, err := pieceStore.GetPieceInfo(loc.PieceCID)
+			if err != nil {
+				return piecestore.PieceInfo{}, xerrors.Errorf("getting piece %s: %w", loc.PieceCID, err)
+			}
+			out.PieceCID = pi.PieceCID
+			out.Deals = append(out.Deals, pi.Deals...)
+		}
+		return out, nil
+	}
+
+	return dealfilter.NewRetrievalFilter(getConfig, lookup, rpn.IsUnsealed)
+}
+
+func NewClientFilter(r repo.LockedRepo, full v1api.FullNode) *dealfilter.ClientFilter {
+	lookup := func(ctx context.Context, addr address.Address) (address.Address, error) {
+		return full.StateLookupID(ctx, addr, types.EmptyTSK)
+	}
+
+	return dealfilter.NewClientFilter(func() (out dealfilter.ClientFilterConfig, err error) {
+		var fc config.ClientFilterConfig
+		if err := readCfg(r, func(cfg *config.StorageMiner) {
+			fc = cfg.Dealmaking.ClientFilter
+		}); err != nil {
+			return out, err
+		}
+
+		parse := func(addrs []string) ([]address.Address, error) {
+			res := make([]address.Address, 0, len(addrs))
+			for _, s := range addrs {
+				a, err := address.NewFromString(s)
+				if err != nil {
+					return nil, xerrors.Errorf("parsing client filter address %q: %w", s, err)
+				}
+				res = append(res, a)
+			}
+			return res, nil
+		}
+
+		if out.Allow, err = parse(fc.Allowlist); err != nil {
+			return out, err
+		}
+		if out.Deny, err = parse(fc.Denylist); err != nil {
+			return out, err
+		}
+		out.MaxBytes = fc.MaxBytes
+		out.Window = time.Duration(fc.Window)
+		return out, nil
+	}, lookup)
+}
+
 func readCfg(r repo.LockedRepo, accessor func(*config.StorageMiner)) error {
 	raw, err := r.Config()
 	if err != nil {
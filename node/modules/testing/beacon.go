@@ -13,3 +13,14 @@ func RandomBeacon() (beacon.Schedule, error) {
 			Beacon: beacon.NewMockBeacon(time.Duration(build.BlockDelaySecs) * time.Second),
 		}}, nil
 }
+
+// DeterministicBeacon returns a constructor for a mock beacon schedule which
+// always produces the same entries for a given seed
+func DeterministicBeacon(seed []byte) func() (beacon.Schedule, error) {
+	return func() (beacon.Schedule, error) {
+		return beacon.Schedule{
+			{Start: 0,
+				Beacon: beacon.NewSeededMockBeacon(time.Duration(build.BlockDelaySecs)*time.Second, seed),
+			}}, nil
+	}
+}
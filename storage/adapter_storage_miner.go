@@ -33,10 +33,13 @@ var _ sealing.SealingAPI = new(SealingAPIAdapter)
 
 type SealingAPIAdapter struct {
 	delegate fullNodeFilteredAPI
+
+	// MaxFee of the miner fee config, see CheckMaxFee
+	maxFee abi.TokenAmount
 }
 
-func NewSealingAPIAdapter(api fullNodeFilteredAPI) SealingAPIAdapter {
-	return SealingAPIAdapter{delegate: api}
+func NewSealingAPIAdapter(api fullNodeFilteredAPI, maxFee abi.TokenAmount) SealingAPIAdapter {
+	return SealingAPIAdapter{delegate: api, maxFee: maxFee}
 }
 
 func (s SealingAPIAdapter) StateMinerSectorSize(ctx context.Context, maddr address.Address, tok sealing.TipSetToken) (abi.SectorSize, error) {
@@ -284,6 +287,15 @@ func (s SealingAPIAdapter) StateMinerPartitions(ctx context.Context, maddr addre
 	return s.delegate.StateMinerPartitions(ctx, maddr, dlIdx, tsk)
 }
 
+func (s SealingAPIAdapter) StateMinerActiveSectors(ctx context.Context, maddr address.Address, tok sealing.TipSetToken) ([]*miner.SectorOnChainInfo, error) {
+	tsk, err := types.TipSetKeyFromBytes(tok)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to unmarshal TipSetToken to TipSetKey: %w", err)
+	}
+
+	return s.delegate.StateMinerActiveSectors(ctx, maddr, tsk)
+}
+
 func (s SealingAPIAdapter) StateLookupID(ctx context.Context, addr address.Address, tok sealing.TipSetToken) (address.Address, error) {
 	tsk, err := types.TipSetKeyFromBytes(tok)
 	if err != nil {
@@ -343,7 +355,23 @@ func (s SealingAPIAdapter) SendMsg(ctx context.Context, from, to address.Address
 		Params: params,
 	}
 
-	smsg, err := s.delegate.MpoolPushMessage(ctx, &msg, &api.MessageSendSpec{MaxFee: maxFee})
+	spec := &api.MessageSendSpec{MaxFee: maxFee}
+
+	if !s.maxFee.Nil() && !s.maxFee.IsZero() {
+		// estimate once, the gas limit of the estimated message is used by
+		// both the check and the message pool
+		gm, err := s.delegate.GasEstimateMessageGas(ctx, &msg, spec, types.EmptyTSK)
+		if err != nil {
+			return cid.Undef, xerrors.Errorf("estimating message gas: %w", err)
+		}
+		msg = *gm
+
+		if err := CheckMaxFee(ctx, s.delegate, &msg, s.maxFee); err != nil {
+			return cid.Undef, err
+		}
+	}
+
+	smsg, err := s.delegate.MpoolPushMessage(ctx, &msg, spec)
 	if err != nil {
 		return cid.Undef, err
 	}
@@ -2,6 +2,10 @@ package storage
 
 import (
 	"context"
+	"sort"
+	"sync"
+
+	"golang.org/x/xerrors"
 
 	"github.com/filecoin-project/go-address"
 	"github.com/filecoin-project/go-state-types/abi"
@@ -18,10 +22,46 @@ type addrSelectApi interface {
 
 	StateAccountKey(context.Context, address.Address, types.TipSetKey) (address.Address, error)
 	StateLookupID(context.Context, address.Address, types.TipSetKey) (address.Address, error)
+
+	MpoolGetNonce(context.Context, address.Address) (uint64, error)
+}
+
+// AddrStrategy decides the order in which the addresses usable for a message
+// are considered. The first address with enough funds is picked; owner and
+// worker fallbacks are always considered last.
+type AddrStrategy string
+
+const (
+	// AddrStrategyOrdered considers addresses in the configured order
+	AddrStrategyOrdered AddrStrategy = "ordered"
+	// AddrStrategyRoundRobin starts from the next address on every message
+	AddrStrategyRoundRobin AddrStrategy = "round-robin"
+	// AddrStrategyLowestNonce considers addresses with the lowest next nonce,
+	// including pending messages, first
+	AddrStrategyLowestNonce AddrStrategy = "lowest-nonce"
+)
+
+// ParseAddrStrategy parses a strategy name from the config, empty = ordered
+func ParseAddrStrategy(s string) (AddrStrategy, error) {
+	switch AddrStrategy(s) {
+	case "":
+		return AddrStrategyOrdered, nil
+	case AddrStrategyOrdered, AddrStrategyRoundRobin, AddrStrategyLowestNonce:
+		return AddrStrategy(s), nil
+	default:
+		return "", xerrors.Errorf("unknown address selection strategy '%s'", s)
+	}
 }
 
 type AddressSelector struct {
 	api.AddressConfig
+
+	// Strategies for each message type, AddrStrategyOrdered is used for
+	// message types without a strategy
+	Strategies map[api.AddrUse]AddrStrategy
+
+	lk sync.Mutex
+	rr map[api.AddrUse]int
 }
 
 func (as *AddressSelector) AddressFor(ctx context.Context, a addrSelectApi, mi miner.MinerInfo, use api.AddrUse, goodFunds, minFunds abi.TokenAmount) (address.Address, abi.TokenAmount, error) {
@@ -70,8 +110,15 @@ func (as *AddressSelector) AddressFor(ctx context.Context, a addrSelectApi, mi m
 		for a := range defaultCtl {
 			addrs = append(addrs, a)
 		}
+
+		// keep the order stable for selection strategies
+		sort.Slice(addrs, func(i, j int) bool {
+			return addrs[i].String() < addrs[j].String()
+		})
 	}
 
+	addrs = as.order(ctx, a, use, addrs)
+
 	if len(addrs) == 0 || !as.DisableWorkerFallback {
 		addrs = append(addrs, mi.Worker)
 	}
@@ -82,6 +129,44 @@ func (as *AddressSelector) AddressFor(ctx context.Context, a addrSelectApi, mi m
 	return pickAddress(ctx, a, mi, goodFunds, minFunds, addrs)
 }
 
+func (as *AddressSelector) order(ctx context.Context, a addrSelectApi, use api.AddrUse, addrs []address.Address) []address.Address {
+	if len(addrs) < 2 {
+		return addrs
+	}
+
+	switch as.Strategies[use] {
+	case AddrStrategyRoundRobin:
+		as.lk.Lock()
+		if as.rr == nil {
+			as.rr = map[api.AddrUse]int{}
+		}
+		start := as.rr[use] % len(addrs)
+		as.rr[use] = start + 1
+		as.lk.Unlock()
+
+		out := make([]address.Address, 0, len(addrs))
+		out = append(out, addrs[start:]...)
+		return append(out, addrs[:start]...)
+	case AddrStrategyLowestNonce:
+		nonces := make(map[address.Address]uint64, len(addrs))
+		for _, addr := range addrs {
+			n, err := a.MpoolGetNonce(ctx, addr)
+			if err != nil {
+				log.Warnw("getting address nonce", "address", addr, "error", err)
+				n = ^uint64(0)
+			}
+			nonces[addr] = n
+		}
+
+		sort.SliceStable(addrs, func(i, j int) bool {
+			return nonces[addrs[i]] < nonces[addrs[j]]
+		})
+		return addrs
+	default:
+		return addrs
+	}
+}
+
 func pickAddress(ctx context.Context, a addrSelectApi, mi miner.MinerInfo, goodFunds, minFunds abi.TokenAmount, addrs []address.Address) (address.Address, abi.TokenAmount, error) {
 	leastBad := mi.Worker
 	bestAvail := minFunds
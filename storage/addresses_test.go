@@ -0,0 +1,148 @@
+package storage
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
+
+	"github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/chain/actors/builtin/miner"
+	"github.com/filecoin-project/lotus/chain/types"
+)
+
+type mockAddrSelectApi struct {
+	balances map[address.Address]abi.TokenAmount
+	nonces   map[address.Address]uint64
+}
+
+func (m *mockAddrSelectApi) WalletBalance(ctx context.Context, a address.Address) (types.BigInt, error) {
+	if b, ok := m.balances[a]; ok {
+		return b, nil
+	}
+	return big.Zero(), nil
+}
+
+func (m *mockAddrSelectApi) WalletHas(ctx context.Context, a address.Address) (bool, error) {
+	return true, nil
+}
+
+func (m *mockAddrSelectApi) StateAccountKey(ctx context.Context, a address.Address, tsk types.TipSetKey) (address.Address, error) {
+	return a, nil
+}
+
+func (m *mockAddrSelectApi) StateLookupID(ctx context.Context, a address.Address, tsk types.TipSetKey) (address.Address, error) {
+	return a, nil
+}
+
+func (m *mockAddrSelectApi) MpoolGetNonce(ctx context.Context, a address.Address) (uint64, error) {
+	return m.nonces[a], nil
+}
+
+func TestAddressSelectorStrategies(t *testing.T) {
+	ctx := context.Background()
+
+	mkAddr := func(id uint64) address.Address {
+		a, err := address.NewIDAddress(id)
+		require.NoError(t, err)
+		return a
+	}
+
+	owner, worker := mkAddr(100), mkAddr(101)
+	ctl := []address.Address{mkAddr(102), mkAddr(103), mkAddr(104)}
+
+	mi := miner.MinerInfo{
+		Owner:            owner,
+		Worker:           worker,
+		ControlAddresses: ctl,
+	}
+
+	funds := abi.NewTokenAmount(1000)
+	a := &mockAddrSelectApi{
+		balances: map[address.Address]abi.TokenAmount{
+			owner:  funds,
+			worker: funds,
+			ctl[0]: funds,
+			ctl[1]: funds,
+			ctl[2]: funds,
+		},
+		nonces: map[address.Address]uint64{
+			ctl[0]: 7,
+			ctl[1]: 3,
+			ctl[2]: 5,
+		},
+	}
+
+	pick := func(as *AddressSelector, use api.AddrUse) address.Address {
+		addr, _, err := as.AddressFor(ctx, a, mi, use, funds, big.Zero())
+		require.NoError(t, err)
+		return addr
+	}
+
+	newSelector := func(s AddrStrategy) *AddressSelector {
+		return &AddressSelector{
+			AddressConfig: api.AddressConfig{
+				PreCommitControl: ctl,
+				CommitControl:    ctl,
+			},
+			Strategies: map[api.AddrUse]AddrStrategy{
+				api.CommitAddr: s,
+			},
+		}
+	}
+
+	// ordered, the default, always picks the first address with funds
+	as := newSelector(AddrStrategyOrdered)
+	for i := 0; i < 4; i++ {
+		require.Equal(t, ctl[0], pick(as, api.CommitAddr))
+	}
+	a.balances[ctl[0]] = big.Zero()
+	require.Equal(t, ctl[1], pick(as, api.CommitAddr))
+	a.balances[ctl[0]] = funds
+
+	// round-robin rotates through the addresses, skipping ones without funds
+	as = newSelector(AddrStrategyRoundRobin)
+	require.Equal(t, ctl[0], pick(as, api.CommitAddr))
+	require.Equal(t, ctl[1], pick(as, api.CommitAddr))
+	require.Equal(t, ctl[2], pick(as, api.CommitAddr))
+	require.Equal(t, ctl[0], pick(as, api.CommitAddr))
+
+	a.balances[ctl[1]] = big.Zero()
+	require.Equal(t, ctl[2], pick(as, api.CommitAddr))
+	a.balances[ctl[1]] = funds
+
+	// other message types keep the default strategy
+	require.Equal(t, ctl[0], pick(as, api.PreCommitAddr))
+	require.Equal(t, ctl[0], pick(as, api.PreCommitAddr))
+
+	// lowest-nonce prefers the address which sent the fewest messages
+	as = newSelector(AddrStrategyLowestNonce)
+	require.Equal(t, ctl[1], pick(as, api.CommitAddr))
+	a.nonces[ctl[1]] = 10
+	require.Equal(t, ctl[2], pick(as, api.CommitAddr))
+	a.balances[ctl[2]] = big.Zero()
+	require.Equal(t, ctl[0], pick(as, api.CommitAddr))
+
+	// the fallbacks are used last
+	for _, c := range ctl {
+		a.balances[c] = big.Zero()
+	}
+	require.Equal(t, worker, pick(as, api.CommitAddr))
+}
+
+func TestParseAddrStrategy(t *testing.T) {
+	s, err := ParseAddrStrategy("")
+	require.NoError(t, err)
+	require.Equal(t, AddrStrategyOrdered, s)
+
+	s, err = ParseAddrStrategy("round-robin")
+	require.NoError(t, err)
+	require.Equal(t, AddrStrategyRoundRobin, s)
+
+	_, err = ParseAddrStrategy("random")
+	require.Error(t, err)
+}
@@ -0,0 +1,189 @@
+package storage
+
+import (
+	"sort"
+
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/chain/actors/builtin/miner"
+)
+
+// maxPartitionsPerDeadline mirrors MaxPartitionsPerDeadline of the miner actor
+const maxPartitionsPerDeadline = 3000
+
+// DeadlineLoads returns the number of partitions and sectors in each of the
+// proving deadlines of the miner, and marks the deadline the next newly
+// committed sector is expected to be assigned to.
+//
+// Deadline assignment is done by the miner actor when sectors are
+// ProveCommitted, the miner can't choose a deadline for a sector. The
+// expected assignment follows the actor's deadline assignment rules, see
+// assignmentLess.
+func DeadlineLoads(mas miner.State, current uint64) ([]api.DeadlineLoad, error) {
+	info, err := mas.Info()
+	if err != nil {
+		return nil, xerrors.Errorf("getting miner info: %w", err)
+	}
+
+	loads := make([]api.DeadlineLoad, miner.WPoStPeriodDeadlines)
+	for i := range loads {
+		loads[i].Index = uint64(i)
+	}
+
+	err = mas.ForEachDeadline(func(dlIdx uint64, dl miner.Deadline) error {
+		load := &loads[dlIdx]
+		return dl.ForEachPartition(func(partIdx uint64, part miner.Partition) error {
+			all, err := part.AllSectors()
+			if err != nil {
+				return xerrors.Errorf("getting all sectors: %w", err)
+			}
+			ac, err := all.Count()
+			if err != nil {
+				return xerrors.Errorf("counting all sectors: %w", err)
+			}
+
+			live, err := part.LiveSectors()
+			if err != nil {
+				return xerrors.Errorf("getting live sectors: %w", err)
+			}
+			lc, err := live.Count()
+			if err != nil {
+				return xerrors.Errorf("counting live sectors: %w", err)
+			}
+
+			faulty, err := part.FaultySectors()
+			if err != nil {
+				return xerrors.Errorf("getting faulty sectors: %w", err)
+			}
+			fc, err := faulty.Count()
+			if err != nil {
+				return xerrors.Errorf("counting faulty sectors: %w", err)
+			}
+
+			load.Partitions++
+			load.TotalSectors += ac
+			load.LiveSectors += lc
+			load.FaultySectors += fc
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, xerrors.Errorf("iterating deadlines: %w", err)
+	}
+
+	markPreferred(loads, current, info.WindowPoStPartitionSectors)
+
+	return loads, nil
+}
+
+// markPreferred sets the Preferred flag on the deadline the next sector is
+// assigned to
+func markPreferred(loads []api.DeadlineLoad, current, partitionSize uint64) {
+	order := assignmentOrder(loads, current, partitionSize)
+	if len(order) == 0 {
+		return
+	}
+	loads[order[0]].Preferred = true
+}
+
+// assignmentOrder returns the indexes of deadlines which can receive new
+// sectors, in the order the miner actor prefers them for the next sector
+func assignmentOrder(loads []api.DeadlineLoad, current, partitionSize uint64) []uint64 {
+	n := uint64(len(loads))
+
+	var out []uint64
+	for _, l := range loads {
+		// the current and next deadlines are immutable
+		if l.Index == current || l.Index == (current+1)%n {
+			continue
+		}
+		out = append(out, l.Index)
+	}
+
+	sort.Slice(out, func(i, j int) bool {
+		return assignmentLess(loads[out[i]], loads[out[j]], partitionSize)
+	})
+
+	return out
+}
+
+// assignDeadlines returns the deadline each of count new sectors is assigned
+// to, assigning them one at a time like the miner actor does when a batch of
+// sectors is committed
+func assignDeadlines(loads []api.DeadlineLoad, current, partitionSize uint64, count int) []uint64 {
+	loads = append([]api.DeadlineLoad{}, loads...)
+
+	out := make([]uint64, 0, count)
+	for i := 0; i < count; i++ {
+		order := assignmentOrder(loads, current, partitionSize)
+		if len(order) == 0 {
+			break
+		}
+
+		dl := &loads[order[0]]
+		if dl.TotalSectors%partitionSize == 0 {
+			dl.Partitions++
+		}
+		dl.TotalSectors++
+		dl.LiveSectors++
+
+		out = append(out, dl.Index)
+	}
+
+	return out
+}
+
+// assignmentLess is a port of the deadline ordering in the miner actor's
+// deadline_assign.go
+func assignmentLess(a, b api.DeadlineLoad, partitionSize uint64) bool {
+	// if one deadline has already reached its max partition count, prefer the
+	// other
+	aMax, bMax := maxPartitionsReached(a, partitionSize), maxPartitionsReached(b, partitionSize)
+	if aMax != bMax {
+		return !aMax
+	}
+
+	// then the deadline with the fewest partitions after assigning the sector
+	// and compacting away the dead sectors
+	aCompact, bCompact := partitionsFor(a.LiveSectors+1, partitionSize), partitionsFor(b.LiveSectors+1, partitionSize)
+	if aCompact != bCompact {
+		return aCompact < bCompact
+	}
+
+	// then the deadline with the fewest partitions after assigning the sector,
+	// without compaction
+	aParts, bParts := partitionsFor(a.TotalSectors+1, partitionSize), partitionsFor(b.TotalSectors+1, partitionSize)
+	if aParts != bParts {
+		return aParts < bParts
+	}
+
+	// then deadlines with a partition which isn't full
+	aFull, bFull := a.TotalSectors%partitionSize == 0, b.TotalSectors%partitionSize == 0
+	if aFull != bFull {
+		return !aFull
+	}
+
+	// then the fullest open partition
+	if !aFull && !bFull {
+		aFill, bFill := a.TotalSectors%partitionSize, b.TotalSectors%partitionSize
+		if aFill != bFill {
+			return aFill > bFill
+		}
+	}
+
+	// then the fewest live sectors
+	if a.LiveSectors != b.LiveSectors {
+		return a.LiveSectors < b.LiveSectors
+	}
+
+	return a.Index < b.Index
+}
+
+func maxPartitionsReached(l api.DeadlineLoad, partitionSize uint64) bool {
+	return l.TotalSectors >= partitionSize*maxPartitionsPerDeadline
+}
+
+func partitionsFor(sectors, partitionSize uint64) uint64 {
+	return (sectors + partitionSize - 1) / partitionSize
+}
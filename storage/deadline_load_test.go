@@ -0,0 +1,135 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/chain/actors/builtin/miner"
+)
+
+// cases from TestDeadlineAssignment in the miner actor
+func TestDeadlineAssignmentMatchesActor(t *testing.T) {
+	const partitionSize = 4
+
+	type deadline struct {
+		live, dead    uint64
+		expectSectors []uint64
+	}
+
+	cases := []struct {
+		name      string
+		sectors   int
+		deadlines map[uint64]deadline
+	}{{
+		name:    "even assignment and striping",
+		sectors: 10,
+		deadlines: map[uint64]deadline{
+			0: {expectSectors: []uint64{0, 1, 2, 3, 8, 9}},
+			1: {expectSectors: []uint64{4, 5, 6, 7}},
+		},
+	}, {
+		name:    "fill non-full first",
+		sectors: 5,
+		deadlines: map[uint64]deadline{
+			0: {expectSectors: []uint64{3, 4}},
+			1: {},
+			3: {live: 1, expectSectors: []uint64{0, 1, 2}},
+		},
+	}, {
+		name:    "least live partitions",
+		sectors: 1,
+		deadlines: map[uint64]deadline{
+			0: {live: 8},
+			1: {live: 7, dead: 5, expectSectors: []uint64{0}},
+		},
+	}, {
+		name:    "avoid increasing max partitions",
+		sectors: 1,
+		deadlines: map[uint64]deadline{
+			0: {live: 4, dead: 4},
+			1: {live: 4, expectSectors: []uint64{0}},
+		},
+	}, {
+		name:    "most full open partition first",
+		sectors: 1,
+		deadlines: map[uint64]deadline{
+			0: {live: 1},
+			1: {live: 2, expectSectors: []uint64{0}},
+		},
+	}, {
+		name:    "dead sectors count",
+		sectors: 1,
+		deadlines: map[uint64]deadline{
+			0: {live: 1},
+			1: {dead: 2, expectSectors: []uint64{0}},
+		},
+	}, {
+		name:    "dead sectors really count",
+		sectors: 1,
+		deadlines: map[uint64]deadline{
+			0: {dead: 1},
+			1: {dead: 2, expectSectors: []uint64{0}},
+		},
+	}, {
+		name:    "equally full partitions, fewest live sectors",
+		sectors: 1,
+		deadlines: map[uint64]deadline{
+			0: {live: 1, dead: 1},
+			1: {dead: 2, expectSectors: []uint64{0}},
+		},
+	}}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			loads := make([]api.DeadlineLoad, miner.WPoStPeriodDeadlines)
+			for i := range loads {
+				loads[i].Index = uint64(i)
+
+				dl, ok := tc.deadlines[uint64(i)]
+				if !ok {
+					// deadlines not taking part in the test case are full
+					dl.live = partitionSize * maxPartitionsPerDeadline
+				}
+
+				loads[i].LiveSectors = dl.live
+				loads[i].TotalSectors = dl.live + dl.dead
+				loads[i].Partitions = partitionsFor(dl.live+dl.dead, partitionSize)
+			}
+
+			expect := make([]uint64, tc.sectors)
+			for idx, dl := range tc.deadlines {
+				for _, s := range dl.expectSectors {
+					expect[s] = idx
+				}
+			}
+
+			// deadlines 46 and 47 are immutable, none of the cases use them
+			require.Equal(t, expect, assignDeadlines(loads, 46, partitionSize, tc.sectors))
+
+			markPreferred(loads, 46, partitionSize)
+			require.True(t, loads[expect[0]].Preferred)
+		})
+	}
+}
+
+func TestDeadlineAssignmentSkipsImmutable(t *testing.T) {
+	const partitionSize = 2349
+
+	loads := make([]api.DeadlineLoad, miner.WPoStPeriodDeadlines)
+	for i := range loads {
+		loads[i] = api.DeadlineLoad{Index: uint64(i), Partitions: 3, LiveSectors: 3 * partitionSize, TotalSectors: 3 * partitionSize}
+	}
+	// least loaded, but immutable while deadline 1 is open
+	loads[2] = api.DeadlineLoad{Index: 2}
+
+	order := assignmentOrder(loads, 1, partitionSize)
+	require.NotContains(t, order, uint64(1))
+	require.NotContains(t, order, uint64(2))
+	require.Equal(t, uint64(0), order[0])
+
+	// once deadline 2 becomes mutable again it's filled first
+	require.Equal(t, uint64(2), assignmentOrder(loads, 4, partitionSize)[0])
+}
@@ -0,0 +1,79 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/ipfs/go-datastore"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-bitfield"
+	"github.com/filecoin-project/go-state-types/abi"
+
+	"github.com/filecoin-project/lotus/api"
+)
+
+// FaultHistory keeps track of WindowPoSt fault and recovery events of each
+// sector, so that operators can find sectors which fault repeatedly
+type FaultHistory struct {
+	lk sync.Mutex
+	ds datastore.Batching
+}
+
+func NewFaultHistory(ds datastore.Batching) *FaultHistory {
+	return &FaultHistory{ds: ds}
+}
+
+func faultHistoryKey(sn abi.SectorNumber) datastore.Key {
+	return datastore.NewKey(fmt.Sprint(sn))
+}
+
+// Record appends the event to the history of every sector in the set
+func (fh *FaultHistory) Record(sectors bitfield.BitField, evt api.SectorFaultEvent) error {
+	fh.lk.Lock()
+	defer fh.lk.Unlock()
+
+	return sectors.ForEach(func(sn uint64) error {
+		hist, err := fh.getLocked(abi.SectorNumber(sn))
+		if err != nil {
+			return err
+		}
+
+		b, err := json.Marshal(append(hist, evt))
+		if err != nil {
+			return xerrors.Errorf("marshaling fault history: %w", err)
+		}
+
+		if err := fh.ds.Put(faultHistoryKey(abi.SectorNumber(sn)), b); err != nil {
+			return xerrors.Errorf("storing fault history for sector %d: %w", sn, err)
+		}
+
+		return nil
+	})
+}
+
+// Get returns the fault history of a sector, oldest events first
+func (fh *FaultHistory) Get(sn abi.SectorNumber) ([]api.SectorFaultEvent, error) {
+	fh.lk.Lock()
+	defer fh.lk.Unlock()
+
+	return fh.getLocked(sn)
+}
+
+func (fh *FaultHistory) getLocked(sn abi.SectorNumber) ([]api.SectorFaultEvent, error) {
+	b, err := fh.ds.Get(faultHistoryKey(sn))
+	switch {
+	case xerrors.Is(err, datastore.ErrNotFound):
+		return nil, nil
+	case err != nil:
+		return nil, xerrors.Errorf("getting fault history for sector %d: %w", sn, err)
+	}
+
+	var hist []api.SectorFaultEvent
+	if err := json.Unmarshal(b, &hist); err != nil {
+		return nil, xerrors.Errorf("unmarshaling fault history for sector %d: %w", sn, err)
+	}
+
+	return hist, nil
+}
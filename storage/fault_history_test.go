@@ -0,0 +1,50 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/ipfs/go-datastore"
+	dssync "github.com/ipfs/go-datastore/sync"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-bitfield"
+	"github.com/filecoin-project/go-state-types/abi"
+
+	"github.com/filecoin-project/lotus/api"
+)
+
+func TestFaultHistory(t *testing.T) {
+	fh := NewFaultHistory(dssync.MutexWrap(datastore.NewMapDatastore()))
+
+	hist, err := fh.Get(1)
+	require.NoError(t, err)
+	require.Empty(t, hist)
+
+	fault := api.SectorFaultEvent{
+		Kind:      api.SectorFaultSkipped,
+		Epoch:     100,
+		Deadline:  3,
+		Partition: 0,
+	}
+	require.NoError(t, fh.Record(bitfield.NewFromSet([]uint64{1, 2}), fault))
+
+	recovery := api.SectorFaultEvent{
+		Kind:      api.SectorRecoveryDeclared,
+		Epoch:     200,
+		Deadline:  3,
+		Partition: 0,
+	}
+	require.NoError(t, fh.Record(bitfield.NewFromSet([]uint64{1}), recovery))
+
+	hist, err = fh.Get(1)
+	require.NoError(t, err)
+	require.Equal(t, []api.SectorFaultEvent{fault, recovery}, hist)
+
+	hist, err = fh.Get(2)
+	require.NoError(t, err)
+	require.Equal(t, []api.SectorFaultEvent{fault}, hist)
+
+	hist, err = fh.Get(abi.SectorNumber(3))
+	require.NoError(t, err)
+	require.Empty(t, hist)
+}
@@ -0,0 +1,105 @@
+package storage
+
+import (
+	"context"
+
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
+
+	"github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/chain/actors"
+	"github.com/filecoin-project/lotus/chain/actors/builtin/market"
+	"github.com/filecoin-project/lotus/chain/types"
+)
+
+// ErrMaxFeeExceeded is returned for messages which would cost more than the
+// MaxFee of the miner fee config at the current base fee
+var ErrMaxFeeExceeded = xerrors.New("message fee above the configured MaxFee")
+
+type maxFeeApi interface {
+	GasEstimateGasLimit(context.Context, *types.Message, types.TipSetKey) (int64, error)
+	GasEstimateFeeCap(context.Context, *types.Message, int64, types.TipSetKey) (types.BigInt, error)
+}
+
+// CheckMaxFee estimates the fee of the message at the current base fee, and
+// fails with ErrMaxFeeExceeded when it's above maxFee, the MaxFee of the miner
+// fee config. The per message type fee limits only cap the fee cap of the
+// messages, which then may not get included for a long time during base fee
+// spikes. The estimated gas limit of the message is used if it's not set yet.
+// A zero maxFee disables the check.
+func CheckMaxFee(ctx context.Context, a maxFeeApi, msg *types.Message, maxFee abi.TokenAmount) error {
+	if maxFee.Nil() || maxFee.IsZero() {
+		return nil
+	}
+
+	gasLimit := msg.GasLimit
+	if gasLimit == 0 {
+		var err error
+		gasLimit, err = a.GasEstimateGasLimit(ctx, msg, types.EmptyTSK)
+		if err != nil {
+			return xerrors.Errorf("estimating gas limit: %w", err)
+		}
+	}
+
+	// the fee cap for inclusion in the next tipset, that is the current base
+	// fee, plus the premium of the message if it's set
+	feeCap, err := a.GasEstimateFeeCap(ctx, msg, 0, types.EmptyTSK)
+	if err != nil {
+		return xerrors.Errorf("estimating fee cap: %w", err)
+	}
+
+	fee := big.Mul(feeCap, big.NewInt(gasLimit))
+	if fee.GreaterThan(maxFee) {
+		return xerrors.Errorf("estimated fee %s of method %d message to %s exceeds %s: %w", types.FIL(fee), msg.Method, msg.To, types.FIL(maxFee), ErrMaxFeeExceeded)
+	}
+
+	return nil
+}
+
+type reserveMaxFeeApi interface {
+	maxFeeApi
+	MarketGetReserved(ctx context.Context, addr address.Address) (types.BigInt, error)
+	StateMarketBalance(context.Context, address.Address, types.TipSetKey) (api.MarketBalance, error)
+}
+
+// CheckReserveMaxFee checks the fee of the AddBalance message the funds
+// manager of the full node sends when amt is reserved for addr, which is sent
+// with the fee config of the full node. No message is sent, and nothing is
+// checked, when the market escrow of addr covers the reserved funds.
+func CheckReserveMaxFee(ctx context.Context, a reserveMaxFeeApi, wallet, addr address.Address, amt, maxFee abi.TokenAmount) error {
+	if maxFee.Nil() || maxFee.IsZero() {
+		return nil
+	}
+
+	reserved, err := a.MarketGetReserved(ctx, addr)
+	if err != nil {
+		return xerrors.Errorf("getting reserved funds: %w", err)
+	}
+
+	bal, err := a.StateMarketBalance(ctx, addr, types.EmptyTSK)
+	if err != nil {
+		return xerrors.Errorf("getting market balance: %w", err)
+	}
+
+	// like the funds manager, add what the available escrow is missing
+	toAdd := big.Sub(big.Add(reserved, amt), big.Sub(bal.Escrow, bal.Locked))
+	if toAdd.LessThanEqual(big.Zero()) {
+		return nil
+	}
+
+	params, err := actors.SerializeParams(&addr)
+	if err != nil {
+		return xerrors.Errorf("serializing params: %w", err)
+	}
+
+	return CheckMaxFee(ctx, a, &types.Message{
+		To:     market.Address,
+		From:   wallet,
+		Value:  toAdd,
+		Method: market.Methods.AddBalance,
+		Params: params,
+	}, maxFee)
+}
@@ -0,0 +1,92 @@
+package storage
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
+
+	"github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/chain/actors/builtin/market"
+	"github.com/filecoin-project/lotus/chain/types"
+)
+
+type maxFeeTestApi struct {
+	gasLimit int64
+	baseFee  abi.TokenAmount
+
+	reserved abi.TokenAmount
+	balance  api.MarketBalance
+	lastMsg  *types.Message
+}
+
+func (a *maxFeeTestApi) GasEstimateGasLimit(ctx context.Context, msg *types.Message, tsk types.TipSetKey) (int64, error) {
+	a.lastMsg = msg
+	return a.gasLimit, nil
+}
+
+func (a *maxFeeTestApi) MarketGetReserved(ctx context.Context, addr address.Address) (types.BigInt, error) {
+	return a.reserved, nil
+}
+
+func (a *maxFeeTestApi) StateMarketBalance(ctx context.Context, addr address.Address, tsk types.TipSetKey) (api.MarketBalance, error) {
+	return a.balance, nil
+}
+
+func (a *maxFeeTestApi) GasEstimateFeeCap(ctx context.Context, msg *types.Message, maxqueueblks int64, tsk types.TipSetKey) (types.BigInt, error) {
+	if maxqueueblks != 0 {
+		return types.BigInt{}, xerrors.Errorf("expected an estimate for the next tipset, got %d", maxqueueblks)
+	}
+	return a.baseFee, nil
+}
+
+func TestCheckMaxFee(t *testing.T) {
+	ctx := context.Background()
+	a := &maxFeeTestApi{gasLimit: 1000, baseFee: big.NewInt(100)}
+	msg := &types.Message{To: address.TestAddress}
+
+	// estimated fee of 100 * 1000
+	require.NoError(t, CheckMaxFee(ctx, a, msg, big.NewInt(100_000)))
+	require.True(t, xerrors.Is(CheckMaxFee(ctx, a, msg, big.NewInt(99_999)), ErrMaxFeeExceeded))
+
+	// disabled
+	require.NoError(t, CheckMaxFee(ctx, a, msg, big.Zero()))
+	require.NoError(t, CheckMaxFee(ctx, a, msg, abi.TokenAmount{}))
+
+	// the gas limit of estimated messages is used
+	msg.GasLimit = 10
+	require.NoError(t, CheckMaxFee(ctx, a, msg, big.NewInt(1000)))
+	require.True(t, xerrors.Is(CheckMaxFee(ctx, a, msg, big.NewInt(999)), ErrMaxFeeExceeded))
+}
+
+func TestCheckReserveMaxFee(t *testing.T) {
+	ctx := context.Background()
+	a := &maxFeeTestApi{
+		gasLimit: 1000,
+		baseFee:  big.NewInt(100),
+		reserved: big.NewInt(10),
+		balance:  api.MarketBalance{Escrow: big.NewInt(30), Locked: big.NewInt(5)},
+	}
+	wallet, addr := address.TestAddress, address.TestAddress2
+
+	// the available escrow of 25 covers the 10 reserved and 15 more
+	require.NoError(t, CheckReserveMaxFee(ctx, a, wallet, addr, big.NewInt(15), big.NewInt(1)))
+	require.Nil(t, a.lastMsg)
+
+	// then the missing funds are added with a message
+	err := CheckReserveMaxFee(ctx, a, wallet, addr, big.NewInt(20), big.NewInt(99_999))
+	require.True(t, xerrors.Is(err, ErrMaxFeeExceeded))
+	require.Equal(t, market.Methods.AddBalance, a.lastMsg.Method)
+	require.Equal(t, wallet, a.lastMsg.From)
+	require.Equal(t, big.NewInt(5), a.lastMsg.Value)
+
+	require.NoError(t, CheckReserveMaxFee(ctx, a, wallet, addr, big.NewInt(20), big.NewInt(100_000)))
+
+	// disabled
+	require.NoError(t, CheckReserveMaxFee(ctx, a, wallet, addr, big.NewInt(20), big.Zero()))
+}
@@ -62,10 +62,16 @@ type Miner struct {
 
 	maddr address.Address
 
-	getSealConfig dtypes.GetSealingConfigFunc
-	sealing       *sealing.Sealing
+	getSealConfig   dtypes.GetSealingConfigFunc
+	sealing         *sealing.Sealing
+	sealingDisabled bool
+	sealerSectors   SealerSectors
 
 	sealingEvtType journal.EventType
+	sealTimes      sealTimes
+
+	// nil on miners which don't run WindowPoSt
+	wdpost *WindowPoStScheduler
 
 	journal journal.Journal
 }
@@ -85,6 +91,7 @@ type fullNodeFilteredAPI interface {
 	// Call a read only method on actors (no interaction with the chain required)
 	StateCall(context.Context, *types.Message, types.TipSetKey) (*api.InvocResult, error)
 	StateMinerSectors(context.Context, address.Address, *bitfield.BitField, types.TipSetKey) ([]*miner.SectorOnChainInfo, error)
+	StateMinerActiveSectors(context.Context, address.Address, types.TipSetKey) ([]*miner.SectorOnChainInfo, error)
 	StateSectorPreCommitInfo(context.Context, address.Address, abi.SectorNumber, types.TipSetKey) (miner.SectorPreCommitOnChainInfo, error)
 	StateSectorGetInfo(context.Context, address.Address, abi.SectorNumber, types.TipSetKey) (*miner.SectorOnChainInfo, error)
 	StateSectorPartition(ctx context.Context, maddr address.Address, sectorNumber abi.SectorNumber, tok types.TipSetKey) (*miner.SectorLocation, error)
@@ -106,7 +113,9 @@ type fullNodeFilteredAPI interface {
 	StateLookupID(context.Context, address.Address, types.TipSetKey) (address.Address, error)
 
 	MpoolPushMessage(context.Context, *types.Message, *api.MessageSendSpec) (*types.SignedMessage, error)
+	MpoolGetNonce(context.Context, address.Address) (uint64, error)
 
+	GasEstimateGasLimit(context.Context, *types.Message, types.TipSetKey) (int64, error)
 	GasEstimateMessageGas(context.Context, *types.Message, *api.MessageSendSpec, types.TipSetKey) (*types.Message, error)
 	GasEstimateFeeCap(context.Context, *types.Message, int64, types.TipSetKey) (types.BigInt, error)
 	GasEstimateGasPremium(_ context.Context, nblocksincl uint64, sender address.Address, gaslimit int64, tsk types.TipSetKey) (types.BigInt, error)
@@ -180,7 +189,7 @@ func (m *Miner) Run(ctx context.Context) error {
 		// with the API that Lotus is capable of providing.
 		// The shim translates between "tipset tokens" and tipset keys, and
 		// provides extra methods.
-		adaptedAPI = NewSealingAPIAdapter(m.api)
+		adaptedAPI = NewSealingAPIAdapter(m.api, abi.TokenAmount(m.feeCfg.MaxFee))
 
 		// Instantiate a precommit policy.
 		defaultDuration = policy.GetMaxSectorExpirationExtension() - (md.WPoStProvingPeriod * 2)
@@ -201,6 +210,15 @@ func (m *Miner) Run(ctx context.Context) error {
 	// Instantiate the sealing FSM.
 	m.sealing = sealing.New(ctx, adaptedAPI, m.feeCfg, evtsAdapter, m.maddr, m.ds, m.sealer, m.sc, m.verif, m.prover, &pcp, cfg, m.handleSealingNotifications, as)
 
+	// Load seal durations of already sealed sectors
+	sectors, err := m.sealing.ListSectors()
+	if err != nil {
+		return xerrors.Errorf("listing sectors: %w", err)
+	}
+	for _, si := range sectors {
+		m.sealTimes.record(si)
+	}
+
 	// Run the sealing FSM.
 	go m.sealing.Run(ctx) //nolint:errcheck // logged intside the function
 
@@ -208,6 +226,10 @@ func (m *Miner) Run(ctx context.Context) error {
 }
 
 func (m *Miner) handleSealingNotifications(before, after sealing.SectorInfo) {
+	if after.State == sealing.FinalizeSector && before.State != sealing.FinalizeSector && before.State != sealing.FinalizeFailed && before.State != sealing.FinalizeReady {
+		m.sealTimes.record(after)
+	}
+
 	m.journal.RecordEvent(m.sealingEvtType, func() interface{} {
 		return SealingStateEvt{
 			SectorNumber: before.SectorNumber,
@@ -219,7 +241,33 @@ func (m *Miner) handleSealingNotifications(before, after sealing.SectorInfo) {
 	})
 }
 
+// RecentSealDuration returns how long it took recently sealed sectors to go
+// from being created to being proven on chain. Returns false if not enough
+// sectors were sealed yet.
+func (m *Miner) RecentSealDuration() (time.Duration, bool) {
+	return m.sealTimes.expected()
+}
+
+// SetWindowPoStScheduler sets the WindowPoSt scheduler proving the sectors of
+// the miner
+func (m *Miner) SetWindowPoStScheduler(s *WindowPoStScheduler) {
+	m.wdpost = s
+}
+
+// WindowPoStDryRun returns the faults and recoveries the WindowPoSt scheduler
+// would declare for the deadline, see WindowPoStScheduler.DryRun
+func (m *Miner) WindowPoStDryRun(ctx context.Context, dlIdx uint64) (api.WindowPoStDryRun, error) {
+	if m.wdpost == nil {
+		return api.WindowPoStDryRun{}, xerrors.New("this miner doesn't run WindowPoSt")
+	}
+
+	return m.wdpost.DryRun(ctx, dlIdx)
+}
+
 func (m *Miner) Stop(ctx context.Context) error {
+	if m.sealingDisabled {
+		return nil
+	}
 	return m.sealing.Stop(ctx)
 }
 
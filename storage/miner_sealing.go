@@ -1,85 +1,316 @@
 package storage
 
 import (
+	"bytes"
 	"context"
 	"io"
 
 	"github.com/ipfs/go-cid"
+	"golang.org/x/xerrors"
 
 	"github.com/filecoin-project/go-address"
 	"github.com/filecoin-project/go-state-types/abi"
 	"github.com/filecoin-project/specs-storage/storage"
 
+	"github.com/filecoin-project/lotus/api"
 	sealing "github.com/filecoin-project/lotus/extern/storage-sealing"
 	"github.com/filecoin-project/lotus/extern/storage-sealing/sealiface"
 )
 
+// ErrSealingDisabled is returned by sealing operations on nodes which don't
+// seal, like retrieval gateways
+var ErrSealingDisabled = xerrors.New("sealing is disabled on this node")
+
+// SealerSectors is the part of the API of the sealing node for the miner
+// actor, which sector info is read from when sealing is disabled
+type SealerSectors interface {
+	SectorsStatus(ctx context.Context, sid abi.SectorNumber, showOnChainInfo bool) (api.SectorInfo, error)
+}
+
+// DisableSealing makes the miner reject sealing operations, for nodes which
+// don't seal, like retrieval gateways. The sealing state machine mustn't be
+// started with Run, sector info is read from the sealing node instead.
+func (m *Miner) DisableSealing(sealer SealerSectors) {
+	m.sealingDisabled = true
+	m.sealerSectors = sealer
+}
+
 // TODO: refactor this to be direct somehow
 
 func (m *Miner) Address() address.Address {
-	return m.sealing.Address()
+	return m.maddr
 }
 
 func (m *Miner) AddPieceToAnySector(ctx context.Context, size abi.UnpaddedPieceSize, r io.Reader, d sealing.DealInfo) (abi.SectorNumber, abi.PaddedPieceSize, error) {
+	if m.sealingDisabled {
+		return 0, 0, ErrSealingDisabled
+	}
 	return m.sealing.AddPieceToAnySector(ctx, size, r, d)
 }
 
+func (m *Miner) OpenSectors(ctx context.Context) ([]sealiface.OpenSector, error) {
+	if m.sealingDisabled {
+		return nil, ErrSealingDisabled
+	}
+	return m.sealing.OpenSectors(ctx)
+}
+
+func (m *Miner) MatchPendingPiecesToOpenSectors(ctx context.Context) error {
+	if m.sealingDisabled {
+		return ErrSealingDisabled
+	}
+	return m.sealing.MatchPendingPiecesToOpenSectors(ctx)
+}
+
+func (m *Miner) SimulatePacking(ctx context.Context, deals []sealiface.PackingDeal) (sealiface.PackingPlan, error) {
+	if m.sealingDisabled {
+		return sealiface.PackingPlan{}, ErrSealingDisabled
+	}
+	return m.sealing.SimulatePacking(ctx, deals)
+}
+
+func (m *Miner) DealSealETA(ctx context.Context, deal abi.DealID) (abi.SectorNumber, abi.ChainEpoch, error) {
+	if m.sealingDisabled {
+		return 0, 0, ErrSealingDisabled
+	}
+	return m.sealing.DealSealETA(ctx, deal)
+}
+
 func (m *Miner) StartPackingSector(sectorNum abi.SectorNumber) error {
+	if m.sealingDisabled {
+		return ErrSealingDisabled
+	}
 	return m.sealing.StartPacking(sectorNum)
 }
 
+func (m *Miner) AbortWaitDealsSector(sectorNum abi.SectorNumber) error {
+	if m.sealingDisabled {
+		return ErrSealingDisabled
+	}
+	return m.sealing.AbortWaitDeals(sectorNum)
+}
+
 func (m *Miner) ListSectors() ([]sealing.SectorInfo, error) {
+	if m.sealingDisabled {
+		return nil, ErrSealingDisabled
+	}
 	return m.sealing.ListSectors()
 }
 
+func (m *Miner) SectorsSummary() map[sealing.SectorState]int {
+	if m.sealingDisabled {
+		return map[sealing.SectorState]int{}
+	}
+	return m.sealing.SectorsSummary()
+}
+
+// ClientDealCount returns the number of deals from the client in proving sectors
+func (m *Miner) ClientDealCount(ctx context.Context, client address.Address) (int, error) {
+	if m.sealingDisabled {
+		return 0, ErrSealingDisabled
+	}
+	sectors, err := m.sealing.ListSectors()
+	if err != nil {
+		return 0, err
+	}
+
+	var n int
+	for _, si := range sectors {
+		if si.State != sealing.Proving {
+			continue
+		}
+		for _, p := range si.Pieces {
+			if p.DealInfo != nil && p.DealInfo.DealProposal != nil && p.DealInfo.DealProposal.Client == client {
+				n++
+			}
+		}
+	}
+
+	return n, nil
+}
+
+func (m *Miner) SealingSnapshot(ctx context.Context) (api.SealingSnapshot, error) {
+	if m.sealingDisabled {
+		return api.SealingSnapshot{}, ErrSealingDisabled
+	}
+	snap, err := m.sealing.Snapshot(ctx)
+	if err != nil {
+		return api.SealingSnapshot{}, err
+	}
+
+	out := api.SealingSnapshot{
+		Taken:   snap.Taken,
+		Sectors: make([][]byte, len(snap.Sectors)),
+	}
+	for i := range snap.Sectors {
+		var buf bytes.Buffer
+		if err := snap.Sectors[i].MarshalCBOR(&buf); err != nil {
+			return api.SealingSnapshot{}, xerrors.Errorf("marshaling sector %d: %w", snap.Sectors[i].SectorNumber, err)
+		}
+		out.Sectors[i] = buf.Bytes()
+	}
+
+	return out, nil
+}
+
+func (m *Miner) SealingRestore(ctx context.Context, snap api.SealingSnapshot) error {
+	if m.sealingDisabled {
+		return ErrSealingDisabled
+	}
+	in := sealing.Snapshot{
+		Taken:   snap.Taken,
+		Sectors: make([]sealing.SectorInfo, len(snap.Sectors)),
+	}
+	for i, b := range snap.Sectors {
+		if err := in.Sectors[i].UnmarshalCBOR(bytes.NewReader(b)); err != nil {
+			return xerrors.Errorf("unmarshaling sector info %d: %w", i, err)
+		}
+	}
+
+	return m.sealing.Restore(ctx, in)
+}
+
 func (m *Miner) GetSectorInfo(sid abi.SectorNumber) (sealing.SectorInfo, error) {
+	if m.sealingDisabled {
+		return m.sealerSectorInfo(sid)
+	}
 	return m.sealing.GetSectorInfo(sid)
 }
 
+// sealerSectorInfo reads the info of a sector from the sealing node, with the
+// fields needed to read and unseal the sector data
+func (m *Miner) sealerSectorInfo(sid abi.SectorNumber) (sealing.SectorInfo, error) {
+	si, err := m.sealerSectors.SectorsStatus(context.TODO(), sid, true)
+	if err != nil {
+		return sealing.SectorInfo{}, xerrors.Errorf("getting sector info from the sealing node: %w", err)
+	}
+
+	return sealing.SectorInfo{
+		State:        sealing.SectorState(si.State),
+		SectorNumber: sid,
+		SectorType:   si.SealProof,
+		TicketValue:  si.Ticket.Value,
+		TicketEpoch:  si.Ticket.Epoch,
+		CommD:        si.CommD,
+		CommR:        si.CommR,
+	}, nil
+}
+
 func (m *Miner) PledgeSector(ctx context.Context) (storage.SectorRef, error) {
+	if m.sealingDisabled {
+		return storage.SectorRef{}, ErrSealingDisabled
+	}
 	return m.sealing.PledgeSector(ctx)
 }
 
 func (m *Miner) ForceSectorState(ctx context.Context, id abi.SectorNumber, state sealing.SectorState) error {
+	if m.sealingDisabled {
+		return ErrSealingDisabled
+	}
 	return m.sealing.ForceSectorState(ctx, id, state)
 }
 
 func (m *Miner) RemoveSector(ctx context.Context, id abi.SectorNumber) error {
+	if m.sealingDisabled {
+		return ErrSealingDisabled
+	}
 	return m.sealing.Remove(ctx, id)
 }
 
 func (m *Miner) TerminateSector(ctx context.Context, id abi.SectorNumber) error {
+	if m.sealingDisabled {
+		return ErrSealingDisabled
+	}
 	return m.sealing.Terminate(ctx, id)
 }
 
+func (m *Miner) ExtendSector(ctx context.Context, id abi.SectorNumber, newExp abi.ChainEpoch) (cid.Cid, error) {
+	if m.sealingDisabled {
+		return cid.Undef, ErrSealingDisabled
+	}
+	return m.sealing.ExtendSector(ctx, id, newExp)
+}
+
 func (m *Miner) TerminateFlush(ctx context.Context) (*cid.Cid, error) {
+	if m.sealingDisabled {
+		return nil, ErrSealingDisabled
+	}
 	return m.sealing.TerminateFlush(ctx)
 }
 
 func (m *Miner) TerminatePending(ctx context.Context) ([]abi.SectorID, error) {
+	if m.sealingDisabled {
+		return nil, ErrSealingDisabled
+	}
 	return m.sealing.TerminatePending(ctx)
 }
 
 func (m *Miner) SectorPreCommitFlush(ctx context.Context) ([]sealiface.PreCommitBatchRes, error) {
+	if m.sealingDisabled {
+		return nil, ErrSealingDisabled
+	}
 	return m.sealing.SectorPreCommitFlush(ctx)
 }
 
 func (m *Miner) SectorPreCommitPending(ctx context.Context) ([]abi.SectorID, error) {
+	if m.sealingDisabled {
+		return nil, ErrSealingDisabled
+	}
 	return m.sealing.SectorPreCommitPending(ctx)
 }
 
 func (m *Miner) CommitFlush(ctx context.Context) ([]sealiface.CommitBatchRes, error) {
+	if m.sealingDisabled {
+		return nil, ErrSealingDisabled
+	}
 	return m.sealing.CommitFlush(ctx)
 }
 
 func (m *Miner) CommitPending(ctx context.Context) ([]abi.SectorID, error) {
+	if m.sealingDisabled {
+		return nil, ErrSealingDisabled
+	}
 	return m.sealing.CommitPending(ctx)
 }
 
+func (m *Miner) FinalizeFlush(ctx context.Context) ([]abi.SectorID, error) {
+	if m.sealingDisabled {
+		return nil, ErrSealingDisabled
+	}
+	return m.sealing.FinalizeFlush(ctx)
+}
+
+func (m *Miner) FinalizePending(ctx context.Context) ([]abi.SectorID, error) {
+	if m.sealingDisabled {
+		return nil, ErrSealingDisabled
+	}
+	return m.sealing.FinalizePending(ctx)
+}
+
+func (m *Miner) ReconcileReport(ctx context.Context) (sealiface.ReconcileReport, error) {
+	if m.sealingDisabled {
+		return sealiface.ReconcileReport{}, ErrSealingDisabled
+	}
+	return m.sealing.ReconcileReport(ctx)
+}
+
+func (m *Miner) PipelineLimits(ctx context.Context) ([]sealiface.PipelineLimit, error) {
+	if m.sealingDisabled {
+		return nil, ErrSealingDisabled
+	}
+	return m.sealing.PipelineLimits(ctx)
+}
+
 func (m *Miner) MarkForUpgrade(id abi.SectorNumber) error {
+	if m.sealingDisabled {
+		return ErrSealingDisabled
+	}
 	return m.sealing.MarkForUpgrade(id)
 }
 
 func (m *Miner) IsMarkedForUpgrade(id abi.SectorNumber) bool {
+	if m.sealingDisabled {
+		return false
+	}
 	return m.sealing.IsMarkedForUpgrade(id)
 }
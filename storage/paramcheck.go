@@ -0,0 +1,324 @@
+package storage
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	fslock "github.com/ipfs/go-fs-lock"
+	"github.com/minio/blake2b-simd"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/lotus/api"
+)
+
+// how often the config is re-read when param verification is disabled
+const paramsConfigRecheck = time.Minute
+
+// paramsFetchLock is the lock file in the parameter directory which
+// paramfetch holds while downloading parameter files. Holding it keeps the
+// verifier from replacing a file while paramfetch, e.g. in a starting worker
+// sharing the directory, fetches or checks it.
+const paramsFetchLock = "fetch.lock"
+
+const paramsLockRetry = 5 * time.Second
+
+const (
+	defaultParamsDir     = "/var/tmp/filecoin-proof-parameters"
+	defaultParamsGateway = "https://proofs.filecoin.io/ipfs/"
+)
+
+type paramFile struct {
+	Cid        string `json:"cid"`
+	Digest     string `json:"digest"`
+	SectorSize uint64 `json:"sector_size"`
+}
+
+// ParamFetchFunc downloads the parameter file with the given cid to path.
+//
+// The default doesn't use paramfetch.GetParams for the download: it remembers
+// the files it checked in the process, so it doesn't fetch a file which went
+// bad after startup, and it resumes downloads into the existing file.
+type ParamFetchFunc func(ctx context.Context, cid string, path string) error
+
+// ParamsVerifier periodically verifies the checksums of the proof parameter
+// files the miner needs against the parameters manifest, and downloads the
+// files which don't match again.
+type ParamsVerifier struct {
+	dir         string
+	params      map[string]paramFile
+	fetch       ParamFetchFunc
+	getInterval func() (time.Duration, error)
+
+	lk      sync.Mutex
+	status  api.ParamsStatus
+	running bool
+
+	stop, stopped chan struct{}
+}
+
+// NewParamsVerifier creates a verifier of the parameter files in the manifest
+// for the sector size. getInterval returns how often to verify the files, 0
+// disables verification.
+func NewParamsVerifier(manifest []byte, ssize uint64, getInterval func() (time.Duration, error)) (*ParamsVerifier, error) {
+	var all map[string]paramFile
+	if err := json.Unmarshal(manifest, &all); err != nil {
+		return nil, xerrors.Errorf("parsing params manifest: %w", err)
+	}
+
+	// like paramfetch, verification keys of all sizes, and the params of the
+	// sector size only
+	params := map[string]paramFile{}
+	for name, info := range all {
+		if info.SectorSize != ssize && strings.HasSuffix(name, ".params") {
+			continue
+		}
+		params[name] = info
+	}
+
+	dir := os.Getenv("FIL_PROOFS_PARAMETER_CACHE")
+	if dir == "" {
+		dir = defaultParamsDir
+	}
+
+	return &ParamsVerifier{
+		dir:         dir,
+		params:      params,
+		fetch:       fetchParam,
+		getInterval: getInterval,
+
+		stop:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}, nil
+}
+
+func (v *ParamsVerifier) Run(ctx context.Context) {
+	v.lk.Lock()
+	v.running = true
+	v.lk.Unlock()
+
+	defer close(v.stopped)
+
+	for {
+		interval, err := v.getInterval()
+		if err != nil {
+			log.Warnw("ParamsVerifier getconfig error", "error", err)
+		}
+
+		enabled := interval > 0
+		if !enabled {
+			interval = paramsConfigRecheck
+		}
+
+		select {
+		case <-v.stop:
+			return
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+
+		if !enabled {
+			continue
+		}
+
+		if err := v.Verify(ctx); err != nil {
+			log.Errorw("verifying proof parameters", "error", err)
+		}
+	}
+}
+
+// Verify checks the checksums of all parameter files, and downloads the
+// corrupted ones again
+func (v *ParamsVerifier) Verify(ctx context.Context) error {
+	names := make([]string, 0, len(v.params))
+	for name := range v.params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	st := api.ParamsStatus{}
+	var errs []string
+
+	for _, name := range names {
+		info := v.params[name]
+		path := filepath.Join(v.dir, name)
+
+		ok, err := checkParamFile(path, info.Digest)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		st.Checked++
+		if ok {
+			continue
+		}
+
+		log.Errorw("proof parameter file checksum mismatch, downloading it again", "file", path)
+		st.Corrupted = append(st.Corrupted, name)
+
+		if err := v.refetch(ctx, path, info); err != nil {
+			errs = append(errs, xerrors.Errorf("downloading %s: %w", name, err).Error())
+			continue
+		}
+		st.Redownloaded = append(st.Redownloaded, name)
+	}
+
+	st.LastVerified = time.Now()
+	if len(errs) > 0 {
+		st.Error = strings.Join(errs, "; ")
+	}
+
+	v.lk.Lock()
+	v.status = st
+	v.lk.Unlock()
+
+	if st.Error != "" {
+		return xerrors.New(st.Error)
+	}
+	return nil
+}
+
+func (v *ParamsVerifier) refetch(ctx context.Context, path string, info paramFile) error {
+	unlock, err := v.lockFetch(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock.Close() // nolint
+
+	// paramfetch may have replaced the file while we were waiting for the lock
+	if ok, err := checkParamFile(path, info.Digest); err == nil && ok {
+		return nil
+	}
+
+	tmp := path + ".verify-tmp"
+	if err := v.fetch(ctx, info.Cid, tmp); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+
+	ok, err := checkParamFile(tmp, info.Digest)
+	if err != nil || !ok {
+		_ = os.Remove(tmp)
+		if err == nil {
+			err = xerrors.New("checksum mismatch in downloaded file")
+		}
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
+
+// Status returns the result of the last verification
+func (v *ParamsVerifier) Status() api.ParamsStatus {
+	v.lk.Lock()
+	defer v.lk.Unlock()
+
+	return v.status
+}
+
+// lockFetch acquires the paramfetch lock of the parameter directory, waiting
+// while another process holds it
+func (v *ParamsVerifier) lockFetch(ctx context.Context) (io.Closer, error) {
+	for {
+		unlock, err := fslock.Lock(v.dir, paramsFetchLock)
+		if err == nil {
+			return unlock, nil
+		}
+
+		le := fslock.LockedError("")
+		if !xerrors.As(err, &le) {
+			return nil, xerrors.Errorf("acquiring param fetch lock: %w", err)
+		}
+
+		log.Warnw("param fetch lock held by another process, waiting", "dir", v.dir, "retry", paramsLockRetry)
+		select {
+		case <-time.After(paramsLockRetry):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// Stop stops the verification loop, it returns immediately if Run was never
+// called
+func (v *ParamsVerifier) Stop(ctx context.Context) error {
+	v.lk.Lock()
+	running := v.running
+	v.lk.Unlock()
+
+	close(v.stop)
+	if !running {
+		return nil
+	}
+
+	select {
+	case <-v.stopped:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// checkParamFile returns whether the checksum of the file matches the digest
+// in the manifest, which is the hex encoded first 16 bytes of the blake2b-512
+// hash of the file. Missing files don't match.
+func checkParamFile(path string, digest string) (bool, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, xerrors.Errorf("opening param file: %w", err)
+	}
+	defer f.Close() // nolint
+
+	h := blake2b.New512()
+	if _, err := io.Copy(h, f); err != nil {
+		return false, xerrors.Errorf("hashing param file %s: %w", path, err)
+	}
+
+	return hex.EncodeToString(h.Sum(nil)[:16]) == digest, nil
+}
+
+func fetchParam(ctx context.Context, cid string, path string) error {
+	gw := os.Getenv("IPFS_GATEWAY")
+	if gw == "" {
+		gw = defaultParamsGateway
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, gw+cid, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return xerrors.Errorf("fetching param file: %w", err)
+	}
+	defer resp.Body.Close() // nolint
+
+	if resp.StatusCode != http.StatusOK {
+		return xerrors.Errorf("fetching param file: non-200 code: %d", resp.StatusCode)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(f, resp.Body); err != nil {
+		_ = f.Close()
+		return xerrors.Errorf("writing param file: %w", err)
+	}
+
+	return f.Close()
+}
@@ -0,0 +1,102 @@
+package storage
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/minio/blake2b-simd"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParamsVerifier(t *testing.T) {
+	dir := t.TempDir()
+
+	files := map[string][]byte{
+		"v28-proof-of-spacetime-fallback-a.vk":     []byte("verifying key"),
+		"v28-proof-of-spacetime-fallback-a.params": []byte("parameters"),
+		"v28-proof-of-spacetime-fallback-b.params": []byte("parameters of another size"),
+	}
+
+	manifest := map[string]paramFile{}
+	for name, data := range files {
+		sum := blake2b.Sum512(data)
+
+		ssize := uint64(2048)
+		if name == "v28-proof-of-spacetime-fallback-b.params" {
+			ssize = 8 << 20
+		}
+
+		manifest[name] = paramFile{
+			Cid:        "cid-" + name,
+			Digest:     hex.EncodeToString(sum[:16]),
+			SectorSize: ssize,
+		}
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), data, 0644))
+	}
+
+	mb, err := json.Marshal(manifest)
+	require.NoError(t, err)
+
+	v, err := NewParamsVerifier(mb, 2048, func() (time.Duration, error) {
+		return 10 * time.Millisecond, nil
+	})
+	require.NoError(t, err)
+	v.dir = dir
+
+	var fetched int32
+	v.fetch = func(ctx context.Context, cid string, path string) error {
+		atomic.AddInt32(&fetched, 1)
+		require.Equal(t, "cid-v28-proof-of-spacetime-fallback-a.params", cid)
+		return ioutil.WriteFile(path, files["v28-proof-of-spacetime-fallback-a.params"], 0644)
+	}
+
+	// params of other sector sizes aren't verified
+	require.Len(t, v.params, 2)
+
+	require.NoError(t, v.Verify(context.Background()))
+	st := v.Status()
+	require.False(t, st.LastVerified.IsZero())
+	require.Equal(t, 2, st.Checked)
+	require.Empty(t, st.Corrupted)
+
+	// corrupt a file, the scheduled verification downloads it again
+	corrupted := filepath.Join(dir, "v28-proof-of-spacetime-fallback-a.params")
+	require.NoError(t, ioutil.WriteFile(corrupted, []byte("parametERs"), 0644))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go v.Run(ctx)
+
+	require.Eventually(t, func() bool {
+		st = v.Status()
+		return len(st.Redownloaded) > 0
+	}, 5*time.Second, 10*time.Millisecond)
+
+	require.NoError(t, v.Stop(ctx))
+
+	require.Equal(t, []string{"v28-proof-of-spacetime-fallback-a.params"}, st.Corrupted)
+	require.Equal(t, []string{"v28-proof-of-spacetime-fallback-a.params"}, st.Redownloaded)
+	require.Empty(t, st.Error)
+	require.EqualValues(t, 1, atomic.LoadInt32(&fetched))
+
+	data, err := ioutil.ReadFile(corrupted)
+	require.NoError(t, err)
+	require.Equal(t, files["v28-proof-of-spacetime-fallback-a.params"], data)
+}
+
+func TestParamsVerifierStopNotStarted(t *testing.T) {
+	v, err := NewParamsVerifier([]byte("{}"), 2048, func() (time.Duration, error) {
+		return 0, nil
+	})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	require.NoError(t, v.Stop(ctx))
+}
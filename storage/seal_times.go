@@ -0,0 +1,103 @@
+package storage
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	sealing "github.com/filecoin-project/lotus/extern/storage-sealing"
+)
+
+const (
+	// number of most recently sealed sectors the expected seal duration is
+	// derived from
+	sealTimesWindow = 20
+	// minimum number of sealed sectors needed to derive the expected seal
+	// duration
+	sealTimesMinSamples = 3
+	// percentile of recent seal durations used as the expected seal duration
+	sealTimesPercentile = 90
+)
+
+// sector log entry kind of the event recorded when a sector commit lands on
+// chain, matches how the sealing FSM names event log entries
+var provingEventKind = fmt.Sprintf("event;%T", sealing.SectorProving{})
+
+// sealTimes keeps the durations it took recently sealed sectors to go from
+// being created to being proven on chain
+type sealTimes struct {
+	lk sync.Mutex
+
+	// sorted by the time the sector landed on chain, oldest first
+	samples []sealTime
+}
+
+type sealTime struct {
+	proven   time.Time
+	duration time.Duration
+}
+
+// record adds the seal duration of the sector, if it has landed on chain
+func (st *sealTimes) record(si sealing.SectorInfo) {
+	s, ok := sectorSealTime(si)
+	if !ok {
+		return
+	}
+
+	st.lk.Lock()
+	defer st.lk.Unlock()
+
+	i := sort.Search(len(st.samples), func(i int) bool {
+		return st.samples[i].proven.After(s.proven)
+	})
+	st.samples = append(st.samples, sealTime{})
+	copy(st.samples[i+1:], st.samples[i:])
+	st.samples[i] = s
+
+	if len(st.samples) > sealTimesWindow {
+		st.samples = st.samples[len(st.samples)-sealTimesWindow:]
+	}
+}
+
+// expected returns the sealTimesPercentile percentile of recent seal
+// durations, false if not enough sectors were sealed yet
+func (st *sealTimes) expected() (time.Duration, bool) {
+	st.lk.Lock()
+	defer st.lk.Unlock()
+
+	if len(st.samples) < sealTimesMinSamples {
+		return 0, false
+	}
+
+	durations := make([]time.Duration, len(st.samples))
+	for i, s := range st.samples {
+		durations[i] = s.duration
+	}
+	sort.Slice(durations, func(i, j int) bool {
+		return durations[i] < durations[j]
+	})
+
+	idx := (len(durations)*sealTimesPercentile+99)/100 - 1
+	return durations[idx], true
+}
+
+// sectorSealTime reads from the sector log when the sector was created and
+// when its commit landed on chain
+func sectorSealTime(si sealing.SectorInfo) (sealTime, bool) {
+	if len(si.Log) == 0 {
+		return sealTime{}, false
+	}
+
+	for _, l := range si.Log {
+		if l.Kind != provingEventKind {
+			continue
+		}
+
+		start := time.Unix(int64(si.Log[0].Timestamp), 0)
+		proven := time.Unix(int64(l.Timestamp), 0)
+		return sealTime{proven: proven, duration: proven.Sub(start)}, true
+	}
+
+	return sealTime{}, false
+}
@@ -0,0 +1,66 @@
+package storage
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-state-types/abi"
+
+	sealing "github.com/filecoin-project/lotus/extern/storage-sealing"
+	"github.com/filecoin-project/lotus/journal"
+)
+
+func sealedSector(num abi.SectorNumber, start time.Time, took time.Duration) sealing.SectorInfo {
+	return sealing.SectorInfo{
+		SectorNumber: num,
+		State:        sealing.FinalizeSector,
+		Log: []sealing.Log{
+			{Timestamp: uint64(start.Unix()), Kind: fmt.Sprintf("event;%T", sealing.SectorStart{})},
+			{Timestamp: uint64(start.Add(took / 2).Unix()), Kind: fmt.Sprintf("event;%T", sealing.SectorPreCommitted{})},
+			{Timestamp: uint64(start.Add(took).Unix()), Kind: fmt.Sprintf("event;%T", sealing.SectorProving{})},
+		},
+	}
+}
+
+func TestRecentSealDuration(t *testing.T) {
+	m := &Miner{journal: journal.NilJournal()}
+
+	seal := func(num abi.SectorNumber, start time.Time, took time.Duration) {
+		after := sealedSector(num, start, took)
+		before := after
+		before.State = sealing.CommitWait
+		m.handleSealingNotifications(before, after)
+	}
+
+	start := time.Now().Add(-24 * time.Hour)
+
+	seal(1, start, 6*time.Hour)
+	seal(2, start.Add(time.Hour), 5*time.Hour)
+
+	_, ok := m.RecentSealDuration()
+	require.False(t, ok, "not enough sectors sealed")
+
+	seal(3, start.Add(2*time.Hour), 8*time.Hour)
+
+	d, ok := m.RecentSealDuration()
+	require.True(t, ok)
+	require.Equal(t, 8*time.Hour, d)
+
+	// state changes other than commits landing don't count
+	m.handleSealingNotifications(sealedSector(4, start, time.Hour), sealedSector(4, start, time.Hour))
+	d, _ = m.RecentSealDuration()
+	require.Equal(t, 8*time.Hour, d)
+
+	// once sealing gets faster, old slow sectors drop out of the window
+	for i := 0; i < sealTimesWindow; i++ {
+		seal(abi.SectorNumber(10+i), start.Add(10*time.Hour+time.Duration(i)*time.Minute), 2*time.Hour+time.Duration(i)*time.Minute)
+	}
+
+	d, ok = m.RecentSealDuration()
+	require.True(t, ok)
+	// 90th percentile of 2h..2h19m
+	require.Equal(t, 2*time.Hour+17*time.Minute, d)
+}
@@ -39,9 +39,9 @@ type changeHandler struct {
 	submitHdlr *submitHandler
 }
 
-func newChangeHandler(api wdPoStCommands, actor address.Address) *changeHandler {
+func newChangeHandler(api wdPoStCommands, actor address.Address, lookahead bool) *changeHandler {
 	posts := newPostsCache()
-	p := newProver(api, posts)
+	p := newProver(api, posts, lookahead)
 	s := newSubmitter(api, posts)
 	return &changeHandler{api: api, actor: actor, proveHdlr: p, submitHdlr: s}
 }
@@ -157,6 +157,11 @@ type proveHandler struct {
 
 	current *currentPost
 
+	// When enabled, proving for the next deadline starts as soon as its
+	// challenge is available, without waiting for the current proof
+	lookaheadEnabled bool
+	lookahead        *currentPost
+
 	shutdownCtx context.Context
 	shutdown    context.CancelFunc
 
@@ -168,15 +173,17 @@ type proveHandler struct {
 func newProver(
 	api wdPoStCommands,
 	posts *postsCache,
+	lookahead bool,
 ) *proveHandler {
 	ctx, cancel := context.WithCancel(context.Background())
 	return &proveHandler{
-		api:         api,
-		posts:       posts,
-		postResults: make(chan *postResult),
-		hcs:         make(chan *headChange),
-		shutdownCtx: ctx,
-		shutdown:    cancel,
+		api:              api,
+		posts:            posts,
+		postResults:      make(chan *postResult),
+		hcs:              make(chan *headChange),
+		lookaheadEnabled: lookahead,
+		shutdownCtx:      ctx,
+		shutdown:         cancel,
 	}
 }
 
@@ -186,6 +193,9 @@ func (p *proveHandler) run() {
 		if p.current != nil {
 			p.current.abort()
 		}
+		if p.lookahead != nil {
+			p.lookahead.abort()
+		}
 	}()
 
 	for p.shutdownCtx.Err() == nil {
@@ -222,29 +232,61 @@ func (p *proveHandler) processHeadChange(ctx context.Context, newTS *types.TipSe
 		p.current = nil
 	}
 
-	// Only generate one proof at a time
-	if p.current != nil {
+	if p.lookahead != nil && newTS.Height() >= p.lookahead.di.Close {
+		p.lookahead.abort()
+		p.lookahead = nil
+	}
+
+	// Once the current proof is done, the proof which was started ahead of
+	// time becomes the current one
+	if p.current == nil && p.lookahead != nil {
+		p.current, p.lookahead = p.lookahead, nil
+	}
+
+	if p.current == nil {
+		// If the proof for the current post window has been generated, check the
+		// next post window
+		_, complete := p.posts.get(di)
+		for complete {
+			di = nextDeadline(di)
+			_, complete = p.posts.get(di)
+		}
+
+		// Check if the chain is above the Challenge height for the post window
+		if newTS.Height() < di.Challenge+ChallengeConfidence {
+			return
+		}
+
+		p.current = p.startProving(ctx, newTS, di)
+	}
+
+	// Only generate one proof at a time, unless look-ahead proving is enabled,
+	// in which case the proof for the following deadline can be generated
+	// while the current one is in progress
+	if !p.lookaheadEnabled || p.lookahead != nil {
 		return
 	}
 
-	// If the proof for the current post window has been generated, check the
-	// next post window
-	_, complete := p.posts.get(di)
-	for complete {
-		di = nextDeadline(di)
-		_, complete = p.posts.get(di)
+	next := nextDeadline(p.current.di)
+	if _, complete := p.posts.get(next); complete {
+		return
 	}
 
-	// Check if the chain is above the Challenge height for the post window
-	if newTS.Height() < di.Challenge+ChallengeConfidence {
+	// The challenge for the next deadline can't be drawn before the chain
+	// reaches its Challenge height
+	if newTS.Height() < next.Challenge+ChallengeConfidence {
 		return
 	}
 
-	p.current = &currentPost{di: di}
-	curr := p.current
-	p.current.abort = p.api.startGeneratePoST(ctx, newTS, di, func(posts []miner.SubmitWindowedPoStParams, err error) {
-		p.postResults <- &postResult{ts: newTS, currPost: curr, posts: posts, err: err}
+	p.lookahead = p.startProving(ctx, newTS, next)
+}
+
+func (p *proveHandler) startProving(ctx context.Context, ts *types.TipSet, di *dline.Info) *currentPost {
+	curr := &currentPost{di: di}
+	curr.abort = p.api.startGeneratePoST(ctx, ts, di, func(posts []miner.SubmitWindowedPoStParams, err error) {
+		p.postResults <- &postResult{ts: ts, currPost: curr, posts: posts, err: err}
 	})
+	return curr
 }
 
 func (p *proveHandler) processPostResult(res *postResult) {
@@ -260,12 +302,18 @@ func (p *proveHandler) processPostResult(res *postResult) {
 			// If the current post was not already aborted, setting it to nil
 			// marks it as complete so that a new post can be started
 			p.current = nil
+		} else if p.lookahead == res.currPost {
+			p.lookahead = nil
 		}
 		return
 	}
 
 	// Completed processing this proving window
-	p.current = nil
+	if p.lookahead == res.currPost {
+		p.lookahead = nil
+	} else {
+		p.current = nil
+	}
 
 	// Add the proofs to the cache
 	p.posts.add(di, res.posts)
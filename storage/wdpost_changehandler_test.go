@@ -433,6 +433,82 @@ func TestChangeHandlerStartProvingNextDeadline(t *testing.T) {
 	require.Equal(t, postStatusProving, s.mock.getPostStatus(di))
 }
 
+// TestChangeHandlerLookaheadProving verifies that with look-ahead proving
+// enabled, the proof for the next deadline is generated while the current
+// deadline is still being proven, but only submitted once its deadline opens
+func TestChangeHandlerLookaheadProving(t *testing.T) {
+	s := makeScaffolding(t)
+	s.ch.proveHdlr.lookaheadEnabled = true
+	mock := s.mock
+
+	defer s.ch.shutdown()
+	s.ch.start()
+
+	// Trigger a head change
+	currentEpoch := abi.ChainEpoch(1)
+	go triggerHeadAdvance(t, s, currentEpoch+ChallengeConfidence)
+
+	// Should start proving
+	<-s.ch.proveHdlr.processedHeadChanges
+	di := mock.getDeadline(currentEpoch)
+	require.Equal(t, postStatusProving, s.mock.getPostStatus(di))
+	<-s.ch.submitHdlr.processedHeadChanges
+
+	// Trigger a head change just before the Challenge epoch of the next
+	// deadline
+	next := nextDeadline(di)
+	go triggerHeadAdvance(t, s, next.Challenge+ChallengeConfidence-1)
+
+	// The next deadline's challenge isn't available yet
+	<-s.ch.proveHdlr.processedHeadChanges
+	require.Equal(t, postStatusStart, s.mock.getPostStatus(next))
+	<-s.ch.submitHdlr.processedHeadChanges
+
+	// Trigger a head change at the Challenge epoch for the next deadline,
+	// while the current deadline is still open and being proven
+	currentEpoch = next.Challenge + ChallengeConfidence
+	require.Less(t, int64(currentEpoch), int64(di.Close))
+	go triggerHeadAdvance(t, s, currentEpoch)
+
+	// Should start generating the next deadline's proof alongside the current
+	<-s.ch.proveHdlr.processedHeadChanges
+	require.Equal(t, postStatusProving, s.mock.getPostStatus(di))
+	require.Equal(t, postStatusProving, s.mock.getPostStatus(next))
+	<-s.ch.submitHdlr.processedHeadChanges
+
+	// Complete both proofs
+	for i := 0; i < 2; i++ {
+		mock.proveResult <- &proveRes{posts: []miner.SubmitWindowedPoStParams{{}}}
+		<-s.ch.proveHdlr.processedPostResults
+	}
+	require.Equal(t, postStatusComplete, s.mock.getPostStatus(di))
+	require.Equal(t, postStatusComplete, s.mock.getPostStatus(next))
+
+	// The current deadline's proof gets submitted
+	mock.submitResult <- nil
+	<-s.ch.submitHdlr.processedSubmitResults
+	require.Equal(t, SubmitStateComplete, s.submitState(di))
+
+	// The next deadline's proof isn't submitted before its deadline opens
+	require.Nil(t, s.ch.submitHdlr.getPostWindow(next))
+
+	// Move to the correct height to submit the next deadline's proof
+	currentEpoch = next.Open + SubmitConfidence
+	go triggerHeadAdvance(t, s, currentEpoch)
+
+	// Shouldn't start proving the deadline after next yet
+	<-s.ch.proveHdlr.processedHeadChanges
+	require.Equal(t, postStatusStart, s.mock.getPostStatus(nextDeadline(next)))
+
+	// Should move to submitting state
+	<-s.ch.submitHdlr.processedHeadChanges
+	require.Equal(t, SubmitStateSubmitting, s.submitState(next))
+
+	mock.submitResult <- nil
+	<-s.ch.submitHdlr.processedSubmitResults
+	require.Equal(t, SubmitStateComplete, s.submitState(next))
+}
+
 // TestChangeHandlerProvingRounds verifies we can generate several rounds of
 // proofs as the chain head advances
 func TestChangeHandlerProvingRounds(t *testing.T) {
@@ -1095,7 +1171,7 @@ func makeScaffolding(t *testing.T) *smScaffolding {
 	ctx := context.Background()
 	actor := tutils.NewActorAddr(t, "actor")
 	mock := newMockAPI()
-	ch := newChangeHandler(mock, actor)
+	ch := newChangeHandler(mock, actor, false)
 	mock.setChangeHandler(ch)
 
 	ch.proveHdlr.processedHeadChanges = make(chan *headChange)
@@ -0,0 +1,52 @@
+package storage
+
+import (
+	"context"
+
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/chain/actors/builtin/miner"
+)
+
+// DryRun checks the sectors of the deadline the way the scheduler does ahead
+// of the deadline, and returns the faults and recoveries it would declare,
+// without sending any messages
+func (s *WindowPoStScheduler) DryRun(ctx context.Context, dlIdx uint64) (api.WindowPoStDryRun, error) {
+	if dlIdx >= miner.WPoStPeriodDeadlines {
+		return api.WindowPoStDryRun{}, xerrors.Errorf("invalid deadline %d, the proving period has %d deadlines", dlIdx, miner.WPoStPeriodDeadlines)
+	}
+
+	ts, err := s.api.ChainHead(ctx)
+	if err != nil {
+		return api.WindowPoStDryRun{}, xerrors.Errorf("getting chain head: %w", err)
+	}
+
+	partitions, err := s.api.StateMinerPartitions(ctx, s.actor, dlIdx, ts.Key())
+	if err != nil {
+		return api.WindowPoStDryRun{}, xerrors.Errorf("getting partitions: %w", err)
+	}
+
+	out := api.WindowPoStDryRun{
+		Deadline: dlIdx,
+		Height:   ts.Height(),
+	}
+
+	recoveries, _, err := s.checkRecoveries(ctx, dlIdx, partitions, ts.Key())
+	if err != nil {
+		return api.WindowPoStDryRun{}, xerrors.Errorf("checking sector recoveries: %w", err)
+	}
+	for _, r := range recoveries {
+		out.Recoveries = append(out.Recoveries, api.PartitionSectors{Partition: r.Partition, Sectors: r.Sectors})
+	}
+
+	faults, _, err := s.checkFaults(ctx, dlIdx, partitions, ts.Key())
+	if err != nil {
+		return api.WindowPoStDryRun{}, xerrors.Errorf("checking sector faults: %w", err)
+	}
+	for _, f := range faults {
+		out.Faults = append(out.Faults, api.PartitionSectors{Partition: f.Partition, Sectors: f.Sectors})
+	}
+
+	return out, nil
+}
@@ -3,6 +3,8 @@ package storage
 import (
 	"bytes"
 	"context"
+	"fmt"
+	"sync"
 	"time"
 
 	"github.com/filecoin-project/go-bitfield"
@@ -66,6 +68,17 @@ func (s *WindowPoStScheduler) recordProofsEvent(partitions []miner.PoStPartition
 	})
 }
 
+// recordFaultHistory adds the event to the fault history of the given sectors
+func (s *WindowPoStScheduler) recordFaultHistory(sectors bitfield.BitField, evt api.SectorFaultEvent) {
+	if s.faultHistory == nil {
+		return
+	}
+
+	if err := s.faultHistory.Record(sectors, evt); err != nil {
+		log.Errorw("recording sector fault history", "kind", evt.Kind, "deadline", evt.Deadline, "partition", evt.Partition, "error", err)
+	}
+}
+
 // startGeneratePoST kicks off the process of generating a PoST
 func (s *WindowPoStScheduler) startGeneratePoST(
 	ctx context.Context,
@@ -186,6 +199,16 @@ func (s *WindowPoStScheduler) runSubmitPoST(
 			log.Errorf("submit window post failed: %+v", submitErr)
 		} else {
 			s.recordProofsEvent(post.Partitions, sm.Cid())
+
+			for _, partition := range post.Partitions {
+				s.recordFaultHistory(partition.Skipped, api.SectorFaultEvent{
+					Kind:      api.SectorFaultSkipped,
+					Epoch:     ts.Height(),
+					Deadline:  post.Deadline,
+					Partition: partition.Index,
+					Message:   sm.Cid(),
+				})
+			}
 		}
 	}
 
@@ -216,7 +239,7 @@ func (s *WindowPoStScheduler) checkSectors(ctx context.Context, check bitfield.B
 		})
 	}
 
-	bad, err := s.faultTracker.CheckProvable(ctx, s.proofType, tocheck, nil)
+	bad, err := s.checkProvable(ctx, tocheck)
 	if err != nil {
 		return bitfield.BitField{}, xerrors.Errorf("checking provable sectors: %w", err)
 	}
@@ -234,41 +257,186 @@ func (s *WindowPoStScheduler) checkSectors(ctx context.Context, check bitfield.B
 	return sbf, nil
 }
 
+// checkProvable returns the sectors which can't be proven. With a
+// SingleCheckTimeout or PartitionCheckTimeout set, the sectors are checked one
+// at a time, and sectors which take too long to check are returned as bad.
+func (s *WindowPoStScheduler) checkProvable(ctx context.Context, sectors []storage.SectorRef) (map[abi.SectorID]string, error) {
+	singleTimeout := time.Duration(s.provingCfg.SingleCheckTimeout)
+	partitionTimeout := time.Duration(s.provingCfg.PartitionCheckTimeout)
+	if singleTimeout <= 0 && partitionTimeout <= 0 {
+		return s.faultTracker.CheckProvable(ctx, s.proofType, sectors, nil)
+	}
+
+	var partitionDone <-chan time.Time
+	if partitionTimeout > 0 {
+		t := build.Clock.Timer(partitionTimeout)
+		defer t.Stop()
+		partitionDone = t.C
+	}
+
+	bad := make(map[abi.SectorID]string)
+	for i, sector := range sectors {
+		sb, timedOut, err := s.checkProvableSingle(ctx, sector, singleTimeout, partitionDone)
+		if err != nil {
+			return nil, err
+		}
+
+		if timedOut {
+			log.Warnw("CheckProvable FAULT: partition check timed out", "unchecked", len(sectors)-i, "timeout", partitionTimeout)
+			for _, sector := range sectors[i:] {
+				bad[sector.ID] = fmt.Sprintf("partition check timed out after %s", partitionTimeout)
+			}
+			break
+		}
+
+		for id, reason := range sb {
+			bad[id] = reason
+		}
+	}
+
+	return bad, nil
+}
+
+// checkProvableSingle checks a single sector, giving up on it after the
+// timeout, or when partitionDone fires, in which case timedOut is returned
+func (s *WindowPoStScheduler) checkProvableSingle(ctx context.Context, sector storage.SectorRef, timeout time.Duration, partitionDone <-chan time.Time) (bad map[abi.SectorID]string, timedOut bool, err error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type checkRes struct {
+		bad map[abi.SectorID]string
+		err error
+	}
+
+	// the checks don't necessarily return when the context is cancelled, e.g.
+	// when stat-ing files on a hung mount, so don't wait for them
+	done := make(chan checkRes, 1)
+	go func() {
+		bad, err := s.faultTracker.CheckProvable(ctx, s.proofType, []storage.SectorRef{sector}, nil)
+		done <- checkRes{bad: bad, err: err}
+	}()
+
+	var singleDone <-chan time.Time
+	if timeout > 0 {
+		t := build.Clock.Timer(timeout)
+		defer t.Stop()
+		singleDone = t.C
+	}
+
+	select {
+	case res := <-done:
+		return res.bad, false, res.err
+	case <-singleDone:
+		log.Warnw("CheckProvable Sector FAULT: check timed out", "sector", sector, "timeout", timeout)
+		return map[abi.SectorID]string{sector.ID: fmt.Sprintf("check timed out after %s", timeout)}, false, nil
+	case <-partitionDone:
+		return nil, true, nil
+	case <-ctx.Done():
+		return nil, false, ctx.Err()
+	}
+}
+
 // declareRecoveries identifies sectors that were previously marked as faulty
 // for our miner, but are now recovered (i.e. are now provable again) and
 // still not reported as such.
 //
-// It then reports the recovery on chain via a `DeclareFaultsRecovered`
-// message to our miner actor.
+// It then reports the recovery on chain via `DeclareFaultsRecovered` messages
+// to our miner actor, each declaring recoveries in up to
+// MaxPartitionsPerRecoveryMessage partitions. Nothing is declared when
+// automatic recovery is disabled in the proving config.
 //
 // This is always invoked ahead of time, before the deadline for the evaluated
 // sectors arrives. That way, recoveries are declared in preparation for those
 // sectors to be proven.
 //
-// If a declaration is made, it awaits for build.MessageConfidence confirmations
-// on chain before returning.
+// If declarations are made, all messages are pushed first, and then it awaits
+// for build.MessageConfidence confirmations of all of them at once. The
+// declarations of the messages which landed are returned, with the messages,
+// also on error.
 //
 // TODO: the waiting should happen in the background. Right now this
 //  is blocking/delaying the actual generation and submission of WindowPoSts in
 //  this deadline!
-func (s *WindowPoStScheduler) declareRecoveries(ctx context.Context, dlIdx uint64, partitions []api.Partition, tsk types.TipSetKey) ([]miner.RecoveryDeclaration, *types.SignedMessage, error) {
+func (s *WindowPoStScheduler) declareRecoveries(ctx context.Context, dlIdx uint64, partitions []api.Partition, tsk types.TipSetKey) ([][]miner.RecoveryDeclaration, []*types.SignedMessage, error) {
 	ctx, span := trace.StartSpan(ctx, "storage.declareRecoveries")
 	defer span.End()
 
-	faulty := uint64(0)
-	params := &miner.DeclareFaultsRecoveredParams{
-		Recoveries: []miner.RecoveryDeclaration{},
+	recoveries, faulty, err := s.checkRecoveries(ctx, dlIdx, partitions, tsk)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(recoveries) == 0 {
+		if faulty != 0 && s.provingCfg.DisableAutoRecovery {
+			log.Warnw("Automatic recovery disabled, not declaring recoveries", "deadline", dlIdx, "faulty", faulty)
+		} else if faulty != 0 {
+			log.Warnw("No recoveries to declare", "deadline", dlIdx, "faulty", faulty)
+		}
+
+		return nil, nil, nil
+	}
+
+	perMsg := len(recoveries)
+	if max := s.provingCfg.MaxPartitionsPerRecoveryMessage; max > 0 && perMsg > max {
+		perMsg = max
+	}
+
+	var (
+		batches [][]miner.RecoveryDeclaration
+		sent    []*types.SignedMessage
+		pushErr error
+	)
+	for i := 0; i < len(recoveries); i += perMsg {
+		end := i + perMsg
+		if end > len(recoveries) {
+			end = len(recoveries)
+		}
+		batch := recoveries[i:end]
+
+		sm, err := s.pushRecoveries(ctx, batch)
+		if err != nil {
+			pushErr = err
+			break
+		}
+
+		batches = append(batches, batch)
+		sent = append(sent, sm)
+	}
+
+	landed, err := s.waitDeclarations("declare faults recovered", sent)
+	if pushErr != nil {
+		err = pushErr
+	}
+
+	var (
+		declared [][]miner.RecoveryDeclaration
+		msgs     []*types.SignedMessage
+	)
+	for i, ok := range landed {
+		if ok {
+			declared = append(declared, batches[i])
+			msgs = append(msgs, sent[i])
+		}
 	}
 
+	return declared, msgs, err
+}
+
+// checkRecoveries returns the recoveries declareRecoveries would declare for
+// the partitions, and the number of faulty sectors which aren't recovering
+func (s *WindowPoStScheduler) checkRecoveries(ctx context.Context, dlIdx uint64, partitions []api.Partition, tsk types.TipSetKey) ([]miner.RecoveryDeclaration, uint64, error) {
+	faulty := uint64(0)
+	var recoveries []miner.RecoveryDeclaration
+
 	for partIdx, partition := range partitions {
 		unrecovered, err := bitfield.SubtractBitField(partition.FaultySectors, partition.RecoveringSectors)
 		if err != nil {
-			return nil, nil, xerrors.Errorf("subtracting recovered set from fault set: %w", err)
+			return nil, 0, xerrors.Errorf("subtracting recovered set from fault set: %w", err)
 		}
 
 		uc, err := unrecovered.Count()
 		if err != nil {
-			return nil, nil, xerrors.Errorf("counting unrecovered sectors: %w", err)
+			return nil, 0, xerrors.Errorf("counting unrecovered sectors: %w", err)
 		}
 
 		if uc == 0 {
@@ -277,40 +445,45 @@ func (s *WindowPoStScheduler) declareRecoveries(ctx context.Context, dlIdx uint6
 
 		faulty += uc
 
+		if s.provingCfg.DisableAutoRecovery {
+			continue
+		}
+
 		recovered, err := s.checkSectors(ctx, unrecovered, tsk)
 		if err != nil {
-			return nil, nil, xerrors.Errorf("checking unrecovered sectors: %w", err)
+			return nil, 0, xerrors.Errorf("checking unrecovered sectors: %w", err)
 		}
 
 		// if all sectors failed to recover, don't declare recoveries
 		recoveredCount, err := recovered.Count()
 		if err != nil {
-			return nil, nil, xerrors.Errorf("counting recovered sectors: %w", err)
+			return nil, 0, xerrors.Errorf("counting recovered sectors: %w", err)
 		}
 
 		if recoveredCount == 0 {
 			continue
 		}
 
-		params.Recoveries = append(params.Recoveries, miner.RecoveryDeclaration{
+		recoveries = append(recoveries, miner.RecoveryDeclaration{
 			Deadline:  dlIdx,
 			Partition: uint64(partIdx),
 			Sectors:   recovered,
 		})
 	}
 
-	recoveries := params.Recoveries
-	if len(recoveries) == 0 {
-		if faulty != 0 {
-			log.Warnw("No recoveries to declare", "deadline", dlIdx, "faulty", faulty)
-		}
+	return recoveries, faulty, nil
+}
 
-		return recoveries, nil, nil
+// pushRecoveries pushes a DeclareFaultsRecovered message with the
+// declarations to the mpool
+func (s *WindowPoStScheduler) pushRecoveries(ctx context.Context, recoveries []miner.RecoveryDeclaration) (*types.SignedMessage, error) {
+	params := &miner.DeclareFaultsRecoveredParams{
+		Recoveries: recoveries,
 	}
 
 	enc, aerr := actors.SerializeParams(params)
 	if aerr != nil {
-		return recoveries, nil, xerrors.Errorf("could not serialize declare recoveries parameters: %w", aerr)
+		return nil, xerrors.Errorf("could not serialize declare recoveries parameters: %w", aerr)
 	}
 
 	msg := &types.Message{
@@ -321,69 +494,158 @@ func (s *WindowPoStScheduler) declareRecoveries(ctx context.Context, dlIdx uint6
 	}
 	spec := &api.MessageSendSpec{MaxFee: abi.TokenAmount(s.feeCfg.MaxWindowPoStGasFee)}
 	if err := s.prepareMessage(ctx, msg, spec); err != nil {
-		return recoveries, nil, err
+		return nil, err
 	}
 
-	sm, err := s.api.MpoolPushMessage(ctx, msg, &api.MessageSendSpec{MaxFee: abi.TokenAmount(s.feeCfg.MaxWindowPoStGasFee)})
+	sm, err := s.api.MpoolPushMessage(ctx, msg, spec)
 	if err != nil {
-		return recoveries, sm, xerrors.Errorf("pushing message to mpool: %w", err)
+		return nil, xerrors.Errorf("pushing message to mpool: %w", err)
 	}
 
 	log.Warnw("declare faults recovered Message CID", "cid", sm.Cid())
 
-	rec, err := s.api.StateWaitMsg(context.TODO(), sm.Cid(), build.MessageConfidence, api.LookbackNoLimit, true)
-	if err != nil {
-		return recoveries, sm, xerrors.Errorf("declare faults recovered wait error: %w", err)
-	}
+	return sm, nil
+}
 
-	if rec.Receipt.ExitCode != 0 {
-		return recoveries, sm, xerrors.Errorf("declare faults recovered wait non-0 exit code: %d", rec.Receipt.ExitCode)
+// waitDeclarations waits for the declaration messages to land on chain, all
+// at once, and returns which of them landed successfully. The error is the
+// first of the messages which didn't.
+func (s *WindowPoStScheduler) waitDeclarations(kind string, msgs []*types.SignedMessage) ([]bool, error) {
+	errs := make([]error, len(msgs))
+
+	var wg sync.WaitGroup
+	wg.Add(len(msgs))
+	for i, sm := range msgs {
+		go func(i int, sm *types.SignedMessage) {
+			defer wg.Done()
+
+			rec, err := s.api.StateWaitMsg(context.TODO(), sm.Cid(), build.MessageConfidence, api.LookbackNoLimit, true)
+			if err != nil {
+				errs[i] = xerrors.Errorf("%s wait error: %w", kind, err)
+				return
+			}
+
+			if rec.Receipt.ExitCode != 0 {
+				errs[i] = xerrors.Errorf("%s wait non-0 exit code: %d", kind, rec.Receipt.ExitCode)
+			}
+		}(i, sm)
+	}
+	wg.Wait()
+
+	landed := make([]bool, len(msgs))
+	var firstErr error
+	for i, err := range errs {
+		landed[i] = err == nil
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
 	}
 
-	return recoveries, sm, nil
+	return landed, firstErr
 }
 
 // declareFaults identifies the sectors on the specified proving deadline that
-// are faulty, and reports the faults on chain via the `DeclareFaults` message
-// to our miner actor.
+// are faulty, and reports the faults on chain via `DeclareFaults` messages
+// to our miner actor, each declaring faults in up to
+// MaxPartitionsPerFaultMessage partitions.
 //
 // This is always invoked ahead of time, before the deadline for the evaluated
 // sectors arrives. That way, faults are declared before a penalty is accrued.
 //
-// If a declaration is made, it awaits for build.MessageConfidence confirmations
-// on chain before returning.
+// If declarations are made, all messages are pushed first, and then it awaits
+// for build.MessageConfidence confirmations of all of them at once. The
+// declarations of the messages which landed are returned, with the messages,
+// also on error.
 //
 // TODO: the waiting should happen in the background. Right now this
 //  is blocking/delaying the actual generation and submission of WindowPoSts in
 //  this deadline!
-func (s *WindowPoStScheduler) declareFaults(ctx context.Context, dlIdx uint64, partitions []api.Partition, tsk types.TipSetKey) ([]miner.FaultDeclaration, *types.SignedMessage, error) {
+func (s *WindowPoStScheduler) declareFaults(ctx context.Context, dlIdx uint64, partitions []api.Partition, tsk types.TipSetKey) ([][]miner.FaultDeclaration, []*types.SignedMessage, error) {
 	ctx, span := trace.StartSpan(ctx, "storage.declareFaults")
 	defer span.End()
 
-	bad := uint64(0)
-	params := &miner.DeclareFaultsParams{
-		Faults: []miner.FaultDeclaration{},
+	faults, bad, err := s.checkFaults(ctx, dlIdx, partitions, tsk)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(faults) == 0 {
+		return nil, nil, nil
+	}
+
+	log.Errorw("DETECTED FAULTY SECTORS, declaring faults", "count", bad, "partitions", len(faults))
+
+	perMsg := len(faults)
+	if max := s.provingCfg.MaxPartitionsPerFaultMessage; max > 0 && perMsg > max {
+		perMsg = max
+	}
+
+	var (
+		batches [][]miner.FaultDeclaration
+		sent    []*types.SignedMessage
+		pushErr error
+	)
+	for i := 0; i < len(faults); i += perMsg {
+		end := i + perMsg
+		if end > len(faults) {
+			end = len(faults)
+		}
+		batch := faults[i:end]
+
+		sm, err := s.pushFaults(ctx, batch)
+		if err != nil {
+			pushErr = err
+			break
+		}
+
+		batches = append(batches, batch)
+		sent = append(sent, sm)
+	}
+
+	landed, err := s.waitDeclarations("declare faults", sent)
+	if pushErr != nil {
+		err = pushErr
+	}
+
+	var (
+		declared [][]miner.FaultDeclaration
+		msgs     []*types.SignedMessage
+	)
+	for i, ok := range landed {
+		if ok {
+			declared = append(declared, batches[i])
+			msgs = append(msgs, sent[i])
+		}
 	}
 
+	return declared, msgs, err
+}
+
+// checkFaults returns the faults declareFaults would declare for the
+// partitions, and the number of newly faulty sectors
+func (s *WindowPoStScheduler) checkFaults(ctx context.Context, dlIdx uint64, partitions []api.Partition, tsk types.TipSetKey) ([]miner.FaultDeclaration, uint64, error) {
+	bad := uint64(0)
+	var faults []miner.FaultDeclaration
+
 	for partIdx, partition := range partitions {
 		nonFaulty, err := bitfield.SubtractBitField(partition.LiveSectors, partition.FaultySectors)
 		if err != nil {
-			return nil, nil, xerrors.Errorf("determining non faulty sectors: %w", err)
+			return nil, 0, xerrors.Errorf("determining non faulty sectors: %w", err)
 		}
 
 		good, err := s.checkSectors(ctx, nonFaulty, tsk)
 		if err != nil {
-			return nil, nil, xerrors.Errorf("checking sectors: %w", err)
+			return nil, 0, xerrors.Errorf("checking sectors: %w", err)
 		}
 
 		newFaulty, err := bitfield.SubtractBitField(nonFaulty, good)
 		if err != nil {
-			return nil, nil, xerrors.Errorf("calculating faulty sector set: %w", err)
+			return nil, 0, xerrors.Errorf("calculating faulty sector set: %w", err)
 		}
 
 		c, err := newFaulty.Count()
 		if err != nil {
-			return nil, nil, xerrors.Errorf("counting faulty sectors: %w", err)
+			return nil, 0, xerrors.Errorf("counting faulty sectors: %w", err)
 		}
 
 		if c == 0 {
@@ -392,23 +654,26 @@ func (s *WindowPoStScheduler) declareFaults(ctx context.Context, dlIdx uint64, p
 
 		bad += c
 
-		params.Faults = append(params.Faults, miner.FaultDeclaration{
+		faults = append(faults, miner.FaultDeclaration{
 			Deadline:  dlIdx,
 			Partition: uint64(partIdx),
 			Sectors:   newFaulty,
 		})
 	}
 
-	faults := params.Faults
-	if len(faults) == 0 {
-		return faults, nil, nil
-	}
+	return faults, bad, nil
+}
 
-	log.Errorw("DETECTED FAULTY SECTORS, declaring faults", "count", bad)
+// pushFaults pushes a DeclareFaults message with the declarations to the
+// mpool
+func (s *WindowPoStScheduler) pushFaults(ctx context.Context, faults []miner.FaultDeclaration) (*types.SignedMessage, error) {
+	params := &miner.DeclareFaultsParams{
+		Faults: faults,
+	}
 
 	enc, aerr := actors.SerializeParams(params)
 	if aerr != nil {
-		return faults, nil, xerrors.Errorf("could not serialize declare faults parameters: %w", aerr)
+		return nil, xerrors.Errorf("could not serialize declare faults parameters: %w", aerr)
 	}
 
 	msg := &types.Message{
@@ -417,28 +682,19 @@ func (s *WindowPoStScheduler) declareFaults(ctx context.Context, dlIdx uint64, p
 		Params: enc,
 		Value:  types.NewInt(0), // TODO: Is there a fee?
 	}
-	spec := &api.MessageSendSpec{MaxFee: abi.TokenAmount(s.feeCfg.MaxWindowPoStGasFee)}
+	spec := &api.MessageSendSpec{MaxFee: abi.TokenAmount(s.feeCfg.MaxDeclareFaultsGasFee)}
 	if err := s.prepareMessage(ctx, msg, spec); err != nil {
-		return faults, nil, err
+		return nil, err
 	}
 
 	sm, err := s.api.MpoolPushMessage(ctx, msg, spec)
 	if err != nil {
-		return faults, sm, xerrors.Errorf("pushing message to mpool: %w", err)
+		return nil, xerrors.Errorf("pushing message to mpool: %w", err)
 	}
 
 	log.Warnw("declare faults Message CID", "cid", sm.Cid())
 
-	rec, err := s.api.StateWaitMsg(context.TODO(), sm.Cid(), build.MessageConfidence, api.LookbackNoLimit, true)
-	if err != nil {
-		return faults, sm, xerrors.Errorf("declare faults wait error: %w", err)
-	}
-
-	if rec.Receipt.ExitCode != 0 {
-		return faults, sm, xerrors.Errorf("declare faults wait non-0 exit code: %d", rec.Receipt.ExitCode)
-	}
-
-	return faults, sm, nil
+	return sm, nil
 }
 
 // runPoStCycle runs a full cycle of the PoSt process:
@@ -465,52 +721,86 @@ func (s *WindowPoStScheduler) runPoStCycle(ctx context.Context, di dline.Info, t
 		}
 
 		var (
-			sigmsg     *types.SignedMessage
-			recoveries []miner.RecoveryDeclaration
-			faults     []miner.FaultDeclaration
-
-			// optionalCid returns the CID of the message, or cid.Undef is the
-			// message is nil. We don't need the argument (could capture the
-			// pointer), but it's clearer and purer like that.
-			optionalCid = func(sigmsg *types.SignedMessage) cid.Cid {
-				if sigmsg == nil {
-					return cid.Undef
-				}
-				return sigmsg.Cid()
-			}
+			recoveries [][]miner.RecoveryDeclaration
+			recMsgs    []*types.SignedMessage
+			faults     [][]miner.FaultDeclaration
+			faultMsgs  []*types.SignedMessage
 		)
 
-		if recoveries, sigmsg, err = s.declareRecoveries(context.TODO(), declDeadline, partitions, ts.Key()); err != nil {
+		if recoveries, recMsgs, err = s.declareRecoveries(context.TODO(), declDeadline, partitions, ts.Key()); err != nil {
 			// TODO: This is potentially quite bad, but not even trying to post when this fails is objectively worse
 			log.Errorf("checking sector recoveries: %v", err)
 		}
 
-		s.journal.RecordEvent(s.evtTypes[evtTypeWdPoStRecoveries], func() interface{} {
-			j := WdPoStRecoveriesProcessedEvt{
-				evtCommon:    s.getEvtCommon(err),
-				Declarations: recoveries,
-				MessageCID:   optionalCid(sigmsg),
+		// messages which landed before an error are still recorded
+		for i, decls := range recoveries {
+			for _, decl := range decls {
+				s.recordFaultHistory(decl.Sectors, api.SectorFaultEvent{
+					Kind:      api.SectorRecoveryDeclared,
+					Epoch:     ts.Height(),
+					Deadline:  decl.Deadline,
+					Partition: decl.Partition,
+					Message:   recMsgs[i].Cid(),
+				})
 			}
-			j.Error = err
-			return j
-		})
+
+			decls, msg := decls, recMsgs[i]
+			s.journal.RecordEvent(s.evtTypes[evtTypeWdPoStRecoveries], func() interface{} {
+				return WdPoStRecoveriesProcessedEvt{
+					evtCommon:    s.getEvtCommon(nil),
+					Declarations: decls,
+					MessageCID:   msg.Cid(),
+				}
+			})
+		}
+
+		if err != nil || len(recoveries) == 0 {
+			s.journal.RecordEvent(s.evtTypes[evtTypeWdPoStRecoveries], func() interface{} {
+				j := WdPoStRecoveriesProcessedEvt{
+					evtCommon: s.getEvtCommon(err),
+				}
+				j.Error = err
+				return j
+			})
+		}
 
 		if ts.Height() > build.UpgradeIgnitionHeight {
 			return // FORK: declaring faults after ignition upgrade makes no sense
 		}
 
-		if faults, sigmsg, err = s.declareFaults(context.TODO(), declDeadline, partitions, ts.Key()); err != nil {
+		if faults, faultMsgs, err = s.declareFaults(context.TODO(), declDeadline, partitions, ts.Key()); err != nil {
 			// TODO: This is also potentially really bad, but we try to post anyways
 			log.Errorf("checking sector faults: %v", err)
 		}
 
-		s.journal.RecordEvent(s.evtTypes[evtTypeWdPoStFaults], func() interface{} {
-			return WdPoStFaultsProcessedEvt{
-				evtCommon:    s.getEvtCommon(err),
-				Declarations: faults,
-				MessageCID:   optionalCid(sigmsg),
+		for i, decls := range faults {
+			for _, decl := range decls {
+				s.recordFaultHistory(decl.Sectors, api.SectorFaultEvent{
+					Kind:      api.SectorFaultDeclared,
+					Epoch:     ts.Height(),
+					Deadline:  decl.Deadline,
+					Partition: decl.Partition,
+					Message:   faultMsgs[i].Cid(),
+				})
 			}
-		})
+
+			decls, msg := decls, faultMsgs[i]
+			s.journal.RecordEvent(s.evtTypes[evtTypeWdPoStFaults], func() interface{} {
+				return WdPoStFaultsProcessedEvt{
+					evtCommon:    s.getEvtCommon(nil),
+					Declarations: decls,
+					MessageCID:   msg.Cid(),
+				}
+			})
+		}
+
+		if err != nil || len(faults) == 0 {
+			s.journal.RecordEvent(s.evtTypes[evtTypeWdPoStFaults], func() interface{} {
+				return WdPoStFaultsProcessedEvt{
+					evtCommon: s.getEvtCommon(err),
+				}
+			})
+		}
 	}()
 
 	buf := new(bytes.Buffer)
@@ -742,6 +1032,11 @@ func (s *WindowPoStScheduler) batchPartitions(partitions []api.Partition, nv net
 		partitionsPerMsg = policy.GetDeclarationsMax(nv)
 	}
 
+	// And the limit set in the proving config
+	if max := s.provingCfg.MaxPartitionsPerPoStMessage; max > 0 && partitionsPerMsg > max {
+		partitionsPerMsg = max
+	}
+
 	// The number of messages will be:
 	// ceiling(number of partitions / partitions per message)
 	batchCount := len(partitions) / partitionsPerMsg
@@ -909,5 +1204,16 @@ func (s *WindowPoStScheduler) prepareMessage(ctx context.Context, msg *types.Mes
 
 		messagepool.CapGasFee(mff, msg, &api.MessageSendSpec{MaxFee: big.Min(big.Sub(avail, msg.Value), msg.RequiredFunds())})
 	}
+
+	// PoSt messages are exempt from MaxFee, missing a proof costs more than
+	// any fee, so only complain loudly
+	if err := CheckMaxFee(ctx, s.api, msg, abi.TokenAmount(s.feeCfg.MaxFee)); err != nil {
+		if xerrors.Is(err, ErrMaxFeeExceeded) {
+			log.Errorw("WINDOW POST MESSAGE FEE ABOVE MaxFee, SENDING ANYWAY", "method", msg.Method, "error", err)
+		} else {
+			log.Errorw("checking window post message fee", "error", err)
+		}
+	}
+
 	return nil
 }
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"testing"
+	"time"
 
 	builtin5 "github.com/filecoin-project/specs-actors/v5/actors/builtin"
 	miner5 "github.com/filecoin-project/specs-actors/v5/actors/builtin/miner"
@@ -35,11 +36,16 @@ import (
 	"github.com/filecoin-project/lotus/chain/types"
 	"github.com/filecoin-project/lotus/extern/sector-storage/storiface"
 	"github.com/filecoin-project/lotus/journal"
+	"github.com/filecoin-project/lotus/node/config"
 )
 
 type mockStorageMinerAPI struct {
 	partitions     []api.Partition
+	head           *types.TipSet
 	pushedMessages chan *types.Message
+	// when set, called by StateWaitMsg before the message is reported as
+	// landed
+	waitMsg func(cid.Cid)
 	fullNodeFilteredAPI
 }
 
@@ -98,6 +104,9 @@ func (m *mockStorageMinerAPI) MpoolPushMessage(ctx context.Context, message *typ
 }
 
 func (m *mockStorageMinerAPI) StateWaitMsg(ctx context.Context, cid cid.Cid, confidence uint64, limit abi.ChainEpoch, allowReplaced bool) (*api.MsgLookup, error) {
+	if m.waitMsg != nil {
+		m.waitMsg(cid)
+	}
 	return &api.MsgLookup{
 		Receipt: types.MessageReceipt{
 			ExitCode: 0,
@@ -162,11 +171,22 @@ func (m mockVerif) GenerateWinningPoStSectorChallenge(context.Context, abi.Regis
 }
 
 type mockFaultTracker struct {
+	bad map[abi.SectorNumber]bool
 }
 
 func (m mockFaultTracker) CheckProvable(ctx context.Context, pp abi.RegisteredPoStProof, sectors []storage.SectorRef, rg storiface.RGetter) (map[abi.SectorID]string, error) {
-	// Returns "bad" sectors so just return empty map meaning all sectors are good
-	return map[abi.SectorID]string{}, nil
+	// Returns "bad" sectors, by default all sectors are good
+	bad := map[abi.SectorID]string{}
+	for _, s := range sectors {
+		if m.bad[s.ID.Number] {
+			bad[s.ID] = "mock bad"
+		}
+	}
+	return bad, nil
+}
+
+func (m mockFaultTracker) VerifyCommR(ctx context.Context, sector storage.SectorRef, commR cid.Cid) (bool, error) {
+	return true, nil
 }
 
 // TestWDPostDoPost verifies that doPost will send the correct number of window
@@ -362,7 +382,7 @@ func (m *mockStorageMinerAPI) GasEstimateMessageGas(ctx context.Context, message
 }
 
 func (m *mockStorageMinerAPI) ChainHead(ctx context.Context) (*types.TipSet, error) {
-	return nil, nil
+	return m.head, nil
 }
 
 func (m *mockStorageMinerAPI) ChainNotify(ctx context.Context) (<-chan []*api.HeadChange, error) {
@@ -402,3 +422,336 @@ func (m *mockStorageMinerAPI) WalletHas(ctx context.Context, address address.Add
 }
 
 var _ fullNodeFilteredAPI = &mockStorageMinerAPI{}
+
+// TestWDPostDeclareRecoveries verifies that provable faulty sectors are
+// declared recovered, unless automatic recovery is disabled
+func TestWDPostDeclareRecoveries(t *testing.T) {
+	ctx := context.Background()
+
+	faulty := bitfield.NewFromSet([]uint64{1, 2})
+	all := bitfield.NewFromSet([]uint64{0, 1, 2, 3})
+	partitions := []api.Partition{{
+		AllSectors:        all,
+		FaultySectors:     faulty,
+		RecoveringSectors: bitfield.New(),
+		LiveSectors:       all,
+		ActiveSectors:     all,
+	}}
+
+	newScheduler := func(pc config.ProvingConfig) (*WindowPoStScheduler, *mockStorageMinerAPI) {
+		mockStgMinerAPI := newMockStorageMinerAPI()
+		return &WindowPoStScheduler{
+			api:          mockStgMinerAPI,
+			provingCfg:   pc,
+			faultTracker: &mockFaultTracker{},
+			proofType:    abi.RegisteredPoStProof_StackedDrgWindow2KiBV1,
+			actor:        tutils.NewIDAddr(t, 100),
+			journal:      journal.NilJournal(),
+			addrSel:      &AddressSelector{},
+		}, mockStgMinerAPI
+	}
+
+	t.Run("enabled", func(t *testing.T) {
+		scheduler, mockStgMinerAPI := newScheduler(config.ProvingConfig{})
+
+		pushed := make(chan *types.Message, 1)
+		go func() {
+			pushed <- <-mockStgMinerAPI.pushedMessages
+		}()
+
+		recoveries, sms, err := scheduler.declareRecoveries(ctx, 3, partitions, types.EmptyTSK)
+		require.NoError(t, err)
+		require.Len(t, sms, 1)
+		require.Len(t, recoveries, 1)
+		require.Len(t, recoveries[0], 1)
+		require.EqualValues(t, 3, recoveries[0][0].Deadline)
+
+		recovered, err := recoveries[0][0].Sectors.All(10)
+		require.NoError(t, err)
+		require.Equal(t, []uint64{1, 2}, recovered)
+
+		msg := <-pushed
+		require.Equal(t, miner.Methods.DeclareFaultsRecovered, msg.Method)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		scheduler, _ := newScheduler(config.ProvingConfig{DisableAutoRecovery: true})
+
+		// nothing reads pushed messages, so pushing one would block
+		recoveries, sms, err := scheduler.declareRecoveries(ctx, 3, partitions, types.EmptyTSK)
+		require.NoError(t, err)
+		require.Empty(t, sms)
+		require.Empty(t, recoveries)
+	})
+
+	t.Run("batched", func(t *testing.T) {
+		scheduler, mockStgMinerAPI := newScheduler(config.ProvingConfig{MaxPartitionsPerRecoveryMessage: 2})
+
+		parts := []api.Partition{partitions[0], partitions[0], partitions[0]}
+
+		pushed := make(chan *types.Message, 2)
+		go func() {
+			for i := 0; i < 2; i++ {
+				pushed <- <-mockStgMinerAPI.pushedMessages
+			}
+		}()
+
+		// all messages are pushed before waiting, and waited for at once: no
+		// message lands until both are waited for. The waits run on their own
+		// goroutines, so they only report whether they were released, and the
+		// test checks that below.
+		waiting := make(chan struct{}, 2)
+		release := make(chan struct{})
+		released := make(chan bool, 2)
+		go func() {
+			<-waiting
+			<-waiting
+			close(release)
+		}()
+		mockStgMinerAPI.waitMsg = func(cid.Cid) {
+			waiting <- struct{}{}
+			select {
+			case <-release:
+				released <- true
+			case <-time.After(5 * time.Second):
+				released <- false
+			}
+		}
+
+		recoveries, sms, err := scheduler.declareRecoveries(ctx, 3, parts, types.EmptyTSK)
+		require.NoError(t, err)
+		for i := 0; i < 2; i++ {
+			require.True(t, <-released, "messages pushed and waited for one at a time")
+		}
+		require.Len(t, sms, 2)
+		require.Len(t, recoveries, 2)
+		require.Len(t, recoveries[0], 2)
+		require.Len(t, recoveries[1], 1)
+		require.EqualValues(t, 2, recoveries[1][0].Partition)
+
+		for _, decls := range recoveries {
+			msg := <-pushed
+			require.Equal(t, miner.Methods.DeclareFaultsRecovered, msg.Method)
+
+			var params miner5.DeclareFaultsRecoveredParams
+			require.NoError(t, params.UnmarshalCBOR(bytes.NewReader(msg.Params)))
+			require.Len(t, params.Recoveries, len(decls))
+		}
+	})
+}
+
+// TestWDPostDeclareFaults verifies that faults in many partitions are declared
+// in as few messages as the proving config allows
+func TestWDPostDeclareFaults(t *testing.T) {
+	ctx := context.Background()
+
+	// 7 partitions with 4 sectors each, two of which are unprovable
+	var partitions []api.Partition
+	bad := map[abi.SectorNumber]bool{}
+	for i := uint64(0); i < 7; i++ {
+		all := bitfield.NewFromSet([]uint64{i * 4, i*4 + 1, i*4 + 2, i*4 + 3})
+		partitions = append(partitions, api.Partition{
+			AllSectors:        all,
+			FaultySectors:     bitfield.New(),
+			RecoveringSectors: bitfield.New(),
+			LiveSectors:       all,
+			ActiveSectors:     all,
+		})
+		bad[abi.SectorNumber(i*4)] = true
+		bad[abi.SectorNumber(i*4+2)] = true
+	}
+
+	declare := func(pc config.ProvingConfig, expectMsgs int) [][]miner.FaultDeclaration {
+		mockStgMinerAPI := newMockStorageMinerAPI()
+		scheduler := &WindowPoStScheduler{
+			api:          mockStgMinerAPI,
+			provingCfg:   pc,
+			faultTracker: &mockFaultTracker{bad: bad},
+			proofType:    abi.RegisteredPoStProof_StackedDrgWindow2KiBV1,
+			actor:        tutils.NewIDAddr(t, 100),
+			journal:      journal.NilJournal(),
+			addrSel:      &AddressSelector{},
+		}
+
+		pushed := make(chan *types.Message, expectMsgs)
+		go func() {
+			for i := 0; i < expectMsgs; i++ {
+				pushed <- <-mockStgMinerAPI.pushedMessages
+			}
+		}()
+
+		faults, sms, err := scheduler.declareFaults(ctx, 3, partitions, types.EmptyTSK)
+		require.NoError(t, err)
+		require.Len(t, sms, expectMsgs)
+		require.Len(t, faults, expectMsgs)
+
+		for _, decls := range faults {
+			msg := <-pushed
+			require.Equal(t, miner.Methods.DeclareFaults, msg.Method)
+
+			var params miner5.DeclareFaultsParams
+			require.NoError(t, params.UnmarshalCBOR(bytes.NewReader(msg.Params)))
+			require.Len(t, params.Faults, len(decls))
+
+			for _, decl := range decls {
+				require.EqualValues(t, 3, decl.Deadline)
+
+				declared, err := decl.Sectors.All(10)
+				require.NoError(t, err)
+				require.Equal(t, []uint64{decl.Partition * 4, decl.Partition*4 + 2}, declared)
+			}
+		}
+
+		return faults
+	}
+
+	// by default all faults are declared in one message
+	faults := declare(config.ProvingConfig{}, 1)
+	require.Len(t, faults[0], 7)
+
+	faults = declare(config.ProvingConfig{MaxPartitionsPerFaultMessage: 3}, 3)
+	require.Len(t, faults[0], 3)
+	require.Len(t, faults[1], 3)
+	require.Len(t, faults[2], 1)
+	require.EqualValues(t, 6, faults[2][0].Partition)
+}
+
+// TestBatchPartitionsConfig verifies that the number of partitions proven in
+// a single message is capped by the proving config
+func TestBatchPartitionsConfig(t *testing.T) {
+	partitions := make([]api.Partition, 5)
+
+	batchSizes := func(pc config.ProvingConfig) []int {
+		s := &WindowPoStScheduler{
+			provingCfg: pc,
+			proofType:  abi.RegisteredPoStProof_StackedDrgWindow2KiBV1,
+		}
+
+		batches, err := s.batchPartitions(partitions, network.Version13)
+		require.NoError(t, err)
+
+		var sizes []int
+		for _, b := range batches {
+			sizes = append(sizes, len(b))
+		}
+		return sizes
+	}
+
+	// by default all partitions fit in a message
+	require.Equal(t, []int{5}, batchSizes(config.ProvingConfig{}))
+	require.Equal(t, []int{2, 2, 1}, batchSizes(config.ProvingConfig{MaxPartitionsPerPoStMessage: 2}))
+	require.Equal(t, []int{5}, batchSizes(config.ProvingConfig{MaxPartitionsPerPoStMessage: 10}))
+}
+
+// TestWDPostDryRun verifies that the dry run returns the faults and
+// recoveries which would be declared, without sending messages
+func TestWDPostDryRun(t *testing.T) {
+	ctx := context.Background()
+
+	// sector 1 is faulty but provable again, sector 2 is live but unprovable
+	all := bitfield.NewFromSet([]uint64{0, 1, 2, 3})
+	mockStgMinerAPI := newMockStorageMinerAPI()
+	mockStgMinerAPI.head = mockTipSet(t)
+	mockStgMinerAPI.setPartitions([]api.Partition{{
+		AllSectors:        all,
+		FaultySectors:     bitfield.NewFromSet([]uint64{1}),
+		RecoveringSectors: bitfield.New(),
+		LiveSectors:       all,
+		ActiveSectors:     all,
+	}})
+
+	scheduler := &WindowPoStScheduler{
+		api:          mockStgMinerAPI,
+		faultTracker: &mockFaultTracker{bad: map[abi.SectorNumber]bool{2: true}},
+		proofType:    abi.RegisteredPoStProof_StackedDrgWindow2KiBV1,
+		actor:        tutils.NewIDAddr(t, 100),
+		journal:      journal.NilJournal(),
+		addrSel:      &AddressSelector{},
+	}
+
+	res, err := scheduler.DryRun(ctx, 3)
+	require.NoError(t, err)
+	require.EqualValues(t, 3, res.Deadline)
+	require.Equal(t, abi.ChainEpoch(1), res.Height)
+
+	require.Len(t, res.Recoveries, 1)
+	recovered, err := res.Recoveries[0].Sectors.All(10)
+	require.NoError(t, err)
+	require.Equal(t, []uint64{1}, recovered)
+
+	require.Len(t, res.Faults, 1)
+	faulty, err := res.Faults[0].Sectors.All(10)
+	require.NoError(t, err)
+	require.Equal(t, []uint64{2}, faulty)
+
+	// nothing was sent
+	select {
+	case msg := <-mockStgMinerAPI.pushedMessages:
+		t.Fatalf("unexpected message pushed: %+v", msg)
+	default:
+	}
+
+	_, err = scheduler.DryRun(ctx, miner.WPoStPeriodDeadlines)
+	require.Error(t, err)
+}
+
+// hangingFaultTracker never finishes checking the sectors in hang
+type hangingFaultTracker struct {
+	mockFaultTracker
+	hang    map[abi.SectorNumber]bool
+	release chan struct{}
+}
+
+func (m hangingFaultTracker) CheckProvable(ctx context.Context, pp abi.RegisteredPoStProof, sectors []storage.SectorRef, rg storiface.RGetter) (map[abi.SectorID]string, error) {
+	for _, s := range sectors {
+		if m.hang[s.ID.Number] {
+			<-m.release
+		}
+	}
+	return m.mockFaultTracker.CheckProvable(ctx, pp, sectors, rg)
+}
+
+// TestWDPostCheckTimeouts verifies that sectors which take too long to check
+// are considered bad
+func TestWDPostCheckTimeouts(t *testing.T) {
+	ctx := context.Background()
+
+	var sectors []storage.SectorRef
+	for i := abi.SectorNumber(0); i < 4; i++ {
+		sectors = append(sectors, storage.SectorRef{ID: abi.SectorID{Miner: 100, Number: i}})
+	}
+
+	release := make(chan struct{})
+	t.Cleanup(func() { close(release) })
+
+	newScheduler := func(pc config.ProvingConfig) *WindowPoStScheduler {
+		return &WindowPoStScheduler{
+			provingCfg: pc,
+			faultTracker: hangingFaultTracker{
+				mockFaultTracker: mockFaultTracker{bad: map[abi.SectorNumber]bool{3: true}},
+				hang:             map[abi.SectorNumber]bool{1: true},
+				release:          release,
+			},
+			proofType: abi.RegisteredPoStProof_StackedDrgWindow2KiBV1,
+		}
+	}
+
+	// the hanging sector is skipped, the others are still checked
+	bad, err := newScheduler(config.ProvingConfig{
+		SingleCheckTimeout: config.Duration(50 * time.Millisecond),
+	}).checkProvable(ctx, sectors)
+	require.NoError(t, err)
+	require.Len(t, bad, 2)
+	require.Contains(t, bad[sectors[1].ID], "check timed out")
+	require.Equal(t, "mock bad", bad[sectors[3].ID])
+
+	// sectors which weren't checked before the partition timeout are bad
+	bad, err = newScheduler(config.ProvingConfig{
+		PartitionCheckTimeout: config.Duration(50 * time.Millisecond),
+	}).checkProvable(ctx, sectors)
+	require.NoError(t, err)
+	require.Len(t, bad, 3)
+	require.NotContains(t, bad, sectors[0].ID)
+	require.Contains(t, bad[sectors[1].ID], "partition check timed out")
+	require.Contains(t, bad[sectors[2].ID], "partition check timed out")
+}
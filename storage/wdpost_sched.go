@@ -32,10 +32,12 @@ import (
 type WindowPoStScheduler struct {
 	api              fullNodeFilteredAPI
 	feeCfg           config.MinerFeeConfig
+	provingCfg       config.ProvingConfig
 	addrSel          *AddressSelector
 	prover           storage.Prover
 	verifier         ffiwrapper.Verifier
 	faultTracker     sectorstorage.FaultTracker
+	faultHistory     *FaultHistory
 	proofType        abi.RegisteredPoStProof
 	partitionSectors uint64
 	ch               *changeHandler
@@ -52,10 +54,12 @@ type WindowPoStScheduler struct {
 // NewWindowedPoStScheduler creates a new WindowPoStScheduler scheduler.
 func NewWindowedPoStScheduler(api fullNodeFilteredAPI,
 	cfg config.MinerFeeConfig,
+	pc config.ProvingConfig,
 	as *AddressSelector,
 	sp storage.Prover,
 	verif ffiwrapper.Verifier,
 	ft sectorstorage.FaultTracker,
+	fh *FaultHistory,
 	j journal.Journal,
 	actor address.Address) (*WindowPoStScheduler, error) {
 	mi, err := api.StateMinerInfo(context.TODO(), actor, types.EmptyTSK)
@@ -66,10 +70,12 @@ func NewWindowedPoStScheduler(api fullNodeFilteredAPI,
 	return &WindowPoStScheduler{
 		api:              api,
 		feeCfg:           cfg,
+		provingCfg:       pc,
 		addrSel:          as,
 		prover:           sp,
 		verifier:         verif,
 		faultTracker:     ft,
+		faultHistory:     fh,
 		proofType:        mi.WindowPoStProofType,
 		partitionSectors: mi.WindowPoStPartitionSectors,
 
@@ -93,7 +99,7 @@ func (s *WindowPoStScheduler) Run(ctx context.Context) {
 		*WindowPoStScheduler
 	}{s.api, s}
 
-	s.ch = newChangeHandler(callbacks, s.actor)
+	s.ch = newChangeHandler(callbacks, s.actor, s.provingCfg.LookaheadProving)
 	defer s.ch.shutdown()
 	s.ch.start()
 